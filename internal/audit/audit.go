@@ -0,0 +1,158 @@
+// Package audit keeps a durable JSONL record of tool executions: who ran
+// which tool with which (redacted) arguments, how long it took and whether
+// it failed.
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Rotation defaults
+const (
+	DefaultMaxBytes   = 10 << 20
+	DefaultMaxBackups = 3
+)
+
+// Entry is one tool execution
+type Entry struct {
+	Time       time.Time       `json:"time"`
+	User       string          `json:"user,omitempty"`
+	SessionID  string          `json:"session_id,omitempty"`
+	Tool       string          `json:"tool"`
+	CallID     string          `json:"call_id,omitempty"`
+	Args       json.RawMessage `json:"args,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+	IsError    bool            `json:"is_error"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// Logger appends entries to a JSONL file. Once the file would grow past
+// MaxBytes it is renamed to path.1, shifting older backups up to
+// path.<MaxBackups>; the oldest is deleted. Safe for concurrent use.
+type Logger struct {
+	path       string
+	MaxBytes   int64
+	MaxBackups int
+
+	mu sync.Mutex
+}
+
+// DefaultPath returns the audit log location
+func DefaultPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".config", "groq-go", "audit.log")
+}
+
+// NewLogger returns a logger writing to path, creating its directory
+func NewLogger(path string) (*Logger, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	return &Logger{path: path, MaxBytes: DefaultMaxBytes, MaxBackups: DefaultMaxBackups}, nil
+}
+
+// Path returns the file the logger writes to
+func (l *Logger) Path() string {
+	return l.path
+}
+
+// Write appends e as one line, rotating first if needed
+func (l *Logger) Write(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if info, err := os.Stat(l.path); err == nil && l.MaxBytes > 0 && info.Size() > 0 && info.Size()+int64(len(line)) > l.MaxBytes {
+		if err := l.rotate(); err != nil {
+			return fmt.Errorf("failed to rotate audit log: %w", err)
+		}
+	}
+
+	// Reopened per entry so the REPL and web server can share the file
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// rotate shifts path.N to path.N+1 and path to path.1. The caller holds l.mu.
+func (l *Logger) rotate() error {
+	if l.MaxBackups < 1 {
+		return os.Remove(l.path)
+	}
+	os.Remove(l.backup(l.MaxBackups))
+	for i := l.MaxBackups - 1; i >= 1; i-- {
+		if err := os.Rename(l.backup(i), l.backup(i+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Rename(l.path, l.backup(1))
+}
+
+func (l *Logger) backup(n int) string {
+	return fmt.Sprintf("%s.%d", l.path, n)
+}
+
+// Recent returns up to n of the latest entries, newest first, reading
+// into the backups when the current file holds fewer. Malformed lines
+// are skipped.
+func (l *Logger) Recent(n int) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var entries []Entry
+	for i := 0; i <= l.MaxBackups && len(entries) < n; i++ {
+		path := l.path
+		if i > 0 {
+			path = l.backup(i)
+		}
+		data, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, lastEntries(data, n-len(entries))...)
+	}
+	return entries, nil
+}
+
+// lastEntries parses up to n entries from the end of a log, newest first
+func lastEntries(data []byte, n int) []Entry {
+	var lines [][]byte
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Bytes())
+	}
+
+	var entries []Entry
+	for i := len(lines) - 1; i >= 0 && len(entries) < n; i-- {
+		var e Entry
+		if json.Unmarshal(lines[i], &e) == nil {
+			entries = append(entries, e)
+		}
+	}
+	return entries
+}
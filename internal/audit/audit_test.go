@@ -0,0 +1,121 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+func newTestLogger(t *testing.T) *Logger {
+	t.Helper()
+	l, err := NewLogger(filepath.Join(t.TempDir(), "logs", "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestRedactArgs(t *testing.T) {
+	args := `{"command":"ls","api_key":"sk-1","nested":{"Password":"hunter2","items":[{"auth_token":"t"}]},"content":"` +
+		strings.Repeat("x", 2000) + `"}`
+	var got map[string]any
+	if err := json.Unmarshal(RedactArgs(args), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["command"] != "ls" || got["api_key"] != "****" {
+		t.Errorf("Unexpected top level %v", got)
+	}
+	nested := got["nested"].(map[string]any)
+	if nested["Password"] != "****" || nested["items"].([]any)[0].(map[string]any)["auth_token"] != "****" {
+		t.Errorf("Expected nested secrets masked, got %v", nested)
+	}
+	if s := got["content"].(string); len(s) > 1100 || !strings.HasSuffix(s, "(2000 bytes)") {
+		t.Errorf("Expected long content truncated, got %d bytes", len(s))
+	}
+
+	if string(RedactArgs("not json")) != `"not json"` {
+		t.Errorf("Expected raw args recorded as a string, got %s", RedactArgs("not json"))
+	}
+	if RedactArgs("") != nil {
+		t.Error("Expected no args for an empty string")
+	}
+}
+
+func TestLoggerRotation(t *testing.T) {
+	l := newTestLogger(t)
+	l.MaxBackups = 2
+	line, _ := json.Marshal(Entry{Tool: "Read", CallID: "call_00"})
+	l.MaxBytes = int64(3 * (len(line) + 1)) // Three entries per file
+
+	for i := 0; i < 12; i++ {
+		if err := l.Write(Entry{Tool: "Read", CallID: fmt.Sprintf("call_%02d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{l.Path(), l.Path() + ".1", l.Path() + ".2"} {
+		if _, err := os.Stat(name); err != nil {
+			t.Errorf("Expected %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(l.Path() + ".3"); !os.IsNotExist(err) {
+		t.Error("Expected backups beyond MaxBackups removed")
+	}
+	if info, _ := os.Stat(l.Path()); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// Newest first, continuing into the backups
+	entries, err := l.Recent(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, e := range entries {
+		ids = append(ids, e.CallID)
+	}
+	if got := strings.Join(ids, ","); got != "call_11,call_10,call_09,call_08,call_07" {
+		t.Errorf("Unexpected recent entries %s", got)
+	}
+	// Only nine entries survive rotation
+	if entries, _ = l.Recent(100); len(entries) != 9 {
+		t.Errorf("Expected 9 retained entries, got %d", len(entries))
+	}
+}
+
+func TestHookRecordsCalls(t *testing.T) {
+	l := newTestLogger(t)
+	h := NewHook(l, "local")
+	call := client.ToolCall{ID: "call_1", Function: client.FunctionCall{Name: "Bash", Arguments: `{"command":"env","token":"abc"}`}}
+
+	h.AfterExecute(context.Background(), call, tool.NewResult("ok"), nil, 1500*time.Millisecond)
+	ctx := tool.WithCaller(context.Background(), tool.Caller{User: "alice", SessionID: "sess-1"})
+	h.AfterExecute(ctx, call, tool.NewErrorResult("exit status 1"), nil, 0)
+	h.AfterExecute(tool.WithCaller(context.Background(), tool.Caller{SessionID: "sess-2"}), call, tool.Result{}, errors.New("boom"), 0)
+
+	entries, err := l.Recent(10)
+	if err != nil || len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d (%v)", len(entries), err)
+	}
+	failed, refused, ok := entries[0], entries[1], entries[2]
+	if ok.User != "local" || ok.Tool != "Bash" || ok.CallID != "call_1" || ok.DurationMS != 1500 || ok.IsError {
+		t.Errorf("Unexpected entry %+v", ok)
+	}
+	if string(ok.Args) != `{"command":"env","token":"****"}` {
+		t.Errorf("Expected redacted args, got %s", ok.Args)
+	}
+	if refused.User != "alice" || refused.SessionID != "sess-1" || !refused.IsError || refused.Error != "exit status 1" {
+		t.Errorf("Unexpected entry %+v", refused)
+	}
+	if failed.User != "local" || failed.SessionID != "sess-2" || failed.Error != "boom" {
+		t.Errorf("Unexpected entry %+v", failed)
+	}
+}
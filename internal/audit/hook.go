@@ -0,0 +1,118 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/logging"
+	"groq-go/internal/tool"
+)
+
+var log = logging.WithComponent("audit")
+
+// Hook is a tool.Hook that writes an Entry for every call
+type Hook struct {
+	logger *Logger
+	user   string // Recorded when the context names no caller
+}
+
+// NewHook returns a hook writing to logger. Calls whose context carries a
+// tool.Caller are recorded under it; others under defaultUser.
+func NewHook(logger *Logger, defaultUser string) *Hook {
+	return &Hook{logger: logger, user: defaultUser}
+}
+
+// BeforeExecute implements tool.Hook
+func (h *Hook) BeforeExecute(ctx context.Context, call client.ToolCall) context.Context {
+	return ctx
+}
+
+// AfterExecute implements tool.Hook
+func (h *Hook) AfterExecute(ctx context.Context, call client.ToolCall, result tool.Result, err error, duration time.Duration) {
+	e := Entry{
+		Time:       time.Now().UTC(),
+		User:       h.user,
+		Tool:       call.Function.Name,
+		CallID:     call.ID,
+		Args:       RedactArgs(call.Function.Arguments),
+		DurationMS: duration.Milliseconds(),
+		IsError:    result.IsError || err != nil,
+	}
+	if c, ok := tool.CallerFromContext(ctx); ok {
+		if c.User != "" {
+			e.User = c.User
+		}
+		e.SessionID = c.SessionID
+	}
+	if err != nil {
+		e.Error = err.Error()
+	} else if result.IsError {
+		e.Error = truncate(result.Content, maxErrorLen)
+	}
+	if err := h.logger.Write(e); err != nil {
+		log.Error("Failed to write audit entry", "tool", e.Tool, "error", err)
+	}
+}
+
+// Limits that keep entries small; file contents passed to Write would
+// otherwise be copied into the log
+const (
+	maxValueLen = 1024
+	maxErrorLen = 500
+)
+
+// secretKey matches argument names whose values are masked
+var secretKey = regexp.MustCompile(`(?i)(token|key|secret|passw(or)?d|credential|auth)`)
+
+// RedactArgs returns tool arguments as JSON with the values of
+// secret-looking keys masked, at any depth, and long strings shortened.
+// Arguments that are not a JSON object are recorded as a string.
+func RedactArgs(args string) json.RawMessage {
+	if args == "" {
+		return nil
+	}
+	var v any
+	if err := json.Unmarshal([]byte(args), &v); err != nil {
+		data, _ := json.Marshal(truncate(args, maxValueLen))
+		return data
+	}
+	data, err := json.Marshal(redact(v))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+func redact(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if secretKey.MatchString(k) {
+				v[k] = "****"
+				continue
+			}
+			v[k] = redact(val)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+		return v
+	case string:
+		return truncate(v, maxValueLen)
+	}
+	return v
+}
+
+// truncate shortens s to n bytes, noting how much was cut
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return fmt.Sprintf("%s... (%d bytes)", s[:n], len(s))
+}
@@ -1,8 +1,12 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
@@ -19,6 +23,7 @@ var (
 	ErrUserNotFound       = errors.New("user not found")
 	ErrUserExists         = errors.New("user already exists")
 	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrEmptyPassword      = errors.New("password must not be empty")
 )
 
 // User represents a user account
@@ -28,11 +33,11 @@ type User struct {
 	CreatedAt    string `yaml:"created_at" json:"created_at"`
 }
 
-// Token represents an authentication token
+// Token represents an authentication token. Only a hash of the value is
+// kept, in memory and in tokens.json.
 type Token struct {
-	Value     string
-	Username  string
-	ExpiresAt time.Time
+	Username  string    `json:"username"`
+	ExpiresAt time.Time `json:"expires_at"`
 }
 
 // Config represents the auth configuration file
@@ -42,12 +47,23 @@ type Config struct {
 
 // Manager handles authentication
 type Manager struct {
-	mu       sync.RWMutex
-	users    map[string]*User
-	tokens   map[string]*Token
+	mu         sync.RWMutex
+	users      map[string]*User
+	tokens     map[string]*Token // Keyed by hashToken
 	configPath string
+	tokensPath string
+	tokenTTL   time.Duration
+	now        func() time.Time
 }
 
+const (
+	// DefaultTokenTTL is how long a token stays valid after login or refresh
+	DefaultTokenTTL = 24 * time.Hour
+
+	// DefaultCleanupInterval is how often StartCleanup drops expired tokens
+	DefaultCleanupInterval = time.Hour
+)
+
 // NewManager creates a new auth manager
 func NewManager() (*Manager, error) {
 	home, err := os.UserHomeDir()
@@ -55,12 +71,15 @@ func NewManager() (*Manager, error) {
 		home = "."
 	}
 
-	configPath := filepath.Join(home, ".config", "groq-go", "users.yaml")
+	dir := filepath.Join(home, ".config", "groq-go")
 
 	m := &Manager{
 		users:      make(map[string]*User),
 		tokens:     make(map[string]*Token),
-		configPath: configPath,
+		configPath: filepath.Join(dir, "users.yaml"),
+		tokensPath: filepath.Join(dir, "tokens.json"),
+		tokenTTL:   DefaultTokenTTL,
+		now:        time.Now,
 	}
 
 	// Load existing users
@@ -68,9 +87,60 @@ func NewManager() (*Manager, error) {
 		return nil, fmt.Errorf("failed to load auth config: %w", err)
 	}
 
+	// Tokens survive restarts; a damaged file only logs everyone out
+	if err := m.loadTokens(); err != nil && !os.IsNotExist(err) {
+		m.tokens = make(map[string]*Token)
+	}
+
 	return m, nil
 }
 
+// hashToken returns the key a token is stored under
+func hashToken(tokenValue string) string {
+	sum := sha256.Sum256([]byte(tokenValue))
+	return hex.EncodeToString(sum[:])
+}
+
+func (m *Manager) loadTokens() error {
+	data, err := os.ReadFile(m.tokensPath)
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]*Token)
+	if err := json.Unmarshal(data, &tokens); err != nil {
+		return fmt.Errorf("failed to parse tokens: %w", err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	for hash, token := range tokens {
+		if token != nil && now.Before(token.ExpiresAt) {
+			m.tokens[hash] = token
+		}
+	}
+	return nil
+}
+
+// saveTokensLocked writes the token hashes; the caller holds m.mu
+func (m *Manager) saveTokensLocked() error {
+	data, err := json.MarshalIndent(m.tokens, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokens: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(m.tokensPath), 0755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	// Write-then-rename so a crash never leaves a truncated file
+	tmp := m.tokensPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write tokens: %w", err)
+	}
+	return os.Rename(tmp, m.tokensPath)
+}
+
 func (m *Manager) loadConfig() error {
 	data, err := os.ReadFile(m.configPath)
 	if err != nil {
@@ -163,7 +233,11 @@ func (m *Manager) Authenticate(username, password string) (string, error) {
 		return "", ErrInvalidCredentials
 	}
 
-	// Generate token
+	return m.issueToken(username)
+}
+
+// issueToken creates and stores a new token for username
+func (m *Manager) issueToken(username string) (string, error) {
 	tokenBytes := make([]byte, 32)
 	if _, err := rand.Read(tokenBytes); err != nil {
 		return "", fmt.Errorf("failed to generate token: %w", err)
@@ -172,37 +246,37 @@ func (m *Manager) Authenticate(username, password string) (string, error) {
 	tokenValue := base64.URLEncoding.EncodeToString(tokenBytes)
 
 	m.mu.Lock()
-	m.tokens[tokenValue] = &Token{
-		Value:     tokenValue,
+	defer m.mu.Unlock()
+	m.tokens[hashToken(tokenValue)] = &Token{
 		Username:  username,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
+		ExpiresAt: m.now().Add(m.tokenTTL),
+	}
+	if err := m.saveTokensLocked(); err != nil {
+		return "", err
 	}
-	m.mu.Unlock()
 
 	return tokenValue, nil
 }
 
+// SetTokenTTL changes the lifetime of tokens issued or refreshed from now on
+func (m *Manager) SetTokenTTL(ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tokenTTL = ttl
+}
+
 // ValidateToken checks if a token is valid
 func (m *Manager) ValidateToken(tokenValue string) (*User, error) {
 	m.mu.RLock()
-	token, exists := m.tokens[tokenValue]
-	m.mu.RUnlock()
-
-	if !exists {
-		return nil, ErrInvalidToken
-	}
+	defer m.mu.RUnlock()
 
-	if time.Now().After(token.ExpiresAt) {
-		m.mu.Lock()
-		delete(m.tokens, tokenValue)
-		m.mu.Unlock()
+	token, exists := m.tokens[hashToken(tokenValue)]
+	if !exists || !m.now().Before(token.ExpiresAt) {
+		// Expired tokens are dropped by CleanupExpired
 		return nil, ErrInvalidToken
 	}
 
-	m.mu.RLock()
 	user, exists := m.users[token.Username]
-	m.mu.RUnlock()
-
 	if !exists {
 		return nil, ErrUserNotFound
 	}
@@ -210,11 +284,112 @@ func (m *Manager) ValidateToken(tokenValue string) (*User, error) {
 	return user, nil
 }
 
+// RefreshToken extends a valid token to a full TTL from now and returns
+// the new expiry. Expired tokens can't be refreshed.
+func (m *Manager) RefreshToken(tokenValue string) (time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	token, exists := m.tokens[hashToken(tokenValue)]
+	now := m.now()
+	if !exists || !now.Before(token.ExpiresAt) {
+		return time.Time{}, ErrInvalidToken
+	}
+	if _, exists := m.users[token.Username]; !exists {
+		return time.Time{}, ErrUserNotFound
+	}
+
+	token.ExpiresAt = now.Add(m.tokenTTL)
+	if err := m.saveTokensLocked(); err != nil {
+		return time.Time{}, err
+	}
+	return token.ExpiresAt, nil
+}
+
 // InvalidateToken removes a token (logout)
 func (m *Manager) InvalidateToken(tokenValue string) {
 	m.mu.Lock()
-	delete(m.tokens, tokenValue)
+	defer m.mu.Unlock()
+	hash := hashToken(tokenValue)
+	if _, exists := m.tokens[hash]; exists {
+		delete(m.tokens, hash)
+		m.saveTokensLocked()
+	}
+}
+
+// CleanupExpired drops expired tokens and returns how many were removed
+func (m *Manager) CleanupExpired() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	removed := 0
+	for hash, token := range m.tokens {
+		if !now.Before(token.ExpiresAt) {
+			delete(m.tokens, hash)
+			removed++
+		}
+	}
+	if removed > 0 {
+		m.saveTokensLocked()
+	}
+	return removed
+}
+
+// StartCleanup runs CleanupExpired every interval until ctx is done
+func (m *Manager) StartCleanup(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				m.CleanupExpired()
+			}
+		}
+	}()
+}
+
+// ChangePassword replaces username's password after checking the old one.
+// All of the user's tokens are revoked.
+func (m *Manager) ChangePassword(username, oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return ErrEmptyPassword
+	}
+
+	m.mu.RLock()
+	user, exists := m.users[username]
+	m.mu.RUnlock()
+	if !exists {
+		return ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(oldPassword)); err != nil {
+		return ErrInvalidCredentials
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	m.mu.Lock()
+	updated := *user
+	updated.PasswordHash = string(hash)
+	m.users[username] = &updated
+	for hash, token := range m.tokens {
+		if token.Username == username {
+			delete(m.tokens, hash)
+		}
+	}
+	tokenErr := m.saveTokensLocked()
 	m.mu.Unlock()
+
+	if err := m.saveConfig(); err != nil {
+		return err
+	}
+	return tokenErr
 }
 
 // HasUsers returns true if any users are configured
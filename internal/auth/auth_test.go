@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestManager returns a manager in a temporary home with user alice.
+// The clock is controlled through the returned pointer.
+func newTestManager(t *testing.T) (*Manager, *time.Time) {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if err := m.CreateUser("alice", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	return m, &now
+}
+
+// restart loads a new manager from the same files with the same clock
+func restart(t *testing.T, now *time.Time) *Manager {
+	t.Helper()
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	m.now = func() time.Time { return *now }
+	m.loadTokens()
+	return m
+}
+
+func TestTokensSurviveRestart(t *testing.T) {
+	m, now := newTestManager(t)
+	token, err := m.Authenticate("alice", "secret")
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+
+	data, err := os.ReadFile(m.tokensPath)
+	if err != nil {
+		t.Fatalf("Expected tokens file: %v", err)
+	}
+	if strings.Contains(string(data), token) {
+		t.Error("Token must be stored hashed")
+	}
+
+	m2 := restart(t, now)
+	if user, err := m2.ValidateToken(token); err != nil || user.Username != "alice" {
+		t.Errorf("Expected token to survive restart, got %v", err)
+	}
+
+	// Logout is persisted too
+	m2.InvalidateToken(token)
+	if _, err := restart(t, now).ValidateToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected logged-out token to stay invalid, got %v", err)
+	}
+}
+
+func TestRefreshToken(t *testing.T) {
+	m, now := newTestManager(t)
+	token, _ := m.Authenticate("alice", "secret")
+
+	// 20 hours in, a refresh gives another full day
+	*now = now.Add(20 * time.Hour)
+	expiresAt, err := m.RefreshToken(token)
+	if err != nil {
+		t.Fatalf("RefreshToken failed: %v", err)
+	}
+	if want := now.Add(DefaultTokenTTL); !expiresAt.Equal(want) {
+		t.Errorf("Expected expiry %v, got %v", want, expiresAt)
+	}
+
+	*now = now.Add(10 * time.Hour)
+	if _, err := m.ValidateToken(token); err != nil {
+		t.Errorf("Expected refreshed token to be valid past the original expiry, got %v", err)
+	}
+	// The extension is persisted
+	if _, err := restart(t, now).ValidateToken(token); err != nil {
+		t.Errorf("Expected refreshed expiry to survive restart, got %v", err)
+	}
+
+	// Once expired, a token can't be refreshed
+	*now = now.Add(DefaultTokenTTL)
+	if _, err := m.RefreshToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken refreshing an expired token, got %v", err)
+	}
+	if _, err := m.RefreshToken("bogus"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("Expected ErrInvalidToken for unknown token, got %v", err)
+	}
+}
+
+func TestCleanupExpired(t *testing.T) {
+	m, now := newTestManager(t)
+	old, _ := m.Authenticate("alice", "secret")
+	*now = now.Add(20 * time.Hour)
+	fresh, _ := m.Authenticate("alice", "secret")
+
+	*now = now.Add(5 * time.Hour)
+	if removed := m.CleanupExpired(); removed != 1 {
+		t.Errorf("Expected 1 expired token removed, got %d", removed)
+	}
+	if len(m.tokens) != 1 {
+		t.Errorf("Expected 1 token left, got %d", len(m.tokens))
+	}
+	if _, err := m.ValidateToken(old); err == nil {
+		t.Error("Expected old token to be gone")
+	}
+	if _, err := m.ValidateToken(fresh); err != nil {
+		t.Errorf("Expected fresh token to remain, got %v", err)
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	m, now := newTestManager(t)
+	token, _ := m.Authenticate("alice", "secret")
+
+	if err := m.ChangePassword("alice", "wrong", "new"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Errorf("Expected ErrInvalidCredentials, got %v", err)
+	}
+	if err := m.ChangePassword("alice", "secret", ""); !errors.Is(err, ErrEmptyPassword) {
+		t.Errorf("Expected ErrEmptyPassword, got %v", err)
+	}
+	if err := m.ChangePassword("alice", "secret", "rotated"); err != nil {
+		t.Fatalf("ChangePassword failed: %v", err)
+	}
+
+	if _, err := m.ValidateToken(token); err == nil {
+		t.Error("Expected existing tokens to be revoked")
+	}
+	m2 := restart(t, now)
+	if _, err := m2.Authenticate("alice", "secret"); err == nil {
+		t.Error("Expected old password to be rejected after restart")
+	}
+	if _, err := m2.Authenticate("alice", "rotated"); err != nil {
+		t.Errorf("Expected new password to work after restart, got %v", err)
+	}
+}
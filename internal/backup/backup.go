@@ -0,0 +1,507 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"groq-go/internal/config"
+)
+
+// ManifestName is the first entry of every archive
+const ManifestName = "manifest.json"
+
+// FormatVersion is bumped when the archive layout changes incompatibly
+const FormatVersion = 1
+
+// ErrNotEmpty is returned when restoring over existing data without Force
+var ErrNotEmpty = errors.New("data directory is not empty; use force to overwrite")
+
+// Manifest describes the contents of a backup archive
+type Manifest struct {
+	FormatVersion   int            `json:"format_version"`
+	AppVersion      string         `json:"app_version"`
+	GoVersion       string         `json:"go_version"`
+	CreatedAt       time.Time      `json:"created_at"`
+	Home            string         `json:"home"`
+	DataDir         string         `json:"data_dir"`
+	IncludesSecrets bool           `json:"includes_secrets"`
+	Files           int            `json:"files"`
+	Counts          map[string]int `json:"counts"`
+}
+
+// entries lists what a backup contains, relative to the data directory.
+// Binaries, build worktrees, uploads and caches are reproducible and skipped.
+var entries = []string{
+	"config.yaml",
+	"users.yaml",
+	"mcp.json",
+	"plugins.yaml",
+	"plugins",
+	"projects.json",
+	"schedules.json",
+	"memory.json",
+	"sessions",
+	"knowledge",
+	"credits",
+	"credits-config.json",
+	"versions",
+	"last_known_good",
+}
+
+// secretFiles are dropped entirely when secrets are excluded.
+// config.yaml is kept with its API keys blanked.
+var secretFiles = map[string]bool{
+	"users.yaml": true,
+	"mcp.json":   true,
+}
+
+// Options configures Create
+type Options struct {
+	DataDir        string // defaults to config.Dir()
+	Home           string // defaults to the user's home directory
+	IncludeSecrets bool
+}
+
+// RestoreOptions configures Restore
+type RestoreOptions struct {
+	DataDir string // defaults to config.Dir()
+	Home    string // defaults to the user's home directory
+	Force   bool
+}
+
+func defaults(dataDir, home string) (string, string) {
+	if dataDir == "" {
+		dataDir = config.Dir()
+	}
+	if home == "" {
+		if h, err := os.UserHomeDir(); err == nil {
+			home = h
+		}
+	}
+	return dataDir, home
+}
+
+// Create writes a tar.gz backup of the data directory to w
+func Create(w io.Writer, opts Options) (*Manifest, error) {
+	dataDir, home := defaults(opts.DataDir, opts.Home)
+
+	type file struct {
+		name string
+		data []byte
+		mode fs.FileMode
+	}
+	var files []file
+	counts := make(map[string]int)
+
+	for _, entry := range entries {
+		if !opts.IncludeSecrets && secretFiles[entry] {
+			continue
+		}
+		root := filepath.Join(dataDir, entry)
+		err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			rel, err := filepath.Rel(dataDir, p)
+			if err != nil {
+				return err
+			}
+			rel = filepath.ToSlash(rel)
+			if d.IsDir() || !d.Type().IsRegular() || !include(rel) {
+				return nil
+			}
+
+			data, err := os.ReadFile(p)
+			if err != nil {
+				return err
+			}
+			if rel == "config.yaml" && !opts.IncludeSecrets {
+				if data, err = redactConfig(data); err != nil {
+					return err
+				}
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			files = append(files, file{name: rel, data: data, mode: info.Mode().Perm()})
+			if category := countCategory(rel); category != "" {
+				counts[category]++
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry, err)
+		}
+	}
+
+	manifest := &Manifest{
+		FormatVersion:   FormatVersion,
+		AppVersion:      appVersion(),
+		GoVersion:       runtime.Version(),
+		CreatedAt:       time.Now().UTC(),
+		Home:            home,
+		DataDir:         dataDir,
+		IncludesSecrets: opts.IncludeSecrets,
+		Files:           len(files),
+		Counts:          counts,
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte, mode fs.FileMode) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    int64(mode),
+			Size:    int64(len(data)),
+			ModTime: manifest.CreatedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := write(ManifestName, manifestData, 0644); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	for _, f := range files {
+		if err := write(f.name, f.data, f.mode); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// Restore extracts a backup into the data directory, rewriting paths under
+// the original home directory to the new one
+func Restore(r io.Reader, opts RestoreOptions) (*Manifest, error) {
+	dataDir, home := defaults(opts.DataDir, opts.Home)
+
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	hdr, err := tr.Next()
+	if err != nil || hdr.Name != ManifestName {
+		return nil, fmt.Errorf("not a backup archive: missing %s", ManifestName)
+	}
+	var manifest Manifest
+	if err := json.NewDecoder(io.LimitReader(tr, 1<<20)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.FormatVersion < 1 || manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("unsupported backup format version %d", manifest.FormatVersion)
+	}
+
+	if !opts.Force {
+		empty, err := isEmpty(dataDir)
+		if err != nil {
+			return nil, err
+		}
+		if !empty {
+			return nil, ErrNotEmpty
+		}
+	}
+
+	// Extract into a staging directory first, so a bad or truncated archive
+	// leaves the data directory as it was
+	if err := os.MkdirAll(dataDir, 0755); err != nil {
+		return nil, err
+	}
+	staging, err := os.MkdirTemp(dataDir, ".restore-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging)
+
+	pairs := pathRewrites(&manifest, dataDir, home)
+	var names []string
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		name := path.Clean(hdr.Name)
+		if path.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") || !include(name) {
+			return nil, fmt.Errorf("refusing to extract %q", hdr.Name)
+		}
+		var rewrite [][2]string
+		if ext := path.Ext(name); ext == ".json" || ext == ".yaml" {
+			rewrite = pairs
+		}
+		if err := extractFile(filepath.Join(staging, filepath.FromSlash(name)), tr, fs.FileMode(hdr.Mode).Perm(), rewrite); err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		names = append(names, name)
+	}
+
+	if len(names) != manifest.Files {
+		return &manifest, fmt.Errorf("archive has %d files, manifest expects %d", len(names), manifest.Files)
+	}
+	if err := swapIn(staging, dataDir, names); err != nil {
+		return &manifest, err
+	}
+	return &manifest, nil
+}
+
+// extractFile streams r into a new file at dest, replacing the paths of
+// rewrite on the way
+func extractFile(dest string, r io.Reader, mode fs.FileMode, rewrite [][2]string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = f
+	rw := newRewriter(f, rewrite)
+	if rw != nil {
+		w = rw
+	}
+	_, err = io.Copy(w, r)
+	if err == nil && rw != nil {
+		err = rw.Close()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// swapIn moves the staged files over their counterparts in dataDir. Files
+// being replaced are moved aside first and put back if a later move fails,
+// so the data directory ends up either fully restored or untouched.
+func swapIn(staging, dataDir string, names []string) error {
+	old, err := os.MkdirTemp(dataDir, ".restore-old-")
+	if err != nil {
+		return fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(old)
+
+	type move struct{ dest, saved string }
+	var done []move
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			m := done[i]
+			os.Remove(m.dest)
+			if m.saved != "" {
+				os.Rename(m.saved, m.dest)
+			}
+		}
+	}
+
+	for _, name := range names {
+		rel := filepath.FromSlash(name)
+		dest := filepath.Join(dataDir, rel)
+		m := move{dest: dest}
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+			rollback()
+			return err
+		}
+		if _, err := os.Lstat(dest); err == nil {
+			m.saved = filepath.Join(old, rel)
+			if err := os.MkdirAll(filepath.Dir(m.saved), 0755); err != nil {
+				rollback()
+				return err
+			}
+			if err := os.Rename(dest, m.saved); err != nil {
+				rollback()
+				return fmt.Errorf("failed to replace %s: %w", name, err)
+			}
+		}
+		if err := os.Rename(filepath.Join(staging, rel), dest); err != nil {
+			if m.saved != "" {
+				os.Rename(m.saved, dest)
+			}
+			rollback()
+			return fmt.Errorf("failed to restore %s: %w", name, err)
+		}
+		done = append(done, m)
+	}
+	return nil
+}
+
+// include reports whether a slash-separated relative path belongs in a backup
+func include(rel string) bool {
+	top, rest, _ := strings.Cut(rel, "/")
+	found := false
+	for _, e := range entries {
+		if e == top {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+	// The knowledge search index is rebuilt from the documents on load
+	if top == "knowledge" && rest == "index.json" {
+		return false
+	}
+	// Only version metadata is kept; builds are reproducible from git
+	if top == "versions" {
+		_, file, ok := strings.Cut(rest, "/")
+		return ok && file == "meta.json"
+	}
+	return true
+}
+
+// countCategory maps a file to the manifest count it contributes to
+func countCategory(rel string) string {
+	switch {
+	case strings.HasPrefix(rel, "sessions/shares/"):
+		return "shares"
+	case strings.HasPrefix(rel, "sessions/") && strings.Count(rel, "/") == 1:
+		return "sessions"
+	case strings.HasPrefix(rel, "knowledge/"):
+		return "knowledge"
+	case strings.HasPrefix(rel, "credits/") && strings.Count(rel, "/") == 1:
+		return "credits"
+	case strings.HasPrefix(rel, "versions/"):
+		return "versions"
+	case strings.HasPrefix(rel, "plugins/"):
+		return "plugins"
+	}
+	return ""
+}
+
+// redactConfig blanks API keys and tokens in config.yaml
+func redactConfig(data []byte) ([]byte, error) {
+	var cfg config.Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	cfg.ClearSecrets()
+	return yaml.Marshal(&cfg)
+}
+
+// pathRewrites lists the absolute paths of the source machine with what
+// they become on this one, in the order they are tried
+func pathRewrites(m *Manifest, dataDir, home string) [][2]string {
+	var pairs [][2]string
+	// Data dir first so a relocated data dir wins over the home prefix
+	if m.DataDir != "" && m.DataDir != dataDir {
+		pairs = append(pairs, [2]string{m.DataDir, dataDir})
+	}
+	if m.Home != "" && home != "" && m.Home != home {
+		pairs = append(pairs, [2]string{m.Home + "/", home + "/"})
+	}
+	return pairs
+}
+
+// rewriter replaces paths in what is written through it in a single pass,
+// like strings.Replacer, so replaced text is never rewritten again. Bytes
+// that may be the start of a path are held until the next Write or Close.
+type rewriter struct {
+	w     io.Writer
+	pairs [][2][]byte
+	buf   []byte
+}
+
+// newRewriter returns a rewriter writing to w, or nil without pairs
+func newRewriter(w io.Writer, pairs [][2]string) *rewriter {
+	if len(pairs) == 0 {
+		return nil
+	}
+	r := &rewriter{w: w}
+	for _, p := range pairs {
+		r.pairs = append(r.pairs, [2][]byte{[]byte(p[0]), []byte(p[1])})
+	}
+	return r
+}
+
+func (r *rewriter) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+	if err := r.flush(false); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close writes out the bytes held back
+func (r *rewriter) Close() error {
+	return r.flush(true)
+}
+
+func (r *rewriter) flush(final bool) error {
+	var out []byte
+	i := 0
+scan:
+	for i < len(r.buf) {
+		rest := r.buf[i:]
+		for _, p := range r.pairs {
+			if bytes.HasPrefix(rest, p[0]) {
+				out = append(out, p[1]...)
+				i += len(p[0])
+				continue scan
+			}
+			if !final && len(rest) < len(p[0]) && bytes.HasPrefix(p[0], rest) {
+				break scan
+			}
+		}
+		out = append(out, r.buf[i])
+		i++
+	}
+	r.buf = append(r.buf[:0], r.buf[i:]...)
+	_, err := r.w.Write(out)
+	return err
+}
+
+func isEmpty(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return true, nil
+		}
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+func appVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
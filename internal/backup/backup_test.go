@@ -0,0 +1,188 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/knowledge"
+	"groq-go/internal/storage"
+)
+
+func populate(t *testing.T, home string) string {
+	dataDir := filepath.Join(home, ".config", "groq-go")
+	ctx := context.Background()
+
+	store, err := storage.NewFileStorage(filepath.Join(dataDir, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.SaveSession(ctx, &storage.Session{
+		ID:       "s1",
+		Messages: []client.Message{{Role: "user", Content: "hello backup"}},
+		Files:    []storage.FileEntry{{Name: "a.txt", Path: filepath.Join(dataDir, "uploads", "a.txt")}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	kb, err := knowledge.NewKnowledgeBase(filepath.Join(dataDir, "knowledge"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{
+		"notes":  "The migration plan uses tarballs.",
+		"recipe": "Boil the pasta for ten minutes.",
+	} {
+		if _, err := kb.AddDocument(ctx, name, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files := map[string]string{
+		"config.yaml":              "api_key: gsk_secret\nmodel: llama-3.3-70b-versatile\n",
+		"users.yaml":               "users: []\n",
+		"versions/v1/meta.json":    `{"id": "v1", "binary_path": "` + filepath.Join(dataDir, "versions", "v1", "groq-go") + `"}`,
+		"versions/v1/groq-go":      "binary",
+		"uploads/a.txt":            "reproducible",
+		"credits/user_1.json":      `{"user_id": "user_1", "balance": 42}`,
+		"sessions/shares/abc.json": `{"share_id": "abc"}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(dataDir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dataDir
+}
+
+func TestBackupRestoreRoundTrip(t *testing.T) {
+	oldHome := t.TempDir()
+	oldData := populate(t, oldHome)
+
+	var buf bytes.Buffer
+	manifest, err := Create(&buf, Options{DataDir: oldData, Home: oldHome, IncludeSecrets: true})
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if manifest.Counts["sessions"] != 1 || manifest.Counts["knowledge"] != 2 || manifest.Counts["shares"] != 1 {
+		t.Errorf("Unexpected counts: %v", manifest.Counts)
+	}
+
+	newHome := t.TempDir()
+	newData := filepath.Join(newHome, ".config", "groq-go")
+	if _, err := Restore(bytes.NewReader(buf.Bytes()), RestoreOptions{DataDir: newData, Home: newHome}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+
+	ctx := context.Background()
+	store, err := storage.NewFileStorage(filepath.Join(newData, "sessions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := store.LoadSession(ctx, "s1")
+	if err != nil || session == nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	if !strings.HasPrefix(session.Files[0].Path, newData) {
+		t.Errorf("Expected path mapped to new home, got %s", session.Files[0].Path)
+	}
+
+	kb, err := knowledge.NewKnowledgeBase(filepath.Join(newData, "knowledge"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results := kb.Search(ctx, "migration tarballs", 5); len(results) == 0 {
+		t.Error("Expected knowledge search to find restored document")
+	}
+
+	meta, _ := os.ReadFile(filepath.Join(newData, "versions", "v1", "meta.json"))
+	if strings.Contains(string(meta), oldHome) {
+		t.Errorf("Version metadata still references old home: %s", meta)
+	}
+	for _, skipped := range []string{"versions/v1/groq-go", "uploads/a.txt"} {
+		if _, err := os.Stat(filepath.Join(newData, skipped)); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be excluded", skipped)
+		}
+	}
+
+	// A second restore over the populated directory needs force
+	if _, err := Restore(bytes.NewReader(buf.Bytes()), RestoreOptions{DataDir: newData, Home: newHome}); !errors.Is(err, ErrNotEmpty) {
+		t.Errorf("Expected ErrNotEmpty, got %v", err)
+	}
+	if _, err := Restore(bytes.NewReader(buf.Bytes()), RestoreOptions{DataDir: newData, Home: newHome, Force: true}); err != nil {
+		t.Errorf("Forced restore failed: %v", err)
+	}
+}
+
+func TestBackupWithoutSecrets(t *testing.T) {
+	home := t.TempDir()
+	dataDir := populate(t, home)
+
+	var buf bytes.Buffer
+	if _, err := Create(&buf, Options{DataDir: dataDir, Home: home}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	newData := filepath.Join(t.TempDir(), "data")
+	if _, err := Restore(&buf, RestoreOptions{DataDir: newData, Home: home}); err != nil {
+		t.Fatalf("Restore failed: %v", err)
+	}
+	cfg, _ := os.ReadFile(filepath.Join(newData, "config.yaml"))
+	if strings.Contains(string(cfg), "gsk_secret") || !strings.Contains(string(cfg), "llama-3.3-70b-versatile") {
+		t.Errorf("Expected keys redacted and model kept, got %s", cfg)
+	}
+	if _, err := os.Stat(filepath.Join(newData, "users.yaml")); !os.IsNotExist(err) {
+		t.Error("Expected users.yaml to be excluded")
+	}
+}
+
+func TestFailedRestoreLeavesDataAlone(t *testing.T) {
+	home := t.TempDir()
+	dataDir := populate(t, home)
+	var buf bytes.Buffer
+	if _, err := Create(&buf, Options{DataDir: dataDir, Home: home, IncludeSecrets: true}); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	target := filepath.Join(t.TempDir(), "data")
+	os.MkdirAll(target, 0755)
+	os.WriteFile(filepath.Join(target, "users.yaml"), []byte("users: [current]\n"), 0644)
+
+	truncated := buf.Bytes()[:buf.Len()*3/4]
+	if _, err := Restore(bytes.NewReader(truncated), RestoreOptions{DataDir: target, Home: home, Force: true}); err == nil {
+		t.Fatal("Expected a truncated archive to fail")
+	}
+	entries, _ := os.ReadDir(target)
+	if len(entries) != 1 || entries[0].Name() != "users.yaml" {
+		t.Errorf("Expected only the existing file left, got %v", entries)
+	}
+	if data, _ := os.ReadFile(filepath.Join(target, "users.yaml")); string(data) != "users: [current]\n" {
+		t.Errorf("Expected users.yaml untouched, got %q", data)
+	}
+}
+
+func TestRewriterMatchesAcrossWrites(t *testing.T) {
+	pairs := [][2]string{{"/old/data", "/new/data"}, {"/old/", "/home/me/"}}
+	input := `{"a": "/old/data/x", "b": "/old/notes", "c": "/olden", "d": "/old/data"}`
+	want := strings.NewReplacer(pairs[0][0], pairs[0][1], pairs[1][0], pairs[1][1]).Replace(input)
+
+	for _, size := range []int{1, 3, 7, len(input)} {
+		var out bytes.Buffer
+		rw := newRewriter(&out, pairs)
+		for i := 0; i < len(input); i += size {
+			rw.Write([]byte(input[i:min(i+size, len(input))]))
+		}
+		rw.Close()
+		if out.String() != want {
+			t.Errorf("Writes of %d bytes: expected %s, got %s", size, want, out.String())
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// ModelInfo describes a model: which provider serves it and what it can do
+type ModelInfo struct {
+	Name          string `json:"name"`
+	Provider      string `json:"provider"`
+	ContextWindow int    `json:"context_window,omitempty"` // Tokens; 0 for DefaultContextWindow
+	Vision        bool   `json:"vision"`
+	Tools         bool   `json:"tools"`
+	CostHint      int    `json:"cost_hint,omitempty"` // Relative cost per request, 1 for the cheapest
+	Legacy        bool   `json:"-"`                   // Routed, but not offered in model pickers
+}
+
+// ModelCatalog is the set of models groq-go knows, in display order. It
+// decides which provider a model is sent to. Safe for concurrent use.
+type ModelCatalog struct {
+	mu        sync.RWMutex
+	models    []ModelInfo
+	index     map[string]int
+	endpoints []string // Providers added with AddProvider
+}
+
+// NewModelCatalog returns a catalog of models
+func NewModelCatalog(models ...ModelInfo) *ModelCatalog {
+	c := &ModelCatalog{index: make(map[string]int)}
+	for _, m := range models {
+		c.add(m)
+	}
+	return c
+}
+
+// AddProvider registers the name of a custom OpenAI-compatible endpoint,
+// see WithEndpoint, so models can be added for it
+func (c *ModelCatalog) AddProvider(name string) error {
+	if !validProviderName(name) {
+		return fmt.Errorf("invalid provider name %q: use lowercase letters, digits and dashes", name)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slices.Contains(Providers, name) || slices.Contains(c.endpoints, name) {
+		return fmt.Errorf("provider %s already exists", name)
+	}
+	c.endpoints = append(c.endpoints, name)
+	return nil
+}
+
+func validProviderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Providers returns the built-in providers followed by custom ones
+func (c *ModelCatalog) Providers() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return append(slices.Clone(Providers), c.endpoints...)
+}
+
+// Add registers a model, replacing any entry of the same name. The
+// provider must be one of Providers or added with AddProvider.
+func (c *ModelCatalog) Add(m ModelInfo) error {
+	if m.Name == "" {
+		return fmt.Errorf("model name is required")
+	}
+	if providers := c.Providers(); !slices.Contains(providers, m.Provider) {
+		return fmt.Errorf("model %s: unknown provider %q (want one of %s)", m.Name, m.Provider, strings.Join(providers, ", "))
+	}
+	if m.ContextWindow < 0 || m.CostHint < 0 {
+		return fmt.Errorf("model %s: context window and cost hint must not be negative", m.Name)
+	}
+	c.add(m)
+	return nil
+}
+
+func (c *ModelCatalog) add(m ModelInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i, ok := c.index[m.Name]; ok {
+		c.models[i] = m
+		return
+	}
+	c.index[m.Name] = len(c.models)
+	c.models = append(c.models, m)
+}
+
+// Lookup returns the entry for a model
+func (c *ModelCatalog) Lookup(name string) (ModelInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	i, ok := c.index[name]
+	if !ok {
+		return ModelInfo{}, false
+	}
+	return c.models[i], true
+}
+
+// Models returns the models offered in pickers, leaving out legacy ones
+func (c *ModelCatalog) Models() []ModelInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	var models []ModelInfo
+	for _, m := range c.models {
+		if !m.Legacy {
+			models = append(models, m)
+		}
+	}
+	return models
+}
+
+// ProviderModels returns the names of a provider's models, best default first
+func (c *ModelCatalog) ProviderModels(provider string) []string {
+	var names []string
+	for _, m := range c.Models() {
+		if m.Provider == provider {
+			names = append(names, m.Name)
+		}
+	}
+	return names
+}
+
+// Catalog holds the built-in models and those added from the config
+var Catalog = NewModelCatalog(builtinModels...)
+
+// builtinModels are grouped by provider, each provider's default first
+var builtinModels = []ModelInfo{
+	// Groq
+	{Name: "llama-3.3-70b-versatile", Provider: "groq", ContextWindow: 131072, Tools: true, CostHint: 2},
+	{Name: "llama-3.1-8b-instant", Provider: "groq", ContextWindow: 131072, Tools: true, CostHint: 1},
+	{Name: "llama-3.2-90b-vision-preview", Provider: "groq", ContextWindow: 8192, Vision: true, Tools: true, CostHint: 2},
+	{Name: "mixtral-8x7b-32768", Provider: "groq", ContextWindow: 32768, Tools: true, CostHint: 1},
+	// Anthropic
+	{Name: "claude-sonnet-4-20250514", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 5},
+	{Name: "claude-3-5-sonnet-20241022", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 5},
+	{Name: "claude-3-5-haiku-20241022", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 2},
+	{Name: "claude-3-opus-20240229", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 10},
+	{Name: "claude-opus-4-20250514", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 10, Legacy: true},
+	{Name: "claude-3-5-sonnet-20240620", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 5, Legacy: true},
+	{Name: "claude-3-sonnet-20240229", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 5, Legacy: true},
+	{Name: "claude-3-haiku-20240307", Provider: "anthropic", ContextWindow: 200000, Vision: true, Tools: true, CostHint: 1, Legacy: true},
+	// OpenAI
+	{Name: "gpt-4o", Provider: "openai", ContextWindow: 128000, Vision: true, Tools: true, CostHint: 5},
+	{Name: "gpt-4o-mini", Provider: "openai", ContextWindow: 128000, Vision: true, Tools: true, CostHint: 1},
+	{Name: "gpt-4-turbo", Provider: "openai", ContextWindow: 128000, Vision: true, Tools: true, CostHint: 8},
+	{Name: "gpt-4", Provider: "openai", ContextWindow: 8192, Tools: true, CostHint: 10, Legacy: true},
+	{Name: "gpt-3.5-turbo", Provider: "openai", ContextWindow: 16385, Tools: true, CostHint: 1, Legacy: true},
+	// Moonshot
+	{Name: "moonshot-v1-32k", Provider: "moonshot", ContextWindow: 32768, Tools: true, CostHint: 2},
+	{Name: "moonshot-v1-8k", Provider: "moonshot", ContextWindow: 8192, Tools: true, CostHint: 1},
+	{Name: "moonshot-v1-128k", Provider: "moonshot", ContextWindow: 131072, Tools: true, CostHint: 4},
+	// Gemini
+	{Name: "gemini-2.0-flash", Provider: "gemini", ContextWindow: 1048576, Vision: true, Tools: true, CostHint: 1},
+	{Name: "gemini-1.5-pro", Provider: "gemini", ContextWindow: 2097152, Vision: true, Tools: true, CostHint: 4},
+	{Name: "gemini-1.5-flash", Provider: "gemini", ContextWindow: 1048576, Vision: true, Tools: true, CostHint: 1},
+	{Name: "gemini-2.0-flash-lite", Provider: "gemini", ContextWindow: 1048576, Vision: true, Tools: true, CostHint: 1, Legacy: true},
+}
+
+// AvailableModels returns the catalog's models whose provider has an API
+// key configured
+func (c *Client) AvailableModels() []ModelInfo {
+	var models []ModelInfo
+	for _, m := range Catalog.Models() {
+		if c.HasKeyFor(m.Name) {
+			models = append(models, m)
+		}
+	}
+	return models
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestModelCatalogAdd(t *testing.T) {
+	c := NewModelCatalog()
+	bad := []ModelInfo{
+		{Provider: "groq"},
+		{Name: "m", Provider: "nowhere"},
+		{Name: "m", Provider: "groq", ContextWindow: -1},
+	}
+	for _, m := range bad {
+		if err := c.Add(m); err == nil {
+			t.Errorf("Expected Add(%+v) to fail", m)
+		}
+	}
+
+	if err := c.Add(ModelInfo{Name: "m", Provider: "groq", ContextWindow: 1000}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Add(ModelInfo{Name: "m", Provider: "openai", ContextWindow: 2000}); err != nil {
+		t.Fatal(err)
+	}
+	m, ok := c.Lookup("m")
+	if !ok || m.Provider != "openai" || m.ContextWindow != 2000 {
+		t.Errorf("Expected the second entry to replace the first, got %+v", m)
+	}
+	if n := len(c.Models()); n != 1 {
+		t.Errorf("Expected 1 model, got %d", n)
+	}
+}
+
+func TestCatalogRoutesAddedModels(t *testing.T) {
+	defer func(saved *ModelCatalog) { Catalog = saved }(Catalog)
+	Catalog = NewModelCatalog(builtinModels...)
+
+	if err := Catalog.Add(ModelInfo{Name: "my-claude", Provider: "anthropic", ContextWindow: 50000, Vision: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got := ProviderFor("my-claude"); got != "anthropic" {
+		t.Errorf("ProviderFor = %q, want anthropic", got)
+	}
+	if !isClaudeModel("my-claude") {
+		t.Error("Expected an added anthropic model to use the Claude API")
+	}
+	if got := ContextWindow("my-claude"); got != 50000 {
+		t.Errorf("ContextWindow = %d, want 50000", got)
+	}
+	if !SupportsVision("my-claude") {
+		t.Error("Expected the added model to support vision")
+	}
+	if SupportsTools("my-claude") {
+		t.Error("Expected Tools: false to be kept")
+	}
+	if !SupportsTools("some-new-model") {
+		t.Error("Expected unknown models to support tools")
+	}
+}
+
+func TestAvailableModels(t *testing.T) {
+	c := New("", WithProviderKey("gemini", "key"))
+	models := c.AvailableModels()
+	if len(models) == 0 {
+		t.Fatal("Expected the Gemini models")
+	}
+	for _, m := range models {
+		if m.Provider != "gemini" {
+			t.Errorf("Expected only Gemini models, got %s (%s)", m.Name, m.Provider)
+		}
+		if m.Legacy {
+			t.Errorf("Expected legacy model %s to be left out", m.Name)
+		}
+	}
+	if !slices.Contains(Catalog.ProviderModels("gemini"), models[0].Name) {
+		t.Errorf("Expected %s in the Gemini models", models[0].Name)
+	}
+}
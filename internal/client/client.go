@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"maps"
+	"slices"
+	"sync"
 	"time"
 )
 
@@ -20,21 +23,40 @@ const (
 	MoonshotBaseURL  = "https://api.moonshot.cn/v1"
 	OpenAIBaseURL    = "https://api.openai.com/v1"
 	AnthropicBaseURL = "https://api.anthropic.com/v1"
+	GeminiBaseURL    = "https://generativelanguage.googleapis.com/v1beta"
 )
 
 // Client is the API client supporting multiple providers
 type Client struct {
+	// mu guards model, apiKey and providerKeys, which SetModel and
+	// SetProviderKey change while requests run. Requests work on a
+	// snapshot instead of taking it.
+	mu *sync.RWMutex
+
 	baseURL      string
 	apiKey       string
 	model        string
 	httpClient   *http.Client
 	providerKeys map[string]string // provider -> apiKey
+	maxAttempts  int               // see WithRetry
+	retryDelay   time.Duration
+	maxTokens    int      // see WithMaxTokens; 0 leaves it to the provider
+	temperature  *float64 // see WithTemperature
+	cachePrompts bool     // see WithPromptCaching
+
+	fallbackModels []string          // see WithFallbackModels
+	endpoints      map[string]string // provider -> base URL, see WithEndpoint
+	debugDir       string            // see WithDebugLog
+	replayDir      string            // see WithReplay
+	cacheDir       string            // see WithCache
+	cacheTTL       time.Duration
 }
 
 // Option is a function that configures the client
 type Option func(*Client)
 
-// WithBaseURL sets a custom base URL
+// WithBaseURL sets a custom base URL for Groq models, e.g. a proxy or a
+// test server. Other providers keep their own endpoints.
 func WithBaseURL(url string) Option {
 	return func(c *Client) {
 		c.baseURL = url
@@ -68,6 +90,7 @@ func WithProviderKey(provider, apiKey string) Option {
 // New creates a new API client
 func New(apiKey string, opts ...Option) *Client {
 	c := &Client{
+		mu:      new(sync.RWMutex),
 		baseURL: DefaultBaseURL,
 		apiKey:  apiKey,
 		model:   DefaultModel,
@@ -75,6 +98,8 @@ func New(apiKey string, opts ...Option) *Client {
 			Timeout: DefaultTimeout,
 		},
 		providerKeys: make(map[string]string),
+		maxAttempts:  DefaultMaxAttempts,
+		retryDelay:   DefaultRetryDelay,
 	}
 	// Default Groq key
 	c.providerKeys["groq"] = apiKey
@@ -82,77 +107,106 @@ func New(apiKey string, opts ...Option) *Client {
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.wrapTransport()
 	return c
 }
 
 // getProviderConfig returns baseURL and apiKey for the current model
 func (c *Client) getProviderConfig() (baseURL, apiKey string) {
-	switch {
-	case isClaudeModel(c.model):
-		return AnthropicBaseURL, c.providerKeys["anthropic"]
-	case isKimiModel(c.model):
-		return MoonshotBaseURL, c.providerKeys["moonshot"]
-	case isOpenAIModel(c.model):
-		return OpenAIBaseURL, c.providerKeys["openai"]
+	provider := ProviderFor(c.model)
+	if baseURL, ok := c.endpointFor(c.model); ok {
+		return baseURL, c.providerKeys[provider]
+	}
+	switch provider {
+	case "anthropic":
+		return AnthropicBaseURL, c.providerKeys[provider]
+	case "moonshot":
+		return MoonshotBaseURL, c.providerKeys[provider]
+	case "openai":
+		return OpenAIBaseURL, c.providerKeys[provider]
+	case "gemini":
+		return GeminiBaseURL, c.providerKeys[provider]
 	default:
-		return GroqBaseURL, c.providerKeys["groq"]
+		return c.baseURL, c.providerKeys["groq"]
 	}
 }
 
 func isClaudeModel(model string) bool {
-	switch model {
-	case "claude-3-opus-20240229", "claude-3-sonnet-20240229", "claude-3-haiku-20240307",
-		"claude-3-5-sonnet-20240620", "claude-3-5-sonnet-20241022", "claude-3-5-haiku-20241022",
-		"claude-sonnet-4-20250514", "claude-opus-4-20250514":
-		return true
-	}
-	return false
+	return ProviderFor(model) == "anthropic"
 }
 
-func isKimiModel(model string) bool {
-	// Kimi K2 is available on Groq, so return false
-	// Only use Moonshot API for moonshot-specific models
-	switch model {
-	case "moonshot-v1-8k", "moonshot-v1-32k", "moonshot-v1-128k":
-		return true
-	}
-	return false
-}
-
-func isOpenAIModel(model string) bool {
-	switch model {
-	case "gpt-4", "gpt-4-turbo", "gpt-4o", "gpt-4o-mini", "gpt-3.5-turbo":
-		return true
-	}
-	return false
+func isGeminiModel(model string) bool {
+	return ProviderFor(model) == "gemini"
 }
 
 // Model returns the current model
 func (c *Client) Model() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.model
 }
 
-// SetModel changes the model
+// SetModel changes the model. Requests already sent keep theirs.
 func (c *Client) SetModel(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.model = model
 }
 
-// ChatCompletion sends a non-streaming chat completion request
-func (c *Client) ChatCompletion(ctx context.Context, messages []Message, tools []Tool) (*ChatCompletionResponse, error) {
+// WithModelOverride returns a copy of the client that uses model, leaving
+// the original untouched so concurrent sessions can each pick their own
+func (c *Client) WithModelOverride(model string) *Client {
+	clone := c.snapshot()
+	clone.model = model
+	return clone
+}
+
+// snapshot returns a copy of the client with its own lock and keys, so a
+// request sees one model and set of keys however the original changes
+func (c *Client) snapshot() *Client {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	clone := *c
+	clone.mu = new(sync.RWMutex)
+	clone.providerKeys = maps.Clone(c.providerKeys)
+	return &clone
+}
+
+// ChatCompletion sends a non-streaming chat completion request.
+// opts override the client's sampling defaults for this call only.
+func (c *Client) ChatCompletion(ctx context.Context, messages []Message, tools []Tool, opts ...RequestOptions) (*ChatCompletionResponse, error) {
+	c = c.snapshot()
+	o := c.requestOptions(opts)
+	ctx, lookup := c.withCacheLookup(ctx, o)
+	resp, err := c.chatCompletion(ctx, messages, tools, o)
+	if err != nil {
+		return nil, err
+	}
+	resp.Cached = lookup.cached()
+	return resp, nil
+}
+
+// chatCompletion sends a non-streaming request to the current model
+func (c *Client) chatCompletion(ctx context.Context, messages []Message, tools []Tool, o RequestOptions) (*ChatCompletionResponse, error) {
 	if isClaudeModel(c.model) {
-		return c.claudeChatCompletion(ctx, messages, tools)
+		return c.claudeChatCompletion(ctx, messages, tools, o)
+	}
+	if isGeminiModel(c.model) {
+		return c.geminiChatCompletion(ctx, messages, tools, o)
 	}
 
 	baseURL, apiKey := c.getProviderConfig()
-	if apiKey == "" {
+	if !c.HasKeyFor(c.model) {
 		return nil, fmt.Errorf("no API key configured for model %s", c.model)
 	}
 
 	req := ChatCompletionRequest{
 		Model:    c.model,
 		Messages: messages,
-		Tools:    tools,
-		Stream:   false,
+		Tools:       tools,
+		Stream:      false,
+		MaxTokens:   o.MaxTokens,
+		Temperature: o.Temperature,
 	}
 
 	if len(tools) > 0 {
@@ -170,11 +224,13 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, tools [
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, attempts, err := c.do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, withAttempts(fmt.Errorf("failed to send request: %w", err), attempts)
 	}
 	defer resp.Body.Close()
 
@@ -184,11 +240,7 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, tools [
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		var errResp ErrorResponse
-		if err := json.Unmarshal(respBody, &errResp); err == nil {
-			return nil, fmt.Errorf("API error: %s (%s)", errResp.Error.Message, errResp.Error.Type)
-		}
-		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, withAttempts(responseError(ProviderFor(c.model), resp, respBody), attempts)
 	}
 
 	var result ChatCompletionResponse
@@ -200,14 +252,14 @@ func (c *Client) ChatCompletion(ctx context.Context, messages []Message, tools [
 }
 
 // claudeChatCompletion handles Claude API requests
-func (c *Client) claudeChatCompletion(ctx context.Context, messages []Message, tools []Tool) (*ChatCompletionResponse, error) {
+func (c *Client) claudeChatCompletion(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*ChatCompletionResponse, error) {
 	apiKey := c.providerKeys["anthropic"]
 	if apiKey == "" {
 		return nil, fmt.Errorf("no API key configured for Claude (set ANTHROPIC_API_KEY)")
 	}
 
 	// Convert messages to Claude format
-	claudeReq := c.buildClaudeRequest(messages, tools, false)
+	claudeReq := c.buildClaudeRequest(messages, tools, false, opts)
 
 	body, err := json.Marshal(claudeReq)
 	if err != nil {
@@ -223,9 +275,9 @@ func (c *Client) claudeChatCompletion(ctx context.Context, messages []Message, t
 	httpReq.Header.Set("x-api-key", apiKey)
 	httpReq.Header.Set("anthropic-version", "2023-06-01")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, attempts, err := c.do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, withAttempts(fmt.Errorf("failed to send request: %w", err), attempts)
 	}
 	defer resp.Body.Close()
 
@@ -235,7 +287,7 @@ func (c *Client) claudeChatCompletion(ctx context.Context, messages []Message, t
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("Claude API error: status %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, withAttempts(responseError("anthropic", resp, respBody), attempts)
 	}
 
 	// Parse Claude response and convert to OpenAI format
@@ -246,10 +298,12 @@ func (c *Client) claudeChatCompletion(ctx context.Context, messages []Message, t
 type ClaudeRequest struct {
 	Model     string         `json:"model"`
 	MaxTokens int            `json:"max_tokens"`
-	System    string         `json:"system,omitempty"`
+	System    ClaudeSystem   `json:"system,omitempty"`
 	Messages  []ClaudeMsg    `json:"messages"`
 	Tools     []ClaudeTool   `json:"tools,omitempty"`
 	Stream    bool           `json:"stream,omitempty"`
+
+	Temperature *float64 `json:"temperature,omitempty"`
 }
 
 // ClaudeMsg represents a Claude message
@@ -267,6 +321,17 @@ type ClaudeBlock struct {
 	Input     any    `json:"input,omitempty"`
 	ToolUseID string `json:"tool_use_id,omitempty"`
 	Content   string `json:"content,omitempty"`
+
+	Source       *ClaudeImageSource  `json:"source,omitempty"`        // image blocks
+	CacheControl *ClaudeCacheControl `json:"cache_control,omitempty"` // see WithPromptCaching
+}
+
+// ClaudeImageSource is the image data of a Claude image block
+type ClaudeImageSource struct {
+	Type      string `json:"type"` // "base64" or "url"
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
 }
 
 // ClaudeTool represents a Claude tool
@@ -285,25 +350,58 @@ type ClaudeResponse struct {
 	Model        string        `json:"model"`
 	StopReason   string        `json:"stop_reason"`
 	StopSequence string        `json:"stop_sequence"`
-	Usage        struct {
-		InputTokens  int `json:"input_tokens"`
-		OutputTokens int `json:"output_tokens"`
-	} `json:"usage"`
+	Usage        ClaudeUsage   `json:"usage"`
 }
 
 // getMessageContent extracts string content from a Message
 func getMessageContent(msg Message) string {
-	if s, ok := msg.Content.(string); ok {
-		return s
+	return msg.Text()
+}
+
+// claudeContentBlocks converts message content to Claude text and image
+// blocks. Data URL images are sent inline, other URLs by reference.
+func claudeContentBlocks(msg Message) []ClaudeBlock {
+	parts, ok := msg.Content.([]ContentPart)
+	if !ok {
+		return []ClaudeBlock{{Type: "text", Text: getMessageContent(msg)}}
+	}
+
+	var blocks []ClaudeBlock
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			if p.Text != "" {
+				blocks = append(blocks, ClaudeBlock{Type: "text", Text: p.Text})
+			}
+		case "image_url":
+			if p.ImageURL == nil {
+				continue
+			}
+			source := &ClaudeImageSource{Type: "url", URL: p.ImageURL.URL}
+			if mediaType, data, ok := ParseDataURL(p.ImageURL.URL); ok {
+				source = &ClaudeImageSource{Type: "base64", MediaType: ImageMediaType(mediaType, data), Data: data}
+			}
+			blocks = append(blocks, ClaudeBlock{Type: "image", Source: source})
+		}
+	}
+	if len(blocks) == 0 {
+		blocks = append(blocks, ClaudeBlock{Type: "text", Text: ""})
 	}
-	return ""
+	return blocks
 }
 
-func (c *Client) buildClaudeRequest(messages []Message, tools []Tool, stream bool) ClaudeRequest {
+func (c *Client) buildClaudeRequest(messages []Message, tools []Tool, stream bool, opts RequestOptions) ClaudeRequest {
 	req := ClaudeRequest{
-		Model:     c.model,
-		MaxTokens: 4096,
-		Stream:    stream,
+		Model:       c.model,
+		MaxTokens:   DefaultClaudeMaxTokens,
+		Temperature: opts.Temperature,
+		Stream:      stream,
+	}
+	if opts.MaxTokens > 0 {
+		req.MaxTokens = opts.MaxTokens
+	}
+	if t := opts.Temperature; t != nil && *t > MaxClaudeTemperature {
+		req.Temperature = Float(MaxClaudeTemperature)
 	}
 
 	// Extract system message
@@ -312,13 +410,13 @@ func (c *Client) buildClaudeRequest(messages []Message, tools []Tool, stream boo
 		content := getMessageContent(msg)
 
 		if msg.Role == "system" {
-			req.System = content
+			req.System = ClaudeSystem{{Type: "text", Text: content}}
 			continue
 		}
 
 		// Handle tool results
 		if msg.Role == "tool" {
-			claudeMsgs = append(claudeMsgs, ClaudeMsg{
+			claudeMsgs = appendClaudeMsg(claudeMsgs, ClaudeMsg{
 				Role: "user",
 				Content: []ClaudeBlock{{
 					Type:      "tool_result",
@@ -348,12 +446,15 @@ func (c *Client) buildClaudeRequest(messages []Message, tools []Tool, stream boo
 		}
 
 		// Regular messages
-		claudeMsgs = append(claudeMsgs, ClaudeMsg{
+		claudeMsgs = appendClaudeMsg(claudeMsgs, ClaudeMsg{
 			Role:    msg.Role,
-			Content: []ClaudeBlock{{Type: "text", Text: content}},
+			Content: claudeContentBlocks(msg),
 		})
 	}
 	req.Messages = claudeMsgs
+	if c.cachePrompts {
+		markCacheBreakpoints(&req)
+	}
 
 	// Convert tools
 	for _, t := range tools {
@@ -367,6 +468,37 @@ func (c *Client) buildClaudeRequest(messages []Message, tools []Tool, stream boo
 	return req
 }
 
+// appendClaudeMsg adds msg to msgs. Claude requires roles to alternate,
+// so tool results, and a user message sent among or after them, are
+// merged into the user message before them. Tool results are kept ahead
+// of other blocks, as Claude expects them first.
+func appendClaudeMsg(msgs []ClaudeMsg, msg ClaudeMsg) []ClaudeMsg {
+	n := len(msgs)
+	if n == 0 || msgs[n-1].Role != "user" || msg.Role != "user" ||
+		!hasToolResults(msgs[n-1]) && !hasToolResults(msg) {
+		return append(msgs, msg)
+	}
+	blocks := append(msgs[n-1].Content, msg.Content...)
+	slices.SortStableFunc(blocks, func(a, b ClaudeBlock) int {
+		return toolResultFirst(a) - toolResultFirst(b)
+	})
+	msgs[n-1].Content = blocks
+	return msgs
+}
+
+// hasToolResults reports whether msg carries tool results
+func hasToolResults(msg ClaudeMsg) bool {
+	return slices.ContainsFunc(msg.Content, func(b ClaudeBlock) bool { return b.Type == "tool_result" })
+}
+
+// toolResultFirst orders tool_result blocks before the others
+func toolResultFirst(b ClaudeBlock) int {
+	if b.Type == "tool_result" {
+		return 0
+	}
+	return 1
+}
+
 func (c *Client) parseClaudeResponse(body []byte) (*ChatCompletionResponse, error) {
 	var claudeResp ClaudeResponse
 	if err := json.Unmarshal(body, &claudeResp); err != nil {
@@ -377,16 +509,12 @@ func (c *Client) parseClaudeResponse(body []byte) (*ChatCompletionResponse, erro
 	resp := &ChatCompletionResponse{
 		ID:    claudeResp.ID,
 		Model: claudeResp.Model,
-		Usage: Usage{
-			PromptTokens:     claudeResp.Usage.InputTokens,
-			CompletionTokens: claudeResp.Usage.OutputTokens,
-			TotalTokens:      claudeResp.Usage.InputTokens + claudeResp.Usage.OutputTokens,
-		},
+		Usage: claudeResp.Usage.toUsage(),
 	}
 
 	choice := Choice{
 		Index:        0,
-		FinishReason: claudeResp.StopReason,
+		FinishReason: claudeFinishReason(claudeResp.StopReason),
 	}
 
 	// Extract text and tool calls
@@ -428,22 +556,44 @@ func joinStrings(parts []string) string {
 	return result
 }
 
-// ChatCompletionStream sends a streaming chat completion request
-func (c *Client) ChatCompletionStream(ctx context.Context, messages []Message, tools []Tool) (*StreamReader, error) {
+// ChatCompletionStream sends a streaming chat completion request.
+// opts override the client's sampling defaults for this call only.
+// When the provider is down, the fallback models are tried in turn; see
+// WithFallbackModels and StreamReader.Model.
+func (c *Client) ChatCompletionStream(ctx context.Context, messages []Message, tools []Tool, opts ...RequestOptions) (*StreamReader, error) {
+	c = c.snapshot()
+	o := c.requestOptions(opts)
+	ctx, lookup := c.withCacheLookup(ctx, o)
+	stream, err := c.streamWithFallback(ctx, messages, tools, o)
+	if err != nil {
+		return nil, err
+	}
+	stream.cached = lookup.cached()
+	return stream, nil
+}
+
+// chatCompletionStream sends a streaming request to the current model
+func (c *Client) chatCompletionStream(ctx context.Context, messages []Message, tools []Tool, o RequestOptions) (*StreamReader, error) {
 	if isClaudeModel(c.model) {
-		return c.claudeChatCompletionStream(ctx, messages, tools)
+		return c.claudeChatCompletionStream(ctx, messages, tools, o)
+	}
+	if isGeminiModel(c.model) {
+		return c.geminiChatCompletionStream(ctx, messages, tools, o)
 	}
 
 	baseURL, apiKey := c.getProviderConfig()
-	if apiKey == "" {
+	if !c.HasKeyFor(c.model) {
 		return nil, fmt.Errorf("no API key configured for model %s", c.model)
 	}
 
 	req := ChatCompletionRequest{
-		Model:    c.model,
-		Messages: messages,
-		Tools:    tools,
-		Stream:   true,
+		Model:         c.model,
+		Messages:      messages,
+		Tools:         tools,
+		Stream:        true,
+		MaxTokens:     o.MaxTokens,
+		Temperature:   o.Temperature,
+		StreamOptions: &StreamOptions{IncludeUsage: true},
 	}
 
 	if len(tools) > 0 {
@@ -461,35 +611,35 @@ func (c *Client) ChatCompletionStream(ctx context.Context, messages []Message, t
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	if apiKey != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+	}
 	httpReq.Header.Set("Accept", "text/event-stream")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, attempts, err := c.do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, withAttempts(fmt.Errorf("failed to send request: %w", err), attempts)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		respBody, _ := io.ReadAll(resp.Body)
-		var errResp ErrorResponse
-		if err := json.Unmarshal(respBody, &errResp); err == nil {
-			return nil, fmt.Errorf("API error: %s (%s)", errResp.Error.Message, errResp.Error.Type)
-		}
-		return nil, fmt.Errorf("API error: status %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, withAttempts(responseError(ProviderFor(c.model), resp, respBody), attempts)
 	}
 
-	return NewStreamReader(resp.Body), nil
+	stream := NewStreamReader(resp.Body)
+	stream.provider = ProviderFor(c.model)
+	return stream, nil
 }
 
 // claudeChatCompletionStream handles Claude streaming API requests
-func (c *Client) claudeChatCompletionStream(ctx context.Context, messages []Message, tools []Tool) (*StreamReader, error) {
+func (c *Client) claudeChatCompletionStream(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*StreamReader, error) {
 	apiKey := c.providerKeys["anthropic"]
 	if apiKey == "" {
 		return nil, fmt.Errorf("no API key configured for Claude (set ANTHROPIC_API_KEY)")
 	}
 
-	claudeReq := c.buildClaudeRequest(messages, tools, true)
+	claudeReq := c.buildClaudeRequest(messages, tools, true, opts)
 
 	body, err := json.Marshal(claudeReq)
 	if err != nil {
@@ -506,15 +656,15 @@ func (c *Client) claudeChatCompletionStream(ctx context.Context, messages []Mess
 	httpReq.Header.Set("anthropic-version", "2023-06-01")
 	httpReq.Header.Set("Accept", "text/event-stream")
 
-	resp, err := c.httpClient.Do(httpReq)
+	resp, attempts, err := c.do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, withAttempts(fmt.Errorf("failed to send request: %w", err), attempts)
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		respBody, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("Claude API error: status %d, body: %s", resp.StatusCode, string(respBody))
+		return nil, withAttempts(responseError("anthropic", resp, respBody), attempts)
 	}
 
 	return NewClaudeStreamReader(resp.Body), nil
@@ -0,0 +1,186 @@
+package client
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const (
+	pngHeader  = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	jpegHeader = "\xff\xd8\xff\xe0\x00\x10JFIF\x00"
+)
+
+func dataURL(mediaType, raw string) string {
+	return "data:" + mediaType + ";base64," + base64.StdEncoding.EncodeToString([]byte(raw))
+}
+
+func TestBuildClaudeRequestImages(t *testing.T) {
+	c := New("", WithModel("claude-sonnet-4-20250514"))
+	msg := NewVisionMessage("user", "compare these",
+		dataURL("image/png", pngHeader),
+		dataURL("image/png", jpegHeader), // mislabeled JPEG
+		"https://example.com/cat.jpg")
+
+	req := c.buildClaudeRequest([]Message{{Role: "system", Content: "sys"}, msg}, nil, false, RequestOptions{})
+	if req.System.Text() != "sys" || len(req.Messages) != 1 {
+		t.Fatalf("Unexpected request: %+v", req)
+	}
+	blocks := req.Messages[0].Content
+	if len(blocks) != 4 {
+		t.Fatalf("Expected a text and three image blocks, got %+v", blocks)
+	}
+	if blocks[0].Type != "text" || blocks[0].Text != "compare these" {
+		t.Errorf("Expected the text first, got %+v", blocks[0])
+	}
+
+	want := []ClaudeImageSource{
+		{Type: "base64", MediaType: "image/png", Data: base64.StdEncoding.EncodeToString([]byte(pngHeader))},
+		{Type: "base64", MediaType: "image/jpeg", Data: base64.StdEncoding.EncodeToString([]byte(jpegHeader))},
+		{Type: "url", URL: "https://example.com/cat.jpg"},
+	}
+	for i, w := range want {
+		b := blocks[i+1]
+		if b.Type != "image" || b.Source == nil || *b.Source != w {
+			t.Errorf("Block %d: expected image %+v, got %+v", i+1, w, b)
+		}
+	}
+}
+
+func TestBuildClaudeRequestText(t *testing.T) {
+	c := New("", WithModel("claude-sonnet-4-20250514"))
+	req := c.buildClaudeRequest([]Message{NewTextMessage("user", "hi")}, nil, false, RequestOptions{})
+	blocks := req.Messages[0].Content
+	if len(blocks) != 1 || blocks[0].Type != "text" || blocks[0].Text != "hi" || blocks[0].Source != nil {
+		t.Errorf("Expected one text block, got %+v", blocks)
+	}
+}
+
+func toolCallMessage(ids ...string) Message {
+	msg := Message{Role: "assistant", Content: "checking"}
+	for _, id := range ids {
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{ID: id, Type: "function", Function: FunctionCall{Name: "Read", Arguments: `{"file_path":"a.go"}`}})
+	}
+	return msg
+}
+
+func toolResult(id string) Message {
+	return Message{Role: "tool", ToolCallID: id, Content: "result of " + id}
+}
+
+func TestBuildClaudeRequestMergesToolResults(t *testing.T) {
+	c := New("", WithModel("claude-sonnet-4-20250514"))
+	req := c.buildClaudeRequest([]Message{
+		{Role: "system", Content: "sys"},
+		NewTextMessage("user", "look at the code"),
+		toolCallMessage("call_1", "call_2"),
+		toolResult("call_1"),
+		toolResult("call_2"),
+		toolCallMessage("call_3", "call_4", "call_5"),
+		toolResult("call_3"),
+		NewTextMessage("user", "actually, skip the tests"),
+		toolResult("call_4"),
+		toolResult("call_5"),
+		{Role: "assistant", Content: "done"},
+	}, nil, false, RequestOptions{})
+
+	var roles []string
+	for i, msg := range req.Messages {
+		roles = append(roles, msg.Role)
+		if i > 0 && req.Messages[i-1].Role == msg.Role {
+			t.Errorf("Message %d repeats role %s", i, msg.Role)
+		}
+	}
+	if got := strings.Join(roles, ","); got != "user,assistant,user,assistant,user,assistant" {
+		t.Fatalf("Unexpected roles %s", got)
+	}
+
+	// Every tool_use is answered in the next message
+	for i, msg := range req.Messages {
+		if msg.Role != "assistant" {
+			continue
+		}
+		for _, b := range msg.Content {
+			if b.Type != "tool_use" {
+				continue
+			}
+			if i+1 >= len(req.Messages) || !hasToolResult(req.Messages[i+1], b.ID) {
+				t.Errorf("tool_use %s has no tool_result after it", b.ID)
+			}
+		}
+	}
+
+	// The correction is kept, after the tool results
+	merged := req.Messages[4].Content
+	var types []string
+	for _, b := range merged {
+		types = append(types, b.Type)
+	}
+	if got := strings.Join(types, ","); got != "tool_result,tool_result,tool_result,text" || merged[3].Text != "actually, skip the tests" {
+		t.Errorf("Unexpected blocks %+v", merged)
+	}
+	if merged[0].ToolUseID != "call_3" || merged[2].ToolUseID != "call_5" {
+		t.Errorf("Expected tool results in call order, got %+v", merged)
+	}
+}
+
+func hasToolResult(msg ClaudeMsg, id string) bool {
+	for _, b := range msg.Content {
+		if b.Type == "tool_result" && b.ToolUseID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// TestSetModelDuringRequests switches models and keys while requests run;
+// run with -race. Each request must use one model from start to end.
+func TestSetModelDuringRequests(t *testing.T) {
+	c := newFailoverClient(t, &providerStub{}, WithProviderKey("anthropic", "claude-key"))
+	models := []string{"llama-3.3-70b-versatile", "claude-3-5-haiku-20241022"}
+
+	var wg sync.WaitGroup
+	for i := range 4 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := range 50 {
+				c.SetModel(models[(i+j)%2])
+				c.SetProviderKey("anthropic", fmt.Sprintf("claude-key-%d", j))
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for range 10 {
+				if baseURL, _ := c.snapshot().getProviderConfig(); baseURL == "" {
+					t.Error("Expected a base URL")
+				}
+				stream, err := c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if got := readAll(t, stream); got != stream.Model() {
+					t.Errorf("Request for %s answered by %s", stream.Model(), got)
+				}
+				c.Model()
+				c.AvailableModels()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestWithModelOverrideKeepsOriginal(t *testing.T) {
+	c := New("groq-key")
+	clone := c.WithModelOverride("claude-3-5-haiku-20241022")
+	clone.SetProviderKey("anthropic", "claude-key")
+	clone.SetModel("llama-3.1-8b-instant")
+
+	if c.Model() != DefaultModel || c.HasKeyFor("claude-3-5-haiku-20241022") {
+		t.Errorf("Expected the original untouched, got model %s", c.Model())
+	}
+}
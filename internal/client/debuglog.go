@@ -0,0 +1,396 @@
+package client
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DebugHTTPEnv turns on the wire log for every client: "1" or "true" logs
+// to DefaultDebugDir, any other value except "0" and "false" is taken as
+// the directory
+const DebugHTTPEnv = "GROQ_GO_DEBUG_HTTP"
+
+// DebugLogMaxBytes caps the size of a debug log directory; the oldest
+// records are removed past it
+var DebugLogMaxBytes int64 = 100 << 20
+
+// redactedHeaders carry API keys
+var redactedHeaders = []string{"Authorization", "X-Api-Key", "X-Goog-Api-Key"}
+
+// DefaultDebugDir returns where GROQ_GO_DEBUG_HTTP=1 writes
+func DefaultDebugDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".config", "groq-go", "http-debug")
+}
+
+// debugDirFromEnv returns the directory set with DebugHTTPEnv, if any
+func debugDirFromEnv() string {
+	switch v := os.Getenv(DebugHTTPEnv); strings.ToLower(v) {
+	case "", "0", "false":
+		return ""
+	case "1", "true":
+		return DefaultDebugDir()
+	default:
+		return v
+	}
+}
+
+// WithDebugLog writes every request the client sends and the response it
+// gets to a JSON file in dir, with API keys redacted. Streamed responses
+// are kept whole and also reassembled into the message they carried. The
+// files can be served back with WithReplay.
+func WithDebugLog(dir string) Option {
+	return func(c *Client) {
+		c.debugDir = dir
+	}
+}
+
+// WithReplay answers requests from the records WithDebugLog wrote to dir
+// instead of the network. A request matches a record when its method,
+// path and body are the same; requests without one fail.
+func WithReplay(dir string) Option {
+	return func(c *Client) {
+		c.replayDir = dir
+	}
+}
+
+// debugRecord is one request and its response, as written to the debug
+// directory
+type debugRecord struct {
+	Key             string            `json:"key"` // See requestKey
+	Time            time.Time         `json:"time"`
+	DurationMs      int64             `json:"duration_ms"`
+	Method          string            `json:"method"`
+	URL             string            `json:"url"`
+	RequestHeaders  map[string]string `json:"request_headers"`
+	Request         json.RawMessage   `json:"request,omitempty"`
+	Status          int               `json:"status,omitempty"`
+	ResponseHeaders map[string]string `json:"response_headers,omitempty"`
+	Response        json.RawMessage   `json:"response,omitempty"`      // A JSON body
+	ResponseText    string            `json:"response_text,omitempty"` // Any other body, such as an event stream
+	Message         *Message          `json:"message,omitempty"`       // Reassembled from an event stream
+	Error           string            `json:"error,omitempty"`
+}
+
+// requestKey identifies a request for replay: a hash of its method, path
+// and body. The query is left out as it may hold a key.
+func requestKey(method, path string, body []byte) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s %s\n", method, path)
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// readRequestBody returns the body of req, leaving req readable
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		return io.ReadAll(body)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	req.Body = io.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// debugTransport records each exchange once the response body is closed
+type debugTransport struct {
+	base    http.RoundTripper
+	dir     string
+	secrets []string // API keys scrubbed from anything written
+
+	mu sync.Mutex // Serializes writes and pruning
+}
+
+func newDebugTransport(base http.RoundTripper, dir string, secrets []string) *debugTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	var keep []string
+	for _, s := range secrets {
+		if s != "" {
+			keep = append(keep, s)
+		}
+	}
+	return &debugTransport{base: base, dir: dir, secrets: keep}
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	rec := &debugRecord{
+		Key:            requestKey(req.Method, req.URL.Path, body),
+		Time:           time.Now(),
+		Method:         req.Method,
+		URL:            t.redact(req.URL.String()),
+		RequestHeaders: t.headers(req.Header),
+		Request:        t.json(body),
+	}
+	if rec.Request == nil && len(body) > 0 {
+		rec.Request, _ = json.Marshal(t.redact(string(body)))
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		rec.DurationMs = time.Since(rec.Time).Milliseconds()
+		rec.Error = t.redact(err.Error())
+		t.write(rec)
+		return nil, err
+	}
+	rec.Status = resp.StatusCode
+	rec.ResponseHeaders = t.headers(resp.Header)
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(data []byte, _ bool) {
+		rec.DurationMs = time.Since(rec.Time).Milliseconds()
+		t.fillResponse(rec, req.URL.Path, resp.Header.Get("Content-Type"), data)
+		t.write(rec)
+	}}
+	return resp, nil
+}
+
+// fillResponse puts the response body in rec, reassembling event streams
+func (t *debugTransport) fillResponse(rec *debugRecord, path, contentType string, data []byte) {
+	if !strings.HasPrefix(contentType, "text/event-stream") {
+		if rec.Response = t.json(data); rec.Response == nil {
+			rec.ResponseText = t.redact(string(data))
+		}
+		return
+	}
+
+	rec.ResponseText = t.redact(string(data))
+	if msg, _, err := collectStream(path, data); err == nil {
+		rec.Message = msg
+	}
+}
+
+// collectStream reassembles the event stream data a request to path got
+func collectStream(path string, data []byte) (*Message, string, error) {
+	var stream *StreamReader
+	switch {
+	case strings.HasSuffix(path, "/messages"):
+		stream = NewClaudeStreamReader(io.NopCloser(bytes.NewReader(data)))
+	case strings.Contains(path, ":streamGenerateContent"):
+		stream = NewGeminiStreamReader(io.NopCloser(bytes.NewReader(data)))
+	default:
+		stream = NewStreamReader(io.NopCloser(bytes.NewReader(data)))
+	}
+	return stream.CollectResponse()
+}
+
+// json returns data as raw JSON with secrets scrubbed, or nil if it is
+// not JSON
+func (t *debugTransport) json(data []byte) json.RawMessage {
+	if len(data) == 0 || !json.Valid(data) {
+		return nil
+	}
+	return json.RawMessage(t.redact(string(data)))
+}
+
+func (t *debugTransport) headers(h http.Header) map[string]string {
+	out := make(map[string]string, len(h))
+	for name, values := range h {
+		value := strings.Join(values, ", ")
+		if slices.Contains(redactedHeaders, http.CanonicalHeaderKey(name)) {
+			value = "REDACTED"
+		}
+		out[name] = t.redact(value)
+	}
+	return out
+}
+
+func (t *debugTransport) redact(s string) string {
+	for _, secret := range t.secrets {
+		s = strings.ReplaceAll(s, secret, "REDACTED")
+	}
+	return s
+}
+
+// write saves rec and prunes the directory. Failures only lose the record.
+func (t *debugTransport) write(rec *debugRecord) {
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return
+	}
+	name := fmt.Sprintf("%s-%s.json", rec.Time.UTC().Format("20060102-150405.000000"), rec.Key)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(t.dir, name), data, 0600); err != nil {
+		return
+	}
+	pruneDebugDir(t.dir, DebugLogMaxBytes)
+}
+
+// pruneDebugDir removes the oldest records until dir holds at most
+// maxBytes. Names start with the time, so they sort oldest first.
+func pruneDebugDir(dir string, maxBytes int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	var total int64
+	sizes := make([]int64, len(entries))
+	for i, e := range entries {
+		if info, err := e.Info(); err == nil && !e.IsDir() {
+			sizes[i] = info.Size()
+			total += sizes[i]
+		}
+	}
+	for i, e := range entries {
+		if total <= maxBytes {
+			return
+		}
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		if os.Remove(filepath.Join(dir, e.Name())) == nil {
+			total -= sizes[i]
+		}
+	}
+}
+
+// recordingBody keeps what is read from a response body and hands it to
+// done when the body is closed, with whether it was read to the end
+type recordingBody struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	eof  bool
+	once sync.Once
+	done func(data []byte, eof bool)
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(func() { b.done(b.buf.Bytes(), b.eof) })
+	return err
+}
+
+// replayTransport answers requests from a debug directory, see WithReplay
+type replayTransport struct {
+	records map[string]*debugRecord // By key; the latest record wins
+	err     error                   // Loading the directory failed
+}
+
+func newReplayTransport(dir string) (*replayTransport, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	t := &replayTransport{records: make(map[string]*debugRecord)}
+	for _, path := range paths { // Sorted, so oldest first
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var rec debugRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		if rec.Error == "" {
+			t.records[rec.Key] = &rec
+		}
+	}
+	return t, nil
+}
+
+func (t *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.err != nil {
+		return nil, fmt.Errorf("replay: %w", t.err)
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := requestKey(req.Method, req.URL.Path, body)
+	rec, ok := t.records[key]
+	if !ok {
+		return nil, fmt.Errorf("replay: no recorded response for %s %s (key %s)", req.Method, req.URL.Path, key)
+	}
+
+	header := make(http.Header)
+	for name, value := range rec.ResponseHeaders {
+		header.Set(name, value)
+	}
+	data := []byte(rec.ResponseText)
+	if rec.Response != nil {
+		data = rec.Response
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", rec.Status, http.StatusText(rec.Status)),
+		StatusCode:    rec.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: int64(len(data)),
+		Request:       req,
+	}, nil
+}
+
+// wrapTransport applies WithDebugLog, DebugHTTPEnv, WithReplay and
+// WithCache. The HTTP client is copied so one passed to WithHTTPClient is
+// left alone.
+func (c *Client) wrapTransport() {
+	if c.debugDir == "" {
+		c.debugDir = debugDirFromEnv()
+	}
+	if c.debugDir == "" && c.replayDir == "" && c.cacheDir == "" {
+		return
+	}
+
+	hc := *c.httpClient
+	if c.replayDir != "" {
+		replay, err := newReplayTransport(c.replayDir)
+		if err != nil {
+			replay = &replayTransport{err: err}
+		}
+		hc.Transport = replay
+	}
+	if c.debugDir != "" {
+		secrets := []string{c.apiKey}
+		for _, key := range c.providerKeys {
+			secrets = append(secrets, key)
+		}
+		hc.Transport = newDebugTransport(hc.Transport, c.debugDir, secrets)
+	}
+	if c.cacheDir != "" {
+		// Outermost, so cache hits are not logged as provider calls
+		hc.Transport = newCacheTransport(hc.Transport, c.cacheDir, c.cacheTTL)
+	}
+	c.httpClient = &hc
+}
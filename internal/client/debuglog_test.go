@@ -0,0 +1,182 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// noNetwork fails every request, standing in for a disabled network
+type noNetwork struct{}
+
+func (noNetwork) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("network disabled")
+}
+
+func readRecords(t *testing.T, dir string) []debugRecord {
+	t.Helper()
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	var recs []debugRecord
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rec debugRecord
+		if err := json.Unmarshal(data, &rec); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+func TestDebugLogRecordAndReplay(t *testing.T) {
+	stub := &providerStub{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/messages") {
+			fmt.Fprint(w, `{"id":"msg_1","content":[{"type":"text","text":"Hello from Claude"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":4}}`)
+			return
+		}
+		stub.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+	target, _ := url.Parse(srv.URL)
+	dir := t.TempDir()
+	messages := []Message{NewTextMessage("user", "hi")}
+
+	c := New("groq-secret",
+		WithBaseURL(srv.URL),
+		WithHTTPClient(&http.Client{Transport: redirectTransport{target}}),
+		WithProviderKey("anthropic", "claude-secret"),
+		WithDebugLog(dir),
+	)
+	stream, err := c.ChatCompletionStream(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, stream); got != DefaultModel {
+		t.Fatalf("Expected the stub's reply, got %q", got)
+	}
+	claude := c.WithModelOverride("claude-sonnet-4-20250514")
+	resp, err := claude.ChatCompletion(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recs := readRecords(t, dir)
+	if len(recs) != 2 {
+		t.Fatalf("Expected two records, got %d", len(recs))
+	}
+	for _, path := range mustGlob(t, dir) {
+		data, _ := os.ReadFile(path)
+		if strings.Contains(string(data), "secret") {
+			t.Errorf("Expected keys redacted in %s:\n%s", path, data)
+		}
+	}
+	groq := recs[0]
+	if groq.Status != 200 || groq.RequestHeaders["Authorization"] != "REDACTED" || !strings.Contains(string(groq.Request), `"stream": true`) {
+		t.Errorf("Unexpected request record %+v", groq)
+	}
+	if groq.Message == nil || groq.Message.Content != DefaultModel || !strings.Contains(groq.ResponseText, "data: [DONE]") {
+		t.Errorf("Expected the stream kept and reassembled, got %+v", groq)
+	}
+	if recs[1].RequestHeaders["X-Api-Key"] != "REDACTED" || !strings.Contains(string(recs[1].Response), "Hello from Claude") {
+		t.Errorf("Unexpected Claude record %+v", recs[1])
+	}
+
+	// Replay the same requests with the network disabled
+	replay := New("groq-secret",
+		WithBaseURL(srv.URL),
+		WithHTTPClient(&http.Client{Transport: noNetwork{}}),
+		WithProviderKey("anthropic", "claude-secret"),
+		WithRetry(1, time.Millisecond),
+		WithReplay(dir),
+	)
+	stream, err = replay.ChatCompletionStream(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, stream); got != DefaultModel {
+		t.Errorf("Expected the recorded stream, got %q", got)
+	}
+	replayed, err := replay.WithModelOverride("claude-sonnet-4-20250514").ChatCompletion(context.Background(), messages, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.Choices[0].Message.Content != resp.Choices[0].Message.Content {
+		t.Errorf("Expected %v, got %v", resp.Choices[0].Message.Content, replayed.Choices[0].Message.Content)
+	}
+	if _, err := replay.ChatCompletion(context.Background(), []Message{NewTextMessage("user", "something else")}, nil); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("Expected an unrecorded request to fail, got %v", err)
+	}
+	if len(stub.calls) != 1 {
+		t.Errorf("Expected replay to stay off the network, got %d upstream calls", len(stub.calls))
+	}
+}
+
+func mustGlob(t *testing.T, dir string) []string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return paths
+}
+
+func TestPruneDebugDir(t *testing.T) {
+	dir := t.TempDir()
+	for i := range 5 {
+		name := fmt.Sprintf("20250101-00000%d.000000-key.json", i)
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, 100), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pruneDebugDir(dir, 250)
+
+	var left []string
+	for _, path := range mustGlob(t, dir) {
+		left = append(left, filepath.Base(path))
+	}
+	if len(left) != 2 || !strings.HasPrefix(left[0], "20250101-000003") {
+		t.Errorf("Expected the two newest records kept, got %v", left)
+	}
+}
+
+func TestDebugDirFromEnv(t *testing.T) {
+	for value, want := range map[string]string{
+		"":          "",
+		"0":         "",
+		"false":     "",
+		"1":         DefaultDebugDir(),
+		"TRUE":      DefaultDebugDir(),
+		"/tmp/wire": "/tmp/wire",
+	} {
+		t.Setenv(DebugHTTPEnv, value)
+		if got := debugDirFromEnv(); got != want {
+			t.Errorf("%s=%q: expected %q, got %q", DebugHTTPEnv, value, want, got)
+		}
+	}
+
+	dir := t.TempDir()
+	t.Setenv(DebugHTTPEnv, dir)
+	stub := &providerStub{}
+	c := newFailoverClient(t, stub)
+	stream, err := c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, stream)
+	if n := len(mustGlob(t, dir)); n != 1 {
+		t.Errorf("Expected the env to turn the log on, got %d records", n)
+	}
+}
@@ -0,0 +1,29 @@
+package client
+
+import "strings"
+
+// CustomProvider names the endpoint configured with CUSTOM_BASE_URL
+const CustomProvider = "custom"
+
+// WithEndpoint routes the models of provider to an OpenAI-compatible API at
+// baseURL, such as Ollama (http://localhost:11434/v1), vLLM or LM Studio.
+// The provider must be registered with Catalog.AddProvider and its models
+// added to the catalog. apiKey may be empty for servers without auth.
+func WithEndpoint(provider, baseURL, apiKey string) Option {
+	return func(c *Client) {
+		if c.endpoints == nil {
+			c.endpoints = make(map[string]string)
+		}
+		c.endpoints[provider] = strings.TrimSuffix(baseURL, "/")
+		if c.providerKeys == nil {
+			c.providerKeys = make(map[string]string)
+		}
+		c.providerKeys[provider] = apiKey
+	}
+}
+
+// endpointFor returns the base URL of the custom endpoint serving model
+func (c *Client) endpointFor(model string) (string, bool) {
+	baseURL, ok := c.endpoints[ProviderFor(model)]
+	return baseURL, ok
+}
@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// openAIStub is an OpenAI-compatible server like Ollama's. It asks for a
+// Read tool call, then answers with the tool result it was sent.
+type openAIStub struct {
+	mu       sync.Mutex
+	paths    []string
+	auth     []string
+	requests []ChatCompletionRequest
+}
+
+func (s *openAIStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req ChatCompletionRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	s.mu.Lock()
+	s.paths = append(s.paths, r.URL.Path)
+	s.auth = append(s.auth, r.Header.Get("Authorization"))
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	last := req.Messages[len(req.Messages)-1]
+	if last.Role == "tool" {
+		fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": \"file says %s\"}, \"finish_reason\": \"stop\"}]}\n\n", last.Content)
+		fmt.Fprint(w, "data: [DONE]\n\n")
+		return
+	}
+	fmt.Fprint(w, `data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "id": "call_1", "type": "function", "function": {"name": "Read", "arguments": "{\"path\":"}}]}}]}`+"\n\n")
+	fmt.Fprint(w, `data: {"choices": [{"delta": {"tool_calls": [{"index": 0, "function": {"arguments": "\"a.txt\"}"}}]}, "finish_reason": "tool_calls"}]}`+"\n\n")
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+// withTestCatalog gives the test its own copy of the built-in catalog
+func withTestCatalog(t *testing.T) {
+	t.Helper()
+	saved := Catalog
+	Catalog = NewModelCatalog(builtinModels...)
+	t.Cleanup(func() { Catalog = saved })
+}
+
+func TestEndpointToolCallRoundTrip(t *testing.T) {
+	withTestCatalog(t)
+	if err := Catalog.AddProvider("ollama"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Catalog.Add(ModelInfo{Name: "qwen2.5-coder", Provider: "ollama", Tools: true}); err != nil {
+		t.Fatal(err)
+	}
+	stub := &openAIStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	// No Groq key: the endpoint needs none
+	c := New("", WithModel("qwen2.5-coder"), WithEndpoint("ollama", srv.URL+"/v1/", ""))
+	if !c.HasKeyFor("qwen2.5-coder") {
+		t.Fatal("Expected a custom endpoint to need no key")
+	}
+	if c.HasKeyFor(DefaultModel) {
+		t.Error("Expected Groq models to still need a key")
+	}
+
+	tools := []Tool{{Type: "function", Function: FunctionSchema{Name: "Read", Parameters: map[string]any{"type": "object"}}}}
+	messages := []Message{NewTextMessage("user", "read a.txt")}
+	stream, err := c.ChatCompletionStream(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg, finish, err := stream.CollectResponse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finish != "tool_calls" || len(msg.ToolCalls) != 1 {
+		t.Fatalf("Expected one tool call, got %q %+v", finish, msg)
+	}
+	call := msg.ToolCalls[0]
+	if call.ID != "call_1" || call.Function.Name != "Read" || call.Function.Arguments != `{"path":"a.txt"}` {
+		t.Errorf("Unexpected tool call %+v", call)
+	}
+
+	messages = append(messages, *msg, Message{Role: "tool", ToolCallID: call.ID, Content: "hello"})
+	stream, err = c.ChatCompletionStream(context.Background(), messages, tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, stream); got != "file says hello" {
+		t.Errorf("Expected the tool result in the reply, got %q", got)
+	}
+
+	for i, path := range stub.paths {
+		if path != "/v1/chat/completions" {
+			t.Errorf("Request %d went to %s", i, path)
+		}
+		if stub.auth[i] != "" {
+			t.Errorf("Expected no Authorization header without a key, got %q", stub.auth[i])
+		}
+		if req := stub.requests[i]; req.Model != "qwen2.5-coder" || len(req.Tools) != 1 || !req.Stream {
+			t.Errorf("Unexpected request %+v", req)
+		}
+	}
+}
+
+func TestEndpointSendsAPIKey(t *testing.T) {
+	withTestCatalog(t)
+	if err := Catalog.AddProvider(CustomProvider); err != nil {
+		t.Fatal(err)
+	}
+	if err := Catalog.Add(ModelInfo{Name: "local-model", Provider: CustomProvider, Tools: true}); err != nil {
+		t.Fatal(err)
+	}
+	stub := &openAIStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+
+	c := New("groq-key", WithModel("local-model"), WithEndpoint(CustomProvider, srv.URL, "vllm-key"))
+	stream, err := c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream.Close()
+	if len(stub.auth) != 1 || stub.auth[0] != "Bearer vllm-key" {
+		t.Errorf("Expected the endpoint's key, got %v", stub.auth)
+	}
+}
+
+func TestAddProvider(t *testing.T) {
+	c := NewModelCatalog()
+	for _, name := range []string{"", "Ollama", "groq", "with space"} {
+		if err := c.AddProvider(name); err == nil {
+			t.Errorf("Expected AddProvider(%q) to fail", name)
+		}
+	}
+	if err := c.AddProvider("custom-1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.AddProvider("custom-1"); err == nil {
+		t.Error("Expected a duplicate provider to fail")
+	}
+	if !strings.Contains(strings.Join(c.Providers(), ","), "gemini,custom-1") {
+		t.Errorf("Expected custom providers after the built-in ones, got %v", c.Providers())
+	}
+}
+
+func TestBaseURLAppliesToGroqModels(t *testing.T) {
+	c := New("key", WithBaseURL("http://localhost:1234/v1"))
+	if url, _ := c.getProviderConfig(); url != "http://localhost:1234/v1" {
+		t.Errorf("Expected the custom base URL for Groq models, got %s", url)
+	}
+
+	c.SetModel("claude-sonnet-4-20250514")
+	if url, _ := c.getProviderConfig(); url != AnthropicBaseURL {
+		t.Errorf("Expected other providers to keep their endpoints, got %s", url)
+	}
+}
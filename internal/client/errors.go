@@ -0,0 +1,125 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIError is an error reported by a provider, either as a non-OK
+// response or as an error event in the middle of a stream. Use errors.As
+// to get it from the errors returned by the client, or the Is helpers
+// below.
+type APIError struct {
+	StatusCode int           // HTTP status, 0 for errors reported mid-stream
+	Provider   string        // "groq", "anthropic", "gemini", ... as in ProviderFor
+	Type       string        // Error type, e.g. "rate_limit_error" or Gemini's "RESOURCE_EXHAUSTED"
+	Code       string        // Error code when the provider gives one, e.g. "context_length_exceeded"
+	Message    string        // The provider's message, or the raw body if it could not be parsed
+	RetryAfter time.Duration // From the Retry-After header, 0 if not given
+}
+
+func (e *APIError) Error() string {
+	prefix := "API error"
+	switch e.Provider {
+	case "anthropic":
+		prefix = "Claude API error"
+	case "gemini":
+		prefix = "Gemini API error"
+	}
+	if e.Type == "" {
+		return fmt.Sprintf("%s: status %d, body: %s", prefix, e.StatusCode, e.Message)
+	}
+	return fmt.Sprintf("%s: %s (%s)", prefix, e.Message, e.Type)
+}
+
+// errorBody covers the error bodies of OpenAI-compatible providers
+// ({"error": {"message", "type", "code"}}), Claude ({"type": "error",
+// "error": {"type", "message"}}) and Gemini ({"error": {"code",
+// "message", "status"}}). Gemini's code repeats the HTTP status.
+type errorBody struct {
+	Error *struct {
+		Message string          `json:"message"`
+		Type    string          `json:"type"`
+		Code    json.RawMessage `json:"code"`
+		Status  string          `json:"status"`
+	} `json:"error"`
+}
+
+// parseAPIError decodes an error body. ok is false if body is not one.
+func parseAPIError(provider string, body []byte) (e *APIError, ok bool) {
+	var b errorBody
+	if json.Unmarshal(body, &b) != nil || b.Error == nil || b.Error.Message == "" {
+		return nil, false
+	}
+	e = &APIError{Provider: provider, Message: b.Error.Message, Type: b.Error.Type}
+	if e.Type == "" {
+		e.Type = b.Error.Status
+	}
+	json.Unmarshal(b.Error.Code, &e.Code) // Only string codes are kept
+	return e, true
+}
+
+// responseError builds the error for a non-OK response whose body was read
+func responseError(provider string, resp *http.Response, body []byte) *APIError {
+	e, ok := parseAPIError(provider, body)
+	if !ok {
+		e = &APIError{Provider: provider, Message: string(body)}
+	}
+	e.StatusCode = resp.StatusCode
+	e.RetryAfter, _ = retryAfter(resp.Header.Get("Retry-After"))
+	return e
+}
+
+// IsRateLimit reports whether err is a provider refusing a request
+// because of rate limits or quota. RetryAfter on the APIError says how
+// long to wait, when the provider said.
+func IsRateLimit(err error) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	return e.StatusCode == http.StatusTooManyRequests || e.Type == "rate_limit_error" ||
+		e.Code == "rate_limit_exceeded" || e.Type == "RESOURCE_EXHAUSTED"
+}
+
+// IsAuthError reports whether err is a provider rejecting the API key
+func IsAuthError(err error) bool {
+	var e *APIError
+	if !errors.As(err, &e) {
+		return false
+	}
+	switch {
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden:
+		return true
+	case e.Type == "authentication_error", e.Type == "permission_error", e.Code == "invalid_api_key":
+		return true
+	case e.Type == "UNAUTHENTICATED", e.Type == "PERMISSION_DENIED":
+		return true
+	}
+	// Gemini answers a bad key with a 400
+	return e.Provider == "gemini" && strings.Contains(e.Message, "API key not valid")
+}
+
+// IsContextLengthExceeded reports whether err is a provider rejecting a
+// request for being too large for the model. Providers rarely give this a
+// code of its own, so the message is matched as well.
+func IsContextLengthExceeded(err error) bool {
+	if err == nil {
+		return false
+	}
+	var e *APIError
+	if errors.As(err, &e) && e.Code == "context_length_exceeded" {
+		return true
+	}
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"context_length_exceeded", "context length", "context window", "maximum context", "reduce the length", "prompt is too long"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
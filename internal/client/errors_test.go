@@ -0,0 +1,226 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newErrorClient returns a client whose requests, to any provider, get
+// status and body
+func newErrorClient(t *testing.T, model string, status int, header http.Header, body string) *Client {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for k, v := range header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(status)
+		fmt.Fprint(w, body)
+	}))
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	return New("groq-key",
+		WithBaseURL(srv.URL),
+		WithHTTPClient(&http.Client{Transport: redirectTransport{target}}),
+		WithRetry(1, time.Millisecond),
+		WithProviderKey("anthropic", "claude-key"),
+		WithProviderKey("gemini", "gemini-key"),
+		WithProviderKey("openai", "openai-key"),
+		WithModel(model),
+	)
+}
+
+func TestAPIErrorBodies(t *testing.T) {
+	tests := []struct {
+		name      string
+		model     string
+		status    int
+		header    http.Header
+		body      string
+		want      APIError
+		message   string
+		rateLimit bool
+		auth      bool
+		context   bool
+	}{
+		{
+			name:   "groq rate limit",
+			model:  "llama-3.3-70b-versatile",
+			status: http.StatusTooManyRequests,
+			header: http.Header{"Retry-After": {"20"}},
+			body:   `{"error":{"message":"Rate limit reached for model","type":"tokens","code":"rate_limit_exceeded"}}`,
+			want: APIError{StatusCode: 429, Provider: "groq", Type: "tokens", Code: "rate_limit_exceeded",
+				Message: "Rate limit reached for model", RetryAfter: 20 * time.Second},
+			message:   "API error: Rate limit reached for model (tokens)",
+			rateLimit: true,
+		},
+		{
+			name:    "openai invalid key",
+			model:   "gpt-4o",
+			status:  http.StatusUnauthorized,
+			body:    `{"error":{"message":"Incorrect API key provided","type":"invalid_request_error","param":null,"code":"invalid_api_key"}}`,
+			want:    APIError{StatusCode: 401, Provider: "openai", Type: "invalid_request_error", Code: "invalid_api_key", Message: "Incorrect API key provided"},
+			message: "API error: Incorrect API key provided (invalid_request_error)",
+			auth:    true,
+		},
+		{
+			name:    "openai context length",
+			model:   "gpt-4o",
+			status:  http.StatusBadRequest,
+			body:    `{"error":{"message":"This model's maximum context length is 128000 tokens.","type":"invalid_request_error","param":"messages","code":"context_length_exceeded"}}`,
+			want:    APIError{StatusCode: 400, Provider: "openai", Type: "invalid_request_error", Code: "context_length_exceeded", Message: "This model's maximum context length is 128000 tokens."},
+			context: true,
+		},
+		{
+			name:    "claude invalid key",
+			model:   "claude-sonnet-4-20250514",
+			status:  http.StatusUnauthorized,
+			body:    `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`,
+			want:    APIError{StatusCode: 401, Provider: "anthropic", Type: "authentication_error", Message: "invalid x-api-key"},
+			message: "Claude API error: invalid x-api-key (authentication_error)",
+			auth:    true,
+		},
+		{
+			name:    "claude prompt too long",
+			model:   "claude-sonnet-4-20250514",
+			status:  http.StatusBadRequest,
+			body:    `{"type":"error","error":{"type":"invalid_request_error","message":"prompt is too long: 210000 tokens > 200000 maximum"}}`,
+			want:    APIError{StatusCode: 400, Provider: "anthropic", Type: "invalid_request_error", Message: "prompt is too long: 210000 tokens > 200000 maximum"},
+			context: true,
+		},
+		{
+			name:      "claude rate limit",
+			model:     "claude-sonnet-4-20250514",
+			status:    http.StatusTooManyRequests,
+			body:      `{"type":"error","error":{"type":"rate_limit_error","message":"Number of request tokens has exceeded your per-minute rate limit"}}`,
+			want:      APIError{StatusCode: 429, Provider: "anthropic", Type: "rate_limit_error", Message: "Number of request tokens has exceeded your per-minute rate limit"},
+			rateLimit: true,
+		},
+		{
+			name:      "gemini quota",
+			model:     "gemini-2.0-flash",
+			status:    http.StatusTooManyRequests,
+			body:      `{"error":{"code":429,"message":"Resource has been exhausted","status":"RESOURCE_EXHAUSTED"}}`,
+			want:      APIError{StatusCode: 429, Provider: "gemini", Type: "RESOURCE_EXHAUSTED", Message: "Resource has been exhausted"},
+			message:   "Gemini API error: Resource has been exhausted (RESOURCE_EXHAUSTED)",
+			rateLimit: true,
+		},
+		{
+			name:   "gemini invalid key",
+			model:  "gemini-2.0-flash",
+			status: http.StatusBadRequest,
+			body:   `{"error":{"code":400,"message":"API key not valid. Please pass a valid API key.","status":"INVALID_ARGUMENT"}}`,
+			want:   APIError{StatusCode: 400, Provider: "gemini", Type: "INVALID_ARGUMENT", Message: "API key not valid. Please pass a valid API key."},
+			auth:   true,
+		},
+		{
+			name:    "unparsable body",
+			model:   "llama-3.3-70b-versatile",
+			status:  http.StatusBadGateway,
+			body:    `<html>Bad gateway</html>`,
+			want:    APIError{StatusCode: 502, Provider: "groq", Message: "<html>Bad gateway</html>"},
+			message: "API error: status 502, body: <html>Bad gateway</html>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newErrorClient(t, tt.model, tt.status, tt.header, tt.body)
+			messages := []Message{NewTextMessage("user", "hi")}
+			_, err := c.ChatCompletion(context.Background(), messages, nil)
+			_, streamErr := c.ChatCompletionStream(context.Background(), messages, nil)
+
+			for _, err := range []error{err, streamErr} {
+				var apiErr *APIError
+				if !errors.As(err, &apiErr) {
+					t.Fatalf("Expected an APIError, got %v", err)
+				}
+				if *apiErr != tt.want {
+					t.Errorf("Expected %+v, got %+v", tt.want, *apiErr)
+				}
+				if tt.message != "" && err.Error() != tt.message {
+					t.Errorf("Expected message %q, got %q", tt.message, err.Error())
+				}
+				if IsRateLimit(err) != tt.rateLimit || IsAuthError(err) != tt.auth || IsContextLengthExceeded(err) != tt.context {
+					t.Errorf("Expected rate limit %v, auth %v, context %v for %v", tt.rateLimit, tt.auth, tt.context, err)
+				}
+			}
+		})
+	}
+}
+
+func TestAPIErrorMidStream(t *testing.T) {
+	tests := []struct {
+		name   string
+		reader func(io.ReadCloser) *StreamReader
+		stream string
+		want   APIError
+	}{
+		{
+			name:   "openai",
+			reader: NewStreamReader,
+			stream: "data: {\"choices\":[{\"delta\":{\"content\":\"Hi\"}}]}\n\ndata: {\"error\":{\"message\":\"Service unavailable\",\"type\":\"server_error\"}}\n\n",
+			want:   APIError{Type: "server_error", Message: "Service unavailable"},
+		},
+		{
+			name:   "claude",
+			reader: NewClaudeStreamReader,
+			stream: "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi\"}}\n\nevent: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n",
+			want:   APIError{Provider: "anthropic", Type: "overloaded_error", Message: "Overloaded"},
+		},
+		{
+			name:   "gemini",
+			reader: NewGeminiStreamReader,
+			stream: "data: {\"candidates\":[{\"content\":{\"parts\":[{\"text\":\"Hi\"}]}}]}\n\ndata: {\"error\":{\"code\":429,\"message\":\"Quota exceeded\",\"status\":\"RESOURCE_EXHAUSTED\"}}\n\n",
+			want:   APIError{Provider: "gemini", Type: "RESOURCE_EXHAUSTED", Message: "Quota exceeded"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := tt.reader(io.NopCloser(strings.NewReader(tt.stream)))
+			chunk, err := s.Read()
+			if err != nil || chunk.Choices[0].Delta.Content != "Hi" {
+				t.Fatalf("Expected the text before the error, got %+v (%v)", chunk, err)
+			}
+			_, err = s.Read()
+			var apiErr *APIError
+			if !errors.As(err, &apiErr) || *apiErr != tt.want {
+				t.Errorf("Expected %+v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	// Text that merely mentions errors is not one
+	s := NewStreamReader(io.NopCloser(strings.NewReader(`data: {"choices":[{"delta":{"content":"{\"error\": 1}"}}]}` + "\n\ndata: [DONE]\n\n")))
+	if got := drain(t, s); got != `{"error": 1}` {
+		t.Errorf("Expected the content, got %q", got)
+	}
+}
+
+func TestIsContextLengthExceeded(t *testing.T) {
+	for msg, want := range map[string]bool{
+		"API error: Please reduce the length of the messages or completion. (invalid_request_error)": true,
+		"Claude API error: status 400, body: prompt is too long: 210000 tokens > 200000 maximum":     true,
+		"API error: status 400, body: context_length_exceeded":                                       true,
+		"API error: Invalid API Key (invalid_request_error)":                                         false,
+	} {
+		if got := IsContextLengthExceeded(errors.New(msg)); got != want {
+			t.Errorf("IsContextLengthExceeded(%q) = %v, want %v", msg, got, want)
+		}
+	}
+	if IsContextLengthExceeded(nil) || IsRateLimit(nil) || IsAuthError(nil) {
+		t.Error("Expected false for nil")
+	}
+	wrapped := fmt.Errorf("API error: %w", &APIError{StatusCode: 429})
+	if !IsRateLimit(wrapped) {
+		t.Error("Expected wrapped errors to be matched")
+	}
+}
@@ -0,0 +1,72 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// WithFallbackModels sets models ChatCompletionStream tries in order when
+// the current model's provider is down: rate limited after retries, a 5xx
+// or unreachable. Requests the provider rejects, such as a 400, are not
+// retried elsewhere. Models without an API key are skipped.
+func WithFallbackModels(models ...string) Option {
+	return func(c *Client) {
+		c.fallbackModels = models
+	}
+}
+
+// FallbackModels returns the models set with WithFallbackModels
+func (c *Client) FallbackModels() []string {
+	return append([]string(nil), c.fallbackModels...)
+}
+
+// shouldFailover reports whether err means the provider, rather than the
+// request, failed
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var ae *APIError
+	if errors.As(err, &ae) {
+		return retryableStatus(ae.StatusCode)
+	}
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+// streamWithFallback runs the request on the current model and then on
+// each fallback until one answers. The stream reports the model that did.
+func (c *Client) streamWithFallback(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*StreamReader, error) {
+	stream, err := c.chatCompletionStream(ctx, messages, tools, opts)
+	if err == nil {
+		stream.model = c.model
+		return stream, nil
+	}
+
+	var failed []string
+	for _, model := range c.fallbackModels {
+		if !shouldFailover(ctx, err) {
+			break
+		}
+		if model == c.model || !c.HasKeyFor(model) {
+			continue
+		}
+		fb := c.WithModelOverride(model)
+		next, ferr := fb.chatCompletionStream(ctx, messages, tools, opts)
+		if ferr == nil {
+			next.model = model
+			return next, nil
+		}
+		failed = append(failed, fmt.Sprintf("%s: %v", model, ferr))
+		if !shouldFailover(ctx, ferr) {
+			break
+		}
+	}
+	if len(failed) > 0 {
+		return nil, fmt.Errorf("%w (fallbacks failed: %s)", err, strings.Join(failed, "; "))
+	}
+	return nil, err
+}
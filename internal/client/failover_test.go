@@ -0,0 +1,187 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// providerStub answers as both Groq and Claude. Models in down fail with
+// their status; others stream a reply naming the model.
+type providerStub struct {
+	mu    sync.Mutex
+	down  map[string]int
+	calls []string // model of each request
+}
+
+func (p *providerStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Model string `json:"model"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	p.mu.Lock()
+	p.calls = append(p.calls, req.Model)
+	status := p.down[req.Model]
+	p.mu.Unlock()
+
+	if status != 0 {
+		w.WriteHeader(status)
+		fmt.Fprint(w, `{"error": {"message": "unavailable", "type": "server_error"}}`)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	if strings.HasSuffix(r.URL.Path, "/messages") {
+		fmt.Fprintf(w, "data: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"%s\"}}\n\n", req.Model)
+		fmt.Fprint(w, "data: {\"type\":\"message_stop\"}\n\n")
+		return
+	}
+	fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": \"%s\"}, \"finish_reason\": \"stop\"}]}\n\ndata: [DONE]\n\n", req.Model)
+}
+
+// redirectTransport sends every request to the stub, whatever its host
+type redirectTransport struct{ target *url.URL }
+
+func (t redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = t.target.Scheme, t.target.Host
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func newFailoverClient(t *testing.T, stub *providerStub, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	target, _ := url.Parse(srv.URL)
+	opts = append([]Option{
+		WithBaseURL(srv.URL),
+		WithHTTPClient(&http.Client{Transport: redirectTransport{target}}),
+		WithRetry(2, time.Millisecond),
+	}, opts...)
+	return New("groq-key", opts...)
+}
+
+func readAll(t *testing.T, s *StreamReader) string {
+	t.Helper()
+	defer s.Close()
+	var b strings.Builder
+	for {
+		chunk, err := s.Read()
+		if err != nil {
+			break
+		}
+		for _, choice := range chunk.Choices {
+			if choice.Delta != nil {
+				b.WriteString(choice.Delta.Content)
+			}
+		}
+	}
+	return b.String()
+}
+
+func TestFailoverToNextModel(t *testing.T) {
+	stub := &providerStub{down: map[string]int{"llama-3.3-70b-versatile": http.StatusServiceUnavailable}}
+	c := newFailoverClient(t, stub,
+		WithProviderKey("anthropic", "claude-key"),
+		WithFallbackModels("gpt-4o", "claude-3-5-haiku-20241022", "llama-3.1-8b-instant"))
+
+	stream, err := c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+	if err != nil {
+		t.Fatalf("Expected a fallback to answer, got %v", err)
+	}
+	if stream.Model() != "claude-3-5-haiku-20241022" {
+		t.Errorf("Expected Claude to serve the turn, got %q", stream.Model())
+	}
+	if got := readAll(t, stream); got != "claude-3-5-haiku-20241022" {
+		t.Errorf("Unexpected reply %q", got)
+	}
+	// The primary is retried first; gpt-4o has no key and is skipped
+	if got := strings.Join(stub.calls, ","); got != "llama-3.3-70b-versatile,llama-3.3-70b-versatile,claude-3-5-haiku-20241022" {
+		t.Errorf("Unexpected requests %s", got)
+	}
+	if c.Model() != "llama-3.3-70b-versatile" {
+		t.Error("Expected the client's model unchanged")
+	}
+
+	// A healthy primary reports itself
+	stub.down = nil
+	stream, err = c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+	if err != nil || stream.Model() != "llama-3.3-70b-versatile" {
+		t.Fatalf("Expected the primary to answer, got %v (%v)", stream, err)
+	}
+	stream.Close()
+}
+
+func TestFailoverSkipsRejectedRequests(t *testing.T) {
+	stub := &providerStub{down: map[string]int{"llama-3.3-70b-versatile": http.StatusBadRequest}}
+	c := newFailoverClient(t, stub, WithFallbackModels("llama-3.1-8b-instant"))
+
+	_, err := c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+	if err == nil {
+		t.Fatal("Expected the 400 to be returned")
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Errorf("Expected an APIError with 400, got %v", err)
+	}
+	if len(stub.calls) != 1 {
+		t.Errorf("Expected no fallback for a rejected request, got %v", stub.calls)
+	}
+}
+
+func TestFailoverAllDown(t *testing.T) {
+	stub := &providerStub{down: map[string]int{
+		"llama-3.3-70b-versatile": http.StatusServiceUnavailable,
+		"llama-3.1-8b-instant":    http.StatusTooManyRequests,
+	}}
+	c := newFailoverClient(t, stub, WithFallbackModels("llama-3.1-8b-instant"))
+
+	_, err := c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+	if err == nil || !strings.Contains(err.Error(), "fallbacks failed: llama-3.1-8b-instant") {
+		t.Errorf("Expected both failures reported, got %v", err)
+	}
+	if len(stub.calls) != 4 {
+		t.Errorf("Expected two attempts per model, got %v", stub.calls)
+	}
+}
+
+func TestFailoverOnConnectionError(t *testing.T) {
+	stub := &providerStub{}
+	srv := httptest.NewServer(stub)
+	defer srv.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	// Groq is unreachable; Claude goes to the stub
+	target, _ := url.Parse(srv.URL)
+	transport := roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		if r.URL.Host == "api.anthropic.com" {
+			return redirectTransport{target}.RoundTrip(r)
+		}
+		return http.DefaultTransport.RoundTrip(r)
+	})
+	c := New("groq-key", WithBaseURL(down.URL), WithRetry(1, time.Millisecond),
+		WithHTTPClient(&http.Client{Transport: transport}),
+		WithProviderKey("anthropic", "claude-key"),
+		WithFallbackModels("claude-3-5-haiku-20241022"))
+
+	stream, err := c.ChatCompletionStream(context.Background(), []Message{NewTextMessage("user", "hi")}, nil)
+	if err != nil {
+		t.Fatalf("Expected a fallback, got %v", err)
+	}
+	defer stream.Close()
+	if stream.Model() != "claude-3-5-haiku-20241022" {
+		t.Errorf("Unexpected model %q", stream.Model())
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
@@ -0,0 +1,361 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// GeminiRequest represents a Gemini generateContent request
+type GeminiRequest struct {
+	Contents          []GeminiContent `json:"contents"`
+	SystemInstruction *GeminiContent  `json:"systemInstruction,omitempty"`
+	Tools             []GeminiTool    `json:"tools,omitempty"`
+	GenerationConfig  *GeminiConfig   `json:"generationConfig,omitempty"`
+}
+
+// GeminiConfig holds Gemini sampling parameters
+type GeminiConfig struct {
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64 `json:"temperature,omitempty"`
+}
+
+// GeminiContent is one turn of a Gemini conversation
+type GeminiContent struct {
+	Role  string       `json:"role,omitempty"` // "user" or "model"
+	Parts []GeminiPart `json:"parts"`
+}
+
+// GeminiPart is a single part of a Gemini turn
+type GeminiPart struct {
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *GeminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *GeminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *GeminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// GeminiInlineData carries base64 media such as images
+type GeminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+// GeminiFunctionCall is a tool call made by the model
+type GeminiFunctionCall struct {
+	Name string         `json:"name"`
+	Args map[string]any `json:"args,omitempty"`
+}
+
+// GeminiFunctionResponse returns a tool result to the model
+type GeminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+// GeminiTool groups function declarations
+type GeminiTool struct {
+	FunctionDeclarations []GeminiFunctionDeclaration `json:"functionDeclarations"`
+}
+
+// GeminiFunctionDeclaration describes a callable tool
+type GeminiFunctionDeclaration struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Parameters  any    `json:"parameters,omitempty"`
+}
+
+// GeminiResponse is a generateContent response or a single streamed chunk
+type GeminiResponse struct {
+	Candidates    []GeminiCandidate    `json:"candidates"`
+	UsageMetadata *GeminiUsageMetadata `json:"usageMetadata,omitempty"`
+	ModelVersion  string               `json:"modelVersion,omitempty"`
+}
+
+// GeminiCandidate is one generated answer
+type GeminiCandidate struct {
+	Content      GeminiContent `json:"content"`
+	FinishReason string        `json:"finishReason,omitempty"`
+	Index        int           `json:"index"`
+}
+
+// GeminiUsageMetadata is Gemini's token usage
+type GeminiUsageMetadata struct {
+	PromptTokenCount     int `json:"promptTokenCount"`
+	CandidatesTokenCount int `json:"candidatesTokenCount"`
+	TotalTokenCount      int `json:"totalTokenCount"`
+}
+
+// usage converts Gemini usage to the OpenAI shape
+func (u *GeminiUsageMetadata) usage() Usage {
+	total := u.TotalTokenCount
+	if total == 0 {
+		total = u.PromptTokenCount + u.CandidatesTokenCount
+	}
+	return Usage{
+		PromptTokens:     u.PromptTokenCount,
+		CompletionTokens: u.CandidatesTokenCount,
+		TotalTokens:      total,
+	}
+}
+
+// buildGeminiRequest converts OpenAI-style messages and tools to Gemini's format
+func buildGeminiRequest(messages []Message, tools []Tool) GeminiRequest {
+	var req GeminiRequest
+
+	// Gemini matches function responses by name, not by call ID
+	callNames := make(map[string]string)
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			req.SystemInstruction = &GeminiContent{
+				Parts: []GeminiPart{{Text: getMessageContent(msg)}},
+			}
+
+		case "tool":
+			part := GeminiPart{FunctionResponse: &GeminiFunctionResponse{
+				Name:     callNames[msg.ToolCallID],
+				Response: map[string]any{"content": getMessageContent(msg)},
+			}}
+			// Results of parallel calls go back together in one turn
+			if n := len(req.Contents); n > 0 && isFunctionResponseTurn(req.Contents[n-1]) {
+				req.Contents[n-1].Parts = append(req.Contents[n-1].Parts, part)
+				continue
+			}
+			req.Contents = append(req.Contents, GeminiContent{Role: "user", Parts: []GeminiPart{part}})
+
+		case "assistant":
+			content := GeminiContent{Role: "model"}
+			if text := getMessageContent(msg); text != "" {
+				content.Parts = append(content.Parts, GeminiPart{Text: text})
+			}
+			for _, tc := range msg.ToolCalls {
+				callNames[tc.ID] = tc.Function.Name
+				var args map[string]any
+				if tc.Function.Arguments != "" {
+					json.Unmarshal([]byte(tc.Function.Arguments), &args)
+				}
+				content.Parts = append(content.Parts, GeminiPart{
+					FunctionCall: &GeminiFunctionCall{Name: tc.Function.Name, Args: args},
+				})
+			}
+			if len(content.Parts) == 0 {
+				continue
+			}
+			req.Contents = append(req.Contents, content)
+
+		default:
+			req.Contents = append(req.Contents, GeminiContent{Role: "user", Parts: geminiUserParts(msg)})
+		}
+	}
+
+	if len(tools) > 0 {
+		decls := make([]GeminiFunctionDeclaration, 0, len(tools))
+		for _, t := range tools {
+			decls = append(decls, GeminiFunctionDeclaration{
+				Name:        t.Function.Name,
+				Description: t.Function.Description,
+				Parameters:  t.Function.Parameters,
+			})
+		}
+		req.Tools = []GeminiTool{{FunctionDeclarations: decls}}
+	}
+
+	return req
+}
+
+func isFunctionResponseTurn(c GeminiContent) bool {
+	return c.Role == "user" && len(c.Parts) > 0 && c.Parts[0].FunctionResponse != nil
+}
+
+// geminiUserParts converts text and base64 data URI images to Gemini parts
+func geminiUserParts(msg Message) []GeminiPart {
+	parts, ok := msg.Content.([]ContentPart)
+	if !ok {
+		return []GeminiPart{{Text: getMessageContent(msg)}}
+	}
+
+	var out []GeminiPart
+	for _, p := range parts {
+		switch p.Type {
+		case "text":
+			out = append(out, GeminiPart{Text: p.Text})
+		case "image_url":
+			if p.ImageURL == nil {
+				continue
+			}
+			mimeType, data, ok := ParseDataURL(p.ImageURL.URL)
+			if !ok {
+				out = append(out, GeminiPart{Text: p.ImageURL.URL})
+				continue
+			}
+			out = append(out, GeminiPart{InlineData: &GeminiInlineData{MimeType: ImageMediaType(mimeType, data), Data: data}})
+		}
+	}
+	if len(out) == 0 {
+		out = append(out, GeminiPart{Text: ""})
+	}
+	return out
+}
+
+// geminiFinishReason maps Gemini finish reasons to OpenAI ones
+func geminiFinishReason(reason string, hasToolCalls bool) string {
+	switch reason {
+	case "":
+		return ""
+	case "STOP":
+		if hasToolCalls {
+			return "tool_calls"
+		}
+		return "stop"
+	case "MAX_TOKENS":
+		return "length"
+	case "SAFETY", "RECITATION", "BLOCKLIST", "PROHIBITED_CONTENT", "SPII":
+		return "content_filter"
+	default:
+		return strings.ToLower(reason)
+	}
+}
+
+// newToolCallID generates an ID for a Gemini function call, which has none
+func newToolCallID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "call_" + hex.EncodeToString(b)
+}
+
+// geminiToolCall converts a Gemini function call to an OpenAI tool call
+func geminiToolCall(index int, fc *GeminiFunctionCall) ToolCall {
+	args := "{}"
+	if fc.Args != nil {
+		if data, err := json.Marshal(fc.Args); err == nil {
+			args = string(data)
+		}
+	}
+	return ToolCall{
+		Index: index,
+		ID:    newToolCallID(),
+		Type:  "function",
+		Function: FunctionCall{
+			Name:      fc.Name,
+			Arguments: args,
+		},
+	}
+}
+
+// newGeminiRequest creates a request for the given Gemini method
+func (c *Client) newGeminiRequest(ctx context.Context, method string, messages []Message, tools []Tool, opts RequestOptions) (*http.Request, error) {
+	apiKey := c.providerKeys["gemini"]
+	if apiKey == "" {
+		return nil, fmt.Errorf("no API key configured for Gemini (set GEMINI_API_KEY)")
+	}
+
+	req := buildGeminiRequest(messages, tools)
+	if opts.MaxTokens > 0 || opts.Temperature != nil {
+		req.GenerationConfig = &GeminiConfig{MaxOutputTokens: opts.MaxTokens, Temperature: opts.Temperature}
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/models/%s:%s", GeminiBaseURL, c.model, method)
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-goog-api-key", apiKey)
+	return httpReq, nil
+}
+
+// geminiChatCompletion handles Gemini API requests
+func (c *Client) geminiChatCompletion(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*ChatCompletionResponse, error) {
+	httpReq, err := c.newGeminiRequest(ctx, "generateContent", messages, tools, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, attempts, err := c.do(httpReq)
+	if err != nil {
+		return nil, withAttempts(fmt.Errorf("failed to send request: %w", err), attempts)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, withAttempts(responseError("gemini", resp, respBody), attempts)
+	}
+
+	return parseGeminiResponse(respBody)
+}
+
+// parseGeminiResponse converts a Gemini response to OpenAI format
+func parseGeminiResponse(body []byte) (*ChatCompletionResponse, error) {
+	var geminiResp GeminiResponse
+	if err := json.Unmarshal(body, &geminiResp); err != nil {
+		return nil, fmt.Errorf("failed to parse Gemini response: %w", err)
+	}
+
+	resp := &ChatCompletionResponse{Model: geminiResp.ModelVersion}
+	if geminiResp.UsageMetadata != nil {
+		resp.Usage = geminiResp.UsageMetadata.usage()
+	}
+
+	for _, cand := range geminiResp.Candidates {
+		var textParts []string
+		var toolCalls []ToolCall
+		for _, part := range cand.Content.Parts {
+			if part.FunctionCall != nil {
+				toolCalls = append(toolCalls, geminiToolCall(len(toolCalls), part.FunctionCall))
+			} else if part.Text != "" {
+				textParts = append(textParts, part.Text)
+			}
+		}
+
+		choice := Choice{
+			Index:        cand.Index,
+			FinishReason: geminiFinishReason(cand.FinishReason, len(toolCalls) > 0),
+		}
+		choice.Message.Role = "assistant"
+		choice.Message.Content = strings.Join(textParts, "")
+		choice.Message.ToolCalls = toolCalls
+		resp.Choices = append(resp.Choices, choice)
+	}
+
+	return resp, nil
+}
+
+// geminiChatCompletionStream handles Gemini streaming API requests
+func (c *Client) geminiChatCompletionStream(ctx context.Context, messages []Message, tools []Tool, opts RequestOptions) (*StreamReader, error) {
+	httpReq, err := c.newGeminiRequest(ctx, "streamGenerateContent?alt=sse", messages, tools, opts)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, attempts, err := c.do(httpReq)
+	if err != nil {
+		return nil, withAttempts(fmt.Errorf("failed to send request: %w", err), attempts)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, withAttempts(responseError("gemini", resp, respBody), attempts)
+	}
+
+	return NewGeminiStreamReader(resp.Body), nil
+}
@@ -0,0 +1,131 @@
+package client
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+func openFixture(t *testing.T, name string) *StreamReader {
+	t.Helper()
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	return NewGeminiStreamReader(f)
+}
+
+func TestGeminiStreamText(t *testing.T) {
+	s := openFixture(t, "gemini_text.sse")
+	defer s.Close()
+
+	msg, finish, err := s.CollectResponse()
+	if err != nil {
+		t.Fatalf("CollectResponse failed: %v", err)
+	}
+	if msg.Content != "Hello, world!" {
+		t.Errorf("Expected content %q, got %q", "Hello, world!", msg.Content)
+	}
+	if finish != "stop" {
+		t.Errorf("Expected finish reason stop, got %q", finish)
+	}
+	want := Usage{PromptTokens: 9, CompletionTokens: 4, TotalTokens: 13}
+	if got := s.Usage(); got != want {
+		t.Errorf("Expected usage %+v, got %+v", want, got)
+	}
+}
+
+func TestGeminiToolCallRoundTrip(t *testing.T) {
+	s := openFixture(t, "gemini_tool.sse")
+	defer s.Close()
+
+	msg, finish, err := s.CollectResponse()
+	if err != nil {
+		t.Fatalf("CollectResponse failed: %v", err)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", finish)
+	}
+	if msg.Content != "Let me check." {
+		t.Errorf("Expected text before the calls, got %q", msg.Content)
+	}
+	if len(msg.ToolCalls) != 2 {
+		t.Fatalf("Expected 2 tool calls, got %+v", msg.ToolCalls)
+	}
+	call := msg.ToolCalls[0]
+	if call.ID == "" || call.ID == msg.ToolCalls[1].ID {
+		t.Errorf("Expected unique tool call IDs, got %q and %q", call.ID, msg.ToolCalls[1].ID)
+	}
+	if call.Function.Name != "Read" || call.Function.Arguments != `{"file_path":"main.go"}` {
+		t.Errorf("Unexpected tool call %+v", call.Function)
+	}
+
+	// Feed the calls and their results back
+	messages := []Message{
+		NewTextMessage("system", "You are helpful."),
+		NewTextMessage("user", "Look at main.go"),
+		*msg,
+		{Role: "tool", ToolCallID: msg.ToolCalls[0].ID, Content: "package main"},
+		{Role: "tool", ToolCallID: msg.ToolCalls[1].ID, Content: "main.go"},
+	}
+	tools := []Tool{{Type: "function", Function: FunctionSchema{
+		Name:        "Read",
+		Description: "Read a file",
+		Parameters:  map[string]any{"type": "object"},
+	}}}
+	req := buildGeminiRequest(messages, tools)
+
+	if req.SystemInstruction == nil || req.SystemInstruction.Parts[0].Text != "You are helpful." {
+		t.Errorf("Expected system instruction, got %+v", req.SystemInstruction)
+	}
+	if len(req.Contents) != 3 {
+		t.Fatalf("Expected user, model and function response turns, got %d", len(req.Contents))
+	}
+
+	model := req.Contents[1]
+	if model.Role != "model" || len(model.Parts) != 3 {
+		t.Fatalf("Expected model turn with text and 2 calls, got %+v", model)
+	}
+	if fc := model.Parts[1].FunctionCall; fc == nil || fc.Name != "Read" || fc.Args["file_path"] != "main.go" {
+		t.Errorf("Unexpected function call %+v", model.Parts[1])
+	}
+
+	results := req.Contents[2]
+	if results.Role != "user" || len(results.Parts) != 2 {
+		t.Fatalf("Expected one turn with 2 function responses, got %+v", results)
+	}
+	for i, name := range []string{"Read", "Glob"} {
+		fr := results.Parts[i].FunctionResponse
+		if fr == nil || fr.Name != name {
+			t.Errorf("Expected function response for %s, got %+v", name, results.Parts[i])
+		}
+	}
+
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	var raw map[string]any
+	json.Unmarshal(data, &raw)
+	if _, ok := raw["tools"].([]any)[0].(map[string]any)["functionDeclarations"]; !ok {
+		t.Errorf("Expected functionDeclarations in %s", data)
+	}
+}
+
+func TestGeminiNonStreamingResponse(t *testing.T) {
+	body := []byte(`{"candidates":[{"content":{"parts":[{"functionCall":{"name":"Bash","args":{"command":"ls"}}}],"role":"model"},"finishReason":"STOP","index":0}],"usageMetadata":{"promptTokenCount":5,"candidatesTokenCount":2,"totalTokenCount":7}}`)
+	resp, err := parseGeminiResponse(body)
+	if err != nil {
+		t.Fatalf("parseGeminiResponse failed: %v", err)
+	}
+	if len(resp.Choices) != 1 || resp.Choices[0].FinishReason != "tool_calls" {
+		t.Fatalf("Unexpected choices %+v", resp.Choices)
+	}
+	calls := resp.Choices[0].Message.ToolCalls
+	if len(calls) != 1 || calls[0].Function.Arguments != `{"command":"ls"}` {
+		t.Errorf("Unexpected tool calls %+v", calls)
+	}
+	if resp.Usage.TotalTokens != 7 {
+		t.Errorf("Expected 7 total tokens, got %d", resp.Usage.TotalTokens)
+	}
+}
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Providers lists the supported providers in display order
+var Providers = []string{"groq", "anthropic", "openai", "moonshot", "gemini"}
+
+// ProviderModels lists the catalog's models for each provider, custom
+// endpoints included, best default first
+func ProviderModels() map[string][]string {
+	providers := Catalog.Providers()
+	models := make(map[string][]string, len(providers))
+	for _, provider := range providers {
+		models[provider] = Catalog.ProviderModels(provider)
+	}
+	return models
+}
+
+// healthCheckTimeout bounds a single key validation request
+const healthCheckTimeout = 15 * time.Second
+
+// CheckKey validates an API key by listing the provider's models.
+// The key is never included in the returned error.
+func CheckKey(ctx context.Context, provider, apiKey string) error {
+	if apiKey == "" {
+		return fmt.Errorf("empty API key")
+	}
+
+	var baseURL string
+	switch provider {
+	case "groq":
+		baseURL = GroqBaseURL
+	case "anthropic":
+		baseURL = AnthropicBaseURL
+	case "openai":
+		baseURL = OpenAIBaseURL
+	case "moonshot":
+		baseURL = MoonshotBaseURL
+	case "gemini":
+		baseURL = GeminiBaseURL
+	default:
+		return fmt.Errorf("unknown provider: %s", provider)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	switch provider {
+	case "anthropic":
+		req.Header.Set("x-api-key", apiKey)
+		req.Header.Set("anthropic-version", "2023-06-01")
+	case "gemini":
+		req.Header.Set("x-goog-api-key", apiKey)
+	default:
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", provider, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%s rejected the API key (status %d)", provider, resp.StatusCode)
+	default:
+		return fmt.Errorf("%s health check failed: status %d", provider, resp.StatusCode)
+	}
+}
+
+// SetProviderKey sets or replaces the API key for a provider
+func (c *Client) SetProviderKey(provider, apiKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.providerKeys[provider] = apiKey
+	if provider == "groq" {
+		c.apiKey = apiKey
+	}
+}
@@ -0,0 +1,113 @@
+package client
+
+import "strings"
+
+// KnownModels returns the models offered in model pickers
+func KnownModels() []string {
+	var names []string
+	for _, m := range Catalog.Models() {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// ProviderFor returns the provider that serves model, as listed in
+// Catalog. Unrecognized models are sent to Groq.
+func ProviderFor(model string) string {
+	if m, ok := Catalog.Lookup(model); ok {
+		return m.Provider
+	}
+	return "groq"
+}
+
+// HasKeyFor reports whether an API key is configured for model's provider.
+// Custom endpoints need no key.
+func (c *Client) HasKeyFor(model string) bool {
+	if _, ok := c.endpointFor(model); ok {
+		return true
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.providerKeys[ProviderFor(model)] != ""
+}
+
+// SupportsVision reports whether model accepts image content
+func SupportsVision(model string) bool {
+	if m, ok := Catalog.Lookup(model); ok {
+		return m.Vision
+	}
+	return strings.Contains(model, "vision")
+}
+
+// SupportsTools reports whether model can be offered tools. Models
+// missing from the catalog are assumed to.
+func SupportsTools(model string) bool {
+	if m, ok := Catalog.Lookup(model); ok {
+		return m.Tools
+	}
+	return true
+}
+
+// HasImages reports whether the message carries image parts
+func (m Message) HasImages() bool {
+	parts, _ := m.Content.([]ContentPart)
+	for _, part := range parts {
+		if part.Type == "image_url" {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultContextWindow is assumed for models without one in the catalog
+const DefaultContextWindow = 8192
+
+// ContextWindow returns the context size of model in tokens
+func ContextWindow(model string) int {
+	if m, ok := Catalog.Lookup(model); ok && m.ContextWindow > 0 {
+		return m.ContextWindow
+	}
+	return DefaultContextWindow
+}
+
+// toolBudgets caps the tokens the tool list may take for models whose
+// limits are tighter than their context window suggests, such as Groq's
+// per-request token limits on small models
+var toolBudgets = map[string]int{
+	"llama-3.1-8b-instant":         3000,
+	"llama-3.2-90b-vision-preview": 2000,
+	"mixtral-8x7b-32768":           4000,
+	"gpt-4":                        2000,
+	"gpt-3.5-turbo":                3000,
+	"moonshot-v1-8k":               2000,
+}
+
+// MaxToolBudget bounds the tool list of models with large context windows
+const MaxToolBudget = 32000
+
+// ToolBudget returns the estimated tokens the tool schemas sent to model
+// may take: the model's entry in toolBudgets, otherwise a quarter of its
+// context window up to MaxToolBudget
+func ToolBudget(model string) int {
+	if n, ok := toolBudgets[model]; ok {
+		return n
+	}
+	return min(ContextWindow(model)/4, MaxToolBudget)
+}
+
+// SummaryModel returns a cheap model from the same provider as model, used
+// for housekeeping such as summarizing old history
+func SummaryModel(model string) string {
+	switch ProviderFor(model) {
+	case "anthropic":
+		return "claude-3-5-haiku-20241022"
+	case "openai":
+		return "gpt-4o-mini"
+	case "gemini":
+		return "gemini-2.0-flash-lite"
+	case "moonshot":
+		return "moonshot-v1-32k"
+	default:
+		return "llama-3.1-8b-instant"
+	}
+}
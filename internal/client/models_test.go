@@ -0,0 +1,78 @@
+package client
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHasKeyFor(t *testing.T) {
+	c := New("groq-key", WithProviderKey("anthropic", "claude-key"))
+
+	tests := map[string]bool{
+		"llama-3.3-70b-versatile":  true,
+		"claude-sonnet-4-20250514": true,
+		"gpt-4o":                   false,
+		"gemini-2.0-flash":         false,
+		"some-new-groq-model":      true, // unknown models go to Groq
+	}
+	for model, want := range tests {
+		if got := c.HasKeyFor(model); got != want {
+			t.Errorf("HasKeyFor(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestKnownModelsIsACopy(t *testing.T) {
+	models := KnownModels()
+	models[0] = "changed"
+	if KnownModels()[0] == "changed" {
+		t.Error("Expected KnownModels to return a copy")
+	}
+}
+
+func TestHasImages(t *testing.T) {
+	if NewTextMessage("user", "hi").HasImages() {
+		t.Error("Expected a text message to have no images")
+	}
+	msg := NewVisionMessage("user", "look", "data:image/png;base64,AAAA")
+	if !msg.HasImages() {
+		t.Error("Expected a vision message to have images")
+	}
+
+	// Messages loaded from storage hold decoded JSON
+	data, _ := json.Marshal(msg)
+	var decoded Message
+	json.Unmarshal(data, &decoded)
+	if !decoded.HasImages() {
+		t.Error("Expected a decoded vision message to have images")
+	}
+}
+
+func TestSupportsVision(t *testing.T) {
+	for model, want := range map[string]bool{
+		"llama-3.2-90b-vision-preview": true,
+		"llama-3.3-70b-versatile":      false,
+		"gpt-4o":                       true,
+		"gpt-3.5-turbo":                false,
+		"claude-3-5-haiku-20241022":    true,
+	} {
+		if got := SupportsVision(model); got != want {
+			t.Errorf("SupportsVision(%q) = %v, want %v", model, got, want)
+		}
+	}
+}
+
+func TestToolBudget(t *testing.T) {
+	if got := ToolBudget("llama-3.1-8b-instant"); got != 3000 {
+		t.Errorf("Expected the table's budget, got %d", got)
+	}
+	if got := ToolBudget("mixtral-8x7b-32768"); got != 4000 {
+		t.Errorf("Expected the table's budget, got %d", got)
+	}
+	if got := ToolBudget("claude-sonnet-4-20250514"); got != MaxToolBudget {
+		t.Errorf("Expected large models capped at %d, got %d", MaxToolBudget, got)
+	}
+	if got := ToolBudget("some-new-model"); got != DefaultContextWindow/4 {
+		t.Errorf("Expected a quarter of the default window, got %d", got)
+	}
+}
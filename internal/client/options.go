@@ -0,0 +1,66 @@
+package client
+
+import "fmt"
+
+// DefaultClaudeMaxTokens is used for Claude, which requires max_tokens
+const DefaultClaudeMaxTokens = 4096
+
+// MaxTemperature is the highest temperature accepted by the OpenAI-compatible
+// providers and Gemini
+const MaxTemperature = 2.0
+
+// MaxClaudeTemperature is the highest temperature Anthropic accepts. Higher
+// settings are clamped for Claude models rather than failing the request,
+// since the same setting applies across models and failover.
+const MaxClaudeTemperature = 1.0
+
+// RequestOptions are per-call sampling overrides. Zero values fall back to
+// the client defaults set with WithMaxTokens and WithTemperature.
+type RequestOptions struct {
+	MaxTokens   int
+	Temperature *float64
+}
+
+// Validate checks that the options are in range
+func (o RequestOptions) Validate() error {
+	if o.MaxTokens < 0 {
+		return fmt.Errorf("max_tokens must be positive")
+	}
+	if o.Temperature != nil && (*o.Temperature < 0 || *o.Temperature > MaxTemperature) {
+		return fmt.Errorf("temperature must be between 0 and %g", MaxTemperature)
+	}
+	return nil
+}
+
+// Float returns a pointer to f, for RequestOptions.Temperature
+func Float(f float64) *float64 {
+	return &f
+}
+
+// WithMaxTokens sets the default completion token limit
+func WithMaxTokens(n int) Option {
+	return func(c *Client) {
+		c.maxTokens = n
+	}
+}
+
+// WithTemperature sets the default sampling temperature
+func WithTemperature(t float64) Option {
+	return func(c *Client) {
+		c.temperature = &t
+	}
+}
+
+// requestOptions merges per-call overrides over the client defaults
+func (c *Client) requestOptions(opts []RequestOptions) RequestOptions {
+	o := RequestOptions{MaxTokens: c.maxTokens, Temperature: c.temperature}
+	for _, opt := range opts {
+		if opt.MaxTokens > 0 {
+			o.MaxTokens = opt.MaxTokens
+		}
+		if opt.Temperature != nil {
+			o.Temperature = opt.Temperature
+		}
+	}
+	return o
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestOptionsOpenAICompatible(t *testing.T) {
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer srv.Close()
+
+	c := New("key", WithBaseURL(srv.URL), WithMaxTokens(512), WithTemperature(0.7))
+	msgs := []Message{NewTextMessage("user", "hi")}
+
+	if _, err := c.ChatCompletion(context.Background(), msgs, nil); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	// Per-call overrides win; a zero temperature is still sent
+	if _, err := c.ChatCompletion(context.Background(), msgs, nil, RequestOptions{MaxTokens: 64, Temperature: Float(0)}); err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+
+	if bodies[0]["max_tokens"] != 512.0 || bodies[0]["temperature"] != 0.7 {
+		t.Errorf("Expected client defaults in request, got %v", bodies[0])
+	}
+	if bodies[1]["max_tokens"] != 64.0 || bodies[1]["temperature"] != 0.0 {
+		t.Errorf("Expected overrides in request, got %v", bodies[1])
+	}
+
+	// Unset options are left to the provider
+	plain := New("key", WithBaseURL(srv.URL))
+	plain.ChatCompletion(context.Background(), msgs, nil)
+	if _, ok := bodies[2]["temperature"]; ok {
+		t.Errorf("Expected no temperature, got %v", bodies[2])
+	}
+	if _, ok := bodies[2]["max_tokens"]; ok {
+		t.Errorf("Expected no max_tokens, got %v", bodies[2])
+	}
+}
+
+func TestRequestOptionsClaude(t *testing.T) {
+	c := New("key", WithModel("claude-sonnet-4-20250514"))
+
+	body := func(opts RequestOptions) map[string]any {
+		data, err := json.Marshal(c.buildClaudeRequest([]Message{NewTextMessage("user", "hi")}, nil, true, opts))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		var m map[string]any
+		json.Unmarshal(data, &m)
+		return m
+	}
+
+	if got := body(RequestOptions{}); got["max_tokens"] != float64(DefaultClaudeMaxTokens) {
+		t.Errorf("Expected default max_tokens, got %v", got["max_tokens"])
+	}
+	got := body(RequestOptions{MaxTokens: 1000, Temperature: Float(0.2)})
+	if got["max_tokens"] != 1000.0 || got["temperature"] != 0.2 {
+		t.Errorf("Expected max_tokens 1000 and temperature 0.2, got %v", got)
+	}
+	// Anthropic rejects temperatures above 1
+	if got := body(RequestOptions{Temperature: Float(1.5)}); got["temperature"] != MaxClaudeTemperature {
+		t.Errorf("Expected temperature clamped to %g, got %v", MaxClaudeTemperature, got["temperature"])
+	}
+}
+
+func TestRequestOptionsGemini(t *testing.T) {
+	c := New("", WithModel("gemini-2.0-flash"), WithProviderKey("gemini", "key"), WithTemperature(0.4))
+	req, err := c.newGeminiRequest(context.Background(), "generateContent", []Message{NewTextMessage("user", "hi")}, nil, c.requestOptions([]RequestOptions{{MaxTokens: 256}}))
+	if err != nil {
+		t.Fatalf("newGeminiRequest failed: %v", err)
+	}
+	data, _ := io.ReadAll(req.Body)
+	var body struct {
+		GenerationConfig GeminiConfig `json:"generationConfig"`
+	}
+	json.Unmarshal(data, &body)
+	if body.GenerationConfig.MaxOutputTokens != 256 || body.GenerationConfig.Temperature == nil || *body.GenerationConfig.Temperature != 0.4 {
+		t.Errorf("Unexpected generationConfig in %s", data)
+	}
+}
+
+func TestRequestOptionsValidate(t *testing.T) {
+	if err := (RequestOptions{Temperature: Float(2.5)}).Validate(); err == nil {
+		t.Error("Expected error for temperature above the maximum")
+	}
+	if err := (RequestOptions{MaxTokens: -1}).Validate(); err == nil {
+		t.Error("Expected error for negative max_tokens")
+	}
+	if err := (RequestOptions{MaxTokens: 10, Temperature: Float(0)}).Validate(); err != nil {
+		t.Errorf("Expected valid options, got %v", err)
+	}
+}
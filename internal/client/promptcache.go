@@ -0,0 +1,104 @@
+package client
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// PromptCacheMinChars is the size from which a Claude message is marked
+// for caching. Claude ignores cache hints on prompts under about 1024
+// tokens, so smaller messages aren't worth a breakpoint.
+const PromptCacheMinChars = 4096
+
+// maxCacheBreakpoints is the most cache_control blocks Claude accepts
+// in one request
+const maxCacheBreakpoints = 4
+
+// WithPromptCaching marks the system prompt and large messages in Claude
+// requests as cacheable, so repeated prefixes are billed at the cache rate.
+// Cache reads and writes are reported in Usage.
+func WithPromptCaching() Option {
+	return func(c *Client) {
+		c.cachePrompts = true
+	}
+}
+
+// ClaudeCacheControl marks the end of a cacheable prefix
+type ClaudeCacheControl struct {
+	Type string `json:"type"` // "ephemeral"
+}
+
+// ephemeralCache is the only cache type Claude supports
+func ephemeralCache() *ClaudeCacheControl {
+	return &ClaudeCacheControl{Type: "ephemeral"}
+}
+
+// ClaudeSystem is the system prompt of a Claude request. It is sent as a
+// plain string unless a block carries a cache hint, which needs the
+// array-of-blocks form.
+type ClaudeSystem []ClaudeBlock
+
+// Text returns the system prompt as one string
+func (s ClaudeSystem) Text() string {
+	parts := make([]string, len(s))
+	for i, b := range s {
+		parts[i] = b.Text
+	}
+	return strings.Join(parts, "\n")
+}
+
+// MarshalJSON implements json.Marshaler
+func (s ClaudeSystem) MarshalJSON() ([]byte, error) {
+	if len(s) == 1 && s[0].CacheControl == nil {
+		return json.Marshal(s[0].Text)
+	}
+	return json.Marshal([]ClaudeBlock(s))
+}
+
+// UnmarshalJSON implements json.Unmarshaler, accepting either form
+func (s *ClaudeSystem) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*s = ClaudeSystem{{Type: "text", Text: text}}
+		return nil
+	}
+	var blocks []ClaudeBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	*s = blocks
+	return nil
+}
+
+// markCacheBreakpoints adds cache hints to the system prompt and to the
+// last block of large messages. Claude caches everything up to a hint, so
+// when there are more large messages than breakpoints the latest ones win.
+func markCacheBreakpoints(req *ClaudeRequest) {
+	left := maxCacheBreakpoints
+	if len(req.System) > 0 {
+		req.System[len(req.System)-1].CacheControl = ephemeralCache()
+		left--
+	}
+	for i := len(req.Messages) - 1; i >= 0 && left > 0; i-- {
+		blocks := req.Messages[i].Content
+		if len(blocks) == 0 || claudeBlocksSize(blocks) < PromptCacheMinChars {
+			continue
+		}
+		blocks[len(blocks)-1].CacheControl = ephemeralCache()
+		left--
+	}
+}
+
+// claudeBlocksSize approximates the size of a message's content in chars
+func claudeBlocksSize(blocks []ClaudeBlock) int {
+	n := 0
+	for _, b := range blocks {
+		n += len(b.Text) + len(b.Content)
+		if b.Input != nil {
+			if raw, ok := b.Input.(json.RawMessage); ok {
+				n += len(raw)
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,99 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestClaudeRequestJSONPromptCaching(t *testing.T) {
+	big := strings.Repeat("k", PromptCacheMinChars)
+	msgs := []Message{
+		{Role: "system", Content: "Be brief."},
+		NewTextMessage("user", big),
+		NewTextMessage("assistant", "ok"),
+		NewTextMessage("user", "hi"),
+	}
+	marshal := func(c *Client) string {
+		data, err := json.Marshal(c.buildClaudeRequest(msgs, nil, false, RequestOptions{}))
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		return string(data)
+	}
+
+	uncached := marshal(New("", WithModel("claude-sonnet-4-20250514")))
+	want := `{"model":"claude-sonnet-4-20250514","max_tokens":4096,"system":"Be brief.","messages":[` +
+		`{"role":"user","content":[{"type":"text","text":"` + big + `"}]},` +
+		`{"role":"assistant","content":[{"type":"text","text":"ok"}]},` +
+		`{"role":"user","content":[{"type":"text","text":"hi"}]}]}`
+	if uncached != want {
+		t.Errorf("Unexpected uncached request:\n got %s\nwant %s", uncached, want)
+	}
+
+	cached := marshal(New("", WithModel("claude-sonnet-4-20250514"), WithPromptCaching()))
+	want = `{"model":"claude-sonnet-4-20250514","max_tokens":4096,` +
+		`"system":[{"type":"text","text":"Be brief.","cache_control":{"type":"ephemeral"}}],"messages":[` +
+		`{"role":"user","content":[{"type":"text","text":"` + big + `","cache_control":{"type":"ephemeral"}}]},` +
+		`{"role":"assistant","content":[{"type":"text","text":"ok"}]},` +
+		`{"role":"user","content":[{"type":"text","text":"hi"}]}]}`
+	if cached != want {
+		t.Errorf("Unexpected cached request:\n got %s\nwant %s", cached, want)
+	}
+}
+
+func TestPromptCachingBreakpointLimit(t *testing.T) {
+	big := strings.Repeat("k", PromptCacheMinChars)
+	msgs := []Message{{Role: "system", Content: "sys"}}
+	for i := 0; i < 6; i++ {
+		msgs = append(msgs, NewTextMessage("user", big))
+	}
+	c := New("", WithModel("claude-sonnet-4-20250514"), WithPromptCaching())
+	req := c.buildClaudeRequest(msgs, nil, false, RequestOptions{})
+
+	var marked []int
+	for i, m := range req.Messages {
+		if m.Content[len(m.Content)-1].CacheControl != nil {
+			marked = append(marked, i)
+		}
+	}
+	// The system prompt takes one breakpoint, the latest messages the rest
+	if len(marked) != 3 || marked[0] != 3 || req.System[0].CacheControl == nil {
+		t.Errorf("Expected the system prompt and messages 3-5 marked, got %v", marked)
+	}
+}
+
+func TestClaudeSystemUnmarshal(t *testing.T) {
+	for _, data := range []string{`"sys"`, `[{"type":"text","text":"sys","cache_control":{"type":"ephemeral"}}]`} {
+		var s ClaudeSystem
+		if err := json.Unmarshal([]byte(data), &s); err != nil || s.Text() != "sys" {
+			t.Errorf("Expected %s to parse as sys, got %q, %v", data, s.Text(), err)
+		}
+	}
+}
+
+func TestClaudeCacheUsage(t *testing.T) {
+	resp, err := New("").parseClaudeResponse([]byte(`{"content":[{"type":"text","text":"hi"}],"usage":` +
+		`{"input_tokens":10,"output_tokens":5,"cache_creation_input_tokens":2000,"cache_read_input_tokens":3000}}`))
+	if err != nil {
+		t.Fatalf("parseClaudeResponse failed: %v", err)
+	}
+	want := Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, CacheReadTokens: 3000, CacheWriteTokens: 2000}
+	if resp.Usage != want {
+		t.Errorf("Expected usage %+v, got %+v", want, resp.Usage)
+	}
+
+	s := NewClaudeStreamReader(io.NopCloser(strings.NewReader(`data: {"type":"message_start","message":{"usage":{"input_tokens":10,"output_tokens":1,"cache_read_input_tokens":3000}}}
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":5}}
+data: {"type":"message_stop"}
+`)))
+	if got := drain(t, s); got != "Hello" {
+		t.Errorf("Expected content Hello, got %q", got)
+	}
+	want = Usage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15, CacheReadTokens: 3000}
+	if got := s.Usage(); got != want {
+		t.Errorf("Expected streamed usage %+v, got %+v", want, got)
+	}
+}
@@ -0,0 +1,240 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheTTL is how long cached responses are served when WithCache
+// is given no TTL
+const DefaultCacheTTL = 24 * time.Hour
+
+// CacheMaxBytes caps the size of a response cache directory; the least
+// recently used responses are removed past it
+var CacheMaxBytes int64 = 100 << 20
+
+// WithCache serves repeated requests from responses stored in dir for up
+// to ttl. Only requests sent at temperature 0 are cached, keyed by a hash
+// of the whole request: model, messages, images, tools and options. Both
+// plain and streamed responses are kept; a stream is replayed chunk for
+// chunk. Responses served from the cache report Cached.
+func WithCache(dir string, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.cacheDir = dir
+		c.cacheTTL = ttl
+		if c.cacheTTL <= 0 {
+			c.cacheTTL = DefaultCacheTTL
+		}
+	}
+}
+
+// cacheEntry is one stored response
+type cacheEntry struct {
+	Key         string    `json:"key"` // See requestKey
+	Time        time.Time `json:"time"`
+	Status      int       `json:"status"`
+	ContentType string    `json:"content_type,omitempty"`
+	Body        string    `json:"body"`
+}
+
+type cacheKey struct{}
+
+// cacheLookup marks a request as cacheable and records whether the cache
+// answered it
+type cacheLookup struct {
+	hit bool
+}
+
+// cached reports whether the response came from the cache; false for nil
+func (l *cacheLookup) cached() bool {
+	return l != nil && l.hit
+}
+
+// withCacheLookup returns a context that lets the cache answer requests
+// sent with opts, or ctx and nil when they may not be cached
+func (c *Client) withCacheLookup(ctx context.Context, opts RequestOptions) (context.Context, *cacheLookup) {
+	if c.cacheDir == "" || opts.Temperature == nil || *opts.Temperature != 0 {
+		return ctx, nil
+	}
+	l := &cacheLookup{}
+	return context.WithValue(ctx, cacheKey{}, l), l
+}
+
+// cacheTransport answers cacheable requests from the cache directory and
+// stores successful responses that were read to the end
+type cacheTransport struct {
+	base http.RoundTripper
+	dir  string
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu sync.Mutex // Serializes writes and pruning
+}
+
+func newCacheTransport(base http.RoundTripper, dir string, ttl time.Duration) *cacheTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &cacheTransport{base: base, dir: dir, ttl: ttl, now: time.Now}
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	lookup, _ := req.Context().Value(cacheKey{}).(*cacheLookup)
+	if lookup == nil {
+		return t.base.RoundTrip(req)
+	}
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	// The host is part of the key as endpoints may share a path
+	key := requestKey(req.Method, req.URL.Host+req.URL.Path, body)
+	if entry := t.load(key); entry != nil {
+		lookup.hit = true
+		return entry.response(req), nil
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	contentType := resp.Header.Get("Content-Type")
+	resp.Body = &recordingBody{ReadCloser: resp.Body, done: func(data []byte, eof bool) {
+		if !eof && !streamFinished(req.URL.Path, contentType, data) {
+			return // Cut short, such as a cancelled stream
+		}
+		t.store(&cacheEntry{Key: key, Time: t.now(), Status: resp.StatusCode, ContentType: contentType, Body: string(data)})
+	}}
+	return resp, nil
+}
+
+// streamFinished reports whether an event stream read only up to its last
+// event holds a whole response
+func streamFinished(path, contentType string, data []byte) bool {
+	if !strings.HasPrefix(contentType, "text/event-stream") {
+		return false
+	}
+	_, finishReason, err := collectStream(path, data)
+	return err == nil && finishReason != ""
+}
+
+func (t *cacheTransport) path(key string) string {
+	return filepath.Join(t.dir, key+".json")
+}
+
+// load returns the live entry for key, or nil. Expired and unreadable
+// entries are removed, so the request goes to the network instead.
+func (t *cacheTransport) load(key string) *cacheEntry {
+	path := t.path(key)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key || entry.Status != http.StatusOK {
+		os.Remove(path)
+		return nil
+	}
+	now := t.now()
+	if now.Sub(entry.Time) > t.ttl {
+		os.Remove(path)
+		return nil
+	}
+	// The modification time is the last use, see pruneCacheDir
+	os.Chtimes(path, now, now)
+	return &entry
+}
+
+// store writes entry and prunes the directory. Failures only lose the
+// entry.
+func (t *cacheTransport) store(entry *cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := os.MkdirAll(t.dir, 0700); err != nil {
+		return
+	}
+	tmp, err := os.CreateTemp(t.dir, entry.Key+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), t.path(entry.Key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return
+	}
+	pruneCacheDir(t.dir, CacheMaxBytes)
+}
+
+// pruneCacheDir removes the least recently used entries until dir holds
+// at most maxBytes
+func pruneCacheDir(dir string, maxBytes int64) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	type file struct {
+		name string
+		size int64
+		used time.Time
+	}
+	var files []file
+	var total int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		if info, err := e.Info(); err == nil {
+			files = append(files, file{e.Name(), info.Size(), info.ModTime()})
+			total += info.Size()
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].used.Before(files[j].used) })
+	for _, f := range files {
+		if total <= maxBytes {
+			return
+		}
+		if os.Remove(filepath.Join(dir, f.name)) == nil {
+			total -= f.size
+		}
+	}
+}
+
+// response replays the entry as the answer to req
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	header := make(http.Header)
+	if e.ContentType != "" {
+		header.Set("Content-Type", e.ContentType)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.Status, http.StatusText(e.Status)),
+		StatusCode:    e.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader([]byte(e.Body))),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
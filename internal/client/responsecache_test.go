@@ -0,0 +1,201 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newCacheClient returns a client caching in dir against an upstream that
+// answers with the number of requests it got
+func newCacheClient(t *testing.T, dir string, opts ...Option) (*Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := calls.Add(1)
+		var req ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprintf(w, "data: {\"choices\": [{\"delta\": {\"content\": \"reply %d\"}, \"finish_reason\": \"stop\"}]}\n\ndata: [DONE]\n\n", n)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"r%d","choices":[{"message":{"role":"assistant","content":"reply %d"},"finish_reason":"stop"}],"usage":{"total_tokens":5}}`, n, n)
+	}))
+	t.Cleanup(srv.Close)
+	opts = append([]Option{WithBaseURL(srv.URL), WithRetry(1, time.Millisecond), WithCache(dir, time.Hour)}, opts...)
+	return New("groq-key", opts...), &calls
+}
+
+func completionText(t *testing.T, c *Client, messages []Message, opts ...RequestOptions) (string, bool) {
+	t.Helper()
+	resp, err := c.ChatCompletion(context.Background(), messages, nil, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := resp.Choices[0].Message.Content.(string)
+	return content, resp.Cached
+}
+
+var deterministic = RequestOptions{Temperature: Float(0)}
+
+func TestResponseCacheHitAndMiss(t *testing.T) {
+	c, calls := newCacheClient(t, t.TempDir())
+	messages := []Message{
+		NewTextMessage("user", "summarize the diff"),
+		{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Type: "function", Function: FunctionCall{Name: "Read", Arguments: `{}`}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "diff --git a/x b/x"},
+	}
+
+	first, cached := completionText(t, c, messages, deterministic)
+	if cached {
+		t.Error("Expected the first request to miss")
+	}
+	second, cached := completionText(t, c, messages, deterministic)
+	if !cached || second != first {
+		t.Errorf("Expected %q from the cache, got %q (cached %v)", first, second, cached)
+	}
+
+	// A different tool result is a different request
+	changed := append(messages[:2:2], Message{Role: "tool", ToolCallID: "call_1", Content: "diff --git a/y b/y"})
+	if _, cached := completionText(t, c, changed, deterministic); cached {
+		t.Error("Expected a changed tool result to miss")
+	}
+
+	// Streams are replayed chunk for chunk
+	stream, err := c.ChatCompletionStream(context.Background(), messages, nil, deterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	streamed := readAll(t, stream)
+	if stream.Cached() {
+		t.Error("Expected the first stream to miss")
+	}
+	stream, err = c.ChatCompletionStream(context.Background(), messages, nil, deterministic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readAll(t, stream); !stream.Cached() || got != streamed {
+		t.Errorf("Expected %q replayed, got %q (cached %v)", streamed, got, stream.Cached())
+	}
+
+	if n := calls.Load(); n != 3 {
+		t.Errorf("Expected 3 upstream calls, got %d", n)
+	}
+}
+
+func TestResponseCacheNeedsTemperatureZero(t *testing.T) {
+	dir := t.TempDir()
+	c, calls := newCacheClient(t, dir)
+	messages := []Message{NewTextMessage("user", "hi")}
+
+	for _, opts := range [][]RequestOptions{nil, {{Temperature: Float(0.7)}}} {
+		for range 2 {
+			if _, cached := completionText(t, c, messages, opts...); cached {
+				t.Errorf("Expected no cache hit with %+v", opts)
+			}
+		}
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("Expected every request upstream, got %d", n)
+	}
+	if paths := mustGlob(t, dir); len(paths) != 0 {
+		t.Errorf("Expected nothing stored, got %v", paths)
+	}
+
+	// The client default counts as well
+	c, _ = newCacheClient(t, dir, WithTemperature(0))
+	completionText(t, c, messages)
+	if _, cached := completionText(t, c, messages); !cached {
+		t.Error("Expected a default temperature of 0 to be cached")
+	}
+}
+
+func TestResponseCacheExpiry(t *testing.T) {
+	c, calls := newCacheClient(t, t.TempDir())
+	cache := c.httpClient.Transport.(*cacheTransport)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	messages := []Message{NewTextMessage("user", "hi")}
+
+	completionText(t, c, messages, deterministic)
+	now = now.Add(59 * time.Minute)
+	if _, cached := completionText(t, c, messages, deterministic); !cached {
+		t.Error("Expected a hit within the TTL")
+	}
+	now = now.Add(2 * time.Minute)
+	if _, cached := completionText(t, c, messages, deterministic); cached {
+		t.Error("Expected an expired response to be fetched again")
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("Expected 2 upstream calls, got %d", n)
+	}
+}
+
+func TestResponseCacheCorruptEntry(t *testing.T) {
+	dir := t.TempDir()
+	c, calls := newCacheClient(t, dir)
+	messages := []Message{NewTextMessage("user", "hi")}
+	completionText(t, c, messages, deterministic)
+
+	paths := mustGlob(t, dir)
+	if len(paths) != 1 {
+		t.Fatalf("Expected one entry, got %v", paths)
+	}
+	if err := os.WriteFile(paths[0], []byte(`{"key": "tru`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	got, cached := completionText(t, c, messages, deterministic)
+	if cached || got != "reply 2" || calls.Load() != 2 {
+		t.Errorf("Expected a bad entry to fall through to the network, got %q (cached %v)", got, cached)
+	}
+	if _, cached := completionText(t, c, messages, deterministic); !cached {
+		t.Error("Expected the entry to be stored again")
+	}
+}
+
+func TestPruneCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	// Written newest first, so only the use times order them
+	for i := range 4 {
+		path := filepath.Join(dir, fmt.Sprintf("key%d.json", i))
+		if err := os.WriteFile(path, make([]byte, 100), 0600); err != nil {
+			t.Fatal(err)
+		}
+		used := now.Add(-time.Duration(i) * time.Minute)
+		os.Chtimes(path, used, used)
+	}
+	pruneCacheDir(dir, 250)
+
+	var left []string
+	for _, path := range mustGlob(t, dir) {
+		left = append(left, filepath.Base(path))
+	}
+	if strings.Join(left, " ") != "key0.json key1.json" {
+		t.Errorf("Expected the two most recently used entries kept, got %v", left)
+	}
+}
+
+func TestStreamFinished(t *testing.T) {
+	whole := "data: {\"choices\": [{\"delta\": {\"content\": \"hi\"}, \"finish_reason\": \"stop\"}]}\n\n"
+	cut := "data: {\"choices\": [{\"delta\": {\"content\": \"hi\"}}]}\n\n"
+	if !streamFinished("/v1/chat/completions", "text/event-stream", []byte(whole)) {
+		t.Error("Expected a stream with a finish reason to be whole")
+	}
+	if streamFinished("/v1/chat/completions", "text/event-stream", []byte(cut)) {
+		t.Error("Expected a stream cut short not to be stored")
+	}
+	if streamFinished("/v1/chat/completions", "application/json", []byte(`{"id":`)) {
+		t.Error("Expected a partly read JSON body not to be stored")
+	}
+}
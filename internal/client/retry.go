@@ -0,0 +1,120 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// DefaultMaxAttempts is the number of tries for rate-limited or transient failures
+	DefaultMaxAttempts = 3
+	// DefaultRetryDelay is the base delay for exponential backoff
+	DefaultRetryDelay = 500 * time.Millisecond
+	// maxRetryDelay caps any single wait, including Retry-After
+	maxRetryDelay = 60 * time.Second
+)
+
+// WithRetry sets how many attempts are made for 429 and 5xx responses and
+// network errors, and the base delay for exponential backoff. maxAttempts
+// of 1 disables retries.
+func WithRetry(maxAttempts int, baseDelay time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = maxAttempts
+		c.retryDelay = baseDelay
+	}
+}
+
+// retryableStatus reports whether a response status is worth retrying
+func retryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout, 529: // 529: Anthropic overloaded
+		return true
+	}
+	return false
+}
+
+// backoff returns the jittered exponential delay before the given retry
+func (c *Client) backoff(attempt int) time.Duration {
+	delay := c.retryDelay << (attempt - 1)
+	if delay <= 0 || delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	// Equal jitter: half fixed, half random
+	half := delay / 2
+	return half + rand.N(half+1)
+}
+
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date
+func retryAfter(header string) (time.Duration, bool) {
+	if header == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(header); err == nil && secs >= 0 {
+		return min(time.Duration(secs)*time.Second, maxRetryDelay), true
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return min(max(time.Until(t), 0), maxRetryDelay), true
+	}
+	return 0, false
+}
+
+// do sends req, retrying rate limits, transient server errors and network
+// errors. Other statuses (e.g. 400, 401, 403) are returned immediately.
+// The number of attempts made is returned for error reporting.
+func (c *Client) do(req *http.Request) (*http.Response, int, error) {
+	ctx := req.Context()
+	attempts := max(c.maxAttempts, 1)
+
+	for attempt := 1; ; attempt++ {
+		r := req
+		if attempt > 1 {
+			r = req.Clone(ctx)
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, attempt - 1, err
+				}
+				r.Body = body
+			}
+		}
+
+		resp, err := c.httpClient.Do(r)
+		if err != nil {
+			if ctx.Err() != nil || attempt >= attempts {
+				return nil, attempt, err
+			}
+		} else if !retryableStatus(resp.StatusCode) || attempt >= attempts {
+			return resp, attempt, nil
+		}
+
+		wait := c.backoff(attempt)
+		if resp != nil {
+			if d, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				wait = d
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, attempt, ctx.Err()
+		}
+	}
+}
+
+// withAttempts notes the number of attempts on an error after retries
+func withAttempts(err error, attempts int) error {
+	if attempts <= 1 {
+		return err
+	}
+	return fmt.Errorf("%w (after %d attempts)", err, attempts)
+}
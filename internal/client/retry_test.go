@@ -0,0 +1,101 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first n requests with status, then succeeds
+func flakyServer(t *testing.T, n int32, status int, retryAfter string) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(status)
+			fmt.Fprint(w, `{"error": {"message": "slow down", "type": "rate_limit"}}`)
+			return
+		}
+		if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			fmt.Fprint(w, "data: {\"choices\": [{\"delta\": {\"content\": \"hi\"}, \"finish_reason\": \"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"choices": [{"message": {"role": "assistant", "content": "hi"}, "finish_reason": "stop"}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func TestRetryAfterRateLimit(t *testing.T) {
+	srv, calls := flakyServer(t, 1, http.StatusTooManyRequests, "0")
+	c := New("key", WithBaseURL(srv.URL), WithRetry(3, time.Hour))
+
+	// Retry-After: 0 must win over the (huge) backoff
+	resp, err := c.ChatCompletion(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("ChatCompletion failed: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "hi" || calls.Load() != 2 {
+		t.Errorf("Expected success on second call, got %d calls", calls.Load())
+	}
+}
+
+func TestRetryStream(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable, "")
+	c := New("key", WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+
+	stream, err := c.ChatCompletionStream(context.Background(), []Message{{Role: "user", Content: "hi"}}, nil)
+	if err != nil {
+		t.Fatalf("ChatCompletionStream failed: %v", err)
+	}
+	defer stream.Close()
+	chunk, err := stream.Read()
+	if err != nil || chunk.Choices[0].Delta.Content != "hi" {
+		t.Errorf("Unexpected chunk %+v, err %v", chunk, err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected 3 calls, got %d", calls.Load())
+	}
+}
+
+func TestRetryExhaustedAndFailFast(t *testing.T) {
+	srv, calls := flakyServer(t, 10, http.StatusTooManyRequests, "")
+	c := New("key", WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+	_, err := c.ChatCompletion(context.Background(), nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "after 3 attempts") {
+		t.Errorf("Expected attempt count in error, got %v", err)
+	}
+
+	srv, calls = flakyServer(t, 10, http.StatusUnauthorized, "")
+	c = New("key", WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+	if _, err := c.ChatCompletion(context.Background(), nil, nil); err == nil {
+		t.Error("Expected 401 to fail")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected 401 not to be retried, got %d calls", calls.Load())
+	}
+}
+
+func TestRetryRespectsCancel(t *testing.T) {
+	srv, _ := flakyServer(t, 10, http.StatusTooManyRequests, "30")
+	c := New("key", WithBaseURL(srv.URL), WithRetry(5, time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := c.ChatCompletionStream(ctx, nil, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected context error, got %v", err)
+	}
+	if time.Since(start) > 5*time.Second {
+		t.Error("Cancellation did not interrupt the Retry-After wait")
+	}
+}
@@ -15,6 +15,23 @@ type StreamReader struct {
 	reader   io.ReadCloser
 	scanner  *bufio.Scanner
 	isClaude bool
+	isGemini bool
+	provider string // For errors reported mid-stream, see APIError
+	usage    Usage
+	model    string // Model that served the request, see Model
+	cached   bool   // See Cached
+
+	geminiCalls int // function calls seen so far, used as tool call indexes
+
+	// Claude numbers content blocks, text included; tool calls get dense
+	// indexes in the order their blocks start
+	claudeCalls map[int]*claudeCall // keyed by content block index
+}
+
+// claudeCall tracks a streaming Claude tool_use block
+type claudeCall struct {
+	index    int  // tool call index reported to callers
+	hasInput bool // some input JSON arrived
 }
 
 // NewStreamReader creates a new stream reader
@@ -31,6 +48,9 @@ func (s *StreamReader) Read() (*StreamChunk, error) {
 	if s.isClaude {
 		return s.ReadClaude()
 	}
+	if s.isGemini {
+		return s.ReadGemini()
+	}
 
 	for s.scanner.Scan() {
 		line := s.scanner.Text()
@@ -52,10 +72,19 @@ func (s *StreamReader) Read() (*StreamChunk, error) {
 			return nil, ErrStreamDone
 		}
 
+		if err := s.streamError(data); err != nil {
+			return nil, err
+		}
+
 		var chunk StreamChunk
 		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
 			return nil, err
 		}
+		if chunk.Usage != nil {
+			s.usage = *chunk.Usage
+		} else if chunk.XGroq != nil && chunk.XGroq.Usage != nil {
+			s.usage = *chunk.XGroq.Usage
+		}
 
 		return &chunk, nil
 	}
@@ -67,6 +96,35 @@ func (s *StreamReader) Read() (*StreamChunk, error) {
 	return nil, io.EOF
 }
 
+// streamError returns the *APIError in an event's data, or nil if the
+// event is not an error
+func (s *StreamReader) streamError(data string) error {
+	if !strings.Contains(data, `"error"`) {
+		return nil
+	}
+	if e, ok := parseAPIError(s.provider, []byte(data)); ok {
+		return e
+	}
+	return nil
+}
+
+// Usage returns the token usage reported by the stream. It is complete
+// once Read has returned ErrStreamDone or io.EOF.
+func (s *StreamReader) Usage() Usage {
+	return s.usage
+}
+
+// Model returns the model that is answering, which differs from the
+// client's when it fell back to another; see WithFallbackModels
+func (s *StreamReader) Model() string {
+	return s.model
+}
+
+// Cached reports whether the stream is a replay from WithCache
+func (s *StreamReader) Cached() bool {
+	return s.cached
+}
+
 // Close closes the underlying reader
 func (s *StreamReader) Close() error {
 	return s.reader.Close()
@@ -155,6 +213,7 @@ func NewClaudeStreamReader(reader io.ReadCloser) *StreamReader {
 		reader:   reader,
 		scanner:  bufio.NewScanner(reader),
 		isClaude: true,
+		provider: "anthropic",
 	}
 }
 
@@ -165,6 +224,44 @@ type ClaudeStreamEvent struct {
 	ContentBlock *ClaudeBlock    `json:"content_block,omitempty"`
 	Delta        *ClaudeDelta    `json:"delta,omitempty"`
 	Message      *ClaudeResponse `json:"message,omitempty"`
+	Usage        *ClaudeUsage    `json:"usage,omitempty"` // On message_delta
+}
+
+// ClaudeUsage is the token usage in Claude responses and streaming events
+type ClaudeUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// toUsage converts Claude usage. Claude's input tokens leave out cached
+// tokens, which are reported separately.
+func (u ClaudeUsage) toUsage() Usage {
+	return Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		TotalTokens:      u.InputTokens + u.OutputTokens,
+		CacheReadTokens:  u.CacheReadInputTokens,
+		CacheWriteTokens: u.CacheCreationInputTokens,
+	}
+}
+
+// addClaudeUsage merges Claude usage; output tokens are cumulative
+func (s *StreamReader) addClaudeUsage(u ClaudeUsage) {
+	if u.InputTokens > 0 {
+		s.usage.PromptTokens = u.InputTokens
+	}
+	if u.OutputTokens > 0 {
+		s.usage.CompletionTokens = u.OutputTokens
+	}
+	if u.CacheReadInputTokens > 0 {
+		s.usage.CacheReadTokens = u.CacheReadInputTokens
+	}
+	if u.CacheCreationInputTokens > 0 {
+		s.usage.CacheWriteTokens = u.CacheCreationInputTokens
+	}
+	s.usage.TotalTokens = s.usage.PromptTokens + s.usage.CompletionTokens
 }
 
 // ClaudeDelta represents delta in Claude streaming
@@ -196,57 +293,76 @@ func (s *StreamReader) ReadClaude() (*StreamChunk, error) {
 
 		// Convert Claude events to OpenAI-compatible chunks
 		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				s.addClaudeUsage(event.Message.Usage)
+			}
+
 		case "content_block_delta":
-			if event.Delta != nil {
-				chunk := &StreamChunk{
-					Choices: []Choice{{
-						Delta: &Delta{
-							Content: event.Delta.Text,
-						},
-					}},
-				}
-				// Handle tool input streaming
-				if event.Delta.PartialJSON != "" {
-					chunk.Choices[0].Delta.ToolCalls = []ToolCall{{
-						Index: event.Index,
-						Function: FunctionCall{
-							Arguments: event.Delta.PartialJSON,
-						},
-					}}
+			if event.Delta == nil {
+				continue
+			}
+			switch event.Delta.Type {
+			case "text_delta":
+				return &StreamChunk{Choices: []Choice{{Delta: &Delta{Content: event.Delta.Text}}}}, nil
+			case "input_json_delta":
+				call, ok := s.claudeCalls[event.Index]
+				if !ok || event.Delta.PartialJSON == "" {
+					continue
 				}
-				return chunk, nil
+				call.hasInput = true
+				return claudeToolChunk(ToolCall{
+					Index:    call.index,
+					Function: FunctionCall{Arguments: event.Delta.PartialJSON},
+				}), nil
 			}
 
 		case "content_block_start":
 			if event.ContentBlock != nil && event.ContentBlock.Type == "tool_use" {
-				chunk := &StreamChunk{
-					Choices: []Choice{{
-						Delta: &Delta{
-							ToolCalls: []ToolCall{{
-								Index: event.Index,
-								ID:    event.ContentBlock.ID,
-								Type:  "function",
-								Function: FunctionCall{
-									Name: event.ContentBlock.Name,
-								},
-							}},
-						},
-					}},
+				if s.claudeCalls == nil {
+					s.claudeCalls = make(map[int]*claudeCall)
 				}
-				return chunk, nil
+				call := &claudeCall{index: len(s.claudeCalls)}
+				s.claudeCalls[event.Index] = call
+				return claudeToolChunk(ToolCall{
+					Index:    call.index,
+					ID:       event.ContentBlock.ID,
+					Type:     "function",
+					Function: FunctionCall{Name: event.ContentBlock.Name},
+				}), nil
+			}
+
+		case "content_block_stop":
+			// A call without arguments streams no input; give it an
+			// empty object so it can be sent back as valid JSON
+			if call, ok := s.claudeCalls[event.Index]; ok && !call.hasInput {
+				call.hasInput = true
+				return claudeToolChunk(ToolCall{
+					Index:    call.index,
+					Function: FunctionCall{Arguments: "{}"},
+				}), nil
 			}
 
 		case "message_delta":
+			if event.Usage != nil {
+				s.addClaudeUsage(*event.Usage)
+			}
 			if event.Delta != nil && event.Delta.StopReason != "" {
 				return &StreamChunk{
 					Choices: []Choice{{
-						FinishReason: event.Delta.StopReason,
+						FinishReason: claudeFinishReason(event.Delta.StopReason),
 					}},
 				}, nil
 			}
 
 		case "message_stop":
 			return nil, ErrStreamDone
+
+		case "error":
+			// e.g. overloaded_error after the response started
+			if err := s.streamError(data); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -256,3 +372,84 @@ func (s *StreamReader) ReadClaude() (*StreamChunk, error) {
 	return nil, io.EOF
 }
 
+// claudeToolChunk wraps a tool call delta in a chunk
+func claudeToolChunk(tc ToolCall) *StreamChunk {
+	return &StreamChunk{Choices: []Choice{{Delta: &Delta{ToolCalls: []ToolCall{tc}}}}}
+}
+
+// claudeFinishReason maps Claude stop reasons to OpenAI finish reasons
+func claudeFinishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence":
+		return "stop"
+	case "tool_use":
+		return "tool_calls"
+	case "max_tokens":
+		return "length"
+	default:
+		return reason
+	}
+}
+
+// NewGeminiStreamReader creates a Gemini-specific stream reader
+func NewGeminiStreamReader(reader io.ReadCloser) *StreamReader {
+	return &StreamReader{
+		reader:   reader,
+		scanner:  bufio.NewScanner(reader),
+		isGemini: true,
+		provider: "gemini",
+	}
+}
+
+// ReadGemini reads Gemini's streaming format and converts to OpenAI format.
+// Gemini sends whole function calls in one chunk and ends the stream without
+// a sentinel, so the end is reported as io.EOF.
+func (s *StreamReader) ReadGemini() (*StreamChunk, error) {
+	for s.scanner.Scan() {
+		line := s.scanner.Text()
+
+		if line == "" || !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		data := strings.TrimPrefix(line, "data: ")
+		if err := s.streamError(data); err != nil {
+			return nil, err
+		}
+
+		var resp GeminiResponse
+		if err := json.Unmarshal([]byte(data), &resp); err != nil {
+			return nil, err
+		}
+		if resp.UsageMetadata != nil {
+			s.usage = resp.UsageMetadata.usage()
+		}
+		if len(resp.Candidates) == 0 {
+			continue
+		}
+
+		cand := resp.Candidates[0]
+		delta := &Delta{}
+		for _, part := range cand.Content.Parts {
+			if part.FunctionCall != nil {
+				delta.ToolCalls = append(delta.ToolCalls, geminiToolCall(s.geminiCalls, part.FunctionCall))
+				s.geminiCalls++
+			} else {
+				delta.Content += part.Text
+			}
+		}
+
+		return &StreamChunk{
+			Model: resp.ModelVersion,
+			Choices: []Choice{{
+				Delta:        delta,
+				FinishReason: geminiFinishReason(cand.FinishReason, s.geminiCalls > 0),
+			}},
+		}, nil
+	}
+
+	if err := s.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
@@ -0,0 +1,123 @@
+package client
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"testing"
+)
+
+func drain(t *testing.T, s *StreamReader) string {
+	t.Helper()
+	var content strings.Builder
+	for {
+		chunk, err := s.Read()
+		if err == ErrStreamDone || err == io.EOF {
+			return content.String()
+		}
+		if err != nil {
+			t.Fatalf("Read failed: %v", err)
+		}
+		for _, c := range chunk.Choices {
+			if c.Delta != nil {
+				content.WriteString(c.Delta.Content)
+			}
+		}
+	}
+}
+
+func TestStreamUsage(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream string
+		claude bool
+		want   Usage
+	}{
+		{
+			name: "openai include_usage",
+			stream: `data: {"choices":[{"delta":{"content":"Hel"}}]}
+data: {"choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}
+data: {"choices":[],"usage":{"prompt_tokens":12,"completion_tokens":3,"total_tokens":15}}
+data: [DONE]
+`,
+			want: Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15},
+		},
+		{
+			name: "groq x_groq",
+			stream: `data: {"choices":[{"delta":{"content":"Hello"}}]}
+data: {"choices":[{"delta":{},"finish_reason":"stop"}],"x_groq":{"usage":{"prompt_tokens":20,"completion_tokens":5,"total_tokens":25}}}
+data: [DONE]
+`,
+			want: Usage{PromptTokens: 20, CompletionTokens: 5, TotalTokens: 25},
+		},
+		{
+			name:   "claude",
+			claude: true,
+			stream: `event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":30,"output_tokens":1}}}
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hello"}}
+data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":7}}
+data: {"type":"message_stop"}
+`,
+			want: Usage{PromptTokens: 30, CompletionTokens: 7, TotalTokens: 37},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := io.NopCloser(strings.NewReader(tt.stream))
+			s := NewStreamReader(body)
+			if tt.claude {
+				s = NewClaudeStreamReader(body)
+			}
+			if got := drain(t, s); got != "Hello" {
+				t.Errorf("Expected content Hello, got %q", got)
+			}
+			if got := s.Usage(); got != tt.want {
+				t.Errorf("Expected usage %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestClaudeStreamToolCalls(t *testing.T) {
+	f, err := os.Open("testdata/claude_tools.sse")
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	s := NewClaudeStreamReader(f)
+	defer s.Close()
+
+	msg, finish, err := s.CollectResponse()
+	if err != nil {
+		t.Fatalf("CollectResponse failed: %v", err)
+	}
+	if finish != "tool_calls" {
+		t.Errorf("Expected finish reason tool_calls, got %q", finish)
+	}
+	if msg.Content != "Let me look at both files." {
+		t.Errorf("Expected the text before the calls, got %q", msg.Content)
+	}
+
+	// The text block takes content block 0, so the calls must be renumbered
+	want := []ToolCall{
+		{ID: "toolu_01A", Type: "function", Function: FunctionCall{Name: "Read", Arguments: `{"file_path": "main.go"}`}},
+		{ID: "toolu_01B", Type: "function", Function: FunctionCall{Name: "Glob", Arguments: `{"pattern": "**/*.go"}`}},
+		{ID: "toolu_01C", Type: "function", Function: FunctionCall{Name: "GitStatus", Arguments: `{}`}},
+	}
+	if len(msg.ToolCalls) != len(want) {
+		t.Fatalf("Expected %d tool calls, got %+v", len(want), msg.ToolCalls)
+	}
+	for i, w := range want {
+		if got := msg.ToolCalls[i]; got.ID != w.ID || got.Function != w.Function {
+			t.Errorf("Call %d: expected %+v, got %+v", i, w, got)
+		}
+		if !json.Valid([]byte(msg.ToolCalls[i].Function.Arguments)) {
+			t.Errorf("Call %d: arguments are not valid JSON: %s", i, msg.ToolCalls[i].Function.Arguments)
+		}
+	}
+	if got := s.Usage(); got.PromptTokens != 412 || got.CompletionTokens != 96 {
+		t.Errorf("Unexpected usage %+v", got)
+	}
+}
@@ -0,0 +1,141 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DefaultTranscriptionModel is the Whisper model Groq transcribes with
+// when none is given
+const DefaultTranscriptionModel = "whisper-large-v3"
+
+// TranscriptionModels are the Whisper models Groq hosts
+var TranscriptionModels = []string{"whisper-large-v3", "whisper-large-v3-turbo"}
+
+// ErrNoTranscriptionKey means no Groq API key is configured to transcribe
+// with
+var ErrNoTranscriptionKey = errors.New("no API key configured for transcription (set GROQ_API_KEY)")
+
+// MaxTranscriptionBytes is the largest audio file Groq accepts
+const MaxTranscriptionBytes = 25 << 20
+
+// transcriptionExts are the audio formats Groq transcribes
+var transcriptionExts = []string{".flac", ".mp3", ".mp4", ".mpeg", ".mpga", ".m4a", ".ogg", ".opus", ".wav", ".webm"}
+
+// CanTranscribe reports whether Groq transcribes the audio format of
+// filename, judged by its extension
+func CanTranscribe(filename string) bool {
+	return slices.Contains(transcriptionExts, strings.ToLower(filepath.Ext(filename)))
+}
+
+// TranscribeOptions adjust a transcription request
+type TranscribeOptions struct {
+	// Timestamps asks for response_format verbose_json, which adds the
+	// detected language, the duration and timed segments
+	Timestamps bool
+	// Prompt guides spelling and style, such as names in the audio
+	Prompt string
+}
+
+// Transcription is the text of an audio file. The fields follow Groq's
+// verbose_json response; Language, Duration and Segments are only set
+// with TranscribeOptions.Timestamps.
+type Transcription struct {
+	Text     string                 `json:"text"`
+	Language string                 `json:"language,omitempty"`
+	Duration float64                `json:"duration,omitempty"` // Seconds
+	Segments []TranscriptionSegment `json:"segments,omitempty"`
+}
+
+// TranscriptionSegment is a stretch of the audio, timed in seconds
+type TranscriptionSegment struct {
+	ID    int     `json:"id"`
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// Transcribe turns audio into text with Groq's Whisper models. filename
+// tells Groq the audio format by its extension. An empty model uses
+// DefaultTranscriptionModel and an empty language is detected.
+func (c *Client) Transcribe(ctx context.Context, audio []byte, filename, model, language string, opts ...TranscribeOptions) (*Transcription, error) {
+	c = c.snapshot()
+	apiKey := c.providerKeys["groq"]
+	if apiKey == "" {
+		return nil, ErrNoTranscriptionKey
+	}
+	if !CanTranscribe(filename) {
+		return nil, fmt.Errorf("unsupported audio format %q (supported: %s)", filepath.Ext(filename), strings.Join(transcriptionExts, " "))
+	}
+	if len(audio) == 0 {
+		return nil, fmt.Errorf("audio is empty")
+	}
+	if len(audio) > MaxTranscriptionBytes {
+		return nil, fmt.Errorf("audio is %d bytes; the limit is %d", len(audio), MaxTranscriptionBytes)
+	}
+	if model == "" {
+		model = DefaultTranscriptionModel
+	}
+	var o TranscribeOptions
+	for _, opt := range opts {
+		o.Timestamps = o.Timestamps || opt.Timestamps
+		if opt.Prompt != "" {
+			o.Prompt = opt.Prompt
+		}
+	}
+
+	format := "json"
+	if o.Timestamps {
+		format = "verbose_json"
+	}
+	// Writes to a bytes.Buffer cannot fail
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", filename)
+	fw.Write(audio)
+	mw.WriteField("model", model)
+	mw.WriteField("response_format", format)
+	if language != "" {
+		mw.WriteField("language", language)
+	}
+	if o.Prompt != "" {
+		mw.WriteField("prompt", o.Prompt)
+	}
+	mw.Close()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/audio/transcriptions", bytes.NewReader(body.Bytes()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", mw.FormDataContentType())
+	httpReq.Header.Set("Authorization", "Bearer "+apiKey)
+
+	resp, attempts, err := c.do(httpReq)
+	if err != nil {
+		return nil, withAttempts(fmt.Errorf("failed to send request: %w", err), attempts)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, withAttempts(responseError("groq", resp, respBody), attempts)
+	}
+
+	var result Transcription
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &result, nil
+}
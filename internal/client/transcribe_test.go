@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// transcribeStub answers like Groq's transcription endpoint and records
+// the form of the last request
+type transcribeStub struct {
+	auth, path string
+	fields     map[string]string
+	filename   string
+	audio      string
+}
+
+func (s *transcribeStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.auth, s.path = r.Header.Get("Authorization"), r.URL.Path
+	if err := r.ParseMultipartForm(1 << 20); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.fields = make(map[string]string)
+	for name, values := range r.MultipartForm.Value {
+		s.fields[name] = values[0]
+	}
+	if files := r.MultipartForm.File["file"]; len(files) == 1 {
+		s.filename = files[0].Filename
+		f, _ := files[0].Open()
+		data, _ := io.ReadAll(f)
+		f.Close()
+		s.audio = string(data)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if s.fields["response_format"] == "verbose_json" {
+		fmt.Fprint(w, `{"task": "transcribe", "language": "japanese", "duration": 3.5, "text": "こんにちは。元気？",
+			"segments": [{"id": 0, "start": 0, "end": 1.5, "text": "こんにちは。"}, {"id": 1, "start": 1.5, "end": 3.5, "text": "元気？"}]}`)
+		return
+	}
+	fmt.Fprint(w, `{"text": "hello there"}`)
+}
+
+func TestTranscribe(t *testing.T) {
+	stub := &transcribeStub{}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	c := New("groq-key", WithBaseURL(srv.URL))
+
+	got, err := c.Transcribe(context.Background(), []byte("RIFF fake wav"), "memo.wav", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Text != "hello there" || got.Segments != nil {
+		t.Errorf("Unexpected transcription %+v", got)
+	}
+	if stub.path != "/audio/transcriptions" || stub.auth != "Bearer groq-key" {
+		t.Errorf("Unexpected request to %s with %q", stub.path, stub.auth)
+	}
+	if stub.filename != "memo.wav" || stub.audio != "RIFF fake wav" {
+		t.Errorf("Expected the audio as memo.wav, got %q: %q", stub.filename, stub.audio)
+	}
+	want := map[string]string{"model": DefaultTranscriptionModel, "response_format": "json"}
+	if fmt.Sprint(stub.fields) != fmt.Sprint(want) {
+		t.Errorf("Expected fields %v without a language, got %v", want, stub.fields)
+	}
+}
+
+func TestTranscribeTimestamps(t *testing.T) {
+	stub := &transcribeStub{}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	c := New("groq-key", WithBaseURL(srv.URL))
+
+	got, err := c.Transcribe(context.Background(), []byte("ID3"), "memo.mp3", "whisper-large-v3-turbo", "ja",
+		TranscribeOptions{Timestamps: true, Prompt: "Greetings"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"model": "whisper-large-v3-turbo", "response_format": "verbose_json", "language": "ja", "prompt": "Greetings"}
+	if fmt.Sprint(stub.fields) != fmt.Sprint(want) {
+		t.Errorf("Expected fields %v, got %v", want, stub.fields)
+	}
+	if got.Language != "japanese" || got.Duration != 3.5 || len(got.Segments) != 2 || got.Segments[1].Start != 1.5 || got.Segments[1].Text != "元気？" {
+		t.Errorf("Unexpected transcription %+v", got)
+	}
+}
+
+func TestTranscribeErrors(t *testing.T) {
+	srv, calls := flakyServer(t, 5, http.StatusBadRequest, "")
+	c := New("groq-key", WithBaseURL(srv.URL), WithRetry(3, time.Millisecond))
+
+	if _, err := c.Transcribe(context.Background(), []byte("data"), "memo.wav", "", ""); err == nil || !strings.Contains(err.Error(), "slow down") {
+		t.Errorf("Expected the provider's error, got %v", err)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected a rejected request not to be retried, got %d calls", calls.Load())
+	}
+	if _, err := c.Transcribe(context.Background(), nil, "memo.wav", "", ""); err == nil {
+		t.Error("Expected empty audio to fail")
+	}
+	if _, err := New("").Transcribe(context.Background(), []byte("data"), "memo.wav", "", ""); err == nil || !strings.Contains(err.Error(), "GROQ_API_KEY") {
+		t.Errorf("Expected a missing key error, got %v", err)
+	}
+}
@@ -1,6 +1,13 @@
 package client
 
-import "encoding/json"
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
 
 // Message represents a chat message
 type Message struct {
@@ -23,6 +30,99 @@ type ImageURL struct {
 	Detail string `json:"detail,omitempty"` // "low", "high", or "auto"
 }
 
+// messageJSON is Message without its JSON methods
+type messageJSON Message
+
+// MarshalJSON writes Content as a plain string when it is a single text
+// part, since some OpenAI-compatible APIs reject arrays for text-only
+// messages, and as an array of parts otherwise
+func (m Message) MarshalJSON() ([]byte, error) {
+	if parts, ok := m.Content.([]ContentPart); ok && len(parts) == 1 && parts[0].Type == "text" {
+		m.Content = parts[0].Text
+	}
+	return json.Marshal(messageJSON(m))
+}
+
+// UnmarshalJSON decodes Content into a string or []ContentPart, so
+// messages read back from storage or the network keep their types
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		messageJSON
+		Content json.RawMessage `json:"content,omitempty"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*m = Message(raw.messageJSON)
+
+	content := bytes.TrimSpace(raw.Content)
+	switch {
+	case len(content) == 0 || bytes.Equal(content, []byte("null")):
+		m.Content = nil
+	case content[0] == '"':
+		var s string
+		if err := json.Unmarshal(content, &s); err != nil {
+			return err
+		}
+		m.Content = s
+	case content[0] == '[':
+		var parts []ContentPart
+		if err := json.Unmarshal(content, &parts); err != nil {
+			return err
+		}
+		m.Content = parts
+	default:
+		return fmt.Errorf("message content must be a string or an array of parts")
+	}
+	return nil
+}
+
+// Text returns the text of the message, joining text parts with newlines
+func (m Message) Text() string {
+	switch c := m.Content.(type) {
+	case string:
+		return c
+	case []ContentPart:
+		var texts []string
+		for _, part := range c {
+			if part.Type == "text" && part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+		return strings.Join(texts, "\n")
+	}
+	return ""
+}
+
+// ParseDataURL splits a base64 data URL ("data:<mime>;base64,<data>")
+// into its media type and data
+func ParseDataURL(url string) (mediaType, data string, ok bool) {
+	rest, ok := strings.CutPrefix(url, "data:")
+	if !ok {
+		return "", "", false
+	}
+	meta, data, ok := strings.Cut(rest, ",")
+	if !ok {
+		return "", "", false
+	}
+	mediaType, ok = strings.CutSuffix(meta, ";base64")
+	return mediaType, data, ok
+}
+
+// ImageMediaType returns the media type of a base64 image, sniffed from its
+// first bytes so mislabeled data URLs still get the right type. declared is
+// used when the data is not a recognized image.
+func ImageMediaType(declared, data string) string {
+	head := data[:min(len(data), 24)]
+	head = head[:len(head)/4*4]
+	if raw, err := base64.StdEncoding.DecodeString(head); err == nil {
+		if sniffed := http.DetectContentType(raw); strings.HasPrefix(sniffed, "image/") {
+			return sniffed
+		}
+	}
+	return declared
+}
+
 // NewTextMessage creates a simple text message
 func NewTextMessage(role, content string) Message {
 	return Message{Role: role, Content: content}
@@ -75,7 +175,14 @@ type ChatCompletionRequest struct {
 	ToolChoice  string    `json:"tool_choice,omitempty"`
 	Stream      bool      `json:"stream"`
 	MaxTokens   int       `json:"max_tokens,omitempty"`
-	Temperature float64   `json:"temperature,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"` // nil leaves the provider default; 0 is valid
+
+	StreamOptions *StreamOptions `json:"stream_options,omitempty"`
+}
+
+// StreamOptions asks the API to send a final usage chunk when streaming
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 // ChatCompletionResponse represents the response from the chat completions API
@@ -86,6 +193,9 @@ type ChatCompletionResponse struct {
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
 	Usage   Usage    `json:"usage"`
+
+	// Cached is set when the response was served by WithCache
+	Cached bool `json:"-"`
 }
 
 // Choice represents a single choice in the response
@@ -108,6 +218,19 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// Prompt caching, reported by Claude apart from PromptTokens
+	CacheReadTokens  int `json:"cache_read_tokens,omitempty"`
+	CacheWriteTokens int `json:"cache_write_tokens,omitempty"`
+}
+
+// Add accumulates usage from another request, e.g. across tool rounds
+func (u *Usage) Add(other Usage) {
+	u.PromptTokens += other.PromptTokens
+	u.CompletionTokens += other.CompletionTokens
+	u.TotalTokens += other.TotalTokens
+	u.CacheReadTokens += other.CacheReadTokens
+	u.CacheWriteTokens += other.CacheWriteTokens
 }
 
 // StreamChunk represents a single chunk in SSE streaming
@@ -117,18 +240,12 @@ type StreamChunk struct {
 	Created int64    `json:"created"`
 	Model   string   `json:"model"`
 	Choices []Choice `json:"choices"`
-}
-
-// ErrorResponse represents an API error
-type ErrorResponse struct {
-	Error APIError `json:"error"`
-}
+	Usage   *Usage   `json:"usage,omitempty"` // Final chunk with include_usage
 
-// APIError contains error details
-type APIError struct {
-	Message string `json:"message"`
-	Type    string `json:"type"`
-	Code    string `json:"code"`
+	// Groq reports usage in its own extension field
+	XGroq *struct {
+		Usage *Usage `json:"usage,omitempty"`
+	} `json:"x_groq,omitempty"`
 }
 
 // ParseToolCallArguments parses the JSON arguments of a tool call
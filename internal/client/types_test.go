@@ -0,0 +1,75 @@
+package client
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMessageJSONRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  Message
+		want string // encoded content
+	}{
+		{"string", NewTextMessage("user", "hello"), `"hello"`},
+		{"single text part", Message{Role: "user", Content: []ContentPart{{Type: "text", Text: "hi"}}}, `"hi"`},
+		{"vision", NewVisionMessage("user", "look", "data:image/png;base64,iVBORw0KGgo="),
+			`[{"type":"text","text":"look"},{"type":"image_url","image_url":{"url":"data:image/png;base64,iVBORw0KGgo=","detail":"auto"}}]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.msg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var encoded struct {
+				Content json.RawMessage `json:"content"`
+			}
+			json.Unmarshal(data, &encoded)
+			if string(encoded.Content) != tt.want {
+				t.Errorf("Expected content %s, got %s", tt.want, encoded.Content)
+			}
+
+			var decoded Message
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if decoded.Role != tt.msg.Role || decoded.Text() != tt.msg.Text() || decoded.HasImages() != tt.msg.HasImages() {
+				t.Errorf("Round trip changed the message: %+v -> %+v", tt.msg, decoded)
+			}
+			if parts, ok := tt.msg.Content.([]ContentPart); ok && len(parts) > 1 && !reflect.DeepEqual(decoded.Content, parts) {
+				t.Errorf("Expected parts %+v, got %+v", parts, decoded.Content)
+			}
+		})
+	}
+}
+
+func TestMessageJSONKeepsOtherFields(t *testing.T) {
+	msg := Message{Role: "assistant", ToolCalls: []ToolCall{{ID: "call_1", Type: "function",
+		Function: FunctionCall{Name: "Read", Arguments: `{"path":"a"}`}}}}
+	data, _ := json.Marshal(msg)
+	var decoded Message
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded.Content != nil || !reflect.DeepEqual(decoded.ToolCalls, msg.ToolCalls) {
+		t.Errorf("Expected tool calls without content, got %+v", decoded)
+	}
+
+	if err := json.Unmarshal([]byte(`{"role":"user","content":{"bad":true}}`), &decoded); err == nil {
+		t.Error("Expected an error for object content")
+	}
+}
+
+func TestParseDataURL(t *testing.T) {
+	mediaType, data, ok := ParseDataURL("data:image/jpeg;base64,/9j/4AAQ")
+	if !ok || mediaType != "image/jpeg" || data != "/9j/4AAQ" {
+		t.Errorf("Unexpected result: %q %q %v", mediaType, data, ok)
+	}
+	for _, url := range []string{"https://example.com/a.png", "data:image/png,raw", "data:image/png;base64"} {
+		if _, _, ok := ParseDataURL(url); ok {
+			t.Errorf("Expected %q to be rejected", url)
+		}
+	}
+}
@@ -1,31 +1,504 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
 	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+
+	"groq-go/internal/client"
+	"groq-go/internal/origin"
+	"groq-go/internal/tool"
 )
 
 // Config holds the application configuration
 type Config struct {
-	APIKey        string `mapstructure:"api_key"`
-	Model         string `mapstructure:"model"`
-	MoonshotKey   string `mapstructure:"moonshot_api_key"`
-	OpenAIKey     string `mapstructure:"openai_api_key"`
-	ClaudeKey     string `mapstructure:"claude_api_key"`
+	APIKey      string      `mapstructure:"api_key" yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	Model       string      `mapstructure:"model" yaml:"model,omitempty" json:"model,omitempty"`
+	MoonshotKey string      `mapstructure:"moonshot_api_key" yaml:"moonshot_api_key,omitempty" json:"moonshot_api_key,omitempty"`
+	OpenAIKey   string      `mapstructure:"openai_api_key" yaml:"openai_api_key,omitempty" json:"openai_api_key,omitempty"`
+	ClaudeKey   string      `mapstructure:"claude_api_key" yaml:"claude_api_key,omitempty" json:"claude_api_key,omitempty"`
+	GeminiKey   string      `mapstructure:"gemini_api_key" yaml:"gemini_api_key,omitempty" json:"gemini_api_key,omitempty"`
+	AutoFormat  bool        `mapstructure:"auto_format" yaml:"auto_format,omitempty" json:"auto_format,omitempty"`
+	Autosave    bool        `mapstructure:"autosave" yaml:"autosave,omitempty" json:"autosave,omitempty"`
+	ContextSize int         `mapstructure:"context_tokens" yaml:"context_tokens,omitempty" json:"context_tokens,omitempty"`
+	Tools       tool.Policy `mapstructure:"tools" yaml:"tools,omitempty" json:"tools,omitempty"`
+	// FormatDataFiles makes auto_format reformat JSON and YAML files too
+	FormatDataFiles bool `mapstructure:"format_data_files" yaml:"format_data_files,omitempty" json:"format_data_files,omitempty"`
+	// PromptCaching marks Claude prompts cacheable, see client.WithPromptCaching
+	PromptCaching bool `mapstructure:"prompt_caching" yaml:"prompt_caching,omitempty" json:"prompt_caching,omitempty"`
+	// GrepMaxFileSize is the largest file, in bytes, Grep searches in a directory
+	GrepMaxFileSize int64 `mapstructure:"grep_max_file_size" yaml:"grep_max_file_size,omitempty" json:"grep_max_file_size,omitempty"`
+	// ToolResultMaxBytes caps one tool result sent to the model, see tool.MaxResultBytes
+	ToolResultMaxBytes int `mapstructure:"tool_result_max_bytes" yaml:"tool_result_max_bytes,omitempty" json:"tool_result_max_bytes,omitempty"`
+	// ToolTurnMaxBytes caps the tool results of one turn, see tool.MaxTurnResultBytes
+	ToolTurnMaxBytes int `mapstructure:"tool_turn_max_bytes" yaml:"tool_turn_max_bytes,omitempty" json:"tool_turn_max_bytes,omitempty"`
+	// SandboxDisabled lets file tools reach outside the current project's root
+	SandboxDisabled bool `mapstructure:"sandbox_disabled" yaml:"sandbox_disabled,omitempty" json:"sandbox_disabled,omitempty"`
+	// Endpoints are OpenAI-compatible servers such as Ollama, see EndpointConfig
+	Endpoints []EndpointConfig `mapstructure:"endpoints" yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+	// Models adds entries to the model catalog, see ModelEntry
+	Models []ModelEntry `mapstructure:"models" yaml:"models,omitempty" json:"models,omitempty"`
+	// Temperature is the default sampling temperature, see client.WithTemperature
+	Temperature *float64 `mapstructure:"temperature" yaml:"temperature,omitempty" json:"temperature,omitempty"`
+	// FallbackModels are tried in order when the model's provider is down
+	FallbackModels []string `mapstructure:"fallback_models" yaml:"fallback_models,omitempty" json:"fallback_models,omitempty"`
+	// SystemPromptAppend is added to every system prompt, see conversation.LoadPrompt
+	SystemPromptAppend string `mapstructure:"system_prompt_append" yaml:"system_prompt_append,omitempty" json:"system_prompt_append,omitempty"`
+
+	Knowledge   KnowledgeConfig   `mapstructure:"knowledge" yaml:"knowledge,omitempty" json:"knowledge,omitempty"`
+	Web         WebConfig         `mapstructure:"web" yaml:"web,omitempty" json:"web,omitempty"`
+	TTS         TTSConfig         `mapstructure:"tts" yaml:"tts,omitempty" json:"tts,omitempty"`
+	SelfImprove SelfImproveConfig `mapstructure:"self_improve" yaml:"self_improve,omitempty" json:"self_improve,omitempty"`
+	ReadOnly    ReadOnlyConfig    `mapstructure:"read_only" yaml:"read_only,omitempty" json:"read_only,omitempty"`
+	Approval    ApprovalConfig    `mapstructure:"approval" yaml:"approval,omitempty" json:"approval,omitempty"`
+	Cache       CacheConfig       `mapstructure:"response_cache" yaml:"response_cache,omitempty" json:"response_cache,omitempty"`
+	MCP         MCPConfig         `mapstructure:"mcp" yaml:"mcp,omitempty" json:"mcp,omitempty"`
+
+	// Notifications sends events about builds, deploys and scheduled jobs
+	// to webhooks, see notify.Dispatcher
+	Notifications NotificationsConfig `mapstructure:"notifications" yaml:"notifications,omitempty" json:"notifications,omitempty"`
+}
+
+// ModelEntry adds a model to client.Catalog, or changes a built-in one,
+// so new or self-hosted models are routed to the right provider
+type ModelEntry struct {
+	Name          string `mapstructure:"name" yaml:"name" json:"name"`
+	Provider      string `mapstructure:"provider" yaml:"provider" json:"provider"`
+	ContextWindow int    `mapstructure:"context_window" yaml:"context_window,omitempty" json:"context_window,omitempty"`
+	Vision        bool   `mapstructure:"vision" yaml:"vision,omitempty" json:"vision,omitempty"`
+	Tools         *bool  `mapstructure:"tools" yaml:"tools,omitempty" json:"tools,omitempty"` // Unset means the model supports tools
+	CostHint      int    `mapstructure:"cost_hint" yaml:"cost_hint,omitempty" json:"cost_hint,omitempty"`
+}
+
+// Info returns the catalog entry for e
+func (e ModelEntry) Info() client.ModelInfo {
+	return client.ModelInfo{
+		Name:          e.Name,
+		Provider:      e.Provider,
+		ContextWindow: e.ContextWindow,
+		Vision:        e.Vision,
+		Tools:         e.Tools == nil || *e.Tools,
+		CostHint:      e.CostHint,
+	}
+}
+
+// EndpointConfig is an OpenAI-compatible server, such as Ollama, vLLM or
+// LM Studio, that serves Models under the provider Name
+type EndpointConfig struct {
+	Name    string   `mapstructure:"name" yaml:"name" json:"name"`
+	BaseURL string   `mapstructure:"base_url" yaml:"base_url" json:"base_url"`
+	APIKey  string   `mapstructure:"api_key" yaml:"api_key,omitempty" json:"api_key,omitempty"`
+	Models  []string `mapstructure:"models" yaml:"models" json:"models"`
+}
+
+// Register adds the endpoint's provider and models to catalog
+func (e EndpointConfig) Register(catalog *client.ModelCatalog) error {
+	if err := catalog.AddProvider(e.Name); err != nil {
+		return err
+	}
+	for _, name := range e.Models {
+		if err := catalog.Add(client.ModelInfo{Name: name, Provider: e.Name, Tools: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// endpointsFromEnv reads CUSTOM_BASE_URL, CUSTOM_API_KEY and CUSTOM_MODELS
+// as the endpoint "custom", then CUSTOM_1_BASE_URL and so on as "custom-1"
+// until a base URL is missing
+func endpointsFromEnv() []EndpointConfig {
+	var endpoints []EndpointConfig
+	read := func(name, prefix string) bool {
+		baseURL := os.Getenv(prefix + "BASE_URL")
+		if baseURL == "" {
+			return false
+		}
+		endpoints = append(endpoints, EndpointConfig{
+			Name:    name,
+			BaseURL: baseURL,
+			APIKey:  os.Getenv(prefix + "API_KEY"),
+			Models:  cleanList(strings.Split(os.Getenv(prefix+"MODELS"), ",")),
+		})
+		return true
+	}
+	read(client.CustomProvider, "CUSTOM_")
+	for i := 1; ; i++ {
+		if !read(fmt.Sprintf("%s-%d", client.CustomProvider, i), fmt.Sprintf("CUSTOM_%d_", i)) {
+			break
+		}
+	}
+	return endpoints
+}
+
+// ParseModelList parses EXTRA_MODELS: comma-separated name=provider pairs
+func ParseModelList(s string) ([]ModelEntry, error) {
+	var entries []ModelEntry
+	for _, item := range cleanList(strings.Split(s, ",")) {
+		name, provider, ok := strings.Cut(item, "=")
+		name, provider = strings.TrimSpace(name), strings.TrimSpace(provider)
+		if !ok || name == "" || provider == "" {
+			return nil, fmt.Errorf("EXTRA_MODELS entry %q must be name=provider", item)
+		}
+		entries = append(entries, ModelEntry{Name: name, Provider: provider})
+	}
+	return entries, nil
+}
+
+// KnowledgeConfig sets how knowledge base documents are chunked, in bytes
+type KnowledgeConfig struct {
+	ChunkSize    int `mapstructure:"chunk_size" yaml:"chunk_size,omitempty" json:"chunk_size,omitempty"`
+	ChunkOverlap int `mapstructure:"chunk_overlap" yaml:"chunk_overlap,omitempty" json:"chunk_overlap,omitempty"`
+}
+
+// WebConfig holds the web server options
+type WebConfig struct {
+	Addr           string   `mapstructure:"addr" yaml:"addr,omitempty" json:"addr,omitempty"`
+	AllowedOrigins []string `mapstructure:"allowed_origins" yaml:"allowed_origins,omitempty" json:"allowed_origins,omitempty"`
+	UploadDir      string   `mapstructure:"upload_dir" yaml:"upload_dir,omitempty" json:"upload_dir,omitempty"`
+	// MainDomain is the domain whose subdomains the version proxy serves
+	MainDomain string `mapstructure:"main_domain" yaml:"main_domain,omitempty" json:"main_domain,omitempty"`
+	// AdminUsers may use the endpoints that move money
+	AdminUsers []string   `mapstructure:"admin_users" yaml:"admin_users,omitempty" json:"admin_users,omitempty"`
+	RateLimits RateLimits `mapstructure:"rate_limits" yaml:"rate_limits,omitempty" json:"rate_limits,omitempty"`
+}
+
+// RateLimits are API requests per minute by class
+type RateLimits struct {
+	Read  int `mapstructure:"read" yaml:"read,omitempty" json:"read,omitempty"`
+	Write int `mapstructure:"write" yaml:"write,omitempty" json:"write,omitempty"`
+	TTS   int `mapstructure:"tts" yaml:"tts,omitempty" json:"tts,omitempty"`
+	// PerUser counts signed-in users per account rather than per IP.
+	// Defaults to true when not set.
+	PerUser *bool `mapstructure:"per_user" yaml:"per_user,omitempty" json:"per_user,omitempty"`
+}
+
+// TTSConfig holds the text-to-speech provider keys
+type TTSConfig struct {
+	ElevenLabsKey   string `mapstructure:"elevenlabs_api_key" yaml:"elevenlabs_api_key,omitempty" json:"elevenlabs_api_key,omitempty"`
+	ElevenLabsVoice string `mapstructure:"elevenlabs_voice_id" yaml:"elevenlabs_voice_id,omitempty" json:"elevenlabs_voice_id,omitempty"`
+	FalKey          string `mapstructure:"fal_api_key" yaml:"fal_api_key,omitempty" json:"fal_api_key,omitempty"`
+	CacheMaxMB      int    `mapstructure:"cache_max_mb" yaml:"cache_max_mb,omitempty" json:"cache_max_mb,omitempty"`
+}
+
+// SelfImproveConfig configures the repository the self-improve tool works on
+type SelfImproveConfig struct {
+	GitHubToken string `mapstructure:"github_token" yaml:"github_token,omitempty" json:"github_token,omitempty"`
+	RepoURL     string `mapstructure:"repo_url" yaml:"repo_url,omitempty" json:"repo_url,omitempty"`
+	AutoMerge   bool   `mapstructure:"auto_merge" yaml:"auto_merge,omitempty" json:"auto_merge,omitempty"`
+	// CheckWrites runs gofmt and go vet after each Go file SelfImprove writes
+	CheckWrites bool `mapstructure:"check_writes" yaml:"check_writes,omitempty" json:"check_writes,omitempty"`
+}
+
+// DefaultReadOnlyMaxTurns is the messages per conversation in read-only
+// mode when read_only.max_turns is not set
+const DefaultReadOnlyMaxTurns = 20
+
+// ReadOnlyConfig is for public demos: tools that change anything are
+// hidden (see tool.Registry.SetReadOnly), only cheap models may be used,
+// and conversations are capped
+type ReadOnlyConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// Models may be selected; empty means the catalog's cheapest (cost hint 1)
+	Models []string `mapstructure:"models" yaml:"models,omitempty" json:"models,omitempty"`
+	// MaxTurns caps the messages per conversation; 0 means DefaultReadOnlyMaxTurns
+	MaxTurns int `mapstructure:"max_turns" yaml:"max_turns,omitempty" json:"max_turns,omitempty"`
+}
+
+// AllowedModels returns the models read-only mode may use
+func (r ReadOnlyConfig) AllowedModels() []string {
+	if len(r.Models) > 0 {
+		return r.Models
+	}
+	var models []string
+	for _, m := range client.Catalog.Models() {
+		if m.CostHint == 1 && !m.Legacy {
+			models = append(models, m.Name)
+		}
+	}
+	return models
+}
+
+// AllowsModel reports whether read-only mode may use model
+func (r ReadOnlyConfig) AllowsModel(model string) bool {
+	return slices.Contains(r.AllowedModels(), model)
+}
+
+// TurnLimit returns the messages allowed per conversation
+func (r ReadOnlyConfig) TurnLimit() int {
+	if r.MaxTurns > 0 {
+		return r.MaxTurns
+	}
+	return DefaultReadOnlyMaxTurns
+}
+
+// ApprovalConfig turns on the approval gate: Bash, CodeExec, SelfImprove,
+// Version, git push and writes outside the working tree wait for the user
+// to approve them, see tool.Registry.RequireApproval
+type ApprovalConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// Tools need approval for every call, besides the defaults
+	Tools []string `mapstructure:"tools" yaml:"tools,omitempty" json:"tools,omitempty"`
+	// TimeoutSeconds denies a call not approved in time; 0 means tool.ApprovalTimeout
+	TimeoutSeconds int `mapstructure:"timeout_seconds" yaml:"timeout_seconds,omitempty" json:"timeout_seconds,omitempty"`
+}
+
+// CacheConfig turns on the response cache, which serves repeated requests
+// sent at temperature 0 from disk, see client.WithCache
+type CacheConfig struct {
+	Enabled bool `mapstructure:"enabled" yaml:"enabled,omitempty" json:"enabled,omitempty"`
+	// Dir holds the cached responses; empty means CacheDir
+	Dir string `mapstructure:"dir" yaml:"dir,omitempty" json:"dir,omitempty"`
+	// TTLSeconds is how long a response is served; 0 means client.DefaultCacheTTL
+	TTLSeconds int `mapstructure:"ttl_seconds" yaml:"ttl_seconds,omitempty" json:"ttl_seconds,omitempty"`
+	// MaxMB caps the directory; 0 means client.CacheMaxBytes
+	MaxMB int `mapstructure:"max_mb" yaml:"max_mb,omitempty" json:"max_mb,omitempty"`
+}
+
+// CacheDir is the default directory of the response cache
+func CacheDir() string {
+	return filepath.Join(Dir(), "response-cache")
+}
+
+// MCPConfig tunes how MCP servers from mcp.json are started
+type MCPConfig struct {
+	// StartTimeoutSeconds is how long startup waits for each server; 0 means mcp.StartupWait
+	StartTimeoutSeconds int `mapstructure:"start_timeout_seconds" yaml:"start_timeout_seconds,omitempty" json:"start_timeout_seconds,omitempty"`
+}
+
+// NotificationsConfig lists where notify.Dispatcher delivers events
+type NotificationsConfig struct {
+	Targets []NotifyTarget `mapstructure:"targets" yaml:"targets,omitempty" json:"targets,omitempty"`
+}
+
+// NotifyTarget is a webhook that receives events as signed JSON, or a
+// Slack incoming webhook that receives them as messages
+type NotifyTarget struct {
+	Name   string `mapstructure:"name" yaml:"name,omitempty" json:"name,omitempty"`
+	Type   string `mapstructure:"type" yaml:"type,omitempty" json:"type,omitempty"` // "webhook" (default) or "slack"
+	URL    string `mapstructure:"url" yaml:"url" json:"url"`
+	Secret string `mapstructure:"secret" yaml:"secret,omitempty" json:"secret,omitempty"` // HMAC key for webhook signatures
+	// Events limits the event types sent, e.g. "version.build_failed" or
+	// "schedule.*"; all events when empty
+	Events []string `mapstructure:"events" yaml:"events,omitempty" json:"events,omitempty"`
 }
 
 // DefaultModel is the default LLM model
 const DefaultModel = "llama-3.3-70b-versatile"
 
-// Load loads configuration from environment and config files
+// Defaults for the web server and self-improvement
+const (
+	DefaultAddr       = ":8080"
+	DefaultMainDomain = "chatweb.ai"
+	DefaultRepoURL    = "https://github.com/yukihamada/groq-go.git"
+)
+
+// ErrNotConfigured is returned by Load when no provider API key is available
+var ErrNotConfigured = errors.New("no API key configured: set GROQ_API_KEY, ANTHROPIC_API_KEY, OPENAI_API_KEY, GEMINI_API_KEY, MOONSHOT_API_KEY or CUSTOM_BASE_URL, or run setup")
+
+// providerEnvVars are the environment variables that supply provider keys,
+// or a custom endpoint that needs none
+var providerEnvVars = []string{"GROQ_API_KEY", "MOONSHOT_API_KEY", "OPENAI_API_KEY", "ANTHROPIC_API_KEY", "GEMINI_API_KEY", "CUSTOM_BASE_URL"}
+
+// Dir returns the configuration directory
+func Dir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".config", "groq-go")
+}
+
+// Path returns the path of the user config file
+func Path() string {
+	return filepath.Join(Dir(), "config.yaml")
+}
+
+// Exists reports whether a user config file is present
+func Exists() bool {
+	_, err := os.Stat(Path())
+	return err == nil
+}
+
+// HasEnvKeys reports whether any provider key is set in the environment
+func HasEnvKeys() bool {
+	for _, name := range providerEnvVars {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsFirstRun reports whether neither a config file nor provider env keys exist
+func IsFirstRun() bool {
+	return !Exists() && !HasEnvKeys()
+}
+
+// HasKeys reports whether at least one provider key or custom endpoint is
+// configured
+func (c *Config) HasKeys() bool {
+	return c.APIKey != "" || c.MoonshotKey != "" || c.OpenAIKey != "" || c.ClaudeKey != "" || c.GeminiKey != "" || len(c.Endpoints) > 0
+}
+
+// IsPerUser reports whether signed-in users are counted per account
+func (r RateLimits) IsPerUser() bool {
+	return r.PerUser == nil || *r.PerUser
+}
+
+// FormatOnWrite returns which files are formatted after Write/Edit. Off
+// unless auto_format is set; data files also need format_data_files.
+func (c *Config) FormatOnWrite() tool.Format {
+	switch {
+	case !c.AutoFormat:
+		return tool.FormatOff
+	case c.FormatDataFiles:
+		return tool.FormatAll
+	}
+	return tool.FormatCode
+}
+
+// ProviderKeys returns the configured keys by provider name
+func (c *Config) ProviderKeys() map[string]string {
+	keys := make(map[string]string)
+	if c.APIKey != "" {
+		keys["groq"] = c.APIKey
+	}
+	if c.ClaudeKey != "" {
+		keys["anthropic"] = c.ClaudeKey
+	}
+	if c.OpenAIKey != "" {
+		keys["openai"] = c.OpenAIKey
+	}
+	if c.MoonshotKey != "" {
+		keys["moonshot"] = c.MoonshotKey
+	}
+	if c.GeminiKey != "" {
+		keys["gemini"] = c.GeminiKey
+	}
+	return keys
+}
+
+// SetProviderKey sets the key for the named provider
+func (c *Config) SetProviderKey(provider, key string) error {
+	switch provider {
+	case "groq":
+		c.APIKey = key
+	case "anthropic":
+		c.ClaudeKey = key
+	case "openai":
+		c.OpenAIKey = key
+	case "moonshot":
+		c.MoonshotKey = key
+	case "gemini":
+		c.GeminiKey = key
+	default:
+		return fmt.Errorf("unknown provider: %s", provider)
+	}
+	return nil
+}
+
+// LoadFile reads the user config file without applying environment overrides
+func LoadFile() (*Config, error) {
+	data, err := os.ReadFile(Path())
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	return &cfg, nil
+}
+
+// Save writes cfg to the user config file, readable only by the owner
+func Save(cfg *Config) error {
+	if err := os.MkdirAll(Dir(), 0700); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	// Write to a temp file first so a crash never leaves a truncated config
+	tmp := Path() + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	if err := os.Rename(tmp, Path()); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("failed to write config: %w", err)
+	}
+	return nil
+}
+
+// envBindings maps config keys to the environment variables that override
+// the config file
+var envBindings = map[string]string{
+	"api_key":                    "GROQ_API_KEY",
+	"model":                      "GROQ_MODEL",
+	"moonshot_api_key":           "MOONSHOT_API_KEY",
+	"openai_api_key":             "OPENAI_API_KEY",
+	"claude_api_key":             "ANTHROPIC_API_KEY",
+	"gemini_api_key":             "GEMINI_API_KEY",
+	"prompt_caching":             "GROQ_PROMPT_CACHING",
+	"sandbox_disabled":           "SANDBOX_DISABLED",
+	"system_prompt_append":       "SYSTEM_PROMPT_APPEND",
+	"fallback_models":            "FALLBACK_MODELS",
+	"web.addr":                   "WEB_ADDR",
+	"web.allowed_origins":        "ALLOWED_ORIGINS",
+	"web.upload_dir":             "UPLOAD_DIR",
+	"web.main_domain":            "MAIN_DOMAIN",
+	"web.admin_users":            "ADMIN_USERS",
+	"web.rate_limits.read":       "RATE_LIMIT_READ",
+	"web.rate_limits.write":      "RATE_LIMIT_WRITE",
+	"web.rate_limits.tts":        "RATE_LIMIT_TTS",
+	"web.rate_limits.per_user":   "RATE_LIMIT_PER_USER",
+	"tts.elevenlabs_api_key":     "ELEVENLABS_API_KEY",
+	"tts.elevenlabs_voice_id":    "ELEVENLABS_VOICE_ID",
+	"tts.fal_api_key":            "FAL_API_KEY",
+	"tts.cache_max_mb":           "TTS_CACHE_MAX_MB",
+	"self_improve.github_token":  "GITHUB_TOKEN",
+	"self_improve.repo_url":      "SELF_REPO_URL",
+	"self_improve.auto_merge":    "SELF_IMPROVE_AUTO_MERGE",
+	"self_improve.check_writes":  "SELF_IMPROVE_CHECK_WRITES",
+	"read_only.enabled":          "READ_ONLY",
+	"read_only.models":           "READ_ONLY_MODELS",
+	"read_only.max_turns":        "READ_ONLY_MAX_TURNS",
+	"approval.enabled":           "REQUIRE_APPROVAL",
+	"approval.tools":             "APPROVAL_TOOLS",
+	"approval.timeout_seconds":   "APPROVAL_TIMEOUT_SECONDS",
+	"temperature":                "GROQ_TEMPERATURE",
+	"response_cache.enabled":     "RESPONSE_CACHE",
+	"response_cache.dir":         "RESPONSE_CACHE_DIR",
+	"response_cache.ttl_seconds": "RESPONSE_CACHE_TTL_SECONDS",
+	"response_cache.max_mb":      "RESPONSE_CACHE_MAX_MB",
+	"mcp.start_timeout_seconds":  "MCP_START_TIMEOUT_SECONDS",
+}
+
+// Load loads configuration from the config file, with environment
+// variables taking precedence. When no provider key is configured it
+// returns the loaded configuration together with ErrNotConfigured.
 func Load() (*Config, error) {
 	v := viper.New()
 
 	// Set defaults
 	v.SetDefault("model", DefaultModel)
+	v.SetDefault("web.addr", DefaultAddr)
+	v.SetDefault("web.main_domain", DefaultMainDomain)
+	v.SetDefault("web.upload_dir", filepath.Join(Dir(), "uploads"))
+	v.SetDefault("self_improve.repo_url", DefaultRepoURL)
 
 	// Config file paths
 	home, err := os.UserHomeDir()
@@ -40,13 +513,9 @@ func Load() (*Config, error) {
 	// Environment variables
 	v.SetEnvPrefix("GROQ")
 	v.AutomaticEnv()
-
-	// Bind specific env vars
-	v.BindEnv("api_key", "GROQ_API_KEY")
-	v.BindEnv("model", "GROQ_MODEL")
-	v.BindEnv("moonshot_api_key", "MOONSHOT_API_KEY")
-	v.BindEnv("openai_api_key", "OPENAI_API_KEY")
-	v.BindEnv("claude_api_key", "ANTHROPIC_API_KEY")
+	for key, env := range envBindings {
+		v.BindEnv(key, env)
+	}
 
 	// Read config file (optional)
 	if err := v.ReadInConfig(); err != nil {
@@ -54,20 +523,192 @@ func Load() (*Config, error) {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			// Only return error if it's not a "file not found" error
 			if _, ok := err.(*os.PathError); !ok {
-				return nil, fmt.Errorf("failed to read config: %w", err)
+				return nil, fmt.Errorf("failed to parse %s: %w", v.ConfigFileUsed(), err)
 			}
 		}
 	}
 
 	var cfg Config
 	if err := v.Unmarshal(&cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+		return nil, fmt.Errorf("invalid config (check %s and the environment): %w", Path(), err)
+	}
+	cfg.Web.AllowedOrigins = cleanList(cfg.Web.AllowedOrigins)
+	cfg.Web.AdminUsers = cleanList(cfg.Web.AdminUsers)
+	cfg.FallbackModels = cleanList(cfg.FallbackModels)
+	cfg.ReadOnly.Models = cleanList(cfg.ReadOnly.Models)
+	cfg.Approval.Tools = cleanList(cfg.Approval.Tools)
+
+	// Tool restrictions from the environment replace the config file's lists
+	if allow := os.Getenv("TOOLS_ALLOW"); allow != "" {
+		cfg.Tools.Allow = tool.ParseToolList(allow)
+	}
+	if deny := os.Getenv("TOOLS_DENY"); deny != "" {
+		cfg.Tools.Deny = tool.ParseToolList(deny)
+	}
+	cfg.Endpoints = append(cfg.Endpoints, endpointsFromEnv()...)
+	if extra := os.Getenv("EXTRA_MODELS"); extra != "" {
+		entries, err := ParseModelList(extra)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Models = append(cfg.Models, entries...)
 	}
 
-	// Validate required fields
-	if cfg.APIKey == "" {
-		return nil, fmt.Errorf("GROQ_API_KEY environment variable is required")
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	// At least one provider must be configured
+	if !cfg.HasKeys() {
+		return &cfg, ErrNotConfigured
 	}
 
 	return &cfg, nil
 }
+
+// Validate checks values the config file and environment cannot express
+// by type alone
+func (c *Config) Validate() error {
+	var errs []error
+	if c.Web.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Web.Addr); err != nil {
+			errs = append(errs, fmt.Errorf("web.addr %q must be host:port, e.g. \":8080\"", c.Web.Addr))
+		}
+	}
+	for name, n := range map[string]int{
+		"web.rate_limits.read":       c.Web.RateLimits.Read,
+		"web.rate_limits.write":      c.Web.RateLimits.Write,
+		"web.rate_limits.tts":        c.Web.RateLimits.TTS,
+		"tts.cache_max_mb":           c.TTS.CacheMaxMB,
+		"context_tokens":             c.ContextSize,
+		"knowledge.chunk_size":       c.Knowledge.ChunkSize,
+		"knowledge.chunk_overlap":    c.Knowledge.ChunkOverlap,
+		"read_only.max_turns":        c.ReadOnly.MaxTurns,
+		"approval.timeout_seconds":   c.Approval.TimeoutSeconds,
+		"response_cache.ttl_seconds": c.Cache.TTLSeconds,
+		"response_cache.max_mb":      c.Cache.MaxMB,
+		"mcp.start_timeout_seconds":  c.MCP.StartTimeoutSeconds,
+	} {
+		if n < 0 {
+			errs = append(errs, fmt.Errorf("%s must not be negative, got %d", name, n))
+		}
+	}
+	if t := c.Temperature; t != nil && (*t < 0 || *t > client.MaxTemperature) {
+		errs = append(errs, fmt.Errorf("temperature must be between 0 and %g, got %g", client.MaxTemperature, *t))
+	}
+	if k := c.Knowledge; k.ChunkSize > 0 && k.ChunkOverlap >= k.ChunkSize {
+		errs = append(errs, fmt.Errorf("knowledge.chunk_overlap (%d) must be smaller than knowledge.chunk_size (%d)", k.ChunkOverlap, k.ChunkSize))
+	}
+	if _, err := origin.NewPolicy(c.Web.AllowedOrigins); err != nil {
+		errs = append(errs, fmt.Errorf("web.allowed_origins: %w", err))
+	}
+	catalog := client.NewModelCatalog()
+	for _, e := range c.Endpoints {
+		if parsed, err := url.Parse(e.BaseURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("endpoints: %s base_url %q must be an http(s) URL", e.Name, e.BaseURL))
+		}
+		if len(e.Models) == 0 {
+			errs = append(errs, fmt.Errorf("endpoints: %s lists no models", e.Name))
+		}
+		if err := e.Register(catalog); err != nil {
+			errs = append(errs, fmt.Errorf("endpoints: %w", err))
+		}
+	}
+	for _, m := range c.Models {
+		if err := catalog.Add(m.Info()); err != nil {
+			errs = append(errs, fmt.Errorf("models: %w", err))
+		}
+	}
+	if c.GrepMaxFileSize < 0 {
+		errs = append(errs, fmt.Errorf("grep_max_file_size must not be negative, got %d", c.GrepMaxFileSize))
+	}
+	if c.ToolResultMaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("tool_result_max_bytes must not be negative, got %d", c.ToolResultMaxBytes))
+	}
+	if c.ToolTurnMaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("tool_turn_max_bytes must not be negative, got %d", c.ToolTurnMaxBytes))
+	}
+	if u := c.SelfImprove.RepoURL; u != "" && !strings.HasPrefix(u, "git@") {
+		if parsed, err := url.Parse(u); err != nil || parsed.Scheme == "" || parsed.Host == "" && parsed.Scheme != "file" {
+			errs = append(errs, fmt.Errorf("self_improve.repo_url %q must be a URL such as %s", u, DefaultRepoURL))
+		}
+	}
+	for i, t := range c.Notifications.Targets {
+		if t.Type != "" && t.Type != "webhook" && t.Type != "slack" {
+			errs = append(errs, fmt.Errorf("notifications.targets[%d]: type %q must be webhook or slack", i, t.Type))
+		}
+		if parsed, err := url.Parse(t.URL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			errs = append(errs, fmt.Errorf("notifications.targets[%d]: url must be an http(s) URL", i))
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return fmt.Errorf("invalid config: %w", errors.Join(errs...))
+}
+
+// cleanList trims list entries and drops empty ones; lists from the
+// environment are comma-separated
+func cleanList(items []string) []string {
+	var out []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// ClearSecrets blanks every API key, token and webhook secret
+func (c *Config) ClearSecrets() {
+	c.APIKey, c.MoonshotKey, c.OpenAIKey, c.ClaudeKey, c.GeminiKey = "", "", "", "", ""
+	c.TTS.ElevenLabsKey, c.TTS.FalKey = "", ""
+	c.SelfImprove.GitHubToken = ""
+	// Slack targets are dropped, their URL is the secret
+	var targets []NotifyTarget
+	for _, t := range c.Notifications.Targets {
+		if t.Type != "slack" {
+			t.Secret = ""
+			targets = append(targets, t)
+		}
+	}
+	c.Notifications.Targets = targets
+}
+
+// Redacted returns a copy safe to show: secrets are masked down to their
+// last four characters, or entirely when short
+func (c *Config) Redacted() *Config {
+	r := *c
+	for _, s := range []*string{
+		&r.APIKey, &r.MoonshotKey, &r.OpenAIKey, &r.ClaudeKey, &r.GeminiKey,
+		&r.TTS.ElevenLabsKey, &r.TTS.FalKey, &r.SelfImprove.GitHubToken,
+	} {
+		*s = redact(*s)
+	}
+	r.Endpoints = slices.Clone(c.Endpoints)
+	for i := range r.Endpoints {
+		r.Endpoints[i].APIKey = redact(r.Endpoints[i].APIKey)
+	}
+	// Slack webhook URLs are credentials themselves
+	r.Notifications.Targets = slices.Clone(c.Notifications.Targets)
+	for i := range r.Notifications.Targets {
+		t := &r.Notifications.Targets[i]
+		t.Secret = redact(t.Secret)
+		if t.Type == "slack" {
+			t.URL = redact(t.URL)
+		}
+	}
+	return &r
+}
+
+func redact(secret string) string {
+	switch {
+	case secret == "":
+		return ""
+	case len(secret) < 12:
+		return "****"
+	default:
+		return "****" + secret[len(secret)-4:]
+	}
+}
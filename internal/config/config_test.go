@@ -0,0 +1,264 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+// writeConfig points HOME at a temp dir holding config.yaml with content
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	for _, env := range envBindings {
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+	dir := filepath.Join(home, ".config", "groq-go")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+const testConfig = `
+api_key: gsk_file_key_1234
+web:
+  addr: ":9090"
+  main_domain: file.example
+  allowed_origins: [file.example]
+  rate_limits:
+    read: 50
+tts:
+  fal_api_key: fal_file_key_5678
+self_improve:
+  github_token: ghp_file_token_9012
+`
+
+func TestLoadEnvOverridesFile(t *testing.T) {
+	writeConfig(t, testConfig)
+	t.Setenv("MAIN_DOMAIN", "env.example")
+	t.Setenv("ALLOWED_ORIGINS", "a.example, b.example,")
+	t.Setenv("RATE_LIMIT_WRITE", "7")
+	t.Setenv("RATE_LIMIT_PER_USER", "false")
+	t.Setenv("ELEVENLABS_API_KEY", "el_env_key")
+	t.Setenv("SELF_IMPROVE_AUTO_MERGE", "true")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if cfg.Web.Addr != ":9090" || cfg.APIKey != "gsk_file_key_1234" || cfg.TTS.FalKey != "fal_file_key_5678" {
+		t.Errorf("Expected file values where the environment is unset, got %+v", cfg)
+	}
+	if cfg.Web.MainDomain != "env.example" || cfg.TTS.ElevenLabsKey != "el_env_key" || !cfg.SelfImprove.AutoMerge {
+		t.Errorf("Expected the environment to win, got %+v %+v %+v", cfg.Web, cfg.TTS, cfg.SelfImprove)
+	}
+	if got := strings.Join(cfg.Web.AllowedOrigins, "|"); got != "a.example|b.example" {
+		t.Errorf("Expected a cleaned origin list, got %q", got)
+	}
+	if rl := cfg.Web.RateLimits; rl.Read != 50 || rl.Write != 7 || rl.IsPerUser() {
+		t.Errorf("Expected merged rate limits, got %+v", rl)
+	}
+	if cfg.Model != DefaultModel || cfg.SelfImprove.RepoURL != DefaultRepoURL || cfg.Web.UploadDir == "" {
+		t.Errorf("Expected defaults for unset values, got %+v", cfg)
+	}
+}
+
+func TestLoadWithoutKeysReturnsConfig(t *testing.T) {
+	writeConfig(t, "web:\n  addr: \":7070\"\n")
+	cfg, err := Load()
+	if !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("Expected ErrNotConfigured, got %v", err)
+	}
+	if cfg == nil || cfg.Web.Addr != ":7070" || cfg.Web.MainDomain != DefaultMainDomain {
+		t.Errorf("Expected the loaded config alongside the error, got %+v", cfg)
+	}
+}
+
+func TestLoadValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		env     map[string]string
+		wantErr []string
+	}{
+		{"malformed yaml", "api_key: k\nweb:\n  addr: [\n", nil, []string{"failed to parse", "config.yaml"}},
+		{"wrong type", "api_key: k\nweb:\n  rate_limits:\n    read: lots\n", nil, []string{"invalid config", "read"}},
+		{"bad env value", "api_key: k\n", map[string]string{"RATE_LIMIT_TTS": "ten"}, []string{"invalid config", "tts"}},
+		{"bad addr", "api_key: k\nweb:\n  addr: \"8080\"\n", nil, []string{`web.addr "8080" must be host:port`}},
+		{"negative values", "api_key: k\ntts:\n  cache_max_mb: -1\nweb:\n  rate_limits:\n    write: -5\n", nil,
+			[]string{"tts.cache_max_mb must not be negative", "web.rate_limits.write must not be negative"}},
+		{"bad origin", "api_key: k\nweb:\n  allowed_origins: [\"https://user@chatweb.ai\"]\n", nil, []string{"web.allowed_origins", "credentials"}},
+		{"bad repo url", "api_key: k\nself_improve:\n  repo_url: not a url\n", nil, []string{"self_improve.repo_url"}},
+		{"bad model provider", "api_key: k\nmodels:\n  - name: m\n    provider: nowhere\n", nil, []string{"models:", `unknown provider "nowhere"`}},
+		{"bad endpoint", "api_key: k\nendpoints:\n  - name: groq\n    base_url: localhost:11434\n", nil,
+			[]string{"must be an http(s) URL", "groq lists no models", "provider groq already exists"}},
+		{"model for unknown endpoint", "api_key: k\nmodels:\n  - name: m\n    provider: ollama\n", nil, []string{`unknown provider "ollama"`}},
+		{"bad notification target", "api_key: k\nnotifications:\n  targets:\n    - type: email\n      url: ops@example.com\n", nil,
+			[]string{"type \"email\" must be webhook or slack", "url must be an http(s) URL"}},
+		{"bad extra models", "api_key: k\n", map[string]string{"EXTRA_MODELS": "just-a-name"}, []string{"EXTRA_MODELS", "name=provider"}},
+		{"negative read-only turns", "api_key: k\nread_only:\n  max_turns: -1\n", nil, []string{"read_only.max_turns must not be negative"}},
+		{"bad temperature", "api_key: k\ntemperature: 3\nresponse_cache:\n  max_mb: -1\n", nil,
+			[]string{"temperature must be between 0 and 2", "response_cache.max_mb must not be negative"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeConfig(t, tt.config)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			_, err := Load()
+			if err == nil {
+				t.Fatal("Expected an error")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Expected %q in %q", want, err)
+				}
+			}
+		})
+	}
+
+	// SSH remotes are valid repo URLs
+	cfg := &Config{SelfImprove: SelfImproveConfig{RepoURL: "git@github.com:me/fork.git"}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Expected an SSH remote to be accepted, got %v", err)
+	}
+}
+
+func TestLoadReadOnly(t *testing.T) {
+	writeConfig(t, "api_key: k\n")
+	t.Setenv("READ_ONLY", "true")
+	t.Setenv("READ_ONLY_MODELS", "llama-3.1-8b-instant, ")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ro := cfg.ReadOnly
+	if !ro.Enabled || !ro.AllowsModel("llama-3.1-8b-instant") || ro.AllowsModel("claude-sonnet-4-20250514") {
+		t.Errorf("Unexpected read-only settings %+v", ro)
+	}
+	if ro.TurnLimit() != DefaultReadOnlyMaxTurns {
+		t.Errorf("Expected the default turn limit, got %d", ro.TurnLimit())
+	}
+
+	// Without a list only the cheapest catalog models are allowed
+	models := ReadOnlyConfig{}.AllowedModels()
+	if len(models) == 0 {
+		t.Fatal("Expected cheap models in the catalog")
+	}
+	for _, m := range models {
+		if info, _ := client.Catalog.Lookup(m); info.CostHint != 1 {
+			t.Errorf("Expected only cost hint 1 models, got %s (%d)", m, info.CostHint)
+		}
+	}
+}
+
+func TestLoadResponseCache(t *testing.T) {
+	writeConfig(t, "api_key: k\nresponse_cache:\n  enabled: true\n  ttl_seconds: 600\n")
+	t.Setenv("GROQ_TEMPERATURE", "0")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.Cache.Enabled || cfg.Cache.TTLSeconds != 600 {
+		t.Errorf("Unexpected cache settings %+v", cfg.Cache)
+	}
+	if cfg.Temperature == nil || *cfg.Temperature != 0 {
+		t.Errorf("Expected temperature 0 from the environment, got %v", cfg.Temperature)
+	}
+}
+
+func TestLoadModels(t *testing.T) {
+	writeConfig(t, "api_key: k\nmodels:\n  - name: local-llama\n    provider: openai\n    context_window: 8192\n    tools: false\n")
+	t.Setenv("EXTRA_MODELS", "my-claude=anthropic, ")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if len(cfg.Models) != 2 {
+		t.Fatalf("Expected 2 models, got %+v", cfg.Models)
+	}
+	local := cfg.Models[0].Info()
+	if local.Name != "local-llama" || local.Provider != "openai" || local.ContextWindow != 8192 || local.Tools {
+		t.Errorf("Unexpected file model %+v", local)
+	}
+	extra := cfg.Models[1].Info()
+	if extra.Name != "my-claude" || extra.Provider != "anthropic" || !extra.Tools {
+		t.Errorf("Expected tools to default to on, got %+v", extra)
+	}
+}
+
+func TestLoadEndpoints(t *testing.T) {
+	writeConfig(t, "endpoints:\n  - name: ollama\n    base_url: http://localhost:11434/v1\n    models: [qwen2.5-coder]\nmodels:\n  - name: qwen2.5-coder\n    provider: ollama\n    context_window: 32768\n")
+	t.Setenv("CUSTOM_BASE_URL", "http://vllm:8000/v1")
+	t.Setenv("CUSTOM_API_KEY", "vllm-key")
+	t.Setenv("CUSTOM_MODELS", "mistral-7b, llama-3-8b,")
+	t.Setenv("CUSTOM_1_BASE_URL", "http://lmstudio:1234/v1")
+	t.Setenv("CUSTOM_1_MODELS", "phi-3")
+	t.Setenv("CUSTOM_3_BASE_URL", "http://skipped/v1")
+
+	// No provider key: an endpoint is enough
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	var names []string
+	for _, e := range cfg.Endpoints {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "ollama,custom,custom-1" {
+		t.Fatalf("Expected endpoints from the file, then CUSTOM_ and CUSTOM_1_, got %s", got)
+	}
+	custom := cfg.Endpoints[1]
+	if custom.BaseURL != "http://vllm:8000/v1" || custom.APIKey != "vllm-key" || strings.Join(custom.Models, ",") != "mistral-7b,llama-3-8b" {
+		t.Errorf("Unexpected custom endpoint %+v", custom)
+	}
+	if r := cfg.Redacted(); r.Endpoints[1].APIKey != "****" || cfg.Endpoints[1].APIKey != "vllm-key" {
+		t.Errorf("Expected the endpoint key redacted in a copy, got %q", r.Endpoints[1].APIKey)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &Config{
+		APIKey:      "gsk_abcdefghijklmnop",
+		ClaudeKey:   "short",
+		Model:       DefaultModel,
+		TTS:         TTSConfig{ElevenLabsKey: "el_abcdefghijkl", ElevenLabsVoice: "rachel"},
+		SelfImprove: SelfImproveConfig{GitHubToken: "ghp_abcdefghijkl", RepoURL: DefaultRepoURL},
+	}
+	r := cfg.Redacted()
+	if r.APIKey != "****mnop" || r.ClaudeKey != "****" || r.OpenAIKey != "" {
+		t.Errorf("Unexpected provider key redaction: %q %q %q", r.APIKey, r.ClaudeKey, r.OpenAIKey)
+	}
+	if r.TTS.ElevenLabsKey != "****ijkl" || r.SelfImprove.GitHubToken != "****ijkl" {
+		t.Errorf("Expected TTS and GitHub secrets redacted, got %q %q", r.TTS.ElevenLabsKey, r.SelfImprove.GitHubToken)
+	}
+	if r.Model != DefaultModel || r.TTS.ElevenLabsVoice != "rachel" || r.SelfImprove.RepoURL != DefaultRepoURL {
+		t.Errorf("Expected non-secrets kept, got %+v", r)
+	}
+	if cfg.APIKey != "gsk_abcdefghijklmnop" {
+		t.Error("Expected the original config untouched")
+	}
+
+	cfg.Notifications.Targets = []NotifyTarget{
+		{URL: "https://ci.example.com/hook", Secret: "whsec_abcdefghijkl"},
+		{Type: "slack", URL: "https://hooks.slack.com/services/T0/B0/abcdefghijkl"},
+	}
+	r = cfg.Redacted()
+	if hook, slack := r.Notifications.Targets[0], r.Notifications.Targets[1]; hook.Secret != "****ijkl" || hook.URL != "https://ci.example.com/hook" || slack.URL != "****ijkl" {
+		t.Errorf("Expected webhook secrets and Slack URLs redacted, got %+v", r.Notifications.Targets)
+	}
+	if cfg.Notifications.Targets[0].Secret != "whsec_abcdefghijkl" {
+		t.Error("Expected the original targets untouched")
+	}
+}
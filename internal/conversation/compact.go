@@ -0,0 +1,242 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"groq-go/internal/client"
+	"groq-go/internal/textutil"
+)
+
+// Compaction settings. Compaction starts when a history is estimated at
+// CompactThreshold of the context window and shrinks it to CompactTarget.
+var (
+	CompactThreshold = 0.8
+	CompactTarget    = 0.5
+	// ContextLimit overrides the model's context window when positive
+	ContextLimit = 0
+	// KeepTurns is how many recent turns are never summarized
+	KeepTurns = 2
+)
+
+const (
+	// SummaryPrefix starts the message that replaces summarized turns
+	SummaryPrefix = "Summary of earlier conversation:\n\n"
+
+	// imageTokens is the rough prompt cost of one image
+	imageTokens = 1000
+	// summaryMaxTokens bounds the summary the model may write
+	summaryMaxTokens = 1024
+	// maxTranscriptMessageBytes caps each message shown to the summarizer
+	maxTranscriptMessageBytes = 2000
+	// maxToolResultBytes is the size kept tool results are cut to when
+	// summarizing alone does not reach the budget
+	maxToolResultBytes = 4096
+)
+
+const summaryPrompt = `You summarize conversations between a user and a coding assistant so the assistant can continue the work. ` +
+	`Write a concise summary of the transcript that keeps the user's goals, decisions, important facts, file paths, ` +
+	`commands and their outcomes, and any open tasks. Reply with the summary only.`
+
+// contextLimit returns the context size compaction works against
+func contextLimit(model string) int {
+	if ContextLimit > 0 {
+		return ContextLimit
+	}
+	return client.ContextWindow(model)
+}
+
+// TokenBudget is the estimated size a history is compacted to for model
+func TokenBudget(model string) int {
+	return int(float64(contextLimit(model)) * CompactTarget)
+}
+
+// NeedsCompaction reports whether msgs are close enough to model's context
+// window to be compacted before the next request
+func NeedsCompaction(msgs []client.Message, model string) bool {
+	return EstimateTokens(msgs) > int(float64(contextLimit(model))*CompactThreshold)
+}
+
+// EstimateTokens approximates the prompt tokens of msgs at 4 bytes per token
+func EstimateTokens(msgs []client.Message) int {
+	n := 0
+	for _, msg := range msgs {
+		n += estimateMessageTokens(msg)
+	}
+	return n
+}
+
+func estimateMessageTokens(msg client.Message) int {
+	bytes := len(msg.Role) + len(msg.ToolCallID)
+	images := 0
+	switch c := msg.Content.(type) {
+	case nil:
+	case string:
+		bytes += len(c)
+	case []client.ContentPart:
+		for _, part := range c {
+			bytes += len(part.Text)
+			if part.ImageURL != nil {
+				images++
+			}
+		}
+	default:
+		data, _ := json.Marshal(c)
+		bytes += len(data)
+	}
+	for _, tc := range msg.ToolCalls {
+		bytes += len(tc.ID) + len(tc.Function.Name) + len(tc.Function.Arguments)
+	}
+	// A few tokens of per-message framing
+	return 4 + (bytes+3)/4 + images*imageTokens
+}
+
+// CompactMessages shortens msgs to about budget estimated tokens. Leading
+// system messages and the latest keepTurns turns are kept verbatim; older
+// turns are replaced by one assistant message summarizing them, written by
+// a cheap model from c's provider. Turns are cut at user messages, so tool
+// calls are never separated from their results. If that is not enough,
+// fewer turns are kept and long tool results are truncated.
+//
+// The usage of the summary request is returned so callers can bill it.
+// When summarizing fails the old turns are still dropped, a placeholder
+// takes the summary's place, and the error is returned with the result.
+func CompactMessages(ctx context.Context, c *client.Client, msgs []client.Message, budget, keepTurns int) ([]client.Message, client.Usage, error) {
+	var usage client.Usage
+	if EstimateTokens(msgs) <= budget {
+		return msgs, usage, nil
+	}
+
+	head := 0
+	for head < len(msgs) && msgs[head].Role == "system" {
+		head++
+	}
+	var turns []int
+	for i := head; i < len(msgs); i++ {
+		if msgs[i].Role == "user" {
+			turns = append(turns, i)
+		}
+	}
+
+	// Keep as many recent turns as fit next to a summary, at least one
+	cut := head
+	if len(turns) > 0 {
+		cut = turns[len(turns)-1]
+	}
+	for keep := min(keepTurns, len(turns)); keep > 1; keep-- {
+		start := turns[len(turns)-keep]
+		if EstimateTokens(msgs[:head])+EstimateTokens(msgs[start:])+summaryMaxTokens <= budget {
+			cut = start
+			break
+		}
+	}
+
+	out := append([]client.Message(nil), msgs[:head]...)
+	var err error
+	if cut > head {
+		var summary string
+		summary, usage, err = summarize(ctx, c, msgs[head:cut])
+		if err != nil {
+			summary = fmt.Sprintf("(%d earlier messages were removed to fit the context window)", cut-head)
+		}
+		out = append(out, client.Message{Role: "assistant", Content: SummaryPrefix + summary})
+	}
+	kept := append([]client.Message(nil), msgs[cut:]...)
+
+	if EstimateTokens(out)+EstimateTokens(kept) > budget {
+		for i, msg := range kept {
+			content, ok := msg.Content.(string)
+			if msg.Role == "tool" && ok && len(content) > maxToolResultBytes {
+				kept[i].Content = textutil.TruncateBytes(content, maxToolResultBytes) + "\n... [truncated to fit the context window]"
+			}
+		}
+	}
+	return append(out, kept...), usage, err
+}
+
+// summarize asks a cheap model for a summary of msgs and returns it with
+// the usage of the request
+func summarize(ctx context.Context, c *client.Client, msgs []client.Message) (string, client.Usage, error) {
+	model := client.SummaryModel(c.Model())
+	summarizer := c.WithModelOverride(model)
+
+	// Leave the summarizer room for its instructions and answer
+	maxBytes := (client.ContextWindow(model) - summaryMaxTokens - 512) * 3
+	text := transcript(msgs, maxTranscriptMessageBytes)
+	if len(text) > maxBytes {
+		text = "...\n" + text[textutil.RuneStart(text, len(text)-maxBytes):]
+	}
+
+	resp, err := summarizer.ChatCompletion(ctx, []client.Message{
+		{Role: "system", Content: summaryPrompt},
+		{Role: "user", Content: text},
+	}, nil, client.RequestOptions{MaxTokens: summaryMaxTokens})
+	if err != nil {
+		return "", client.Usage{}, fmt.Errorf("failed to summarize history: %w", err)
+	}
+	var summary string
+	if len(resp.Choices) > 0 {
+		summary, _ = resp.Choices[0].Message.Content.(string)
+	}
+	if summary = strings.TrimSpace(summary); summary == "" {
+		return "", resp.Usage, fmt.Errorf("failed to summarize history: empty response")
+	}
+	return summary, resp.Usage, nil
+}
+
+// transcript renders msgs as plain text, cutting each message to
+// maxMessageBytes
+func transcript(msgs []client.Message, maxMessageBytes int) string {
+	var b strings.Builder
+	for _, msg := range msgs {
+		text := messageText(msg)
+		if len(text) > maxMessageBytes {
+			text = textutil.TruncateBytes(text, maxMessageBytes) + " ... [truncated]"
+		}
+		switch msg.Role {
+		case "tool":
+			fmt.Fprintf(&b, "Tool result: %s\n", text)
+		case "assistant":
+			if text != "" {
+				fmt.Fprintf(&b, "Assistant: %s\n", text)
+			}
+			for _, tc := range msg.ToolCalls {
+				args := tc.Function.Arguments
+				if len(args) > maxMessageBytes {
+					args = textutil.TruncateBytes(args, maxMessageBytes) + " ... [truncated]"
+				}
+				fmt.Fprintf(&b, "Assistant called %s(%s)\n", tc.Function.Name, args)
+			}
+		case "system":
+			fmt.Fprintf(&b, "System: %s\n", text)
+		default:
+			fmt.Fprintf(&b, "User: %s\n", text)
+		}
+	}
+	return b.String()
+}
+
+// messageText returns the text of a message, noting attached images
+func messageText(msg client.Message) string {
+	switch c := msg.Content.(type) {
+	case string:
+		return c
+	case []client.ContentPart:
+		var parts []string
+		for _, part := range c {
+			if part.Type == "text" {
+				parts = append(parts, part.Text)
+			} else {
+				parts = append(parts, "[image]")
+			}
+		}
+		return strings.Join(parts, " ")
+	case nil:
+		return ""
+	default:
+		data, _ := json.Marshal(c)
+		return string(data)
+	}
+}
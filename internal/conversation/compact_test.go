@@ -0,0 +1,193 @@
+package conversation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"groq-go/internal/client"
+)
+
+// summaryServer answers chat completions with a fixed summary and records
+// the requested models
+func summaryServer(t *testing.T, status int) (*client.Client, *[]string) {
+	t.Helper()
+	var mu sync.Mutex
+	var models []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		models = append(models, req.Model)
+		mu.Unlock()
+		if status != http.StatusOK {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		json.NewEncoder(w).Encode(client.ChatCompletionResponse{
+			Choices: []client.Choice{{
+				Message: client.Message{Role: "assistant", Content: "The user is refactoring the parser."},
+			}},
+			Usage: client.Usage{PromptTokens: 900, CompletionTokens: 60, TotalTokens: 960},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return client.New("key", client.WithBaseURL(srv.URL), client.WithRetry(1, 0)), &models
+}
+
+// longHistory returns a system prompt followed by n turns, each a user
+// message, a tool call with a large result, and an answer
+func longHistory(n int) []client.Message {
+	msgs := []client.Message{{Role: "system", Content: "You are helpful."}}
+	for i := 0; i < n; i++ {
+		id := fmt.Sprintf("call_%d", i)
+		msgs = append(msgs,
+			client.Message{Role: "user", Content: fmt.Sprintf("question %d", i)},
+			client.Message{Role: "assistant", ToolCalls: []client.ToolCall{{
+				ID: id, Type: "function", Function: client.FunctionCall{Name: "Read", Arguments: `{"path":"a.go"}`},
+			}}},
+			client.Message{Role: "tool", ToolCallID: id, Content: strings.Repeat("x", 2000)},
+			client.Message{Role: "assistant", Content: fmt.Sprintf("answer %d", i)},
+		)
+	}
+	return msgs
+}
+
+func TestCompactMessagesFitsBudget(t *testing.T) {
+	c, models := summaryServer(t, http.StatusOK)
+	msgs := longHistory(40)
+	budget := 4000
+	if EstimateTokens(msgs) <= budget {
+		t.Fatal("Expected the synthetic history to exceed the budget")
+	}
+
+	out, usage, err := CompactMessages(context.Background(), c, msgs, budget, 2)
+	if err != nil {
+		t.Fatalf("CompactMessages failed: %v", err)
+	}
+	if usage.PromptTokens != 900 || usage.CompletionTokens != 60 {
+		t.Errorf("Expected the summary's usage returned, got %+v", usage)
+	}
+	if got := EstimateTokens(out); got > budget {
+		t.Errorf("Expected at most %d tokens, got %d", budget, got)
+	}
+	if out[0].Role != "system" {
+		t.Errorf("Expected the system prompt first, got %q", out[0].Role)
+	}
+	if out[1].Role != "assistant" || !strings.HasPrefix(out[1].Content.(string), SummaryPrefix) {
+		t.Errorf("Expected a summary message, got %+v", out[1])
+	}
+
+	// The last two turns are kept verbatim
+	tail := msgs[len(msgs)-8:]
+	if len(out) != 2+len(tail) {
+		t.Fatalf("Expected system, summary and 8 kept messages, got %d", len(out))
+	}
+	for i, msg := range tail {
+		if fmt.Sprint(out[2+i]) != fmt.Sprint(msg) {
+			t.Errorf("Expected kept message %d verbatim, got %+v", i, out[2+i])
+		}
+	}
+
+	if len(*models) != 1 || (*models)[0] != client.SummaryModel(c.Model()) {
+		t.Errorf("Expected one summary request to %s, got %v", client.SummaryModel(c.Model()), *models)
+	}
+}
+
+func TestCompactMessagesKeepsToolPairs(t *testing.T) {
+	c, _ := summaryServer(t, http.StatusOK)
+	out, _, _ := CompactMessages(context.Background(), c, longHistory(30), 3000, 3)
+
+	calls := make(map[string]bool)
+	for _, msg := range out {
+		for _, tc := range msg.ToolCalls {
+			calls[tc.ID] = true
+		}
+		if msg.Role == "tool" && !calls[msg.ToolCallID] {
+			t.Errorf("Tool result %s kept without its call", msg.ToolCallID)
+		}
+	}
+}
+
+func TestCompactMessagesTruncatesLatestTurn(t *testing.T) {
+	c, _ := summaryServer(t, http.StatusOK)
+	msgs := longHistory(1)
+	msgs[3].Content = strings.Repeat("日", 30000)
+
+	out, _, err := CompactMessages(context.Background(), c, msgs, 2000, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := EstimateTokens(out); got > 2000 {
+		t.Errorf("Expected at most 2000 tokens, got %d", got)
+	}
+	if len(out) != len(msgs) {
+		t.Fatalf("Expected the only turn to be kept, got %d messages", len(out))
+	}
+	if content := out[3].Content.(string); !utf8.ValidString(content) {
+		t.Errorf("Expected the tool result cut at a rune boundary, got %q", content[len(content)-60:])
+	}
+}
+
+func TestCompactMessagesSummaryFailure(t *testing.T) {
+	c, _ := summaryServer(t, http.StatusServiceUnavailable)
+	out, _, err := CompactMessages(context.Background(), c, longHistory(40), 4000, 2)
+	if err == nil {
+		t.Error("Expected the summary error to be returned")
+	}
+	if got := EstimateTokens(out); got > 4000 {
+		t.Errorf("Expected old turns to be dropped anyway, got %d tokens", got)
+	}
+	if !strings.Contains(out[1].Content.(string), "earlier messages were removed") {
+		t.Errorf("Expected a placeholder summary, got %+v", out[1])
+	}
+}
+
+func TestCompactMessagesUnderBudget(t *testing.T) {
+	c, models := summaryServer(t, http.StatusOK)
+	msgs := longHistory(2)
+	out, _, err := CompactMessages(context.Background(), c, msgs, 100000, 2)
+	if err != nil || len(out) != len(msgs) || len(*models) != 0 {
+		t.Errorf("Expected no change and no request, got %d messages, %d requests, err %v", len(out), len(*models), err)
+	}
+}
+
+func TestNeedsCompaction(t *testing.T) {
+	defer func(v int) { ContextLimit = v }(ContextLimit)
+
+	msgs := longHistory(10) // about 5000 tokens
+	if NeedsCompaction(msgs, "llama-3.3-70b-versatile") {
+		t.Error("Expected a 128k window to have room")
+	}
+	ContextLimit = 4000
+	if !NeedsCompaction(msgs, "llama-3.3-70b-versatile") {
+		t.Error("Expected compaction with a 4k context limit")
+	}
+	if got := TokenBudget("llama-3.3-70b-versatile"); got != 2000 {
+		t.Errorf("Expected budget 2000, got %d", got)
+	}
+}
+
+func TestHistoryCompact(t *testing.T) {
+	c, _ := summaryServer(t, http.StatusOK)
+	defer func(v int) { ContextLimit = v }(ContextLimit)
+	ContextLimit = 8000
+
+	h := NewHistory(1000)
+	h.AddAll(longHistory(40))
+	if !h.NeedsCompaction(c.Model()) {
+		t.Fatal("Expected the history to need compaction")
+	}
+	if err := h.Compact(context.Background(), c); err != nil {
+		t.Fatal(err)
+	}
+	if got := EstimateTokens(h.Messages()); got > TokenBudget(c.Model()) {
+		t.Errorf("Expected at most %d tokens, got %d", TokenBudget(c.Model()), got)
+	}
+}
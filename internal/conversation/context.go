@@ -13,6 +13,10 @@ import (
 // Context provides system context and prompts
 type Context struct {
 	workingDir string
+	prompt     *Prompt              // Deployment customization, nil for the built-in prompt
+	tools      func() []client.Tool // Listed as {{.ToolList}}
+	session    string               // Instructions added with /system append
+	memory     func() string        // The user's memories, see SetMemory
 }
 
 // NewContext creates a new context
@@ -25,7 +29,14 @@ func NewContext() *Context {
 
 // SystemMessage generates the system message for the conversation
 func (c *Context) SystemMessage() client.Message {
-	prompt := c.buildSystemPrompt()
+	var tools []client.Tool
+	if c.tools != nil {
+		tools = c.tools()
+	}
+	prompt := c.prompt.Render(c.buildSystemPrompt(), NewPromptVars(c.workingDir, tools), c.session)
+	if c.memory != nil {
+		prompt = AppendSection(prompt, c.memory())
+	}
 	return client.Message{
 		Role:    "system",
 		Content: prompt,
@@ -50,13 +61,17 @@ func (c *Context) buildSystemPrompt() string {
 ## Available Tools
 
 ### Read
-Read file contents. Returns content with line numbers.
+Read file contents. Returns content with line numbers. Binary files are summarized; images are shown to vision models.
 - file_path (required): Absolute path to the file
+- offset (optional): Line number to start from (1-indexed)
+- limit (optional): Maximum number of lines (default 2000)
 
 ### Write
-Create or overwrite a file.
+Create or overwrite a file. Existing files must be read first in this conversation.
 - file_path (required): Absolute path
 - content (required): Full file content
+- overwrite (optional): true to replace an existing file without reading it
+- create_dirs (optional): false to fail instead of creating missing parent directories
 
 ### Edit
 Replace exact text in a file. The old_string must match exactly.
@@ -69,6 +84,7 @@ Replace exact text in a file. The old_string must match exactly.
 Find files matching a pattern.
 - pattern (required): Glob pattern like "**/*.go" or "src/*.ts"
 - path (optional): Directory to search in
+- no_ignore (optional): true to include files excluded by .gitignore, node_modules, .git, dist and target
 
 ### Grep
 Search file contents with regex.
@@ -76,11 +92,18 @@ Search file contents with regex.
 - path (optional): File or directory to search
 - glob (optional): Filter files by pattern
 - output_mode (optional): "content" or "files_with_matches"
+- no_ignore (optional): true to include ignored files, as for Glob
 
 ### Bash
-Execute shell commands.
+Execute shell commands. Long output keeps its beginning and end.
 - command (required): The command to run
 - timeout (optional): Timeout in milliseconds
+- run_in_background (optional): Return a job ID right away for long-running commands
+
+### BashOutput
+Check on a background Bash job.
+- job_id (optional): The job to report on; lists jobs when omitted
+- kill (optional): Kill the job
 
 ### WebFetch
 Fetch content from URLs. HTML is converted to readable text. Fast but no JavaScript.
@@ -108,6 +131,34 @@ Control a browser with Playwright. Use for JavaScript-rendered pages, screenshot
 	)
 }
 
+// SetPrompt customizes the system prompt with p, listing the tools
+// returns for {{.ToolList}}
+func (c *Context) SetPrompt(p *Prompt, tools func() []client.Tool) {
+	c.prompt = p
+	c.tools = tools
+}
+
+// SetMemory adds the section section returns, such as the user's memories,
+// to every system message
+func (c *Context) SetMemory(section func() string) {
+	c.memory = section
+}
+
+// SetSessionPrompt replaces the instructions this conversation adds to the
+// system prompt
+func (c *Context) SetSessionPrompt(text string) error {
+	if err := ValidateSessionPrompt(text); err != nil {
+		return err
+	}
+	c.session = text
+	return nil
+}
+
+// SessionPrompt returns the instructions set with SetSessionPrompt
+func (c *Context) SessionPrompt() string {
+	return c.session
+}
+
 // UpdateWorkingDir updates the working directory
 func (c *Context) UpdateWorkingDir(dir string) error {
 	absDir, err := filepath.Abs(dir)
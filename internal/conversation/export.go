@@ -0,0 +1,178 @@
+package conversation
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+// Export formats
+const (
+	FormatMarkdown = "md"
+	FormatJSON     = "json"
+)
+
+// TranscriptVersion is the version of the JSON export format. Import
+// rejects transcripts from newer versions.
+const TranscriptVersion = 1
+
+// Transcript is a conversation in the JSON export format
+type Transcript struct {
+	Version    int              `json:"version"`
+	Title      string           `json:"title,omitempty"`
+	ExportedAt time.Time        `json:"exported_at"`
+	Messages   []client.Message `json:"messages"`
+}
+
+// ExportFormat returns the export format for a file name: markdown for
+// .md and .markdown, JSON for .json
+func ExportFormat(filename string) (string, error) {
+	switch {
+	case strings.HasSuffix(filename, ".md"), strings.HasSuffix(filename, ".markdown"):
+		return FormatMarkdown, nil
+	case strings.HasSuffix(filename, ".json"):
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("cannot tell the format of %s: use a .md or .json file", filename)
+}
+
+// Export renders messages as markdown, for reading, or as a JSON
+// Transcript that Import can load back
+func Export(messages []client.Message, format, title string) ([]byte, error) {
+	switch format {
+	case FormatMarkdown:
+		return []byte(renderMarkdown(messages, title)), nil
+	case FormatJSON:
+		return json.MarshalIndent(Transcript{
+			Version:    TranscriptVersion,
+			Title:      title,
+			ExportedAt: time.Now().UTC(),
+			Messages:   messages,
+		}, "", "  ")
+	}
+	return nil, fmt.Errorf("unknown export format %q (want %s or %s)", format, FormatMarkdown, FormatJSON)
+}
+
+// Import reads a JSON export. It also takes a saved session, which has
+// the same title and messages fields, or a bare array of messages. Tool
+// call IDs are kept, so the conversation can be continued.
+func Import(data []byte) (*Transcript, error) {
+	data = bytes.TrimSpace(data)
+	var t Transcript
+	switch {
+	case len(data) == 0:
+		return nil, errors.New("nothing to import")
+	case data[0] == '[':
+		if err := json.Unmarshal(data, &t.Messages); err != nil {
+			return nil, fmt.Errorf("invalid conversation: %w", err)
+		}
+	case data[0] == '{':
+		if err := json.Unmarshal(data, &t); err != nil {
+			return nil, fmt.Errorf("invalid conversation: %w", err)
+		}
+		if t.Version > TranscriptVersion {
+			return nil, fmt.Errorf("conversation export version %d is newer than this groq-go supports (%d)", t.Version, TranscriptVersion)
+		}
+	default:
+		return nil, errors.New("only JSON exports can be imported")
+	}
+	if err := validateMessages(t.Messages); err != nil {
+		return nil, err
+	}
+	t.Version = TranscriptVersion
+	return &t, nil
+}
+
+// validateMessages checks that every tool result answers an earlier call
+func validateMessages(messages []client.Message) error {
+	if len(messages) == 0 {
+		return errors.New("conversation has no messages")
+	}
+	calls := make(map[string]bool)
+	for i, m := range messages {
+		switch m.Role {
+		case "system", "user":
+		case "assistant":
+			for _, tc := range m.ToolCalls {
+				calls[tc.ID] = true
+			}
+		case "tool":
+			if !calls[m.ToolCallID] {
+				return fmt.Errorf("message %d: tool result for unknown call %q", i+1, m.ToolCallID)
+			}
+		default:
+			return fmt.Errorf("message %d: unknown role %q", i+1, m.Role)
+		}
+	}
+	return nil
+}
+
+// renderMarkdown writes a section per message. Tool calls, tool results
+// and the system prompt fold away in <details> blocks.
+func renderMarkdown(messages []client.Message, title string) string {
+	if title == "" {
+		title = "Conversation"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	for _, m := range messages {
+		switch m.Role {
+		case "system":
+			writeDetails(&b, "System prompt", "", m.Text())
+		case "user":
+			b.WriteString("## User\n\n")
+			writeContent(&b, m)
+		case "assistant":
+			b.WriteString("## Assistant\n\n")
+			writeContent(&b, m)
+			for _, tc := range m.ToolCalls {
+				args, lang := tc.Function.Arguments, ""
+				var out bytes.Buffer
+				if json.Indent(&out, []byte(args), "", "  ") == nil {
+					args, lang = out.String(), "json"
+				}
+				writeDetails(&b, fmt.Sprintf("Tool call: %s (%s)", tc.Function.Name, tc.ID), lang, args)
+			}
+		case "tool":
+			writeDetails(&b, fmt.Sprintf("Tool result (%s)", m.ToolCallID), "", m.Text())
+		}
+	}
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// writeContent writes a message's text and its images. Inline images
+// become a placeholder rather than pages of base64.
+func writeContent(b *strings.Builder, m client.Message) {
+	parts, ok := m.Content.([]client.ContentPart)
+	if !ok {
+		if text := strings.TrimSpace(m.Text()); text != "" {
+			b.WriteString(text + "\n\n")
+		}
+		return
+	}
+	for _, part := range parts {
+		switch {
+		case part.Type == "text" && strings.TrimSpace(part.Text) != "":
+			b.WriteString(strings.TrimSpace(part.Text) + "\n\n")
+		case part.Type == "image_url" && part.ImageURL != nil:
+			if mediaType, data, ok := client.ParseDataURL(part.ImageURL.URL); ok {
+				fmt.Fprintf(b, "*[image: %s, %d bytes]*\n\n", mediaType, len(data)*3/4)
+			} else {
+				fmt.Fprintf(b, "![image](%s)\n\n", part.ImageURL.URL)
+			}
+		}
+	}
+}
+
+func writeDetails(b *strings.Builder, summary, lang, body string) {
+	fence := "```"
+	for strings.Contains(body, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "<details>\n<summary>%s</summary>\n\n%s%s\n%s\n%s\n\n</details>\n\n", summary, fence, lang, strings.TrimRight(body, "\n"), fence)
+}
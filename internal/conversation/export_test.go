@@ -0,0 +1,113 @@
+package conversation
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden files")
+
+// exportTestMessages covers each role, a vision message and a tool round trip
+func exportTestMessages() []client.Message {
+	return []client.Message{
+		client.NewTextMessage("system", "You are a helpful assistant."),
+		client.NewVisionMessage("user", "What is in this screenshot? Also check @main.go", "data:image/png;base64,iVBORw0KGgo=", "https://example.com/cat.jpg"),
+		{Role: "assistant", Content: "Let me read the file.", ToolCalls: []client.ToolCall{
+			{ID: "call_1", Type: "function", Function: client.FunctionCall{Name: "Read", Arguments: `{"file_path":"main.go"}`}},
+			{ID: "call_2", Type: "function", Function: client.FunctionCall{Name: "Bash", Arguments: `not json`}},
+		}},
+		{Role: "tool", ToolCallID: "call_1", Content: "package main\n\n// Example:\n// ```go\n// main()\n// ```\n"},
+		{Role: "tool", ToolCallID: "call_2", Content: "exit status 1"},
+		client.NewTextMessage("assistant", "The screenshot shows a login form, and main.go is empty."),
+	}
+}
+
+func TestExportMarkdownGolden(t *testing.T) {
+	got, err := Export(exportTestMessages(), FormatMarkdown, "Login bug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "export.golden.md")
+	if *update {
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("Exported markdown differs from %s (run with -update to accept):\n%s", golden, got)
+	}
+}
+
+func TestExportJSONRoundTrip(t *testing.T) {
+	messages := exportTestMessages()
+	data, err := Export(messages, FormatJSON, "Login bug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Import(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Version != TranscriptVersion || got.Title != "Login bug" || got.ExportedAt.IsZero() {
+		t.Errorf("Unexpected transcript header %+v", got)
+	}
+	if !reflect.DeepEqual(got.Messages, messages) {
+		t.Errorf("Messages changed in the round trip:\n got %#v\nwant %#v", got.Messages, messages)
+	}
+	if _, ok := got.Messages[1].Content.([]client.ContentPart); !ok {
+		t.Errorf("Expected the vision message to keep its parts, got %T", got.Messages[1].Content)
+	}
+}
+
+func TestImportFormats(t *testing.T) {
+	// A saved session and a bare message array import too
+	session := `{"id": "s1", "title": "Saved", "messages": [{"role": "user", "content": "hi"}]}`
+	if got, err := Import([]byte(session)); err != nil || got.Title != "Saved" || got.Messages[0].Text() != "hi" {
+		t.Errorf("Expected a session to import, got %+v, %v", got, err)
+	}
+	array, _ := json.Marshal(exportTestMessages()[1:])
+	if got, err := Import(array); err != nil || len(got.Messages) != 5 {
+		t.Errorf("Expected a message array to import, got %+v, %v", got, err)
+	}
+
+	bad := map[string]string{
+		"empty":          "  ",
+		"markdown":       "# Conversation\n\n## User\n",
+		"newer version":  `{"version": 99, "messages": [{"role": "user", "content": "hi"}]}`,
+		"no messages":    `{"version": 1, "messages": []}`,
+		"unknown role":   `[{"role": "robot", "content": "hi"}]`,
+		"orphan result":  `[{"role": "user", "content": "hi"}, {"role": "tool", "tool_call_id": "call_9", "content": "x"}]`,
+		"invalid parts":  `[{"role": "user", "content": 42}]`,
+		"malformed json": `{"messages": [`,
+	}
+	for name, data := range bad {
+		if _, err := Import([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestExportFormat(t *testing.T) {
+	for name, want := range map[string]string{"chat.md": FormatMarkdown, "a/b.markdown": FormatMarkdown, "chat.json": FormatJSON} {
+		if got, err := ExportFormat(name); err != nil || got != want {
+			t.Errorf("ExportFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ExportFormat("chat.txt"); err == nil || !strings.Contains(err.Error(), ".md or .json") {
+		t.Errorf("Expected an error for .txt, got %v", err)
+	}
+	if _, err := Export(nil, "html", ""); err == nil {
+		t.Error("Expected an unknown format to fail")
+	}
+}
@@ -1,6 +1,8 @@
 package conversation
 
 import (
+	"context"
+
 	"groq-go/internal/client"
 )
 
@@ -53,6 +55,16 @@ func (h *History) AddAll(msgs []client.Message) {
 	}
 }
 
+// SetSystem replaces the leading system message, or inserts msg when the
+// history has none
+func (h *History) SetSystem(msg client.Message) {
+	if len(h.messages) > 0 && h.messages[0].Role == "system" {
+		h.messages[0] = msg
+		return
+	}
+	h.messages = append([]client.Message{msg}, h.messages...)
+}
+
 // Messages returns all messages in the history
 func (h *History) Messages() []client.Message {
 	return h.messages
@@ -75,3 +87,27 @@ func (h *History) Last() *client.Message {
 	}
 	return &h.messages[len(h.messages)-1]
 }
+
+// UserTurns counts the messages the user sent in msgs
+func UserTurns(msgs []client.Message) int {
+	n := 0
+	for _, m := range msgs {
+		if m.Role == "user" {
+			n++
+		}
+	}
+	return n
+}
+
+// NeedsCompaction reports whether the history is close to model's context window
+func (h *History) NeedsCompaction(model string) bool {
+	return NeedsCompaction(h.messages, model)
+}
+
+// Compact summarizes the oldest turns with a cheap model from c's provider
+// until the history fits TokenBudget for c's model. See CompactMessages.
+func (h *History) Compact(ctx context.Context, c *client.Client) error {
+	msgs, _, err := CompactMessages(ctx, c, h.messages, TokenBudget(c.Model()), KeepTurns)
+	h.messages = msgs
+	return err
+}
@@ -0,0 +1,155 @@
+package conversation
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/template"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+// MaxSessionPromptLen bounds the instructions a session adds to the system prompt
+const MaxSessionPromptLen = 4000
+
+// ReadOnlyNotice is appended to the system prompt in read-only mode so the
+// model says why it cannot change anything instead of trying
+const ReadOnlyNotice = "This is a read-only demo. Tools that write files, run commands or change the deployment are disabled; if asked to change something, explain that it cannot be done here and show what you would do instead."
+
+// PromptVars are the values system prompt templates can use, as
+// {{.WorkingDir}}, {{.Date}}, {{.Platform}} and {{.ToolList}}
+type PromptVars struct {
+	WorkingDir string
+	Date       string
+	Platform   string
+	ToolList   string // One "- Name: summary" line per tool
+}
+
+// NewPromptVars returns the template values for workingDir and tools
+func NewPromptVars(workingDir string, tools []client.Tool) PromptVars {
+	return PromptVars{
+		WorkingDir: workingDir,
+		Date:       time.Now().Format("2006-01-02"),
+		Platform:   runtime.GOOS + "/" + runtime.GOARCH,
+		ToolList:   ToolList(tools),
+	}
+}
+
+// ToolList lists tools with the first line of their descriptions
+func ToolList(tools []client.Tool) string {
+	var b strings.Builder
+	for _, t := range tools {
+		summary, _, _ := strings.Cut(t.Function.Description, "\n")
+		fmt.Fprintf(&b, "- %s: %s\n", t.Function.Name, strings.TrimSpace(summary))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// PromptFile returns the system prompt override, ~/.config/groq-go/system_prompt.md
+func PromptFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".config", "groq-go", "system_prompt.md")
+}
+
+// Prompt customizes a deployment's system prompt: a file that replaces the
+// built-in prompt and text appended to it, both templates over PromptVars.
+// A nil Prompt leaves the built-in prompt alone.
+type Prompt struct {
+	override *template.Template
+	appended *template.Template
+}
+
+// LoadPrompt reads the override at path, if the file exists, and parses
+// appendText. Templates that fail to parse or render are rejected here
+// rather than on every conversation. It returns nil when there is nothing
+// to customize.
+func LoadPrompt(path, appendText string) (*Prompt, error) {
+	p := &Prompt{}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if p.override, err = parsePrompt(filepath.Base(path), string(data)); err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("failed to read system prompt: %w", err)
+	}
+	if strings.TrimSpace(appendText) != "" {
+		if p.appended, err = parsePrompt("system_prompt_append", appendText); err != nil {
+			return nil, err
+		}
+	}
+	if p.override == nil && p.appended == nil {
+		return nil, nil
+	}
+	return p, nil
+}
+
+func parsePrompt(name, text string) (*template.Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system prompt template: %w", err)
+	}
+	if _, err := execute(tmpl, PromptVars{}); err != nil {
+		return nil, fmt.Errorf("invalid system prompt template: %w", err)
+	}
+	return tmpl, nil
+}
+
+func execute(tmpl *template.Template, vars PromptVars) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// Render returns the system prompt: the override in place of def when one
+// was loaded, then the appended text and the session's instructions
+func (p *Prompt) Render(def string, vars PromptVars, session string) string {
+	if p != nil && p.override != nil {
+		if text, err := execute(p.override, vars); err == nil {
+			def = text
+		}
+	}
+	return p.Extend(def, vars, session)
+}
+
+// Extend appends the configured text and the session's instructions to
+// prompt without applying the override, for prompts that must keep their
+// built-in wording
+func (p *Prompt) Extend(prompt string, vars PromptVars, session string) string {
+	if p != nil && p.appended != nil {
+		if text, err := execute(p.appended, vars); err == nil && text != "" {
+			prompt += "\n\n" + text
+		}
+	}
+	if session = strings.TrimSpace(session); session != "" {
+		prompt += "\n\n## Session Instructions\n" + session
+	}
+	return prompt
+}
+
+// AppendSection adds a section, such as the user's memories, to prompt;
+// an empty section leaves it alone
+func AppendSection(prompt, section string) string {
+	if section = strings.TrimSpace(section); section == "" {
+		return prompt
+	}
+	return prompt + "\n\n" + section
+}
+
+// ValidateSessionPrompt checks instructions a session adds to its prompt
+func ValidateSessionPrompt(text string) error {
+	if len(text) > MaxSessionPromptLen {
+		return fmt.Errorf("system prompt too long: %d bytes (max %d)", len(text), MaxSessionPromptLen)
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package conversation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+func testTools() []client.Tool {
+	return []client.Tool{
+		{Type: "function", Function: client.FunctionSchema{Name: "Read", Description: "Read a file.\nMore detail."}},
+		{Type: "function", Function: client.FunctionSchema{Name: "Bash", Description: "Run a command"}},
+	}
+}
+
+func TestPromptTemplateRendering(t *testing.T) {
+	p, err := LoadPrompt(filepath.Join(t.TempDir(), "missing.md"), "Never push to main. Work in {{.WorkingDir}} on {{.Date}}.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := NewPromptVars("/src/app", testTools())
+	if vars.ToolList != "- Read: Read a file.\n- Bash: Run a command" {
+		t.Errorf("Unexpected tool list %q", vars.ToolList)
+	}
+
+	got := p.Render("Built-in prompt", vars, "")
+	want := "Built-in prompt\n\nNever push to main. Work in /src/app on " + vars.Date + "."
+	if got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+
+	// Without customization the built-in prompt is used as is
+	if p, err := LoadPrompt(filepath.Join(t.TempDir(), "missing.md"), "  "); err != nil || p != nil {
+		t.Fatalf("Expected no prompt, got %v (%v)", p, err)
+	}
+	var none *Prompt
+	if got := none.Render("Built-in prompt", vars, ""); got != "Built-in prompt" {
+		t.Errorf("Expected the built-in prompt, got %q", got)
+	}
+
+	for _, bad := range []string{"{{.WorkingDir", "{{.Nonexistent}}"} {
+		if _, err := LoadPrompt(filepath.Join(t.TempDir(), "missing.md"), bad); err == nil {
+			t.Errorf("Expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPromptFileOverride(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "system_prompt.md")
+	if err := os.WriteFile(path, []byte("You are a reviewer in {{.WorkingDir}}.\nTools:\n{{.ToolList}}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	p, err := LoadPrompt(path, "Answer in Japanese.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := NewPromptVars("/src/app", testTools())
+
+	// The file replaces the built-in prompt; appended and session text follow it
+	got := p.Render("Built-in prompt", vars, "Prefer short answers.")
+	want := "You are a reviewer in /src/app.\nTools:\n- Read: Read a file.\n- Bash: Run a command" +
+		"\n\nAnswer in Japanese.\n\n## Session Instructions\nPrefer short answers."
+	if got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	// Extend keeps the given prompt
+	if got := p.Extend("Improvement mode", vars, ""); got != "Improvement mode\n\nAnswer in Japanese." {
+		t.Errorf("Unexpected extended prompt %q", got)
+	}
+
+	if err := os.WriteFile(path, []byte("{{if}}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPrompt(path, ""); err == nil {
+		t.Error("Expected an invalid override file to be rejected")
+	}
+}
+
+func TestContextSessionPrompt(t *testing.T) {
+	c := NewContext()
+	c.SetPrompt(nil, testTools)
+	if err := c.SetSessionPrompt(strings.Repeat("x", MaxSessionPromptLen+1)); err == nil {
+		t.Error("Expected an oversized session prompt to be rejected")
+	}
+	if err := c.SetSessionPrompt("Never push to main."); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.SystemMessage().Content.(string); !strings.HasSuffix(got, "## Session Instructions\nNever push to main.") {
+		t.Errorf("Expected the session instructions last, got %q", got[len(got)-80:])
+	}
+
+	h := NewHistory(10)
+	h.Add(client.Message{Role: "user", Content: "hi"})
+	h.SetSystem(c.SystemMessage())
+	h.SetSystem(client.Message{Role: "system", Content: "replaced"})
+	if msgs := h.Messages(); len(msgs) != 2 || msgs[0].Content != "replaced" || msgs[1].Content != "hi" {
+		t.Errorf("Unexpected history %+v", msgs)
+	}
+}
+
+func TestContextMemory(t *testing.T) {
+	c := NewContext()
+	section := ""
+	c.SetMemory(func() string { return section })
+	c.SetSessionPrompt("Never push to main.")
+	if got := c.SystemMessage().Content.(string); !strings.HasSuffix(got, "Never push to main.") {
+		t.Errorf("Expected no memory section while it is empty, got %q", got[len(got)-80:])
+	}
+
+	section = "## User Memory\n- package_manager: pnpm"
+	if got := c.SystemMessage().Content.(string); !strings.HasSuffix(got, "Never push to main.\n\n"+section) {
+		t.Errorf("Expected the memory section last, got %q", got[len(got)-80:])
+	}
+}
@@ -5,15 +5,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
 	"sync"
 	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/ids"
+	"groq-go/internal/logging"
 )
 
+var log = logging.WithComponent("credits")
+
+// validKey matches idempotency keys, which become file names
+var validKey = regexp.MustCompile(`^[A-Za-z0-9_-]{1,128}$`)
+
 // Manager handles credit management for users
 type Manager struct {
-	dataDir string
-	users   map[string]*UserCredits
-	mu      sync.RWMutex
+	dataDir     string
+	pricingPath string
+	pricing     *Pricing
+	users       map[string]*UserCredits
+	mu          sync.RWMutex
+	spendMu     sync.Mutex
+	now         func() time.Time
+
+	// generation counts writes; cached summaries from an older one are stale
+	generation uint64
+	summaryMu  sync.Mutex
+	summaryGen uint64
+	summaries  map[summaryWindow]*UsageSummary
 }
 
 // UserCredits represents a user's credit balance
@@ -26,13 +46,14 @@ type UserCredits struct {
 	FreeCredits  int       `json:"free_credits"`  // Free credits given
 	LastUsed     time.Time `json:"last_used"`
 	CreatedAt    time.Time `json:"created_at"`
+	MergedInto   string    `json:"merged_into,omitempty"` // Account this balance moved to
 	Transactions []Transaction `json:"transactions"`
 }
 
 // Transaction represents a credit transaction
 type Transaction struct {
 	ID        string    `json:"id"`
-	Type      string    `json:"type"` // "use", "buy", "free", "refund"
+	Type      string    `json:"type"` // "use", "buy", "free", "refund", "merge"
 	Amount    int       `json:"amount"`
 	Balance   int       `json:"balance_after"`
 	Model     string    `json:"model,omitempty"`
@@ -41,7 +62,9 @@ type Transaction struct {
 	Timestamp time.Time `json:"timestamp"`
 }
 
-// CreditCost defines cost per model
+// CreditCost defines the default flat cost per request, used for models
+// without token prices and responses without usage. Operators override it
+// in credits-config.json; see Pricing.
 var CreditCost = map[string]int{
 	// Claude models (expensive)
 	"claude-sonnet-4-20250514":    5,
@@ -56,11 +79,57 @@ var CreditCost = map[string]int{
 	// OpenAI models
 	"gpt-4o":                      5,
 	"gpt-4o-mini":                 2,
+	// Gemini models
+	"gemini-2.0-flash":            1,
+	"gemini-1.5-flash":            1,
+	"gemini-1.5-pro":              4,
 }
 
+// PricePer1kPrompt and PricePer1kCompletion are the default credits per
+// 1000 prompt and completion tokens, one credit being roughly one US cent
+var (
+	PricePer1kPrompt = map[string]float64{
+		"claude-sonnet-4-20250514":     0.3,
+		"claude-3-5-sonnet-20241022":   0.3,
+		"claude-3-5-haiku-20241022":    0.08,
+		"claude-3-opus-20240229":       1.5,
+		"llama-3.3-70b-versatile":      0.059,
+		"llama-3.1-8b-instant":         0.005,
+		"llama-3.2-90b-vision-preview": 0.09,
+		"mixtral-8x7b-32768":           0.024,
+		"gpt-4o":                       0.25,
+		"gpt-4o-mini":                  0.015,
+		"gemini-2.0-flash":             0.01,
+		"gemini-1.5-flash":             0.0075,
+		"gemini-1.5-pro":               0.125,
+	}
+	PricePer1kCompletion = map[string]float64{
+		"claude-sonnet-4-20250514":     1.5,
+		"claude-3-5-sonnet-20241022":   1.5,
+		"claude-3-5-haiku-20241022":    0.4,
+		"claude-3-opus-20240229":       7.5,
+		"llama-3.3-70b-versatile":      0.079,
+		"llama-3.1-8b-instant":         0.008,
+		"llama-3.2-90b-vision-preview": 0.09,
+		"mixtral-8x7b-32768":           0.024,
+		"gpt-4o":                       1.0,
+		"gpt-4o-mini":                  0.06,
+		"gemini-2.0-flash":             0.04,
+		"gemini-1.5-flash":             0.03,
+		"gemini-1.5-pro":               0.5,
+	}
+)
+
 const (
+	// FreeCreditsForNewUser is the default welcome bonus
 	FreeCreditsForNewUser = 100
 	DefaultDataDir        = ".config/groq-go/credits"
+
+	// processedDirName holds a marker per applied AddCreditsOnce key
+	processedDirName = "processed"
+
+	// maxTransactions is how many transactions are kept per user
+	maxTransactions = 100
 )
 
 // NewManager creates a new credit manager
@@ -75,9 +144,18 @@ func NewManager() (*Manager, error) {
 		return nil, err
 	}
 
+	pricingPath := DefaultPricingPath()
+	pricing, err := LoadPricing(pricingPath)
+	if err != nil {
+		return nil, err
+	}
+
 	m := &Manager{
-		dataDir: dataDir,
-		users:   make(map[string]*UserCredits),
+		dataDir:     dataDir,
+		pricingPath: pricingPath,
+		pricing:     pricing,
+		users:       make(map[string]*UserCredits),
+		now:         time.Now,
 	}
 
 	// Load existing users
@@ -98,20 +176,26 @@ func (m *Manager) GetOrCreateUser(userID, email string) *UserCredits {
 	}
 
 	// Create new user with free credits
+	free := m.pricing.FreeCredits
+	now := m.now()
 	user := &UserCredits{
 		UserID:      userID,
 		Email:       email,
-		Balance:     FreeCreditsForNewUser,
-		FreeCredits: FreeCreditsForNewUser,
-		CreatedAt:   time.Now(),
-		Transactions: []Transaction{{
-			ID:        fmt.Sprintf("tx_%d", time.Now().UnixNano()),
+		Balance:     free,
+		FreeCredits: free,
+		CreatedAt:   now,
+	}
+	if free > 0 {
+		if err := m.appendTransaction(user, Transaction{
+			ID:        ids.Prefixed("tx", 16),
 			Type:      "free",
-			Amount:    FreeCreditsForNewUser,
-			Balance:   FreeCreditsForNewUser,
+			Amount:    free,
+			Balance:   free,
 			Note:      "Welcome bonus",
-			Timestamp: time.Now(),
-		}},
+			Timestamp: now,
+		}); err != nil {
+			log.Error("Failed to record welcome bonus", "user_id", userID, "error", err)
+		}
 	}
 
 	m.users[userID] = user
@@ -130,40 +214,49 @@ func (m *Manager) GetBalance(userID string) int {
 	return 0
 }
 
-// UseCredits deducts credits for API usage
-func (m *Manager) UseCredits(userID, model string, tokens int) error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
+// UseCredits deducts the cost of a request with the given usage and
+// records it against the user's and IP's daily spend. Daily caps are not
+// enforced here so a turn that crosses a cap still completes; CheckCredits
+// blocks the next one. A turn that costs more than the remaining balance
+// takes it to zero. The spend is recorded first, under its file lock but
+// not m.mu, and the balance only changes once that succeeded.
+func (m *Manager) UseCredits(userID, ip, model string, usage client.Usage) error {
+	m.mu.RLock()
 	user, exists := m.users[userID]
+	var cost int
+	if exists {
+		cost = min(m.pricing.ComputeCost(model, usage), user.Balance)
+	}
+	m.mu.RUnlock()
 	if !exists {
 		return fmt.Errorf("user not found")
 	}
 
-	cost := getCost(model)
-	if user.Balance < cost {
-		return fmt.Errorf("insufficient credits: need %d, have %d", cost, user.Balance)
+	if err := m.recordSpend(userID, ip, cost); err != nil {
+		return fmt.Errorf("failed to record spend: %w", err)
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Another request may have spent from the balance meanwhile
+	cost = min(cost, user.Balance)
+	now := m.now()
 	user.Balance -= cost
 	user.TotalUsed += cost
-	user.LastUsed = time.Now()
+	user.LastUsed = now
 
-	user.Transactions = append(user.Transactions, Transaction{
-		ID:        fmt.Sprintf("tx_%d", time.Now().UnixNano()),
+	if err := m.appendTransaction(user, Transaction{
+		ID:        ids.Prefixed("tx", 16),
 		Type:      "use",
 		Amount:    -cost,
 		Balance:   user.Balance,
 		Model:     model,
-		Tokens:    tokens,
-		Timestamp: time.Now(),
-	})
-
-	// Keep only last 100 transactions
-	if len(user.Transactions) > 100 {
-		user.Transactions = user.Transactions[len(user.Transactions)-100:]
+		Tokens:    usage.TotalTokens,
+		Timestamp: now,
+	}); err != nil {
+		return err
 	}
-
 	return m.saveUser(user)
 }
 
@@ -172,6 +265,47 @@ func (m *Manager) AddCredits(userID string, amount int, txType, note string) err
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	return m.addCreditsLocked(userID, amount, txType, note)
+}
+
+// AddCreditsOnce is AddCredits keyed by an external ID, such as a payment
+// event, so a redelivered event does not credit twice. The user is created
+// without a welcome bonus if needed. It reports whether credits were added.
+func (m *Manager) AddCreditsOnce(key, userID string, amount int, txType, note string) (bool, error) {
+	if !validKey.MatchString(key) {
+		return false, fmt.Errorf("invalid idempotency key %q", key)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The marker is written first so a crash can't leave credits without it
+	dir := filepath.Join(m.dataDir, processedDirName)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return false, err
+	}
+	marker := filepath.Join(dir, key)
+	f, err := os.OpenFile(marker, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	fmt.Fprintf(f, "%s %d\n", userID, amount)
+	f.Close()
+
+	if _, exists := m.users[userID]; !exists {
+		m.users[userID] = &UserCredits{UserID: userID, CreatedAt: m.now()}
+	}
+	if err := m.addCreditsLocked(userID, amount, txType, note); err != nil {
+		os.Remove(marker)
+		return false, err
+	}
+	return true, nil
+}
+
+func (m *Manager) addCreditsLocked(userID string, amount int, txType, note string) error {
 	user, exists := m.users[userID]
 	if !exists {
 		return fmt.Errorf("user not found")
@@ -184,30 +318,112 @@ func (m *Manager) AddCredits(userID string, amount int, txType, note string) err
 		user.FreeCredits += amount
 	}
 
-	user.Transactions = append(user.Transactions, Transaction{
-		ID:        fmt.Sprintf("tx_%d", time.Now().UnixNano()),
+	if err := m.appendTransaction(user, Transaction{
+		ID:        ids.Prefixed("tx", 16),
 		Type:      txType,
 		Amount:    amount,
 		Balance:   user.Balance,
 		Note:      note,
-		Timestamp: time.Now(),
-	})
+		Timestamp: m.now(),
+	}); err != nil {
+		return err
+	}
 
 	return m.saveUser(user)
 }
 
-// CheckCredits checks if user has enough credits
-func (m *Manager) CheckCredits(userID, model string) (bool, int, int) {
+// MergeUsers moves the balance and totals of fromID into toID, typically an
+// anonymous IP-based balance into an account on first login. toID is created
+// without a welcome bonus if needed. The source record is kept with a zero
+// balance so the same client is not granted a second welcome bonus. It
+// returns the number of credits moved.
+func (m *Manager) MergeUsers(fromID, toID string) (int, error) {
+	if fromID == toID {
+		return 0, fmt.Errorf("cannot merge user %s into itself", fromID)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	from, exists := m.users[fromID]
+	if !exists || from.MergedInto != "" {
+		return 0, nil
+	}
+
+	now := m.now()
+	to, exists := m.users[toID]
+	if !exists {
+		to = &UserCredits{UserID: toID, Email: from.Email, CreatedAt: now}
+		m.users[toID] = to
+	}
+
+	moved := from.Balance
+	to.Balance += moved
+	to.TotalUsed += from.TotalUsed
+	to.TotalBought += from.TotalBought
+	to.FreeCredits += from.FreeCredits
+	if from.LastUsed.After(to.LastUsed) {
+		to.LastUsed = from.LastUsed
+	}
+	if err := m.appendTransaction(to, Transaction{
+		ID:        ids.Prefixed("tx", 16),
+		Type:      "merge",
+		Amount:    moved,
+		Balance:   to.Balance,
+		Note:      "Merged from " + fromID,
+		Timestamp: now,
+	}); err != nil {
+		return 0, err
+	}
+
+	from.Balance = 0
+	from.TotalUsed = 0
+	from.TotalBought = 0
+	from.FreeCredits = 0
+	from.MergedInto = toID
+	if err := m.appendTransaction(from, Transaction{
+		ID:        ids.Prefixed("tx", 16),
+		Type:      "merge",
+		Amount:    -moved,
+		Balance:   0,
+		Note:      "Merged into " + toID,
+		Timestamp: now,
+	}); err != nil {
+		return 0, err
+	}
+
+	if err := m.saveUser(to); err != nil {
+		return 0, err
+	}
+	return moved, m.saveUser(from)
+}
+
+// CheckCredits checks if user can afford a request to model whose prompt is
+// promptBytes long. The cost is estimated from the prompt alone, so it is a
+// lower bound. It returns a *LimitError when the user or IP has reached a
+// daily cap.
+func (m *Manager) CheckCredits(userID, ip, model string, promptBytes int) (bool, int, int, error) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	user, exists := m.users[userID]
 	if !exists {
-		return false, 0, 0
+		return false, 0, 0, nil
 	}
 
-	cost := getCost(model)
-	return user.Balance >= cost, user.Balance, cost
+	tokens := EstimateTokens(promptBytes)
+	cost := m.pricing.ComputeCost(model, client.Usage{PromptTokens: tokens, TotalTokens: tokens})
+	if err := m.checkLimits(userID, ip, m.pricing.DailyLimits); err != nil {
+		return false, user.Balance, cost, err
+	}
+	return user.Balance >= cost, user.Balance, cost, nil
+}
+
+// ComputeCost returns the credits a request to model with usage costs
+func (m *Manager) ComputeCost(model string, usage client.Usage) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.pricing.ComputeCost(model, usage)
 }
 
 // GetUserInfo returns user credit info
@@ -221,14 +437,42 @@ func (m *Manager) GetUserInfo(userID string) *UserCredits {
 	return nil
 }
 
-func getCost(model string) int {
-	if cost, ok := CreditCost[model]; ok {
-		return cost
+// Pricing returns a copy of the active pricing
+func (m *Manager) Pricing() Pricing {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	costs := make(map[string]int, len(m.pricing.ModelCosts))
+	for model, cost := range m.pricing.ModelCosts {
+		costs[model] = cost
+	}
+	return Pricing{
+		FreeCredits:          m.pricing.FreeCredits,
+		DefaultCost:          m.pricing.DefaultCost,
+		ModelCosts:           costs,
+		PricePer1kPrompt:     copyPrices(m.pricing.PricePer1kPrompt),
+		PricePer1kCompletion: copyPrices(m.pricing.PricePer1kCompletion),
+		DailyLimits:          m.pricing.DailyLimits,
+	}
+}
+
+// ReloadPricing re-reads the pricing config. On error the current pricing
+// stays in effect. Balances are not touched.
+func (m *Manager) ReloadPricing() error {
+	pricing, err := LoadPricing(m.pricingPath)
+	if err != nil {
+		return err
 	}
-	return 1 // Default cost
+
+	m.mu.Lock()
+	m.pricing = pricing
+	m.mu.Unlock()
+	return nil
 }
 
+// saveUser writes a user's file; m.mu must be held for writing
 func (m *Manager) saveUser(user *UserCredits) error {
+	m.generation++
 	path := filepath.Join(m.dataDir, user.UserID+".json")
 	data, err := json.MarshalIndent(user, "", "  ")
 	if err != nil {
@@ -254,14 +498,19 @@ func (m *Manager) loadAll() error {
 		path := filepath.Join(m.dataDir, entry.Name())
 		data, err := os.ReadFile(path)
 		if err != nil {
+			log.Warn("Skipping unreadable credits file", "path", path, "error", err)
 			continue
 		}
 
 		var user UserCredits
 		if err := json.Unmarshal(data, &user); err != nil {
+			log.Warn("Skipping invalid credits file", "path", path, "error", err)
 			continue
 		}
 
+		if err := m.seedLedger(&user); err != nil {
+			return fmt.Errorf("failed to seed ledger for %s: %w", user.UserID, err)
+		}
 		m.users[user.UserID] = &user
 	}
 
@@ -0,0 +1,103 @@
+package credits
+
+import (
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+func TestMergeUsers(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 100, "model_costs": {"gpt-4o": 5}}`)
+	m.GetOrCreateUser("user_10_0_0_1", "")
+	if err := m.UseCredits("user_10_0_0_1", "10.0.0.1", "gpt-4o", client.Usage{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddCredits("user_10_0_0_1", 50, "buy", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// First login: the account does not exist yet and gets no welcome bonus
+	moved, err := m.MergeUsers("user_10_0_0_1", "acct_alice")
+	if err != nil {
+		t.Fatalf("MergeUsers failed: %v", err)
+	}
+	if moved != 145 {
+		t.Errorf("Expected 145 credits moved, got %d", moved)
+	}
+	acct := m.GetUserInfo("acct_alice")
+	if acct == nil {
+		t.Fatal("Expected account to be created")
+	}
+	if acct.Balance != 145 || acct.TotalUsed != 5 || acct.TotalBought != 50 || acct.FreeCredits != 100 {
+		t.Errorf("Unexpected account totals: %+v", acct)
+	}
+	if last := acct.Transactions[len(acct.Transactions)-1]; last.Type != "merge" || last.Amount != 145 {
+		t.Errorf("Expected merge transaction, got %+v", last)
+	}
+
+	// The anonymous record stays empty so it is not re-granted a bonus
+	anon := m.GetOrCreateUser("user_10_0_0_1", "")
+	if anon.Balance != 0 || anon.MergedInto != "acct_alice" {
+		t.Errorf("Expected emptied source record, got balance=%d merged_into=%q", anon.Balance, anon.MergedInto)
+	}
+
+	// Merging again is a no-op
+	if moved, err := m.MergeUsers("user_10_0_0_1", "acct_alice"); err != nil || moved != 0 {
+		t.Errorf("Expected no-op second merge, got moved=%d err=%v", moved, err)
+	}
+	if got := m.GetBalance("acct_alice"); got != 145 {
+		t.Errorf("Expected balance 145 after second merge, got %d", got)
+	}
+}
+
+func TestMergeUsersIntoExistingAccount(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 100}`)
+	m.GetOrCreateUser("acct_bob", "")
+	m.GetOrCreateUser("user_10_0_0_2", "")
+
+	if _, err := m.MergeUsers("user_10_0_0_2", "acct_bob"); err != nil {
+		t.Fatalf("MergeUsers failed: %v", err)
+	}
+	if got := m.GetBalance("acct_bob"); got != 200 {
+		t.Errorf("Expected balances to add up to 200, got %d", got)
+	}
+
+	// Unknown source and self-merge
+	if moved, err := m.MergeUsers("user_missing", "acct_bob"); err != nil || moved != 0 {
+		t.Errorf("Expected no-op for unknown source, got moved=%d err=%v", moved, err)
+	}
+	if _, err := m.MergeUsers("acct_bob", "acct_bob"); err == nil {
+		t.Error("Expected error merging a user into itself")
+	}
+}
+
+func TestAddCreditsOnce(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 100}`)
+
+	for i := 0; i < 2; i++ {
+		added, err := m.AddCreditsOnce("evt_1", "acct_carol", 300, "buy", "")
+		if err != nil {
+			t.Fatalf("AddCreditsOnce failed: %v", err)
+		}
+		if added != (i == 0) {
+			t.Errorf("Call %d: expected added=%v", i, i == 0)
+		}
+	}
+	// The buyer is created without a welcome bonus
+	user := m.GetUserInfo("acct_carol")
+	if user.Balance != 300 || user.TotalBought != 300 || user.FreeCredits != 0 {
+		t.Errorf("Unexpected user %+v", user)
+	}
+
+	// Keys survive a restart
+	m2, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added, _ := m2.AddCreditsOnce("evt_1", "acct_carol", 300, "buy", ""); added {
+		t.Error("Expected key to be remembered across restarts")
+	}
+	if _, err := m2.AddCreditsOnce("../evt", "acct_carol", 300, "buy", ""); err == nil {
+		t.Error("Expected error for a key with a path separator")
+	}
+}
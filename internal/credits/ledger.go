@@ -0,0 +1,185 @@
+package credits
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ledgerDirName holds ledger/<user>/<YYYY-MM>.jsonl, one append-only file
+// per user and month with every transaction ever applied
+const ledgerDirName = "ledger"
+
+// BalanceCheck is the result of replaying a user's ledger
+type BalanceCheck struct {
+	UserID        string `json:"user_id"`
+	Balance       int    `json:"balance"`        // Balance on the account
+	LedgerBalance int    `json:"ledger_balance"` // Balance the ledger adds up to
+	Drift         int    `json:"drift"`          // Balance - LedgerBalance
+	Entries       int    `json:"entries"`
+}
+
+// OK reports whether the balance matches the ledger
+func (c BalanceCheck) OK() bool {
+	return c.Drift == 0
+}
+
+func (m *Manager) ledgerDir(userID string) string {
+	return filepath.Join(m.dataDir, ledgerDirName, userID)
+}
+
+// appendTransaction writes tx to the user's ledger and adds it to the
+// bounded recent-activity list; m.mu must be held for writing
+func (m *Manager) appendTransaction(user *UserCredits, tx Transaction) error {
+	if err := m.writeLedger(user.UserID, tx); err != nil {
+		return fmt.Errorf("failed to write ledger: %w", err)
+	}
+	user.Transactions = append(user.Transactions, tx)
+	if len(user.Transactions) > maxTransactions {
+		user.Transactions = user.Transactions[len(user.Transactions)-maxTransactions:]
+	}
+	return nil
+}
+
+func (m *Manager) writeLedger(userID string, txs ...Transaction) error {
+	dir := m.ledgerDir(userID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// Transactions are grouped by month so each file is opened once
+	byMonth := make(map[string][]byte)
+	var months []string
+	for _, tx := range txs {
+		line, err := json.Marshal(tx)
+		if err != nil {
+			return err
+		}
+		month := tx.Timestamp.UTC().Format("2006-01")
+		if _, ok := byMonth[month]; !ok {
+			months = append(months, month)
+		}
+		byMonth[month] = append(append(byMonth[month], line...), '\n')
+	}
+	for _, month := range months {
+		f, err := os.OpenFile(filepath.Join(dir, month+".jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(byMonth[month])
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedLedger starts the ledger of a user saved before ledgers existed from
+// their recent transactions. Replay then starts from the balance before
+// the oldest of them.
+func (m *Manager) seedLedger(user *UserCredits) error {
+	if _, err := os.Stat(m.ledgerDir(user.UserID)); err == nil || len(user.Transactions) == 0 {
+		return nil
+	}
+	return m.writeLedger(user.UserID, user.Transactions...)
+}
+
+// GetLedger returns a user's transactions between from (inclusive) and to
+// (exclusive), oldest first. A zero from or to leaves that end open.
+// Unlike UserCredits.Transactions the ledger is never truncated.
+func (m *Manager) GetLedger(userID string, from, to time.Time) ([]Transaction, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.readLedger(userID, from, to)
+}
+
+func (m *Manager) readLedger(userID string, from, to time.Time) ([]Transaction, error) {
+	dir := m.ledgerDir(userID)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var months []string
+	for _, e := range entries {
+		month, ok := strings.CutSuffix(e.Name(), ".jsonl")
+		if !ok || e.IsDir() {
+			continue
+		}
+		// Skip files wholly outside the window
+		if !from.IsZero() && month < from.UTC().Format("2006-01") {
+			continue
+		}
+		if !to.IsZero() && month > to.UTC().Format("2006-01") {
+			continue
+		}
+		months = append(months, month)
+	}
+	sort.Strings(months)
+
+	var txs []Transaction
+	for _, month := range months {
+		path := filepath.Join(dir, month+".jsonl")
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			var tx Transaction
+			if err := json.Unmarshal(scanner.Bytes(), &tx); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("%s line %d: %w", path, line, err)
+			}
+			if tx.Timestamp.Before(from) || (!to.IsZero() && !tx.Timestamp.Before(to)) {
+				continue
+			}
+			txs = append(txs, tx)
+		}
+		err = scanner.Err()
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(txs, func(i, j int) bool { return txs[i].Timestamp.Before(txs[j].Timestamp) })
+	return txs, nil
+}
+
+// VerifyBalance replays a user's ledger and compares the result with the
+// account balance. A non-zero Drift means the balance was changed outside
+// the ledger, or the ledger was edited.
+func (m *Manager) VerifyBalance(userID string) (BalanceCheck, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	user, exists := m.users[userID]
+	if !exists {
+		return BalanceCheck{}, fmt.Errorf("user not found")
+	}
+	txs, err := m.readLedger(userID, time.Time{}, time.Time{})
+	if err != nil {
+		return BalanceCheck{}, err
+	}
+
+	check := BalanceCheck{UserID: userID, Balance: user.Balance, Entries: len(txs)}
+	if len(txs) > 0 {
+		// A seeded ledger starts mid-history; new ones start from zero
+		check.LedgerBalance = txs[0].Balance - txs[0].Amount
+	}
+	for _, tx := range txs {
+		check.LedgerBalance += tx.Amount
+	}
+	check.Drift = check.Balance - check.LedgerBalance
+	return check, nil
+}
@@ -0,0 +1,147 @@
+package credits
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+func TestLedgerRotatesByMonth(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 100, "model_costs": {"gpt-4o": 5}}`)
+	now := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	m.GetOrCreateUser("alice", "")
+	if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+		t.Fatal(err)
+	}
+	now = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for month, want := range map[string]int{"2026-01": 2, "2026-02": 1} {
+		data, err := os.ReadFile(filepath.Join(m.ledgerDir("alice"), month+".jsonl"))
+		if err != nil {
+			t.Fatalf("Expected a ledger file for %s: %v", month, err)
+		}
+		if lines := bytes.Count(data, []byte("\n")); lines != want {
+			t.Errorf("Expected %d entries in %s, got %d", want, month, lines)
+		}
+	}
+
+	all, err := m.GetLedger("alice", time.Time{}, time.Time{})
+	if err != nil || len(all) != 3 || all[0].Type != "free" || all[2].Balance != 90 {
+		t.Fatalf("Unexpected ledger %+v, %v", all, err)
+	}
+	feb, _ := m.GetLedger("alice", now, time.Time{})
+	if len(feb) != 1 || !feb[0].Timestamp.Equal(now) {
+		t.Errorf("Expected only the February entry, got %+v", feb)
+	}
+	jan, _ := m.GetLedger("alice", time.Time{}, now)
+	if len(jan) != 2 {
+		t.Errorf("Expected the two January entries, got %+v", jan)
+	}
+}
+
+func TestLedgerKeepsPurchasesPastTruncation(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 0, "model_costs": {"gpt-4o": 1}}`)
+	if _, err := m.AddCreditsOnce("evt_1", "alice", 500, "buy", "Small pack"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxTransactions+20; i++ {
+		if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	user := m.GetUserInfo("alice")
+	if len(user.Transactions) != maxTransactions || user.Transactions[0].Type == "buy" {
+		t.Errorf("Expected the recent list bounded to %d without the purchase", maxTransactions)
+	}
+	ledger, err := m.GetLedger("alice", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ledger) != maxTransactions+21 || ledger[0].Type != "buy" || ledger[0].Amount != 500 || ledger[0].Note != "Small pack" {
+		t.Errorf("Expected the purchase at the start of a complete ledger, got %d entries starting %+v", len(ledger), ledger[0])
+	}
+	if check, err := m.VerifyBalance("alice"); err != nil || !check.OK() || check.Balance != 380 {
+		t.Errorf("Expected a consistent balance of 380, got %+v, %v", check, err)
+	}
+}
+
+func TestVerifyBalanceDetectsDrift(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 100, "model_costs": {"gpt-4o": 5}}`)
+	m.GetOrCreateUser("alice", "")
+	if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.MergeUsers("alice", "acct_alice"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"alice", "acct_alice"} {
+		if check, err := m.VerifyBalance(id); err != nil || !check.OK() {
+			t.Errorf("Expected %s consistent, got %+v, %v", id, check, err)
+		}
+	}
+
+	// Corrupt the balance on disk and reload
+	path := filepath.Join(m.dataDir, "acct_alice.json")
+	data, _ := os.ReadFile(path)
+	var user UserCredits
+	json.Unmarshal(data, &user)
+	user.Balance += 1000
+	data, _ = json.Marshal(user)
+	os.WriteFile(path, data, 0644)
+
+	reloaded, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	check, err := reloaded.VerifyBalance("acct_alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if check.OK() || check.Drift != 1000 || check.LedgerBalance != 95 {
+		t.Errorf("Expected a drift of 1000 over a ledger balance of 95, got %+v", check)
+	}
+	if _, err := reloaded.VerifyBalance("nobody"); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+}
+
+func TestLedgerSeededForExistingUsers(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, DefaultDataDir)
+	os.MkdirAll(dir, 0755)
+	// A user from before ledgers, whose older history was already trimmed
+	os.WriteFile(filepath.Join(dir, "bob.json"), []byte(`{"user_id": "bob", "balance": 40, "transactions": [
+		{"id": "tx1", "type": "use", "amount": -5, "balance_after": 45, "timestamp": "2026-03-01T10:00:00Z"},
+		{"id": "tx2", "type": "use", "amount": -5, "balance_after": 40, "timestamp": "2026-03-02T10:00:00Z"}]}`), 0644)
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ledger, _ := m.GetLedger("bob", time.Time{}, time.Time{}); len(ledger) != 2 {
+		t.Fatalf("Expected the recent transactions seeded into the ledger, got %+v", ledger)
+	}
+	if check, err := m.VerifyBalance("bob"); err != nil || !check.OK() {
+		t.Errorf("Expected a seeded ledger to replay from its first entry, got %+v, %v", check, err)
+	}
+
+	// Loading again must not seed twice
+	if _, err := NewManager(); err != nil {
+		t.Fatal(err)
+	}
+	if ledger, _ := m.GetLedger("bob", time.Time{}, time.Time{}); len(ledger) != 2 {
+		t.Errorf("Expected the ledger seeded once, got %d entries", len(ledger))
+	}
+}
@@ -0,0 +1,199 @@
+package credits
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"groq-go/internal/client"
+)
+
+// PricingConfigFile is the name of the pricing file in the config directory
+const PricingConfigFile = "credits-config.json"
+
+// DefaultCost is charged for models missing from the cost table
+const DefaultCost = 1
+
+// MinimumCharge is the least a request with token pricing costs
+const MinimumCharge = 1
+
+// Pricing holds the welcome bonus, per-model costs and daily spend caps.
+// Models with per-1k-token prices are charged by usage; ModelCosts is the
+// flat fallback for other models and for responses without usage.
+type Pricing struct {
+	FreeCredits          int                `json:"free_credits"`
+	DefaultCost          int                `json:"default_cost"`
+	ModelCosts           map[string]int     `json:"model_costs"`
+	PricePer1kPrompt     map[string]float64 `json:"price_per_1k_prompt"`
+	PricePer1kCompletion map[string]float64 `json:"price_per_1k_completion"`
+	DailyLimits          DailyLimits        `json:"daily_limits"`
+}
+
+// DailyLimits caps spend per UTC day regardless of balance. Zero means unlimited.
+type DailyLimits struct {
+	UserCredits  int `json:"user_credits"`
+	UserRequests int `json:"user_requests"`
+	IPCredits    int `json:"ip_credits"`
+	IPRequests   int `json:"ip_requests"`
+}
+
+// pricingFile is the on-disk format; nil fields fall back to the defaults
+type pricingFile struct {
+	FreeCredits          *int               `json:"free_credits"`
+	DefaultCost          *int               `json:"default_cost"`
+	ModelCosts           map[string]int     `json:"model_costs"`
+	PricePer1kPrompt     map[string]float64 `json:"price_per_1k_prompt"`
+	PricePer1kCompletion map[string]float64 `json:"price_per_1k_completion"`
+	DailyLimits          *DailyLimits       `json:"daily_limits"`
+}
+
+// DefaultPricing returns the built-in pricing
+func DefaultPricing() *Pricing {
+	costs := make(map[string]int, len(CreditCost))
+	for model, cost := range CreditCost {
+		costs[model] = cost
+	}
+	return &Pricing{
+		FreeCredits:          FreeCreditsForNewUser,
+		DefaultCost:          DefaultCost,
+		ModelCosts:           costs,
+		PricePer1kPrompt:     copyPrices(PricePer1kPrompt),
+		PricePer1kCompletion: copyPrices(PricePer1kCompletion),
+	}
+}
+
+func copyPrices(prices map[string]float64) map[string]float64 {
+	out := make(map[string]float64, len(prices))
+	for model, price := range prices {
+		out[model] = price
+	}
+	return out
+}
+
+// DefaultPricingPath returns ~/.config/groq-go/credits-config.json
+func DefaultPricingPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return filepath.Join(home, ".config", "groq-go", PricingConfigFile)
+}
+
+// LoadPricing reads pricing from path. A missing file yields the defaults.
+func LoadPricing(path string) (*Pricing, error) {
+	p := DefaultPricing()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return p, nil
+		}
+		return nil, fmt.Errorf("failed to read pricing config: %w", err)
+	}
+
+	var f pricingFile
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&f); err != nil {
+		return nil, fmt.Errorf("failed to parse pricing config: %w", err)
+	}
+
+	if f.FreeCredits != nil {
+		p.FreeCredits = *f.FreeCredits
+	}
+	if f.DefaultCost != nil {
+		p.DefaultCost = *f.DefaultCost
+	}
+	if f.ModelCosts != nil {
+		p.ModelCosts = f.ModelCosts
+	}
+	if f.PricePer1kPrompt != nil {
+		p.PricePer1kPrompt = f.PricePer1kPrompt
+	}
+	if f.PricePer1kCompletion != nil {
+		p.PricePer1kCompletion = f.PricePer1kCompletion
+	}
+	if f.DailyLimits != nil {
+		p.DailyLimits = *f.DailyLimits
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Validate checks that all values are non-negative and model names are set
+func (p *Pricing) Validate() error {
+	if p.FreeCredits < 0 {
+		return fmt.Errorf("invalid pricing config: free_credits must be non-negative")
+	}
+	if p.DefaultCost < 0 {
+		return fmt.Errorf("invalid pricing config: default_cost must be non-negative")
+	}
+	l := p.DailyLimits
+	if l.UserCredits < 0 || l.UserRequests < 0 || l.IPCredits < 0 || l.IPRequests < 0 {
+		return fmt.Errorf("invalid pricing config: daily_limits must be non-negative")
+	}
+	for model, cost := range p.ModelCosts {
+		if model == "" {
+			return fmt.Errorf("invalid pricing config: empty model name")
+		}
+		if cost < 0 {
+			return fmt.Errorf("invalid pricing config: cost for %s must be non-negative", model)
+		}
+	}
+	for _, prices := range []map[string]float64{p.PricePer1kPrompt, p.PricePer1kCompletion} {
+		for model, price := range prices {
+			if model == "" {
+				return fmt.Errorf("invalid pricing config: empty model name")
+			}
+			if price < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+				return fmt.Errorf("invalid pricing config: token price for %s must be non-negative", model)
+			}
+		}
+	}
+	return nil
+}
+
+// Cost returns the flat cost of one request to model
+func (p *Pricing) Cost(model string) int {
+	if cost, ok := p.ModelCosts[model]; ok {
+		return cost
+	}
+	return p.DefaultCost
+}
+
+// tokenPriced reports whether model has per-1k-token prices
+func (p *Pricing) tokenPriced(model string) bool {
+	_, prompt := p.PricePer1kPrompt[model]
+	_, completion := p.PricePer1kCompletion[model]
+	return prompt || completion
+}
+
+// ComputeCost returns the credits for a request to model that used usage.
+// Token-priced models cost prompt/1000*PricePer1kPrompt plus
+// completion/1000*PricePer1kCompletion, rounded up to a whole credit with
+// a minimum of MinimumCharge. Without usage, or for models without token
+// prices, the flat Cost applies.
+func (p *Pricing) ComputeCost(model string, usage client.Usage) int {
+	if !p.tokenPriced(model) || usage.PromptTokens+usage.CompletionTokens == 0 {
+		return p.Cost(model)
+	}
+	raw := float64(usage.PromptTokens)/1000*p.PricePer1kPrompt[model] +
+		float64(usage.CompletionTokens)/1000*p.PricePer1kCompletion[model]
+	// Absorb float error so exact multiples don't round up a credit
+	cost := int(math.Ceil(raw - 1e-9))
+	if cost < MinimumCharge {
+		cost = MinimumCharge
+	}
+	return cost
+}
+
+// EstimateTokens approximates the token count of n bytes of prompt text
+func EstimateTokens(n int) int {
+	return (n + 3) / 4
+}
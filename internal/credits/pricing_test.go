@@ -0,0 +1,158 @@
+package credits
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+func TestReloadPricing(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+
+	m.GetOrCreateUser("alice", "")
+	if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+		t.Fatalf("UseCredits failed: %v", err)
+	}
+	if got := m.GetBalance("alice"); got != FreeCreditsForNewUser-5 {
+		t.Fatalf("Expected balance %d, got %d", FreeCreditsForNewUser-5, got)
+	}
+
+	config := `{"free_credits": 0, "default_cost": 3, "model_costs": {"gpt-4o": 7}}`
+	if err := os.WriteFile(m.pricingPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Use credits concurrently with the reload
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			m.CheckCredits("alice", "", "gpt-4o", 0)
+		}()
+	}
+	if err := m.ReloadPricing(); err != nil {
+		t.Fatalf("ReloadPricing failed: %v", err)
+	}
+	wg.Wait()
+
+	if got := m.GetBalance("alice"); got != FreeCreditsForNewUser-5 {
+		t.Errorf("Reload should not change balances, got %d", got)
+	}
+	if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+		t.Fatalf("UseCredits failed: %v", err)
+	}
+	if err := m.UseCredits("alice", "", "unknown-model", client.Usage{}); err != nil {
+		t.Fatalf("UseCredits failed: %v", err)
+	}
+	if got := m.GetBalance("alice"); got != FreeCreditsForNewUser-5-7-3 {
+		t.Errorf("Expected new costs to apply, got balance %d", got)
+	}
+	if got := m.GetOrCreateUser("bob", "").Balance; got != 0 {
+		t.Errorf("Expected no welcome bonus, got %d", got)
+	}
+
+	// An invalid file keeps the previous pricing
+	if err := os.WriteFile(m.pricingPath, []byte(`{"default_cost": -1}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ReloadPricing(); err == nil {
+		t.Error("Expected error for negative cost")
+	}
+	if p := m.Pricing(); p.Cost("gpt-4o") != 7 {
+		t.Errorf("Expected previous pricing to be kept, got %+v", p)
+	}
+}
+
+func TestLoadPricingDefaults(t *testing.T) {
+	p, err := LoadPricing(filepath.Join(t.TempDir(), PricingConfigFile))
+	if err != nil {
+		t.Fatalf("LoadPricing failed: %v", err)
+	}
+	if p.FreeCredits != FreeCreditsForNewUser || p.Cost("gpt-4o") != CreditCost["gpt-4o"] {
+		t.Errorf("Expected built-in defaults, got %+v", p)
+	}
+}
+
+func TestComputeCost(t *testing.T) {
+	p := &Pricing{
+		DefaultCost:          2,
+		ModelCosts:           map[string]int{"flat-model": 5},
+		PricePer1kPrompt:     map[string]float64{"token-model": 0.3},
+		PricePer1kCompletion: map[string]float64{"token-model": 1.5},
+	}
+
+	tests := []struct {
+		name  string
+		model string
+		usage client.Usage
+		want  int
+	}{
+		// 2000*0.3/1000 + 1000*1.5/1000 = 2.1, rounded up
+		{"rounds up", "token-model", client.Usage{PromptTokens: 2000, CompletionTokens: 1000}, 3},
+		// 10000*0.3/1000 = 3 exactly, float error must not add a credit
+		{"exact multiple", "token-model", client.Usage{PromptTokens: 10000}, 3},
+		{"minimum charge", "token-model", client.Usage{PromptTokens: 10, CompletionTokens: 2}, MinimumCharge},
+		{"large request", "token-model", client.Usage{PromptTokens: 100000, CompletionTokens: 4000}, 36},
+		{"no usage falls back to flat cost", "token-model", client.Usage{}, 2},
+		{"flat model ignores usage", "flat-model", client.Usage{PromptTokens: 100000}, 5},
+		{"unknown model", "other", client.Usage{PromptTokens: 100000}, 2},
+	}
+	for _, tt := range tests {
+		if got := p.ComputeCost(tt.model, tt.usage); got != tt.want {
+			t.Errorf("%s: ComputeCost = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestUseCreditsChargesUsage(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 10, "price_per_1k_prompt": {"m": 1}, "price_per_1k_completion": {"m": 2}}`)
+	m.GetOrCreateUser("alice", "")
+
+	// A prompt of 8000 bytes is estimated at 2000 tokens, 2 credits
+	if ok, _, cost, _ := m.CheckCredits("alice", "", "m", 8000); !ok || cost != 2 {
+		t.Errorf("Expected affordable estimate of 2, got ok=%v cost=%d", ok, cost)
+	}
+	// 44000 bytes is estimated at 11000 tokens, more than the balance
+	if ok, _, cost, _ := m.CheckCredits("alice", "", "m", 44000); ok || cost != 11 {
+		t.Errorf("Expected unaffordable estimate of 11, got ok=%v cost=%d", ok, cost)
+	}
+
+	usage := client.Usage{PromptTokens: 1500, CompletionTokens: 500, TotalTokens: 2000}
+	if err := m.UseCredits("alice", "", "m", usage); err != nil {
+		t.Fatalf("UseCredits failed: %v", err)
+	}
+	user := m.GetUserInfo("alice")
+	if user.Balance != 7 {
+		t.Errorf("Expected balance 7 after a 3 credit turn, got %d", user.Balance)
+	}
+	if tx := user.Transactions[len(user.Transactions)-1]; tx.Amount != -3 || tx.Tokens != 2000 {
+		t.Errorf("Unexpected transaction %+v", tx)
+	}
+
+	// A turn costing more than the balance empties it
+	if err := m.UseCredits("alice", "", "m", client.Usage{PromptTokens: 20000, TotalTokens: 20000}); err != nil {
+		t.Fatalf("UseCredits failed: %v", err)
+	}
+	if got := m.GetBalance("alice"); got != 0 {
+		t.Errorf("Expected balance 0, got %d", got)
+	}
+}
+
+func TestLoadPricingRejectsNegativeTokenPrice(t *testing.T) {
+	path := filepath.Join(t.TempDir(), PricingConfigFile)
+	if err := os.WriteFile(path, []byte(`{"price_per_1k_prompt": {"m": -1}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPricing(path); err == nil {
+		t.Error("Expected error for negative token price")
+	}
+}
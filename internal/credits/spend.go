@@ -0,0 +1,262 @@
+package credits
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	spendDirName       = "spend"
+	spendRetentionDays = 31
+	lockTimeout        = 5 * time.Second
+	staleLockAge       = 30 * time.Second
+)
+
+// ErrDailyLimit is matched by errors.Is for any *LimitError
+var ErrDailyLimit = errors.New("daily limit reached")
+
+// LimitError is returned when a daily spend cap blocks a request
+type LimitError struct {
+	Scope   string // "user" or "ip"
+	Unit    string // "credits" or "requests"
+	Limit   int
+	ResetAt time.Time
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("daily limit reached (%d %s per %s), resets at %s UTC",
+		e.Limit, e.Unit, e.Scope, e.ResetAt.UTC().Format("15:04"))
+}
+
+// Is reports whether target is ErrDailyLimit
+func (e *LimitError) Is(target error) bool {
+	return target == ErrDailyLimit
+}
+
+// Spend is the amount used in the current UTC day
+type Spend struct {
+	Credits  int `json:"credits"`
+	Requests int `json:"requests"`
+}
+
+// DailyOverride replaces a user's caps until the end of the UTC day
+type DailyOverride struct {
+	Credits  int `json:"credits"`
+	Requests int `json:"requests"`
+}
+
+// dailySpend is the rollup stored in spend/<date>.json
+type dailySpend struct {
+	Date      string                   `json:"date"`
+	Usage     map[string]*Spend        `json:"usage"`
+	Overrides map[string]DailyOverride `json:"overrides,omitempty"`
+}
+
+func userKey(userID string) string { return "user:" + userID }
+func ipKey(ip string) string       { return "ip:" + ip }
+
+// resetTime returns the next UTC midnight after t
+func resetTime(t time.Time) time.Time {
+	y, mo, d := t.UTC().Date()
+	return time.Date(y, mo, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+func (m *Manager) spendPath(t time.Time) string {
+	return filepath.Join(m.dataDir, spendDirName, t.UTC().Format("2006-01-02")+".json")
+}
+
+// loadSpend reads the rollup for the day containing t
+func (m *Manager) loadSpend(t time.Time) (*dailySpend, error) {
+	day := &dailySpend{
+		Date:  t.UTC().Format("2006-01-02"),
+		Usage: make(map[string]*Spend),
+	}
+	data, err := os.ReadFile(m.spendPath(t))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return day, nil
+		}
+		return nil, fmt.Errorf("failed to read spend rollup: %w", err)
+	}
+	if err := json.Unmarshal(data, day); err != nil {
+		return nil, fmt.Errorf("failed to parse spend rollup: %w", err)
+	}
+	if day.Usage == nil {
+		day.Usage = make(map[string]*Spend)
+	}
+	return day, nil
+}
+
+// updateSpend applies fn to today's rollup under a lock shared with other
+// instances using the same data directory
+func (m *Manager) updateSpend(fn func(day *dailySpend)) error {
+	m.spendMu.Lock()
+	defer m.spendMu.Unlock()
+
+	now := m.now()
+	path := m.spendPath(now)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	_, statErr := os.Stat(path)
+	day, err := m.loadSpend(now)
+	if err != nil {
+		return err
+	}
+	fn(day)
+
+	data, err := json.MarshalIndent(day, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+
+	if os.IsNotExist(statErr) {
+		m.pruneSpend(now)
+	}
+	return nil
+}
+
+// pruneSpend removes rollups older than the retention window
+func (m *Manager) pruneSpend(now time.Time) {
+	dir := filepath.Join(m.dataDir, spendDirName)
+	cutoff := now.UTC().AddDate(0, 0, -spendRetentionDays).Format("2006-01-02")
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		date := strings.TrimSuffix(e.Name(), ".json")
+		if date != e.Name() && date < cutoff {
+			os.Remove(filepath.Join(dir, e.Name()))
+		}
+	}
+}
+
+// recordSpend adds one request of the given cost for the user and IP
+func (m *Manager) recordSpend(userID, ip string, cost int) error {
+	return m.updateSpend(func(day *dailySpend) {
+		keys := []string{userKey(userID)}
+		if ip != "" {
+			keys = append(keys, ipKey(ip))
+		}
+		for _, key := range keys {
+			s := day.Usage[key]
+			if s == nil {
+				s = &Spend{}
+				day.Usage[key] = s
+			}
+			s.Credits += cost
+			s.Requests++
+		}
+	})
+}
+
+// checkLimits returns a *LimitError if the user or IP has reached a daily cap.
+// A request that starts under the cap is allowed to finish past it.
+func (m *Manager) checkLimits(userID, ip string, limits DailyLimits) error {
+	now := m.now()
+	day, err := m.loadSpend(now)
+	if err != nil {
+		return err
+	}
+
+	userCredits, userRequests := limits.UserCredits, limits.UserRequests
+	if o, ok := day.Overrides[userID]; ok {
+		userCredits, userRequests = o.Credits, o.Requests
+	}
+
+	check := func(key, scope string, credits, requests int) error {
+		s := day.Usage[key]
+		if s == nil {
+			return nil
+		}
+		if credits > 0 && s.Credits >= credits {
+			return &LimitError{Scope: scope, Unit: "credits", Limit: credits, ResetAt: resetTime(now)}
+		}
+		if requests > 0 && s.Requests >= requests {
+			return &LimitError{Scope: scope, Unit: "requests", Limit: requests, ResetAt: resetTime(now)}
+		}
+		return nil
+	}
+
+	if err := check(userKey(userID), "user", userCredits, userRequests); err != nil {
+		return err
+	}
+	if ip != "" {
+		return check(ipKey(ip), "ip", limits.IPCredits, limits.IPRequests)
+	}
+	return nil
+}
+
+// DailyUsage returns what the user has spent so far today
+func (m *Manager) DailyUsage(userID string) Spend {
+	day, err := m.loadSpend(m.now())
+	if err != nil {
+		return Spend{}
+	}
+	if s := day.Usage[userKey(userID)]; s != nil {
+		return *s
+	}
+	return Spend{}
+}
+
+// SetDailyOverride replaces a user's daily caps until the next UTC midnight.
+// Zero values mean unlimited. It returns when the override expires.
+func (m *Manager) SetDailyOverride(userID string, credits, requests int) (time.Time, error) {
+	if credits < 0 || requests < 0 {
+		return time.Time{}, fmt.Errorf("limits must be non-negative")
+	}
+	err := m.updateSpend(func(day *dailySpend) {
+		if day.Overrides == nil {
+			day.Overrides = make(map[string]DailyOverride)
+		}
+		day.Overrides[userID] = DailyOverride{Credits: credits, Requests: requests}
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	return resetTime(m.now()), nil
+}
+
+// lockFile acquires an exclusive lock file, breaking locks left behind by
+// crashed processes
+func lockFile(path string) (func(), error) {
+	deadline := time.Now().Add(lockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock: %w", err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > staleLockAge {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for lock %s", path)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
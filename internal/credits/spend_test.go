@@ -0,0 +1,158 @@
+package credits
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+func newLimitedManager(t *testing.T, config string) *Manager {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if err := os.WriteFile(m.pricingPath, []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.ReloadPricing(); err != nil {
+		t.Fatalf("ReloadPricing failed: %v", err)
+	}
+	return m
+}
+
+func TestDailyCapCrossedMidTurn(t *testing.T) {
+	m := newLimitedManager(t, `{"model_costs": {"gpt-4o": 5}, "daily_limits": {"user_credits": 8}}`)
+	now := time.Date(2026, 3, 1, 22, 30, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	m.GetOrCreateUser("alice", "")
+
+	for turn := 0; turn < 2; turn++ {
+		if ok, _, _, err := m.CheckCredits("alice", "10.0.0.1", "gpt-4o", 0); !ok || err != nil {
+			t.Fatalf("Turn %d should be allowed: ok=%v err=%v", turn, ok, err)
+		}
+		// The second turn crosses the cap but must still be charged
+		if err := m.UseCredits("alice", "10.0.0.1", "gpt-4o", client.Usage{}); err != nil {
+			t.Fatalf("Turn %d UseCredits failed: %v", turn, err)
+		}
+	}
+
+	_, _, _, err := m.CheckCredits("alice", "10.0.0.1", "gpt-4o", 0)
+	if !errors.Is(err, ErrDailyLimit) {
+		t.Fatalf("Expected ErrDailyLimit, got %v", err)
+	}
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || limitErr.ResetAt != time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC) {
+		t.Errorf("Expected reset at next UTC midnight, got %v", err)
+	}
+	if got := m.GetBalance("alice"); got != FreeCreditsForNewUser-10 {
+		t.Errorf("Expected balance %d, got %d", FreeCreditsForNewUser-10, got)
+	}
+
+	// The admin override raises the cap for the rest of the day
+	if _, err := m.SetDailyOverride("alice", 20, 0); err != nil {
+		t.Fatalf("SetDailyOverride failed: %v", err)
+	}
+	if _, _, _, err := m.CheckCredits("alice", "10.0.0.1", "gpt-4o", 0); err != nil {
+		t.Errorf("Expected override to lift the cap, got %v", err)
+	}
+
+	// Caps and overrides reset at UTC midnight
+	now = now.Add(2 * time.Hour)
+	if usage := m.DailyUsage("alice"); usage.Credits != 0 {
+		t.Errorf("Expected fresh usage after midnight, got %+v", usage)
+	}
+}
+
+func TestDailyCapPerIP(t *testing.T) {
+	m := newLimitedManager(t, `{"daily_limits": {"ip_requests": 2}}`)
+	m.GetOrCreateUser("alice", "")
+	m.GetOrCreateUser("bob", "")
+
+	m.UseCredits("alice", "10.0.0.1", "gpt-4o-mini", client.Usage{})
+	m.UseCredits("bob", "10.0.0.1", "gpt-4o-mini", client.Usage{})
+
+	if _, _, _, err := m.CheckCredits("bob", "10.0.0.1", "gpt-4o-mini", 0); !errors.Is(err, ErrDailyLimit) {
+		t.Errorf("Expected IP cap to block, got %v", err)
+	}
+	if _, _, _, err := m.CheckCredits("bob", "10.0.0.2", "gpt-4o-mini", 0); err != nil {
+		t.Errorf("Other IPs should not be blocked: %v", err)
+	}
+}
+
+func TestRecordSpendAcrossInstances(t *testing.T) {
+	m := newLimitedManager(t, `{}`)
+	other, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	m.GetOrCreateUser("alice", "")
+	other.GetOrCreateUser("alice", "")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(mgr *Manager) {
+			defer wg.Done()
+			if err := mgr.recordSpend("alice", "10.0.0.1", 1); err != nil {
+				t.Errorf("recordSpend failed: %v", err)
+			}
+		}([]*Manager{m, other}[i%2])
+	}
+	wg.Wait()
+
+	if usage := m.DailyUsage("alice"); usage.Requests != 20 || usage.Credits != 20 {
+		t.Errorf("Expected 20 requests and credits, got %+v", usage)
+	}
+}
+
+func TestUseCreditsRecordsSpendFirst(t *testing.T) {
+	m := newLimitedManager(t, `{"model_costs": {"gpt-4o": 5}}`)
+	m.GetOrCreateUser("alice", "")
+
+	// Waiting for the spend lock doesn't hold up other callers
+	path := m.spendPath(m.now())
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path+".lock", nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() { done <- m.UseCredits("alice", "10.0.0.1", "gpt-4o", client.Usage{}) }()
+	time.Sleep(50 * time.Millisecond)
+	balance := make(chan int, 1)
+	go func() { balance <- m.GetBalance("alice") }()
+	select {
+	case got := <-balance:
+		if got != FreeCreditsForNewUser {
+			t.Errorf("Expected the balance unchanged while the spend waits, got %d", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetBalance blocked while UseCredits waited for the spend lock")
+	}
+	os.Remove(path + ".lock")
+	if err := <-done; err != nil {
+		t.Fatalf("UseCredits failed: %v", err)
+	}
+	if got := m.GetBalance("alice"); got != FreeCreditsForNewUser-5 {
+		t.Errorf("Expected balance %d, got %d", FreeCreditsForNewUser-5, got)
+	}
+
+	// A spend that can't be recorded leaves the balance alone
+	os.RemoveAll(filepath.Dir(path))
+	os.WriteFile(filepath.Dir(path), nil, 0644)
+	if err := m.UseCredits("alice", "10.0.0.1", "gpt-4o", client.Usage{}); err == nil {
+		t.Fatal("Expected UseCredits to fail without a spend rollup")
+	}
+	if got := m.GetBalance("alice"); got != FreeCreditsForNewUser-5 {
+		t.Errorf("Expected the balance unchanged after a failed spend, got %d", got)
+	}
+	if info := m.GetUserInfo("alice"); info.TotalUsed != 5 {
+		t.Errorf("Expected no usage recorded for the failed spend, got %d", info.TotalUsed)
+	}
+}
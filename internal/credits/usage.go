@@ -0,0 +1,156 @@
+package credits
+
+import (
+	"sort"
+	"time"
+)
+
+// UsageSummary aggregates the "use" transactions in a time window
+type UsageSummary struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Credits  int       `json:"credits"`
+	Requests int       `json:"requests"`
+	Tokens   int       `json:"tokens"`
+
+	// Models and Users are sorted by credits, highest first
+	Models []ModelUsage `json:"models"`
+	Users  []UserUsage  `json:"users"`
+
+	// Partial is set when a user's history was trimmed to the last
+	// maxTransactions after From, so older spend is missing
+	Partial bool `json:"partial,omitempty"`
+}
+
+// ModelUsage is the spend on one model
+type ModelUsage struct {
+	Model    string `json:"model"`
+	Credits  int    `json:"credits"`
+	Requests int    `json:"requests"`
+	Tokens   int    `json:"tokens"`
+}
+
+// UserUsage is the spend of one user
+type UserUsage struct {
+	UserID   string `json:"user_id"`
+	Email    string `json:"email,omitempty"`
+	Credits  int    `json:"credits"`
+	Requests int    `json:"requests"`
+	Tokens   int    `json:"tokens"`
+}
+
+// summaryWindow keys cached summaries
+type summaryWindow struct {
+	from, to int64
+}
+
+// UsageSummary totals the spend between from (inclusive) and to
+// (exclusive). A zero from or to leaves that end open. Results are cached
+// until the next write, so callers should round the window, e.g. to whole
+// days, to get cache hits.
+func (m *Manager) UsageSummary(from, to time.Time) UsageSummary {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key := summaryWindow{unixOrZero(from), unixOrZero(to)}
+	m.summaryMu.Lock()
+	if m.summaryGen != m.generation {
+		m.summaries, m.summaryGen = nil, m.generation
+	}
+	cached, ok := m.summaries[key]
+	m.summaryMu.Unlock()
+	if ok {
+		return cached.clone()
+	}
+
+	summary := m.computeSummary(from, to)
+	m.summaryMu.Lock()
+	if m.summaries == nil {
+		m.summaries = make(map[summaryWindow]*UsageSummary)
+	}
+	m.summaries[key] = &summary
+	m.summaryMu.Unlock()
+	return summary.clone()
+}
+
+// TopUsers returns the n users who spent the most credits in their
+// retained transactions
+func (m *Manager) TopUsers(n int) []UserUsage {
+	users := m.UsageSummary(time.Time{}, time.Time{}).Users
+	if n >= 0 && len(users) > n {
+		users = users[:n]
+	}
+	return users
+}
+
+// computeSummary walks every user's transactions; m.mu must be held
+func (m *Manager) computeSummary(from, to time.Time) UsageSummary {
+	summary := UsageSummary{From: from, To: to}
+	models := make(map[string]*ModelUsage)
+
+	for _, user := range m.users {
+		if len(user.Transactions) >= maxTransactions && !from.IsZero() && user.Transactions[0].Timestamp.After(from) {
+			summary.Partial = true
+		}
+		usage := UserUsage{UserID: user.UserID, Email: user.Email}
+		for _, tx := range user.Transactions {
+			if tx.Type != "use" || tx.Timestamp.Before(from) || (!to.IsZero() && !tx.Timestamp.Before(to)) {
+				continue
+			}
+			cost := -tx.Amount
+			usage.Credits += cost
+			usage.Requests++
+			usage.Tokens += tx.Tokens
+
+			mu := models[tx.Model]
+			if mu == nil {
+				mu = &ModelUsage{Model: tx.Model}
+				models[tx.Model] = mu
+			}
+			mu.Credits += cost
+			mu.Requests++
+			mu.Tokens += tx.Tokens
+		}
+		if usage.Requests == 0 {
+			continue
+		}
+		summary.Credits += usage.Credits
+		summary.Requests += usage.Requests
+		summary.Tokens += usage.Tokens
+		summary.Users = append(summary.Users, usage)
+	}
+
+	for _, mu := range models {
+		summary.Models = append(summary.Models, *mu)
+	}
+	sort.Slice(summary.Models, func(i, j int) bool {
+		a, b := summary.Models[i], summary.Models[j]
+		if a.Credits != b.Credits {
+			return a.Credits > b.Credits
+		}
+		return a.Model < b.Model
+	})
+	sort.Slice(summary.Users, func(i, j int) bool {
+		a, b := summary.Users[i], summary.Users[j]
+		if a.Credits != b.Credits {
+			return a.Credits > b.Credits
+		}
+		return a.UserID < b.UserID
+	})
+	return summary
+}
+
+// clone copies the breakdowns so callers can't modify the cache
+func (s *UsageSummary) clone() UsageSummary {
+	c := *s
+	c.Models = append([]ModelUsage{}, s.Models...)
+	c.Users = append([]UserUsage{}, s.Users...)
+	return c
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
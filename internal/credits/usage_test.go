@@ -0,0 +1,146 @@
+package credits
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+// seedUsage gives each of n users a transaction per day for ten days,
+// ending on day, alternating between two models. User i spends i+1
+// credits per request.
+func seedUsage(m *Manager, n int, day time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := 0; i < n; i++ {
+		user := &UserCredits{UserID: fmt.Sprintf("user%02d", i), Email: fmt.Sprintf("u%d@example.com", i)}
+		user.Transactions = append(user.Transactions, Transaction{Type: "buy", Amount: 500, Timestamp: day.AddDate(0, 0, -20)})
+		for d := 9; d >= 0; d-- {
+			model := "gpt-4o"
+			if d%2 == 1 {
+				model = "llama-3.3-70b-versatile"
+			}
+			user.Transactions = append(user.Transactions, Transaction{
+				Type:      "use",
+				Amount:    -(i + 1),
+				Model:     model,
+				Tokens:    100,
+				Timestamp: day.AddDate(0, 0, -d),
+			})
+		}
+		m.users[user.UserID] = user
+		m.saveUser(user)
+	}
+}
+
+func TestUsageSummary(t *testing.T) {
+	m := newLimitedManager(t, `{}`)
+	day := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	seedUsage(m, 5, day)
+
+	all := m.UsageSummary(time.Time{}, time.Time{})
+	// 10 requests each costing 1+2+3+4+5 across the users
+	if all.Requests != 50 || all.Credits != 150 || all.Tokens != 5000 {
+		t.Errorf("Unexpected totals: %+v", all)
+	}
+	if len(all.Users) != 5 || all.Users[0].UserID != "user04" || all.Users[0].Credits != 50 || all.Users[4].Credits != 10 {
+		t.Errorf("Unexpected user breakdown: %+v", all.Users)
+	}
+	if len(all.Models) != 2 || all.Models[0].Credits+all.Models[1].Credits != 150 || all.Models[0].Requests != 25 {
+		t.Errorf("Unexpected model breakdown: %+v", all.Models)
+	}
+
+	// The last three days: day-2, day-1 and day itself
+	from := day.AddDate(0, 0, -2).Truncate(24 * time.Hour)
+	to := day.AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	recent := m.UsageSummary(from, to)
+	if recent.Requests != 15 || recent.Credits != 45 {
+		t.Errorf("Expected 15 requests and 45 credits in the window, got %+v", recent)
+	}
+	byModel := map[string]int{}
+	for _, mu := range recent.Models {
+		byModel[mu.Model] = mu.Requests
+	}
+	if byModel["gpt-4o"] != 10 || byModel["llama-3.3-70b-versatile"] != 5 {
+		t.Errorf("Unexpected model requests %v", byModel)
+	}
+	if recent.Partial {
+		t.Error("Expected complete histories")
+	}
+
+	// An empty window
+	if none := m.UsageSummary(day.AddDate(0, 0, 5), time.Time{}); none.Requests != 0 || len(none.Users) != 0 {
+		t.Errorf("Expected no usage after the last transaction, got %+v", none)
+	}
+
+	top := m.TopUsers(2)
+	if len(top) != 2 || top[0].UserID != "user04" || top[1].UserID != "user03" {
+		t.Errorf("Unexpected top users %+v", top)
+	}
+}
+
+func TestUsageSummaryCacheInvalidation(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 100, "model_costs": {"gpt-4o": 5}}`)
+	m.GetOrCreateUser("alice", "")
+
+	before := m.UsageSummary(time.Time{}, time.Time{})
+	if before.Requests != 0 {
+		t.Fatalf("Expected no usage, got %+v", before)
+	}
+	// Callers can't modify the cached copy
+	before.Users = append(before.Users, UserUsage{UserID: "mallory"})
+
+	if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+		t.Fatal(err)
+	}
+	after := m.UsageSummary(time.Time{}, time.Time{})
+	if after.Requests != 1 || after.Credits != 5 || len(after.Users) != 1 || after.Users[0].UserID != "alice" {
+		t.Errorf("Expected the new spend after a write, got %+v", after)
+	}
+}
+
+func TestUsageSummaryTrimmedHistory(t *testing.T) {
+	m := newLimitedManager(t, `{"free_credits": 1000, "model_costs": {"gpt-4o": 1}}`)
+	start := time.Now()
+	m.GetOrCreateUser("alice", "")
+	for i := 0; i < maxTransactions+5; i++ {
+		if err := m.UseCredits("alice", "", "gpt-4o", client.Usage{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	summary := m.UsageSummary(start.Add(-time.Hour), time.Time{})
+	if !summary.Partial {
+		t.Error("Expected a partial summary once history is trimmed")
+	}
+	if summary.Requests != maxTransactions {
+		t.Errorf("Expected the %d retained requests, got %d", maxTransactions, summary.Requests)
+	}
+}
+
+func TestLoadSkipsInvalidUserFiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	dir := filepath.Join(home, DefaultDataDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{not json"), 0644)
+	os.WriteFile(filepath.Join(dir, "bob.json"), []byte(`{"user_id": "bob", "balance": 7, "transactions": [
+		{"type": "use", "amount": -3, "model": "gpt-4o", "timestamp": "2026-03-01T10:00:00Z"}]}`), 0644)
+
+	m, err := NewManager()
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	if got := m.GetBalance("bob"); got != 7 {
+		t.Errorf("Expected bob's balance to load, got %d", got)
+	}
+	if summary := m.UsageSummary(time.Time{}, time.Time{}); summary.Credits != 3 || len(summary.Users) != 1 {
+		t.Errorf("Unexpected summary %+v", summary)
+	}
+}
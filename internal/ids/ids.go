@@ -0,0 +1,41 @@
+// Package ids generates random identifiers from crypto/rand, so IDs made
+// in a tight loop or on several goroutines do not collide.
+package ids
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+const (
+	alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	lowercase    = "abcdefghijklmnopqrstuvwxyz0123456789"
+)
+
+// New returns n random letters and digits, for IDs in URLs
+func New(n int) string {
+	return fromCharset(alphanumeric, n)
+}
+
+// Lower returns n random lowercase letters and digits, for IDs used as
+// file names on case-insensitive file systems
+func Lower(n int) string {
+	return fromCharset(lowercase, n)
+}
+
+// Prefixed returns prefix, an underscore and n random lowercase letters
+// and digits, e.g. "tx_3k9f..."
+func Prefixed(prefix string, n int) string {
+	return prefix + "_" + Lower(n)
+}
+
+func fromCharset(charset string, n int) string {
+	max := big.NewInt(int64(len(charset)))
+	b := make([]byte, n)
+	for i := range b {
+		// crypto/rand does not fail on supported platforms
+		idx, _ := rand.Int(rand.Reader, max)
+		b[i] = charset[idx.Int64()]
+	}
+	return string(b)
+}
@@ -0,0 +1,26 @@
+package ids
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIDs(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 10000 {
+		id := Lower(12)
+		if len(id) != 12 || strings.Trim(id, lowercase) != "" {
+			t.Fatalf("Unexpected ID %q", id)
+		}
+		if seen[id] {
+			t.Fatalf("Duplicate ID %q", id)
+		}
+		seen[id] = true
+	}
+	if id := New(24); len(id) != 24 || strings.Trim(id, alphanumeric) != "" {
+		t.Errorf("Unexpected ID %q", id)
+	}
+	if id := Prefixed("tx", 16); !strings.HasPrefix(id, "tx_") || len(id) != 19 {
+		t.Errorf("Unexpected ID %q", id)
+	}
+}
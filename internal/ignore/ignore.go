@@ -0,0 +1,218 @@
+// Package ignore walks directory trees the way git sees them, skipping
+// paths excluded by .gitignore files and a few well-known build and
+// dependency directories.
+package ignore
+
+import (
+	"bufio"
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// DefaultExcludes are skipped even without a .gitignore. A .gitignore
+// negation such as "!dist/" brings one back.
+var DefaultExcludes = []string{".git", "node_modules/", "dist/", "target/", "*.min.js"}
+
+// ignoreRule is one line of a .gitignore file
+type ignoreRule struct {
+	pattern  string
+	negate   bool // "!pattern" re-includes what an earlier rule excluded
+	dirOnly  bool // "pattern/" only matches directories
+	anchored bool // The pattern has a slash, so it matches the path below the .gitignore's directory
+}
+
+// parseIgnoreLine parses a .gitignore line; ok is false for blanks and comments
+func parseIgnoreLine(line string) (rule ignoreRule, ok bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return rule, false
+	}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		rule.anchored = true
+		line = strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return rule, false
+	}
+	rule.pattern = line
+	return rule, true
+}
+
+// matches reports whether the rule applies to rel, the slash-separated
+// path below the directory the rule came from
+func (r ignoreRule) matches(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	name := rel
+	if !r.anchored {
+		name = rel[strings.LastIndex(rel, "/")+1:]
+	}
+	// Bad patterns simply never match
+	ok, _ := doublestar.Match(r.pattern, name)
+	return ok
+}
+
+// ignoreSet holds the .gitignore rules seen while walking a tree. Rules
+// from deeper directories and later lines win, as in git.
+type ignoreSet struct {
+	top      string                  // Outermost directory whose rules apply
+	defaults []ignoreRule            // DefaultExcludes, lowest precedence
+	rules    map[string][]ignoreRule // .gitignore rules by directory
+}
+
+// newIgnoreSet prepares to walk root. Inside a git repository the
+// .gitignore files between the repository root and root apply too.
+func newIgnoreSet(root string) *ignoreSet {
+	s := &ignoreSet{top: root, rules: make(map[string][]ignoreRule)}
+	for _, line := range DefaultExcludes {
+		if rule, ok := parseIgnoreLine(line); ok {
+			s.defaults = append(s.defaults, rule)
+		}
+	}
+
+	// Only climb to a repository root; unrelated parent directories stay out
+	var dirs []string
+	for dir := root; ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			s.top = dir
+			break
+		}
+		if dir == filepath.Dir(dir) {
+			dirs = dirs[:1]
+			break
+		}
+	}
+	for _, dir := range dirs {
+		s.load(dir)
+	}
+	return s
+}
+
+// load reads dir's .gitignore, if it has one
+func (s *ignoreSet) load(dir string) {
+	f, err := os.Open(filepath.Join(dir, ".gitignore"))
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseIgnoreLine(scanner.Text()); ok {
+			rules = append(rules, rule)
+		}
+	}
+	if len(rules) > 0 {
+		s.rules[dir] = rules
+	}
+}
+
+// ignored reports whether path is excluded. Its parent directories must
+// already have been checked: a file in an excluded directory cannot be
+// re-included, again as in git.
+func (s *ignoreSet) ignored(path string, isDir bool) bool {
+	// Directories from path's parent up to top, innermost first
+	var dirs []string
+	for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+		dirs = append(dirs, dir)
+		if dir == s.top || dir == filepath.Dir(dir) {
+			break
+		}
+	}
+
+	ignored := false
+	name := filepath.Base(path)
+	for _, rule := range s.defaults {
+		if rule.matches(name, isDir) {
+			ignored = !rule.negate
+		}
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rules := s.rules[dirs[i]]
+		if len(rules) == 0 {
+			continue
+		}
+		rel, err := filepath.Rel(dirs[i], path)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, rule := range rules {
+			if rule.matches(rel, isDir) {
+				ignored = !rule.negate
+			}
+		}
+	}
+	return ignored
+}
+
+// Walk calls fn for each regular file below root, in lexical order.
+// Unless noIgnore is set, paths excluded by .gitignore files or
+// DefaultExcludes are skipped, and excluded directories are not entered.
+// fn may return fs.SkipAll to stop early.
+func Walk(ctx context.Context, root string, noIgnore bool, fn func(path string, d fs.DirEntry) error) error {
+	var ignores *ignoreSet
+	if !noIgnore {
+		ignores = newIgnoreSet(root)
+	}
+
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Unreadable entries are skipped, not fatal
+			if d != nil && d.IsDir() && path != root {
+				return fs.SkipDir
+			}
+			if path == root {
+				return err
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if path == root {
+			return nil
+		}
+
+		if d.IsDir() {
+			if ignores != nil {
+				if ignores.ignored(path, true) {
+					return fs.SkipDir
+				}
+				ignores.load(path)
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			// Follow links to files, never to directories
+			if d.Type()&fs.ModeSymlink == 0 {
+				return nil
+			}
+			if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+				return nil
+			}
+		}
+		if ignores != nil && ignores.ignored(path, false) {
+			return nil
+		}
+		return fn(path, d)
+	})
+}
@@ -0,0 +1,114 @@
+package ignore
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// makeTree creates files, by slash-separated relative path, in a temp dir
+func makeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+// walkedFiles lists what Walk visits, relative to root
+func walkedFiles(t *testing.T, root string, noIgnore bool) []string {
+	t.Helper()
+	var got []string
+	err := Walk(context.Background(), root, noIgnore, func(path string, d fs.DirEntry) error {
+		rel, _ := filepath.Rel(root, path)
+		got = append(got, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(got)
+	return got
+}
+
+func TestWalkFilesGitignore(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		".git/HEAD":                  "ref: refs/heads/main\n",
+		".gitignore":                 "# build output\n*.log\n!keep.log\n/build/\ndocs/*.html\ntmp/\n",
+		"main.go":                    "",
+		"debug.log":                  "",
+		"keep.log":                   "",
+		"build/out.bin":              "",
+		"cmd/build/main.go":          "",
+		"docs/index.html":            "",
+		"docs/api/index.html":        "",
+		"tmp/scratch.txt":            "",
+		"web/.gitignore":             "*.css\n!site.css\n",
+		"web/app.css":                "",
+		"web/site.css":               "",
+		"web/app.min.js":             "",
+		"web/node_modules/x/x.js":    "",
+		"web/tmp/cache.txt":          "",
+		"vendor/.gitignore":          "!*.log\n",
+		"vendor/vendored.log":        "",
+		"target/debug/app":           "",
+		"dist/bundle.js":             "",
+		"node_modules/pkg/index.js":  "",
+		"nested/deep/node_modules/a": "",
+	})
+
+	want := []string{
+		".gitignore",
+		"cmd/build/main.go",   // /build/ is anchored to the root
+		"docs/api/index.html", // docs/*.html does not cross directories
+		"keep.log",            // Negated
+		"main.go",
+		"vendor/.gitignore",
+		"vendor/vendored.log", // A deeper .gitignore re-includes it
+		"web/.gitignore",
+		"web/site.css",
+	}
+	if got := walkedFiles(t, root, false); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+
+	if got := walkedFiles(t, root, true); len(got) != 22 {
+		t.Errorf("Expected every file with no_ignore, got %d: %v", len(got), got)
+	}
+}
+
+func TestWalkFilesParentGitignore(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		".git/HEAD":     "",
+		".gitignore":    "*.gen.go\nsrc/skip/\n",
+		"src/a.go":      "",
+		"src/a.gen.go":  "",
+		"src/skip/b.go": "",
+	})
+
+	// Rules from the repository root apply below a subdirectory search
+	got := walkedFiles(t, filepath.Join(root, "src"), false)
+	if strings.Join(got, ",") != "a.go" {
+		t.Errorf("Expected only a.go, got %v", got)
+	}
+
+	// Outside a repository, parent directories' rules are ignored
+	outside := makeTree(t, map[string]string{
+		".gitignore": "*.txt\n",
+		"sub/a.txt":  "",
+	})
+	if got := walkedFiles(t, filepath.Join(outside, "sub"), false); strings.Join(got, ",") != "a.txt" {
+		t.Errorf("Expected a.txt outside a repository, got %v", got)
+	}
+}
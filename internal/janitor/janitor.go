@@ -0,0 +1,461 @@
+package janitor
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/logging"
+	"groq-go/internal/storage"
+)
+
+var log = logging.WithComponent("janitor")
+
+// Categories reported by a sweep
+const (
+	CategoryVersions       = "versions"
+	CategoryUploads        = "uploads"
+	CategoryPartialUploads = "partial_uploads"
+)
+
+const (
+	// DefaultUploadRetention is how long unreferenced uploads are kept
+	DefaultUploadRetention = 7 * 24 * time.Hour
+	// DefaultPartialUploadTTL is how long an incomplete chunked upload may sit idle
+	DefaultPartialUploadTTL = 24 * time.Hour
+	// DefaultMinAge protects entries that may still be in the middle of being written
+	DefaultMinAge = time.Hour
+	// DefaultInterval is the background sweep interval
+	DefaultInterval = 6 * time.Hour
+)
+
+// uploadRefPrefix marks image parts the web server spilled to the upload
+// directory: upload://<mime>;<name>
+const uploadRefPrefix = "upload://"
+
+// Cache describes a directory that is pruned to a size cap (oldest files first)
+type Cache struct {
+	Name     string
+	Dir      string
+	MaxBytes int64
+}
+
+// Config configures the janitor
+type Config struct {
+	VersionsDir     string        // ~/.config/groq-go/versions
+	UploadsDir      string        // ~/.config/groq-go/uploads
+	PartialDir      string        // Incomplete chunked uploads, one directory each
+	Caches          []Cache       // TTS, artifacts, ...
+	UploadRetention time.Duration // Unreferenced uploads older than this are removed
+	PartialTTL      time.Duration // Idle incomplete uploads older than this are removed
+	MinAge          time.Duration // Never remove anything modified more recently than this
+	Sessions        storage.Storage
+}
+
+// DefaultConfig returns the configuration for the standard data directories
+func DefaultConfig() Config {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	base := filepath.Join(home, ".config", "groq-go")
+
+	return Config{
+		VersionsDir: filepath.Join(base, "versions"),
+		UploadsDir:  filepath.Join(base, "uploads"),
+		PartialDir:  filepath.Join(base, "uploads", ".partial"),
+		Caches: []Cache{
+			{Name: "tts", Dir: filepath.Join(base, "tts-cache"), MaxBytes: 200 << 20},
+			{Name: "artifacts", Dir: filepath.Join(base, "artifacts"), MaxBytes: 500 << 20},
+		},
+		UploadRetention: DefaultUploadRetention,
+		PartialTTL:      DefaultPartialUploadTTL,
+		MinAge:          DefaultMinAge,
+	}
+}
+
+// Deletion records a single removed (or, in dry-run mode, removable) path
+type Deletion struct {
+	Category string `json:"category"`
+	Path     string `json:"path"`
+	Reason   string `json:"reason"`
+	Bytes    int64  `json:"bytes"`
+}
+
+// Report summarizes a sweep
+type Report struct {
+	DryRun    bool             `json:"dry_run"`
+	Reclaimed map[string]int64 `json:"reclaimed"` // Bytes per category
+	Deletions []Deletion       `json:"deletions"`
+	Errors    []string         `json:"errors,omitempty"`
+	StartedAt time.Time        `json:"started_at"`
+	Duration  time.Duration    `json:"duration"`
+}
+
+// TotalBytes returns the total bytes reclaimed across all categories
+func (r *Report) TotalBytes() int64 {
+	var total int64
+	for _, n := range r.Reclaimed {
+		total += n
+	}
+	return total
+}
+
+// Janitor removes orphaned and expired files from the data directories
+type Janitor struct {
+	cfg   Config
+	roots []string
+	mu    sync.Mutex // Serializes sweeps
+	now   func() time.Time
+}
+
+// New creates a new janitor
+func New(cfg Config) *Janitor {
+	if cfg.UploadRetention <= 0 {
+		cfg.UploadRetention = DefaultUploadRetention
+	}
+	if cfg.PartialTTL <= 0 {
+		cfg.PartialTTL = DefaultPartialUploadTTL
+	}
+	if cfg.MinAge < 0 {
+		cfg.MinAge = 0
+	}
+
+	var roots []string
+	for _, dir := range []string{cfg.VersionsDir, cfg.UploadsDir, cfg.PartialDir} {
+		if dir != "" {
+			roots = append(roots, cleanRoot(dir))
+		}
+	}
+	for _, c := range cfg.Caches {
+		if c.Dir != "" {
+			roots = append(roots, cleanRoot(c.Dir))
+		}
+	}
+
+	return &Janitor{cfg: cfg, roots: roots, now: time.Now}
+}
+
+// Start runs a sweep every interval until ctx is cancelled
+func (j *Janitor) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				report := j.Sweep(ctx, false)
+				log.Info("Sweep finished", "reclaimed_bytes", report.TotalBytes(), "deletions", len(report.Deletions), "errors", len(report.Errors))
+			}
+		}
+	}()
+}
+
+// Sweep runs all collectors once. In dry-run mode nothing is removed.
+func (j *Janitor) Sweep(ctx context.Context, dryRun bool) *Report {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	report := &Report{
+		DryRun:    dryRun,
+		Reclaimed: make(map[string]int64),
+		StartedAt: j.now(),
+	}
+
+	if j.cfg.VersionsDir != "" {
+		j.sweepVersions(report)
+	}
+	if j.cfg.UploadsDir != "" {
+		j.sweepUploads(ctx, report)
+	}
+	if j.cfg.PartialDir != "" {
+		j.sweepPartialUploads(report)
+	}
+	for _, c := range j.cfg.Caches {
+		if c.Dir != "" && c.MaxBytes > 0 {
+			j.sweepCache(c, report)
+		}
+	}
+
+	report.Duration = time.Since(report.StartedAt)
+	return report
+}
+
+// sweepVersions removes version directories without a meta.json
+func (j *Janitor) sweepVersions(report *Report) {
+	entries, err := os.ReadDir(j.cfg.VersionsDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("read versions dir: %v", err))
+		}
+		return
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(j.cfg.VersionsDir, entry.Name())
+		if _, err := os.Stat(filepath.Join(dir, "meta.json")); err == nil {
+			continue
+		}
+		if j.tooRecent(dir) {
+			continue
+		}
+		j.remove(report, CategoryVersions, dir, "no version metadata")
+	}
+}
+
+// sweepUploads removes expired uploads that no session references
+func (j *Janitor) sweepUploads(ctx context.Context, report *Report) {
+	entries, err := os.ReadDir(j.cfg.UploadsDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("read uploads dir: %v", err))
+		}
+		return
+	}
+
+	referenced, err := j.referencedFiles(ctx)
+	if err != nil {
+		// Without the reference set we can't tell what is safe to delete
+		report.Errors = append(report.Errors, fmt.Sprintf("list session attachments: %v", err))
+		return
+	}
+
+	cutoff := j.now().Add(-j.cfg.UploadRetention)
+	for _, entry := range entries {
+		// Hidden entries (e.g. incomplete chunked uploads) are handled separately
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(j.cfg.UploadsDir, entry.Name())
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if referenced[path] || referenced[entry.Name()] {
+			continue
+		}
+		j.remove(report, CategoryUploads, path, fmt.Sprintf("unreferenced for more than %s", j.cfg.UploadRetention))
+	}
+}
+
+// sweepPartialUploads removes incomplete chunked uploads that have been idle too long
+func (j *Janitor) sweepPartialUploads(report *Report) {
+	entries, err := os.ReadDir(j.cfg.PartialDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("read partial uploads dir: %v", err))
+		}
+		return
+	}
+
+	cutoff := j.now().Add(-j.cfg.PartialTTL)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(j.cfg.PartialDir, entry.Name())
+		// meta.json is rewritten on every received chunk
+		info, err := os.Stat(filepath.Join(dir, "meta.json"))
+		if err != nil {
+			info, err = entry.Info()
+		}
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		j.remove(report, CategoryPartialUploads, dir, fmt.Sprintf("incomplete upload idle for more than %s", j.cfg.PartialTTL))
+	}
+}
+
+// referencedFiles collects attachment paths and names, and the uploads
+// behind image references in messages, from all sessions
+func (j *Janitor) referencedFiles(ctx context.Context) (map[string]bool, error) {
+	refs := make(map[string]bool)
+	if j.cfg.Sessions == nil {
+		return refs, nil
+	}
+
+	metas, err := j.cfg.Sessions.ListSessions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, meta := range metas {
+		session, err := j.cfg.Sessions.LoadSession(ctx, meta.ID)
+		if err != nil {
+			return nil, fmt.Errorf("load session %s: %w", meta.ID, err)
+		}
+		if session == nil {
+			continue
+		}
+		for _, f := range session.Files {
+			if f.Path != "" {
+				refs[filepath.Clean(f.Path)] = true
+			}
+			if f.Name != "" {
+				refs[filepath.Base(f.Name)] = true
+			}
+		}
+		for _, msg := range session.Messages {
+			parts, ok := msg.Content.([]client.ContentPart)
+			if !ok {
+				continue
+			}
+			for _, part := range parts {
+				if part.ImageURL == nil {
+					continue
+				}
+				if ref, ok := strings.CutPrefix(part.ImageURL.URL, uploadRefPrefix); ok {
+					if _, name, ok := strings.Cut(ref, ";"); ok && name != "" {
+						refs[filepath.Base(name)] = true
+					}
+				}
+			}
+		}
+	}
+	return refs, nil
+}
+
+// sweepCache removes the oldest files until the cache fits its size cap
+func (j *Janitor) sweepCache(c Cache, report *Report) {
+	type cacheFile struct {
+		path    string
+		size    int64
+		modTime time.Time
+	}
+
+	var files []cacheFile
+	var total int64
+	err := filepath.WalkDir(c.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		files = append(files, cacheFile{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		if !os.IsNotExist(err) {
+			report.Errors = append(report.Errors, fmt.Sprintf("walk %s cache: %v", c.Name, err))
+		}
+		return
+	}
+	if total <= c.MaxBytes {
+		return
+	}
+
+	sort.Slice(files, func(a, b int) bool {
+		return files[a].modTime.Before(files[b].modTime)
+	})
+
+	reason := fmt.Sprintf("cache over %d byte cap", c.MaxBytes)
+	for _, f := range files {
+		if total <= c.MaxBytes {
+			break
+		}
+		if j.remove(report, c.Name, f.path, reason) {
+			total -= f.size
+		}
+	}
+}
+
+// remove deletes path (unless dry-run) and records it in the report.
+// It refuses to touch anything outside the configured roots.
+func (j *Janitor) remove(report *Report, category, path, reason string) bool {
+	if !j.withinRoots(path) {
+		report.Errors = append(report.Errors, fmt.Sprintf("refusing to remove %s: outside data roots", path))
+		log.Error("Refusing to remove path outside data roots", "path", path, "category", category)
+		return false
+	}
+
+	size := diskUsage(path)
+	if !report.DryRun {
+		if err := os.RemoveAll(path); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("remove %s: %v", path, err))
+			log.Warn("Failed to remove path", "path", path, "category", category, "error", err)
+			return false
+		}
+	}
+
+	log.Info("Removed path", "path", path, "category", category, "reason", reason, "bytes", size, "dry_run", report.DryRun)
+	report.Deletions = append(report.Deletions, Deletion{
+		Category: category,
+		Path:     path,
+		Reason:   reason,
+		Bytes:    size,
+	})
+	report.Reclaimed[category] += size
+	return true
+}
+
+// withinRoots reports whether path lies strictly inside one of the data roots
+func (j *Janitor) withinRoots(path string) bool {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range j.roots {
+		rel, err := filepath.Rel(root, abs)
+		if err != nil || rel == "." {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// tooRecent reports whether path was modified within MinAge
+func (j *Janitor) tooRecent(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return true
+	}
+	return j.now().Sub(info.ModTime()) < j.cfg.MinAge
+}
+
+// diskUsage returns the total size of regular files under path, without following symlinks
+func diskUsage(path string) int64 {
+	var total int64
+	filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if info, err := d.Info(); err == nil {
+				total += info.Size()
+			}
+		}
+		return nil
+	})
+	return total
+}
+
+func cleanRoot(dir string) string {
+	if abs, err := filepath.Abs(dir); err == nil {
+		return abs
+	}
+	return filepath.Clean(dir)
+}
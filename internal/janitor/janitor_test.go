@@ -0,0 +1,143 @@
+package janitor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/storage"
+)
+
+func testConfig(t *testing.T) Config {
+	base := t.TempDir()
+	cfg := Config{
+		VersionsDir:     filepath.Join(base, "versions"),
+		UploadsDir:      filepath.Join(base, "uploads"),
+		Caches:          []Cache{{Name: "tts", Dir: filepath.Join(base, "tts"), MaxBytes: 10}},
+		UploadRetention: time.Hour,
+	}
+	for _, dir := range []string{cfg.VersionsDir, cfg.UploadsDir, cfg.Caches[0].Dir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return cfg
+}
+
+func writeFile(t *testing.T, path string, size int, age time.Duration) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Now().Add(-age)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSweepRemovesOrphans(t *testing.T) {
+	cfg := testConfig(t)
+
+	writeFile(t, filepath.Join(cfg.VersionsDir, "kept", "meta.json"), 2, 0)
+	writeFile(t, filepath.Join(cfg.VersionsDir, "orphan", "groq-go"), 100, 0)
+	writeFile(t, filepath.Join(cfg.UploadsDir, "old.txt"), 5, 2*time.Hour)
+	writeFile(t, filepath.Join(cfg.UploadsDir, "new.txt"), 5, 0)
+	writeFile(t, filepath.Join(cfg.Caches[0].Dir, "a.mp3"), 8, 2*time.Hour)
+	writeFile(t, filepath.Join(cfg.Caches[0].Dir, "b.mp3"), 8, 0)
+
+	report := New(cfg).Sweep(context.Background(), false)
+
+	if len(report.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", report.Errors)
+	}
+	if report.Reclaimed[CategoryVersions] != 100 {
+		t.Errorf("versions reclaimed = %d, want 100", report.Reclaimed[CategoryVersions])
+	}
+	if report.Reclaimed[CategoryUploads] != 5 {
+		t.Errorf("uploads reclaimed = %d, want 5", report.Reclaimed[CategoryUploads])
+	}
+	if report.Reclaimed["tts"] != 8 {
+		t.Errorf("tts reclaimed = %d, want 8", report.Reclaimed["tts"])
+	}
+
+	for _, path := range []string{
+		filepath.Join(cfg.VersionsDir, "kept", "meta.json"),
+		filepath.Join(cfg.UploadsDir, "new.txt"),
+		filepath.Join(cfg.Caches[0].Dir, "b.mp3"),
+	} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected %s to be kept: %v", path, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cfg.VersionsDir, "orphan")); !os.IsNotExist(err) {
+		t.Error("expected orphan version dir to be removed")
+	}
+}
+
+func TestSweepKeepsSessionImages(t *testing.T) {
+	cfg := testConfig(t)
+	store, err := storage.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Sessions = store
+	err = store.SaveSession(context.Background(), &storage.Session{
+		ID: "s1",
+		Messages: []client.Message{{Role: "user", Content: []client.ContentPart{
+			{Type: "text", Text: "what is this?"},
+			{Type: "image_url", ImageURL: &client.ImageURL{URL: "upload://image/png;wsimg_1_abc.png"}},
+		}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, filepath.Join(cfg.UploadsDir, "wsimg_1_abc.png"), 5, 2*time.Hour)
+	writeFile(t, filepath.Join(cfg.UploadsDir, "wsimg_2_def.png"), 5, 2*time.Hour)
+
+	report := New(cfg).Sweep(context.Background(), false)
+
+	if len(report.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", report.Errors)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.UploadsDir, "wsimg_1_abc.png")); err != nil {
+		t.Errorf("expected the session's image to be kept: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cfg.UploadsDir, "wsimg_2_def.png")); !os.IsNotExist(err) {
+		t.Error("expected the unreferenced image to be removed")
+	}
+}
+
+func TestSweepDryRun(t *testing.T) {
+	cfg := testConfig(t)
+	orphan := filepath.Join(cfg.VersionsDir, "orphan", "groq-go")
+	writeFile(t, orphan, 10, 0)
+
+	report := New(cfg).Sweep(context.Background(), true)
+
+	if len(report.Deletions) != 1 {
+		t.Fatalf("deletions = %d, want 1", len(report.Deletions))
+	}
+	if _, err := os.Stat(orphan); err != nil {
+		t.Errorf("dry run removed %s", orphan)
+	}
+}
+
+func TestWithinRoots(t *testing.T) {
+	cfg := testConfig(t)
+	j := New(cfg)
+
+	if !j.withinRoots(filepath.Join(cfg.UploadsDir, "file.txt")) {
+		t.Error("expected path inside uploads dir to be allowed")
+	}
+	if j.withinRoots(cfg.UploadsDir) {
+		t.Error("expected root itself to be rejected")
+	}
+	if j.withinRoots(filepath.Join(cfg.UploadsDir, "..", "elsewhere")) {
+		t.Error("expected path escaping the root to be rejected")
+	}
+}
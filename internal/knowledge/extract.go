@@ -0,0 +1,565 @@
+package knowledge
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Content types recorded on documents
+const (
+	ContentTypeText = "text/plain"
+	ContentTypePDF  = "application/pdf"
+	ContentTypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+)
+
+var (
+	// ErrUnsupported is returned for files whose text cannot be extracted
+	ErrUnsupported = errors.New("unsupported document type")
+	// ErrEncrypted is returned for password-protected documents
+	ErrEncrypted = errors.New("document is encrypted")
+)
+
+// maxDecodedStream bounds a single decompressed PDF stream
+const maxDecodedStream = 32 << 20
+
+// maxDecodedTotal bounds the streams of a PDF together; the rest of the
+// document is ignored. A variable so tests can shrink it.
+var maxDecodedTotal = 64 << 20
+
+var (
+	pdfMagic  = []byte("%PDF-")
+	zipMagic  = []byte("PK\x03\x04")
+	oleMagic  = []byte{0xD0, 0xCF, 0x11, 0xE0} // encrypted Office files are OLE containers
+	pdfStream = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+	pdfObject = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+
+	pdfType0     = regexp.MustCompile(`/Subtype\s*/Type0\b`)
+	pdfToUnicode = regexp.MustCompile(`/ToUnicode\s+(\d+)\s+\d+\s+R`)
+	pdfFontRef   = regexp.MustCompile(`/([^\s/<>\[\]()%]+)\s+(\d+)\s+\d+\s+R`)
+	pdfBfChar    = regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`)
+	pdfBfRange   = regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`)
+	pdfCharPair  = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]*)>`)
+	pdfRange     = regexp.MustCompile(`<([0-9A-Fa-f]+)>\s*<([0-9A-Fa-f]+)>\s*(<[0-9A-Fa-f]*>|\[[^\]]*\])`)
+	pdfHexString = regexp.MustCompile(`<([0-9A-Fa-f]*)>`)
+)
+
+// DetectContentType identifies a document by magic bytes, then extension
+func DetectContentType(filename string, data []byte) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	switch {
+	case bytes.HasPrefix(data, pdfMagic):
+		return ContentTypePDF
+	case bytes.HasPrefix(data, zipMagic) && ext == ".docx":
+		return ContentTypeDOCX
+	case bytes.HasPrefix(data, oleMagic) && ext == ".docx":
+		return ContentTypeDOCX
+	case utf8.Valid(data) && !bytes.ContainsRune(data, 0):
+		return ContentTypeText
+	}
+	return "application/octet-stream"
+}
+
+// ExtractText returns the plain text of a text, PDF or DOCX file
+func ExtractText(filename string, data []byte) (string, error) {
+	var text string
+	var err error
+	switch DetectContentType(filename, data) {
+	case ContentTypeText:
+		return string(data), nil
+	case ContentTypePDF:
+		text, err = extractPDF(data)
+	case ContentTypeDOCX:
+		text, err = extractDOCX(data)
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupported, filename)
+	}
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(text) == "" {
+		return "", fmt.Errorf("%w: no extractable text in %s", ErrUnsupported, filename)
+	}
+	return text, nil
+}
+
+// extractDOCX reads the paragraphs of word/document.xml
+func extractDOCX(data []byte) (string, error) {
+	if bytes.HasPrefix(data, oleMagic) {
+		return "", ErrEncrypted
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid DOCX: %v", ErrUnsupported, err)
+	}
+	f, err := zr.Open("word/document.xml")
+	if err != nil {
+		return "", fmt.Errorf("%w: DOCX has no document body", ErrUnsupported)
+	}
+	defer f.Close()
+
+	var sb strings.Builder
+	dec := xml.NewDecoder(io.LimitReader(f, maxDecodedStream))
+	inText := false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("%w: invalid DOCX XML: %v", ErrUnsupported, err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				sb.WriteByte('\t')
+			case "br", "cr":
+				sb.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				sb.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				sb.Write(t)
+			}
+		}
+	}
+	return sb.String(), nil
+}
+
+// extractPDF pulls the text shown by content streams. It handles
+// uncompressed and FlateDecode streams with simple fonts, and composite
+// (Type0) fonts through their ToUnicode maps, which covers most text-based
+// PDFs; scanned documents have no text to extract.
+func extractPDF(data []byte) (string, error) {
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return "", ErrEncrypted
+	}
+
+	streams := pdfStreams(data)
+	fonts, err := pdfCompositeFonts(data, streams)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, s := range streams {
+		if !s.objStm {
+			pdfContentText(s.content, fonts, &sb)
+		}
+	}
+	return sb.String(), nil
+}
+
+// pdfStreamData is a decoded stream and the object it belongs to
+type pdfStreamData struct {
+	obj     string
+	objStm  bool
+	content []byte
+}
+
+// pdfStreams decodes the streams that may hold text, fonts or objects,
+// up to maxDecodedTotal bytes in all
+func pdfStreams(data []byte) []pdfStreamData {
+	objs := pdfObject.FindAllSubmatchIndex(data, -1)
+	var streams []pdfStreamData
+	budget := maxDecodedTotal
+	o := 0
+	for _, loc := range pdfStream.FindAllSubmatchIndex(data, -1) {
+		if budget <= 0 {
+			break
+		}
+		dict := string(data[loc[2]:loc[3]])
+		start := loc[1]
+		end := bytes.Index(data[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		raw := data[start : start+end]
+
+		if strings.Contains(dict, "/Subtype/Image") || strings.Contains(dict, "/Subtype /Image") ||
+			strings.Contains(dict, "/XRef") {
+			continue
+		}
+
+		content := raw[:min(len(raw), budget)]
+		if strings.Contains(dict, "/Filter") {
+			if !strings.Contains(dict, "/FlateDecode") {
+				continue
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(raw))
+			if err != nil {
+				continue
+			}
+			decoded, err := io.ReadAll(io.LimitReader(zr, int64(min(maxDecodedStream, budget))))
+			zr.Close()
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			content = decoded
+		}
+		budget -= len(content)
+
+		for o+1 < len(objs) && objs[o+1][0] < loc[0] {
+			o++
+		}
+		s := pdfStreamData{objStm: strings.Contains(dict, "/ObjStm"), content: content}
+		if o < len(objs) && objs[o][0] < loc[0] {
+			s.obj = string(data[objs[o][2]:objs[o][3]])
+		}
+		streams = append(streams, s)
+	}
+	return streams
+}
+
+// pdfCompositeFonts maps the resource names of composite (Type0) fonts
+// to their ToUnicode maps. Their strings hold glyph IDs rather than
+// characters, so without a usable map the text cannot be recovered.
+func pdfCompositeFonts(data []byte, streams []pdfStreamData) (map[string]*pdfCMap, error) {
+	cmaps := make(map[string]*pdfCMap) // by object number
+	for _, s := range streams {
+		if s.objStm {
+			// Fonts packed in object streams are not resolved
+			if pdfType0.Match(s.content) {
+				return nil, fmt.Errorf("%w: PDF has composite fonts in object streams", ErrUnsupported)
+			}
+			continue
+		}
+		if bytes.Contains(s.content, []byte("begincmap")) {
+			if m := parseCMap(s.content); m != nil {
+				cmaps[s.obj] = m
+			}
+		}
+	}
+
+	byObj := make(map[string]*pdfCMap)
+	objs := pdfObject.FindAllSubmatchIndex(data, -1)
+	for i, loc := range objs {
+		end := len(data)
+		if i+1 < len(objs) {
+			end = objs[i+1][0]
+		}
+		dict := data[loc[1]:end]
+		if k := bytes.Index(dict, []byte("stream")); k >= 0 {
+			dict = dict[:k]
+		}
+		if !pdfType0.Match(dict) {
+			continue
+		}
+		ref := pdfToUnicode.FindSubmatch(dict)
+		if ref == nil {
+			return nil, fmt.Errorf("%w: PDF has a composite font without a ToUnicode map", ErrUnsupported)
+		}
+		m, ok := cmaps[string(ref[1])]
+		if !ok {
+			return nil, fmt.Errorf("%w: PDF has a composite font with an unreadable ToUnicode map", ErrUnsupported)
+		}
+		byObj[string(data[loc[2]:loc[3]])] = m
+	}
+	if len(byObj) == 0 {
+		return nil, nil
+	}
+
+	// Resource dictionaries name fonts as /F1 5 0 R; other references
+	// don't point at fonts and are skipped
+	fonts := make(map[string]*pdfCMap)
+	for _, ref := range pdfFontRef.FindAllSubmatch(data, -1) {
+		if m, ok := byObj[string(ref[2])]; ok {
+			fonts[string(ref[1])] = m
+		}
+	}
+	return fonts, nil
+}
+
+// pdfCMap is a ToUnicode map from 1- or 2-byte character codes to text
+type pdfCMap struct {
+	width int
+	chars map[int]string
+}
+
+// parseCMap reads the bfchar and bfrange mappings of a ToUnicode CMap,
+// or returns nil if it has none or uses codes wider than 2 bytes
+func parseCMap(b []byte) *pdfCMap {
+	m := &pdfCMap{chars: make(map[int]string)}
+	code := func(h []byte) (int, bool) {
+		if w := len(h) / 2; m.width == 0 && (w == 1 || w == 2) {
+			m.width = w
+		}
+		if len(h) != 2*m.width {
+			return 0, false
+		}
+		v, err := strconv.ParseUint(string(h), 16, 16)
+		return int(v), err == nil
+	}
+
+	for _, section := range pdfBfChar.FindAllSubmatch(b, -1) {
+		for _, pair := range pdfCharPair.FindAllSubmatch(section[1], -1) {
+			if c, ok := code(pair[1]); ok {
+				m.chars[c] = utf16Hex(pair[2], 0)
+			}
+		}
+	}
+	for _, section := range pdfBfRange.FindAllSubmatch(b, -1) {
+		for _, r := range pdfRange.FindAllSubmatch(section[1], -1) {
+			lo, ok1 := code(r[1])
+			hi, ok2 := code(r[2])
+			if !ok1 || !ok2 || hi < lo {
+				continue
+			}
+			if r[3][0] == '[' {
+				for i, dst := range pdfHexString.FindAllSubmatch(r[3], -1) {
+					if lo+i > hi {
+						break
+					}
+					m.chars[lo+i] = utf16Hex(dst[1], 0)
+				}
+				continue
+			}
+			dst := r[3][1 : len(r[3])-1]
+			for c := lo; c <= hi; c++ {
+				m.chars[c] = utf16Hex(dst, c-lo)
+			}
+		}
+	}
+	if m.width == 0 || len(m.chars) == 0 {
+		return nil
+	}
+	return m
+}
+
+// utf16Hex decodes UTF-16BE hex digits, adding offset to the last unit
+// as bfrange destinations do
+func utf16Hex(h []byte, offset int) string {
+	b, err := hex.DecodeString(string(h))
+	if err != nil || len(b) < 2 {
+		return ""
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+	}
+	units[len(units)-1] += uint16(offset)
+	return string(utf16.Decode(units))
+}
+
+// decode maps a shown string's codes to text, dropping unmapped codes
+func (m *pdfCMap) decode(s string) string {
+	var sb strings.Builder
+	for i := 0; i+m.width <= len(s); i += m.width {
+		c := int(s[i])
+		if m.width == 2 {
+			c = c<<8 | int(s[i+1])
+		}
+		sb.WriteString(m.chars[c])
+	}
+	return sb.String()
+}
+
+// pdfContentText interprets the text operators of a content stream.
+// Strings shown in a composite font decode through its ToUnicode map.
+func pdfContentText(content []byte, fonts map[string]*pdfCMap, sb *strings.Builder) {
+	var operands []string
+	var name string
+	var font *pdfCMap
+	shown := func() string {
+		var text strings.Builder
+		for _, s := range operands {
+			if font != nil {
+				text.WriteString(font.decode(s))
+			} else {
+				text.WriteString(pdfLatin1(s))
+			}
+		}
+		return text.String()
+	}
+	inText := false
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '(':
+			s, n := pdfLiteral(content[i:])
+			operands = append(operands, s)
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] != '<':
+			end := bytes.IndexByte(content[i:], '>')
+			if end < 0 {
+				return
+			}
+			operands = append(operands, pdfHex(content[i+1:i+end]))
+			i += end + 1
+		case c == '[' || c == ']':
+			// TJ arrays: the strings are collected as operands and the
+			// kerning numbers between them are ignored
+			i++
+		case c == '/':
+			i++
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !strings.ContainsRune("()<>[]/%", rune(content[i])) {
+				i++
+			}
+			name = string(content[start:i])
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case isPDFSpace(c):
+			i++
+		default:
+			start := i
+			for i < len(content) && !isPDFSpace(content[i]) && !strings.ContainsRune("()<>[]/%", rune(content[i])) {
+				i++
+			}
+			if i == start {
+				i++ // other delimiters
+				continue
+			}
+			switch op := string(content[start:i]); op {
+			case "BT":
+				inText = true
+				operands = operands[:0]
+			case "ET":
+				inText = false
+				sb.WriteByte('\n')
+				operands = operands[:0]
+			case "Tj", "TJ":
+				if inText {
+					sb.WriteString(shown())
+				}
+				operands = operands[:0]
+			case "'", "\"":
+				if inText {
+					sb.WriteByte('\n')
+					sb.WriteString(shown())
+				}
+				operands = operands[:0]
+			case "Tf":
+				font = fonts[name]
+				operands = operands[:0]
+			case "T*", "Td", "TD":
+				if inText {
+					sb.WriteByte('\n')
+				}
+				operands = operands[:0]
+			default:
+				if op[0] >= 'A' && op[0] <= 'z' && !isNumeric(op) {
+					operands = operands[:0]
+				}
+			}
+		}
+	}
+}
+
+// pdfLiteral decodes a (...) string to its raw bytes and returns it with
+// the bytes consumed
+func pdfLiteral(b []byte) (string, int) {
+	var sb strings.Builder
+	depth := 0
+	i := 0
+	for ; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case '(':
+			depth++
+			if depth == 1 {
+				continue
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return sb.String(), i + 1
+			}
+		case '\\':
+			i++
+			if i >= len(b) {
+				return sb.String(), i
+			}
+			switch e := b[i]; e {
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					j := 0
+					for ; j < 3 && i+j < len(b) && b[i+j] >= '0' && b[i+j] <= '7'; j++ {
+						v = v*8 + int(b[i+j]-'0')
+					}
+					i += j - 1
+					sb.WriteByte(byte(v))
+				} else {
+					sb.WriteByte(e)
+				}
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String(), i
+}
+
+// pdfHex decodes a <...> string to its raw bytes
+func pdfHex(b []byte) string {
+	clean := strings.Map(func(r rune) rune {
+		if isPDFSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, string(b))
+	if len(clean)%2 == 1 {
+		clean += "0"
+	}
+	decoded, err := hex.DecodeString(clean)
+	if err != nil {
+		return ""
+	}
+	return string(decoded)
+}
+
+// pdfLatin1 decodes a string shown in a simple font, dropping control
+// characters; Latin-1 approximates the standard encodings
+func pdfLatin1(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; c >= 0x20 || c == '\n' || c == '\r' || c == '\t' {
+			sb.WriteRune(rune(c))
+		}
+	}
+	return sb.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isNumeric(s string) bool {
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '.' && r != '-' && r != '+' {
+			return false
+		}
+	}
+	return true
+}
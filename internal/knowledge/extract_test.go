@@ -0,0 +1,155 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+	return data
+}
+
+func TestExtractText(t *testing.T) {
+	tests := []struct {
+		file string
+		want []string
+	}{
+		{"sample.pdf", []string{"Quarterly revenue grew in the (northern) region.", "Widgetsales", "doubled."}},
+		{"sample.docx", []string{"Onboarding checklist", "Request a badge\tand laptop."}},
+	}
+	for _, tt := range tests {
+		text, err := ExtractText(tt.file, readFixture(t, tt.file))
+		if err != nil {
+			t.Errorf("%s: ExtractText failed: %v", tt.file, err)
+			continue
+		}
+		for _, want := range tt.want {
+			if !strings.Contains(text, want) {
+				t.Errorf("%s: expected %q in extracted text %q", tt.file, want, text)
+			}
+		}
+	}
+}
+
+func TestExtractTextErrors(t *testing.T) {
+	encrypted := []byte("%PDF-1.4\n1 0 obj\n<< /Filter /Standard >>\nendobj\ntrailer\n<< /Encrypt 1 0 R >>\n%%EOF\n")
+	if _, err := ExtractText("secret.pdf", encrypted); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Expected ErrEncrypted, got %v", err)
+	}
+	if _, err := ExtractText("image.png", []byte("\x89PNG\r\n\x1a\n\x00\x00")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for binary data, got %v", err)
+	}
+	if _, err := ExtractText("broken.docx", []byte("PK\x03\x04garbage")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported for a corrupt DOCX, got %v", err)
+	}
+}
+
+// pdfWithFont builds a one-page PDF showing text in font /F1, defined by
+// fontDict; extra objects follow it
+func pdfWithFont(fontDict, text string, extra ...string) []byte {
+	content := "BT /F1 12 Tf 72 720 Td " + text + " Tj ET"
+	pdf := "%PDF-1.4\n" +
+		"1 0 obj\n<< /Type /Page /Resources << /Font << /F1 2 0 R >> >> /Contents 3 0 R >>\nendobj\n" +
+		"2 0 obj\n" + fontDict + "\nendobj\n" +
+		fmt.Sprintf("3 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(content), content)
+	for _, obj := range extra {
+		pdf += obj
+	}
+	return []byte(pdf + "trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+}
+
+func TestExtractPDFCompositeFonts(t *testing.T) {
+	cmap := "/CIDInit /ProcSet findresource begin 12 dict begin begincmap\n" +
+		"1 begincodespacerange <0000> <FFFF> endcodespacerange\n" +
+		"2 beginbfchar <0003> <0020> <0010> <00E9> endbfchar\n" +
+		"2 beginbfrange <0020> <0022> <0041> <0030> <0031> [<0078> <D83DDE00>] endbfrange\n" +
+		"endcmap CMapName currentdict /CMap defineresource pop end end"
+	cmapObj := fmt.Sprintf("4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(cmap), cmap)
+
+	data := pdfWithFont("<< /Type /Font /Subtype /Type0 /BaseFont /Noto /Encoding /Identity-H /ToUnicode 4 0 R >>",
+		"<0020002100220003001000030030 0031>", cmapObj)
+	text, err := ExtractText("cid.pdf", data)
+	if err != nil {
+		t.Fatalf("ExtractText failed: %v", err)
+	}
+	if !strings.Contains(text, "ABC é x\U0001F600") {
+		t.Errorf("Expected the text decoded through ToUnicode, got %q", text)
+	}
+
+	// Without a ToUnicode map the strings are glyph IDs, not text
+	data = pdfWithFont("<< /Type /Font /Subtype /Type0 /BaseFont /Noto /Encoding /Identity-H >>", "<00200021>")
+	if _, err := ExtractText("cid.pdf", data); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported without ToUnicode, got %v", err)
+	}
+	data = pdfWithFont("<< /Type /Font /Subtype /Type0 /ToUnicode 9 0 R >>", "<00200021>")
+	if _, err := ExtractText("cid.pdf", data); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported with a missing ToUnicode, got %v", err)
+	}
+
+	// Simple fonts still decode byte by byte
+	data = pdfWithFont("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>", "(Caf\\351)")
+	if text, err := ExtractText("simple.pdf", data); err != nil || !strings.Contains(text, "Café") {
+		t.Errorf("Expected a simple font decoded as Latin-1, got %q (%v)", text, err)
+	}
+}
+
+func TestExtractPDFTotalLimit(t *testing.T) {
+	old := maxDecodedTotal
+	t.Cleanup(func() { maxDecodedTotal = old })
+
+	second := "BT (second page) Tj ET"
+	data := pdfWithFont("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>", "(first page)",
+		fmt.Sprintf("4 0 obj\n<< /Length %d >>\nstream\n%s\nendstream\nendobj\n", len(second), second))
+	text, err := ExtractText("long.pdf", data)
+	if err != nil || !strings.Contains(text, "second page") {
+		t.Fatalf("Expected both pages, got %q (%v)", text, err)
+	}
+
+	maxDecodedTotal = 45
+	text, err = ExtractText("long.pdf", data)
+	if err != nil || !strings.Contains(text, "first page") || strings.Contains(text, "second page") {
+		t.Errorf("Expected extraction to stop after the limit, got %q (%v)", text, err)
+	}
+}
+
+func TestAddFileIsSearchable(t *testing.T) {
+	kb, err := NewKnowledgeBase(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := kb.AddDocument(ctx, "notes.txt", "Unrelated notes about gardening and tomatoes."); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+
+	for _, tt := range []struct{ file, contentType, query string }{
+		{"sample.pdf", ContentTypePDF, "quarterly revenue"},
+		{"sample.docx", ContentTypeDOCX, "onboarding badge"},
+	} {
+		doc, err := kb.AddFile(ctx, tt.file, readFixture(t, tt.file))
+		if err != nil {
+			t.Fatalf("%s: AddFile failed: %v", tt.file, err)
+		}
+		if doc.ContentType != tt.contentType {
+			t.Errorf("%s: expected content type %s, got %s", tt.file, tt.contentType, doc.ContentType)
+		}
+		results := kb.Search(ctx, tt.query, 3)
+		if len(results) == 0 || results[0].DocName != tt.file {
+			t.Errorf("%s: expected %q to find the document, got %+v", tt.file, tt.query, results)
+		}
+	}
+
+	if _, err := kb.AddFile(ctx, "image.png", []byte("\x89PNG\r\n\x1a\n\x00\x00")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Expected ErrUnsupported, got %v", err)
+	}
+}
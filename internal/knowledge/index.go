@@ -0,0 +1,231 @@
+package knowledge
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IndexFile is the name of the serialized search index in the knowledge dir
+const IndexFile = "index.json"
+
+// indexVersion is bumped when tokenization or the format changes so old
+// indexes are rebuilt
+const indexVersion = 2
+
+// Posting records how often a term occurs in a chunk
+type Posting struct {
+	ChunkID string `json:"c"`
+	TF      int    `json:"f"`
+}
+
+// searchIndex is an inverted index from term to the chunks containing it
+type searchIndex struct {
+	Version  int                  `json:"version"`
+	Docs     map[string]string    `json:"docs"`     // document ID -> chunk hash
+	Lengths  map[string]int       `json:"lengths"`  // chunk ID -> token count
+	Postings map[string][]Posting `json:"postings"` // term -> chunks
+
+	chunks map[string]chunkRef // chunk ID -> location, rebuilt on load
+	terms  []string            // sorted keys of Postings, for prefix matches
+}
+
+// chunkRef locates a chunk within its document
+type chunkRef struct {
+	doc *Document
+	pos int
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{
+		Version:  indexVersion,
+		Docs:     make(map[string]string),
+		Lengths:  make(map[string]int),
+		Postings: make(map[string][]Posting),
+		chunks:   make(map[string]chunkRef),
+	}
+}
+
+// link records where a document's chunks live without re-indexing them
+func (idx *searchIndex) link(doc *Document) {
+	for i, chunk := range doc.Chunks {
+		idx.chunks[chunk.ID] = chunkRef{doc: doc, pos: i}
+	}
+}
+
+// chunkHash fingerprints what the index holds of a document, its chunks
+func chunkHash(doc *Document) string {
+	h := sha256.New()
+	for _, chunk := range doc.Chunks {
+		h.Write([]byte(chunk.ID))
+		h.Write([]byte{0})
+		h.Write([]byte(chunk.Text))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// add indexes the chunks of a document
+func (idx *searchIndex) add(doc *Document) {
+	idx.Docs[doc.ID] = chunkHash(doc)
+	idx.link(doc)
+	var added []string
+	for _, chunk := range doc.Chunks {
+		terms := tokenize(chunk.Text)
+		idx.Lengths[chunk.ID] = len(terms)
+
+		freq := make(map[string]int)
+		for _, t := range terms {
+			freq[t]++
+		}
+		for term, tf := range freq {
+			if _, ok := idx.Postings[term]; !ok {
+				added = append(added, term)
+			}
+			idx.Postings[term] = append(idx.Postings[term], Posting{ChunkID: chunk.ID, TF: tf})
+		}
+	}
+	if len(added) > 0 {
+		idx.mergeTerms(added)
+	}
+}
+
+// sortTerms rebuilds the sorted term list from the postings
+func (idx *searchIndex) sortTerms() {
+	idx.terms = make([]string, 0, len(idx.Postings))
+	for term := range idx.Postings {
+		idx.terms = append(idx.terms, term)
+	}
+	sort.Strings(idx.terms)
+}
+
+// mergeTerms adds new terms to the sorted term list
+func (idx *searchIndex) mergeTerms(added []string) {
+	sort.Strings(added)
+	merged := make([]string, 0, len(idx.terms)+len(added))
+	i, j := 0, 0
+	for i < len(idx.terms) && j < len(added) {
+		if idx.terms[i] < added[j] {
+			merged = append(merged, idx.terms[i])
+			i++
+		} else {
+			merged = append(merged, added[j])
+			j++
+		}
+	}
+	merged = append(merged, idx.terms[i:]...)
+	idx.terms = append(merged, added[j:]...)
+}
+
+// remove drops every posting of a document
+func (idx *searchIndex) remove(doc *Document) {
+	delete(idx.Docs, doc.ID)
+	chunkIDs := make(map[string]bool, len(doc.Chunks))
+	terms := make(map[string]bool)
+	for _, chunk := range doc.Chunks {
+		chunkIDs[chunk.ID] = true
+		delete(idx.Lengths, chunk.ID)
+		delete(idx.chunks, chunk.ID)
+		for _, t := range tokenize(chunk.Text) {
+			terms[t] = true
+		}
+	}
+
+	dropped := false
+	for term := range terms {
+		postings := idx.Postings[term][:0]
+		for _, p := range idx.Postings[term] {
+			if !chunkIDs[p.ChunkID] {
+				postings = append(postings, p)
+			}
+		}
+		if len(postings) == 0 {
+			delete(idx.Postings, term)
+			dropped = true
+		} else {
+			idx.Postings[term] = postings
+		}
+	}
+	if dropped {
+		kept := idx.terms[:0]
+		for _, term := range idx.terms {
+			if _, ok := idx.Postings[term]; ok {
+				kept = append(kept, term)
+			}
+		}
+		idx.terms = kept
+	}
+}
+
+// match returns the chunks matching a query term with the term's exact
+// frequency in each. A query term also matches longer indexed terms
+// starting with it ("request" matches "requests"); those count as a
+// single occurrence.
+func (idx *searchIndex) match(term string) map[string]int {
+	matches := make(map[string]int)
+	for i := sort.SearchStrings(idx.terms, term); i < len(idx.terms) && strings.HasPrefix(idx.terms[i], term); i++ {
+		indexed := idx.terms[i]
+		for _, p := range idx.Postings[indexed] {
+			if indexed == term {
+				matches[p.ChunkID] = p.TF
+			} else if _, ok := matches[p.ChunkID]; !ok {
+				matches[p.ChunkID] = 0
+			}
+		}
+	}
+	return matches
+}
+
+// covers reports whether the index was built from exactly these documents,
+// chunk for chunk
+func (idx *searchIndex) covers(docs map[string]*Document) bool {
+	if idx.Version != indexVersion || len(idx.Docs) != len(docs) {
+		return false
+	}
+	for id, doc := range docs {
+		if h, ok := idx.Docs[id]; !ok || h != chunkHash(doc) {
+			return false
+		}
+	}
+	return true
+}
+
+// loadIndex reads the serialized index, rebuilding it if it is missing or
+// out of date with the documents on disk
+func (kb *KnowledgeBase) loadIndex() error {
+	if data, err := os.ReadFile(filepath.Join(kb.dir, IndexFile)); err == nil {
+		idx := newSearchIndex()
+		if json.Unmarshal(data, idx) == nil && idx.covers(kb.documents) {
+			for _, doc := range kb.documents {
+				idx.link(doc)
+			}
+			idx.sortTerms()
+			kb.index = idx
+			return nil
+		}
+	}
+
+	kb.index = newSearchIndex()
+	for _, doc := range kb.documents {
+		kb.index.add(doc)
+	}
+	return kb.saveIndex()
+}
+
+// saveIndex writes the index atomically
+func (kb *KnowledgeBase) saveIndex() error {
+	data, err := json.Marshal(kb.index)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(kb.dir, IndexFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,267 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// scanSearch is the full-scan search the index replaced, kept to check
+// the index against and to benchmark it
+func scanSearch(kb *KnowledgeBase, query string, maxResults int) []SearchResult {
+	queryTerms := tokenize(query)
+	if len(queryTerms) == 0 {
+		return nil
+	}
+
+	idf := make(map[string]float64)
+	totalDocs := 0
+	for _, doc := range kb.documents {
+		totalDocs += len(doc.Chunks)
+	}
+	for _, term := range queryTerms {
+		docCount := 0
+		for _, doc := range kb.documents {
+			for _, chunk := range doc.Chunks {
+				if strings.Contains(strings.ToLower(chunk.Text), term) {
+					docCount++
+					break
+				}
+			}
+		}
+		if docCount > 0 {
+			idf[term] = math.Log(float64(totalDocs+1) / float64(docCount+1))
+		}
+	}
+
+	var results []SearchResult
+	for _, doc := range kb.documents {
+		for _, chunk := range doc.Chunks {
+			textLower := strings.ToLower(chunk.Text)
+			textTerms := tokenize(chunk.Text)
+			termFreq := make(map[string]int)
+			for _, t := range textTerms {
+				termFreq[t]++
+			}
+			dl := float64(len(textTerms))
+			score := 0.0
+			for _, term := range queryTerms {
+				tf := float64(termFreq[term])
+				if tf > 0 || strings.Contains(textLower, term) {
+					if tf == 0 {
+						tf = 1
+					}
+					score += idf[term] * (tf * 2.2) / (tf + 1.2*(0.25+0.75*dl/100))
+				}
+			}
+			if score > 0 {
+				results = append(results, SearchResult{Chunk: chunk, DocName: doc.Name, Score: score})
+			}
+		}
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Chunk.ID < results[j].Chunk.ID
+	})
+	if len(results) > maxResults {
+		results = results[:maxResults]
+	}
+	return results
+}
+
+var benchWords = strings.Fields("server client request response cache index token chunk query score " +
+	"document search latency throughput replica shard backup restore session upload " +
+	"goroutine channel mutex deadline context timeout retry network socket buffer")
+
+// newBenchKB builds a knowledge base of 50 documents with 20 chunks each
+func newBenchKB(tb testing.TB) *KnowledgeBase {
+	tb.Helper()
+	kb, err := NewKnowledgeBase(tb.TempDir())
+	if err != nil {
+		tb.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	rng := rand.New(rand.NewSource(1))
+	for d := 0; d < 50; d++ {
+		var paras []string
+		for c := 0; c < 20; c++ {
+			words := make([]string, 60)
+			for i := range words {
+				words[i] = benchWords[rng.Intn(len(benchWords))]
+			}
+			paras = append(paras, strings.Join(words, " "))
+		}
+		if _, err := kb.AddDocument(context.Background(), fmt.Sprintf("doc%d.md", d), strings.Join(paras, "\n\n")); err != nil {
+			tb.Fatalf("AddDocument failed: %v", err)
+		}
+	}
+	return kb
+}
+
+func TestIndexMatchesScan(t *testing.T) {
+	kb := newBenchKB(t)
+	for _, query := range []string{"cache latency", "goroutine mutex deadline", "replica", "sock", "unknownterm"} {
+		got := kb.Search(context.Background(), query, 10)
+		want := scanSearch(kb, query, 10)
+		if len(got) != len(want) {
+			t.Errorf("Search(%q): got %d results, scan found %d", query, len(got), len(want))
+			continue
+		}
+		// Equal scores may differ in the last bits, so ties can swap places
+		wantScores := make(map[string]float64)
+		for _, r := range want {
+			wantScores[r.Chunk.ID] = r.Score
+		}
+		for i := range got {
+			if math.Abs(got[i].Score-want[i].Score) > 1e-9 {
+				t.Errorf("Search(%q) result %d: got score %.6f, scan found %.6f", query, i, got[i].Score, want[i].Score)
+			}
+			if s, ok := wantScores[got[i].Chunk.ID]; ok && math.Abs(s-got[i].Score) > 1e-9 {
+				t.Errorf("Search(%q): chunk %s scored %.6f, scan scored %.6f", query, got[i].Chunk.ID, got[i].Score, s)
+			}
+		}
+	}
+}
+
+func TestIndexDeleteRemovesPostings(t *testing.T) {
+	kb := newTestKB(t, englishCorpus...)
+	doc, err := kb.AddDocument(context.Background(), "kafka.md", "Kafka partitions replicate logs.")
+	if err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if len(kb.Search(context.Background(), "kafka", 5)) != 1 {
+		t.Fatal("Expected the new document to be searchable")
+	}
+
+	if err := kb.DeleteDocument(context.Background(), doc.ID); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+	for _, term := range []string{"kafka", "partitions", "replicate", "logs"} {
+		if _, ok := kb.index.Postings[term]; ok {
+			t.Errorf("Expected postings for %q to be removed", term)
+		}
+	}
+	for id := range kb.index.Lengths {
+		if strings.HasPrefix(id, doc.ID+"-") {
+			t.Errorf("Expected chunk %s to be removed from the index", id)
+		}
+	}
+	if results := kb.Search(context.Background(), "kafka", 5); len(results) != 0 {
+		t.Errorf("Expected no results after delete, got %+v", results)
+	}
+
+	// Shared terms keep the postings of the remaining documents
+	if len(kb.index.Postings["concurrent"]) != 2 {
+		t.Errorf("Expected other postings to survive, got %+v", kb.index.Postings["concurrent"])
+	}
+}
+
+func TestIndexPersistence(t *testing.T) {
+	dir := t.TempDir()
+	kb, err := NewKnowledgeBase(dir)
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	for _, d := range englishCorpus {
+		kb.AddDocument(context.Background(), d[0], d[1])
+	}
+	want := kb.Search(context.Background(), "concurrent goroutines", 5)
+
+	if _, err := os.Stat(filepath.Join(dir, IndexFile)); err != nil {
+		t.Fatalf("Expected %s to be written: %v", IndexFile, err)
+	}
+
+	reloaded, err := NewKnowledgeBase(dir)
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	if len(reloaded.ListDocuments(context.Background())) != len(englishCorpus) {
+		t.Errorf("Expected the index file not to load as a document")
+	}
+	if got := reloaded.Search(context.Background(), "concurrent goroutines", 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the same results after reload, got %+v want %+v", got, want)
+	}
+
+	// A stale index is rebuilt from the documents
+	os.WriteFile(filepath.Join(dir, IndexFile), []byte(`{"version":1,"docs":{}}`), 0644)
+	rebuilt, err := NewKnowledgeBase(dir)
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	if got := rebuilt.Search(context.Background(), "concurrent goroutines", 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected a rebuilt index, got %+v", got)
+	}
+}
+
+func TestIndexStaleDocument(t *testing.T) {
+	dir := t.TempDir()
+	kb, err := NewKnowledgeBase(dir)
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	for _, d := range englishCorpus {
+		kb.AddDocument(context.Background(), d[0], d[1])
+	}
+	doc, err := kb.AddDocument(context.Background(), "deploy.md", "Deploys run on Fly.")
+	if err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+
+	// Edited outside the index with the same number of chunks
+	doc.Content = "Deploys run on Nomad."
+	doc.Chunks[0].Text = doc.Content
+	if err := kb.saveDocument(doc); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := NewKnowledgeBase(dir)
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	if results := reloaded.Search(context.Background(), "nomad", 5); len(results) != 1 {
+		t.Errorf("Expected the edited document re-indexed, got %+v", results)
+	}
+	if results := reloaded.Search(context.Background(), "fly", 5); len(results) != 0 {
+		t.Errorf("Expected no stale postings, got %+v", results)
+	}
+}
+
+func TestIndexPrefixMatch(t *testing.T) {
+	kb := newTestKB(t, englishCorpus...)
+	if _, err := kb.AddDocument(context.Background(), "queue.md", "Requeue failed jobs."); err != nil {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+	if matches := kb.index.match("request"); len(matches) == 0 {
+		t.Errorf("Expected %q to match longer terms, got %v", "request", matches)
+	}
+	if matches := kb.index.match("queue"); len(matches) != 0 {
+		t.Errorf("Expected no match inside a term, got %v", matches)
+	}
+	if matches := kb.index.match("requeue"); len(matches) != 1 {
+		t.Errorf("Expected a term added later to match, got %v", matches)
+	}
+}
+
+func BenchmarkSearchScan(b *testing.B) {
+	kb := newBenchKB(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		scanSearch(kb, "cache latency timeout", 5)
+	}
+}
+
+func BenchmarkSearchIndex(b *testing.B) {
+	kb := newBenchKB(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		kb.Search(context.Background(), "cache latency timeout", 5)
+	}
+}
@@ -0,0 +1,187 @@
+package knowledge
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"groq-go/internal/ignore"
+)
+
+// IngestMaxFileSize is the largest file, in bytes, IngestDirectory adds
+var IngestMaxFileSize int64 = 1 << 20
+
+// IngestResult counts the files an ingestion touched
+type IngestResult struct {
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	Skipped int `json:"skipped"` // Unchanged, binary, empty or too large
+	Removed int `json:"removed"` // Documents whose file is gone or no longer included
+}
+
+// ingestFile is a file whose content differs from its document
+type ingestFile struct {
+	path    string // Slash-separated, relative to the root
+	content string
+	hash    string
+}
+
+// IngestDirectory adds the text files below root to the knowledge base as
+// documents of projectID, named by their path relative to root. The walk
+// respects .gitignore files and ignore.DefaultExcludes. include and exclude
+// are doublestar globs matched against the relative path; with no include
+// globs every file is a candidate.
+//
+// Re-ingesting compares content hashes: unchanged files are skipped,
+// changed ones are updated in place and documents of files that are gone
+// are removed.
+func (kb *KnowledgeBase) IngestDirectory(ctx context.Context, projectID, root string, include, exclude []string) (*IngestResult, error) {
+	if projectID == "" {
+		return nil, fmt.Errorf("project ID required")
+	}
+	for _, pattern := range append(append([]string{}, include...), exclude...) {
+		if !doublestar.ValidatePattern(pattern) {
+			return nil, fmt.Errorf("invalid glob pattern %q", pattern)
+		}
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	// Hashes of the project's documents as of now, by source path
+	kb.mu.RLock()
+	known := make(map[string]string)
+	for _, doc := range kb.documents {
+		if doc.ProjectID == projectID {
+			known[doc.SourcePath] = doc.ContentHash
+		}
+	}
+	kb.mu.RUnlock()
+
+	// Read outside the lock so searches are not held up by the walk
+	result := &IngestResult{}
+	seen := make(map[string]bool)
+	var changed []ingestFile
+	err = ignore.Walk(ctx, root, false, func(path string, d fs.DirEntry) error {
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if !matchesIngestGlobs(rel, include, exclude) {
+			return nil
+		}
+		if info, err := d.Info(); err != nil || info.Size() > IngestMaxFileSize {
+			result.Skipped++
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil || len(data) == 0 || DetectContentType(rel, data) != ContentTypeText {
+			result.Skipped++
+			return nil
+		}
+
+		seen[rel] = true
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if known[rel] == hash {
+			result.Skipped++
+			return nil
+		}
+		changed = append(changed, ingestFile{path: rel, content: string(data), hash: hash})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	existing := make(map[string]*Document)
+	for _, doc := range kb.documents {
+		if doc.ProjectID == projectID {
+			existing[doc.SourcePath] = doc
+		}
+	}
+
+	now := time.Now()
+	for _, f := range changed {
+		old := existing[f.path]
+		doc := &Document{
+			Name:        f.path,
+			ContentType: ContentTypeText,
+			Content:     f.content,
+			ProjectID:   projectID,
+			SourcePath:  f.path,
+			ContentHash: f.hash,
+			CreatedAt:   now,
+		}
+		if old != nil {
+			doc.ID, doc.CreatedAt, doc.UpdatedAt = old.ID, old.CreatedAt, now
+		} else if doc.ID, err = kb.uniqueIDLocked(); err != nil {
+			return nil, err
+		}
+		doc.Chunks = kb.chunkText(doc.ID, doc.Content)
+
+		if err := kb.saveDocument(doc); err != nil {
+			return nil, err
+		}
+		if old != nil {
+			kb.index.remove(old)
+			result.Updated++
+		} else {
+			result.Added++
+		}
+		kb.index.add(doc)
+		kb.documents[doc.ID] = doc
+	}
+
+	for path, doc := range existing {
+		if seen[path] {
+			continue
+		}
+		if err := os.Remove(filepath.Join(kb.dir, doc.ID+".json")); err != nil && !os.IsNotExist(err) {
+			return nil, err
+		}
+		kb.index.remove(doc)
+		delete(kb.documents, doc.ID)
+		result.Removed++
+	}
+
+	if result.Added+result.Updated+result.Removed > 0 {
+		if err := kb.saveIndex(); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// matchesIngestGlobs reports whether rel is included and not excluded
+func matchesIngestGlobs(rel string, include, exclude []string) bool {
+	for _, pattern := range exclude {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return false
+		}
+	}
+	if len(include) == 0 {
+		return true
+	}
+	for _, pattern := range include {
+		if ok, _ := doublestar.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package knowledge
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// writeTree creates files, by slash-separated relative path, below root
+func writeTree(t *testing.T, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// projectPaths lists the source paths of a project's documents
+func projectPaths(kb *KnowledgeBase, projectID string) []string {
+	var paths []string
+	for _, doc := range kb.ListDocuments(context.Background()) {
+		if doc.ProjectID == projectID {
+			paths = append(paths, doc.SourcePath)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func TestIngestDirectory(t *testing.T) {
+	orig := IngestMaxFileSize
+	IngestMaxFileSize = 1000
+	t.Cleanup(func() { IngestMaxFileSize = orig })
+
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		".gitignore":          "*.log\n",
+		"main.go":             "package main\n\nfunc main() { startServer() }\n",
+		"server/server.go":    "package server\n\n// startServer listens for websocket connections\n",
+		"docs/guide.md":       "# Guide\n\nDeploy with flyctl.\n",
+		"debug.log":           "websocket debug output\n",
+		"logo.png":            "\x89PNG\r\n\x1a\n\x00\x00",
+		"large.txt":           strings.Repeat("websocket ", 200),
+		"node_modules/x/x.js": "websocket\n",
+	})
+	kb := newTestKB(t, [2]string{"notes.md", "Unrelated websocket notes."})
+	ctx := context.Background()
+
+	result, err := kb.IngestDirectory(ctx, "proj1", root, nil, nil)
+	if err != nil {
+		t.Fatalf("IngestDirectory failed: %v", err)
+	}
+	if *result != (IngestResult{Added: 4, Skipped: 2}) {
+		t.Errorf("Unexpected first ingest %+v", *result)
+	}
+	want := ".gitignore,docs/guide.md,main.go,server/server.go"
+	if got := strings.Join(projectPaths(kb, "proj1"), ","); got != want {
+		t.Errorf("Expected documents %s, got %s", want, got)
+	}
+
+	// Re-ingest after modifying one file and deleting another
+	writeTree(t, root, map[string]string{"server/server.go": "package server\n\n// startServer listens for grpc connections\n"})
+	if err := os.Remove(filepath.Join(root, "docs", "guide.md")); err != nil {
+		t.Fatal(err)
+	}
+	before := kb.ListDocuments(ctx)
+	result, err = kb.IngestDirectory(ctx, "proj1", root, nil, nil)
+	if err != nil {
+		t.Fatalf("Re-ingest failed: %v", err)
+	}
+	if *result != (IngestResult{Updated: 1, Skipped: 4, Removed: 1}) {
+		t.Errorf("Unexpected re-ingest %+v", *result)
+	}
+	after := kb.ListDocuments(ctx)
+	if len(after) != len(before)-1 {
+		t.Errorf("Expected the deleted file's document removed, got %d documents from %d", len(after), len(before))
+	}
+
+	// The updated file keeps its ID and is searchable by its new content
+	results := kb.SearchProject(ctx, "proj1", "grpc", 5)
+	if len(results) != 1 || results[0].DocName != "server/server.go" {
+		t.Fatalf("Expected the updated file, got %+v", results)
+	}
+	doc, _ := kb.GetDocument(ctx, results[0].DocID)
+	if doc.UpdatedAt.IsZero() || !doc.UpdatedAt.After(doc.CreatedAt) {
+		t.Errorf("Expected the document updated in place, got %+v", doc)
+	}
+	for _, d := range before {
+		if d.SourcePath == "server/server.go" && d.ID != doc.ID {
+			t.Errorf("Expected ID %s kept, got %s", d.ID, doc.ID)
+		}
+	}
+
+	// An unchanged tree touches nothing
+	if result, _ = kb.IngestDirectory(ctx, "proj1", root, nil, nil); *result != (IngestResult{Skipped: 5}) {
+		t.Errorf("Expected everything skipped, got %+v", *result)
+	}
+}
+
+func TestIngestDirectoryGlobs(t *testing.T) {
+	root := t.TempDir()
+	writeTree(t, root, map[string]string{
+		"cmd/main.go":          "package main",
+		"internal/a/a.go":      "package a",
+		"internal/a/a_test.go": "package a",
+		"README.md":            "readme",
+	})
+	kb := newTestKB(t)
+
+	if _, err := kb.IngestDirectory(context.Background(), "proj1", root, []string{"**/*.go"}, []string{"**/*_test.go"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(projectPaths(kb, "proj1"), ","); got != "cmd/main.go,internal/a/a.go" {
+		t.Errorf("Unexpected documents %s", got)
+	}
+
+	if _, err := kb.IngestDirectory(context.Background(), "proj1", root, []string{"[bad"}, nil); err == nil {
+		t.Error("Expected an invalid glob to be rejected")
+	}
+	if _, err := kb.IngestDirectory(context.Background(), "proj1", filepath.Join(root, "missing"), nil, nil); err == nil {
+		t.Error("Expected a missing root to be rejected")
+	}
+}
+
+func TestSearchProjectFilter(t *testing.T) {
+	rootA, rootB := t.TempDir(), t.TempDir()
+	writeTree(t, rootA, map[string]string{"a.md": "The deploy script uses flyctl."})
+	writeTree(t, rootB, map[string]string{"b.md": "Deploy with kubectl apply."})
+	kb := newTestKB(t, [2]string{"notes.md", "Remember to deploy on Fridays."}, [2]string{"other.md", "Unrelated."})
+	ctx := context.Background()
+	for id, root := range map[string]string{"a": rootA, "b": rootB} {
+		if _, err := kb.IngestDirectory(ctx, id, root, nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := len(kb.Search(ctx, "deploy", 10)); n != 3 {
+		t.Errorf("Expected every document without a filter, got %d", n)
+	}
+	results := kb.SearchProject(ctx, "b", "deploy", 10)
+	if len(results) != 1 || results[0].DocName != "b.md" {
+		t.Errorf("Expected only project b, got %+v", results)
+	}
+
+	// Ingesting one project leaves the other's documents alone
+	if err := os.Remove(filepath.Join(rootA, "a.md")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := kb.IngestDirectory(ctx, "a", rootA, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(projectPaths(kb, "a")) != 0 || len(projectPaths(kb, "b")) != 1 {
+		t.Errorf("Expected only project a emptied, got a=%v b=%v", projectPaths(kb, "a"), projectPaths(kb, "b"))
+	}
+}
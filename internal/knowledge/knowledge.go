@@ -3,6 +3,7 @@ package knowledge
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"os"
@@ -12,15 +13,23 @@ import (
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
+
+	"groq-go/internal/ids"
 )
 
 // Document represents a document in the knowledge base
 type Document struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Content   string    `json:"content"`
-	Chunks    []Chunk   `json:"chunks"`
-	CreatedAt time.Time `json:"created_at"`
+	ID          string    `json:"id"`
+	Name        string    `json:"name"`
+	ContentType string    `json:"content_type,omitempty"` // of the original upload
+	Content     string    `json:"content"`
+	Chunks      []Chunk   `json:"chunks"`
+	ProjectID   string    `json:"project_id,omitempty"`   // Set on documents ingested from a project
+	SourcePath  string    `json:"source_path,omitempty"`  // Slash-separated, relative to the project root
+	ContentHash string    `json:"content_hash,omitempty"` // SHA-256 of the ingested file
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at,omitempty"`
 }
 
 // Chunk represents a text chunk from a document
@@ -34,32 +43,82 @@ type Chunk struct {
 // SearchResult represents a search result
 type SearchResult struct {
 	Chunk   Chunk   `json:"chunk"`
+	DocID   string  `json:"doc_id"`
 	DocName string  `json:"doc_name"`
 	Score   float64 `json:"score"`
 }
 
+// ErrNotFound is returned for unknown document IDs
+var ErrNotFound = errors.New("document not found")
+
+// Chunking defaults, in bytes
+const (
+	DefaultChunkSize    = 500
+	DefaultChunkOverlap = 50
+)
+
 // KnowledgeBase manages documents and search
 type KnowledgeBase struct {
-	dir       string
-	documents map[string]*Document
-	mu        sync.RWMutex
+	dir          string
+	documents    map[string]*Document
+	index        *searchIndex
+	chunkSize    int
+	chunkOverlap int
+	mu           sync.RWMutex
 }
 
-// NewKnowledgeBase creates a new knowledge base
-func NewKnowledgeBase(dir string) (*KnowledgeBase, error) {
+// Option configures a KnowledgeBase
+type Option func(*KnowledgeBase)
+
+// WithChunkSize sets the target chunk length in bytes. Paragraphs up to
+// this size are kept whole; longer ones are split between sentences.
+func WithChunkSize(n int) Option {
+	return func(kb *KnowledgeBase) {
+		if n > 0 {
+			kb.chunkSize = n
+		}
+	}
+}
+
+// WithChunkOverlap sets how many bytes from the end of a chunk are repeated
+// at the start of the next one when a paragraph is split, so text spanning
+// the boundary stays findable. Zero disables overlap.
+func WithChunkOverlap(n int) Option {
+	return func(kb *KnowledgeBase) {
+		if n >= 0 {
+			kb.chunkOverlap = n
+		}
+	}
+}
+
+// NewKnowledgeBase creates a new knowledge base. Chunking options apply to
+// documents added or updated from now on.
+func NewKnowledgeBase(dir string, opts ...Option) (*KnowledgeBase, error) {
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		return nil, err
 	}
 
 	kb := &KnowledgeBase{
-		dir:       dir,
-		documents: make(map[string]*Document),
+		dir:          dir,
+		documents:    make(map[string]*Document),
+		chunkSize:    DefaultChunkSize,
+		chunkOverlap: DefaultChunkOverlap,
+	}
+	for _, opt := range opts {
+		opt(kb)
+	}
+	// An overlap as large as a chunk would never make progress
+	if kb.chunkOverlap >= kb.chunkSize {
+		kb.chunkOverlap = kb.chunkSize / 2
 	}
 
 	// Load existing documents
 	if err := kb.loadDocuments(); err != nil {
 		return nil, err
 	}
+	if err := kb.loadIndex(); err != nil {
+		return nil, fmt.Errorf("failed to load search index: %w", err)
+	}
 
 	return kb, nil
 }
@@ -73,16 +132,36 @@ func DefaultKnowledgeDir() string {
 	return filepath.Join(home, ".config", "groq-go", "knowledge")
 }
 
-// AddDocument adds a document to the knowledge base
+// AddDocument adds a text document to the knowledge base
 func (kb *KnowledgeBase) AddDocument(ctx context.Context, name, content string) (*Document, error) {
+	return kb.addDocument(name, ContentTypeText, content)
+}
+
+// AddFile extracts the text of an uploaded text, PDF or DOCX file and adds
+// it to the knowledge base. It returns ErrUnsupported or ErrEncrypted for
+// files it cannot read.
+func (kb *KnowledgeBase) AddFile(ctx context.Context, filename string, data []byte) (*Document, error) {
+	content, err := ExtractText(filename, data)
+	if err != nil {
+		return nil, err
+	}
+	return kb.addDocument(filename, DetectContentType(filename, data), content)
+}
+
+func (kb *KnowledgeBase) addDocument(name, contentType, content string) (*Document, error) {
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
+	id, err := kb.uniqueIDLocked()
+	if err != nil {
+		return nil, err
+	}
 	doc := &Document{
-		ID:        generateID(),
-		Name:      name,
-		Content:   content,
-		CreatedAt: time.Now(),
+		ID:          id,
+		Name:        name,
+		ContentType: contentType,
+		Content:     content,
+		CreatedAt:   time.Now(),
 	}
 
 	// Split content into chunks
@@ -94,10 +173,53 @@ func (kb *KnowledgeBase) AddDocument(ctx context.Context, name, content string)
 	if err := kb.saveDocument(doc); err != nil {
 		return nil, err
 	}
+	kb.index.add(doc)
+	if err := kb.saveIndex(); err != nil {
+		return nil, err
+	}
 
 	return doc, nil
 }
 
+// UpdateDocument replaces the name and content of a document and re-chunks
+// it, keeping its ID, content type, project and creation time. An empty
+// name keeps the current one. An edited project file is re-read on the
+// next ingestion.
+func (kb *KnowledgeBase) UpdateDocument(ctx context.Context, id, name, content string) (*Document, error) {
+	kb.mu.Lock()
+	defer kb.mu.Unlock()
+
+	old, ok := kb.documents[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if name == "" {
+		name = old.Name
+	}
+	doc := &Document{
+		ID:          id,
+		Name:        name,
+		ContentType: old.ContentType,
+		Content:     content,
+		ProjectID:   old.ProjectID,
+		SourcePath:  old.SourcePath,
+		CreatedAt:   old.CreatedAt,
+		UpdatedAt:   time.Now(),
+	}
+	doc.Chunks = kb.chunkText(id, content)
+
+	if err := kb.saveDocument(doc); err != nil {
+		return nil, err
+	}
+	kb.index.remove(old)
+	kb.index.add(doc)
+	kb.documents[id] = doc
+	if err := kb.saveIndex(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
 // GetDocument retrieves a document by ID
 func (kb *KnowledgeBase) GetDocument(ctx context.Context, id string) (*Document, error) {
 	kb.mu.RLock()
@@ -105,7 +227,7 @@ func (kb *KnowledgeBase) GetDocument(ctx context.Context, id string) (*Document,
 
 	doc, ok := kb.documents[id]
 	if !ok {
-		return nil, fmt.Errorf("document not found: %s", id)
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 
 	return doc, nil
@@ -119,9 +241,13 @@ func (kb *KnowledgeBase) ListDocuments(ctx context.Context) []Document {
 	docs := make([]Document, 0, len(kb.documents))
 	for _, doc := range kb.documents {
 		docs = append(docs, Document{
-			ID:        doc.ID,
-			Name:      doc.Name,
-			CreatedAt: doc.CreatedAt,
+			ID:          doc.ID,
+			Name:        doc.Name,
+			ContentType: doc.ContentType,
+			ProjectID:   doc.ProjectID,
+			SourcePath:  doc.SourcePath,
+			CreatedAt:   doc.CreatedAt,
+			UpdatedAt:   doc.UpdatedAt,
 		})
 	}
 
@@ -137,18 +263,29 @@ func (kb *KnowledgeBase) DeleteDocument(ctx context.Context, id string) error {
 	kb.mu.Lock()
 	defer kb.mu.Unlock()
 
-	if _, ok := kb.documents[id]; !ok {
-		return fmt.Errorf("document not found: %s", id)
+	doc, ok := kb.documents[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrNotFound, id)
 	}
 
 	delete(kb.documents, id)
+	kb.index.remove(doc)
 
 	// Remove from disk
-	return os.Remove(filepath.Join(kb.dir, id+".json"))
+	if err := os.Remove(filepath.Join(kb.dir, id+".json")); err != nil {
+		return err
+	}
+	return kb.saveIndex()
 }
 
 // Search performs semantic search using BM25-like scoring
 func (kb *KnowledgeBase) Search(ctx context.Context, query string, maxResults int) []SearchResult {
+	return kb.SearchProject(ctx, "", query, maxResults)
+}
+
+// SearchProject is Search limited to the documents of one project. An
+// empty projectID searches every document.
+func (kb *KnowledgeBase) SearchProject(ctx context.Context, projectID, query string, maxResults int) []SearchResult {
 	kb.mu.RLock()
 	defer kb.mu.RUnlock()
 
@@ -161,46 +298,54 @@ func (kb *KnowledgeBase) Search(ctx context.Context, query string, maxResults in
 		return nil
 	}
 
-	// Calculate IDF for query terms
+	// Look up the chunks containing each query term
+	totalDocs := len(kb.index.Lengths)
 	idf := make(map[string]float64)
-	totalDocs := 0
-	for _, doc := range kb.documents {
-		totalDocs += len(doc.Chunks)
-	}
-
+	matches := make(map[string]map[string]int)
+	candidates := make(map[string]bool)
 	for _, term := range queryTerms {
-		docCount := 0
-		for _, doc := range kb.documents {
-			for _, chunk := range doc.Chunks {
-				if strings.Contains(strings.ToLower(chunk.Text), term) {
-					docCount++
-					break
-				}
+		if _, ok := matches[term]; ok {
+			continue
+		}
+		matches[term] = kb.index.match(term)
+
+		// IDF counts the documents containing the term
+		docs := make(map[string]bool)
+		for id := range matches[term] {
+			candidates[id] = true
+			if ref, ok := kb.index.chunks[id]; ok {
+				docs[ref.doc.ID] = true
 			}
 		}
-		if docCount > 0 {
+		if docCount := len(docs); docCount > 0 {
 			idf[term] = math.Log(float64(totalDocs+1) / float64(docCount+1))
 		}
 	}
 
-	// Score each chunk
+	// Score only the chunks that matched
 	var results []SearchResult
-	for _, doc := range kb.documents {
-		for _, chunk := range doc.Chunks {
-			score := kb.scoreChunk(chunk.Text, queryTerms, idf)
-			if score > 0 {
-				results = append(results, SearchResult{
-					Chunk:   chunk,
-					DocName: doc.Name,
-					Score:   score,
-				})
-			}
+	for id := range candidates {
+		ref, ok := kb.index.chunks[id]
+		if !ok || (projectID != "" && ref.doc.ProjectID != projectID) {
+			continue
+		}
+		score := kb.scoreChunk(id, queryTerms, matches, idf)
+		if score > 0 {
+			results = append(results, SearchResult{
+				Chunk:   ref.doc.Chunks[ref.pos],
+				DocID:   ref.doc.ID,
+				DocName: ref.doc.Name,
+				Score:   score,
+			})
 		}
 	}
 
 	// Sort by score
 	sort.Slice(results, func(i, j int) bool {
-		return results[i].Score > results[j].Score
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].Chunk.ID < results[j].Chunk.ID
 	})
 
 	// Limit results
@@ -211,94 +356,145 @@ func (kb *KnowledgeBase) Search(ctx context.Context, query string, maxResults in
 	return results
 }
 
-func (kb *KnowledgeBase) scoreChunk(text string, queryTerms []string, idf map[string]float64) float64 {
-	textLower := strings.ToLower(text)
-	textTerms := tokenize(text)
-	termFreq := make(map[string]int)
-	for _, t := range textTerms {
-		termFreq[t]++
-	}
-
+func (kb *KnowledgeBase) scoreChunk(chunkID string, queryTerms []string, matches map[string]map[string]int, idf map[string]float64) float64 {
 	// BM25 parameters
 	k1 := 1.2
 	b := 0.75
 	avgDl := 100.0 // Average document length assumption
-	dl := float64(len(textTerms))
+	dl := float64(kb.index.Lengths[chunkID])
 
 	score := 0.0
 	for _, term := range queryTerms {
-		tf := float64(termFreq[term])
-		if tf > 0 || strings.Contains(textLower, term) {
-			if tf == 0 {
-				tf = 1
-			}
-			idfScore := idf[term]
-			tfScore := (tf * (k1 + 1)) / (tf + k1*(1-b+b*dl/avgDl))
-			score += idfScore * tfScore
+		// Substring matches count once, which also lets a single CJK
+		// character match the bigrams it appears in
+		n, ok := matches[term][chunkID]
+		if !ok {
+			continue
 		}
+		tf := float64(n)
+		if tf == 0 {
+			tf = 1
+		}
+		idfScore := idf[term]
+		tfScore := (tf * (k1 + 1)) / (tf + k1*(1-b+b*dl/avgDl))
+		score += idfScore * tfScore
 	}
 
 	return score
 }
 
+// chunkText splits text into chunks: a paragraph per chunk, with long
+// paragraphs split between sentences and overlapping by chunkOverlap
 func (kb *KnowledgeBase) chunkText(docID, text string) []Chunk {
-	// Split by paragraphs, then by sentences if paragraph is too long
-	paragraphs := strings.Split(text, "\n\n")
-	var chunks []Chunk
-	position := 0
-
-	for _, para := range paragraphs {
+	var texts []string
+	for _, para := range strings.Split(text, "\n\n") {
 		para = strings.TrimSpace(para)
 		if para == "" {
 			continue
 		}
-
-		// If paragraph is short enough, use as one chunk
-		if len(para) <= 500 {
-			chunks = append(chunks, Chunk{
-				ID:       fmt.Sprintf("%s-%d", docID, position),
-				DocID:    docID,
-				Text:     para,
-				Position: position,
-			})
-			position++
+		if len(para) <= kb.chunkSize {
+			texts = append(texts, para)
 			continue
 		}
+		texts = append(texts, kb.splitParagraph(para)...)
+	}
 
-		// Split long paragraphs by sentences
-		sentences := splitSentences(para)
-		currentChunk := ""
-		for _, sentence := range sentences {
-			if len(currentChunk)+len(sentence) > 500 && currentChunk != "" {
-				chunks = append(chunks, Chunk{
-					ID:       fmt.Sprintf("%s-%d", docID, position),
-					DocID:    docID,
-					Text:     strings.TrimSpace(currentChunk),
-					Position: position,
-				})
-				position++
-				currentChunk = sentence
-			} else {
-				if currentChunk != "" {
-					currentChunk += " "
-				}
-				currentChunk += sentence
-			}
+	chunks := make([]Chunk, len(texts))
+	for i, t := range texts {
+		chunks[i] = Chunk{
+			ID:       fmt.Sprintf("%s-%d", docID, i),
+			DocID:    docID,
+			Text:     t,
+			Position: i,
 		}
-		if currentChunk != "" {
-			chunks = append(chunks, Chunk{
-				ID:       fmt.Sprintf("%s-%d", docID, position),
-				DocID:    docID,
-				Text:     strings.TrimSpace(currentChunk),
-				Position: position,
-			})
-			position++
+	}
+	return chunks
+}
+
+// splitParagraph packs the sentences of a long paragraph into chunks of
+// about chunkSize. Each chunk after the first starts with the end of the
+// previous one. Sentences longer than a chunk are split between words.
+func (kb *KnowledgeBase) splitParagraph(para string) []string {
+	var pieces []string
+	for _, sentence := range splitSentences(para) {
+		if sentence = strings.TrimSpace(sentence); sentence != "" {
+			pieces = append(pieces, splitWords(sentence, kb.chunkSize)...)
 		}
 	}
 
+	var chunks []string
+	current := ""
+	for _, piece := range pieces {
+		if current != "" && len(current)+1+len(piece) > kb.chunkSize {
+			chunks = append(chunks, current)
+			current = overlapTail(current, kb.chunkOverlap)
+		}
+		if current != "" {
+			current += " "
+		}
+		current += piece
+	}
+	if current != "" {
+		chunks = append(chunks, current)
+	}
 	return chunks
 }
 
+// splitWords splits text longer than size between words, or between
+// characters when a word alone is too long
+func splitWords(text string, size int) []string {
+	if len(text) <= size {
+		return []string{text}
+	}
+	var parts []string
+	current := ""
+	for _, word := range strings.Fields(text) {
+		for len(word) > size {
+			cut := size
+			for cut > 0 && !utf8.RuneStart(word[cut]) {
+				cut--
+			}
+			if cut == 0 {
+				_, cut = utf8.DecodeRuneInString(word)
+			}
+			if current != "" {
+				parts = append(parts, current)
+				current = ""
+			}
+			parts = append(parts, word[:cut])
+			word = word[cut:]
+		}
+		if current != "" && len(current)+1+len(word) > size {
+			parts = append(parts, current)
+			current = ""
+		}
+		if current != "" {
+			current += " "
+		}
+		current += word
+	}
+	if current != "" {
+		parts = append(parts, current)
+	}
+	return parts
+}
+
+// overlapTail returns at most n bytes from the end of s, starting at a word
+// boundary when there is one
+func overlapTail(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return ""
+	}
+	tail := s[len(s)-n:]
+	if i := strings.IndexByte(tail, ' '); i >= 0 {
+		return tail[i+1:]
+	}
+	for len(tail) > 0 && !utf8.RuneStart(tail[0]) {
+		tail = tail[1:]
+	}
+	return tail
+}
+
 func (kb *KnowledgeBase) saveDocument(doc *Document) error {
 	data, err := json.MarshalIndent(doc, "", "  ")
 	if err != nil {
@@ -317,7 +513,7 @@ func (kb *KnowledgeBase) loadDocuments() error {
 	}
 
 	for _, entry := range entries {
-		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" || entry.Name() == IndexFile {
 			continue
 		}
 
@@ -337,37 +533,57 @@ func (kb *KnowledgeBase) loadDocuments() error {
 	return nil
 }
 
-func generateID() string {
-	const charset = "abcdefghijklmnopqrstuvwxyz0123456789"
-	b := make([]byte, 12)
-	for i := range b {
-		b[i] = charset[time.Now().UnixNano()%int64(len(charset))]
-		time.Sleep(time.Nanosecond)
+// newID generates document IDs; replaced by tests to force collisions
+var newID = func() string { return ids.Lower(12) }
+
+// maxIDAttempts bounds the retries when a new ID is already taken
+const maxIDAttempts = 5
+
+// uniqueIDLocked returns an ID used by no loaded document and no file on
+// disk. The caller holds kb.mu.
+func (kb *KnowledgeBase) uniqueIDLocked() (string, error) {
+	for range maxIDAttempts {
+		id := newID()
+		if _, taken := kb.documents[id]; taken {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(kb.dir, id+".json")); err == nil {
+			continue
+		}
+		return id, nil
 	}
-	return string(b)
+	return "", fmt.Errorf("failed to generate a unique document ID after %d attempts", maxIDAttempts)
 }
 
-var wordRegex = regexp.MustCompile(`[a-zA-Z0-9]+`)
+// wordRegex matches ASCII words and runs of CJK characters
+var wordRegex = regexp.MustCompile(`[a-zA-Z0-9]+|[\p{Han}\p{Hiragana}\p{Katakana}\p{Hangul}ー々]+`)
+
+// stopwords are English words dropped from the index; CJK tokens are never filtered
+var stopwords = map[string]bool{
+	"the": true, "a": true, "an": true, "and": true, "or": true,
+	"but": true, "in": true, "on": true, "at": true, "to": true,
+	"for": true, "of": true, "with": true, "by": true, "from": true,
+	"is": true, "are": true, "was": true, "were": true, "be": true,
+	"been": true, "being": true, "have": true, "has": true, "had": true,
+	"do": true, "does": true, "did": true, "will": true, "would": true,
+	"could": true, "should": true, "may": true, "might": true,
+	"this": true, "that": true, "these": true, "those": true,
+	"it": true, "its": true, "i": true, "me": true, "my": true,
+}
 
+// tokenize splits text into lowercase ASCII words and CJK character bigrams.
+// CJK text has no word boundaries, so each run is indexed as overlapping
+// pairs ("東京都" -> "東京", "京都"); a lone character is kept as is.
 func tokenize(text string) []string {
 	text = strings.ToLower(text)
 	matches := wordRegex.FindAllString(text, -1)
 
-	// Remove stopwords
-	stopwords := map[string]bool{
-		"the": true, "a": true, "an": true, "and": true, "or": true,
-		"but": true, "in": true, "on": true, "at": true, "to": true,
-		"for": true, "of": true, "with": true, "by": true, "from": true,
-		"is": true, "are": true, "was": true, "were": true, "be": true,
-		"been": true, "being": true, "have": true, "has": true, "had": true,
-		"do": true, "does": true, "did": true, "will": true, "would": true,
-		"could": true, "should": true, "may": true, "might": true,
-		"this": true, "that": true, "these": true, "those": true,
-		"it": true, "its": true, "i": true, "me": true, "my": true,
-	}
-
 	var tokens []string
 	for _, match := range matches {
+		if match[0] >= utf8.RuneSelf {
+			tokens = append(tokens, cjkBigrams(match)...)
+			continue
+		}
 		if !stopwords[match] && len(match) > 1 {
 			tokens = append(tokens, match)
 		}
@@ -375,7 +591,20 @@ func tokenize(text string) []string {
 	return tokens
 }
 
-var sentenceRegex = regexp.MustCompile(`[.!?]+\s+`)
+// cjkBigrams returns the overlapping character pairs of a CJK run
+func cjkBigrams(run string) []string {
+	runes := []rune(run)
+	if len(runes) == 1 {
+		return []string{run}
+	}
+	bigrams := make([]string, 0, len(runes)-1)
+	for i := 0; i+1 < len(runes); i++ {
+		bigrams = append(bigrams, string(runes[i:i+2]))
+	}
+	return bigrams
+}
+
+var sentenceRegex = regexp.MustCompile(`[.!?]+\s+|[。！？]+`)
 
 func splitSentences(text string) []string {
 	indices := sentenceRegex.FindAllStringIndex(text, -1)
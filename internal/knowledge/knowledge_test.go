@@ -0,0 +1,202 @@
+package knowledge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestAddDocumentConcurrentIDsAreUnique(t *testing.T) {
+	kb := newTestKB(t)
+	const n = 1000
+
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := kb.AddDocument(context.Background(), fmt.Sprintf("doc%d.md", i), fmt.Sprintf("document number %d", i)); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("AddDocument failed: %v", err)
+	}
+
+	docs := kb.ListDocuments(context.Background())
+	if len(docs) != n {
+		t.Errorf("Expected %d documents, got %d", n, len(docs))
+	}
+	entries, err := os.ReadDir(kb.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := 0
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".json") && e.Name() != IndexFile {
+			files++
+		}
+	}
+	if files != n {
+		t.Errorf("Expected %d files on disk, got %d", n, files)
+	}
+
+	reloaded, err := NewKnowledgeBase(kb.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if docs := reloaded.ListDocuments(context.Background()); len(docs) != n {
+		t.Errorf("Expected %d documents after reload, got %d", n, len(docs))
+	}
+}
+
+func TestAddDocumentRetriesTakenIDs(t *testing.T) {
+	kb := newTestKB(t)
+	orig := newID
+	t.Cleanup(func() { newID = orig })
+
+	queue := []string{"aaaaaaaaaaaa", "aaaaaaaaaaaa", "bbbbbbbbbbbb"}
+	newID = func() string {
+		id := queue[0]
+		queue = queue[1:]
+		return id
+	}
+	first, err := kb.AddDocument(context.Background(), "first.md", "first")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := kb.AddDocument(context.Background(), "second.md", "second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID != "aaaaaaaaaaaa" || second.ID != "bbbbbbbbbbbb" {
+		t.Errorf("Expected the duplicate ID to be skipped, got %s and %s", first.ID, second.ID)
+	}
+	if doc, _ := kb.GetDocument(context.Background(), first.ID); doc.Content != "first" {
+		t.Error("Expected the first document to survive")
+	}
+
+	newID = func() string { return "aaaaaaaaaaaa" }
+	if _, err := kb.AddDocument(context.Background(), "third.md", "third"); err == nil {
+		t.Error("Expected an error once every attempt collides")
+	}
+}
+
+func TestUpdateDocumentInPlace(t *testing.T) {
+	kb := newTestKB(t, [2]string{"other.md", "Unrelated notes about lunch."})
+	ctx := context.Background()
+	doc, err := kb.AddDocument(ctx, "deploy.md", "Deploys run on Fly.\n\nRollbacks use the previous image.\n\nAlerts page the on-call engineer.")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := kb.UpdateDocument(ctx, doc.ID, "", "Deploys run on Kubernetes.")
+	if err != nil {
+		t.Fatalf("UpdateDocument failed: %v", err)
+	}
+	if updated.ID != doc.ID || updated.Name != "deploy.md" || !updated.CreatedAt.Equal(doc.CreatedAt) || updated.UpdatedAt.IsZero() {
+		t.Errorf("Expected ID, name and creation time kept with UpdatedAt set, got %+v", updated)
+	}
+	if len(updated.Chunks) != 1 || updated.Chunks[0].ID != doc.ID+"-0" {
+		t.Errorf("Expected one re-chunked chunk, got %+v", updated.Chunks)
+	}
+
+	// Stale chunks are gone from search, including ones past the new end
+	for _, stale := range []string{"Fly", "rollbacks", "on-call"} {
+		if results := kb.Search(ctx, stale, 5); len(results) != 0 {
+			t.Errorf("Expected no results for stale %q, got %+v", stale, results)
+		}
+	}
+	results := kb.Search(ctx, "kubernetes", 5)
+	if len(results) != 1 || results[0].DocID != doc.ID || results[0].Chunk.Text != "Deploys run on Kubernetes." {
+		t.Errorf("Expected the updated chunk with its document ID, got %+v", results)
+	}
+
+	// The update survives a restart, and the index matches it
+	reloaded, err := NewKnowledgeBase(kb.dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := reloaded.GetDocument(ctx, doc.ID); got.Content != "Deploys run on Kubernetes." || got.UpdatedAt.IsZero() {
+		t.Errorf("Expected the update on disk, got %+v", got)
+	}
+	if results := reloaded.Search(ctx, "rollbacks", 5); len(results) != 0 {
+		t.Errorf("Expected no stale results after reload, got %+v", results)
+	}
+
+	if renamed, _ := kb.UpdateDocument(ctx, doc.ID, "deploy-v2.md", "Deploys run on Nomad."); renamed.Name != "deploy-v2.md" {
+		t.Errorf("Expected the new name, got %q", renamed.Name)
+	}
+	if _, err := kb.UpdateDocument(ctx, "missing", "", "x"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestChunkOverlap(t *testing.T) {
+	kb, err := NewKnowledgeBase(t.TempDir(), WithChunkSize(80), WithChunkOverlap(30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	para := "The deploy script builds the image first. It then pushes the image to the registry. " +
+		"Finally the release command migrates the database schema. Health checks gate the traffic switch."
+	doc, err := kb.AddDocument(ctx, "deploy.md", para)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(doc.Chunks) < 3 {
+		t.Fatalf("Expected the paragraph split into several chunks, got %d", len(doc.Chunks))
+	}
+	for i, c := range doc.Chunks {
+		if len(c.Text) > 80+30 {
+			t.Errorf("Chunk %d is %d bytes, over size plus overlap", i, len(c.Text))
+		}
+		if i == 0 {
+			continue
+		}
+		prev := doc.Chunks[i-1].Text
+		words := strings.Fields(c.Text)
+		if !strings.HasSuffix(prev, words[0]) && !strings.Contains(prev, words[0]+" "+words[1]) {
+			t.Errorf("Expected chunk %d to start with the end of chunk %d:\n%q\n%q", i, i-1, prev, c.Text)
+		}
+	}
+
+	// A phrase across the first boundary is whole in the second chunk
+	if !strings.Contains(doc.Chunks[1].Text, "image first. It then pushes") {
+		t.Errorf("Expected the boundary to be covered, got %q", doc.Chunks[1].Text)
+	}
+
+	// Without overlap the chunks just partition the text
+	plain, _ := NewKnowledgeBase(t.TempDir(), WithChunkSize(80), WithChunkOverlap(0))
+	doc, _ = plain.AddDocument(ctx, "deploy.md", para)
+	var joined []string
+	for _, c := range doc.Chunks {
+		joined = append(joined, c.Text)
+	}
+	if strings.Join(joined, " ") != para {
+		t.Errorf("Expected chunks to partition the paragraph, got %q", joined)
+	}
+}
+
+func TestChunkLongSentence(t *testing.T) {
+	kb, _ := NewKnowledgeBase(t.TempDir(), WithChunkSize(20), WithChunkOverlap(0))
+	long := strings.Repeat("word ", 10) + strings.Repeat("あ", 30)
+	for _, c := range kb.chunkText("d", long+"\n\n"+long) {
+		if len(c.Text) > 20 {
+			t.Errorf("Chunk %q is over the size", c.Text)
+		}
+		if !utf8.ValidString(c.Text) {
+			t.Errorf("Chunk %q splits a character", c.Text)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package knowledge
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func newTestKB(t *testing.T, docs ...[2]string) *KnowledgeBase {
+	t.Helper()
+	kb, err := NewKnowledgeBase(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewKnowledgeBase failed: %v", err)
+	}
+	for _, d := range docs {
+		if _, err := kb.AddDocument(context.Background(), d[0], d[1]); err != nil {
+			t.Fatalf("AddDocument failed: %v", err)
+		}
+	}
+	return kb
+}
+
+// englishCorpus backs the snapshot tests guarding English scoring
+var englishCorpus = [][2]string{
+	{"go.md", "Go is a statically typed language.\n\nGoroutines are lightweight threads managed by the Go runtime.\n\nChannels connect concurrent goroutines."},
+	{"http.md", "The HTTP server handles requests.\n\nMiddleware wraps handlers to add logging and authentication to every request."},
+	{"db.md", "SQLite stores data in a single file.\n\nTransactions keep concurrent writes consistent."},
+}
+
+func TestTokenizeEnglishSnapshot(t *testing.T) {
+	got := tokenize("The Go runtime schedules goroutines, and it is FAST: 10x in v1.2!")
+	want := []string{"go", "runtime", "schedules", "goroutines", "fast", "10x", "v1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %q, want %q", got, want)
+	}
+}
+
+func TestSearchEnglishSnapshot(t *testing.T) {
+	kb := newTestKB(t, englishCorpus...)
+	tests := []struct {
+		query string
+		want  []string // "doc:text prefix:score"
+	}{
+		{"concurrent goroutines", []string{
+			"go.md:Channels connect:3.8980",
+			"go.md:Goroutines are:2.2525",
+			"db.md:Transactions keep:1.6043",
+		}},
+		{"http request logging", []string{
+			"http.md:The HTTP:4.5656",
+			"http.md:Middleware wraps:4.4458",
+		}},
+		{"single file", []string{
+			"db.md:SQLite stores:4.5351",
+		}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, r := range kb.Search(context.Background(), tt.query, 5) {
+			prefix := strings.Join(strings.Fields(r.Chunk.Text)[:2], " ")
+			got = append(got, fmt.Sprintf("%s:%s:%.4f", r.DocName, prefix, r.Score))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeCJK(t *testing.T) {
+	got := tokenize("東京都の API サーバー")
+	want := []string{"東京", "京都", "都の", "api", "サー", "ーバ", "バー"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize = %q, want %q", got, want)
+	}
+	if got := tokenize("猫"); !reflect.DeepEqual(got, []string{"猫"}) {
+		t.Errorf("Expected a single character to be kept, got %q", got)
+	}
+}
+
+func TestSearchJapanese(t *testing.T) {
+	kb := newTestKB(t, append(englishCorpus,
+		[2]string{"guide.md", "本システムは認証機能を提供します。\n\n請求書は毎月末に発行されます。\n\nログは三十日間保存されます。"},
+	)...)
+
+	results := kb.Search(context.Background(), "請求", 3)
+	if len(results) == 0 || !strings.HasPrefix(results[0].Chunk.Text, "請求書は") {
+		t.Fatalf("Expected the billing chunk first, got %+v", results)
+	}
+	if len(results) != 1 {
+		t.Errorf("Expected only the billing chunk to match, got %+v", results)
+	}
+
+	if results := kb.Search(context.Background(), "認証", 3); len(results) == 0 || results[0].DocName != "guide.md" {
+		t.Errorf("Expected the authentication chunk, got %+v", results)
+	}
+}
@@ -3,6 +3,7 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -60,18 +61,21 @@ func (t *ToolAdapter) Execute(ctx context.Context, argsJSON json.RawMessage) (to
 	}
 
 	result, err := t.manager.CallTool(ctx, t.serverName, t.toolDef.Name, args)
+	if errors.Is(err, errServerNotRunning) {
+		return tool.NewErrorResult(fmt.Sprintf("tool %s is no longer available: MCP server %s was removed", t.Name(), t.serverName)), nil
+	}
 	if err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("MCP call failed: %v", err)), nil
 	}
 
-	// Extract text content from result
+	// Extract text content from result, with embedded resources
 	var content strings.Builder
 	for _, block := range result.Content {
-		if block.Type == "text" {
+		if block.Type == "text" || block.Type == "resource" {
 			if content.Len() > 0 {
 				content.WriteString("\n")
 			}
-			content.WriteString(block.Text)
+			content.WriteString(block.String())
 		}
 	}
 
@@ -82,21 +86,76 @@ func (t *ToolAdapter) Execute(ctx context.Context, argsJSON json.RawMessage) (to
 	return tool.NewResult(content.String()), nil
 }
 
-// RegisterMCPTools registers all MCP tools with the tool registry
+// RegisterMCPTools registers all MCP tools with the tool registry. The
+// registry is kept in sync as servers are added, removed or reloaded.
 func RegisterMCPTools(registry *tool.Registry, manager *Manager) int {
+	manager.mu.Lock()
+	manager.registry = registry
+	manager.mu.Unlock()
+
 	count := 0
-	allTools := manager.GetAllTools()
-
-	for serverName, tools := range allTools {
-		for _, toolDef := range tools {
-			adapter := NewToolAdapter(manager, serverName, toolDef)
-			if err := registry.Register(adapter); err != nil {
-				// Tool might already exist, skip it
-				continue
-			}
-			count++
+	for _, serverName := range manager.ServerNames() {
+		count += manager.registerTools(serverName)
+	}
+	return count
+}
+
+// registerTools registers a running server's tools with the attached
+// registry and returns how many were added
+func (m *Manager) registerTools(serverName string) int {
+	m.mu.RLock()
+	registry := m.registry
+	client, ok := m.clients[serverName]
+	_, done := m.registered[serverName]
+	m.mu.RUnlock()
+	if registry == nil || !ok || done {
+		// A server that came up during RegisterMCPTools is registered once
+		return 0
+	}
+
+	adapters := make([]tool.Tool, 0, len(client.Tools())+1)
+	for _, toolDef := range client.Tools() {
+		adapters = append(adapters, NewToolAdapter(m, serverName, toolDef))
+	}
+	if client.Capabilities().Resources != nil {
+		adapters = append(adapters, NewResourceAdapter(m, serverName))
+	}
+
+	var names []string
+	for _, adapter := range adapters {
+		if err := registry.Register(adapter); err != nil {
+			// Tool might already exist, skip it
+			continue
 		}
+		names = append(names, adapter.Name())
 	}
 
-	return count
+	m.mu.Lock()
+	if m.clients[serverName] != client {
+		// Stopped meanwhile, see stopServer
+		m.mu.Unlock()
+		for _, name := range names {
+			registry.Unregister(name)
+		}
+		return 0
+	}
+	m.registered[serverName] = names
+	m.mu.Unlock()
+	return len(names)
+}
+
+// unregisterTools removes a server's tools from the attached registry
+func (m *Manager) unregisterTools(serverName string) {
+	m.mu.Lock()
+	registry := m.registry
+	names := m.registered[serverName]
+	delete(m.registered, serverName)
+	m.mu.Unlock()
+
+	if registry == nil {
+		return
+	}
+	for _, name := range names {
+		registry.Unregister(name)
+	}
 }
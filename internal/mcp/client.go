@@ -4,26 +4,51 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os/exec"
 	"sync"
 	"sync/atomic"
+	"time"
+
+	"groq-go/internal/logging"
+)
+
+const (
+	// stderrBufferSize is how much of a server's stderr is kept for diagnostics
+	stderrBufferSize = 16 << 10
+	// closeGrace is how long a server may take to exit after stdin closes
+	closeGrace = 2 * time.Second
+	// maxPages bounds how many pages of a list are fetched
+	maxPages = 100
 )
 
-// Client represents an MCP client connected to a server
+// ErrClosed is returned by calls on a client whose server has exited
+var ErrClosed = errors.New("MCP server connection closed")
+
+// Client represents an MCP client connected to a server. A background
+// loop reads the server's output and hands each response to the call
+// waiting for its ID, so servers may reply out of order and interleave
+// notifications.
 type Client struct {
-	name    string
-	cmd     *exec.Cmd
-	stdin   io.WriteCloser
-	stdout  *bufio.Reader
-	stderr  io.ReadCloser
+	name   string
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stderr *ringBuffer
 
-	mu        sync.Mutex
+	writeMu   sync.Mutex
 	requestID atomic.Int32
-	tools     []ToolDef
 
-	serverInfo ServerInfo
+	mu      sync.Mutex
+	pending map[int]chan *JSONRPCResponse
+	tools   []ToolDef
+	// readErr is why the read loop stopped; done is closed when it does
+	readErr error
+	done    chan struct{}
+
+	serverInfo   ServerInfo
+	capabilities ServerCaps
 }
 
 // NewClient creates a new MCP client
@@ -52,13 +77,26 @@ func NewClient(name, command string, args []string, env []string) (*Client, erro
 		return nil, fmt.Errorf("failed to start MCP server: %w", err)
 	}
 
-	return &Client{
-		name:   name,
-		cmd:    cmd,
-		stdin:  stdin,
-		stdout: bufio.NewReader(stdout),
-		stderr: stderr,
-	}, nil
+	c := newClient(name, stdin, stdout, stderr)
+	c.cmd = cmd
+	return c, nil
+}
+
+// newClient starts the read loops for a server connected over the given
+// streams; stderr may be nil
+func newClient(name string, stdin io.WriteCloser, stdout, stderr io.Reader) *Client {
+	c := &Client{
+		name:    name,
+		stdin:   stdin,
+		stderr:  newRingBuffer(stderrBufferSize),
+		pending: make(map[int]chan *JSONRPCResponse),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop(bufio.NewReader(stdout))
+	if stderr != nil {
+		go io.Copy(c.stderr, stderr)
+	}
+	return c
 }
 
 // Name returns the client name
@@ -66,6 +104,11 @@ func (c *Client) Name() string {
 	return c.name
 }
 
+// Stderr returns the most recent output the server wrote to stderr
+func (c *Client) Stderr() string {
+	return c.stderr.String()
+}
+
 // Initialize performs the MCP initialization handshake
 func (c *Client) Initialize(ctx context.Context) error {
 	params := InitializeParams{
@@ -85,6 +128,7 @@ func (c *Client) Initialize(ctx context.Context) error {
 	}
 
 	c.serverInfo = result.ServerInfo
+	c.capabilities = result.Capabilities
 
 	// Send initialized notification
 	if err := c.notify("notifications/initialized", nil); err != nil {
@@ -101,10 +145,19 @@ func (c *Client) ListTools(ctx context.Context) ([]ToolDef, error) {
 		return nil, fmt.Errorf("tools/list failed: %w", err)
 	}
 
+	c.mu.Lock()
 	c.tools = result.Tools
+	c.mu.Unlock()
 	return result.Tools, nil
 }
 
+// Tools returns the tools from the last ListTools call
+func (c *Client) Tools() []ToolDef {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tools
+}
+
 // CallTool invokes a tool on the MCP server
 func (c *Client) CallTool(ctx context.Context, name string, args map[string]any) (*CallToolResult, error) {
 	params := CallToolParams{
@@ -120,10 +173,79 @@ func (c *Client) CallTool(ctx context.Context, name string, args map[string]any)
 	return &result, nil
 }
 
-// Close shuts down the MCP server
+// ListResources retrieves the resources the server publishes, following
+// pages until the last
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	var params PaginatedParams
+	for page := 0; page < maxPages; page++ {
+		var result ListResourcesResult
+		if err := c.call(ctx, "resources/list", params, &result); err != nil {
+			return nil, fmt.Errorf("resources/list failed: %w", err)
+		}
+		resources = append(resources, result.Resources...)
+		if result.NextCursor == "" {
+			break
+		}
+		params.Cursor = result.NextCursor
+	}
+	return resources, nil
+}
+
+// ReadResource reads the contents of the resource at uri
+func (c *Client) ReadResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	var result ReadResourceResult
+	if err := c.call(ctx, "resources/read", ReadResourceParams{URI: uri}, &result); err != nil {
+		return nil, fmt.Errorf("resources/read failed: %w", err)
+	}
+	return &result, nil
+}
+
+// ListPrompts retrieves the prompt templates the server offers, following
+// pages until the last
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var prompts []Prompt
+	var params PaginatedParams
+	for page := 0; page < maxPages; page++ {
+		var result ListPromptsResult
+		if err := c.call(ctx, "prompts/list", params, &result); err != nil {
+			return nil, fmt.Errorf("prompts/list failed: %w", err)
+		}
+		prompts = append(prompts, result.Prompts...)
+		if result.NextCursor == "" {
+			break
+		}
+		params.Cursor = result.NextCursor
+	}
+	return prompts, nil
+}
+
+// GetPrompt renders the prompt name with args
+func (c *Client) GetPrompt(ctx context.Context, name string, args map[string]string) (*GetPromptResult, error) {
+	var result GetPromptResult
+	if err := c.call(ctx, "prompts/get", GetPromptParams{Name: name, Arguments: args}, &result); err != nil {
+		return nil, fmt.Errorf("prompts/get failed: %w", err)
+	}
+	return &result, nil
+}
+
+// Close shuts down the MCP server, killing it if it does not exit soon
+// after its stdin is closed
 func (c *Client) Close() error {
 	c.stdin.Close()
-	return c.cmd.Wait()
+	if c.cmd == nil {
+		return nil
+	}
+
+	exited := make(chan error, 1)
+	go func() { exited <- c.cmd.Wait() }()
+	select {
+	case err := <-exited:
+		return err
+	case <-time.After(closeGrace):
+		c.cmd.Process.Kill()
+		return <-exited
+	}
 }
 
 // ServerInfo returns information about the connected server
@@ -131,11 +253,94 @@ func (c *Client) ServerInfo() ServerInfo {
 	return c.serverInfo
 }
 
-func (c *Client) call(ctx context.Context, method string, params any, result any) error {
+// Capabilities returns what the server declared during Initialize
+func (c *Client) Capabilities() ServerCaps {
+	return c.capabilities
+}
+
+// incomingMessage is any message a server sends: a response to one of
+// our calls, a notification, or a request of its own
+type incomingMessage struct {
+	ID     *int            `json:"id"`
+	Method string          `json:"method"`
+	Result json.RawMessage `json:"result"`
+	Error  *JSONRPCError   `json:"error"`
+}
+
+func (c *Client) readLoop(r *bufio.Reader) {
+	var err error
+	for {
+		var line []byte
+		line, err = r.ReadBytes('\n')
+		if len(line) > 0 {
+			c.dispatch(line)
+		}
+		if err != nil {
+			break
+		}
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+	if err == io.EOF {
+		err = ErrClosed
+	}
+	c.readErr = err
+	close(c.done)
+}
+
+// dispatch routes one line from the server
+func (c *Client) dispatch(line []byte) {
+	var msg incomingMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		logging.Debug("Ignoring malformed MCP message", "server", c.name, "error", err)
+		return
+	}
+
+	switch {
+	case msg.Method != "" && msg.ID == nil:
+		logging.Debug("MCP notification", "server", c.name, "method", msg.Method)
+	case msg.Method != "":
+		c.replyToServer(*msg.ID, msg.Method)
+	case msg.ID != nil:
+		c.mu.Lock()
+		ch, ok := c.pending[*msg.ID]
+		delete(c.pending, *msg.ID)
+		c.mu.Unlock()
+		if !ok {
+			logging.Debug("Ignoring MCP response with unknown id", "server", c.name, "id", *msg.ID)
+			return
+		}
+		ch <- &JSONRPCResponse{JSONRPC: "2.0", ID: *msg.ID, Result: msg.Result, Error: msg.Error}
+	}
+}
+
+// replyToServer answers requests the server makes of us. Only ping is
+// supported.
+func (c *Client) replyToServer(id int, method string) {
+	reply := map[string]any{"jsonrpc": "2.0", "id": id}
+	if method == "ping" {
+		reply["result"] = struct{}{}
+	} else {
+		reply["error"] = JSONRPCError{Code: -32601, Message: "method not found: " + method}
+	}
+	if err := c.write(reply); err != nil {
+		logging.Debug("Failed to reply to MCP server request", "server", c.name, "method", method, "error", err)
+	}
+}
 
+func (c *Client) call(ctx context.Context, method string, params any, result any) error {
 	id := int(c.requestID.Add(1))
+	ch := make(chan *JSONRPCResponse, 1)
+
+	c.mu.Lock()
+	if c.readErr != nil {
+		err := c.readErr
+		c.mu.Unlock()
+		return err
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
 
 	req := JSONRPCRequest{
 		JSONRPC: "2.0",
@@ -144,25 +349,29 @@ func (c *Client) call(ctx context.Context, method string, params any, result any
 		Params:  params,
 	}
 
-	// Send request
-	reqBytes, err := json.Marshal(req)
-	if err != nil {
-		return fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	if _, err := c.stdin.Write(append(reqBytes, '\n')); err != nil {
+	if err := c.write(req); err != nil {
+		c.forget(id)
 		return fmt.Errorf("failed to write request: %w", err)
 	}
 
-	// Read response
-	line, err := c.stdout.ReadBytes('\n')
-	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var resp JSONRPCResponse
-	if err := json.Unmarshal(line, &resp); err != nil {
-		return fmt.Errorf("failed to unmarshal response: %w", err)
+	var resp *JSONRPCResponse
+	select {
+	case resp = <-ch:
+	case <-ctx.Done():
+		c.forget(id)
+		c.notify("notifications/cancelled", map[string]any{"requestId": id, "reason": ctx.Err().Error()})
+		return ctx.Err()
+	case <-c.done:
+		// The response may have arrived just before the server exited
+		select {
+		case resp = <-ch:
+		default:
+			c.forget(id)
+			c.mu.Lock()
+			err := c.readErr
+			c.mu.Unlock()
+			return err
+		}
 	}
 
 	if resp.Error != nil {
@@ -178,10 +387,15 @@ func (c *Client) call(ctx context.Context, method string, params any, result any
 	return nil
 }
 
-func (c *Client) notify(method string, params any) error {
+// forget drops a call that is no longer waited on; a late response to it
+// is ignored
+func (c *Client) forget(id int) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
 
+func (c *Client) notify(method string, params any) error {
 	// Notifications don't have an ID
 	req := struct {
 		JSONRPC string `json:"jsonrpc"`
@@ -192,12 +406,45 @@ func (c *Client) notify(method string, params any) error {
 		Method:  method,
 		Params:  params,
 	}
+	return c.write(req)
+}
 
-	reqBytes, err := json.Marshal(req)
+// write sends one message; writes are serialized so lines never interleave
+func (c *Client) write(msg any) error {
+	data, err := json.Marshal(msg)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to marshal message: %w", err)
 	}
 
-	_, err = c.stdin.Write(append(reqBytes, '\n'))
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	_, err = c.stdin.Write(append(data, '\n'))
 	return err
 }
+
+// ringBuffer keeps the last size bytes written to it
+type ringBuffer struct {
+	mu   sync.Mutex
+	size int
+	buf  []byte
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	return &ringBuffer{size: size}
+}
+
+func (b *ringBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf = append(b.buf, p...)
+	if over := len(b.buf) - b.size; over > 0 {
+		b.buf = append(b.buf[:0], b.buf[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *ringBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return string(b.buf)
+}
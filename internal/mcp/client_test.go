@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer talks to a client over in-process pipes. Requests are read
+// continuously so the client never blocks writing.
+type fakeServer struct {
+	requests chan JSONRPCRequest
+	out      io.Writer
+	mu       sync.Mutex
+}
+
+func newFakeServer(in io.Reader, out io.Writer) *fakeServer {
+	s := &fakeServer{requests: make(chan JSONRPCRequest, 16), out: out}
+	go func() {
+		r := bufio.NewReader(in)
+		for {
+			line, err := r.ReadBytes('\n')
+			if err != nil {
+				close(s.requests)
+				return
+			}
+			var req JSONRPCRequest
+			json.Unmarshal(line, &req)
+			s.requests <- req
+		}
+	}()
+	return s
+}
+
+func (s *fakeServer) send(msg string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintln(s.out, msg)
+}
+
+func (s *fakeServer) next(t *testing.T) JSONRPCRequest {
+	t.Helper()
+	select {
+	case req := <-s.requests:
+		return req
+	case <-time.After(5 * time.Second):
+		t.Fatal("fake server got no request")
+		return JSONRPCRequest{}
+	}
+}
+
+func newFakeClient(t *testing.T) (*Client, *fakeServer, io.Writer) {
+	t.Helper()
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	c := newClient("fake", clientOut, clientIn, stderrR)
+	t.Cleanup(func() {
+		c.Close()
+		serverOut.Close()
+		stderrW.Close()
+	})
+	return c, newFakeServer(serverIn, serverOut), stderrW
+}
+
+func TestCallHandlesNotificationsAndOutOfOrderResponses(t *testing.T) {
+	c, srv, _ := newFakeClient(t)
+
+	type outcome struct {
+		name string
+		res  *CallToolResult
+		err  error
+	}
+	results := make(chan outcome, 2)
+	for _, name := range []string{"first", "second"} {
+		go func(name string) {
+			res, err := c.CallTool(context.Background(), name, nil)
+			results <- outcome{name, res, err}
+		}(name)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ids := map[string]int{}
+	for i := 0; i < 2; i++ {
+		req := srv.next(t)
+		params, _ := json.Marshal(req.Params)
+		var p CallToolParams
+		json.Unmarshal(params, &p)
+		ids[p.Name] = req.ID
+	}
+
+	// Reply in reverse order with notifications and a server request in between
+	srv.send(`{"jsonrpc":"2.0","method":"notifications/message","params":{"level":"info","data":"working"}}`)
+	srv.send(`{"jsonrpc":"2.0","id":99,"method":"ping"}`)
+	srv.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"content":[{"type":"text","text":"second done"}]}}`, ids["second"]))
+	srv.send(`not json at all`)
+	srv.send(fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"result":{"content":[{"type":"text","text":"first done"}]}}`, ids["first"]))
+
+	if ping := srv.next(t); ping.ID != 99 {
+		t.Errorf("Expected a reply to the server's ping, got %+v", ping)
+	}
+
+	for i := 0; i < 2; i++ {
+		o := <-results
+		if o.err != nil {
+			t.Fatalf("%s failed: %v", o.name, o.err)
+		}
+		if got := o.res.Content[0].Text; got != o.name+" done" {
+			t.Errorf("Call %s got response %q", o.name, got)
+		}
+	}
+}
+
+func TestCallHonorsContextDeadline(t *testing.T) {
+	c, _, _ := newFakeClient(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := c.Initialize(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline error, got %v", err)
+	}
+	if time.Since(start) > time.Second {
+		t.Errorf("Expected call to return at the deadline, took %s", time.Since(start))
+	}
+}
+
+func TestCallFailsWhenServerExits(t *testing.T) {
+	clientIn, serverOut := io.Pipe()
+	serverIn, clientOut := io.Pipe()
+	go io.Copy(io.Discard, serverIn)
+	c := newClient("fake", clientOut, clientIn, nil)
+
+	serverOut.Close()
+	_, err := c.ListTools(context.Background())
+	if !errors.Is(err, ErrClosed) {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+}
+
+func TestStderrKeepsTail(t *testing.T) {
+	c, _, stderr := newFakeClient(t)
+	fmt.Fprint(stderr, strings.Repeat("x", stderrBufferSize))
+	fmt.Fprint(stderr, "last words")
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.HasSuffix(c.Stderr(), "last words") && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	got := c.Stderr()
+	if !strings.HasSuffix(got, "last words") || len(got) != stderrBufferSize {
+		t.Errorf("Expected the last %d bytes, got %d ending %q", stderrBufferSize, len(got), got[len(got)-10:])
+	}
+}
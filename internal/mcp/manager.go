@@ -3,12 +3,32 @@ package mcp
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"sync"
+	"time"
+
+	"groq-go/internal/logging"
+	"groq-go/internal/tool"
 )
 
+// startupTimeout bounds initialization and tool listing for one server.
+// It is generous as the first npx run of a server installs it.
+const startupTimeout = 2 * time.Minute
+
+// StartupWait is how long StartServers waits for the servers, which start
+// at the same time. Servers not up by then keep starting in the background
+// and register their tools when ready.
+var StartupWait = 15 * time.Second
+
+// errServerNotRunning is returned for calls to servers that are not running
+var errServerNotRunning = errors.New("MCP server not running")
+
 // ServerConfig represents a single MCP server configuration
 type ServerConfig struct {
 	Command string            `json:"command"`
@@ -21,53 +41,205 @@ type Config struct {
 	MCPServers map[string]ServerConfig `json:"mcpServers"`
 }
 
+// ServerStatus describes a configured server
+type ServerStatus struct {
+	Name      string       `json:"name"`
+	Config    ServerConfig `json:"config"`
+	Connected bool         `json:"connected"`
+	Starting  bool         `json:"starting,omitempty"`
+	Tools     []string     `json:"tools,omitempty"`
+	Error     string       `json:"error,omitempty"`
+}
+
+// ReloadResult lists the servers a Reload changed
+type ReloadResult struct {
+	Added     []string `json:"added,omitempty"`
+	Removed   []string `json:"removed,omitempty"`
+	Restarted []string `json:"restarted,omitempty"`
+}
+
+// StartResult summarizes StartServers
+type StartResult struct {
+	Started []string
+	Failed  map[string]error
+	// Pending servers were still starting after StartupWait
+	Pending []string
+}
+
 // Manager manages multiple MCP server connections
 type Manager struct {
-	mu      sync.RWMutex
-	clients map[string]*Client
-	config  Config
+	// opMu serializes AddServer, RemoveServer and Reload
+	opMu       sync.Mutex
+	mu         sync.RWMutex
+	clients    map[string]*Client
+	config     Config
+	configPath string
+	// startErrs records why configured servers are not running
+	startErrs map[string]string
+	// starting cancels the background starts of StartServers
+	starting map[string]context.CancelFunc
+
+	// registry receives tools of servers started or stopped at runtime
+	registry   *tool.Registry
+	registered map[string][]string
 }
 
 // NewManager creates a new MCP manager
 func NewManager() *Manager {
 	return &Manager{
-		clients: make(map[string]*Client),
+		clients:    make(map[string]*Client),
+		startErrs:  make(map[string]string),
+		starting:   make(map[string]context.CancelFunc),
+		registered: make(map[string][]string),
 	}
 }
 
 // LoadConfig loads MCP configuration from the config file
 func (m *Manager) LoadConfig() error {
-	configPath := m.getConfigPath()
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-	data, err := os.ReadFile(configPath)
+	if m.configPath == "" {
+		m.configPath = m.getConfigPath()
+	}
+	config, err := readConfig(m.configPath)
+	if err != nil {
+		return err
+	}
+	m.config = config
+	return nil
+}
+
+// readConfig reads a config file; a missing file is an empty config
+func readConfig(path string) (Config, error) {
+	var config Config
+	data, err := os.ReadFile(path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			// No config file, that's ok
-			return nil
+			return config, nil
 		}
-		return fmt.Errorf("failed to read config: %w", err)
+		return config, fmt.Errorf("failed to read config: %w", err)
 	}
 
-	if err := json.Unmarshal(data, &m.config); err != nil {
-		return fmt.Errorf("failed to parse config: %w", err)
+	if err := json.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("failed to parse config: %w", err)
 	}
+	return config, nil
+}
 
-	return nil
+// saveConfig writes the config file. The caller must hold m.mu.
+func (m *Manager) saveConfig() error {
+	if m.configPath == "" {
+		m.configPath = m.getConfigPath()
+	}
+	data, err := json.MarshalIndent(m.config, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.configPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(m.configPath, data, 0600)
 }
 
-// StartServers starts all configured MCP servers
-func (m *Manager) StartServers(ctx context.Context) error {
+// StartServers starts all configured MCP servers at once and waits up to
+// StartupWait for them. A server that fails does not stop the others.
+func (m *Manager) StartServers(ctx context.Context) *StartResult {
+	m.mu.Lock()
+	servers := make(map[string]ServerConfig, len(m.config.MCPServers))
+	starts := make(map[string]context.Context, len(m.config.MCPServers))
 	for name, cfg := range m.config.MCPServers {
-		if err := m.startServer(ctx, name, cfg); err != nil {
-			// Log error but continue with other servers
-			fmt.Fprintf(os.Stderr, "Warning: failed to start MCP server %s: %v\n", name, err)
+		servers[name] = cfg
+		startCtx, cancel := context.WithCancel(ctx)
+		starts[name] = startCtx
+		m.starting[name] = cancel
+	}
+	m.mu.Unlock()
+
+	done := make(chan startOutcome, len(servers))
+	begin := time.Now()
+	for name, cfg := range servers {
+		go func() {
+			err := m.startServer(starts[name], name, cfg)
+			m.mu.Lock()
+			if cancel, ok := m.starting[name]; ok && starts[name].Err() == nil {
+				cancel()
+				delete(m.starting, name)
+			}
+			m.mu.Unlock()
+			if err == nil {
+				m.registerTools(name)
+			}
+			done <- startOutcome{name, err, time.Since(begin)}
+		}()
+	}
+
+	result := &StartResult{Failed: make(map[string]error)}
+	waiting := make(map[string]bool, len(servers))
+	for name := range servers {
+		waiting[name] = true
+	}
+	timer := time.NewTimer(StartupWait)
+	defer timer.Stop()
+	for len(waiting) > 0 {
+		select {
+		case o := <-done:
+			delete(waiting, o.name)
+			if o.err != nil {
+				result.Failed[o.name] = o.err
+				logging.Warn("MCP server failed to start", "server", o.name, "duration", o.took.Round(time.Millisecond), "error", o.err)
+				continue
+			}
+			result.Started = append(result.Started, o.name)
+			logging.Info("MCP server started", "server", o.name, "duration", o.took.Round(time.Millisecond))
+		case <-timer.C:
+			for name := range waiting {
+				result.Pending = append(result.Pending, name)
+			}
+			go logLateStarts(done, len(waiting))
+			waiting = nil
+		}
+	}
+	sort.Strings(result.Started)
+	sort.Strings(result.Pending)
+	return result
+}
+
+// startOutcome is how the start of one server ended and how long it took
+type startOutcome struct {
+	name string
+	err  error
+	took time.Duration
+}
+
+// logLateStarts reports the n servers StartServers stopped waiting for as
+// they come up, their tools being registered by then
+func logLateStarts(done <-chan startOutcome, n int) {
+	for range n {
+		o := <-done
+		if o.err != nil {
+			logging.Warn("MCP server failed to start", "server", o.name, "duration", o.took.Round(time.Millisecond), "error", o.err)
 			continue
 		}
+		logging.Info("MCP server started late, its tools are now available", "server", o.name, "duration", o.took.Round(time.Millisecond))
 	}
-	return nil
 }
 
-func (m *Manager) startServer(ctx context.Context, name string, cfg ServerConfig) error {
+func (m *Manager) startServer(ctx context.Context, name string, cfg ServerConfig) (err error) {
+	stopped := ctx
+	defer func() {
+		m.mu.Lock()
+		if stopped.Err() != nil {
+			// Removed or closed while starting, see stopServer
+		} else if err != nil {
+			m.startErrs[name] = err.Error()
+		} else {
+			delete(m.startErrs, name)
+		}
+		m.mu.Unlock()
+	}()
+
 	// Convert env map to slice
 	var env []string
 	if len(cfg.Env) > 0 {
@@ -82,9 +254,16 @@ func (m *Manager) startServer(ctx context.Context, name string, cfg ServerConfig
 		return err
 	}
 
+	// A server that never answers must not block startup
+	ctx, cancel := context.WithTimeout(ctx, startupTimeout)
+	defer cancel()
+
 	// Initialize the connection
 	if err := client.Initialize(ctx); err != nil {
 		client.Close()
+		if stderr := strings.TrimSpace(client.Stderr()); stderr != "" {
+			return fmt.Errorf("%w\nserver stderr:\n%s", err, stderr)
+		}
 		return err
 	}
 
@@ -95,10 +274,171 @@ func (m *Manager) startServer(ctx context.Context, name string, cfg ServerConfig
 	}
 
 	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := stopped.Err(); err != nil {
+		client.Close()
+		return err
+	}
 	m.clients[name] = client
+	return nil
+}
+
+// stopServer closes a running server and unregisters its tools
+func (m *Manager) stopServer(name string) {
+	m.mu.Lock()
+	client := m.clients[name]
+	delete(m.clients, name)
+	delete(m.startErrs, name)
+	if cancel, ok := m.starting[name]; ok {
+		cancel()
+		delete(m.starting, name)
+	}
 	m.mu.Unlock()
 
-	return nil
+	m.unregisterTools(name)
+	if client != nil {
+		client.Close()
+	}
+}
+
+// AddServer starts a server, registers its tools and saves it to the
+// config file. The server is not saved if it fails to start.
+func (m *Manager) AddServer(ctx context.Context, name string, cfg ServerConfig) error {
+	if name == "" || cfg.Command == "" {
+		return errors.New("server name and command are required")
+	}
+
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+
+	m.mu.RLock()
+	_, exists := m.config.MCPServers[name]
+	m.mu.RUnlock()
+	if exists {
+		return fmt.Errorf("MCP server %q already exists", name)
+	}
+
+	if err := m.startServer(ctx, name, cfg); err != nil {
+		m.mu.Lock()
+		delete(m.startErrs, name)
+		m.mu.Unlock()
+		return err
+	}
+	m.registerTools(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.config.MCPServers == nil {
+		m.config.MCPServers = make(map[string]ServerConfig)
+	}
+	m.config.MCPServers[name] = cfg
+	return m.saveConfig()
+}
+
+// RemoveServer stops a server, unregisters its tools and removes it from
+// the config file
+func (m *Manager) RemoveServer(name string) error {
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+
+	m.mu.RLock()
+	_, configured := m.config.MCPServers[name]
+	_, running := m.clients[name]
+	_, starting := m.starting[name]
+	m.mu.RUnlock()
+	if !configured && !running && !starting {
+		return fmt.Errorf("MCP server %q not found", name)
+	}
+
+	m.stopServer(name)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.config.MCPServers, name)
+	return m.saveConfig()
+}
+
+// Reload re-reads the config file and starts, stops or restarts servers
+// whose configuration changed. Servers that fail to start are reported by
+// Servers and do not stop the reload.
+func (m *Manager) Reload(ctx context.Context) (*ReloadResult, error) {
+	m.opMu.Lock()
+	defer m.opMu.Unlock()
+
+	m.mu.Lock()
+	if m.configPath == "" {
+		m.configPath = m.getConfigPath()
+	}
+	path := m.configPath
+	m.mu.Unlock()
+
+	config, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	old := m.config
+	m.config = config
+	m.mu.Unlock()
+
+	result := &ReloadResult{}
+	for name, oldCfg := range old.MCPServers {
+		newCfg, ok := config.MCPServers[name]
+		if !ok {
+			m.stopServer(name)
+			result.Removed = append(result.Removed, name)
+		} else if !reflect.DeepEqual(oldCfg, newCfg) {
+			m.stopServer(name)
+			result.Restarted = append(result.Restarted, name)
+		}
+	}
+	for name, cfg := range config.MCPServers {
+		oldCfg, existed := old.MCPServers[name]
+		if existed && reflect.DeepEqual(oldCfg, cfg) {
+			continue
+		}
+		if !existed {
+			result.Added = append(result.Added, name)
+		}
+		if err := m.startServer(ctx, name, cfg); err == nil {
+			m.registerTools(name)
+		}
+	}
+
+	sort.Strings(result.Added)
+	sort.Strings(result.Removed)
+	sort.Strings(result.Restarted)
+	return result, nil
+}
+
+// Servers returns the status of every configured or running server
+func (m *Manager) Servers() []ServerStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make(map[string]bool)
+	for name := range m.config.MCPServers {
+		names[name] = true
+	}
+	for name := range m.clients {
+		names[name] = true
+	}
+
+	statuses := make([]ServerStatus, 0, len(names))
+	for name := range names {
+		status := ServerStatus{Name: name, Config: m.config.MCPServers[name], Error: m.startErrs[name]}
+		_, status.Starting = m.starting[name]
+		if client, ok := m.clients[name]; ok {
+			status.Connected = true
+			for _, t := range client.Tools() {
+				status.Tools = append(status.Tools, t.Name)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
 }
 
 // GetAllTools returns all tools from all connected MCP servers
@@ -108,7 +448,7 @@ func (m *Manager) GetAllTools() map[string][]ToolDef {
 
 	result := make(map[string][]ToolDef)
 	for name, client := range m.clients {
-		result[name] = client.tools
+		result[name] = client.Tools()
 	}
 	return result
 }
@@ -120,19 +460,77 @@ func (m *Manager) CallTool(ctx context.Context, serverName, toolName string, arg
 	m.mu.RUnlock()
 
 	if !ok {
-		return nil, fmt.Errorf("MCP server %q not found", serverName)
+		return nil, fmt.Errorf("%w: %s", errServerNotRunning, serverName)
 	}
 
 	return client.CallTool(ctx, toolName, args)
 }
 
+// client returns the running server name
+func (m *Manager) client(name string) (*Client, error) {
+	m.mu.RLock()
+	client, ok := m.clients[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errServerNotRunning, name)
+	}
+	return client, nil
+}
+
+// ListResources lists the resources of the specified MCP server
+func (m *Manager) ListResources(ctx context.Context, serverName string) ([]Resource, error) {
+	client, err := m.client(serverName)
+	if err != nil {
+		return nil, err
+	}
+	return client.ListResources(ctx)
+}
+
+// ReadResource reads a resource from the specified MCP server
+func (m *Manager) ReadResource(ctx context.Context, serverName, uri string) (*ReadResourceResult, error) {
+	client, err := m.client(serverName)
+	if err != nil {
+		return nil, err
+	}
+	return client.ReadResource(ctx, uri)
+}
+
+// ListPrompts returns the prompts of every connected server that offers
+// them, by server name
+func (m *Manager) ListPrompts(ctx context.Context) (map[string][]Prompt, error) {
+	result := make(map[string][]Prompt)
+	var errs []error
+	for _, name := range m.ServerNames() {
+		client, err := m.client(name)
+		if err != nil || client.Capabilities().Prompts == nil {
+			continue
+		}
+		prompts, err := client.ListPrompts(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		result[name] = prompts
+	}
+	return result, errors.Join(errs...)
+}
+
+// GetPrompt renders a prompt of the specified MCP server
+func (m *Manager) GetPrompt(ctx context.Context, serverName, name string, args map[string]string) (*GetPromptResult, error) {
+	client, err := m.client(serverName)
+	if err != nil {
+		return nil, err
+	}
+	return client.GetPrompt(ctx, name, args)
+}
+
 // FindToolServer finds which server provides a given tool
 func (m *Manager) FindToolServer(toolName string) (string, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	for serverName, client := range m.clients {
-		for _, tool := range client.tools {
+		for _, tool := range client.Tools() {
 			if tool.Name == toolName {
 				return serverName, true
 			}
@@ -146,6 +544,10 @@ func (m *Manager) Close() {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	for _, cancel := range m.starting {
+		cancel()
+	}
+	m.starting = make(map[string]context.CancelFunc)
 	for _, client := range m.clients {
 		client.Close()
 	}
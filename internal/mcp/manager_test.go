@@ -0,0 +1,306 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// TestMain lets the test binary act as an MCP server when started by one
+// of the tests, offering the tools named in FAKE_MCP_TOOLS after waiting
+// FAKE_MCP_DELAY to initialize
+func TestMain(m *testing.M) {
+	if os.Getenv("FAKE_MCP_SERVER") == "1" {
+		runFakeServer()
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeServer() {
+	var tools []ToolDef
+	for _, name := range strings.Split(os.Getenv("FAKE_MCP_TOOLS"), ",") {
+		tools = append(tools, ToolDef{Name: name, Description: "fake " + name})
+	}
+	r := bufio.NewReader(os.Stdin)
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+		var req JSONRPCRequest
+		if json.Unmarshal(line, &req) != nil || req.ID == 0 {
+			continue
+		}
+		var result any
+		var rpcErr *JSONRPCError
+		switch req.Method {
+		case "initialize":
+			if delay, err := time.ParseDuration(os.Getenv("FAKE_MCP_DELAY")); err == nil {
+				time.Sleep(delay)
+			}
+			init := InitializeResult{ServerInfo: ServerInfo{Name: "fake"}}
+			if os.Getenv("FAKE_MCP_RESOURCES") == "1" {
+				init.Capabilities = ServerCaps{Resources: &ResourcesCaps{}, Prompts: &PromptsCaps{}}
+			}
+			result = init
+		case "tools/list":
+			result = ListToolsResult{Tools: tools}
+		case "tools/call":
+			result = CallToolResult{Content: []ContentBlock{{Type: "text", Text: "called"}}}
+		default:
+			result, rpcErr = fakeResourcesAndPrompts(req)
+		}
+		data, _ := json.Marshal(result)
+		out, _ := json.Marshal(JSONRPCResponse{JSONRPC: "2.0", ID: req.ID, Result: data, Error: rpcErr})
+		fmt.Println(string(out))
+	}
+}
+
+func fakeServerConfig(t *testing.T, tools string) ServerConfig {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ServerConfig{
+		Command: exe,
+		Env:     map[string]string{"FAKE_MCP_SERVER": "1", "FAKE_MCP_TOOLS": tools},
+	}
+}
+
+func newTestManager(t *testing.T) (*Manager, *tool.Registry, string) {
+	t.Helper()
+	m := NewManager()
+	m.configPath = filepath.Join(t.TempDir(), "mcp.json")
+	t.Cleanup(m.Close)
+	registry := tool.NewRegistry()
+	RegisterMCPTools(registry, m)
+	return m, registry, m.configPath
+}
+
+func toolNames(registry *tool.Registry) []string {
+	var names []string
+	for _, t := range registry.ToClientTools() {
+		names = append(names, t.Function.Name)
+	}
+	return names
+}
+
+func TestAddRemoveServerKeepsRegistryInSync(t *testing.T) {
+	m, registry, path := newTestManager(t)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if err := m.AddServer(ctx, "fake", fakeServerConfig(t, "alpha,beta")); err != nil {
+			t.Fatalf("AddServer (cycle %d): %v", i, err)
+		}
+		if names := toolNames(registry); len(names) != 2 {
+			t.Fatalf("Expected 2 registered tools, got %v", names)
+		}
+		data, _ := os.ReadFile(path)
+		if !strings.Contains(string(data), `"fake"`) {
+			t.Errorf("Expected server saved to config, got %s", data)
+		}
+
+		if err := m.AddServer(ctx, "fake", fakeServerConfig(t, "alpha")); err == nil {
+			t.Error("Expected duplicate add to fail")
+		}
+
+		if err := m.RemoveServer("fake"); err != nil {
+			t.Fatalf("RemoveServer: %v", err)
+		}
+		if names := toolNames(registry); len(names) != 0 {
+			t.Fatalf("Expected tools unregistered, got %v", names)
+		}
+		data, _ = os.ReadFile(path)
+		if strings.Contains(string(data), `"fake"`) {
+			t.Errorf("Expected server removed from config, got %s", data)
+		}
+	}
+
+	if err := m.RemoveServer("fake"); err == nil {
+		t.Error("Expected removing an unknown server to fail")
+	}
+}
+
+func TestRemovedToolReturnsCleanResult(t *testing.T) {
+	m, registry, _ := newTestManager(t)
+	if err := m.AddServer(context.Background(), "fake", fakeServerConfig(t, "alpha")); err != nil {
+		t.Fatal(err)
+	}
+	adapter, _ := registry.Get("mcp_fake_alpha")
+	executor := tool.NewExecutor(registry)
+	call := client.ToolCall{ID: "1", Function: client.FunctionCall{Name: "mcp_fake_alpha", Arguments: "{}"}}
+
+	result, _ := executor.ExecuteToolCall(context.Background(), call)
+	if result.IsError || result.Content != "called" {
+		t.Fatalf("Expected tool call to succeed, got %+v", result)
+	}
+
+	m.RemoveServer("fake")
+	result, _ = executor.ExecuteToolCall(context.Background(), call)
+	if !result.IsError || !strings.Contains(result.Content, "no longer available") {
+		t.Errorf("Expected no longer available result, got %+v", result)
+	}
+
+	// An adapter held across the removal fails cleanly too
+	result, err := adapter.Execute(context.Background(), nil)
+	if err != nil || !result.IsError || !strings.Contains(result.Content, "no longer available") {
+		t.Errorf("Expected no longer available result from stale adapter, got %+v, %v", result, err)
+	}
+}
+
+func TestReloadAppliesConfigChanges(t *testing.T) {
+	m, registry, path := newTestManager(t)
+	ctx := context.Background()
+
+	writeConfig := func(servers map[string]ServerConfig) {
+		data, _ := json.Marshal(Config{MCPServers: servers})
+		os.WriteFile(path, data, 0600)
+	}
+
+	writeConfig(map[string]ServerConfig{
+		"one": fakeServerConfig(t, "a"),
+		"two": fakeServerConfig(t, "b"),
+	})
+	result, err := m.Reload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(result.Added, ",") != "one,two" {
+		t.Errorf("Expected one and two added, got %+v", result)
+	}
+	if names := toolNames(registry); len(names) != 2 {
+		t.Fatalf("Expected 2 tools, got %v", names)
+	}
+
+	writeConfig(map[string]ServerConfig{
+		"two":   fakeServerConfig(t, "b,c"),
+		"three": {Command: "/nonexistent/mcp-server"},
+	})
+	result, err = m.Reload(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(result.Removed, ",") != "one" || strings.Join(result.Restarted, ",") != "two" || strings.Join(result.Added, ",") != "three" {
+		t.Errorf("Unexpected reload result %+v", result)
+	}
+	if _, ok := registry.Get("mcp_one_a"); ok {
+		t.Error("Expected removed server's tools unregistered")
+	}
+	if _, ok := registry.Get("mcp_two_c"); !ok {
+		t.Error("Expected restarted server's new tool registered")
+	}
+
+	for _, s := range m.Servers() {
+		if s.Name == "three" && (s.Connected || s.Error == "") {
+			t.Errorf("Expected failed server to report its error, got %+v", s)
+		}
+	}
+}
+
+// slowServerConfig is a fake server taking delay to initialize
+func slowServerConfig(t *testing.T, tools string, delay time.Duration) ServerConfig {
+	cfg := fakeServerConfig(t, tools)
+	cfg.Env["FAKE_MCP_DELAY"] = delay.String()
+	return cfg
+}
+
+func TestStartServersInParallel(t *testing.T) {
+	m, registry, _ := newTestManager(t)
+	m.config.MCPServers = map[string]ServerConfig{
+		"quick":  fakeServerConfig(t, "a"),
+		"slow":   slowServerConfig(t, "b", 600*time.Millisecond),
+		"slower": slowServerConfig(t, "c", 800*time.Millisecond),
+		"broken": {Command: "/nonexistent/mcp-server"},
+	}
+
+	begin := time.Now()
+	result := m.StartServers(context.Background())
+	took := time.Since(begin)
+
+	if strings.Join(result.Started, ",") != "quick,slow,slower" || len(result.Pending) != 0 {
+		t.Errorf("Unexpected start result %+v", result)
+	}
+	if result.Failed["broken"] == nil {
+		t.Errorf("Expected the broken server reported, got %+v", result.Failed)
+	}
+	// Started one after the other this would take at least 1.4s
+	if took < 800*time.Millisecond || took > 1300*time.Millisecond {
+		t.Errorf("Expected startup to take about as long as the slowest server, took %s", took)
+	}
+	if names := toolNames(registry); len(names) != 3 {
+		t.Errorf("Expected the tools of every started server, got %v", names)
+	}
+}
+
+func TestStartServersRegistersLateServers(t *testing.T) {
+	defer func(wait time.Duration) { StartupWait = wait }(StartupWait)
+	StartupWait = 200 * time.Millisecond
+
+	m, registry, _ := newTestManager(t)
+	m.config.MCPServers = map[string]ServerConfig{
+		"quick": fakeServerConfig(t, "a"),
+		"late":  slowServerConfig(t, "b", time.Second),
+	}
+	changes, cancel := registry.Subscribe()
+	defer cancel()
+
+	result := m.StartServers(context.Background())
+	if strings.Join(result.Started, ",") != "quick" || strings.Join(result.Pending, ",") != "late" {
+		t.Fatalf("Expected late to be pending, got %+v", result)
+	}
+	for _, s := range m.Servers() {
+		if s.Name == "late" && (!s.Starting || s.Connected) {
+			t.Errorf("Expected late reported as starting, got %+v", s)
+		}
+	}
+
+	deadline := time.After(5 * time.Second)
+	for {
+		if _, ok := registry.Get("mcp_late_b"); ok {
+			break
+		}
+		select {
+		case <-changes:
+		case <-deadline:
+			t.Fatalf("Expected the late server's tool registered, got %v", toolNames(registry))
+		}
+	}
+	for _, s := range m.Servers() {
+		if s.Name == "late" && (s.Starting || !s.Connected) {
+			t.Errorf("Expected late reported as connected, got %+v", s)
+		}
+	}
+}
+
+func TestRemoveServerStopsBackgroundStart(t *testing.T) {
+	defer func(wait time.Duration) { StartupWait = wait }(StartupWait)
+	StartupWait = 50 * time.Millisecond
+
+	m, registry, _ := newTestManager(t)
+	m.config.MCPServers = map[string]ServerConfig{"late": slowServerConfig(t, "b", 300*time.Millisecond)}
+	if result := m.StartServers(context.Background()); len(result.Pending) != 1 {
+		t.Fatalf("Expected late to be pending, got %+v", result)
+	}
+	if err := m.RemoveServer("late"); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(600 * time.Millisecond)
+	if names := toolNames(registry); len(names) != 0 || m.ServerCount() != 0 {
+		t.Errorf("Expected the removed server never to come up, got %v", names)
+	}
+	if servers := m.Servers(); len(servers) != 0 {
+		t.Errorf("Expected no servers left, got %+v", servers)
+	}
+}
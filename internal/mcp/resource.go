@@ -0,0 +1,177 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+
+	"groq-go/internal/tool"
+)
+
+// ResourceAdapter lets the model list and read the resources of one MCP
+// server, such as files or database schemas. It is registered for servers
+// that declare the resources capability.
+type ResourceAdapter struct {
+	manager    *Manager
+	serverName string
+}
+
+// NewResourceAdapter creates the resource tool of a server
+func NewResourceAdapter(manager *Manager, serverName string) *ResourceAdapter {
+	return &ResourceAdapter{manager: manager, serverName: serverName}
+}
+
+// Name returns the tool name with server prefix
+func (t *ResourceAdapter) Name() string {
+	return fmt.Sprintf("mcp_%s_resource", t.serverName)
+}
+
+func (t *ResourceAdapter) Description() string {
+	return fmt.Sprintf("[MCP:%s] List the resources the %s server publishes, such as files or schemas, or read one by URI. List first to find the URIs.", t.serverName, t.serverName)
+}
+
+func (t *ResourceAdapter) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"enum":        []string{"list", "read"},
+				"description": "list the resources, or read the one at uri",
+			},
+			"uri": map[string]any{
+				"type":        "string",
+				"description": "URI of the resource to read",
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+type resourceArgs struct {
+	Action string `json:"action"`
+	URI    string `json:"uri"`
+}
+
+func (t *ResourceAdapter) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.Result, error) {
+	var args resourceArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("invalid arguments: %v", err)), nil
+	}
+
+	var content string
+	var err error
+	switch args.Action {
+	case "list":
+		content, err = t.list(ctx)
+	case "read":
+		if args.URI == "" {
+			return tool.NewErrorResult("uri is required to read a resource"), nil
+		}
+		content, err = t.read(ctx, args.URI)
+	default:
+		return tool.NewErrorResult(fmt.Sprintf("unknown action %q, use list or read", args.Action)), nil
+	}
+	if errors.Is(err, errServerNotRunning) {
+		return tool.NewErrorResult(fmt.Sprintf("tool %s is no longer available: MCP server %s was removed", t.Name(), t.serverName)), nil
+	}
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("MCP call failed: %v", err)), nil
+	}
+	return tool.NewResult(content), nil
+}
+
+func (t *ResourceAdapter) list(ctx context.Context) (string, error) {
+	resources, err := t.manager.ListResources(ctx, t.serverName)
+	if err != nil {
+		return "", err
+	}
+	if len(resources) == 0 {
+		return fmt.Sprintf("%s publishes no resources", t.serverName), nil
+	}
+	var b strings.Builder
+	for _, r := range resources {
+		fmt.Fprintf(&b, "%s - %s", r.URI, r.Name)
+		if r.MimeType != "" {
+			fmt.Fprintf(&b, " (%s)", r.MimeType)
+		}
+		if r.Description != "" {
+			fmt.Fprintf(&b, ": %s", r.Description)
+		}
+		b.WriteString("\n")
+	}
+	return b.String(), nil
+}
+
+func (t *ResourceAdapter) read(ctx context.Context, uri string) (string, error) {
+	result, err := t.manager.ReadResource(ctx, t.serverName, uri)
+	if err != nil {
+		return "", err
+	}
+	if len(result.Contents) == 0 {
+		return fmt.Sprintf("%s is empty", uri), nil
+	}
+	var parts []string
+	for _, c := range result.Contents {
+		text := c.String()
+		if len(result.Contents) > 1 {
+			text = fmt.Sprintf("--- %s\n%s", c.URI, text)
+		}
+		parts = append(parts, text)
+	}
+	return strings.Join(parts, "\n"), nil
+}
+
+// String returns the text of the contents. Blobs of a text type are
+// decoded; other blobs are described rather than sent as base64.
+func (c ResourceContents) String() string {
+	if c.Blob == "" {
+		return c.Text
+	}
+	data, err := base64.StdEncoding.DecodeString(c.Blob)
+	if err != nil {
+		return fmt.Sprintf("[binary contents of %s that are not valid base64]", c.URI)
+	}
+	if isTextType(c.MimeType) {
+		return string(data)
+	}
+	mimeType := c.MimeType
+	if mimeType == "" {
+		mimeType = "unknown type"
+	}
+	return fmt.Sprintf("[binary contents of %s: %s, %d bytes]", c.URI, mimeType, len(data))
+}
+
+// isTextType reports whether a MIME type holds text
+func isTextType(mimeType string) bool {
+	mediaType, _, err := mime.ParseMediaType(mimeType)
+	if err != nil {
+		return false
+	}
+	if strings.HasPrefix(mediaType, "text/") {
+		return true
+	}
+	switch mediaType {
+	case "application/json", "application/xml", "application/yaml", "application/javascript", "application/sql":
+		return true
+	}
+	return strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml")
+}
+
+// String returns the text of a content block: its text, the contents of
+// an embedded resource, or a placeholder for an image
+func (b ContentBlock) String() string {
+	switch b.Type {
+	case "resource":
+		if b.Resource != nil {
+			return b.Resource.String()
+		}
+	case "image", "audio":
+		return fmt.Sprintf("[%s: %s]", b.Type, b.MimeType)
+	}
+	return b.Text
+}
@@ -0,0 +1,161 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// fakeResourcesAndPrompts answers the resource and prompt requests of the
+// fake server: two pages of resources and a prompt taking a "topic"
+func fakeResourcesAndPrompts(req JSONRPCRequest) (any, *JSONRPCError) {
+	params, _ := json.Marshal(req.Params)
+	switch req.Method {
+	case "resources/list":
+		var p PaginatedParams
+		json.Unmarshal(params, &p)
+		if p.Cursor == "" {
+			return ListResourcesResult{
+				Resources:  []Resource{{URI: "file:///notes.txt", Name: "notes", MimeType: "text/plain"}},
+				NextCursor: "page2",
+			}, nil
+		}
+		return ListResourcesResult{Resources: []Resource{
+			{URI: "db://schema", Name: "schema", Description: "Table definitions"},
+			{URI: "file:///logo.png", Name: "logo", MimeType: "image/png"},
+		}}, nil
+	case "resources/read":
+		var p ReadResourceParams
+		json.Unmarshal(params, &p)
+		switch p.URI {
+		case "file:///notes.txt":
+			return ReadResourceResult{Contents: []ResourceContents{{URI: p.URI, MimeType: "text/plain", Text: "buy milk"}}}, nil
+		case "db://schema":
+			return ReadResourceResult{Contents: []ResourceContents{
+				{URI: "db://schema/users", MimeType: "application/sql", Blob: base64.StdEncoding.EncodeToString([]byte("CREATE TABLE users (id int);"))},
+				{URI: "db://schema/orders", Text: "CREATE TABLE orders (id int);"},
+			}}, nil
+		case "file:///logo.png":
+			return ReadResourceResult{Contents: []ResourceContents{{URI: p.URI, MimeType: "image/png", Blob: base64.StdEncoding.EncodeToString(make([]byte, 42))}}}, nil
+		}
+		return nil, &JSONRPCError{Code: -32002, Message: "resource not found: " + p.URI}
+	case "prompts/list":
+		return ListPromptsResult{Prompts: []Prompt{{
+			Name:        "review",
+			Description: "Review code about a topic",
+			Arguments:   []PromptArgument{{Name: "topic", Required: true}},
+		}}}, nil
+	case "prompts/get":
+		var p GetPromptParams
+		json.Unmarshal(params, &p)
+		if p.Name != "review" || p.Arguments["topic"] == "" {
+			return nil, &JSONRPCError{Code: -32602, Message: "missing required argument topic"}
+		}
+		return GetPromptResult{Messages: []PromptMessage{
+			{Role: "assistant", Content: ContentBlock{Type: "text", Text: "I review code."}},
+			{Role: "user", Content: ContentBlock{Type: "text", Text: "Review the " + p.Arguments["topic"] + " code"}},
+			{Role: "user", Content: ContentBlock{Type: "resource", Resource: &ResourceContents{URI: "file:///notes.txt", Text: "buy milk"}}},
+		}}, nil
+	}
+	return nil, &JSONRPCError{Code: -32601, Message: "method not found: " + req.Method}
+}
+
+func newResourceServer(t *testing.T) (*Manager, *tool.Registry) {
+	t.Helper()
+	m, registry, _ := newTestManager(t)
+	cfg := fakeServerConfig(t, "alpha")
+	cfg.Env["FAKE_MCP_RESOURCES"] = "1"
+	if err := m.AddServer(context.Background(), "fake", cfg); err != nil {
+		t.Fatal(err)
+	}
+	return m, registry
+}
+
+func readResource(t *testing.T, registry *tool.Registry, args string) tool.Result {
+	t.Helper()
+	call := client.ToolCall{ID: "1", Function: client.FunctionCall{Name: "mcp_fake_resource", Arguments: args}}
+	result, err := tool.NewExecutor(registry).ExecuteToolCall(context.Background(), call)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestResourceTool(t *testing.T) {
+	_, registry := newResourceServer(t)
+
+	list := readResource(t, registry, `{"action":"list"}`)
+	for _, want := range []string{"file:///notes.txt - notes (text/plain)", "db://schema - schema: Table definitions", "file:///logo.png"} {
+		if !strings.Contains(list.Content, want) {
+			t.Errorf("Expected %q in the listing across pages, got:\n%s", want, list.Content)
+		}
+	}
+
+	if got := readResource(t, registry, `{"action":"read","uri":"file:///notes.txt"}`); got.Content != "buy milk" {
+		t.Errorf("Expected the text contents, got %+v", got)
+	}
+	schema := readResource(t, registry, `{"action":"read","uri":"db://schema"}`).Content
+	if !strings.Contains(schema, "--- db://schema/users\nCREATE TABLE users") || !strings.Contains(schema, "--- db://schema/orders\nCREATE TABLE orders") {
+		t.Errorf("Expected both contents, the text blob decoded, got:\n%s", schema)
+	}
+	if got := readResource(t, registry, `{"action":"read","uri":"file:///logo.png"}`).Content; got != "[binary contents of file:///logo.png: image/png, 42 bytes]" {
+		t.Errorf("Expected binary contents described, got %q", got)
+	}
+
+	if got := readResource(t, registry, `{"action":"read","uri":"file:///missing"}`); !got.IsError || !strings.Contains(got.Content, "resource not found") {
+		t.Errorf("Expected the server's error, got %+v", got)
+	}
+	if got := readResource(t, registry, `{"action":"read"}`); !got.IsError {
+		t.Errorf("Expected a read without uri to fail, got %+v", got)
+	}
+}
+
+func TestResourceToolOnlyForResourceServers(t *testing.T) {
+	m, registry, _ := newTestManager(t)
+	if err := m.AddServer(context.Background(), "plain", fakeServerConfig(t, "alpha")); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := registry.Get("mcp_plain_resource"); ok {
+		t.Error("Expected no resource tool for a server without resources")
+	}
+	if prompts, err := m.ListPrompts(context.Background()); err != nil || len(prompts) != 0 {
+		t.Errorf("Expected no prompts, got %v, %v", prompts, err)
+	}
+}
+
+func TestPrompts(t *testing.T) {
+	m, _ := newResourceServer(t)
+	ctx := context.Background()
+
+	prompts, err := m.ListPrompts(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := prompts["fake"]; len(got) != 1 || got[0].Name != "review" || !got[0].Arguments[0].Required {
+		t.Fatalf("Unexpected prompts %+v", prompts)
+	}
+
+	rendered, err := m.GetPrompt(ctx, "fake", "review", map[string]string{"topic": "parser"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var texts []string
+	for _, msg := range rendered.Messages {
+		texts = append(texts, msg.Role+": "+msg.Content.String())
+	}
+	if got := strings.Join(texts, "|"); got != "assistant: I review code.|user: Review the parser code|user: buy milk" {
+		t.Errorf("Unexpected rendering %q", got)
+	}
+
+	if _, err := m.GetPrompt(ctx, "fake", "review", nil); err == nil || !strings.Contains(err.Error(), "missing required argument") {
+		t.Errorf("Expected the server to reject missing arguments, got %v", err)
+	}
+	if _, err := m.GetPrompt(ctx, "gone", "review", nil); err == nil {
+		t.Error("Expected an unknown server to fail")
+	}
+}
@@ -50,7 +50,18 @@ type InitializeResult struct {
 }
 
 type ServerCaps struct {
-	Tools *ToolsCaps `json:"tools,omitempty"`
+	Tools     *ToolsCaps     `json:"tools,omitempty"`
+	Resources *ResourcesCaps `json:"resources,omitempty"`
+	Prompts   *PromptsCaps   `json:"prompts,omitempty"`
+}
+
+type ResourcesCaps struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+type PromptsCaps struct {
+	ListChanged bool `json:"listChanged,omitempty"`
 }
 
 type ServerInfo struct {
@@ -79,6 +90,74 @@ type CallToolResult struct {
 }
 
 type ContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text,omitempty"`
+	Type     string            `json:"type"`
+	Text     string            `json:"text,omitempty"`
+	Data     string            `json:"data,omitempty"`     // Base64, for "image"
+	MimeType string            `json:"mimeType,omitempty"` // For "image"
+	Resource *ResourceContents `json:"resource,omitempty"` // For "resource"
+}
+
+// PaginatedParams asks for the page of a list after Cursor
+type PaginatedParams struct {
+	Cursor string `json:"cursor,omitempty"`
+}
+
+type Resource struct {
+	URI         string `json:"uri"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	MimeType    string `json:"mimeType,omitempty"`
+}
+
+type ListResourcesResult struct {
+	Resources  []Resource `json:"resources"`
+	NextCursor string     `json:"nextCursor,omitempty"`
+}
+
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// ResourceContents holds either Text or Blob, base64-encoded binary data
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+type Prompt struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Arguments   []PromptArgument `json:"arguments,omitempty"`
+}
+
+type PromptArgument struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+}
+
+type ListPromptsResult struct {
+	Prompts    []Prompt `json:"prompts"`
+	NextCursor string   `json:"nextCursor,omitempty"`
+}
+
+type GetPromptParams struct {
+	Name      string            `json:"name"`
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+type PromptMessage struct {
+	Role    string       `json:"role"` // "user" or "assistant"
+	Content ContentBlock `json:"content"`
 }
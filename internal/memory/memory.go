@@ -0,0 +1,266 @@
+// Package memory keeps what the user wants the assistant to remember
+// across sessions, such as "we use pnpm" or "respond in Japanese". The
+// memories of a namespace are added to every system prompt.
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"groq-go/internal/config"
+)
+
+const (
+	// MaxKeyLen and MaxValueLen bound one memory, in bytes
+	MaxKeyLen   = 64
+	MaxValueLen = 500
+	// MaxPerNamespace caps the memories of one namespace
+	MaxPerNamespace = 200
+	// DefaultPromptBytes caps the section added to the system prompt
+	DefaultPromptBytes = 2000
+)
+
+// ErrNotFound is returned for unknown keys
+var ErrNotFound = errors.New("memory not found")
+
+// Origin says who stored a memory
+type Origin string
+
+const (
+	// FromUser memories were set by the user, with /memory set
+	FromUser Origin = "user"
+	// FromModel memories were proposed by the model with the Memory tool
+	FromModel Origin = "model"
+)
+
+// Memory is one remembered fact or preference
+type Memory struct {
+	// Namespace separates users; the REPL uses the empty namespace
+	Namespace string    `json:"namespace,omitempty"`
+	Key       string    `json:"key"`
+	Value     string    `json:"value"`
+	Origin    Origin    `json:"origin"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	// UsedAt is the last time the memory was set or read; the most
+	// recently used memories are kept when the prompt section is capped
+	UsedAt time.Time `json:"used_at"`
+}
+
+// Store holds the memories of every namespace in one JSON file
+type Store struct {
+	mu       sync.Mutex
+	path     string
+	memories map[string]map[string]*Memory // By namespace, then key
+	now      func() time.Time
+}
+
+// DefaultPath is where memories are stored
+func DefaultPath() string {
+	return filepath.Join(config.Dir(), "memory.json")
+}
+
+// Open loads the memories stored at path; a missing file is an empty store
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:     path,
+		memories: make(map[string]map[string]*Memory),
+		now:      time.Now,
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var memories []*Memory
+	if err := json.Unmarshal(data, &memories); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	for _, m := range memories {
+		s.namespace(m.Namespace)[m.Key] = m
+	}
+	return s, nil
+}
+
+// namespace returns the memories of ns, creating the map. The caller must
+// hold s.mu.
+func (s *Store) namespace(ns string) map[string]*Memory {
+	memories, ok := s.memories[ns]
+	if !ok {
+		memories = make(map[string]*Memory)
+		s.memories[ns] = memories
+	}
+	return memories
+}
+
+// NormalizeKey trims key and checks it is a single short line
+func NormalizeKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	switch {
+	case key == "":
+		return "", errors.New("memory key is required")
+	case len(key) > MaxKeyLen:
+		return "", fmt.Errorf("memory key too long: %d bytes (max %d)", len(key), MaxKeyLen)
+	case strings.ContainsAny(key, "\r\n") || !utf8.ValidString(key):
+		return "", errors.New("memory key must be a single line of text")
+	}
+	return key, nil
+}
+
+// Set stores value under key in ns, replacing an existing memory
+func (s *Store) Set(ns, key, value string, origin Origin) (Memory, error) {
+	key, err := NormalizeKey(key)
+	if err != nil {
+		return Memory{}, err
+	}
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return Memory{}, errors.New("memory value is required")
+	case len(value) > MaxValueLen:
+		return Memory{}, fmt.Errorf("memory value too long: %d bytes (max %d)", len(value), MaxValueLen)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	memories := s.namespace(ns)
+	prev, exists := memories[key]
+	if !exists && len(memories) >= MaxPerNamespace {
+		return Memory{}, fmt.Errorf("too many memories (max %d); delete some first", MaxPerNamespace)
+	}
+
+	now := s.now()
+	m := &Memory{Namespace: ns, Key: key, Value: value, Origin: origin, CreatedAt: now, UpdatedAt: now, UsedAt: now}
+	if exists {
+		m.CreatedAt = prev.CreatedAt
+	}
+	memories[key] = m
+	if err := s.saveLocked(); err != nil {
+		if exists {
+			memories[key] = prev
+		} else {
+			delete(memories, key)
+		}
+		return Memory{}, err
+	}
+	return *m, nil
+}
+
+// Get returns the memory under key in ns and marks it used
+func (s *Store) Get(ns, key string) (Memory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.memories[ns][strings.TrimSpace(key)]
+	if !ok {
+		return Memory{}, ErrNotFound
+	}
+	m.UsedAt = s.now()
+	// Losing the use time only changes what a capped prompt keeps
+	s.saveLocked()
+	return *m, nil
+}
+
+// List returns the memories of ns, most recently used first
+func (s *Store) List(ns string) []Memory {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Memory, 0, len(s.memories[ns]))
+	for _, m := range s.memories[ns] {
+		list = append(list, *m)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if !list[i].UsedAt.Equal(list[j].UsedAt) {
+			return list[i].UsedAt.After(list[j].UsedAt)
+		}
+		return list[i].Key < list[j].Key
+	})
+	return list
+}
+
+// Delete removes the memory under key in ns
+func (s *Store) Delete(ns, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key = strings.TrimSpace(key)
+	m, ok := s.memories[ns][key]
+	if !ok {
+		return ErrNotFound
+	}
+	delete(s.memories[ns], key)
+	if err := s.saveLocked(); err != nil {
+		s.memories[ns][key] = m
+		return err
+	}
+	return nil
+}
+
+// PromptSection returns the "User Memory" section of the system prompt for
+// ns: one line per memory, most recently used first, in at most maxBytes.
+// Memories past the cap are left out with a note. It returns "" when ns
+// has no memories.
+func (s *Store) PromptSection(ns string, maxBytes int) string {
+	if s == nil {
+		return ""
+	}
+	list := s.List(ns)
+	if len(list) == 0 {
+		return ""
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultPromptBytes
+	}
+
+	header := "## User Memory\nThe user asked you to remember these across sessions. Follow them unless told otherwise, and keep them current with the Memory tool.\n"
+	lines := make([]string, len(list))
+	for i, m := range list {
+		lines[i] = fmt.Sprintf("- %s: %s\n", m.Key, strings.ReplaceAll(m.Value, "\n", " "))
+	}
+	// Keep as many memories as fit along with the note on the rest
+	for n := len(lines); ; n-- {
+		text := header + strings.Join(lines[:n], "")
+		if n < len(lines) {
+			text += fmt.Sprintf("- (%d more; list them with the Memory tool)\n", len(lines)-n)
+		}
+		if len(text) <= maxBytes || n == 0 {
+			return strings.TrimSuffix(text, "\n")
+		}
+	}
+}
+
+func (s *Store) saveLocked() error {
+	var memories []*Memory
+	for _, ns := range s.memories {
+		for _, m := range ns {
+			memories = append(memories, m)
+		}
+	}
+	sort.Slice(memories, func(i, j int) bool {
+		if memories[i].Namespace != memories[j].Namespace {
+			return memories[i].Namespace < memories[j].Namespace
+		}
+		return memories[i].Key < memories[j].Key
+	})
+
+	data, err := json.MarshalIndent(memories, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestStore returns a store in a temp dir whose clock advances a
+// second on every read
+func newTestStore(t *testing.T) (*Store, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "memory.json")
+	s, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tick(s)
+	return s, path
+}
+
+func tick(s *Store) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func TestSetGetListDelete(t *testing.T) {
+	s, _ := newTestStore(t)
+
+	if _, err := s.Set("", " package_manager ", "pnpm", FromUser); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Set("", "tests", "in /e2e", FromModel); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.Get("", "package_manager")
+	if err != nil || m.Value != "pnpm" || m.Origin != FromUser {
+		t.Fatalf("Expected the trimmed key to be stored, got %+v, %v", m, err)
+	}
+
+	// Replacing keeps the creation time and takes the new origin
+	updated, err := s.Set("", "package_manager", "bun", FromModel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.CreatedAt.Equal(m.CreatedAt) || !updated.UpdatedAt.After(m.UpdatedAt) || updated.Origin != FromModel {
+		t.Errorf("Unexpected replacement %+v of %+v", updated, m)
+	}
+
+	list := s.List("")
+	if len(list) != 2 || list[0].Key != "package_manager" || list[1].Key != "tests" {
+		t.Errorf("Expected the most recently used first, got %+v", list)
+	}
+
+	if err := s.Delete("", "tests"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("", "tests"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after delete, got %v", err)
+	}
+	if err := s.Delete("", "tests"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a second delete, got %v", err)
+	}
+}
+
+func TestSetValidates(t *testing.T) {
+	s, _ := newTestStore(t)
+	for _, tc := range []struct{ key, value string }{
+		{"", "value"},
+		{"key", "  "},
+		{"two\nlines", "value"},
+		{strings.Repeat("k", MaxKeyLen+1), "value"},
+		{"key", strings.Repeat("v", MaxValueLen+1)},
+	} {
+		if _, err := s.Set("", tc.key, tc.value, FromUser); err == nil {
+			t.Errorf("Expected Set(%q, %q) to fail", tc.key, tc.value)
+		}
+	}
+
+	for i := range MaxPerNamespace {
+		if _, err := s.Set("alice", fmt.Sprintf("key%d", i), "value", FromUser); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.Set("alice", "one more", "value", FromUser); err == nil {
+		t.Error("Expected a full namespace to refuse new keys")
+	}
+	if _, err := s.Set("alice", "key0", "changed", FromUser); err != nil {
+		t.Errorf("Expected a full namespace to accept updates, got %v", err)
+	}
+}
+
+func TestNamespacesAreSeparate(t *testing.T) {
+	s, _ := newTestStore(t)
+	s.Set("alice", "language", "Japanese", FromUser)
+	s.Set("bob", "language", "English", FromUser)
+
+	if m, _ := s.Get("alice", "language"); m.Value != "Japanese" {
+		t.Errorf("Expected alice's memory, got %+v", m)
+	}
+	if list := s.List(""); len(list) != 0 {
+		t.Errorf("Expected the default namespace empty, got %+v", list)
+	}
+	s.Delete("bob", "language")
+	if _, err := s.Get("alice", "language"); err != nil {
+		t.Errorf("Expected bob's delete to leave alice's memory, got %v", err)
+	}
+}
+
+func TestPersistsAcrossRestarts(t *testing.T) {
+	s, path := newTestStore(t)
+	s.Set("", "package_manager", "pnpm", FromUser)
+	s.Set("alice", "language", "Japanese", FromModel)
+	s.Set("", "tests", "in /e2e", FromUser)
+	s.Delete("", "tests")
+	s.Get("", "package_manager") // Marks it used after alice's memory
+
+	reopened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reopened.List(""); len(got) != 1 || got[0].Value != "pnpm" || !got[0].UsedAt.Equal(s.List("")[0].UsedAt) {
+		t.Errorf("Expected the memory and its use time back, got %+v", got)
+	}
+	if got := reopened.List("alice"); len(got) != 1 || got[0].Origin != FromModel {
+		t.Errorf("Expected alice's memory with its origin back, got %+v", got)
+	}
+}
+
+func TestPromptSection(t *testing.T) {
+	s, _ := newTestStore(t)
+	if got := s.PromptSection("", 0); got != "" {
+		t.Errorf("Expected no section without memories, got %q", got)
+	}
+	var none *Store
+	if got := none.PromptSection("", 0); got != "" {
+		t.Errorf("Expected no section from a nil store, got %q", got)
+	}
+
+	s.Set("", "package_manager", "pnpm", FromUser)
+	s.Set("", "tests", "live in /e2e", FromUser)
+	s.Set("", "language", "respond in\nJapanese", FromModel)
+	s.Get("", "package_manager")
+
+	full := s.PromptSection("", 0)
+	if !strings.HasPrefix(full, "## User Memory\n") || !strings.HasSuffix(full, "- package_manager: pnpm\n- language: respond in Japanese\n- tests: live in /e2e") {
+		t.Errorf("Expected every memory, most recently used first, got:\n%s", full)
+	}
+
+	// Capped, the least recently used make way for a note
+	capped := s.PromptSection("", len(full)-1)
+	if len(capped) > len(full)-1 {
+		t.Errorf("Expected at most %d bytes, got %d", len(full)-1, len(capped))
+	}
+	if !strings.HasSuffix(capped, "\n- package_manager: pnpm\n- (2 more; list them with the Memory tool)") {
+		t.Errorf("Expected the most recently used memory and a note on the rest, got:\n%s", capped)
+	}
+}
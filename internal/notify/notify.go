@@ -0,0 +1,411 @@
+// Package notify delivers events about long-running operations, such as
+// version builds, self-improve pushes and scheduled jobs, to webhooks and
+// Slack. Delivery happens in the background: emitting an event never
+// waits on a receiver.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/ids"
+	"groq-go/internal/logging"
+)
+
+var log = logging.WithComponent("notify")
+
+// Event types
+const (
+	VersionBuilt         = "version.build_succeeded"
+	VersionBuildFailed   = "version.build_failed"
+	VersionStarted       = "version.started"
+	VersionStopped       = "version.stopped"
+	VersionPromoted      = "version.promoted"
+	VersionPromoteFailed = "version.promote_failed"
+	PushSucceeded        = "selfimprove.push_succeeded"
+	PushFailed           = "selfimprove.push_failed"
+	ScheduleSucceeded    = "schedule.run_succeeded"
+	ScheduleFailed       = "schedule.run_failed"
+	Test                 = "test"
+)
+
+// SchemaVersion is sent with every event and bumped on incompatible
+// changes to Event
+const SchemaVersion = 1
+
+// Headers set on webhook deliveries
+const (
+	HeaderEvent     = "X-Groq-Go-Event"
+	HeaderDelivery  = "X-Groq-Go-Delivery"
+	HeaderTimestamp = "X-Groq-Go-Timestamp"
+	HeaderSignature = "X-Groq-Go-Signature"
+)
+
+const (
+	// DefaultAttempts is how often a delivery is tried before it is
+	// written to the dead-letter log
+	DefaultAttempts = 3
+	defaultBackoff  = time.Second
+	// queueSize bounds the events waiting for delivery; more are
+	// dead-lettered rather than blocking the caller
+	queueSize       = 100
+	deliveryTimeout = 10 * time.Second
+	// closeTimeout is how long Close waits for queued events
+	closeTimeout = 5 * time.Second
+)
+
+// Event is the JSON body POSTed to webhook targets
+type Event struct {
+	ID     string    `json:"id"` // Unique per event, the same across retries
+	Type   string    `json:"type"`
+	Schema int       `json:"schema"`
+	Time   time.Time `json:"time"` // When the event happened
+	// StartedAt is when the operation began, for events that end one
+	StartedAt time.Time `json:"started_at,omitzero"`
+	Message   string    `json:"message"` // One line for people, used for Slack
+	Error     string    `json:"error,omitempty"`
+	// Refs identify what the event is about, e.g. version_id, commit,
+	// job_id or session_id
+	Refs map[string]string `json:"refs,omitempty"`
+}
+
+// Delivery is the outcome of sending an event to one target
+type Delivery struct {
+	Target   string `json:"target"`
+	Attempts int    `json:"attempts"`
+	Status   int    `json:"status,omitempty"` // HTTP status of the last attempt
+	Error    string `json:"error,omitempty"`
+}
+
+// deadLetter is a line of the dead-letter log
+type deadLetter struct {
+	Time     time.Time `json:"time"`
+	Target   string    `json:"target"`
+	Attempts int       `json:"attempts"`
+	Error    string    `json:"error"`
+	Event    Event     `json:"event"`
+}
+
+// Dispatcher sends events to the configured targets. A nil Dispatcher
+// drops every event, so managers work without one.
+type Dispatcher struct {
+	targets    []config.NotifyTarget
+	httpClient *http.Client
+	deadLetter string
+	attempts   int
+	backoff    time.Duration
+
+	queue  chan Event
+	mu     sync.RWMutex // Guards closed against Emit racing Close
+	closed bool
+	fileMu sync.Mutex // Serializes dead-letter writes
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// Option configures a Dispatcher
+type Option func(*Dispatcher)
+
+// WithDeadLetter sets the file undeliverable events are appended to
+func WithDeadLetter(path string) Option {
+	return func(d *Dispatcher) { d.deadLetter = path }
+}
+
+// WithRetry sets the attempts per delivery and the backoff before the
+// second one, which doubles after each failure
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.attempts = max(attempts, 1)
+		d.backoff = backoff
+	}
+}
+
+// WithHTTPClient sets the client deliveries are sent with
+func WithHTTPClient(c *http.Client) Option {
+	return func(d *Dispatcher) { d.httpClient = c }
+}
+
+// DefaultDeadLetterPath is where undeliverable events are logged
+func DefaultDeadLetterPath() string {
+	return filepath.Join(config.Dir(), "notifications-dead.jsonl")
+}
+
+// New starts a dispatcher for the configured targets. Close stops it.
+func New(cfg config.NotificationsConfig, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		targets:    cfg.Targets,
+		httpClient: &http.Client{Timeout: deliveryTimeout},
+		deadLetter: DefaultDeadLetterPath(),
+		attempts:   DefaultAttempts,
+		backoff:    defaultBackoff,
+		queue:      make(chan Event, queueSize),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
+
+	go func() {
+		defer close(d.done)
+		for e := range d.queue {
+			d.Send(d.ctx, e)
+		}
+	}()
+	return d
+}
+
+// Enabled reports whether any target is configured
+func (d *Dispatcher) Enabled() bool {
+	return d != nil && len(d.targets) > 0
+}
+
+// Emit queues an event for delivery and returns at once. ID, Schema and
+// Time are filled in when unset. Events that do not fit in the queue are
+// written to the dead-letter log.
+func (d *Dispatcher) Emit(e Event) {
+	if !d.Enabled() {
+		return
+	}
+	e = e.withDefaults()
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		return
+	}
+	select {
+	case d.queue <- e:
+	default:
+		log.Warn("Notification queue full, dropping event", "type", e.Type, "id", e.ID)
+		d.writeDeadLetter(e, "queue", 0, "queue full")
+	}
+}
+
+// Send delivers an event to every target that wants it, retrying
+// failures, and waits for the results. Deliveries that still fail are
+// written to the dead-letter log.
+func (d *Dispatcher) Send(ctx context.Context, e Event) []Delivery {
+	if !d.Enabled() {
+		return nil
+	}
+	e = e.withDefaults()
+
+	var results []Delivery
+	for _, t := range d.targets {
+		if !wants(t, e.Type) {
+			continue
+		}
+		res := d.deliver(ctx, t, e)
+		if res.Error != "" {
+			log.Warn("Notification failed", "target", res.Target, "type", e.Type, "attempts", res.Attempts, "error", res.Error)
+			d.writeDeadLetter(e, res.Target, res.Attempts, res.Error)
+		}
+		results = append(results, res)
+	}
+	return results
+}
+
+// Close stops accepting events and waits briefly for queued ones. Events
+// still pending after that are cancelled and dead-lettered.
+func (d *Dispatcher) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	close(d.queue)
+	d.mu.Unlock()
+
+	select {
+	case <-d.done:
+	case <-time.After(closeTimeout):
+		d.cancel()
+		<-d.done
+	}
+	d.cancel()
+}
+
+func (e Event) withDefaults() Event {
+	if e.ID == "" {
+		e.ID = ids.Prefixed("evt", 16)
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	e.Schema = SchemaVersion
+	return e
+}
+
+// wants reports whether t is subscribed to events of type typ
+func wants(t config.NotifyTarget, typ string) bool {
+	if len(t.Events) == 0 {
+		return true
+	}
+	for _, pattern := range t.Events {
+		if pattern == typ || pattern == "*" {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "*"); ok && strings.HasPrefix(typ, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliver sends e to t, retrying network errors, 429 and 5xx responses
+func (d *Dispatcher) deliver(ctx context.Context, t config.NotifyTarget, e Event) Delivery {
+	res := Delivery{Target: targetName(t)}
+	body, err := payload(t, e)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	backoff := d.backoff
+	for res.Attempts < d.attempts {
+		if res.Attempts > 0 {
+			select {
+			case <-ctx.Done():
+				res.Error = ctx.Err().Error()
+				return res
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		res.Attempts++
+
+		status, err := d.post(ctx, t, e, body)
+		res.Status = status
+		switch {
+		case err != nil:
+			res.Error = err.Error()
+		case status >= 200 && status < 300:
+			res.Error = ""
+			return res
+		default:
+			res.Error = fmt.Sprintf("receiver answered %d", status)
+			if status != http.StatusTooManyRequests && status < 500 {
+				return res
+			}
+		}
+	}
+	return res
+}
+
+func (d *Dispatcher) post(ctx context.Context, t config.NotifyTarget, e Event, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "groq-go")
+	if t.Type != "slack" {
+		req.Header.Set(HeaderEvent, e.Type)
+		req.Header.Set(HeaderDelivery, e.ID)
+		if t.Secret != "" {
+			ts := time.Now().Unix()
+			req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+			req.Header.Set(HeaderSignature, Sign(t.Secret, ts, body))
+		}
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	return resp.StatusCode, nil
+}
+
+// payload is the request body for t: the event itself for webhooks, a
+// message for Slack
+func payload(t config.NotifyTarget, e Event) ([]byte, error) {
+	if t.Type != "slack" {
+		return json.Marshal(e)
+	}
+	text := fmt.Sprintf("*%s* %s", e.Type, e.Message)
+	if e.Error != "" {
+		text += "\n```" + e.Error + "```"
+	}
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// targetName names t in logs without leaking its URL, which is a secret
+// for Slack
+func targetName(t config.NotifyTarget) string {
+	if t.Name != "" {
+		return t.Name
+	}
+	if u, err := url.Parse(t.URL); err == nil && u.Host != "" {
+		return u.Host
+	}
+	return "webhook"
+}
+
+// Sign returns the signature header value for a webhook body sent at
+// timestamp: "sha256=" and the hex HMAC-SHA256 of "<timestamp>.<body>"
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature and that its timestamp is within
+// tolerance of now, for receivers written in Go
+func Verify(secret string, r *http.Request, body []byte, tolerance time.Duration) bool {
+	ts, err := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(r.Header.Get(HeaderSignature)), []byte(Sign(secret, ts, body)))
+}
+
+func (d *Dispatcher) writeDeadLetter(e Event, target string, attempts int, reason string) {
+	if d.deadLetter == "" {
+		return
+	}
+	line, err := json.Marshal(deadLetter{Time: time.Now().UTC(), Target: target, Attempts: attempts, Error: reason, Event: e})
+	if err != nil {
+		return
+	}
+
+	d.fileMu.Lock()
+	defer d.fileMu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(d.deadLetter), 0755); err != nil {
+		log.Error("Failed to write dead letter", "error", err)
+		return
+	}
+	f, err := os.OpenFile(d.deadLetter, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		log.Error("Failed to write dead letter", "error", err)
+		return
+	}
+	defer f.Close()
+	f.Write(append(line, '\n'))
+}
@@ -0,0 +1,210 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"groq-go/internal/config"
+)
+
+// receiver records deliveries and answers with the statuses in order,
+// then 200
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func newReceiver(t *testing.T, statuses ...int) *receiver {
+	t.Helper()
+	rec := &receiver{statuses: statuses}
+	rec.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		rec.mu.Lock()
+		defer rec.mu.Unlock()
+		rec.requests = append(rec.requests, r)
+		rec.bodies = append(rec.bodies, body)
+		if len(rec.statuses) > 0 {
+			w.WriteHeader(rec.statuses[0])
+			rec.statuses = rec.statuses[1:]
+		}
+	}))
+	t.Cleanup(rec.Close)
+	return rec
+}
+
+func (rec *receiver) count() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.requests)
+}
+
+func newTestDispatcher(t *testing.T, targets ...config.NotifyTarget) (*Dispatcher, string) {
+	t.Helper()
+	deadLetter := filepath.Join(t.TempDir(), "dead.jsonl")
+	d := New(config.NotificationsConfig{Targets: targets}, WithDeadLetter(deadLetter), WithRetry(3, time.Millisecond))
+	t.Cleanup(d.Close)
+	return d, deadLetter
+}
+
+func TestWebhookIsSigned(t *testing.T) {
+	rec := newReceiver(t)
+	d, _ := newTestDispatcher(t, config.NotifyTarget{Name: "ci", URL: rec.URL, Secret: "s3cret"})
+
+	results := d.Send(context.Background(), Event{
+		Type:    VersionBuilt,
+		Message: "Version v1 built",
+		Refs:    map[string]string{"version_id": "abc123"},
+	})
+	if len(results) != 1 || results[0].Error != "" || results[0].Attempts != 1 || results[0].Target != "ci" {
+		t.Fatalf("Unexpected deliveries %+v", results)
+	}
+
+	r, body := rec.requests[0], rec.bodies[0]
+	if !Verify("s3cret", r, body, time.Minute) {
+		t.Error("Expected a valid signature")
+	}
+	if Verify("wrong", r, body, time.Minute) {
+		t.Error("Expected the wrong secret to fail verification")
+	}
+	if Verify("s3cret", r, append(body, ' '), time.Minute) {
+		t.Error("Expected a modified body to fail verification")
+	}
+
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		t.Fatal(err)
+	}
+	if e.Type != VersionBuilt || e.Schema != SchemaVersion || e.Refs["version_id"] != "abc123" || e.Time.IsZero() || !strings.HasPrefix(e.ID, "evt_") {
+		t.Errorf("Unexpected event %+v", e)
+	}
+	if r.Header.Get(HeaderEvent) != VersionBuilt || r.Header.Get(HeaderDelivery) != e.ID {
+		t.Errorf("Unexpected headers %v", r.Header)
+	}
+	// The JSON schema is stable: unset timestamps are left out
+	if strings.Contains(string(body), "started_at") {
+		t.Errorf("Expected no started_at, got %s", body)
+	}
+}
+
+func TestRetriesServerErrors(t *testing.T) {
+	rec := newReceiver(t, 500, 503)
+	d, deadLetterPath := newTestDispatcher(t, config.NotifyTarget{URL: rec.URL})
+
+	results := d.Send(context.Background(), Event{Type: Test})
+	if len(results) != 1 || results[0].Error != "" || results[0].Attempts != 3 || results[0].Status != 200 {
+		t.Fatalf("Expected success on the third attempt, got %+v", results)
+	}
+	// Every attempt carries the same event ID so receivers can dedupe
+	var first, last Event
+	json.Unmarshal(rec.bodies[0], &first)
+	json.Unmarshal(rec.bodies[2], &last)
+	if first.ID == "" || first.ID != last.ID {
+		t.Errorf("Expected retries to resend the same event, got %q and %q", first.ID, last.ID)
+	}
+	if _, err := os.Stat(deadLetterPath); !os.IsNotExist(err) {
+		t.Error("Expected no dead letter for a delivered event")
+	}
+}
+
+func TestDeadLetter(t *testing.T) {
+	failing := newReceiver(t, 500, 500, 500)
+	rejecting := newReceiver(t, 400)
+	d, deadLetterPath := newTestDispatcher(t,
+		config.NotifyTarget{Name: "down", URL: failing.URL},
+		config.NotifyTarget{Name: "strict", URL: rejecting.URL},
+	)
+
+	results := d.Send(context.Background(), Event{Type: ScheduleFailed, Error: "API error: 401"})
+	if len(results) != 2 || results[0].Attempts != 3 || results[1].Attempts != 1 {
+		t.Fatalf("Expected 5xx retried and 4xx not, got %+v", results)
+	}
+
+	data, err := os.ReadFile(deadLetterPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 dead letters, got %q", data)
+	}
+	var dl deadLetter
+	if err := json.Unmarshal([]byte(lines[0]), &dl); err != nil {
+		t.Fatal(err)
+	}
+	if dl.Target != "down" || dl.Attempts != 3 || dl.Event.Type != ScheduleFailed || !strings.Contains(dl.Error, "500") {
+		t.Errorf("Unexpected dead letter %+v", dl)
+	}
+}
+
+func TestEmitDoesNotBlock(t *testing.T) {
+	release := make(chan struct{})
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer hanging.Close()
+	defer close(release)
+
+	d, deadLetterPath := newTestDispatcher(t, config.NotifyTarget{URL: hanging.URL})
+	start := time.Now()
+	for range queueSize + 10 {
+		d.Emit(Event{Type: VersionStarted})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected Emit to return at once, took %s", elapsed)
+	}
+	// Events beyond the queue are dead-lettered, not waited on
+	if data, _ := os.ReadFile(deadLetterPath); !strings.Contains(string(data), "queue full") {
+		t.Errorf("Expected overflow in the dead-letter log, got %q", data)
+	}
+}
+
+func TestEmitDelivers(t *testing.T) {
+	rec := newReceiver(t)
+	d, _ := newTestDispatcher(t, config.NotifyTarget{URL: rec.URL})
+	d.Emit(Event{Type: PushSucceeded})
+	d.Close()
+	if rec.count() != 1 {
+		t.Errorf("Expected Close to flush the queued event, got %d deliveries", rec.count())
+	}
+	// Closed dispatchers and nil ones drop events
+	d.Emit(Event{Type: PushSucceeded})
+	var nilDispatcher *Dispatcher
+	nilDispatcher.Emit(Event{Type: PushSucceeded})
+	nilDispatcher.Close()
+}
+
+func TestSlackAndEventFilter(t *testing.T) {
+	slack := newReceiver(t)
+	builds := newReceiver(t)
+	d, _ := newTestDispatcher(t,
+		config.NotifyTarget{Type: "slack", URL: slack.URL},
+		config.NotifyTarget{URL: builds.URL, Events: []string{"version.*"}},
+	)
+
+	d.Send(context.Background(), Event{Type: ScheduleFailed, Message: "Scheduled job nightly failed", Error: "timeout"})
+	d.Send(context.Background(), Event{Type: VersionBuildFailed, Message: "Build of version v2 failed"})
+
+	if slack.count() != 2 || builds.count() != 1 {
+		t.Fatalf("Expected slack to get both events and the filtered target one, got %d and %d", slack.count(), builds.count())
+	}
+	var msg map[string]string
+	json.Unmarshal(slack.bodies[0], &msg)
+	if !strings.Contains(msg["text"], "Scheduled job nightly failed") || !strings.Contains(msg["text"], "timeout") {
+		t.Errorf("Unexpected Slack message %q", msg["text"])
+	}
+	if slack.requests[0].Header.Get(HeaderSignature) != "" {
+		t.Error("Expected Slack messages unsigned")
+	}
+}
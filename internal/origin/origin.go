@@ -0,0 +1,150 @@
+// Package origin decides which browser origins may open WebSocket
+// connections. Origins are parsed and matched by exact host, never by
+// substring, so lookalikes such as chatweb.ai.evil.com are rejected.
+package origin
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// pattern is one allowed origin
+type pattern struct {
+	scheme   string // Empty matches http and https
+	host     string // Lowercase hostname, without "*." for wildcards
+	port     string // Empty matches any port
+	wildcard bool   // Matches subdomains of host, not host itself
+}
+
+// Policy holds the allowed origins. A nil or empty Policy allows only the
+// host the request was sent to.
+type Policy struct {
+	patterns []pattern
+}
+
+// NewPolicy parses allowed origins. Each entry is a full origin
+// ("https://chatweb.ai", port included if not the default), a host that
+// matches on any port ("localhost"), a host and port ("localhost:8080"),
+// or a wildcard for subdomains ("*.chatweb.ai").
+func NewPolicy(allowed []string) (*Policy, error) {
+	p := &Policy{}
+	for _, entry := range allowed {
+		pat, err := parsePattern(entry)
+		if err != nil {
+			return nil, err
+		}
+		p.patterns = append(p.patterns, pat)
+	}
+	return p, nil
+}
+
+func parsePattern(entry string) (pattern, error) {
+	entry = strings.ToLower(strings.TrimSpace(entry))
+	var pat pattern
+	if strings.Contains(entry, "://") {
+		u, err := parseOrigin(entry)
+		if err != nil {
+			return pattern{}, fmt.Errorf("invalid allowed origin %q: %w", entry, err)
+		}
+		pat.scheme, pat.port = u.Scheme, portOf(u)
+		entry = u.Hostname()
+	} else if host, port, err := net.SplitHostPort(entry); err == nil {
+		if port == "" {
+			return pattern{}, fmt.Errorf("invalid allowed origin %q: empty port", entry)
+		}
+		entry, pat.port = host, port
+	}
+
+	entry = strings.Trim(entry, "[]") // IPv6 literals
+	if rest, ok := strings.CutPrefix(entry, "*."); ok {
+		entry, pat.wildcard = rest, true
+	}
+	if entry == "" || strings.ContainsAny(entry, "*/@ ") || strings.Contains(entry, "://") {
+		return pattern{}, fmt.Errorf("invalid allowed origin %q: expected a host such as chatweb.ai, *.chatweb.ai or https://chatweb.ai", entry)
+	}
+	pat.host = entry
+	return pat, nil
+}
+
+// parseOrigin parses an Origin header value, which is only a scheme, host
+// and optional port
+func parseOrigin(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return nil, fmt.Errorf("scheme must be http or https")
+	case u.User != nil:
+		return nil, fmt.Errorf("must not contain credentials")
+	case u.Hostname() == "":
+		return nil, fmt.Errorf("missing host")
+	case u.Path != "" && u.Path != "/", u.RawQuery != "", u.Fragment != "":
+		return nil, fmt.Errorf("must not contain a path")
+	}
+	return u, nil
+}
+
+// portOf returns the explicit port of u, or its scheme's default
+func portOf(u *url.URL) string {
+	if port := u.Port(); port != "" {
+		return port
+	}
+	if u.Scheme == "https" {
+		return "443"
+	}
+	return "80"
+}
+
+// Allow reports whether r may be upgraded. Requests without an Origin
+// header come from non-browser clients and are allowed; "null" and
+// malformed origins are not.
+func (p *Policy) Allow(r *http.Request) bool {
+	header := r.Header.Get("Origin")
+	if header == "" {
+		return true
+	}
+	u, err := parseOrigin(strings.ToLower(header))
+	if err != nil {
+		return false
+	}
+	if p == nil || len(p.patterns) == 0 {
+		return sameHost(u, r.Host)
+	}
+	for _, pat := range p.patterns {
+		if pat.matches(u) {
+			return true
+		}
+	}
+	return false
+}
+
+func (pat pattern) matches(u *url.URL) bool {
+	if pat.scheme != "" && pat.scheme != u.Scheme {
+		return false
+	}
+	if pat.port != "" && pat.port != portOf(u) {
+		return false
+	}
+	host := u.Hostname()
+	if pat.wildcard {
+		return strings.HasSuffix(host, "."+pat.host)
+	}
+	return host == pat.host
+}
+
+// sameHost reports whether the origin names the host the request was sent
+// to. A Host without a port, as behind a TLS-terminating proxy, only
+// matches an origin on its scheme's default port.
+func sameHost(u *url.URL, requestHost string) bool {
+	requestHost = strings.ToLower(requestHost)
+	host, port, err := net.SplitHostPort(requestHost)
+	if err != nil {
+		return u.Port() == "" && u.Hostname() == strings.Trim(requestHost, "[]")
+	}
+	return u.Hostname() == host && portOf(u) == port
+}
@@ -0,0 +1,268 @@
+// Package payments creates Stripe Checkout Sessions for credit packs and
+// verifies the webhooks Stripe sends when they are paid.
+package payments
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the Stripe API endpoint
+	DefaultBaseURL = "https://api.stripe.com"
+
+	// SignatureTolerance is how old a webhook timestamp may be
+	SignatureTolerance = 5 * time.Minute
+
+	// EventCheckoutCompleted is sent when a Checkout Session finishes
+	EventCheckoutCompleted = "checkout.session.completed"
+	// EventCheckoutAsyncSucceeded is sent when a delayed payment method,
+	// such as a bank debit or konbini, settles after the session finished
+	EventCheckoutAsyncSucceeded = "checkout.session.async_payment_succeeded"
+)
+
+var (
+	// ErrNotConfigured is returned when STRIPE_SECRET_KEY is not set
+	ErrNotConfigured = errors.New("payments not configured")
+	// ErrInvalidSignature is returned for webhooks that fail verification
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Pack is a credit pack sold for a Stripe price
+type Pack struct {
+	Name    string `json:"name"`
+	PriceID string `json:"price_id"`
+	Credits int    `json:"credits"`
+}
+
+// Config holds the Stripe keys and the packs on sale
+type Config struct {
+	SecretKey     string
+	WebhookSecret string
+	Packs         []Pack
+	BaseURL       string
+}
+
+// ConfigFromEnv reads STRIPE_SECRET_KEY, STRIPE_WEBHOOK_SECRET and
+// STRIPE_CREDIT_PACKS. Packs are comma-separated name=price_id:credits,
+// e.g. "small=price_123:500,large=price_456:3000".
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		SecretKey:     os.Getenv("STRIPE_SECRET_KEY"),
+		WebhookSecret: os.Getenv("STRIPE_WEBHOOK_SECRET"),
+	}
+	packs, err := ParsePacks(os.Getenv("STRIPE_CREDIT_PACKS"))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Packs = packs
+	return cfg, nil
+}
+
+// ParsePacks parses the STRIPE_CREDIT_PACKS format
+func ParsePacks(s string) ([]Pack, error) {
+	var packs []Pack
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, rest, ok := strings.Cut(entry, "=")
+		priceID, credits, ok2 := strings.Cut(rest, ":")
+		n, err := strconv.Atoi(credits)
+		if !ok || !ok2 || name == "" || priceID == "" || err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid credit pack %q: want name=price_id:credits", entry)
+		}
+		packs = append(packs, Pack{Name: name, PriceID: priceID, Credits: n})
+	}
+	return packs, nil
+}
+
+// Stripe is a minimal client for Checkout Sessions and webhooks
+type Stripe struct {
+	cfg        Config
+	httpClient *http.Client
+	now        func() time.Time
+}
+
+// NewStripe creates a client for cfg
+func NewStripe(cfg Config) *Stripe {
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultBaseURL
+	}
+	return &Stripe{
+		cfg:        cfg,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+		now:        time.Now,
+	}
+}
+
+// Enabled reports whether checkout can be used
+func (s *Stripe) Enabled() bool {
+	return s != nil && s.cfg.SecretKey != "" && len(s.cfg.Packs) > 0
+}
+
+// Packs returns the packs on sale
+func (s *Stripe) Packs() []Pack {
+	return append([]Pack(nil), s.cfg.Packs...)
+}
+
+// Pack looks up a pack by name
+func (s *Stripe) Pack(name string) (Pack, bool) {
+	for _, p := range s.cfg.Packs {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Pack{}, false
+}
+
+// CheckoutSession is the part of a Stripe Checkout Session we use
+type CheckoutSession struct {
+	ID                string            `json:"id"`
+	URL               string            `json:"url"`
+	ClientReferenceID string            `json:"client_reference_id"`
+	PaymentStatus     string            `json:"payment_status"`
+	Metadata          map[string]string `json:"metadata"`
+}
+
+// CreateCheckoutSession starts a one-off payment for pack by userID. The
+// user ID and credit amount travel in the session metadata and come back
+// in the webhook.
+func (s *Stripe) CreateCheckoutSession(ctx context.Context, pack Pack, userID, successURL, cancelURL string) (*CheckoutSession, error) {
+	if s == nil || s.cfg.SecretKey == "" {
+		return nil, ErrNotConfigured
+	}
+
+	form := url.Values{}
+	form.Set("mode", "payment")
+	form.Set("line_items[0][price]", pack.PriceID)
+	form.Set("line_items[0][quantity]", "1")
+	form.Set("success_url", successURL)
+	form.Set("cancel_url", cancelURL)
+	form.Set("client_reference_id", userID)
+	form.Set("metadata[user_id]", userID)
+	form.Set("metadata[pack]", pack.Name)
+	form.Set("metadata[credits]", strconv.Itoa(pack.Credits))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.BaseURL+"/v1/checkout/sessions", strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.SetBasicAuth(s.cfg.SecretKey, "")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("stripe request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stripe response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		json.Unmarshal(body, &apiErr)
+		return nil, fmt.Errorf("stripe error (status %d): %s", resp.StatusCode, apiErr.Error.Message)
+	}
+
+	var session CheckoutSession
+	if err := json.Unmarshal(body, &session); err != nil {
+		return nil, fmt.Errorf("failed to parse stripe response: %w", err)
+	}
+	return &session, nil
+}
+
+// Event is a Stripe webhook event
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Data struct {
+		Object json.RawMessage `json:"object"`
+	} `json:"data"`
+}
+
+// ParseWebhook verifies the Stripe-Signature header against the webhook
+// secret and decodes the event
+func (s *Stripe) ParseWebhook(payload []byte, sigHeader string) (*Event, error) {
+	if s == nil || s.cfg.WebhookSecret == "" {
+		return nil, ErrNotConfigured
+	}
+	if err := VerifySignature(payload, sigHeader, s.cfg.WebhookSecret, s.now(), SignatureTolerance); err != nil {
+		return nil, err
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("failed to parse event: %w", err)
+	}
+	if event.ID == "" {
+		return nil, fmt.Errorf("event without id")
+	}
+	return &event, nil
+}
+
+// VerifySignature checks a Stripe-Signature header of the form
+// "t=<unix>,v1=<hex>[,v1=...]". The signature is an HMAC-SHA256 of
+// "<t>.<payload>" with secret, and t must be within tolerance of now.
+func VerifySignature(payload []byte, header, secret string, now time.Time, tolerance time.Duration) error {
+	var timestamp string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+	if timestamp == "" || len(signatures) == 0 {
+		return fmt.Errorf("%w: missing timestamp or signature", ErrInvalidSignature)
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: bad timestamp", ErrInvalidSignature)
+	}
+	if age := now.Sub(time.Unix(ts, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := Sign(payload, secret, ts)
+	for _, sig := range signatures {
+		if hmac.Equal([]byte(sig), []byte(expected)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: no matching signature", ErrInvalidSignature)
+}
+
+// Sign returns the v1 signature of payload at timestamp ts
+func Sign(payload []byte, secret string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", ts)
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
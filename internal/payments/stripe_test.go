@@ -0,0 +1,90 @@
+package payments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestParsePacks(t *testing.T) {
+	packs, err := ParsePacks("small=price_1:500, large=price_2:3000")
+	if err != nil {
+		t.Fatalf("ParsePacks failed: %v", err)
+	}
+	if len(packs) != 2 || packs[1] != (Pack{Name: "large", PriceID: "price_2", Credits: 3000}) {
+		t.Errorf("Unexpected packs: %+v", packs)
+	}
+	for _, bad := range []string{"small", "small=price_1", "small=price_1:x", "=price_1:5", "small=price_1:0"} {
+		if _, err := ParsePacks(bad); err == nil {
+			t.Errorf("Expected error for %q", bad)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	payload := []byte(`{"id":"evt_1"}`)
+	now := time.Unix(1700000000, 0)
+	sig := Sign(payload, "whsec_test", now.Unix())
+
+	tests := []struct {
+		name    string
+		header  string
+		payload []byte
+		ok      bool
+	}{
+		{"valid", fmt.Sprintf("t=%d,v1=%s", now.Unix(), sig), payload, true},
+		{"one of several signatures", fmt.Sprintf("t=%d,v1=deadbeef,v1=%s", now.Unix(), sig), payload, true},
+		{"tampered payload", fmt.Sprintf("t=%d,v1=%s", now.Unix(), sig), []byte(`{"id":"evt_2"}`), false},
+		{"wrong secret", fmt.Sprintf("t=%d,v1=%s", now.Unix(), Sign(payload, "other", now.Unix())), payload, false},
+		{"stale timestamp", fmt.Sprintf("t=%d,v1=%s", now.Add(-time.Hour).Unix(), Sign(payload, "whsec_test", now.Add(-time.Hour).Unix())), payload, false},
+		{"missing signature", fmt.Sprintf("t=%d", now.Unix()), payload, false},
+		{"empty header", "", payload, false},
+	}
+	for _, tt := range tests {
+		err := VerifySignature(tt.payload, tt.header, "whsec_test", now, SignatureTolerance)
+		if tt.ok && err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected ErrInvalidSignature, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestCreateCheckoutSession(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, _ := r.BasicAuth(); user != "sk_test" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"bad key"}}`)
+			return
+		}
+		r.ParseForm()
+		if r.URL.Path != "/v1/checkout/sessions" || r.Form.Get("line_items[0][price]") != "price_1" ||
+			r.Form.Get("metadata[user_id]") != "acct_alice" || r.Form.Get("metadata[credits]") != "500" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprintf(w, `{"error":{"message":"unexpected request %s"}}`, r.Form.Encode())
+			return
+		}
+		fmt.Fprint(w, `{"id":"cs_1","url":"https://checkout.stripe.com/c/cs_1"}`)
+	}))
+	defer api.Close()
+
+	pack := Pack{Name: "small", PriceID: "price_1", Credits: 500}
+	s := NewStripe(Config{SecretKey: "sk_test", Packs: []Pack{pack}, BaseURL: api.URL})
+	session, err := s.CreateCheckoutSession(context.Background(), pack, "acct_alice", "https://x/ok", "https://x/cancel")
+	if err != nil {
+		t.Fatalf("CreateCheckoutSession failed: %v", err)
+	}
+	if session.ID != "cs_1" || session.URL == "" {
+		t.Errorf("Unexpected session %+v", session)
+	}
+
+	s = NewStripe(Config{SecretKey: "sk_wrong", BaseURL: api.URL})
+	if _, err := s.CreateCheckoutSession(context.Background(), pack, "acct_alice", "", ""); err == nil {
+		t.Error("Expected error for rejected key")
+	}
+}
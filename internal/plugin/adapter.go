@@ -45,6 +45,12 @@ func (a *PluginToolAdapter) Parameters() map[string]any {
 	return a.toolDef.Parameters
 }
 
+// Available reports whether the plugin is enabled; plugins disabled by
+// the circuit breaker stop offering their tools
+func (a *PluginToolAdapter) Available() bool {
+	return a.manager.IsEnabled(a.pluginName)
+}
+
 // Execute executes the plugin tool
 func (a *PluginToolAdapter) Execute(ctx context.Context, args json.RawMessage) (tool.Result, error) {
 	resp, err := a.manager.ExecuteTool(ctx, a.pluginName, a.toolDef.Name, args)
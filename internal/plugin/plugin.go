@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -23,6 +24,18 @@ type Plugin struct {
 	Enabled     bool              `json:"enabled" yaml:"enabled"`
 	Tools       []PluginTool      `json:"tools" yaml:"tools"`
 	Headers     map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Timeout bounds each tool call in seconds; 0 uses DefaultTimeout
+	Timeout int `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+	// DisabledReason records why the circuit breaker disabled the plugin
+	DisabledReason string `json:"disabled_reason,omitempty" yaml:"disabled_reason,omitempty"`
+}
+
+// timeout returns the per-call limit for the plugin
+func (p *Plugin) timeout() time.Duration {
+	if p.Timeout > 0 {
+		return time.Duration(p.Timeout) * time.Second
+	}
+	return DefaultTimeout
 }
 
 // PluginTool represents a tool exposed by a plugin
@@ -38,12 +51,35 @@ type PluginResponse struct {
 	IsError bool   `json:"is_error"`
 }
 
+// HealthStatus is the outcome of a plugin health check
+type HealthStatus struct {
+	Healthy    bool   `json:"healthy"`
+	StatusCode int    `json:"status_code,omitempty"`
+	LatencyMS  int64  `json:"latency_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+const (
+	// DefaultTimeout bounds plugin calls when the plugin sets no timeout
+	DefaultTimeout = 30 * time.Second
+
+	// DefaultMaxFailures is how many consecutive failed calls disable a plugin
+	DefaultMaxFailures = 3
+
+	// healthTimeout bounds a health check
+	healthTimeout = 5 * time.Second
+)
+
 // Manager manages plugins
 type Manager struct {
 	plugins    map[string]*Plugin
 	configPath string
 	httpClient *http.Client
 	mu         sync.RWMutex
+
+	// failures counts consecutive failed calls per plugin
+	failures    map[string]int
+	maxFailures int
 }
 
 // NewManager creates a new plugin manager
@@ -53,14 +89,17 @@ func NewManager() (*Manager, error) {
 		home = "."
 	}
 
-	configPath := filepath.Join(home, ".config", "groq-go", "plugins.yaml")
+	return newManager(filepath.Join(home, ".config", "groq-go", "plugins.yaml"))
+}
 
+func newManager(configPath string) (*Manager, error) {
+	// Calls are bounded per plugin through their contexts
 	m := &Manager{
-		plugins:    make(map[string]*Plugin),
-		configPath: configPath,
-		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
+		plugins:     make(map[string]*Plugin),
+		configPath:  configPath,
+		httpClient:  &http.Client{},
+		failures:    make(map[string]int),
+		maxFailures: DefaultMaxFailures,
 	}
 
 	// Load existing config
@@ -97,11 +136,8 @@ func (m *Manager) loadConfig() error {
 	return nil
 }
 
-// saveConfig saves plugins to config file
+// saveConfig saves plugins to config file. The caller must hold m.mu.
 func (m *Manager) saveConfig() error {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
 	var plugins []Plugin
 	for _, p := range m.plugins {
 		plugins = append(plugins, *p)
@@ -145,9 +181,10 @@ func (m *Manager) AddPlugin(plugin *Plugin) error {
 // RemovePlugin removes a plugin
 func (m *Manager) RemovePlugin(name string) error {
 	m.mu.Lock()
-	delete(m.plugins, name)
-	m.mu.Unlock()
+	defer m.mu.Unlock()
 
+	delete(m.plugins, name)
+	delete(m.failures, name)
 	return m.saveConfig()
 }
 
@@ -172,7 +209,16 @@ func (m *Manager) ListPlugins() []*Plugin {
 	return plugins
 }
 
-// EnablePlugin enables a plugin
+// IsEnabled reports whether the named plugin exists and is enabled
+func (m *Manager) IsEnabled(name string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	p, ok := m.plugins[name]
+	return ok && p.Enabled
+}
+
+// EnablePlugin enables a plugin and resets its circuit breaker
 func (m *Manager) EnablePlugin(name string) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -183,6 +229,8 @@ func (m *Manager) EnablePlugin(name string) error {
 	}
 
 	p.Enabled = true
+	p.DisabledReason = ""
+	delete(m.failures, name)
 	return m.saveConfig()
 }
 
@@ -197,12 +245,94 @@ func (m *Manager) DisablePlugin(name string) error {
 	}
 
 	p.Enabled = false
+	p.DisabledReason = ""
 	return m.saveConfig()
 }
 
+// SetMaxFailures changes how many consecutive failures disable a plugin;
+// 0 disables the circuit breaker
+func (m *Manager) SetMaxFailures(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxFailures = n
+}
+
+// Failures returns the number of consecutive failed calls to a plugin
+func (m *Manager) Failures(name string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.failures[name]
+}
+
+// recordResult updates the circuit breaker after a call. A plugin that
+// fails maxFailures times in a row is disabled and the reason persisted.
+func (m *Manager) recordResult(name string, callErr error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if callErr == nil {
+		delete(m.failures, name)
+		return
+	}
+	m.failures[name]++
+	p, ok := m.plugins[name]
+	if !ok || m.maxFailures <= 0 || m.failures[name] < m.maxFailures {
+		return
+	}
+	p.Enabled = false
+	p.DisabledReason = fmt.Sprintf("disabled after %d consecutive failures: %v", m.failures[name], callErr)
+	m.saveConfig()
+}
+
+// HealthCheck calls the plugin's /health endpoint. It does not affect the
+// circuit breaker, so a disabled plugin can be checked before re-enabling.
+func (m *Manager) HealthCheck(ctx context.Context, name string) (*HealthStatus, error) {
+	m.mu.RLock()
+	p, ok := m.plugins[name]
+	var url string
+	var headers map[string]string
+	if ok {
+		url, headers = p.URL, p.Headers
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("plugin not found: %s", name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, healthTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", url+"/health", nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	start := time.Now()
+	resp, err := m.httpClient.Do(req)
+	status := &HealthStatus{LatencyMS: time.Since(start).Milliseconds()}
+	if err != nil {
+		status.Error = err.Error()
+		return status, nil
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	status.StatusCode = resp.StatusCode
+	status.Healthy = resp.StatusCode >= 200 && resp.StatusCode < 300
+	if !status.Healthy {
+		status.Error = resp.Status
+	}
+	return status, nil
+}
+
 // discoverTools calls the plugin's discovery endpoint to get available tools
 func (m *Manager) discoverTools(plugin *Plugin) ([]PluginTool, error) {
-	req, err := http.NewRequest("GET", plugin.URL+"/tools", nil)
+	ctx, cancel := context.WithTimeout(context.Background(), plugin.timeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", plugin.URL+"/tools", nil)
 	if err != nil {
 		return nil, err
 	}
@@ -230,20 +360,40 @@ func (m *Manager) discoverTools(plugin *Plugin) ([]PluginTool, error) {
 	return tools, nil
 }
 
-// ExecuteTool executes a plugin tool
+// ExecuteTool executes a plugin tool within the plugin's timeout. Failed
+// calls count toward the plugin's circuit breaker.
 func (m *Manager) ExecuteTool(ctx context.Context, pluginName, toolName string, args json.RawMessage) (*PluginResponse, error) {
 	m.mu.RLock()
 	plugin, ok := m.plugins[pluginName]
+	var snapshot Plugin
+	if ok {
+		snapshot = *plugin
+	}
 	m.mu.RUnlock()
 
 	if !ok {
 		return nil, fmt.Errorf("plugin not found: %s", pluginName)
 	}
 
-	if !plugin.Enabled {
+	if !snapshot.Enabled {
+		if snapshot.DisabledReason != "" {
+			return nil, fmt.Errorf("plugin is disabled: %s (%s)", pluginName, snapshot.DisabledReason)
+		}
 		return nil, fmt.Errorf("plugin is disabled: %s", pluginName)
 	}
 
+	result, err := m.callPlugin(ctx, &snapshot, toolName, args)
+	// Calls canceled by the caller say nothing about the plugin's health
+	if ctx.Err() == nil {
+		m.recordResult(pluginName, err)
+	}
+	return result, err
+}
+
+func (m *Manager) callPlugin(ctx context.Context, plugin *Plugin, toolName string, args json.RawMessage) (*PluginResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, plugin.timeout())
+	defer cancel()
+
 	// Call the plugin's execute endpoint
 	payload, _ := json.Marshal(map[string]any{
 		"tool": toolName,
@@ -262,13 +412,21 @@ func (m *Manager) ExecuteTool(ctx context.Context, pluginName, toolName string,
 
 	resp, err := m.httpClient.Do(req)
 	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return nil, fmt.Errorf("plugin %s timed out after %s", plugin.Name, plugin.timeout())
+		}
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("plugin %s returned %s: %s", plugin.Name, resp.Status, bytes.TrimSpace(body))
+	}
+
 	var result PluginResponse
 	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return nil, err
+		return nil, fmt.Errorf("plugin %s returned an invalid response: %w", plugin.Name, err)
 	}
 
 	return &result, nil
@@ -0,0 +1,136 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"groq-go/internal/tool"
+)
+
+func newTestManager(t *testing.T, handler http.HandlerFunc) (*Manager, string) {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	path := filepath.Join(t.TempDir(), "plugins.yaml")
+	m, err := newManager(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = m.AddPlugin(&Plugin{
+		Name:    "slow",
+		URL:     srv.URL,
+		Enabled: true,
+		Timeout: 1,
+		Tools:   []PluginTool{{Name: "echo", Description: "Echo"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, path
+}
+
+func TestCircuitBreakerDisablesTimingOutPlugin(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	m, path := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	})
+	m.SetMaxFailures(2)
+
+	registry := tool.NewRegistry()
+	if n := RegisterPluginTools(registry, m); n != 1 {
+		t.Fatalf("Expected 1 plugin tool, got %d", n)
+	}
+	if len(registry.ToClientTools()) != 1 {
+		t.Fatal("Expected the plugin tool to be offered")
+	}
+
+	for i := 0; i < 2; i++ {
+		start := time.Now()
+		_, err := m.ExecuteTool(context.Background(), "slow", "echo", json.RawMessage(`{}`))
+		if err == nil || !strings.Contains(err.Error(), "timed out") {
+			t.Fatalf("Expected timeout error, got %v", err)
+		}
+		if time.Since(start) > 3*time.Second {
+			t.Fatalf("Expected the per-plugin timeout to apply, took %s", time.Since(start))
+		}
+	}
+
+	if m.IsEnabled("slow") {
+		t.Fatal("Expected the breaker to disable the plugin")
+	}
+	if len(registry.ToClientTools()) != 0 {
+		t.Error("Expected the registry to stop offering the plugin's tools")
+	}
+	_, err := m.ExecuteTool(context.Background(), "slow", "echo", json.RawMessage(`{}`))
+	if err == nil || !strings.Contains(err.Error(), "consecutive failures") {
+		t.Errorf("Expected disabled error with reason, got %v", err)
+	}
+
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "disabled after 2 consecutive failures") {
+		t.Errorf("Expected reason persisted, got:\n%s", data)
+	}
+
+	status, err := m.HealthCheck(context.Background(), "slow")
+	if err != nil || status.Healthy || status.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected unhealthy status, got %+v, %v", status, err)
+	}
+
+	if err := m.EnablePlugin("slow"); err != nil {
+		t.Fatal(err)
+	}
+	if m.Failures("slow") != 0 {
+		t.Error("Expected enabling to reset the breaker")
+	}
+	if p, _ := m.GetPlugin("slow"); p.DisabledReason != "" {
+		t.Errorf("Expected reason cleared, got %q", p.DisabledReason)
+	}
+	if len(registry.ToClientTools()) != 1 {
+		t.Error("Expected the plugin tool to be offered again")
+	}
+}
+
+func TestSuccessfulCallResetsFailures(t *testing.T) {
+	var fail atomic.Bool
+	fail.Store(true)
+	m, _ := newTestManager(t, func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		json.NewEncoder(w).Encode(PluginResponse{Content: "ok"})
+	})
+
+	if _, err := m.ExecuteTool(context.Background(), "slow", "echo", nil); err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Fatalf("Expected status error, got %v", err)
+	}
+	if m.Failures("slow") != 1 {
+		t.Fatalf("Expected 1 failure, got %d", m.Failures("slow"))
+	}
+
+	fail.Store(false)
+	resp, err := m.ExecuteTool(context.Background(), "slow", "echo", nil)
+	if err != nil || resp.Content != "ok" {
+		t.Fatalf("Expected success, got %+v, %v", resp, err)
+	}
+	if m.Failures("slow") != 0 {
+		t.Errorf("Expected failures reset, got %d", m.Failures("slow"))
+	}
+}
@@ -0,0 +1,76 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"groq-go/internal/tool"
+)
+
+// maxApprovalSubject caps how much of a call is shown when asking
+const maxApprovalSubject = 500
+
+// approveCall asks on the terminal whether a tool call may run. Anything
+// but yes or always denies it.
+func (r *REPL) approveCall(ctx context.Context, req tool.ApprovalRequest) (tool.Decision, error) {
+	subject := req.Subject
+	if len(subject) > maxApprovalSubject {
+		subject = subject[:maxApprovalSubject] + "..."
+	}
+	r.output.Question(fmt.Sprintf("Allow %s: %s", req.Tool, subject), []string{"yes", "no", "always allow " + req.Tool})
+	r.input.SetPrompt("approve [y/N/a]> ")
+	defer r.input.SetPrompt("> ")
+
+	line, err := r.input.ReadLine()
+	if IsInterrupt(err) || IsEOF(err) {
+		return tool.Deny, nil
+	}
+	if err != nil {
+		return tool.Deny, err
+	}
+	if err := ctx.Err(); err != nil {
+		return tool.Deny, err
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes", "1":
+		return tool.Approve, nil
+	case "a", "always", "3":
+		return tool.ApproveAlways, nil
+	}
+	return tool.Deny, nil
+}
+
+func cmdApprove(r *REPL, args string) error {
+	args = strings.TrimSpace(args)
+	switch sub, rest, _ := strings.Cut(args, " "); sub {
+	case "":
+		rules := r.approvals.List()
+		if len(rules) == 0 {
+			r.output.Muted("No auto-approve rules; add one with /approve <tool> [regexp]")
+			return nil
+		}
+		for i, rule := range rules {
+			r.output.Muted("  %d) %s", i+1, rule)
+		}
+		return nil
+	case "rm":
+		n, err := strconv.Atoi(strings.TrimSpace(rest))
+		if err != nil || !r.approvals.Remove(n-1) {
+			return fmt.Errorf("usage: /approve rm <n> (see /approve)")
+		}
+		r.output.Success("Rule %d removed", n)
+		return nil
+	}
+
+	rule, err := tool.ParseApprovalRule(args)
+	if err != nil {
+		return err
+	}
+	if err := r.approvals.Add(rule); err != nil {
+		return err
+	}
+	r.output.Success("Auto-approving %s", rule)
+	return nil
+}
@@ -1,7 +1,13 @@
 package repl
 
 import (
+	"fmt"
+	"slices"
+	"strconv"
 	"strings"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
 )
 
 // Command represents a slash command
@@ -29,6 +35,71 @@ func DefaultCommands() map[string]Command {
 			Description: "Show or change the current model",
 			Handler:     cmdModel,
 		},
+		"approve": {
+			Name:        "approve",
+			Description: "List, add (/approve <tool> [regexp]) or remove (/approve rm <n>) auto-approve rules",
+			Handler:     cmdApprove,
+		},
+		"format": {
+			Name:        "format",
+			Description: "Toggle formatting of files after Write/Edit",
+			Handler:     cmdFormat,
+		},
+		"set": {
+			Name:        "set",
+			Description: "Show or change temperature and max_tokens",
+			Handler:     cmdSet,
+		},
+		"save": {
+			Name:        "save",
+			Description: "Save the conversation as a session",
+			Handler:     cmdSave,
+		},
+		"load": {
+			Name:        "load",
+			Description: "Load a saved session",
+			Handler:     cmdLoad,
+		},
+		"sessions": {
+			Name:        "sessions",
+			Description: "List saved sessions, or find them with /sessions search <terms>",
+			Handler:     cmdSessions,
+		},
+		"export": {
+			Name:        "export",
+			Description: "Write the conversation to a markdown or JSON file",
+			Handler:     cmdExport,
+		},
+		"import": {
+			Name:        "import",
+			Description: "Replace the conversation with a JSON export",
+			Handler:     cmdImport,
+		},
+		"usage": {
+			Name:        "usage",
+			Description: "Show tokens used in this session",
+			Handler:     cmdUsage,
+		},
+		"project": {
+			Name:        "project",
+			Description: "List projects or switch the current one",
+			Handler:     cmdProject,
+		},
+		"mcp": {
+			Name:        "mcp",
+			Description: "List MCP servers and prompts, or send a prompt with /mcp use-prompt",
+			Handler:     cmdMCP,
+		},
+		"memory": {
+			Name:        "memory",
+			Description: "List what the assistant remembers across sessions, or forget (/memory forget <key>) or set (/memory set <key> <value>) a memory",
+			Handler:     cmdMemory,
+		},
+		"system": {
+			Name:        "system",
+			Description: "Show or add to the system prompt",
+			Handler:     cmdSystem,
+		},
 		"exit": {
 			Name:        "exit",
 			Description: "Exit the REPL",
@@ -46,10 +117,23 @@ func cmdHelp(r *REPL, args string) error {
 	r.output.Println()
 	r.output.Info("Available commands:")
 	r.output.Println()
-	r.output.Muted("  /help   - Show this help message")
-	r.output.Muted("  /clear  - Clear conversation history")
-	r.output.Muted("  /model  - Show or set model (e.g., /model llama-3.1-8b-instant)")
-	r.output.Muted("  /exit   - Exit groq-go")
+	r.output.Muted("  /help     - Show this help message")
+	r.output.Muted("  /clear    - Clear conversation history")
+	r.output.Muted("  /model    - Show or set model (e.g., /model llama-3.1-8b-instant)")
+	r.output.Muted("  /format   - Show or set formatting after Write/Edit (on/all/off)")
+	r.output.Muted("  /set      - Show or set temperature/max_tokens (e.g., /set temperature 0.2)")
+	r.output.Muted("  /save     - Save the conversation (e.g., /save refactor notes)")
+	r.output.Muted("  /load     - Replace the conversation with a saved session (/load <id>)")
+	r.output.Muted("  /sessions - List saved sessions")
+	r.output.Muted("  /export   - Write the conversation to a file (/export chat.md or chat.json)")
+	r.output.Muted("  /import   - Replace the conversation with a JSON export (/import chat.json)")
+	r.output.Muted("  /usage    - Show tokens used per model in this session")
+	r.output.Muted("  /project  - List projects or confine file tools to one (/project use <id|none>)")
+	r.output.Muted("  /system   - Show or add to the system prompt (/system show|append <text>|clear)")
+	r.output.Muted("  /approve  - List or change auto-approve rules (/approve <tool> [regexp], /approve rm <n>)")
+	r.output.Muted("  /mcp      - List MCP servers and prompts, or send one (/mcp prompts, /mcp use-prompt <server> <name> [arg=value ...])")
+	r.output.Muted("  /memory   - List memories kept across sessions (/memory forget <key>, /memory set <key> <value>)")
+	r.output.Muted("  /exit     - Exit groq-go")
 	r.output.Println()
 	r.output.Info("Tips:")
 	r.output.Muted("  - Press Ctrl+C to cancel current operation")
@@ -60,30 +144,157 @@ func cmdHelp(r *REPL, args string) error {
 
 func cmdClear(r *REPL, args string) error {
 	r.history.Clear()
+	r.reads.Reset()
 	// Re-add system message
 	r.history.Add(r.context.SystemMessage())
+	// The next /save starts a new session
+	r.session = nil
 	r.output.Success("Conversation cleared")
 	return nil
 }
 
+func cmdSystem(r *REPL, args string) error {
+	action, text, _ := strings.Cut(strings.TrimSpace(args), " ")
+	switch action {
+	case "", "show":
+		r.output.Println(r.context.SystemMessage().Content)
+		return nil
+	case "append":
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return fmt.Errorf("usage: /system append <text>")
+		}
+		if prev := r.context.SessionPrompt(); prev != "" {
+			text = prev + "\n" + text
+		}
+		if err := r.context.SetSessionPrompt(text); err != nil {
+			return err
+		}
+	case "clear":
+		r.context.SetSessionPrompt("")
+	default:
+		return fmt.Errorf("usage: /system [show|append <text>|clear]")
+	}
+	// Takes effect on the next message, as a mode switch does in the web UI
+	r.history.SetSystem(r.context.SystemMessage())
+	r.output.Success("System prompt updated")
+	return nil
+}
+
 func cmdModel(r *REPL, args string) error {
 	args = strings.TrimSpace(args)
 	if args == "" {
-		r.output.Info("Current model: %s", r.client.Model())
+		current := r.client.Model()
+		r.output.Info("Current model: %s", current)
 		r.output.Println()
 		r.output.Muted("Available models:")
-		r.output.Muted("  - llama-3.3-70b-versatile (default)")
-		r.output.Muted("  - llama-3.1-8b-instant")
-		r.output.Muted("  - llama-3.2-90b-vision-preview")
-		r.output.Muted("  - mixtral-8x7b-32768")
+		for _, model := range client.KnownModels() {
+			if r.readOnlyModels != nil && !slices.Contains(r.readOnlyModels, model) {
+				continue
+			}
+			line := fmt.Sprintf("  - %s (%s)", model, client.ProviderFor(model))
+			if model == current {
+				line += " [current]"
+			}
+			if !r.client.HasKeyFor(model) {
+				line += " [no API key]"
+			}
+			r.output.Muted("%s", line)
+		}
 		return nil
 	}
 
+	if r.readOnlyModels != nil && !slices.Contains(r.readOnlyModels, args) {
+		return fmt.Errorf("%s is not available in read-only mode (allowed: %s)", args, strings.Join(r.readOnlyModels, ", "))
+	}
+	if !r.client.HasKeyFor(args) {
+		return fmt.Errorf("no API key configured for %s (provider %s)", args, client.ProviderFor(args))
+	}
+	if !client.SupportsVision(args) {
+		for _, msg := range r.history.Messages() {
+			if msg.HasImages() {
+				r.output.Warning("%s does not support images; the images in this conversation may be rejected (use /clear to start over)", args)
+				break
+			}
+		}
+	}
+
 	r.client.SetModel(args)
 	r.output.Success("Model changed to: %s", args)
 	return nil
 }
 
+func cmdFormat(r *REPL, args string) error {
+	switch strings.ToLower(strings.TrimSpace(args)) {
+	case "":
+	case "on", "code":
+		r.format = tool.FormatCode
+	case "all":
+		r.format = tool.FormatAll
+	case "off":
+		r.format = tool.FormatOff
+	default:
+		return fmt.Errorf("usage: /format [on|all|off]")
+	}
+	r.output.Info("Formatting after Write/Edit: %s", r.format)
+	return nil
+}
+
+func cmdSet(r *REPL, args string) error {
+	fields := strings.Fields(args)
+	if len(fields) == 0 {
+		temperature, maxTokens := "default", "default"
+		if r.options.Temperature != nil {
+			temperature = strconv.FormatFloat(*r.options.Temperature, 'g', -1, 64)
+		}
+		if r.options.MaxTokens > 0 {
+			maxTokens = strconv.Itoa(r.options.MaxTokens)
+		}
+		r.output.Info("temperature: %s", temperature)
+		r.output.Info("max_tokens: %s", maxTokens)
+		return nil
+	}
+	if len(fields) != 2 {
+		return fmt.Errorf("usage: /set <temperature|max_tokens> <value|default>")
+	}
+
+	opts := r.options
+	name, value := strings.ToLower(fields[0]), fields[1]
+	switch name {
+	case "temperature", "temp":
+		name = "temperature"
+		if value == "default" {
+			opts.Temperature = nil
+			break
+		}
+		t, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("invalid temperature: %s", value)
+		}
+		opts.Temperature = &t
+	case "max_tokens", "max-tokens", "maxtokens":
+		name = "max_tokens"
+		if value == "default" {
+			opts.MaxTokens = 0
+			break
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n <= 0 {
+			return fmt.Errorf("invalid max_tokens: %s", value)
+		}
+		opts.MaxTokens = n
+	default:
+		return fmt.Errorf("unknown setting: %s (use temperature or max_tokens)", fields[0])
+	}
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+
+	r.options = opts
+	r.output.Success("%s set to %s", name, value)
+	return nil
+}
+
 func cmdExit(r *REPL, args string) error {
 	return ErrExit
 }
@@ -0,0 +1,177 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+)
+
+func TestModelCommandLists(t *testing.T) {
+	r, out := newSessionTestREPL(t, "")
+	if err := cmdModel(r, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, model := range client.KnownModels() {
+		if !strings.Contains(out.String(), model) {
+			t.Errorf("Expected %s in the listing", model)
+		}
+	}
+	if !strings.Contains(out.String(), "gpt-4o (openai) [no API key]") {
+		t.Errorf("Expected models without a key to be marked, got:\n%s", out.String())
+	}
+}
+
+func TestModelCommandRequiresKey(t *testing.T) {
+	r, _ := newSessionTestREPL(t, "")
+	if err := cmdModel(r, "gpt-4o"); err == nil {
+		t.Error("Expected an error switching to a model without a key")
+	}
+	if r.client.Model() != client.DefaultModel {
+		t.Errorf("Expected the model to stay %s, got %s", client.DefaultModel, r.client.Model())
+	}
+
+	if err := cmdModel(r, "llama-3.1-8b-instant"); err != nil {
+		t.Fatalf("Expected switching to succeed: %v", err)
+	}
+	if r.client.Model() != "llama-3.1-8b-instant" {
+		t.Errorf("Expected model to change, got %s", r.client.Model())
+	}
+}
+
+func TestModelCommandCustomEndpoint(t *testing.T) {
+	saved := client.Catalog
+	client.Catalog = client.NewModelCatalog(saved.Models()...)
+	t.Cleanup(func() { client.Catalog = saved })
+	if err := client.Catalog.AddProvider("ollama"); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Catalog.Add(client.ModelInfo{Name: "qwen2.5-coder", Provider: "ollama", Tools: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	r, out := newSessionTestREPL(t, "")
+	r.client = client.New("key", client.WithEndpoint("ollama", "http://localhost:11434/v1", ""))
+	if err := cmdModel(r, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "qwen2.5-coder (ollama)\n") {
+		t.Errorf("Expected the endpoint's model listed with no key warning, got:\n%s", out.String())
+	}
+	if err := cmdModel(r, "qwen2.5-coder"); err != nil {
+		t.Fatalf("Expected switching to a custom model to succeed: %v", err)
+	}
+	if r.client.Model() != "qwen2.5-coder" {
+		t.Errorf("Expected model to change, got %s", r.client.Model())
+	}
+}
+
+func TestModelCommandWarnsAboutImages(t *testing.T) {
+	r, out := newSessionTestREPL(t, "")
+	r.history.Add(client.NewVisionMessage("user", "what is this?", "data:image/png;base64,AAAA"))
+
+	if err := cmdModel(r, "llama-3.2-90b-vision-preview"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "does not support images") {
+		t.Error("Expected no warning for a vision model")
+	}
+
+	if err := cmdModel(r, "llama-3.1-8b-instant"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "llama-3.1-8b-instant does not support images") {
+		t.Errorf("Expected a vision warning, got:\n%s", out.String())
+	}
+}
+
+func TestSystemCommand(t *testing.T) {
+	r, out := newSessionTestREPL(t, "")
+	p, err := conversation.LoadPrompt(t.TempDir()+"/missing.md", "House rule: never push to main.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetSystemPrompt(p)
+	r.history.Add(client.Message{Role: "user", Content: "hello"})
+
+	if err := cmdSystem(r, "show"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "House rule: never push to main.") {
+		t.Errorf("Expected the appended text shown, got:\n%s", out.String())
+	}
+
+	if err := cmdSystem(r, "append Answer in Japanese."); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmdSystem(r, "append Keep it short."); err != nil {
+		t.Fatal(err)
+	}
+	msgs := r.history.Messages()
+	if len(msgs) != 2 || msgs[1].Content != "hello" {
+		t.Fatalf("Expected the conversation kept, got %+v", msgs)
+	}
+	system := msgs[0].Content.(string)
+	if !strings.HasSuffix(system, "House rule: never push to main.\n\n## Session Instructions\nAnswer in Japanese.\nKeep it short.") {
+		t.Errorf("Expected history[0] rewritten, got ...%s", system[len(system)-120:])
+	}
+
+	// Session instructions survive /clear but not /system clear
+	if err := cmdClear(r, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(r.history.Messages()[0].Content.(string), "Keep it short.") {
+		t.Error("Expected session instructions to survive /clear")
+	}
+	if err := cmdSystem(r, "clear"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(r.history.Messages()[0].Content.(string), "Session Instructions") {
+		t.Error("Expected session instructions removed")
+	}
+
+	if err := cmdSystem(r, "append "+strings.Repeat("x", conversation.MaxSessionPromptLen+1)); err == nil {
+		t.Error("Expected an oversized append to be rejected")
+	}
+	if err := cmdSystem(r, "append"); err == nil {
+		t.Error("Expected a usage error without text")
+	}
+}
+
+func TestUsageCommand(t *testing.T) {
+	r, out := newSessionTestREPL(t, "")
+	if err := cmdUsage(r, ""); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "No API calls") {
+		t.Errorf("Expected an empty session notice, got %q", out.String())
+	}
+
+	out.Reset()
+	r.usage.add("gpt-4o", client.Usage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120})
+	r.usage.add("gpt-4o", client.Usage{PromptTokens: 300, CompletionTokens: 30, TotalTokens: 330})
+	r.usage.add("llama-3.3-70b-versatile", client.Usage{PromptTokens: 50, CompletionTokens: 5, TotalTokens: 55})
+	if err := cmdUsage(r, ""); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"gpt-4o                   2         400     50          450",
+		"llama-3.3-70b-versatile  1         50      5           55",
+		"total                    3         450     55          505",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("Expected %q in:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestUsageCountsTurns(t *testing.T) {
+	r := newOneShotTestREPL(t, loopingUpstream(t, 1))
+	if _, _, err := runOnce(t, r, OutputText, 0); err != nil {
+		t.Fatal(err)
+	}
+	if m := r.usage.models[client.DefaultModel]; m == nil || m.requests != 2 {
+		t.Errorf("Expected two requests recorded for %s, got %+v", client.DefaultModel, r.usage.models)
+	}
+}
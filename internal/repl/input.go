@@ -14,8 +14,20 @@ type Input struct {
 	rl       *readline.Instance
 	isPiped  bool
 	scanner  *bufio.Scanner
+
+	prompt  string // see SetPrompt
+	started bool   // whether piped input has been read from
 }
 
+// fence opens and closes a multi-line message
+const fence = `"""`
+
+// continuationPrompt is shown while a multi-line message is read
+const continuationPrompt = "... "
+
+// maxPipedLine bounds a single line of piped input
+const maxPipedLine = 1 << 20
+
 // NewInput creates a new input handler
 func NewInput() (*Input, error) {
 	// Check if stdin is a pipe
@@ -24,9 +36,11 @@ func NewInput() (*Input, error) {
 
 	if isPiped {
 		// Use simple scanner for piped input
+		scanner := bufio.NewScanner(os.Stdin)
+		scanner.Buffer(nil, maxPipedLine)
 		return &Input{
 			isPiped: true,
-			scanner: bufio.NewScanner(os.Stdin),
+			scanner: scanner,
 		}, nil
 	}
 
@@ -42,30 +56,136 @@ func NewInput() (*Input, error) {
 		return nil, err
 	}
 
-	return &Input{rl: rl, isPiped: false}, nil
+	return &Input{rl: rl, isPiped: false, prompt: "> "}, nil
 }
 
-// ReadLine reads a line of input from the user
+// ReadLine reads a message from the user. A message can span lines: one
+// opening with """ runs to the closing """, and a line ending in a
+// backslash continues on the next. Piped input is read whole as a single
+// message, unless it starts with a slash command; then it is a script and
+// each line is read on its own.
 func (i *Input) ReadLine() (string, error) {
-	if i.isPiped {
-		if i.scanner.Scan() {
-			return strings.TrimSpace(i.scanner.Text()), nil
+	line, err := i.readRaw()
+	if err != nil {
+		return "", err
+	}
+	if i.isPiped && !i.started {
+		i.started = true
+		if !strings.HasPrefix(strings.TrimSpace(line), "/") {
+			return i.readAll(line)
 		}
-		if err := i.scanner.Err(); err != nil {
+	}
+
+	trimmed := strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(trimmed, fence):
+		return i.readFenced(strings.TrimPrefix(trimmed, fence))
+	case strings.HasSuffix(trimmed, `\`):
+		return i.readContinued(trimmed)
+	}
+	return trimmed, nil
+}
+
+// readRaw reads one line as typed
+func (i *Input) readRaw() (string, error) {
+	if i.rl != nil {
+		return i.rl.Readline()
+	}
+	if i.scanner.Scan() {
+		return i.scanner.Text(), nil
+	}
+	if err := i.scanner.Err(); err != nil {
+		return "", err
+	}
+	return "", io.EOF
+}
+
+// readAll reads the rest of piped input after first
+func (i *Input) readAll(first string) (string, error) {
+	lines := []string{first}
+	for {
+		line, err := i.readRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
 			return "", err
 		}
-		return "", io.EOF
+		lines = append(lines, line)
 	}
+	return strings.TrimSpace(strings.Join(lines, "\n")), nil
+}
 
-	line, err := i.rl.Readline()
-	if err != nil {
-		return "", err
+// readFenced reads up to the closing fence. Lines keep their indentation,
+// so pasted code arrives intact; EOF ends the message early.
+func (i *Input) readFenced(first string) (string, error) {
+	if body, ok := strings.CutSuffix(first, fence); ok {
+		return strings.TrimSpace(body), nil
+	}
+	defer i.withPrompt(continuationPrompt)()
+	lines := []string{first}
+	for {
+		line, err := i.readRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		if body, ok := strings.CutSuffix(strings.TrimRight(line, " \t"), fence); ok {
+			lines = append(lines, body)
+			break
+		}
+		lines = append(lines, line)
+	}
+	return trimBlankLines(strings.Join(lines, "\n")), nil
+}
+
+// readContinued joins lines for as long as they end in a backslash
+func (i *Input) readContinued(line string) (string, error) {
+	defer i.withPrompt(continuationPrompt)()
+	var lines []string
+	for {
+		body, more := strings.CutSuffix(line, `\`)
+		lines = append(lines, body)
+		if !more {
+			break
+		}
+		next, err := i.readRaw()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+		line = strings.TrimRight(next, " \t")
+	}
+	return trimBlankLines(strings.Join(lines, "\n")), nil
+}
+
+// trimBlankLines drops leading and trailing blank lines and trailing
+// spaces, but keeps the first line's indentation
+func trimBlankLines(s string) string {
+	s = strings.TrimRight(s, " \t\r\n")
+	for {
+		line, rest, ok := strings.Cut(s, "\n")
+		if !ok || strings.TrimSpace(line) != "" {
+			return s
+		}
+		s = rest
 	}
-	return strings.TrimSpace(line), nil
+}
+
+// withPrompt shows prompt until the returned func restores the previous one
+func (i *Input) withPrompt(prompt string) func() {
+	previous := i.prompt
+	i.SetPrompt(prompt)
+	return func() { i.SetPrompt(previous) }
 }
 
 // SetPrompt changes the prompt
 func (i *Input) SetPrompt(prompt string) {
+	i.prompt = prompt
 	if i.rl != nil {
 		i.rl.SetPrompt(prompt)
 	}
@@ -0,0 +1,70 @@
+package repl
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+)
+
+// scriptedInput reads script as if typed; piped reads it as stdin
+func scriptedInput(script string, piped bool) *Input {
+	return &Input{isPiped: piped, scanner: bufio.NewScanner(strings.NewReader(script)), prompt: "> "}
+}
+
+// readAllMessages reads messages until EOF
+func readAllMessages(t *testing.T, in *Input) []string {
+	t.Helper()
+	var messages []string
+	for {
+		msg, err := in.ReadLine()
+		if IsEOF(err) {
+			return messages
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, msg)
+	}
+}
+
+func TestReadLineMultiLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"single lines", "  hello \nworld\n", []string{"hello", "world"}},
+		{"fence", "\"\"\"\nfunc main() {\n\tfmt.Println(1)\n}\n\"\"\"\nnext\n", []string{"func main() {\n\tfmt.Println(1)\n}", "next"}},
+		{"fence with text", "\"\"\"fix this:\n  x := 1\ndone\"\"\"\n", []string{"fix this:\n  x := 1\ndone"}},
+		{"one-line fence", "\"\"\"just this\"\"\"\n", []string{"just this"}},
+		{"unclosed fence", "\"\"\"\na\nb\n", []string{"a\nb"}},
+		{"continuation", "first \\\n  second\\\nthird\nnext\n", []string{"first \n  second\nthird", "next"}},
+		{"continuation at EOF", "dangling\\\n", []string{"dangling"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := scriptedInput(tt.script, false)
+			got := readAllMessages(t, in)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("Expected %q, got %q", tt.want, got)
+			}
+			if in.prompt != "> " {
+				t.Errorf("Expected the prompt restored, got %q", in.prompt)
+			}
+		})
+	}
+}
+
+func TestReadLinePiped(t *testing.T) {
+	in := scriptedInput("explain this:\n\nfunc f() {\n\treturn\n}\n", true)
+	got := readAllMessages(t, in)
+	if len(got) != 1 || got[0] != "explain this:\n\nfunc f() {\n\treturn\n}" {
+		t.Errorf("Expected stdin as one message, got %q", got)
+	}
+
+	// Scripts of slash commands are still read line by line
+	in = scriptedInput("/model\n/clear\n", true)
+	if got := readAllMessages(t, in); strings.Join(got, "|") != "/model|/clear" {
+		t.Errorf("Expected one command per line, got %q", got)
+	}
+}
@@ -0,0 +1,286 @@
+package repl
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ANSI styles used by the markdown renderer. They are written directly
+// rather than through fatih/color so rendering does not depend on the
+// terminal; callers decide whether to render at all.
+const (
+	ansiReset     = "\x1b[0m"
+	ansiBold      = "\x1b[1m"
+	ansiItalic    = "\x1b[3m"
+	ansiUnderline = "\x1b[4m"
+	ansiGreen     = "\x1b[32m"
+	ansiYellow    = "\x1b[33m"
+	ansiMagenta   = "\x1b[35m"
+	ansiCyan      = "\x1b[36m"
+	ansiGray      = "\x1b[90m"
+)
+
+var (
+	headingRe     = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	ruleRe        = regexp.MustCompile(`^\s*(?:(?:-\s*){3,}|(?:\*\s*){3,}|(?:_\s*){3,})$`)
+	bulletRe      = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	orderedRe     = regexp.MustCompile(`^(\s*)(\d+)[.)]\s+(.*)$`)
+	quoteRe       = regexp.MustCompile(`^\s*>\s?(.*)$`)
+	fenceRe       = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")
+	linkRe        = regexp.MustCompile(`^\[([^\]]+)\]\(([^)\s]+)\)`)
+	horizontalBar = strings.Repeat("─", 40)
+)
+
+// RenderMarkdown formats markdown for the terminal: headings, emphasis,
+// inline code, links, lists, quotes and rules are styled, and fenced code
+// blocks are syntax highlighted. Every line of the result ends in a newline.
+func RenderMarkdown(src string) string {
+	var b strings.Builder
+	var fence, lang string // open fence marker and its language
+
+	lines := strings.Split(strings.TrimRight(src, "\n"), "\n")
+	for _, line := range lines {
+		if fence != "" {
+			if strings.HasPrefix(strings.TrimSpace(line), fence) {
+				b.WriteString(ansiGray + fence + ansiReset + "\n")
+				fence = ""
+				continue
+			}
+			b.WriteString(highlightCode(line, lang) + "\n")
+			continue
+		}
+
+		if m := fenceRe.FindStringSubmatch(line); m != nil {
+			fence, lang = m[1], strings.ToLower(m[2])
+			b.WriteString(ansiGray + strings.TrimSpace(line) + ansiReset + "\n")
+			continue
+		}
+
+		switch {
+		case headingRe.MatchString(line):
+			m := headingRe.FindStringSubmatch(line)
+			style := ansiBold
+			if len(m[1]) == 1 {
+				style += ansiUnderline
+			} else if len(m[1]) == 2 {
+				style += ansiCyan
+			}
+			b.WriteString(style + renderInline(m[2], style) + ansiReset)
+		case ruleRe.MatchString(line):
+			b.WriteString(ansiGray + horizontalBar + ansiReset)
+		case bulletRe.MatchString(line):
+			m := bulletRe.FindStringSubmatch(line)
+			b.WriteString(m[1] + ansiCyan + "•" + ansiReset + " " + renderInline(m[2], ""))
+		case orderedRe.MatchString(line):
+			m := orderedRe.FindStringSubmatch(line)
+			b.WriteString(m[1] + ansiCyan + m[2] + "." + ansiReset + " " + renderInline(m[3], ""))
+		case quoteRe.MatchString(line):
+			m := quoteRe.FindStringSubmatch(line)
+			b.WriteString(ansiGray + "│ " + ansiItalic + renderInline(m[1], ansiGray+ansiItalic) + ansiReset)
+		default:
+			b.WriteString(renderInline(line, ""))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// renderInline styles code spans, bold, italics and links within a line.
+// outer is the style of the enclosing block, restored after each span.
+func renderInline(s, outer string) string {
+	var b strings.Builder
+	restore := ansiReset + outer
+
+	for i := 0; i < len(s); {
+		rest := s[i:]
+		switch {
+		case rest[0] == '`':
+			if end := strings.IndexByte(rest[1:], '`'); end >= 0 {
+				b.WriteString(ansiCyan + rest[1:1+end] + restore)
+				i += end + 2
+				continue
+			}
+		case strings.HasPrefix(rest, "**") || strings.HasPrefix(rest, "__"):
+			marker := rest[:2]
+			if end := strings.Index(rest[2:], marker); end > 0 {
+				b.WriteString(ansiBold + renderInline(rest[2:2+end], outer+ansiBold) + restore)
+				i += end + 4
+				continue
+			}
+		case rest[0] == '*' || rest[0] == '_':
+			// Intraword underscores are part of names like snake_case
+			wordBefore := i > 0 && isWordByte(s[i-1])
+			if len(rest) > 1 && rest[1] != ' ' && !(rest[0] == '_' && wordBefore) {
+				if end := closingEmphasis(rest[1:], rest[0]); end > 0 {
+					b.WriteString(ansiItalic + renderInline(rest[1:1+end], outer+ansiItalic) + restore)
+					i += end + 2
+					continue
+				}
+			}
+		case rest[0] == '[':
+			if m := linkRe.FindStringSubmatch(rest); m != nil {
+				b.WriteString(ansiUnderline + m[1] + restore)
+				if m[2] != m[1] {
+					b.WriteString(" " + ansiGray + "(" + m[2] + ")" + restore)
+				}
+				i += len(m[0])
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+		i++
+	}
+	return b.String()
+}
+
+// closingEmphasis finds the marker that closes a single-character
+// emphasis span in s, or -1
+func closingEmphasis(s string, marker byte) int {
+	for j := 1; j < len(s); j++ {
+		if s[j] != marker || s[j-1] == ' ' {
+			continue
+		}
+		if marker == '_' && j+1 < len(s) && isWordByte(s[j+1]) {
+			continue
+		}
+		return j
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// codeLanguage describes how to highlight one language
+type codeLanguage struct {
+	keywords map[string]bool
+	comment  string // line comment marker
+}
+
+func words(s string) map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		m[w] = true
+	}
+	return m
+}
+
+var (
+	goLang = codeLanguage{comment: "//", keywords: words(`break case chan const continue default defer else
+		fallthrough for func go goto if import interface map package range return select struct switch type var
+		nil true false`)}
+	pythonLang = codeLanguage{comment: "#", keywords: words(`and as assert async await break class continue def del
+		elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while
+		with yield None True False`)}
+	jsLang = codeLanguage{comment: "//", keywords: words(`async await break case catch class const continue default
+		delete do else export extends finally for from function if import in instanceof interface let new of return
+		static switch this throw try type typeof var void while yield null undefined true false`)}
+	shellLang = codeLanguage{comment: "#", keywords: words(`if then else elif fi for while until do done case esac
+		function in return export local echo cd exit`)}
+	rustLang = codeLanguage{comment: "//", keywords: words(`as async await break const continue crate else enum extern
+		fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait type unsafe
+		use where while true false`)}
+	sqlLang = codeLanguage{comment: "--", keywords: words(`select from where insert into values update set delete
+		create table drop alter join left right inner outer on group by order having limit and or not null as
+		SELECT FROM WHERE INSERT INTO VALUES UPDATE SET DELETE CREATE TABLE DROP ALTER JOIN LEFT RIGHT INNER OUTER
+		ON GROUP BY ORDER HAVING LIMIT AND OR NOT NULL AS`)}
+)
+
+// codeLanguages maps fence info strings to languages
+var codeLanguages = map[string]codeLanguage{
+	"go":         goLang,
+	"golang":     goLang,
+	"python":     pythonLang,
+	"py":         pythonLang,
+	"javascript": jsLang,
+	"js":         jsLang,
+	"typescript": jsLang,
+	"ts":         jsLang,
+	"jsx":        jsLang,
+	"tsx":        jsLang,
+	"sh":         shellLang,
+	"bash":       shellLang,
+	"shell":      shellLang,
+	"zsh":        shellLang,
+	"rust":       rustLang,
+	"rs":         rustLang,
+	"sql":        sqlLang,
+	"yaml":       {comment: "#"},
+	"yml":        {comment: "#"},
+	"toml":       {comment: "#"},
+}
+
+// highlightCode colors keywords, strings, numbers and comments in one line
+// of code. Unknown languages only get strings and numbers highlighted.
+func highlightCode(line, lang string) string {
+	l := codeLanguages[lang]
+	var b strings.Builder
+
+	for i := 0; i < len(line); {
+		c := line[i]
+		switch {
+		case l.comment != "" && strings.HasPrefix(line[i:], l.comment):
+			b.WriteString(ansiGray + line[i:] + ansiReset)
+			return b.String()
+
+		case c == '"' || c == '\'' || c == '`':
+			end := i + 1
+			for end < len(line) && line[end] != c {
+				if line[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(line))
+			b.WriteString(ansiGreen + line[i:end] + ansiReset)
+			i = end
+
+		case c >= '0' && c <= '9' && (i == 0 || !isWordByte(line[i-1])):
+			end := i
+			for end < len(line) && (isWordByte(line[end]) || line[end] == '.') {
+				end++
+			}
+			b.WriteString(ansiYellow + line[i:end] + ansiReset)
+			i = end
+
+		case isWordByte(c):
+			end := i
+			for end < len(line) && isWordByte(line[end]) {
+				end++
+			}
+			if word := line[i:end]; l.keywords[word] {
+				b.WriteString(ansiMagenta + word + ansiReset)
+			} else {
+				b.WriteString(word)
+			}
+			i = end
+
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String()
+}
+
+// displayRows returns how many terminal rows text occupies at width
+// columns, counting wrapped lines
+func displayRows(text string, width int, runeWidth func(rune) int) int {
+	rows := 0
+	for _, line := range strings.Split(text, "\n") {
+		cols := 0
+		for _, r := range line {
+			if r == '\t' {
+				cols += 8 - cols%8
+				continue
+			}
+			if unicode.IsPrint(r) {
+				cols += runeWidth(r)
+			}
+		}
+		rows += max(1, (cols+width-1)/width)
+	}
+	return rows
+}
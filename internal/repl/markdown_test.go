@@ -0,0 +1,116 @@
+package repl
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+var update = flag.Bool("update", false, "Rewrite golden files")
+
+func TestRenderMarkdownGolden(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("testdata", "markdown.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := RenderMarkdown(string(src))
+
+	golden := filepath.Join("testdata", "markdown.golden")
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("Rendered markdown differs from %s (run with -update to accept):\n%q", golden, got)
+	}
+}
+
+func TestRenderMarkdownInline(t *testing.T) {
+	tests := map[string]string{
+		"use `x := 1` here":    "use " + ansiCyan + "x := 1" + ansiReset + " here",
+		"a **b** c":            "a " + ansiBold + "b" + ansiReset + " c",
+		"snake_case_name":      "snake_case_name",
+		"2 * 3 * 4":            "2 * 3 * 4",
+		"[go](https://go.dev)": ansiUnderline + "go" + ansiReset + " " + ansiGray + "(https://go.dev)" + ansiReset,
+	}
+	for in, want := range tests {
+		if got := renderInline(in, ""); got != want {
+			t.Errorf("renderInline(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestStreamEndRendersInPlace(t *testing.T) {
+	var out bytes.Buffer
+	o := NewOutput(&out)
+	o.pretty = true
+	o.screenSize = func() (int, int, error) { return 80, 24, nil }
+
+	o.StreamStart()
+	o.StreamToken("# Title\n")
+	o.StreamToken("body")
+	o.StreamEnd()
+
+	raw := "# Title\nbody\n"
+	if !strings.HasPrefix(out.String(), raw+"\x1b[2F\x1b[J") {
+		t.Errorf("Expected the raw text to be erased, got %q", out.String())
+	}
+	if !strings.HasSuffix(out.String(), RenderMarkdown("# Title\nbody")) {
+		t.Errorf("Expected the rendered text after the raw text, got %q", out.String())
+	}
+
+	// Without a terminal size the rendered copy follows the raw text
+	out.Reset()
+	o.screenSize = func() (int, int, error) { return 0, 0, errors.New("not a terminal") }
+	o.StreamToken("plain")
+	o.StreamEnd()
+	if strings.Contains(out.String(), "\x1b[J") || !strings.HasPrefix(out.String(), "plain\n") {
+		t.Errorf("Expected no cursor movement, got %q", out.String())
+	}
+}
+
+func TestPrettyRespectsNoColor(t *testing.T) {
+	defer func(v bool) { color.NoColor = v }(color.NoColor)
+
+	color.NoColor = true
+	var out bytes.Buffer
+	o := NewOutput(&out)
+	o.SetPretty(true)
+	o.StreamToken("**bold**")
+	o.StreamEnd()
+	if out.String() != "**bold**\n" {
+		t.Errorf("Expected plain output with colors off, got %q", out.String())
+	}
+
+	color.NoColor = false
+	o.SetPretty(true)
+	if !o.pretty {
+		t.Error("Expected pretty output with colors on")
+	}
+}
+
+func TestDisplayRows(t *testing.T) {
+	width := func(r rune) int {
+		if r > 0x1100 {
+			return 2
+		}
+		return 1
+	}
+	if got := displayRows("abc\n\n"+strings.Repeat("x", 25), 10, width); got != 5 {
+		t.Errorf("Expected 5 rows, got %d", got)
+	}
+	if got := displayRows("日本語です", 4, width); got != 3 {
+		t.Errorf("Expected wide runes to wrap to 3 rows, got %d", got)
+	}
+}
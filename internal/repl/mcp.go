@@ -0,0 +1,123 @@
+package repl
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/mcp"
+)
+
+// mcpTimeout bounds one request to an MCP server from /mcp
+const mcpTimeout = 30 * time.Second
+
+// SetMCPManager enables /mcp
+func (r *REPL) SetMCPManager(m *mcp.Manager) {
+	r.mcp = m
+}
+
+func cmdMCP(r *REPL, args string) error {
+	if r.mcp == nil {
+		return fmt.Errorf("MCP not available")
+	}
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		return listMCPServers(r)
+	case fields[0] == "prompts" && len(fields) == 1:
+		return listMCPPrompts(r)
+	case fields[0] == "use-prompt" && len(fields) >= 3:
+		return useMCPPrompt(r, fields[1], fields[2], fields[3:])
+	}
+	return fmt.Errorf("usage: /mcp [prompts|use-prompt <server> <name> [arg=value ...]]")
+}
+
+func listMCPServers(r *REPL) error {
+	servers := r.mcp.Servers()
+	if len(servers) == 0 {
+		r.output.Muted("No MCP servers configured")
+		return nil
+	}
+	rows := make([][]string, 0, len(servers))
+	for _, s := range servers {
+		status := "connected"
+		if s.Starting {
+			status = "starting"
+		} else if !s.Connected {
+			status = "not running"
+		}
+		rows = append(rows, []string{s.Name, status, fmt.Sprintf("%d", len(s.Tools))})
+	}
+	r.output.Table([]string{"SERVER", "STATUS", "TOOLS"}, rows)
+	return nil
+}
+
+func listMCPPrompts(r *REPL) error {
+	ctx, cancel := context.WithTimeout(context.Background(), mcpTimeout)
+	defer cancel()
+	prompts, err := r.mcp.ListPrompts(ctx)
+	if err != nil {
+		r.output.Warning("%v", err)
+	}
+
+	var rows [][]string
+	for server, list := range prompts {
+		for _, p := range list {
+			var params []string
+			for _, a := range p.Arguments {
+				if a.Required {
+					params = append(params, a.Name)
+				} else {
+					params = append(params, "["+a.Name+"]")
+				}
+			}
+			rows = append(rows, []string{server, p.Name, strings.Join(params, " "), p.Description})
+		}
+	}
+	if len(rows) == 0 {
+		r.output.Muted("No MCP prompts")
+		return nil
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i][0]+"\x00"+rows[i][1] < rows[j][0]+"\x00"+rows[j][1]
+	})
+	r.output.Table([]string{"SERVER", "PROMPT", "ARGUMENTS", "DESCRIPTION"}, rows)
+	r.output.Muted("Use one with /mcp use-prompt <server> <prompt> [arg=value ...]")
+	return nil
+}
+
+// useMCPPrompt renders a prompt and sends it as the next user message.
+// Earlier messages of a multi-message prompt are added to the history
+// first.
+func useMCPPrompt(r *REPL, server, name string, pairs []string) error {
+	args := make(map[string]string, len(pairs))
+	for _, pair := range pairs {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return fmt.Errorf("prompt arguments are arg=value, got %q", pair)
+		}
+		args[key] = value
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), mcpTimeout)
+	defer cancel()
+	prompt, err := r.mcp.GetPrompt(ctx, server, name, args)
+	if err != nil {
+		return err
+	}
+	msgs := prompt.Messages
+	if len(msgs) == 0 || msgs[len(msgs)-1].Role != "user" {
+		return fmt.Errorf("prompt %s of %s does not end with a user message", name, server)
+	}
+
+	for _, m := range msgs[:len(msgs)-1] {
+		r.history.Add(client.NewTextMessage(m.Role, m.Content.String()))
+	}
+	text := msgs[len(msgs)-1].Content.String()
+	r.output.Muted("Using prompt %s from %s:", name, server)
+	r.output.Muted("%s", text)
+	return r.processMessage(text)
+}
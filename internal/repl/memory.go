@@ -0,0 +1,60 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+
+	"groq-go/internal/memory"
+)
+
+// SetMemory enables /memory and adds the memories to the system prompt of
+// this and later conversations. The REPL uses the empty namespace, as the
+// Memory tool does without a caller.
+func (r *REPL) SetMemory(store *memory.Store) {
+	r.memory = store
+	r.context.SetMemory(func() string {
+		return store.PromptSection("", memory.DefaultPromptBytes)
+	})
+	r.history.SetSystem(r.context.SystemMessage())
+}
+
+func cmdMemory(r *REPL, args string) error {
+	if r.memory == nil {
+		return fmt.Errorf("memory not available")
+	}
+	sub, rest, _ := strings.Cut(strings.TrimSpace(args), " ")
+	rest = strings.TrimSpace(rest)
+	switch {
+	case sub == "" || sub == "list":
+		memories := r.memory.List("")
+		if len(memories) == 0 {
+			r.output.Muted("No memories; add one with /memory set <key> <value>")
+			return nil
+		}
+		rows := make([][]string, 0, len(memories))
+		for _, m := range memories {
+			rows = append(rows, []string{m.Key, m.Value, string(m.Origin), m.UpdatedAt.Format("2006-01-02 15:04")})
+		}
+		r.output.Table([]string{"KEY", "VALUE", "SET BY", "UPDATED"}, rows)
+		return nil
+
+	case sub == "forget" && rest != "":
+		if err := r.memory.Delete("", rest); err != nil {
+			return err
+		}
+		r.history.SetSystem(r.context.SystemMessage())
+		r.output.Success("Forgot %s", rest)
+		return nil
+
+	case sub == "set" && rest != "":
+		key, value, _ := strings.Cut(rest, " ")
+		m, err := r.memory.Set("", key, value, memory.FromUser)
+		if err != nil {
+			return err
+		}
+		r.history.SetSystem(r.context.SystemMessage())
+		r.output.Success("Remembered %s: %s", m.Key, m.Value)
+		return nil
+	}
+	return fmt.Errorf("usage: /memory [list|forget <key>|set <key> <value>]")
+}
@@ -0,0 +1,132 @@
+package repl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// ErrMaxTurns stops a message whose tool loop used up its turns, see RunOnce
+var ErrMaxTurns = errors.New("stopped at the turn limit")
+
+// One-shot output formats
+const (
+	OutputText = "text" // the reply as plain text
+	OutputJSON = "json" // a single OneShotResult object
+)
+
+// TurnResult is what one message to the model produced
+type TurnResult struct {
+	Model     string           `json:"model"` // The model that answered last
+	Text      string           `json:"text"`  // The final reply
+	ToolCalls []ToolCallRecord `json:"tool_calls"`
+	Usage     client.Usage     `json:"usage"`
+	Turns     int              `json:"turns"` // API calls made
+}
+
+// ToolCallRecord is a tool call the model made and its result
+type ToolCallRecord struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+	Result    string `json:"result"`
+	IsError   bool   `json:"is_error"`
+}
+
+// OneShotResult is the JSON printed by RunOnce. Error is set when the turn
+// failed; the other fields show how far it got.
+type OneShotResult struct {
+	TurnResult
+	Error string `json:"error,omitempty"`
+}
+
+// OneShotOptions configure RunOnce
+type OneShotOptions struct {
+	Output   string    // OutputText or OutputJSON
+	MaxTurns int       // API calls allowed, 0 for no limit
+	Stdout   io.Writer // the reply, or the JSON result
+	Stderr   io.Writer // tool progress and warnings; streamed tokens in JSON mode
+}
+
+// NewOneShot creates a REPL for RunOnce. It never reads the terminal, so
+// tools asking the user a question get no answer.
+func NewOneShot(c *client.Client, registry *tool.Registry) *REPL {
+	input := &Input{isPiped: true, started: true, scanner: bufio.NewScanner(strings.NewReader(""))}
+	return newREPL(c, registry, input)
+}
+
+// RunOnce sends prompt as a single message, runs its tool loop and prints
+// the outcome without colors. The reply goes to Stdout as it streams, or
+// in JSON mode as part of one OneShotResult once the turn is over.
+func (r *REPL) RunOnce(prompt string, opts OneShotOptions) error {
+	if opts.Output != OutputText && opts.Output != OutputJSON {
+		return fmt.Errorf("unknown output format %q (want %s or %s)", opts.Output, OutputText, OutputJSON)
+	}
+	prompt = strings.TrimSpace(prompt)
+	if prompt == "" {
+		return fmt.Errorf("empty prompt: pass it as an argument or on stdin")
+	}
+
+	color.NoColor = true
+	r.output = NewOutput(opts.Stderr)
+	if opts.Output == OutputText {
+		r.output.SetStreamWriter(opts.Stdout)
+	}
+	r.maxTurns = opts.MaxTurns
+
+	result, err := r.runTurn(prompt)
+	if opts.Output == OutputJSON {
+		out := OneShotResult{TurnResult: *result}
+		if out.ToolCalls == nil {
+			out.ToolCalls = []ToolCallRecord{}
+		}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		if encErr := json.NewEncoder(opts.Stdout).Encode(out); encErr != nil && err == nil {
+			err = encErr
+		}
+	}
+	return err
+}
+
+// ReadPrompt joins a one-shot prompt from args and, when piped, stdin:
+// "git diff | groq-go -p summarize" sends "summarize" followed by the diff
+func ReadPrompt(args []string, stdin io.Reader, piped bool) (string, error) {
+	prompt := strings.Join(args, " ")
+	if !piped {
+		return prompt, nil
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", fmt.Errorf("failed to read stdin: %w", err)
+	}
+	if in := strings.TrimSpace(string(data)); in != "" {
+		if prompt != "" {
+			prompt += "\n\n"
+		}
+		prompt += in
+	}
+	return prompt, nil
+}
+
+// ExitCode is the process exit status for err: 0 on success, 2 at the turn
+// limit and 1 for any other failure
+func ExitCode(err error) int {
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, ErrMaxTurns):
+		return 2
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,174 @@
+package repl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+	"groq-go/internal/tool"
+)
+
+// loopingUpstream asks for the Read tool toolTurns times, then answers.
+// A negative toolTurns makes it fail with 401.
+func loopingUpstream(t *testing.T, toolTurns int) *httptest.Server {
+	t.Helper()
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if toolTurns < 0 {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":{"message":"Invalid API Key","type":"invalid_request_error"}}`)
+			return
+		}
+		chunk := client.StreamChunk{Choices: []client.Choice{{Delta: &client.Delta{Content: "all done"}, FinishReason: "stop"}}}
+		if n < toolTurns {
+			chunk = client.StreamChunk{Choices: []client.Choice{{Delta: &client.Delta{ToolCalls: []client.ToolCall{{
+				ID: fmt.Sprintf("call_%d", n), Type: "function",
+				Function: client.FunctionCall{Name: "Read", Arguments: `{"file_path":"a.txt"}`},
+			}}}, FinishReason: "tool_calls"}}}
+		}
+		n++
+		data, _ := json.Marshal(chunk)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newOneShotTestREPL(t *testing.T, upstream *httptest.Server) *REPL {
+	t.Helper()
+	saved := color.NoColor
+	t.Cleanup(func() { color.NoColor = saved })
+	registry := tool.NewRegistry()
+	registry.Register(&echoTool{name: "Read"})
+	return &REPL{
+		client:   client.New("key", client.WithBaseURL(upstream.URL), client.WithRetry(1, 0)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+		history:  conversation.NewHistory(100),
+		context:  conversation.NewContext(),
+		output:   NewOutput(io.Discard),
+	}
+}
+
+func runOnce(t *testing.T, r *REPL, output string, maxTurns int) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := r.RunOnce("read a.txt", OneShotOptions{Output: output, MaxTurns: maxTurns, Stdout: &stdout, Stderr: &stderr})
+	return stdout.String(), stderr.String(), err
+}
+
+func TestRunOnceJSON(t *testing.T) {
+	r := newOneShotTestREPL(t, loopingUpstream(t, 1))
+	stdout, stderr, err := runOnce(t, r, OutputJSON, 0)
+	if err != nil || ExitCode(err) != 0 {
+		t.Fatalf("RunOnce failed: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal([]byte(stdout), &got); err != nil {
+		t.Fatalf("Expected stdout to be one JSON object, got %q: %v", stdout, err)
+	}
+	for _, key := range []string{"model", "text", "tool_calls", "usage", "turns"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("Expected %q in %s", key, stdout)
+		}
+	}
+	if _, ok := got["error"]; ok {
+		t.Errorf("Expected no error field, got %s", stdout)
+	}
+
+	var res OneShotResult
+	json.Unmarshal([]byte(stdout), &res)
+	if res.Text != "all done" || res.Turns != 2 || res.Model != client.DefaultModel {
+		t.Errorf("Unexpected result %+v", res)
+	}
+	want := ToolCallRecord{ID: "call_0", Name: "Read", Arguments: `{"file_path":"a.txt"}`, Result: "Read ran"}
+	if len(res.ToolCalls) != 1 || res.ToolCalls[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, res.ToolCalls)
+	}
+	if !strings.Contains(stderr, "all done") || !strings.Contains(stderr, "Read") {
+		t.Errorf("Expected tokens and tool progress on stderr, got %q", stderr)
+	}
+	if strings.Contains(stderr, "\x1b[") {
+		t.Errorf("Expected no colors, got %q", stderr)
+	}
+}
+
+func TestRunOnceText(t *testing.T) {
+	r := newOneShotTestREPL(t, loopingUpstream(t, 1))
+	stdout, stderr, err := runOnce(t, r, OutputText, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stdout != "all done\n" {
+		t.Errorf("Expected only the reply on stdout, got %q", stdout)
+	}
+	if !strings.Contains(stderr, "Read") {
+		t.Errorf("Expected tool progress on stderr, got %q", stderr)
+	}
+}
+
+func TestRunOnceFailures(t *testing.T) {
+	// The model keeps calling tools past the limit
+	r := newOneShotTestREPL(t, loopingUpstream(t, 10))
+	stdout, _, err := runOnce(t, r, OutputJSON, 2)
+	if ExitCode(err) != 2 {
+		t.Errorf("Expected exit code 2 at the turn limit, got %d (%v)", ExitCode(err), err)
+	}
+	var res OneShotResult
+	if err := json.Unmarshal([]byte(stdout), &res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Turns != 2 || len(res.ToolCalls) != 2 || !strings.Contains(res.Error, "turn limit") {
+		t.Errorf("Expected the partial turn and an error, got %+v", res)
+	}
+
+	// The API rejects the request
+	r = newOneShotTestREPL(t, loopingUpstream(t, -1))
+	stdout, _, err = runOnce(t, r, OutputJSON, 0)
+	if ExitCode(err) != 1 {
+		t.Errorf("Expected exit code 1 on an API error, got %d (%v)", ExitCode(err), err)
+	}
+	json.Unmarshal([]byte(stdout), &res)
+	if !strings.Contains(res.Error, "API error") || res.ToolCalls == nil {
+		t.Errorf("Expected the API error and an empty tool call list, got %s", stdout)
+	}
+
+	if err := r.RunOnce("hi", OneShotOptions{Output: "yaml"}); err == nil {
+		t.Error("Expected an unknown output format to fail")
+	}
+	if err := r.RunOnce("  ", OneShotOptions{Output: OutputText}); err == nil {
+		t.Error("Expected an empty prompt to fail")
+	}
+}
+
+func TestReadPrompt(t *testing.T) {
+	tests := []struct {
+		args  []string
+		stdin string
+		piped bool
+		want  string
+	}{
+		{[]string{"summarize", "this"}, "", false, "summarize this"},
+		{nil, "  diff --git a b\n", true, "diff --git a b"},
+		{[]string{"summarize"}, "diff\n", true, "summarize\n\ndiff"},
+		{[]string{"hi"}, "ignored", false, "hi"},
+	}
+	for _, tt := range tests {
+		got, err := ReadPrompt(tt.args, strings.NewReader(tt.stdin), tt.piped)
+		if err != nil || got != tt.want {
+			t.Errorf("ReadPrompt(%q, %q) = %q, %v; want %q", tt.args, tt.stdin, got, err, tt.want)
+		}
+	}
+}
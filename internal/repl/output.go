@@ -4,19 +4,52 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"os"
 	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
 
+	"github.com/chzyer/readline"
 	"github.com/fatih/color"
+
+	"groq-go/internal/tool"
 )
 
 // Output handles formatted output to the terminal
 type Output struct {
-	writer io.Writer
+	writer   io.Writer
+	pretty   bool            // re-render streamed responses as markdown
+	streamed strings.Builder // raw text of the response being streamed
+	// screenSize reports the terminal size used to erase raw streamed text
+	screenSize func() (width, height int, err error)
+	status     bool // a tool progress line is showing and must be erased
+	spin       int  // spinner frame of the progress line
+
+	stream io.Writer // where response tokens go, see SetStreamWriter
 }
 
 // NewOutput creates a new output handler
 func NewOutput(w io.Writer) *Output {
-	return &Output{writer: w}
+	return &Output{writer: w, screenSize: func() (int, int, error) { return readline.GetSize(int(os.Stdout.Fd())) }}
+}
+
+// SetPretty enables markdown rendering of streamed responses. It stays off
+// when colors are disabled by NO_COLOR or because stdout is not a terminal.
+func (o *Output) SetPretty(enabled bool) {
+	o.pretty = enabled && !color.NoColor
+}
+
+// SetStreamWriter sends streamed response tokens to w instead of the
+// output's writer, so a script can read the reply apart from tool progress
+func (o *Output) SetStreamWriter(w io.Writer) {
+	o.stream = w
+}
+
+func (o *Output) streamWriter() io.Writer {
+	if o.stream != nil {
+		return o.stream
+	}
+	return o.writer
 }
 
 // Print prints a message
@@ -110,9 +143,12 @@ func shortenPath(path string) string {
 	return ".../" + strings.Join(parts[len(parts)-2:], "/")
 }
 
-// ToolResult prints a tool result
-func (o *Output) ToolResult(name string, result string, isError bool) {
-	if isError {
+// ToolResult prints a tool result, with a line count summary when the
+// tool reported a diff
+func (o *Output) ToolResult(name string, res tool.Result) {
+	o.clearStatus()
+	result := res.Content
+	if res.IsError {
 		red := color.New(color.FgRed)
 		red.Fprintf(o.writer, "  ✗ ")
 		// Show error message
@@ -150,6 +186,64 @@ func (o *Output) ToolResult(name string, result string, isError bool) {
 			}
 		}
 	}
+
+	if diff := res.Metadata[tool.MetadataDiff]; diff != "" {
+		added, removed := tool.DiffStats(diff)
+		fmt.Fprint(o.writer, "    ")
+		color.New(color.FgGreen).Fprintf(o.writer, "+%d", added)
+		fmt.Fprint(o.writer, " ")
+		color.New(color.FgRed).Fprintf(o.writer, "-%d", removed)
+		fmt.Fprintln(o.writer)
+	}
+}
+
+// spinnerFrames animate the tool progress line
+var spinnerFrames = []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+
+// ToolProgress shows a tool's current stage on a status line that each
+// report overwrites, advancing a spinner. pct is omitted when negative.
+func (o *Output) ToolProgress(name, stage string, pct int) {
+	gray := color.New(color.FgHiBlack)
+	line := fmt.Sprintf("  %s %s: %s", spinnerFrames[o.spin%len(spinnerFrames)], name, stage)
+	if pct >= 0 {
+		line += fmt.Sprintf(" %d%%", pct)
+	}
+	o.spin++
+	fmt.Fprint(o.writer, "\r\033[K")
+	gray.Fprint(o.writer, line)
+	o.status = true
+}
+
+// clearStatus erases the tool progress line before other output
+func (o *Output) clearStatus() {
+	if o.status {
+		fmt.Fprint(o.writer, "\r\033[K")
+		o.status = false
+	}
+}
+
+// ToolOutput prints an incremental progress line from a running tool
+func (o *Output) ToolOutput(stage string, detail string) {
+	o.clearStatus()
+	gray := color.New(color.FgHiBlack)
+	if len(detail) > 80 {
+		cut := 80
+		for cut > 0 && !utf8.RuneStart(detail[cut]) {
+			cut--
+		}
+		detail = detail[:cut] + "..."
+	}
+	gray.Fprintf(o.writer, "  ⋯ %s: %s\n", stage, detail)
+}
+
+// Question prints a clarification question with numbered choices
+func (o *Output) Question(text string, choices []string) {
+	o.clearStatus()
+	yellow := color.New(color.FgYellow, color.Bold)
+	yellow.Fprintf(o.writer, "\n? %s\n", text)
+	for i, choice := range choices {
+		fmt.Fprintf(o.writer, "  %d) %s\n", i+1, choice)
+	}
 }
 
 // Error prints an error message
@@ -182,12 +276,44 @@ func (o *Output) Muted(format string, args ...any) {
 	c.Fprintf(o.writer, format+"\n", args...)
 }
 
+// Table prints rows in aligned columns under a header
+func (o *Output) Table(headers []string, rows [][]string) {
+	tw := tabwriter.NewWriter(o.writer, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+}
+
+// StreamStart begins a streamed response
+func (o *Output) StreamStart() {
+	o.streamed.Reset()
+}
+
 // StreamToken prints a single token during streaming
 func (o *Output) StreamToken(token string) {
-	fmt.Fprint(o.writer, token)
+	fmt.Fprint(o.streamWriter(), token)
+	if o.pretty {
+		o.streamed.WriteString(token)
+	}
 }
 
-// StreamEnd ends a streaming output
+// StreamEnd ends a streaming output. In pretty mode the raw text is replaced
+// by its rendered markdown, or followed by it if it has scrolled off screen.
 func (o *Output) StreamEnd() {
-	fmt.Fprintln(o.writer)
+	fmt.Fprintln(o.streamWriter())
+	if !o.pretty || o.streamed.Len() == 0 {
+		return
+	}
+	text := o.streamed.String()
+	o.streamed.Reset()
+
+	if width, height, err := o.screenSize(); err == nil && width > 0 {
+		if rows := displayRows(text, width, readline.Runes{}.Width); rows < height {
+			// Move to the start of the raw text and clear to the end of the screen
+			fmt.Fprintf(o.writer, "\x1b[%dF\x1b[J", rows)
+		}
+	}
+	fmt.Fprint(o.writer, RenderMarkdown(text))
 }
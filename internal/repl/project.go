@@ -0,0 +1,98 @@
+package repl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"groq-go/internal/project"
+	"groq-go/internal/tool"
+)
+
+// SetProjects enables /project. While a project is current, file tools are
+// confined to its root unless sandboxDisabled is set.
+func (r *REPL) SetProjects(pm *project.Manager, sandboxDisabled bool) {
+	r.projects = pm
+	r.sandboxDisabled = sandboxDisabled
+}
+
+// sandbox returns the sandbox for the current project, or nil when there
+// is none or sandboxing is off
+func (r *REPL) sandbox() (*tool.Sandbox, error) {
+	if r.projects == nil || r.sandboxDisabled {
+		return nil, nil
+	}
+	proj := r.projects.Current()
+	if proj == nil {
+		return nil, nil
+	}
+	sb, err := tool.NewSandbox(proj.RootPath)
+	if err != nil {
+		return nil, fmt.Errorf("project %s is unavailable (/project use none to leave it): %w", proj.Name, err)
+	}
+	return sb, nil
+}
+
+func cmdProject(r *REPL, args string) error {
+	if r.projects == nil {
+		return fmt.Errorf("projects not available")
+	}
+	fields := strings.Fields(args)
+	switch {
+	case len(fields) == 0:
+		return listProjects(r)
+	case len(fields) == 2 && fields[0] == "use":
+	default:
+		return fmt.Errorf("usage: /project [use <id>|use none]")
+	}
+
+	id := fields[1]
+	if id == "none" {
+		if err := r.projects.SetCurrent(""); err != nil {
+			return err
+		}
+		r.output.Success("No project selected; file tools are not confined")
+		return nil
+	}
+	proj, err := r.projects.Get(id)
+	if err != nil {
+		return err
+	}
+	if !r.sandboxDisabled {
+		// Check the root before committing to it
+		if _, err := tool.NewSandbox(proj.RootPath); err != nil {
+			return err
+		}
+	}
+	if err := r.projects.SetCurrent(id); err != nil {
+		return err
+	}
+	if r.sandboxDisabled {
+		r.output.Success("Using project %s (%s); sandbox disabled", proj.Name, proj.RootPath)
+		return nil
+	}
+	r.output.Success("Using project %s; file tools are confined to %s", proj.Name, proj.RootPath)
+	return nil
+}
+
+func listProjects(r *REPL) error {
+	projects := r.projects.List()
+	if len(projects) == 0 {
+		r.output.Muted("No projects")
+		return nil
+	}
+	var current string
+	if proj := r.projects.Current(); proj != nil {
+		current = proj.ID
+	}
+	rows := make([][]string, 0, len(projects))
+	for _, p := range projects {
+		name := p.Name
+		if p.ID == current {
+			name += " [current]"
+		}
+		rows = append(rows, []string{p.ID, name, p.RootPath, p.UpdatedAt.Local().Format(time.DateTime)})
+	}
+	r.output.Table([]string{"ID", "NAME", "ROOT", "UPDATED"}, rows)
+	return nil
+}
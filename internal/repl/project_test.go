@@ -0,0 +1,43 @@
+package repl
+
+import (
+	"strings"
+	"testing"
+
+	"groq-go/internal/project"
+)
+
+func TestProjectUseSetsSandbox(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm, err := project.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	proj, _ := pm.Create("demo", root, "")
+
+	r, out := newSessionTestREPL(t, "/project\n/project use "+proj.ID+"\n")
+	r.SetProjects(pm, false)
+	if err := r.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "demo") || !strings.Contains(out.String(), "confined to "+root) {
+		t.Errorf("Expected the project listed and selected, got %q", out.String())
+	}
+	sb, err := r.sandbox()
+	if err != nil || sb.Root() != root {
+		t.Errorf("Expected a sandbox at %s, got %v, %v", root, sb, err)
+	}
+
+	r.SetProjects(pm, true)
+	if sb, _ := r.sandbox(); sb != nil {
+		t.Errorf("Expected no sandbox when disabled, got %s", sb.Root())
+	}
+
+	if err := cmdProject(r, "use none"); err != nil || pm.Current() != nil {
+		t.Errorf("Expected the project cleared, got %v", err)
+	}
+	if err := cmdProject(r, "use missing"); err == nil {
+		t.Error("Expected an unknown project rejected")
+	}
+}
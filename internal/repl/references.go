@@ -0,0 +1,134 @@
+package repl
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"groq-go/internal/tool"
+)
+
+// MaxReferenceBytes caps how much of a file an @-reference attaches
+const MaxReferenceBytes = 100 * 1024
+
+// fileRefPattern matches @path at the start of the input or after
+// whitespace, so addresses such as user@example.com are left alone
+var fileRefPattern = regexp.MustCompile(`(?:^|\s)@([^\s@]+)`)
+
+// expandFileRefs attaches the files named by @path tokens to input as
+// fenced blocks. Paths are resolved in the sandbox, if any. The returned
+// warnings name references that were skipped or truncated; their tokens
+// are left as typed.
+func expandFileRefs(input string, sandbox *tool.Sandbox) (string, []string) {
+	var blocks, warnings []string
+	seen := make(map[string]bool)
+	for _, m := range fileRefPattern.FindAllStringSubmatch(input, -1) {
+		ref := m[1]
+		if seen[ref] {
+			continue
+		}
+		seen[ref] = true
+
+		name, path, err := resolveFileRef(ref, sandbox)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("@%s: %v", ref, err))
+			continue
+		}
+		content, truncated, err := readFileRef(path)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("@%s: %v", ref, err))
+			continue
+		}
+		if truncated {
+			warnings = append(warnings, fmt.Sprintf("@%s: truncated to the first %d bytes", ref, MaxReferenceBytes))
+		}
+		blocks = append(blocks, fencedFile(name, content, truncated))
+	}
+	if len(blocks) == 0 {
+		return input, warnings
+	}
+	return input + "\n\n" + strings.Join(blocks, "\n\n"), warnings
+}
+
+// resolveFileRef finds the file ref names, returning the name as matched
+// and its path. Trailing punctuation, as in "look at @main.go, then", is
+// dropped if the file only exists without it.
+func resolveFileRef(ref string, sandbox *tool.Sandbox) (string, string, error) {
+	candidates := []string{ref}
+	if trimmed := strings.TrimRight(ref, ",.;:!?)"); trimmed != ref && trimmed != "" {
+		candidates = append(candidates, trimmed)
+	}
+	var firstErr error
+	for _, c := range candidates {
+		path, err := sandbox.Resolve(c)
+		if err == nil {
+			var info os.FileInfo
+			if info, err = os.Stat(path); err == nil && info.IsDir() {
+				err = fmt.Errorf("is a directory")
+			}
+		}
+		if err == nil {
+			return c, path, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	if errors.Is(firstErr, os.ErrNotExist) {
+		return "", "", fmt.Errorf("no such file")
+	}
+	return "", "", firstErr
+}
+
+// readFileRef reads up to MaxReferenceBytes of a text file
+func readFileRef(path string) (string, bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, MaxReferenceBytes+1))
+	if err != nil {
+		return "", false, err
+	}
+	truncated := len(data) > MaxReferenceBytes
+	if truncated {
+		data = data[:MaxReferenceBytes]
+		// Don't split a UTF-8 sequence at the cut
+		for i := 1; i < utf8.UTFMax && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+	if !utf8.Valid(data) {
+		return "", false, fmt.Errorf("not a text file")
+	}
+	return string(data), truncated, nil
+}
+
+// fencedFile formats a file's content for the message, with a fence longer
+// than any backtick run inside it
+func fencedFile(name, content string, truncated bool) string {
+	ticks := "```"
+	for strings.Contains(content, ticks) {
+		ticks += "`"
+	}
+	header := "File: " + name
+	if truncated {
+		header += fmt.Sprintf(" (first %d bytes)", MaxReferenceBytes)
+	}
+	return fmt.Sprintf("%s\n%s\n%s\n%s", header, ticks, strings.TrimRight(content, "\n"), ticks)
+}
+
+// expandReferences applies expandFileRefs to a message and reports its
+// warnings
+func (r *REPL) expandReferences(input string, sandbox *tool.Sandbox) string {
+	expanded, warnings := expandFileRefs(input, sandbox)
+	for _, w := range warnings {
+		r.output.Warning("%s", w)
+	}
+	return expanded
+}
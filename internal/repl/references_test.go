@@ -0,0 +1,54 @@
+package repl
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/tool"
+)
+
+func TestExpandFileRefs(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "main.go"), []byte("package main\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.md"), []byte("has ```code``` inside"), 0644)
+	os.WriteFile(filepath.Join(dir, "big.txt"), []byte(strings.Repeat("x", MaxReferenceBytes+10)), 0644)
+	os.WriteFile(filepath.Join(dir, "blob.bin"), []byte{0xff, 0xfe, 0x00}, 0644)
+	sb, err := tool.NewSandbox(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, warnings := expandFileRefs("review @main.go, and @notes.md", sb)
+	if !strings.HasPrefix(got, "review @main.go, and @notes.md\n\n") {
+		t.Errorf("Expected the input kept as typed, got %q", got)
+	}
+	if !strings.Contains(got, "File: main.go\n```\npackage main\n```") {
+		t.Errorf("Expected main.go fenced, got %q", got)
+	}
+	if !strings.Contains(got, "File: notes.md\n````\nhas ```code``` inside\n````") {
+		t.Errorf("Expected a longer fence around backticks, got %q", got)
+	}
+	if len(warnings) != 0 {
+		t.Errorf("Unexpected warnings %v", warnings)
+	}
+
+	got, warnings = expandFileRefs("@missing.go @big.txt @blob.bin @../outside user@example.com", sb)
+	if !strings.Contains(got, "File: big.txt (first") || strings.Contains(got, "missing.go\n") {
+		t.Errorf("Expected only big.txt attached, got %.200q", got)
+	}
+	want := []string{"@missing.go: no such file", "@big.txt: truncated", "@blob.bin: not a text file", "@../outside:"}
+	if len(warnings) != len(want) {
+		t.Fatalf("Expected %d warnings, got %v", len(want), warnings)
+	}
+	for i, w := range want {
+		if !strings.HasPrefix(warnings[i], w) {
+			t.Errorf("Expected warning %q, got %q", w, warnings[i])
+		}
+	}
+
+	if got, warnings := expandFileRefs("mail user@example.com", sb); got != "mail user@example.com" || len(warnings) != 0 {
+		t.Errorf("Expected an address left alone, got %q %v", got, warnings)
+	}
+}
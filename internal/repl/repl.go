@@ -7,10 +7,18 @@ import (
 	"io"
 	"os"
 	"os/signal"
+	"os/user"
+	"strings"
 	"syscall"
+	"time"
 
+	"groq-go/internal/audit"
 	"groq-go/internal/client"
 	"groq-go/internal/conversation"
+	"groq-go/internal/mcp"
+	"groq-go/internal/memory"
+	"groq-go/internal/project"
+	"groq-go/internal/storage"
 	"groq-go/internal/tool"
 )
 
@@ -26,6 +34,26 @@ type REPL struct {
 	input    *Input
 	output   *Output
 	commands map[string]Command
+	format   tool.Format           // formatting after Write/Edit, toggled with /format
+	options  client.RequestOptions // sampling overrides, changed with /set
+	storage  storage.Storage       // saved sessions, nil if unavailable
+	session  *storage.Session      // session of the last /save or /load
+	autosave bool                  // save the session on exit
+	reads    *tool.ReadTracker     // files the model has seen, reset with the conversation
+
+	approvals *tool.ApprovalRules // auto-approve rules, set with /approve and saved with the session
+
+	mcp    *mcp.Manager  // nil disables /mcp
+	memory *memory.Store // nil disables /memory
+
+	projects        *project.Manager // nil disables /project
+	sandboxDisabled bool             // file tools may leave the current project
+
+	maxTurns int          // API calls allowed per message, 0 for no limit; see RunOnce
+	usage    sessionUsage // tokens per model, shown by /usage
+
+	readOnlyModels []string // models /model may pick, nil unless read-only; see SetReadOnly
+	readOnlyTurns  int      // messages allowed per conversation in read-only mode
 }
 
 // New creates a new REPL instance
@@ -34,26 +62,90 @@ func New(c *client.Client, registry *tool.Registry) (*REPL, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize input: %w", err)
 	}
+	return newREPL(c, registry, input), nil
+}
 
+func newREPL(c *client.Client, registry *tool.Registry, input *Input) *REPL {
 	ctx := conversation.NewContext()
 	history := conversation.NewHistory(100)
 	history.Add(ctx.SystemMessage())
 
+	// Sessions live next to the web server's (STORAGE_BACKEND=sqlite for the database backend)
+	store, err := storage.Open(context.Background(), os.Getenv("STORAGE_BACKEND"), storage.DefaultStorageDir())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: session storage unavailable: %v\n", err)
+		store = nil
+	}
+
+	// Tool calls are recorded under the local user
+	executor := tool.NewExecutor(registry)
+	if auditLog, err := audit.NewLogger(audit.DefaultPath()); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: audit log unavailable: %v\n", err)
+	} else {
+		executor.AddHook(audit.NewHook(auditLog, localUsername()))
+	}
+
 	return &REPL{
 		client:   c,
 		registry: registry,
-		executor: tool.NewExecutor(registry),
+		executor: executor,
 		history:  history,
 		context:  ctx,
 		input:    input,
 		output:   NewOutput(os.Stdout),
 		commands: DefaultCommands(),
-	}, nil
+		storage:  store,
+		reads:    tool.NewReadTracker(),
+
+		approvals: tool.NewApprovalRules(nil),
+	}
+}
+
+// localUsername names the user running the REPL, for the audit log
+func localUsername() string {
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// SetSystemPrompt customizes the system prompt of this and later
+// conversations
+func (r *REPL) SetSystemPrompt(p *conversation.Prompt) {
+	r.context.SetPrompt(p, r.registry.ToClientTools)
+	r.history.SetSystem(r.context.SystemMessage())
+}
+
+// SetPretty controls markdown rendering of responses. Piped sessions always
+// stay plain.
+func (r *REPL) SetPretty(enabled bool) {
+	r.output.SetPretty(enabled && !r.input.IsPiped())
+}
+
+// SetFormat sets which files Write and Edit format, until /format
+// changes it
+func (r *REPL) SetFormat(f tool.Format) {
+	r.format = f
+}
+
+// SetAutosave controls whether the conversation is saved on a clean exit
+func (r *REPL) SetAutosave(enabled bool) {
+	r.autosave = enabled
+}
+
+// SetReadOnly limits /model to models and conversations to maxTurns
+// messages, for read-only mode. The registry hides the tools.
+func (r *REPL) SetReadOnly(models []string, maxTurns int) {
+	r.readOnlyModels = models
+	r.readOnlyTurns = maxTurns
 }
 
 // Run starts the REPL loop
 func (r *REPL) Run() error {
 	defer r.input.Close()
+	if r.storage != nil {
+		defer r.storage.Close()
+	}
 
 	if !r.input.IsPiped() {
 		r.printWelcome()
@@ -62,6 +154,7 @@ func (r *REPL) Run() error {
 	for {
 		line, err := r.input.ReadLine()
 		if IsEOF(err) {
+			r.autosaveSession()
 			if !r.input.IsPiped() {
 				r.output.Println()
 				r.output.Muted("Goodbye!")
@@ -85,6 +178,7 @@ func (r *REPL) Run() error {
 			if handler, ok := r.commands[cmd]; ok {
 				if err := handler.Handler(r, args); err != nil {
 					if errors.Is(err, ErrExit) {
+						r.autosaveSession()
 						if !r.input.IsPiped() {
 							r.output.Muted("Goodbye!")
 						}
@@ -106,76 +200,183 @@ func (r *REPL) Run() error {
 				continue
 			}
 			r.output.Error("%v", err)
+			if hint := apiErrorHint(err); hint != "" {
+				r.output.Muted("%s", hint)
+			}
 		}
 	}
 }
 
 func (r *REPL) processMessage(userInput string) error {
+	_, err := r.runTurn(userInput)
+	return err
+}
+
+// apiErrorHint suggests what to do about a failed model request, or
+// returns "" if there is nothing specific to suggest
+func apiErrorHint(err error) string {
+	var apiErr *client.APIError
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+	switch {
+	case client.IsRateLimit(err):
+		if apiErr.RetryAfter > 0 {
+			return fmt.Sprintf("Hint: %s is rate limiting requests; try again in %s, or switch models with /model", apiErr.Provider, apiErr.RetryAfter.Round(time.Second))
+		}
+		return fmt.Sprintf("Hint: %s is rate limiting requests; wait a moment, or switch models with /model", apiErr.Provider)
+	case client.IsAuthError(err):
+		return fmt.Sprintf("Hint: %s rejected the API key; check %s_API_KEY or your config file", apiErr.Provider, strings.ToUpper(apiErr.Provider))
+	case client.IsContextLengthExceeded(err):
+		return "Hint: the conversation is too long for this model; start over with /clear, or switch to a model with a larger context window with /model"
+	}
+	return ""
+}
+
+// runTurn sends userInput and runs tool calls until the model answers. The
+// result covers what happened even when an error cut the turn short.
+func (r *REPL) runTurn(userInput string) (*TurnResult, error) {
+	result := &TurnResult{Model: r.client.Model()}
+	if r.readOnlyModels != nil && conversation.UserTurns(r.history.Messages()) >= r.readOnlyTurns {
+		return result, fmt.Errorf("read-only mode allows %d messages per conversation; use /clear to start over", r.readOnlyTurns)
+	}
+	sandbox, err := r.sandbox()
+	if err != nil {
+		return result, err
+	}
+	userInput = r.expandReferences(userInput, sandbox)
+
 	// Set up cancellation with Ctrl+C
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	done := ctx.Done() // ctx is rewrapped below
 	go func() {
 		select {
 		case <-sigCh:
 			cancel()
-		case <-ctx.Done():
+		case <-done:
 		}
 	}()
 	defer signal.Stop(sigCh)
 
+	// Let tools pause the turn to ask the user a question
+	ctx = tool.WithAsk(ctx, r.askUser)
+	ctx = tool.WithApproval(ctx, r.approveCall, r.approvals)
+	ctx = tool.WithFormat(ctx, r.format)
+	ctx = tool.WithReadTracker(ctx, r.reads)
+	ctx = tool.WithSandbox(ctx, sandbox)
+	ctx = r.executor.StartTurn(ctx)
+	if r.session != nil {
+		ctx = tool.WithCaller(ctx, tool.Caller{SessionID: r.session.ID})
+	}
+
 	// Add user message to history
 	r.history.Add(client.Message{
 		Role:    "user",
 		Content: userInput,
 	})
 
-	// Get tools for the API
-	tools := r.registry.ToClientTools()
+	// Get tools for the API, fitted to what the model accepts
+	model := r.client.Model()
+	toolBudget, halved := client.ToolBudget(model), false
+	servedModel := model
+	tools := r.registry.ToClientToolsBudgeted(model, toolBudget)
 
 	// Main conversation loop
+	defer func() {
+		if u := result.Usage; u.TotalTokens > 0 {
+			r.output.Muted("Tokens: %d prompt + %d completion = %d", u.PromptTokens, u.CompletionTokens, u.TotalTokens)
+		}
+	}()
 	for {
 		select {
 		case <-ctx.Done():
-			return ctx.Err()
+			return result, ctx.Err()
 		default:
 		}
+		if r.maxTurns > 0 && result.Turns >= r.maxTurns {
+			return result, fmt.Errorf("%w after %d", ErrMaxTurns, result.Turns)
+		}
+		result.Turns++
+
+		// Summarize old turns before the history outgrows the context window
+		if r.history.NeedsCompaction(model) {
+			before := conversation.EstimateTokens(r.history.Messages())
+			if err := r.history.Compact(ctx, r.client); err != nil {
+				r.output.Warning("%v", err)
+			}
+			r.output.Muted("Compacted conversation history: ~%d -> ~%d tokens", before, conversation.EstimateTokens(r.history.Messages()))
+		}
 
 		// Call the API with streaming
-		stream, err := r.client.ChatCompletionStream(ctx, r.history.Messages(), tools)
+		stream, err := r.client.ChatCompletionStream(ctx, r.history.Messages(), tools, r.options)
+		if err != nil && client.IsContextLengthExceeded(err) && !halved {
+			// Retry once with half the tool budget for the rest of the turn
+			toolBudget, halved = toolBudget/2, true
+			tools = r.registry.ToClientToolsBudgeted(model, toolBudget)
+			r.output.Warning("Request too large for %s, retrying with fewer tools", model)
+			stream, err = r.client.ChatCompletionStream(ctx, r.history.Messages(), tools, r.options)
+		}
 		if err != nil {
-			return fmt.Errorf("API error: %w", err)
+			return result, fmt.Errorf("API error: %w", err)
+		}
+		if served := stream.Model(); served != servedModel {
+			servedModel = served
+			result.Model = served
+			if served != model {
+				r.output.Warning("%s is unavailable, fell back to %s", model, served)
+			}
 		}
 
 		// Collect the response while streaming
 		msg, finishReason, err := r.streamResponse(ctx, stream)
 		stream.Close()
+		result.Usage.Add(stream.Usage())
+		r.usage.add(servedModel, stream.Usage())
 
 		if err != nil {
 			if errors.Is(err, context.Canceled) {
-				return err
+				return result, err
 			}
-			return fmt.Errorf("stream error: %w", err)
+			return result, fmt.Errorf("stream error: %w", err)
 		}
 
 		// Add assistant message to history
 		r.history.Add(*msg)
+		result.Text, _ = msg.Content.(string)
 
 		// Check if we need to execute tools
 		if finishReason == "tool_calls" && len(msg.ToolCalls) > 0 {
-			// Execute tool calls
+			// Execute independent tool calls concurrently
 			for _, tc := range msg.ToolCalls {
 				r.output.ToolCall(tc.Function.Name, tc.Function.Arguments)
+			}
+			results := r.executor.ExecuteToolCallsParallelWithProgress(ctx, msg.ToolCalls, tool.DefaultMaxConcurrency,
+				func(tc client.ToolCall, stage, detail string) {
+					r.output.ToolOutput(stage, detail)
+				},
+				func(tc client.ToolCall, stage string, pct int) {
+					r.output.ToolProgress(tc.Function.Name, stage, pct)
+				})
 
-				result, _ := r.executor.ExecuteToolCall(ctx, tc)
-				r.output.ToolResult(tc.Function.Name, result.Content, result.IsError)
+			// Results go into history in the original call order
+			for i, tc := range msg.ToolCalls {
+				res := results[i]
+				r.output.ToolResult(tc.Function.Name, res)
+				result.ToolCalls = append(result.ToolCalls, ToolCallRecord{
+					ID:        tc.ID,
+					Name:      tc.Function.Name,
+					Arguments: tc.Function.Arguments,
+					Result:    res.Content,
+					IsError:   res.IsError,
+				})
 
-				// Add tool result to history
 				r.history.Add(client.Message{
 					Role:       "tool",
-					Content:    result.Content,
+					Content:    res.Content,
 					ToolCallID: tc.ID,
 				})
 			}
@@ -188,7 +389,7 @@ func (r *REPL) processMessage(userInput string) error {
 		break
 	}
 
-	return nil
+	return result, nil
 }
 
 func (r *REPL) streamResponse(ctx context.Context, stream *client.StreamReader) (*client.Message, string, error) {
@@ -198,6 +399,7 @@ func (r *REPL) streamResponse(ctx context.Context, stream *client.StreamReader)
 	toolCallsMap := make(map[int]*client.ToolCall)
 
 	r.output.Println()
+	r.output.StreamStart()
 
 	for {
 		select {
@@ -283,6 +485,26 @@ func (r *REPL) streamResponse(ctx context.Context, stream *client.StreamReader)
 	return msg, finishReason, nil
 }
 
+// askUser prompts for an answer to a tool's clarification question.
+// Ctrl+C or EOF at the prompt cancels the question.
+func (r *REPL) askUser(ctx context.Context, q tool.Question) (string, error) {
+	r.output.Question(q.Text, q.Choices)
+	r.input.SetPrompt("answer> ")
+	defer r.input.SetPrompt("> ")
+
+	line, err := r.input.ReadLine()
+	if IsInterrupt(err) || IsEOF(err) {
+		return "", tool.ErrQuestionCancelled
+	}
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	return q.Resolve(line), nil
+}
+
 func (r *REPL) printWelcome() {
 	r.output.Println()
 	r.output.Info("groq-go")
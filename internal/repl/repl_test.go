@@ -0,0 +1,238 @@
+package repl
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+	"groq-go/internal/tool"
+)
+
+// echoTool returns its arguments
+type echoTool struct{ name string }
+
+func (t *echoTool) Name() string               { return t.name }
+func (t *echoTool) Description() string        { return "" }
+func (t *echoTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+
+func (t *echoTool) Execute(ctx context.Context, args json.RawMessage) (tool.Result, error) {
+	return tool.NewResult(t.name + " ran"), nil
+}
+
+func TestProcessMessageWithRestrictedTools(t *testing.T) {
+	var mu sync.Mutex
+	var requests []client.ChatCompletionRequest
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		n := len(requests)
+		requests = append(requests, req)
+		mu.Unlock()
+
+		// The model asks for a denied tool first, then answers
+		delta := client.Delta{Content: "done"}
+		finish := "stop"
+		if n == 0 {
+			delta = client.Delta{ToolCalls: []client.ToolCall{{
+				ID: "call_1", Type: "function",
+				Function: client.FunctionCall{Name: "Bash", Arguments: "{}"},
+			}}}
+			finish = "tool_calls"
+		}
+		data, _ := json.Marshal(client.StreamChunk{Choices: []client.Choice{{Delta: &delta, FinishReason: finish}}})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	}))
+	defer upstream.Close()
+
+	registry := tool.NewRegistry()
+	registry.Register(&echoTool{name: "Read"})
+	registry.Register(&echoTool{name: "Bash"})
+	registry.SetPolicy(&tool.Policy{Deny: []string{"Bash"}})
+
+	var out bytes.Buffer
+	r := &REPL{
+		client:   client.New("key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+		history:  conversation.NewHistory(100),
+		output:   NewOutput(&out),
+	}
+	if err := r.processMessage("list files"); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("Expected the turn to continue after the rejected call, got %d requests", len(requests))
+	}
+	for _, req := range requests {
+		for _, tl := range req.Tools {
+			if tl.Function.Name == "Bash" {
+				t.Error("Expected Bash not to be offered to the model")
+			}
+		}
+	}
+	msgs := requests[1].Messages
+	last := msgs[len(msgs)-1]
+	if last.Role != "tool" || !strings.Contains(fmt.Sprint(last.Content), "disabled by the server's tool policy") {
+		t.Errorf("Expected a policy rejection in history, got %+v", last)
+	}
+}
+
+func TestProcessMessageCompactsHistory(t *testing.T) {
+	var mu sync.Mutex
+	var sent []client.ChatCompletionRequest
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		sent = append(sent, req)
+		mu.Unlock()
+
+		if !req.Stream {
+			json.NewEncoder(w).Encode(client.ChatCompletionResponse{Choices: []client.Choice{{
+				Message: client.Message{Role: "assistant", Content: "earlier work summarized"},
+			}}})
+			return
+		}
+		data, _ := json.Marshal(client.StreamChunk{Choices: []client.Choice{{Delta: &client.Delta{Content: "ok"}, FinishReason: "stop"}}})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	}))
+	defer upstream.Close()
+
+	defer func(v int) { conversation.ContextLimit = v }(conversation.ContextLimit)
+	conversation.ContextLimit = 2000
+
+	registry := tool.NewRegistry()
+	var out bytes.Buffer
+	r := &REPL{
+		client:   client.New("key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+		history:  conversation.NewHistory(1000),
+		output:   NewOutput(&out),
+	}
+	r.history.Add(client.Message{Role: "system", Content: "system prompt"})
+	for i := 0; i < 20; i++ {
+		r.history.Add(client.Message{Role: "user", Content: strings.Repeat("q", 400)})
+		r.history.Add(client.Message{Role: "assistant", Content: strings.Repeat("a", 400)})
+	}
+
+	if err := r.processMessage("latest question"); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if len(sent) != 2 || sent[0].Stream || !sent[1].Stream {
+		t.Fatalf("Expected a summary request then the chat request, got %d requests", len(sent))
+	}
+	msgs := sent[1].Messages
+	if msgs[0].Content != "system prompt" || !strings.Contains(fmt.Sprint(msgs[1].Content), "earlier work summarized") {
+		t.Errorf("Expected system prompt then summary, got %+v", msgs[:2])
+	}
+	if last := msgs[len(msgs)-1]; last.Content != "latest question" {
+		t.Errorf("Expected the latest question to be kept, got %+v", last)
+	}
+	if !strings.Contains(out.String(), "Compacted conversation history") {
+		t.Errorf("Expected a compaction notice, got %q", out.String())
+	}
+}
+
+func TestToolProgressStatusLine(t *testing.T) {
+	var out bytes.Buffer
+	o := NewOutput(&out)
+	o.ToolProgress("Browser", "starting", -1)
+	o.ToolProgress("Browser", "capturing screenshot", 50)
+	o.ToolResult("Browser", tool.NewResult("saved"))
+
+	got := out.String()
+	if !strings.Contains(got, "⠋ Browser: starting") || !strings.Contains(got, "⠙ Browser: capturing screenshot 50%") {
+		t.Errorf("Expected a spinner line per report, got %q", got)
+	}
+	// Each report overwrites the line, and the result erases it
+	if n := strings.Count(got, "\r\033[K"); n != 3 {
+		t.Errorf("Expected the status line erased 3 times, got %d in %q", n, got)
+	}
+	if strings.Contains(got, "starting\n") {
+		t.Errorf("Expected progress to stay on one line, got %q", got)
+	}
+}
+
+func TestToolOutputTruncatesOnRune(t *testing.T) {
+	var out bytes.Buffer
+	o := NewOutput(&out)
+	// Byte 80 falls in the middle of a two-byte rune
+	o.ToolOutput("compile", "x"+strings.Repeat("é", 60))
+
+	if got := out.String(); !utf8.ValidString(got) || !strings.Contains(got, "x"+strings.Repeat("é", 39)+"...") {
+		t.Errorf("Expected the detail cut between runes, got %q", got)
+	}
+}
+
+func TestProcessMessageRetriesWithFewerTools(t *testing.T) {
+	var mu sync.Mutex
+	var sent []client.ChatCompletionRequest
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req client.ChatCompletionRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		mu.Lock()
+		n := len(sent)
+		sent = append(sent, req)
+		mu.Unlock()
+
+		if n == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"Please reduce the length of the messages or completion.","type":"invalid_request_error","code":"context_length_exceeded"}}`)
+			return
+		}
+		data, _ := json.Marshal(client.StreamChunk{Choices: []client.Choice{{Delta: &client.Delta{Content: "ok"}, FinishReason: "stop"}}})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	}))
+	defer upstream.Close()
+
+	registry := tool.NewRegistry()
+	for _, name := range tool.CoreTools {
+		registry.Register(&echoTool{name: name})
+	}
+	for i := 0; i < 40; i++ {
+		registry.Register(&describedTool{echoTool{name: fmt.Sprintf("mcp_%02d", i)}})
+	}
+
+	var out bytes.Buffer
+	r := &REPL{
+		client:   client.New("key", client.WithBaseURL(upstream.URL), client.WithModel("llama-3.1-8b-instant")),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+		history:  conversation.NewHistory(100),
+		output:   NewOutput(&out),
+	}
+	if err := r.processMessage("hello"); err != nil {
+		t.Fatalf("processMessage failed: %v", err)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("Expected one retry, got %d requests", len(sent))
+	}
+	if len(sent[1].Tools) >= len(sent[0].Tools) || len(sent[1].Tools) < len(tool.CoreTools) {
+		t.Errorf("Expected fewer tools on retry, got %d then %d", len(sent[0].Tools), len(sent[1].Tools))
+	}
+	if !strings.Contains(out.String(), "retrying with fewer tools") {
+		t.Errorf("Expected a retry warning, got:\n%s", out.String())
+	}
+}
+
+// describedTool has a long description, as MCP tools often do
+type describedTool struct{ echoTool }
+
+func (t *describedTool) Description() string {
+	return strings.Repeat("Detailed instructions for this tool. ", 30)
+}
@@ -0,0 +1,255 @@
+package repl
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+	"groq-go/internal/storage"
+	"groq-go/internal/tool"
+)
+
+// newSessionID returns an ID for a session saved from the CLI
+func newSessionID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return "cli-" + hex.EncodeToString(b)
+}
+
+// validSessionID reports whether id is safe to use as a storage key
+func validSessionID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// hasUserMessage reports whether the history holds anything worth saving
+func (r *REPL) hasUserMessage() bool {
+	for _, msg := range r.history.Messages() {
+		if msg.Role == "user" {
+			return true
+		}
+	}
+	return false
+}
+
+// saveSession writes the history to storage under the current session,
+// starting a new one if needed. The system prompt is not stored.
+func (r *REPL) saveSession(title string) (*storage.Session, error) {
+	if r.storage == nil {
+		return nil, fmt.Errorf("session storage not available")
+	}
+	if r.session == nil {
+		r.session = &storage.Session{ID: newSessionID()}
+	}
+	if title != "" {
+		r.session.Title = title
+	}
+
+	var msgs []client.Message
+	for _, msg := range r.history.Messages() {
+		if msg.Role != "system" {
+			msgs = append(msgs, msg)
+		}
+	}
+	r.session.Messages = msgs
+	r.session.ApprovalRules = r.approvals.List()
+
+	if err := r.storage.SaveSession(context.Background(), r.session); err != nil {
+		return nil, err
+	}
+	return r.session, nil
+}
+
+// autosaveSession saves the conversation on exit when autosave is on
+func (r *REPL) autosaveSession() {
+	if !r.autosave || !r.hasUserMessage() {
+		return
+	}
+	session, err := r.saveSession("")
+	if err != nil {
+		r.output.Error("Autosave failed: %v", err)
+		return
+	}
+	r.output.Muted("Session saved as %s", session.ID)
+}
+
+func cmdSave(r *REPL, args string) error {
+	if !r.hasUserMessage() {
+		return fmt.Errorf("nothing to save yet")
+	}
+	session, err := r.saveSession(strings.TrimSpace(args))
+	if err != nil {
+		return err
+	}
+	r.output.Success("Session saved: %s", session.ID)
+	return nil
+}
+
+func cmdLoad(r *REPL, args string) error {
+	id := strings.TrimSpace(args)
+	if id == "" {
+		return fmt.Errorf("usage: /load <id> (see /sessions)")
+	}
+	if r.storage == nil {
+		return fmt.Errorf("session storage not available")
+	}
+	if !validSessionID(id) {
+		return fmt.Errorf("invalid session ID: %s", id)
+	}
+	session, err := r.storage.LoadSession(context.Background(), id)
+	if err != nil {
+		return err
+	}
+	if session == nil {
+		return fmt.Errorf("session not found: %s", id)
+	}
+
+	// The system prompt always comes from the current context, never from storage
+	r.history.Clear()
+	r.reads.Reset()
+	r.history.Add(r.context.SystemMessage())
+	n := 0
+	for _, msg := range session.Messages {
+		if msg.Role != "system" {
+			r.history.Add(msg)
+			n++
+		}
+	}
+	r.session = session
+	r.approvals = tool.NewApprovalRules(session.ApprovalRules)
+
+	title := session.Title
+	if title == "" {
+		title = "(untitled)"
+	}
+	r.output.Success("Loaded %s: %s (%d messages)", session.ID, title, n)
+	return nil
+}
+
+func cmdSessions(r *REPL, args string) error {
+	if r.storage == nil {
+		return fmt.Errorf("session storage not available")
+	}
+	switch sub, query, _ := strings.Cut(strings.TrimSpace(args), " "); sub {
+	case "":
+	case "search":
+		return searchSessions(r, query)
+	default:
+		return fmt.Errorf("usage: /sessions [search <terms>]")
+	}
+	sessions, err := r.storage.ListSessions(context.Background())
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		r.output.Muted("No saved sessions")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(sessions))
+	for _, s := range sessions {
+		title := s.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		rows = append(rows, []string{s.ID, title, s.UpdatedAt.Local().Format(time.DateTime)})
+	}
+	r.output.Table([]string{"ID", "TITLE", "UPDATED"}, rows)
+	return nil
+}
+
+// searchSessions lists the saved sessions matching query, best first,
+// with the text around each first match
+func searchSessions(r *REPL, query string) error {
+	if strings.TrimSpace(query) == "" {
+		return fmt.Errorf("usage: /sessions search <terms>")
+	}
+	matches, err := r.storage.SearchSessions(context.Background(), query, "", storage.DefaultSearchLimit)
+	if err != nil {
+		return err
+	}
+	if len(matches) == 0 {
+		r.output.Muted("No sessions match %q", strings.TrimSpace(query))
+		return nil
+	}
+	for _, m := range matches {
+		title := m.Title
+		if title == "" {
+			title = "(untitled)"
+		}
+		r.output.Info("%s  %s  %s", m.ID, title, m.UpdatedAt.Local().Format(time.DateTime))
+		if m.Snippet != "" {
+			r.output.Muted("    %s", m.Snippet)
+		}
+	}
+	r.output.Muted("Load one with /load <id>")
+	return nil
+}
+
+func cmdExport(r *REPL, args string) error {
+	path := strings.TrimSpace(args)
+	if path == "" {
+		return fmt.Errorf("usage: /export <file.md|file.json>")
+	}
+	format, err := conversation.ExportFormat(path)
+	if err != nil {
+		return err
+	}
+	var title string
+	if r.session != nil {
+		title = r.session.Title
+	}
+	data, err := conversation.Export(r.history.Messages(), format, title)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	r.output.Success("Exported %d messages to %s", r.history.Len(), path)
+	return nil
+}
+
+func cmdImport(r *REPL, args string) error {
+	path := strings.TrimSpace(args)
+	if path == "" {
+		return fmt.Errorf("usage: /import <file.json>")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read import: %w", err)
+	}
+	t, err := conversation.Import(data)
+	if err != nil {
+		return err
+	}
+
+	// As with /load, the system prompt comes from the current context
+	r.history.Clear()
+	r.reads.Reset()
+	r.history.Add(r.context.SystemMessage())
+	n := 0
+	for _, msg := range t.Messages {
+		if msg.Role != "system" {
+			r.history.Add(msg)
+			n++
+		}
+	}
+	// The next /save starts a new session
+	r.session = nil
+	r.output.Success("Imported %d messages from %s", n, path)
+	return nil
+}
@@ -0,0 +1,212 @@
+package repl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+	"groq-go/internal/storage"
+	"groq-go/internal/tool"
+)
+
+// newSessionTestREPL returns a REPL reading script line by line, with
+// sessions stored in a temp dir
+func newSessionTestREPL(t *testing.T, script string) (*REPL, *bytes.Buffer) {
+	t.Helper()
+	store, err := storage.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := conversation.NewContext()
+	history := conversation.NewHistory(100)
+	history.Add(ctx.SystemMessage())
+	registry := tool.NewRegistry()
+
+	var out bytes.Buffer
+	return &REPL{
+		client:    client.New("key"),
+		registry:  registry,
+		executor:  tool.NewExecutor(registry),
+		history:   history,
+		context:   ctx,
+		input:     &Input{isPiped: true, scanner: bufio.NewScanner(strings.NewReader(script))},
+		output:    NewOutput(&out),
+		commands:  DefaultCommands(),
+		storage:   store,
+		approvals: tool.NewApprovalRules(nil),
+	}, &out
+}
+
+func TestSaveLoadAndListSessions(t *testing.T) {
+	r, out := newSessionTestREPL(t, "/save\n/save First chat\n/clear\n/sessions\n")
+	r.history.Add(client.Message{Role: "user", Content: "hello"})
+	r.history.Add(client.Message{Role: "assistant", Content: "hi there"})
+
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	sessions, err := r.storage.ListSessions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 1 {
+		t.Fatalf("Expected the second /save to update the same session, got %d", len(sessions))
+	}
+	id := sessions[0].ID
+	if sessions[0].Title != "First chat" {
+		t.Errorf("Expected title %q, got %q", "First chat", sessions[0].Title)
+	}
+	stored, _ := r.storage.LoadSession(context.Background(), id)
+	for _, msg := range stored.Messages {
+		if msg.Role == "system" {
+			t.Error("Expected the system prompt not to be stored")
+		}
+	}
+	if !strings.Contains(out.String(), id) || !strings.Contains(out.String(), "UPDATED") {
+		t.Errorf("Expected /sessions to print a table with %s, got:\n%s", id, out.String())
+	}
+
+	// Loading replaces the history and puts the current system prompt first
+	r.history.Add(client.Message{Role: "user", Content: "unrelated"})
+	if err := cmdLoad(r, id); err != nil {
+		t.Fatalf("/load failed: %v", err)
+	}
+	msgs := r.history.Messages()
+	if len(msgs) != 3 {
+		t.Fatalf("Expected system + 2 messages, got %d", len(msgs))
+	}
+	if msgs[0].Role != "system" || msgs[0].Content != r.context.SystemMessage().Content {
+		t.Errorf("Expected the current system prompt first, got %+v", msgs[0])
+	}
+	if msgs[1].Content != "hello" || msgs[2].Content != "hi there" {
+		t.Errorf("Unexpected restored history: %+v", msgs[1:])
+	}
+}
+
+func TestLoadRejectsBadIDs(t *testing.T) {
+	r, _ := newSessionTestREPL(t, "")
+	for _, id := range []string{"", "../etc/passwd", "missing"} {
+		if err := cmdLoad(r, id); err == nil {
+			t.Errorf("Expected /load %q to fail", id)
+		}
+	}
+}
+
+func TestAutosaveOnExit(t *testing.T) {
+	r, _ := newSessionTestREPL(t, "/exit\n")
+	r.history.Add(client.Message{Role: "user", Content: "remember me"})
+	r.SetAutosave(true)
+
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	sessions, _ := r.storage.ListSessions(context.Background())
+	if len(sessions) != 1 || sessions[0].Title != "remember me" {
+		t.Errorf("Expected one autosaved session, got %+v", sessions)
+	}
+
+	// Nothing is saved for an empty conversation
+	r, _ = newSessionTestREPL(t, "")
+	r.SetAutosave(true)
+	r.Run()
+	if sessions, _ := r.storage.ListSessions(context.Background()); len(sessions) != 0 {
+		t.Errorf("Expected no session for an empty conversation, got %d", len(sessions))
+	}
+}
+
+func TestExportImportCommands(t *testing.T) {
+	r, out := newSessionTestREPL(t, "")
+	r.history.Add(client.NewTextMessage("user", "read a.txt"))
+	r.history.Add(client.Message{Role: "assistant", ToolCalls: []client.ToolCall{{ID: "call_1", Type: "function", Function: client.FunctionCall{Name: "Read", Arguments: `{}`}}}})
+	r.history.Add(client.Message{Role: "tool", ToolCallID: "call_1", Content: "hello"})
+	r.history.Add(client.NewTextMessage("assistant", "It says hello."))
+	want := r.history.Messages()
+
+	dir := t.TempDir()
+	for _, name := range []string{"chat.md", "chat.json"} {
+		if err := cmdExport(r, filepath.Join(dir, name)); err != nil {
+			t.Fatalf("/export %s failed: %v", name, err)
+		}
+	}
+	md, _ := os.ReadFile(filepath.Join(dir, "chat.md"))
+	if !strings.Contains(string(md), "## Assistant\n\nIt says hello.") {
+		t.Errorf("Unexpected markdown export:\n%s", md)
+	}
+	if err := cmdExport(r, filepath.Join(dir, "chat.txt")); err == nil {
+		t.Error("Expected an unknown extension to fail")
+	}
+
+	cmdClear(r, "")
+	if err := cmdImport(r, filepath.Join(dir, "chat.md")); err == nil {
+		t.Error("Expected markdown imports to fail")
+	}
+	if err := cmdImport(r, filepath.Join(dir, "chat.json")); err != nil {
+		t.Fatalf("/import failed: %v", err)
+	}
+	if got := r.history.Messages(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected the conversation restored:\n got %+v\nwant %+v", got, want)
+	}
+	if !strings.Contains(out.String(), "Imported 4 messages") {
+		t.Errorf("Unexpected output:\n%s", out.String())
+	}
+}
+
+func TestSessionsSearch(t *testing.T) {
+	r, out := newSessionTestREPL(t, "/save\n/sessions search websocket\n/sessions search nothing\n")
+	r.history.Add(client.Message{Role: "user", Content: "why does the websocket drop?"})
+
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if !strings.Contains(out.String(), "**websocket**") {
+		t.Errorf("Expected a snippet of the match, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), `No sessions match "nothing"`) {
+		t.Errorf("Expected a message for no matches, got:\n%s", out.String())
+	}
+	if err := cmdSessions(r, "find x"); err == nil {
+		t.Error("Expected an unknown subcommand to fail")
+	}
+}
+
+func TestApproveCommandAndPrompt(t *testing.T) {
+	r, out := newSessionTestREPL(t, "/approve Bash ^go test\n/approve Git\n/approve rm 2\n/approve\n/save\n")
+	r.history.Add(client.Message{Role: "user", Content: "run the tests"})
+	if err := r.Run(); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if got := r.approvals.List(); len(got) != 1 || got[0].String() != "Bash ^go test" {
+		t.Fatalf("Expected one rule left, got %v", got)
+	}
+	if !strings.Contains(out.String(), "1) Bash ^go test") {
+		t.Errorf("Expected the rules listed, got:\n%s", out.String())
+	}
+	if err := cmdApprove(r, "Bash ("); err == nil {
+		t.Error("Expected an invalid pattern to fail")
+	}
+
+	sessions, _ := r.storage.ListSessions(context.Background())
+	r.approvals = tool.NewApprovalRules(nil)
+	if err := cmdLoad(r, sessions[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.approvals.List(); len(got) != 1 {
+		t.Errorf("Expected the rules restored with the session, got %v", got)
+	}
+
+	for script, want := range map[string]tool.Decision{"y\n": tool.Approve, "always\n": tool.ApproveAlways, "n\n": tool.Deny, "": tool.Deny} {
+		r, _ := newSessionTestREPL(t, script)
+		got, err := r.approveCall(context.Background(), tool.ApprovalRequest{Tool: "Bash", Subject: "rm -rf build"})
+		if err != nil || got != want {
+			t.Errorf("Answer %q: expected %v, got %v, %v", script, want, got, err)
+		}
+	}
+}
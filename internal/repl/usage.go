@@ -0,0 +1,72 @@
+package repl
+
+import (
+	"sort"
+	"strconv"
+
+	"groq-go/internal/client"
+)
+
+// sessionUsage totals API usage per model since the REPL started
+type sessionUsage struct {
+	models map[string]*modelUsage
+}
+
+type modelUsage struct {
+	requests int
+	usage    client.Usage
+}
+
+// add records one API call to model
+func (s *sessionUsage) add(model string, u client.Usage) {
+	if s.models == nil {
+		s.models = make(map[string]*modelUsage)
+	}
+	m := s.models[model]
+	if m == nil {
+		m = &modelUsage{}
+		s.models[model] = m
+	}
+	m.requests++
+	m.usage.Add(u)
+}
+
+func cmdUsage(r *REPL, args string) error {
+	if len(r.usage.models) == 0 {
+		r.output.Info("No API calls yet in this session")
+		return nil
+	}
+
+	names := make([]string, 0, len(r.usage.models))
+	for name := range r.usage.models {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var total modelUsage
+	rows := make([][]string, 0, len(names)+1)
+	for _, name := range names {
+		m := r.usage.models[name]
+		total.requests += m.requests
+		total.usage.Add(m.usage)
+		rows = append(rows, usageRow(name, m))
+	}
+	if len(names) > 1 {
+		rows = append(rows, usageRow("total", &total))
+	}
+	r.output.Table([]string{"MODEL", "REQUESTS", "PROMPT", "COMPLETION", "TOTAL"}, rows)
+	if total.usage.CacheReadTokens > 0 {
+		r.output.Muted("%d prompt tokens read from cache", total.usage.CacheReadTokens)
+	}
+	return nil
+}
+
+func usageRow(name string, m *modelUsage) []string {
+	return []string{
+		name,
+		strconv.Itoa(m.requests),
+		strconv.Itoa(m.usage.PromptTokens),
+		strconv.Itoa(m.usage.CompletionTokens),
+		strconv.Itoa(m.usage.TotalTokens),
+	}
+}
@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+	"groq-go/internal/ids"
+	"groq-go/internal/storage"
+	"groq-go/internal/tool"
+)
+
+// DefaultRunTimeout bounds a whole run, tool calls included
+const DefaultRunTimeout = 10 * time.Minute
+
+// Runner executes jobs with the model and tools, storing each run as a
+// session
+type Runner struct {
+	client   *client.Client
+	registry *tool.Registry
+	store    storage.Storage // nil keeps runs out of storage
+	prompt   *conversation.Prompt
+	hooks    []tool.Hook
+	timeout  time.Duration
+}
+
+// NewRunner creates a runner that calls tools from registry
+func NewRunner(c *client.Client, registry *tool.Registry, store storage.Storage) *Runner {
+	return &Runner{client: c, registry: registry, store: store, timeout: DefaultRunTimeout}
+}
+
+// SetSystemPrompt customizes the system prompt of runs
+func (r *Runner) SetSystemPrompt(p *conversation.Prompt) {
+	r.prompt = p
+}
+
+// AddHook adds a hook to the tool calls of runs, such as the audit log
+func (r *Runner) AddHook(h tool.Hook) {
+	r.hooks = append(r.hooks, h)
+}
+
+// Run executes job: the prompt is sent, tool calls are answered until the
+// model stops or MaxTurns API calls were made, and the conversation is
+// saved as a session, failed runs included
+func (r *Runner) Run(ctx context.Context, job Job) Run {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	run := Run{StartedAt: time.Now(), SessionID: "sched-" + ids.New(24)}
+	messages, err := r.converse(ctx, job, &run)
+	if err != nil {
+		run.Error = err.Error()
+	}
+	run.FinishedAt = time.Now()
+
+	if r.store != nil {
+		session := &storage.Session{
+			ID:        run.SessionID,
+			Title:     fmt.Sprintf("%s (%s)", job.Name, run.StartedAt.Format("2006-01-02 15:04")),
+			Messages:  messages,
+			CreatedAt: run.StartedAt,
+			UpdatedAt: run.FinishedAt,
+		}
+		// The run's context may be done; saving must not depend on it
+		if err := r.store.SaveSession(context.Background(), session); err != nil {
+			log.Error("Failed to save scheduled run", "id", job.ID, "error", err)
+			run.SessionID = ""
+			if run.Error == "" {
+				run.Error = "failed to save session: " + err.Error()
+			}
+		}
+	} else {
+		run.SessionID = ""
+	}
+	return run
+}
+
+func (r *Runner) converse(ctx context.Context, job Job, run *Run) ([]client.Message, error) {
+	// Only the job's tools are offered or executed
+	allowed := tool.NewRegistry()
+	for _, name := range job.Tools {
+		if t, ok := r.registry.Get(name); ok && r.registry.Enabled("", name) {
+			allowed.Register(t)
+		}
+	}
+	executor := tool.NewExecutor(allowed)
+	for _, h := range r.hooks {
+		executor.AddHook(h)
+	}
+	var tools []client.Tool
+	if len(job.Tools) > 0 {
+		tools = allowed.ToClientTools()
+	}
+
+	c := r.client
+	if job.Model != "" {
+		c = c.WithModelOverride(job.Model)
+	}
+	convCtx := conversation.NewContext()
+	if r.prompt != nil {
+		convCtx.SetPrompt(r.prompt, allowed.ToClientTools)
+	}
+	messages := []client.Message{
+		convCtx.SystemMessage(),
+		{Role: "user", Content: job.Prompt},
+	}
+	ctx = tool.WithCaller(ctx, tool.Caller{User: "scheduler", SessionID: run.SessionID})
+	// The job's prompt is one turn, however many round trips it takes
+	ctx = executor.StartTurn(ctx)
+
+	maxTurns := job.MaxTurns
+	if maxTurns == 0 {
+		maxTurns = DefaultMaxTurns
+	}
+	for run.Turns < maxTurns {
+		run.Turns++
+		resp, err := c.ChatCompletion(ctx, messages, tools)
+		if err != nil {
+			return messages, fmt.Errorf("API error: %w", err)
+		}
+		if len(resp.Choices) == 0 {
+			return messages, fmt.Errorf("API returned no choices")
+		}
+		msg := resp.Choices[0].Message
+		messages = append(messages, msg)
+		if len(msg.ToolCalls) == 0 {
+			return messages, nil
+		}
+		messages = append(messages, executor.ExecuteToolCalls(ctx, msg.ToolCalls)...)
+	}
+	return messages, fmt.Errorf("stopped after %d turns without a final answer", maxTurns)
+}
@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/storage"
+	"groq-go/internal/tool"
+)
+
+// recordingTool counts its calls
+type recordingTool struct {
+	name  string
+	mu    sync.Mutex
+	calls int
+}
+
+func (t *recordingTool) Name() string               { return t.name }
+func (t *recordingTool) Description() string        { return t.name + " tool" }
+func (t *recordingTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+func (t *recordingTool) Execute(ctx context.Context, args json.RawMessage) (tool.Result, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls++
+	return tool.NewResult(t.name + " ran"), nil
+}
+
+// stubUpstream calls each of toolNames once, then answers. It records the
+// tools offered in each request.
+func stubUpstream(t *testing.T, toolNames ...string) (*httptest.Server, *[][]string) {
+	t.Helper()
+	var offered [][]string
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Tools []client.Tool `json:"tools"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var names []string
+		for _, tl := range req.Tools {
+			names = append(names, tl.Function.Name)
+		}
+		offered = append(offered, names)
+
+		msg := client.Message{Role: "assistant", Content: "all done"}
+		finish := "stop"
+		if n < len(toolNames) {
+			msg = client.Message{Role: "assistant", ToolCalls: []client.ToolCall{{
+				ID: fmt.Sprintf("call_%d", n), Type: "function",
+				Function: client.FunctionCall{Name: toolNames[n], Arguments: `{}`},
+			}}}
+			finish = "tool_calls"
+		}
+		n++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(client.ChatCompletionResponse{
+			Choices: []client.Choice{{Message: msg, FinishReason: finish}},
+		})
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &offered
+}
+
+func newTestRunner(t *testing.T, upstream *httptest.Server) (*Runner, storage.Storage, *recordingTool, *recordingTool) {
+	t.Helper()
+	store, err := storage.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, bash := &recordingTool{name: "Read"}, &recordingTool{name: "Bash"}
+	registry := tool.NewRegistry()
+	registry.Register(read)
+	registry.Register(bash)
+	c := client.New("key", client.WithBaseURL(upstream.URL), client.WithRetry(1, 0))
+	return NewRunner(c, registry, store), store, read, bash
+}
+
+func TestRunnerRunsAllowedTools(t *testing.T) {
+	upstream, offered := stubUpstream(t, "Read", "Bash")
+	runner, store, read, bash := newTestRunner(t, upstream)
+
+	run := runner.Run(context.Background(), Job{ID: "sched_1", Name: "nightly", Prompt: "check the logs", Tools: []string{"Read"}})
+	if run.Error != "" || run.Turns != 3 {
+		t.Fatalf("Unexpected run %+v", run)
+	}
+	if got := (*offered)[0]; len(got) != 1 || got[0] != "Read" {
+		t.Errorf("Expected only Read offered, got %v", got)
+	}
+	if read.calls != 1 || bash.calls != 0 {
+		t.Errorf("Expected Read to run and Bash to be refused, got %d and %d calls", read.calls, bash.calls)
+	}
+
+	session, err := store.LoadSession(context.Background(), run.SessionID)
+	if err != nil {
+		t.Fatalf("Expected the run saved as a session: %v", err)
+	}
+	if !strings.HasPrefix(session.Title, "nightly (") {
+		t.Errorf("Unexpected session title %q", session.Title)
+	}
+	// system, user, call, result, call, refusal, answer
+	if n := len(session.Messages); n != 7 || session.Messages[1].Text() != "check the logs" || session.Messages[6].Text() != "all done" {
+		t.Errorf("Unexpected transcript of %d messages: %+v", n, session.Messages)
+	}
+}
+
+func TestRunnerMaxTurns(t *testing.T) {
+	upstream, _ := stubUpstream(t, "Read", "Read", "Read")
+	runner, store, _, _ := newTestRunner(t, upstream)
+
+	run := runner.Run(context.Background(), Job{Name: "loop", Prompt: "p", Tools: []string{"Read"}, MaxTurns: 2})
+	if run.Turns != 2 || !strings.Contains(run.Error, "stopped after 2 turns") {
+		t.Errorf("Expected the run to stop at max turns, got %+v", run)
+	}
+	// Failed runs are kept for review too
+	if _, err := store.LoadSession(context.Background(), run.SessionID); err != nil {
+		t.Errorf("Expected the failed run saved: %v", err)
+	}
+}
+
+func TestRunnerAPIError(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"Invalid API Key","type":"invalid_request_error"}}`)
+	}))
+	defer upstream.Close()
+	runner, _, _, _ := newTestRunner(t, upstream)
+
+	run := runner.Run(context.Background(), Job{Name: "broken", Prompt: "p"})
+	if !strings.Contains(run.Error, "API error") || run.FinishedAt.IsZero() {
+		t.Errorf("Expected the API error recorded, got %+v", run)
+	}
+}
@@ -0,0 +1,403 @@
+// Package scheduler runs agent prompts on a cron schedule while the web
+// server is up. Each run is stored as a session so its output can be
+// reviewed in the web UI.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/ids"
+	"groq-go/internal/logging"
+	"groq-go/internal/notify"
+)
+
+var log = logging.WithComponent("scheduler")
+
+const (
+	// DefaultMaxTurns bounds the API calls of a run when the job sets none
+	DefaultMaxTurns = 10
+	// MaxJobs caps the number of jobs
+	MaxJobs = 50
+	// tickInterval is how often Start checks for due jobs
+	tickInterval = 30 * time.Second
+)
+
+// ErrNotFound is returned for unknown job IDs
+var ErrNotFound = errors.New("schedule not found")
+
+// Job is a prompt run on a cron schedule
+type Job struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Cron   string `json:"cron"`
+	Prompt string `json:"prompt"`
+	Model  string `json:"model,omitempty"` // Defaults to the server's model
+	// Tools the run may call; none when empty
+	Tools     []string  `json:"tools,omitempty"`
+	MaxTurns  int       `json:"max_turns,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+
+	NextRun time.Time `json:"next_run"`
+	LastRun *Run      `json:"last_run,omitempty"`
+	// Skipped counts runs dropped because the previous one was still going
+	Skipped int  `json:"skipped,omitempty"`
+	Running bool `json:"running"`
+}
+
+// Run is the outcome of one execution of a job
+type Run struct {
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	SessionID  string    `json:"session_id,omitempty"`
+	Turns      int       `json:"turns"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// RunFunc executes a job and reports the run; Runner.Run is the real one
+type RunFunc func(ctx context.Context, job Job) Run
+
+// Scheduler keeps the jobs, persists them and starts due runs
+type Scheduler struct {
+	mu      sync.Mutex
+	path    string
+	jobs    map[string]*Job
+	crons   map[string]*Cron
+	running map[string]bool
+	run     RunFunc
+	now     func() time.Time
+
+	notifier *notify.Dispatcher // Run results; nil sends none
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// DefaultPath is where jobs are stored
+func DefaultPath() string {
+	return filepath.Join(config.Dir(), "schedules.json")
+}
+
+// New loads the jobs stored at path. Nothing runs until SetRunner and
+// Start are called.
+func New(path string) (*Scheduler, error) {
+	s := &Scheduler{
+		path:    path,
+		jobs:    make(map[string]*Job),
+		crons:   make(map[string]*Cron),
+		running: make(map[string]bool),
+		now:     time.Now,
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	now := s.now()
+	for _, job := range jobs {
+		c, err := ParseCron(job.Cron)
+		if err != nil {
+			log.Warn("Skipping schedule with an invalid cron expression", "id", job.ID, "error", err)
+			continue
+		}
+		// Runs missed while the server was down are not caught up
+		job.Running = false
+		if job.NextRun.Before(now) {
+			job.NextRun = c.Next(now)
+		}
+		s.jobs[job.ID] = job
+		s.crons[job.ID] = c
+	}
+	return s, nil
+}
+
+// SetRunner sets what executes due jobs
+func (s *Scheduler) SetRunner(run RunFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.run = run
+}
+
+// SetNotifier sends the result of every run to n
+func (s *Scheduler) SetNotifier(n *notify.Dispatcher) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = n
+}
+
+// Add validates and stores a new job, filling in its ID and first run
+func (s *Scheduler) Add(job Job) (Job, error) {
+	c, next, err := s.validate(&job)
+	if err != nil {
+		return Job{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.jobs) >= MaxJobs {
+		return Job{}, fmt.Errorf("too many schedules (max %d)", MaxJobs)
+	}
+
+	job.ID = ids.Prefixed("sched", 12)
+	if job.Name == "" {
+		job.Name = job.ID
+	}
+	job.CreatedAt = s.now()
+	job.NextRun = next
+	job.LastRun, job.Skipped, job.Running = nil, 0, false
+
+	s.jobs[job.ID] = &job
+	s.crons[job.ID] = c
+	if err := s.saveLocked(); err != nil {
+		delete(s.jobs, job.ID)
+		delete(s.crons, job.ID)
+		return Job{}, err
+	}
+	return job, nil
+}
+
+// Update replaces the definition of a job, keeping its ID and history.
+// The next run is recomputed from the new cron expression.
+func (s *Scheduler) Update(id string, job Job) (Job, error) {
+	c, next, err := s.validate(&job)
+	if err != nil {
+		return Job{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	prev, prevCron := *stored, s.crons[id]
+	stored.Name, stored.Cron, stored.Prompt = job.Name, job.Cron, job.Prompt
+	stored.Model, stored.Tools, stored.MaxTurns = job.Model, job.Tools, job.MaxTurns
+	if stored.Name == "" {
+		stored.Name = id
+	}
+	stored.NextRun = next
+	s.crons[id] = c
+	if err := s.saveLocked(); err != nil {
+		*stored, s.crons[id] = prev, prevCron
+		return Job{}, err
+	}
+	return s.snapshotLocked(stored), nil
+}
+
+// validate normalizes a job definition and returns its parsed schedule
+// and first run
+func (s *Scheduler) validate(job *Job) (*Cron, time.Time, error) {
+	job.Name = strings.TrimSpace(job.Name)
+	job.Prompt = strings.TrimSpace(job.Prompt)
+	if job.Prompt == "" {
+		return nil, time.Time{}, errors.New("prompt is required")
+	}
+	if job.MaxTurns < 0 {
+		return nil, time.Time{}, errors.New("max_turns must not be negative")
+	}
+	c, err := ParseCron(job.Cron)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	next := c.Next(s.now())
+	if next.IsZero() {
+		return nil, time.Time{}, fmt.Errorf("cron expression %q never matches", job.Cron)
+	}
+	return c, next, nil
+}
+
+// Get returns a job by ID
+func (s *Scheduler) Get(id string) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return s.snapshotLocked(job), nil
+}
+
+// List returns the jobs ordered by their next run
+func (s *Scheduler) List() []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, s.snapshotLocked(job))
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if !jobs[i].NextRun.Equal(jobs[j].NextRun) {
+			return jobs[i].NextRun.Before(jobs[j].NextRun)
+		}
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// Remove deletes a job. A run in progress finishes, but its result is
+// not recorded.
+func (s *Scheduler) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	c := s.crons[id]
+	delete(s.jobs, id)
+	delete(s.crons, id)
+	if err := s.saveLocked(); err != nil {
+		s.jobs[id], s.crons[id] = job, c
+		return err
+	}
+	return nil
+}
+
+// Start checks for due jobs until Stop is called
+func (s *Scheduler) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(tickInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.ctx.Done():
+				return
+			case <-ticker.C:
+				s.Tick()
+			}
+		}
+	}()
+}
+
+// Stop cancels running jobs and waits for them to record their results
+func (s *Scheduler) Stop() {
+	s.cancel()
+	s.wg.Wait()
+}
+
+// Tick starts every job that is due. A job whose previous run is still
+// going is skipped, and its next run is scheduled as usual.
+func (s *Scheduler) Tick() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.run == nil || s.ctx.Err() != nil {
+		return
+	}
+
+	now := s.now()
+	changed := false
+	for id, job := range s.jobs {
+		if job.NextRun.After(now) {
+			continue
+		}
+		job.NextRun = s.crons[id].Next(now)
+		changed = true
+		if s.running[id] {
+			job.Skipped++
+			log.Warn("Skipping scheduled run, the previous one is still going", "id", id, "name", job.Name)
+			continue
+		}
+		s.running[id] = true
+		s.wg.Add(1)
+		go s.execute(*job, s.run)
+	}
+	if changed {
+		if err := s.saveLocked(); err != nil {
+			log.Error("Failed to save schedules", "error", err)
+		}
+	}
+}
+
+func (s *Scheduler) execute(job Job, run RunFunc) {
+	defer s.wg.Done()
+	log.Info("Running scheduled job", "id", job.ID, "name", job.Name)
+	result := run(s.ctx, job)
+	if result.Error != "" {
+		log.Warn("Scheduled job failed", "id", job.ID, "name", job.Name, "error", result.Error)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier.Emit(runEvent(job, result))
+	delete(s.running, job.ID)
+	stored, ok := s.jobs[job.ID]
+	if !ok {
+		return
+	}
+	stored.LastRun = &result
+	if err := s.saveLocked(); err != nil {
+		log.Error("Failed to save schedules", "error", err)
+	}
+}
+
+// runEvent reports a finished run
+func runEvent(job Job, run Run) notify.Event {
+	e := notify.Event{
+		Type:      notify.ScheduleSucceeded,
+		Time:      run.FinishedAt,
+		StartedAt: run.StartedAt,
+		Message:   fmt.Sprintf("Scheduled job %s finished after %d turns", job.Name, run.Turns),
+		Refs:      map[string]string{"job_id": job.ID, "name": job.Name},
+	}
+	if run.SessionID != "" {
+		e.Refs["session_id"] = run.SessionID
+	}
+	if run.Error != "" {
+		e.Type, e.Message, e.Error = notify.ScheduleFailed, fmt.Sprintf("Scheduled job %s failed", job.Name), run.Error
+	}
+	return e
+}
+
+// snapshotLocked copies a job for callers; s.mu must be held
+func (s *Scheduler) snapshotLocked(job *Job) Job {
+	c := *job
+	c.Tools = append([]string(nil), job.Tools...)
+	if job.LastRun != nil {
+		run := *job.LastRun
+		c.LastRun = &run
+	}
+	c.Running = s.running[job.ID]
+	return c
+}
+
+func (s *Scheduler) saveLocked() error {
+	jobs := make([]*Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].CreatedAt.Before(jobs[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,256 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/notify"
+)
+
+// fakeClock is a settable clock for driving Tick
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
+
+func newTestScheduler(t *testing.T, path string, clock *fakeClock) *Scheduler {
+	t.Helper()
+	s, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.now = clock.Now
+	t.Cleanup(s.Stop)
+	return s
+}
+
+func TestTickRunsDueJobs(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 4, 7, 58, 0, 0, time.UTC)}
+	path := filepath.Join(t.TempDir(), "schedules.json")
+	s := newTestScheduler(t, path, clock)
+
+	runs := make(chan Job, 10)
+	s.SetRunner(func(ctx context.Context, job Job) Run {
+		runs <- job
+		return Run{StartedAt: clock.Now(), SessionID: "sched-1", Turns: 1}
+	})
+	job, err := s.Add(Job{Name: "morning", Cron: "0 8 * * *", Prompt: "run the tests"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC); !job.NextRun.Equal(want) {
+		t.Fatalf("Expected first run at %s, got %s", want, job.NextRun)
+	}
+
+	s.Tick()
+	select {
+	case <-runs:
+		t.Fatal("Expected no run before the job is due")
+	default:
+	}
+
+	clock.Set(time.Date(2026, 3, 4, 8, 0, 10, 0, time.UTC))
+	s.Tick()
+	if got := <-runs; got.ID != job.ID || got.Prompt != "run the tests" {
+		t.Errorf("Unexpected job run: %+v", got)
+	}
+	waitIdle(t, s, job.ID)
+
+	got, _ := s.Get(job.ID)
+	if got.LastRun == nil || got.LastRun.SessionID != "sched-1" || got.LastRun.Error != "" {
+		t.Errorf("Expected the run recorded, got %+v", got.LastRun)
+	}
+	if want := time.Date(2026, 3, 5, 8, 0, 0, 0, time.UTC); !got.NextRun.Equal(want) {
+		t.Errorf("Expected the next run at %s, got %s", want, got.NextRun)
+	}
+
+	// The same minute doesn't run twice
+	s.Tick()
+	select {
+	case <-runs:
+		t.Error("Expected one run per due time")
+	default:
+	}
+
+	// Jobs and their last run survive a restart
+	reloaded := newTestScheduler(t, path, clock)
+	if jobs := reloaded.List(); len(jobs) != 1 || jobs[0].LastRun == nil || jobs[0].Name != "morning" {
+		t.Errorf("Expected the job to be reloaded, got %+v", jobs)
+	}
+}
+
+func TestTickSkipsOverlappingRuns(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, filepath.Join(t.TempDir(), "schedules.json"), clock)
+
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	s.SetRunner(func(ctx context.Context, job Job) Run {
+		started <- struct{}{}
+		<-release
+		return Run{}
+	})
+	job, err := s.Add(Job{Cron: "* * * * *", Prompt: "slow job"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Set(clock.Now().Add(time.Minute))
+	s.Tick()
+	<-started
+	if got, _ := s.Get(job.ID); !got.Running {
+		t.Error("Expected the job to be reported as running")
+	}
+
+	// Due again while the first run is still going
+	clock.Set(clock.Now().Add(time.Minute))
+	s.Tick()
+	close(release)
+	waitIdle(t, s, job.ID)
+	select {
+	case <-started:
+		t.Error("Expected the overlapping run to be skipped")
+	default:
+	}
+	if got, _ := s.Get(job.ID); got.Skipped != 1 {
+		t.Errorf("Expected one skipped run, got %d", got.Skipped)
+	}
+}
+
+func TestFailedRunIsRecorded(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, filepath.Join(t.TempDir(), "schedules.json"), clock)
+	s.SetRunner(func(ctx context.Context, job Job) Run {
+		return Run{Error: errors.New("API error: 401").Error()}
+	})
+	job, _ := s.Add(Job{Cron: "@hourly", Prompt: "p"})
+
+	clock.Set(job.NextRun)
+	s.Tick()
+	waitIdle(t, s, job.ID)
+	if got, _ := s.Get(job.ID); got.LastRun == nil || got.LastRun.Error != "API error: 401" {
+		t.Errorf("Expected the failure in the job listing, got %+v", got.LastRun)
+	}
+}
+
+func TestRunIsNotified(t *testing.T) {
+	events := make(chan notify.Event, 1)
+	release := make(chan struct{})
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		json.NewDecoder(r.Body).Decode(&e)
+		events <- e
+		// A receiver that hangs must not hold up the scheduler
+		<-release
+	}))
+	defer receiver.Close()
+	defer close(release)
+	notifier := notify.New(config.NotificationsConfig{Targets: []config.NotifyTarget{{URL: receiver.URL}}},
+		notify.WithDeadLetter(filepath.Join(t.TempDir(), "dead.jsonl")))
+	t.Cleanup(notifier.Close)
+
+	clock := &fakeClock{now: time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, filepath.Join(t.TempDir(), "schedules.json"), clock)
+	s.SetNotifier(notifier)
+	s.SetRunner(func(ctx context.Context, job Job) Run {
+		return Run{SessionID: "sched-1", Error: "API error: 401"}
+	})
+	job, _ := s.Add(Job{Name: "nightly", Cron: "@hourly", Prompt: "p"})
+
+	clock.Set(job.NextRun)
+	s.Tick()
+	waitIdle(t, s, job.ID)
+	if got, _ := s.Get(job.ID); got.LastRun == nil {
+		t.Fatal("Expected the run recorded while the receiver hangs")
+	}
+
+	select {
+	case e := <-events:
+		if e.Type != notify.ScheduleFailed || e.Refs["job_id"] != job.ID || e.Refs["session_id"] != "sched-1" || e.Error != "API error: 401" {
+			t.Errorf("Unexpected event %+v", e)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the notification")
+	}
+}
+
+func TestJobCRUD(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)}
+	s := newTestScheduler(t, filepath.Join(t.TempDir(), "schedules.json"), clock)
+
+	for _, bad := range []Job{
+		{Cron: "* * * * *"},
+		{Cron: "bogus", Prompt: "p"},
+		{Cron: "0 0 30 2 *", Prompt: "p"},
+		{Cron: "* * * * *", Prompt: "p", MaxTurns: -1},
+	} {
+		if _, err := s.Add(bad); err == nil {
+			t.Errorf("Expected %+v to be rejected", bad)
+		}
+	}
+
+	job, err := s.Add(Job{Cron: "@daily", Prompt: "  summarize  ", Tools: []string{"Bash"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Name != job.ID || job.Prompt != "summarize" {
+		t.Errorf("Expected defaults filled in, got %+v", job)
+	}
+
+	updated, err := s.Update(job.ID, Job{Name: "hourly", Cron: "@hourly", Prompt: "check"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != job.ID || updated.Name != "hourly" || !updated.NextRun.Equal(time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)) || len(updated.Tools) != 0 {
+		t.Errorf("Unexpected update result %+v", updated)
+	}
+	if _, err := s.Update("sched_missing", Job{Cron: "@hourly", Prompt: "p"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	if err := s.Remove(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Remove(job.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound on a second remove, got %v", err)
+	}
+	if len(s.List()) != 0 {
+		t.Error("Expected no jobs left")
+	}
+}
+
+// waitIdle waits for the job's run to finish and be recorded
+func waitIdle(t *testing.T, s *Scheduler, id string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		running := s.running[id]
+		s.mu.Unlock()
+		if !running {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("Timed out waiting for the run to finish")
+}
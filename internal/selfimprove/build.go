@@ -0,0 +1,83 @@
+package selfimprove
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Build stages reported to progress callbacks
+const (
+	StageCheckout     = "checkout"
+	StageDependencies = "dependencies"
+	StageCompile      = "compile"
+	StageVerify       = "verify"
+)
+
+// ErrAlreadyBuilding is returned when a build of the same target is already running
+var ErrAlreadyBuilding = errors.New("already building, see progress above")
+
+// ProgressFunc receives checkpoints from long-running operations.
+// A nil ProgressFunc is valid and ignored.
+type ProgressFunc func(stage, detail string)
+
+// Report invokes the callback if it is set
+func (p ProgressFunc) Report(stage, detail string) {
+	if p != nil {
+		p(stage, detail)
+	}
+}
+
+// execCommand is swapped out in tests to fake go toolchain output
+var execCommand = exec.CommandContext
+
+// GoBuild runs `go build -o output .` in dir, reporting dependency downloads
+// and compilation as they appear in the go command's output
+func GoBuild(ctx context.Context, dir, output string, env []string, progress ProgressFunc) error {
+	cmd := execCommand(ctx, "go", "build", "-o", output, ".")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), env...)
+
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to capture build output: %w", err)
+	}
+	cmd.Stderr = cmd.Stdout
+
+	progress.Report(StageCompile, "go build")
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start build: %w", err)
+	}
+
+	var out strings.Builder
+	downloading := false
+	// A Reader rather than a Scanner: a line longer than the scanner's
+	// buffer would stop the loop and leave the build blocked on the pipe
+	reader := bufio.NewReader(pipe)
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			break
+		}
+		out.WriteString(line)
+		line = strings.TrimRight(line, "\r\n")
+
+		// go prints "go: downloading module vX" while fetching dependencies
+		if strings.HasPrefix(line, "go: downloading ") {
+			downloading = true
+			progress.Report(StageDependencies, strings.TrimPrefix(line, "go: downloading "))
+		} else if downloading {
+			downloading = false
+			progress.Report(StageCompile, "dependencies ready")
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("%s - %w", out.String(), err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package selfimprove
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// fakeGo replaces the go toolchain with a shell script for the duration of a test
+func fakeGo(t *testing.T, script string) {
+	orig := execCommand
+	execCommand = func(ctx context.Context, name string, args ...string) *exec.Cmd {
+		return exec.CommandContext(ctx, "sh", "-c", script)
+	}
+	t.Cleanup(func() { execCommand = orig })
+}
+
+func TestGoBuildReportsStages(t *testing.T) {
+	fakeGo(t, `echo "go: downloading github.com/foo/bar v1.0.0"; echo "go: downloading github.com/baz/qux v0.2.0"; echo "compiling" 1>&2`)
+
+	var stages []string
+	var details []string
+	err := GoBuild(context.Background(), t.TempDir(), "/dev/null", nil, func(stage, detail string) {
+		stages = append(stages, stage)
+		details = append(details, detail)
+	})
+	if err != nil {
+		t.Fatalf("GoBuild failed: %v", err)
+	}
+
+	want := []string{StageCompile, StageDependencies, StageDependencies, StageCompile}
+	if len(stages) != len(want) {
+		t.Fatalf("Expected stages %v, got %v", want, stages)
+	}
+	for i := range want {
+		if stages[i] != want[i] {
+			t.Errorf("Stage %d: expected %s, got %s", i, want[i], stages[i])
+		}
+	}
+	if details[1] != "github.com/foo/bar v1.0.0" {
+		t.Errorf("Expected module detail, got %q", details[1])
+	}
+}
+
+func TestGoBuildLongLines(t *testing.T) {
+	// A line past bufio.Scanner's 64KB limit must not stop progress
+	fakeGo(t, `head -c 100000 /dev/zero | tr '\0' x; echo; echo "go: downloading github.com/foo/bar v1.0.0"; echo done`)
+
+	var details []string
+	err := GoBuild(context.Background(), t.TempDir(), "/dev/null", nil, func(stage, detail string) {
+		details = append(details, detail)
+	})
+	if err != nil {
+		t.Fatalf("GoBuild failed: %v", err)
+	}
+	if len(details) != 3 || details[1] != "github.com/foo/bar v1.0.0" {
+		t.Errorf("Expected progress after the long line, got %v", details)
+	}
+}
+
+func TestGoBuildFailureIncludesOutput(t *testing.T) {
+	fakeGo(t, `echo "main.go:1: syntax error" 1>&2; exit 1`)
+
+	err := GoBuild(context.Background(), t.TempDir(), "/dev/null", nil, nil)
+	if err == nil {
+		t.Fatal("Expected build error")
+	}
+	if want := "main.go:1: syntax error"; !strings.Contains(err.Error(), want) {
+		t.Errorf("Expected error to contain %q, got %q", want, err.Error())
+	}
+}
+
+func TestVerifyBuildRejectsConcurrent(t *testing.T) {
+	fakeGo(t, `true`)
+	m := &Manager{repoDir: t.TempDir()}
+
+	m.verifying = true
+	if err := m.VerifyBuild(context.Background(), nil); !errors.Is(err, ErrAlreadyBuilding) {
+		t.Errorf("Expected ErrAlreadyBuilding, got %v", err)
+	}
+
+	m.verifying = false
+	var stages []string
+	if err := m.VerifyBuild(context.Background(), func(stage, detail string) { stages = append(stages, stage) }); err != nil {
+		t.Fatalf("VerifyBuild failed: %v", err)
+	}
+	if len(stages) == 0 || stages[len(stages)-1] != StageVerify {
+		t.Errorf("Expected final stage %s, got %v", StageVerify, stages)
+	}
+	if m.verifying {
+		t.Error("Expected in-flight flag to be cleared")
+	}
+}
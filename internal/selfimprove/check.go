@@ -0,0 +1,63 @@
+package selfimprove
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// checkTimeout bounds the quick checks run after a write
+const checkTimeout = 2 * time.Minute
+
+// maxCheckOutput caps the errors reported by CheckGoFile
+const maxCheckOutput = 2000
+
+// ChecksWrites reports whether writes of Go files should be followed by
+// CheckGoFile (self_improve.check_writes)
+func (m *Manager) ChecksWrites() bool {
+	return m.checkWrites
+}
+
+// CheckGoFile runs quick checks on a Go file written to the repository:
+// gofmt -e for syntax errors, then go vet on the file's package. It
+// returns a summary, with the errors on failure, and whether the file
+// passed. Unlike VerifyBuild it does not build the whole program.
+func (m *Manager) CheckGoFile(ctx context.Context, path string) (string, bool) {
+	defer m.LockRepo()()
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+
+	cmd := execCommand(ctx, "gofmt", "-e", "-l", path)
+	cmd.Dir = m.repoDir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "❌ gofmt: syntax errors in " + path + ":\n" + truncateCheckOutput(string(out), err), false
+	}
+	var notes string
+	if strings.TrimSpace(string(out)) != "" {
+		notes = " (not gofmt-formatted)"
+	}
+
+	pkg := "./" + filepath.ToSlash(filepath.Dir(filepath.Clean(path)))
+	cmd = execCommand(ctx, "go", "vet", pkg)
+	cmd.Dir = m.repoDir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Sprintf("❌ go vet %s failed%s:\n%s", pkg, notes, truncateCheckOutput(string(out), err)), false
+	}
+	return fmt.Sprintf("✅ gofmt and go vet %s passed%s", pkg, notes), true
+}
+
+// truncateCheckOutput returns a command's output, or its error when it
+// printed nothing, cut to maxCheckOutput
+func truncateCheckOutput(out string, err error) string {
+	out = strings.TrimSpace(out)
+	if out == "" {
+		out = err.Error()
+	}
+	if len(out) > maxCheckOutput {
+		out = out[:maxCheckOutput] + "\n... (truncated)"
+	}
+	return out
+}
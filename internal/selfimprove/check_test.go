@@ -0,0 +1,43 @@
+package selfimprove
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCheckGoFile(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"ok/ok.go":         "package ok\n\nfunc Add(a, b int) int { return a + b }\n",
+		"messy/messy.go":   "package messy\n\nfunc Add(a,b int) int {return a+b}\n",
+		"broken/broken.go": "package broken\n\nfunc Add(a, b int) int {\n\treturn a +\n",
+		"vet/vet.go":       "package vet\n\nimport \"fmt\"\n\nfunc Hello() string { return fmt.Sprintf(\"%d\", \"x\") }\n",
+	})
+	m := &Manager{repoDir: dir}
+	ctx := context.Background()
+
+	tests := []struct {
+		path string
+		ok   bool
+		want string
+	}{
+		{"ok/ok.go", true, "✅ gofmt and go vet ./ok passed"},
+		{"messy/messy.go", true, "not gofmt-formatted"},
+		{"broken/broken.go", false, "❌ gofmt: syntax errors in broken/broken.go"},
+		{"vet/vet.go", false, "❌ go vet ./vet failed"},
+	}
+	for _, tt := range tests {
+		summary, ok := m.CheckGoFile(ctx, tt.path)
+		if ok != tt.ok || !strings.Contains(summary, tt.want) {
+			t.Errorf("%s: expected ok=%v and %q, got ok=%v and %q", tt.path, tt.ok, tt.want, ok, summary)
+		}
+	}
+
+	// Failures say where
+	if summary, _ := m.CheckGoFile(ctx, "broken/broken.go"); !strings.Contains(summary, "broken.go:") {
+		t.Errorf("Expected the syntax error's position, got %q", summary)
+	}
+	if summary, _ := m.CheckGoFile(ctx, "vet/vet.go"); !strings.Contains(summary, "Sprintf") {
+		t.Errorf("Expected the vet finding, got %q", summary)
+	}
+}
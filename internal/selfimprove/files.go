@@ -0,0 +1,180 @@
+package selfimprove
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultTreeDepth is how many directory levels RenderTree expands
+const DefaultTreeDepth = 3
+
+// FileInfo describes a file in the repository
+type FileInfo struct {
+	Path    string // Relative to the repository root, with forward slashes
+	Size    int64
+	ModTime time.Time
+}
+
+// LineRange is the part of a file returned by ReadFileRange
+type LineRange struct {
+	Start int // First line, 1-based
+	End   int // Last line, inclusive
+	Total int // Lines in the file
+	Lines []string
+}
+
+// ListFiles lists the files in the repository whose path contains
+// pattern, skipping .git
+func (m *Manager) ListFiles(ctx context.Context, pattern string) ([]FileInfo, error) {
+	var files []FileInfo
+	err := filepath.WalkDir(m.repoDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		relPath, _ := filepath.Rel(m.repoDir, path)
+		relPath = filepath.ToSlash(relPath)
+		if pattern != "" && !strings.Contains(relPath, pattern) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, FileInfo{Path: relPath, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	return files, err
+}
+
+// ReadFileRange reads lines start to end, inclusive and 1-based, of a
+// file in the repository. start below 1 reads from the first line, end
+// below 1 or past the last line reads to the end; a start past the last
+// line is an error.
+func (m *Manager) ReadFileRange(ctx context.Context, path string, start, end int) (LineRange, error) {
+	content, err := m.ReadFile(ctx, path)
+	if err != nil {
+		return LineRange{}, err
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if content == "" {
+		lines = nil
+	}
+
+	r := LineRange{Start: max(start, 1), End: end, Total: len(lines)}
+	if r.End < 1 || r.End > r.Total {
+		r.End = r.Total
+	}
+	if r.Start > r.Total {
+		return LineRange{}, fmt.Errorf("start_line %d is past the end of %s, which has %d lines", start, path, r.Total)
+	}
+	if r.Start > r.End {
+		return LineRange{}, fmt.Errorf("start_line %d is after end_line %d", start, end)
+	}
+	r.Lines = lines[r.Start-1 : r.End]
+	return r, nil
+}
+
+// treeNode is a directory in RenderTree
+type treeNode struct {
+	dirs  map[string]*treeNode
+	files []FileInfo
+	count int   // Files below, at any depth
+	size  int64 // Their total size
+}
+
+// RenderTree draws files as a directory tree with their sizes. Directories
+// deeper than depth are collapsed to a line with their file count and
+// size; depth 0 means DefaultTreeDepth.
+func RenderTree(files []FileInfo, depth int) string {
+	if depth <= 0 {
+		depth = DefaultTreeDepth
+	}
+	root := &treeNode{}
+	for _, f := range files {
+		node := root
+		node.count++
+		node.size += f.Size
+		parts := strings.Split(f.Path, "/")
+		for _, dir := range parts[:len(parts)-1] {
+			if node.dirs == nil {
+				node.dirs = make(map[string]*treeNode)
+			}
+			child := node.dirs[dir]
+			if child == nil {
+				child = &treeNode{}
+				node.dirs[dir] = child
+			}
+			node = child
+			node.count++
+			node.size += f.Size
+		}
+		node.files = append(node.files, FileInfo{Path: parts[len(parts)-1], Size: f.Size, ModTime: f.ModTime})
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "./ (%s)\n", countFiles(root.count, root.size))
+	root.render(&sb, "", depth)
+	return sb.String()
+}
+
+// render writes the entries of n, directories first, each prefixed by
+// indent and a branch
+func (n *treeNode) render(sb *strings.Builder, indent string, depth int) {
+	names := make([]string, 0, len(n.dirs))
+	for name := range n.dirs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sort.Slice(n.files, func(i, j int) bool { return n.files[i].Path < n.files[j].Path })
+
+	entries := len(names) + len(n.files)
+	branch := func(i int) (string, string) {
+		if i == entries-1 {
+			return "└── ", "    "
+		}
+		return "├── ", "│   "
+	}
+	for i, name := range names {
+		dir := n.dirs[name]
+		b, next := branch(i)
+		fmt.Fprintf(sb, "%s%s%s/ (%s)\n", indent, b, name, countFiles(dir.count, dir.size))
+		if depth > 1 {
+			dir.render(sb, indent+next, depth-1)
+		}
+	}
+	for i, f := range n.files {
+		b, _ := branch(len(names) + i)
+		fmt.Fprintf(sb, "%s%s%s (%s)\n", indent, b, f.Path, formatSize(f.Size))
+	}
+}
+
+func countFiles(n int, size int64) string {
+	if n == 1 {
+		return "1 file, " + formatSize(size)
+	}
+	return fmt.Sprintf("%d files, %s", n, formatSize(size))
+}
+
+// formatSize formats a byte count for people
+func formatSize(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
+}
@@ -0,0 +1,128 @@
+package selfimprove
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fixtureRepo writes a small repository with a .git directory
+func fixtureRepo(t *testing.T) *Manager {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":                      "package main\n",
+		"go.mod":                       "module groq-go\n",
+		"internal/tool/executor.go":    strings.Repeat("x", 2048),
+		"internal/tool/tools/read.go":  "package tools\n",
+		"internal/tool/tools/write.go": "package tools\n",
+		"internal/web/server.go":       "line 1\nline 2\nline 3\nline 4\nline 5\n",
+		".git/HEAD":                    "ref: refs/heads/main\n",
+		".git/objects/ab/cdef":         "blob",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &Manager{repoDir: dir}
+}
+
+func TestListFiles(t *testing.T) {
+	m := fixtureRepo(t)
+	files, err := m.ListFiles(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 6 {
+		t.Fatalf("Expected 6 files without .git, got %+v", files)
+	}
+	for _, f := range files {
+		if strings.HasPrefix(f.Path, ".git") {
+			t.Errorf("Expected .git skipped, got %s", f.Path)
+		}
+		if f.Path == "internal/tool/executor.go" && (f.Size != 2048 || f.ModTime.IsZero()) {
+			t.Errorf("Expected size and modified time, got %+v", f)
+		}
+	}
+
+	files, _ = m.ListFiles(context.Background(), "tools/")
+	if len(files) != 2 {
+		t.Errorf("Expected the pattern to match 2 files, got %+v", files)
+	}
+}
+
+func TestRenderTree(t *testing.T) {
+	m := fixtureRepo(t)
+	files, err := m.ListFiles(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `./ (6 files, 2.1 KB)
+├── internal/ (4 files, 2.1 KB)
+│   ├── tool/ (3 files, 2.0 KB)
+│   │   ├── tools/ (2 files, 28 B)
+│   │   └── executor.go (2.0 KB)
+│   └── web/ (1 file, 35 B)
+│       └── server.go (35 B)
+├── go.mod (15 B)
+└── main.go (13 B)
+`
+	if got := RenderTree(files, 0); got != want {
+		t.Errorf("Unexpected tree at the default depth:\n%s\nwant:\n%s", got, want)
+	}
+
+	shallow := RenderTree(files, 1)
+	if !strings.Contains(shallow, "├── internal/ (4 files, 2.1 KB)\n├── go.mod") {
+		t.Errorf("Expected directories collapsed at depth 1, got:\n%s", shallow)
+	}
+	deep := RenderTree(files, 4)
+	if !strings.Contains(deep, "│   │   │   ├── read.go (14 B)") || strings.Contains(deep, ".git") {
+		t.Errorf("Expected depth 4 to reach every file and skip .git, got:\n%s", deep)
+	}
+}
+
+func TestReadFileRange(t *testing.T) {
+	m := fixtureRepo(t)
+	ctx := context.Background()
+	const path = "internal/web/server.go"
+
+	tests := []struct {
+		start, end         int
+		wantStart, wantEnd int
+	}{
+		{2, 3, 2, 3},
+		{0, 2, 1, 2},  // Start clamped to the first line
+		{4, 99, 4, 5}, // End clamped to the last line
+		{3, 0, 3, 5},  // No end reads to the end
+		{5, 5, 5, 5},
+	}
+	for _, tt := range tests {
+		r, err := m.ReadFileRange(ctx, path, tt.start, tt.end)
+		if err != nil {
+			t.Errorf("%d-%d: %v", tt.start, tt.end, err)
+			continue
+		}
+		if r.Start != tt.wantStart || r.End != tt.wantEnd || r.Total != 5 || len(r.Lines) != r.End-r.Start+1 {
+			t.Errorf("%d-%d: unexpected range %+v", tt.start, tt.end, r)
+		}
+		if r.Lines[0] != "line "+string(rune('0'+r.Start)) {
+			t.Errorf("%d-%d: expected to start at line %d, got %q", tt.start, tt.end, r.Start, r.Lines[0])
+		}
+	}
+
+	if _, err := m.ReadFileRange(ctx, path, 6, 10); err == nil || !strings.Contains(err.Error(), "5 lines") {
+		t.Errorf("Expected a start past the end refused, got %v", err)
+	}
+	if _, err := m.ReadFileRange(ctx, path, 4, 2); err == nil {
+		t.Error("Expected a reversed range refused")
+	}
+	if _, err := m.ReadFileRange(ctx, "missing.go", 1, 2); err == nil {
+		t.Error("Expected a missing file to fail")
+	}
+}
@@ -0,0 +1,322 @@
+package selfimprove
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultGitHubAPI is the GitHub REST API base URL
+const DefaultGitHubAPI = "https://api.github.com"
+
+// baseBranch is the branch deployments are made from and PRs target
+const baseBranch = "main"
+
+// workBranchPrefix namespaces branches created for self-improvement
+const workBranchPrefix = "self-improve/"
+
+// ErrAutoMergeDisabled is returned by MergePR unless SELF_IMPROVE_AUTO_MERGE is set
+var ErrAutoMergeDisabled = errors.New("auto-merge is disabled (set SELF_IMPROVE_AUTO_MERGE=true to allow merging)")
+
+var (
+	branchUnsafe = regexp.MustCompile(`[^a-z0-9._-]+`)
+	repoSlugRe   = regexp.MustCompile(`github\.com[:/]([^/]+)/([^/]+?)(?:\.git)?/?$`)
+)
+
+// PullRequest is the part of a GitHub pull request we use
+type PullRequest struct {
+	Number         int    `json:"number"`
+	Title          string `json:"title"`
+	HTMLURL        string `json:"html_url"`
+	State          string `json:"state"`
+	Merged         bool   `json:"merged"`
+	Mergeable      *bool  `json:"mergeable"` // nil while GitHub computes it
+	MergeableState string `json:"mergeable_state"`
+	Head           struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+}
+
+// CheckRun is a CI check on a pull request's head commit
+type CheckRun struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`     // queued, in_progress, completed
+	Conclusion string `json:"conclusion"` // success, failure, ... once completed
+}
+
+// PRStatus is a pull request with its checks
+type PRStatus struct {
+	PullRequest
+	Checks []CheckRun `json:"checks"`
+}
+
+// Summary renders the state, mergeability and checks for the model
+func (s *PRStatus) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "PR #%d: %s\n%s\n", s.Number, s.Title, s.HTMLURL)
+	state := s.State
+	if s.Merged {
+		state = "merged"
+	}
+	fmt.Fprintf(&b, "State: %s\n", state)
+	switch {
+	case s.Mergeable == nil:
+		b.WriteString("Mergeable: unknown (still being computed)\n")
+	case *s.Mergeable:
+		fmt.Fprintf(&b, "Mergeable: yes (%s)\n", s.MergeableState)
+	default:
+		fmt.Fprintf(&b, "Mergeable: no (%s)\n", s.MergeableState)
+	}
+	if len(s.Checks) == 0 {
+		b.WriteString("Checks: none reported\n")
+	}
+	for _, c := range s.Checks {
+		result := c.Status
+		if c.Status == "completed" {
+			result = c.Conclusion
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", c.Name, result)
+	}
+	return b.String()
+}
+
+// WorkBranch returns the branch created by CreateWorkBranch, if any
+func (m *Manager) WorkBranch() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.branch
+}
+
+// CreateWorkBranch creates and checks out a branch for the next changes,
+// so commits land there instead of on main. Uncommitted changes are
+// carried over.
+func (m *Manager) CreateWorkBranch(ctx context.Context, name string) (string, error) {
+	defer m.LockRepo()()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	slug := strings.Trim(branchUnsafe.ReplaceAllString(strings.ToLower(name), "-"), "-.")
+	if slug == "" {
+		slug = time.Now().Format("20060102-150405")
+	}
+	branch := workBranchPrefix + slug
+
+	if err := m.runGit(ctx, "check-ref-format", "--branch", branch); err != nil {
+		return "", fmt.Errorf("invalid branch name %q", branch)
+	}
+	if err := m.runGit(ctx, "checkout", "-b", branch); err != nil {
+		return "", err
+	}
+	m.branch = branch
+	return branch, nil
+}
+
+// OpenPR pushes the work branch and opens a pull request against main.
+// An empty title or body is generated from the branch's commits.
+func (m *Manager) OpenPR(ctx context.Context, title, body string) (*PullRequest, error) {
+	defer m.LockRepo()()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.branch == "" {
+		return nil, fmt.Errorf("no work branch, create one with create_branch first")
+	}
+	owner, repo, err := m.repoSlug()
+	if err != nil {
+		return nil, err
+	}
+
+	subjects, err := m.gitOutput(ctx, "log", "--reverse", "--format=%s", "origin/"+baseBranch+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	subjects = strings.TrimSpace(subjects)
+	if subjects == "" {
+		return nil, fmt.Errorf("branch %s has no commits ahead of %s", m.branch, baseBranch)
+	}
+	lines := strings.Split(subjects, "\n")
+	if title == "" {
+		title = lines[0]
+		if len(lines) > 1 {
+			title = fmt.Sprintf("Self-improvement: %s (+%d more)", lines[0], len(lines)-1)
+		}
+	}
+	if body == "" {
+		stat, _ := m.gitOutput(ctx, "diff", "--stat", "origin/"+baseBranch+"...HEAD")
+		var b strings.Builder
+		b.WriteString("Automated change proposed by the groq-go self-improvement tool.\n\n## Commits\n\n")
+		for _, s := range lines {
+			fmt.Fprintf(&b, "- %s\n", s)
+		}
+		fmt.Fprintf(&b, "\n## Changed files\n\n```\n%s```\n", stat)
+		body = b.String()
+	}
+
+	if err := m.runGit(ctx, "push", "-u", "origin", m.branch); err != nil {
+		return nil, err
+	}
+
+	var pr PullRequest
+	err = m.github(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/pulls", owner, repo), map[string]any{
+		"title": title,
+		"head":  m.branch,
+		"base":  baseBranch,
+		"body":  body,
+	}, &pr)
+	if err != nil {
+		return nil, err
+	}
+	m.lastPR = pr.Number
+	return &pr, nil
+}
+
+// PRStatus returns the state, mergeability and checks of pull request
+// number, or of the last opened one if number is 0
+func (m *Manager) PRStatus(ctx context.Context, number int) (*PRStatus, error) {
+	owner, repo, number, err := m.prTarget(number)
+	if err != nil {
+		return nil, err
+	}
+
+	var status PRStatus
+	if err := m.github(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/pulls/%d", owner, repo, number), nil, &status.PullRequest); err != nil {
+		return nil, err
+	}
+	var checks struct {
+		CheckRuns []CheckRun `json:"check_runs"`
+	}
+	if err := m.github(ctx, http.MethodGet, fmt.Sprintf("/repos/%s/%s/commits/%s/check-runs", owner, repo, status.Head.SHA), nil, &checks); err != nil {
+		return nil, err
+	}
+	status.Checks = checks.CheckRuns
+	return &status, nil
+}
+
+// MergePR squash-merges pull request number (or the last opened one) and
+// returns the merge commit. It requires SELF_IMPROVE_AUTO_MERGE.
+func (m *Manager) MergePR(ctx context.Context, number int) (string, error) {
+	if !m.autoMerge {
+		return "", ErrAutoMergeDisabled
+	}
+	owner, repo, number, err := m.prTarget(number)
+	if err != nil {
+		return "", err
+	}
+
+	var result struct {
+		SHA    string `json:"sha"`
+		Merged bool   `json:"merged"`
+	}
+	err = m.github(ctx, http.MethodPut, fmt.Sprintf("/repos/%s/%s/pulls/%d/merge", owner, repo, number), map[string]any{
+		"merge_method": "squash",
+	}, &result)
+	if err != nil {
+		return "", err
+	}
+	if !result.Merged {
+		return "", fmt.Errorf("pull request #%d was not merged", number)
+	}
+	return result.SHA, nil
+}
+
+// prTarget resolves the repository and a PR number, defaulting to the last opened PR
+func (m *Manager) prTarget(number int) (owner, repo string, n int, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if number == 0 {
+		number = m.lastPR
+	}
+	if number <= 0 {
+		return "", "", 0, fmt.Errorf("no pull request number given and none opened yet")
+	}
+	owner, repo, err = m.repoSlug()
+	return owner, repo, number, err
+}
+
+// repoSlug extracts owner and repository from the GitHub repo URL
+func (m *Manager) repoSlug() (owner, repo string, err error) {
+	match := repoSlugRe.FindStringSubmatch(m.repoURL)
+	if match == nil {
+		return "", "", fmt.Errorf("not a GitHub repository: %s", m.repoURL)
+	}
+	return match[1], match[2], nil
+}
+
+// github sends a GitHub REST API request and decodes the response into out
+func (m *Manager) github(ctx context.Context, method, path string, in, out any) error {
+	if m.githubToken == "" {
+		return fmt.Errorf("GITHUB_TOKEN is not set")
+	}
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, m.apiBase+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+m.githubToken)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return fmt.Errorf("failed to read github response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		var apiErr struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Message string `json:"message"`
+			} `json:"errors"`
+		}
+		json.Unmarshal(data, &apiErr)
+		msg := apiErr.Message
+		for _, e := range apiErr.Errors {
+			if e.Message != "" {
+				msg += ": " + e.Message
+			}
+		}
+		return fmt.Errorf("github error (status %d): %s", resp.StatusCode, msg)
+	}
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to parse github response: %w", err)
+		}
+	}
+	return nil
+}
+
+// gitOutput runs git in the repository and returns its output
+func (m *Manager) gitOutput(ctx context.Context, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", m.repoDir}, args...)...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w", args[0], err)
+	}
+	return string(output), nil
+}
@@ -0,0 +1,195 @@
+package selfimprove
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// gitRepo creates a clone of a bare "origin" repository with one commit on
+// main and returns the clone and origin paths
+func gitRepo(t *testing.T) (repo, origin string) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	origin = filepath.Join(dir, "origin.git")
+	repo = filepath.Join(dir, "repo")
+
+	run := func(dir string, args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	os.MkdirAll(src, 0755)
+	run(src, "init", "-b", "main")
+	os.WriteFile(filepath.Join(src, "main.go"), []byte("package main\n"), 0644)
+	run(src, "add", "-A")
+	run(src, "commit", "-m", "initial")
+	run(dir, "clone", "--bare", src, origin)
+	run(dir, "clone", origin, repo)
+	run(repo, "config", "user.name", "test")
+	run(repo, "config", "user.email", "test@example.com")
+	return repo, origin
+}
+
+// fakeGitHub records requests and answers them from routes keyed by
+// "METHOD path"
+type fakeGitHub struct {
+	mu       sync.Mutex
+	requests map[string]map[string]any
+	routes   map[string]string
+}
+
+func (f *fakeGitHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := r.Method + " " + r.URL.Path
+	if r.Header.Get("Authorization") != "Bearer tok" {
+		http.Error(w, `{"message":"Bad credentials"}`, http.StatusUnauthorized)
+		return
+	}
+	var body map[string]any
+	json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	f.requests[key] = body
+	f.mu.Unlock()
+
+	resp, ok := f.routes[key]
+	if !ok {
+		http.Error(w, `{"message":"Not Found"}`, http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(resp))
+}
+
+func newPRTestManager(t *testing.T, routes map[string]string) (*Manager, *fakeGitHub, string) {
+	repo, origin := gitRepo(t)
+	gh := &fakeGitHub{requests: make(map[string]map[string]any), routes: routes}
+	api := httptest.NewServer(gh)
+	t.Cleanup(api.Close)
+	return &Manager{
+		repoDir:     repo,
+		repoURL:     "https://github.com/owner/groq-go.git",
+		githubToken: "tok",
+		apiBase:     api.URL,
+		httpClient:  api.Client(),
+	}, gh, origin
+}
+
+func TestOpenPR(t *testing.T) {
+	m, gh, origin := newPRTestManager(t, map[string]string{
+		"POST /repos/owner/groq-go/pulls": `{"number": 7, "title": "Fix glob sorting", "html_url": "https://github.com/owner/groq-go/pull/7"}`,
+	})
+	ctx := context.Background()
+
+	if _, err := m.OpenPR(ctx, "", ""); err == nil {
+		t.Error("Expected an error without a work branch")
+	}
+	branch, err := m.CreateWorkBranch(ctx, "Fix glob sorting!")
+	if err != nil {
+		t.Fatalf("CreateWorkBranch failed: %v", err)
+	}
+	if branch != "self-improve/fix-glob-sorting" {
+		t.Errorf("Unexpected branch %q", branch)
+	}
+	if _, err := m.OpenPR(ctx, "", ""); err == nil {
+		t.Error("Expected an error without commits on the branch")
+	}
+
+	m.WriteFile(ctx, "glob.go", "package main\n")
+	if _, err := m.Commit(ctx, "Fix glob sorting"); err != nil {
+		t.Fatalf("Commit failed: %v", err)
+	}
+	pr, err := m.OpenPR(ctx, "", "")
+	if err != nil {
+		t.Fatalf("OpenPR failed: %v", err)
+	}
+	if pr.Number != 7 || pr.HTMLURL != "https://github.com/owner/groq-go/pull/7" {
+		t.Errorf("Unexpected PR %+v", pr)
+	}
+
+	req := gh.requests["POST /repos/owner/groq-go/pulls"]
+	if req["head"] != branch || req["base"] != "main" || req["title"] != "Fix glob sorting" {
+		t.Errorf("Unexpected PR payload %v", req)
+	}
+	if body, _ := req["body"].(string); !strings.Contains(body, "- Fix glob sorting") || !strings.Contains(body, "glob.go") {
+		t.Errorf("Expected generated body to list commits and files, got %q", body)
+	}
+
+	// The branch was pushed; main was not touched
+	if err := exec.Command("git", "--git-dir", origin, "rev-parse", "--verify", "refs/heads/"+branch).Run(); err != nil {
+		t.Error("Expected work branch on origin")
+	}
+	out, _ := exec.Command("git", "--git-dir", origin, "log", "--format=%s", "main").Output()
+	if strings.TrimSpace(string(out)) != "initial" {
+		t.Errorf("Expected main unchanged, got %q", out)
+	}
+}
+
+func TestPRStatus(t *testing.T) {
+	m, _, _ := newPRTestManager(t, map[string]string{
+		"GET /repos/owner/groq-go/pulls/3": `{"number": 3, "title": "Add tool", "state": "open", "mergeable": true,
+			"mergeable_state": "clean", "head": {"ref": "self-improve/add-tool", "sha": "abc123"}}`,
+		"GET /repos/owner/groq-go/commits/abc123/check-runs": `{"check_runs": [
+			{"name": "test", "status": "completed", "conclusion": "failure"},
+			{"name": "lint", "status": "in_progress"}]}`,
+	})
+
+	if _, err := m.PRStatus(context.Background(), 0); err == nil {
+		t.Error("Expected an error without a PR number")
+	}
+	status, err := m.PRStatus(context.Background(), 3)
+	if err != nil {
+		t.Fatalf("PRStatus failed: %v", err)
+	}
+	summary := status.Summary()
+	for _, want := range []string{"PR #3: Add tool", "Mergeable: yes (clean)", "- test: failure", "- lint: in_progress"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected summary to contain %q, got:\n%s", want, summary)
+		}
+	}
+}
+
+func TestMergePR(t *testing.T) {
+	m, gh, _ := newPRTestManager(t, map[string]string{
+		"PUT /repos/owner/groq-go/pulls/3/merge": `{"sha": "def456", "merged": true}`,
+	})
+	m.lastPR = 3
+
+	if _, err := m.MergePR(context.Background(), 0); !errors.Is(err, ErrAutoMergeDisabled) {
+		t.Fatalf("Expected ErrAutoMergeDisabled, got %v", err)
+	}
+	if len(gh.requests) != 0 {
+		t.Error("Expected no API call while auto-merge is disabled")
+	}
+
+	m.autoMerge = true
+	sha, err := m.MergePR(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("MergePR failed: %v", err)
+	}
+	if sha != "def456" {
+		t.Errorf("Expected merge sha, got %q", sha)
+	}
+	if req := gh.requests["PUT /repos/owner/groq-go/pulls/3/merge"]; req["merge_method"] != "squash" {
+		t.Errorf("Expected squash merge, got %v", req)
+	}
+
+	// API errors carry GitHub's message
+	m.githubToken = "wrong"
+	if _, err := m.MergePR(context.Background(), 3); err == nil || !strings.Contains(err.Error(), "Bad credentials") {
+		t.Errorf("Expected API error message, got %v", err)
+	}
+}
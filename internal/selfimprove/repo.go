@@ -0,0 +1,154 @@
+package selfimprove
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Working tree errors. Both are returned wrapped with the branches involved.
+var (
+	// ErrWrongBranch means another branch is checked out than the operation expects
+	ErrWrongBranch = errors.New("unexpected branch checked out")
+	// ErrDirtyWorktree means uncommitted changes would follow a checkout to another branch
+	ErrDirtyWorktree = errors.New("working tree has uncommitted changes")
+)
+
+// LockRepo takes the lock that serializes checkouts, writes and builds in
+// the shared working tree. version.Manager borrows it for its branches.
+// Call the returned function to release it.
+func (m *Manager) LockRepo() func() {
+	m.repoMu.Lock()
+	return m.repoMu.Unlock
+}
+
+// CurrentBranch returns the branch checked out in the working tree
+func (m *Manager) CurrentBranch(ctx context.Context) (string, error) {
+	out, err := m.gitOutput(ctx, "symbolic-ref", "--short", "HEAD")
+	if err != nil {
+		return "", fmt.Errorf("cannot determine current branch (detached HEAD?): %w", err)
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// checkBranch verifies that self-improve's branch is checked out: the work
+// branch if one was created, main otherwise. A self-improve/ branch left
+// checked out by an earlier run is adopted as the work branch. The caller
+// must hold the repo lock but not m.mu.
+func (m *Manager) checkBranch(ctx context.Context) (string, error) {
+	current, err := m.CurrentBranch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.branch == "" && strings.HasPrefix(current, workBranchPrefix) {
+		m.branch = current
+	}
+	expected := m.branch
+	if expected == "" {
+		expected = baseBranch
+	}
+	if current != expected {
+		return "", fmt.Errorf("%w: %s is checked out but self-improve works on %s; another tool (e.g. a version build) may have switched branches, run `git checkout %s` in %s",
+			ErrWrongBranch, current, expected, expected, m.repoDir)
+	}
+	return current, nil
+}
+
+// OnBranch checks out branch, runs fn and switches back to the branch
+// that was checked out before, all under the repo lock. It refuses to
+// switch while there are uncommitted changes, since they would be carried
+// over to branch.
+func (m *Manager) OnBranch(ctx context.Context, branch string, fn func(repoDir string) error) error {
+	defer m.LockRepo()()
+
+	previous, err := m.CurrentBranch(ctx)
+	if err != nil {
+		return err
+	}
+	if previous != branch {
+		status, err := m.gitOutput(ctx, "status", "--porcelain")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(status) != "" {
+			return fmt.Errorf("%w on %s; commit or discard them before switching to %s", ErrDirtyWorktree, previous, branch)
+		}
+		if err := m.runGit(ctx, "checkout", branch); err != nil {
+			return err
+		}
+		defer func() {
+			if err := m.runGit(context.WithoutCancel(ctx), "checkout", previous); err != nil {
+				log.Error("Failed to restore branch", "branch", previous, "error", err)
+			}
+		}()
+	}
+	return fn(m.repoDir)
+}
+
+// ApplyToBranch writes a file on branch and commits it there, leaving the
+// checked-out branch as it was
+func (m *Manager) ApplyToBranch(ctx context.Context, branch, path, content, message string) (string, error) {
+	var hash string
+	err := m.OnBranch(ctx, branch, func(repoDir string) error {
+		if err := writeRepoFile(repoDir, path, content); err != nil {
+			return err
+		}
+		if err := m.runGit(ctx, "add", "-A"); err != nil {
+			return err
+		}
+		if err := m.runGit(ctx, "commit", "-m", message); err != nil {
+			return err
+		}
+		out, err := m.gitOutput(ctx, "rev-parse", "HEAD")
+		hash = strings.TrimSpace(out)
+		return err
+	})
+	return hash, err
+}
+
+// MergeBranch merges branch into main, fast-forwarding when possible, and
+// returns main's new commit. A conflicting merge is aborted.
+func (m *Manager) MergeBranch(ctx context.Context, branch, message string) (string, error) {
+	var hash string
+	err := m.OnBranch(ctx, baseBranch, func(repoDir string) error {
+		if err := m.runGit(ctx, "merge", "--no-edit", "-m", message, branch); err != nil {
+			m.runGit(context.WithoutCancel(ctx), "merge", "--abort")
+			return err
+		}
+		out, err := m.gitOutput(ctx, "rev-parse", "HEAD")
+		hash = strings.TrimSpace(out)
+		return err
+	})
+	return hash, err
+}
+
+// AddBranch creates branch at the current commit without checking it out
+func (m *Manager) AddBranch(ctx context.Context, branch string) error {
+	defer m.LockRepo()()
+	return m.runGit(ctx, "branch", branch)
+}
+
+// DeleteBranch deletes branch, switching to main first if it is checked out
+func (m *Manager) DeleteBranch(ctx context.Context, branch string) error {
+	defer m.LockRepo()()
+	if current, _ := m.CurrentBranch(ctx); current == branch {
+		if err := m.runGit(ctx, "checkout", baseBranch); err != nil {
+			return err
+		}
+	}
+	return m.runGit(ctx, "branch", "-D", branch)
+}
+
+func writeRepoFile(repoDir, path, content string) error {
+	fullPath := filepath.Join(repoDir, path)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(fullPath, []byte(content), 0644)
+}
@@ -6,12 +6,19 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"net/http"
 	"path/filepath"
 	"strings"
 	"sync"
 	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/logging"
+	"groq-go/internal/notify"
 )
 
+var log = logging.WithComponent("selfimprove")
+
 // Manager handles self-improvement operations
 type Manager struct {
 	repoDir         string
@@ -21,6 +28,17 @@ type Manager struct {
 	history         []Commit
 	lastKnownGood   string // Last known working commit hash
 	safeCommitFile  string // File to persist last known good commit
+	repoMu          sync.Mutex // Serializes working tree operations, see LockRepo
+	verifyMu        sync.Mutex
+	verifying       bool // A VerifyBuild or VerifyTests is in flight
+	branch          string // Work branch from CreateWorkBranch
+	lastPR          int    // Number of the last PR opened
+	autoMerge       bool   // merge_pr is allowed (SELF_IMPROVE_AUTO_MERGE)
+	apiBase         string // GitHub API base URL
+	httpClient      *http.Client
+
+	notifier    *notify.Dispatcher // Push events; nil sends none
+	checkWrites bool               // See ChecksWrites
 }
 
 // Commit represents a git commit
@@ -28,14 +46,14 @@ type Commit struct {
 	Hash      string    `json:"hash"`
 	Message   string    `json:"message"`
 	Timestamp time.Time `json:"timestamp"`
+	Branch    string    `json:"branch,omitempty"`
 }
 
 // NewManager creates a new self-improvement manager
-func NewManager() (*Manager, error) {
-	githubToken := os.Getenv("GITHUB_TOKEN")
-	repoURL := os.Getenv("SELF_REPO_URL")
+func NewManager(cfg config.SelfImproveConfig) (*Manager, error) {
+	repoURL := cfg.RepoURL
 	if repoURL == "" {
-		repoURL = "https://github.com/yukihamada/groq-go.git"
+		repoURL = config.DefaultRepoURL
 	}
 
 	// Working directory for the repo
@@ -49,9 +67,13 @@ func NewManager() (*Manager, error) {
 	m := &Manager{
 		repoDir:        repoDir,
 		repoURL:        repoURL,
-		githubToken:    githubToken,
+		githubToken:    cfg.GitHubToken,
 		history:        make([]Commit, 0),
 		safeCommitFile: safeCommitFile,
+		autoMerge:      cfg.AutoMerge,
+		checkWrites:    cfg.CheckWrites,
+		apiBase:        DefaultGitHubAPI,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
 	}
 
 	// Load last known good commit
@@ -64,6 +86,7 @@ func NewManager() (*Manager, error) {
 
 // Init initializes the repository
 func (m *Manager) Init(ctx context.Context) error {
+	defer m.LockRepo()()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -115,40 +138,29 @@ func (m *Manager) ReadFile(ctx context.Context, path string) (string, error) {
 	return string(data), nil
 }
 
-// WriteFile writes a file to the repository
-func (m *Manager) WriteFile(ctx context.Context, path, content string) error {
-	fullPath := filepath.Join(m.repoDir, path)
-	if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-		return err
+// WriteFile writes a file to the repository and returns the branch it was
+// written to. It fails with ErrWrongBranch unless self-improve's branch is
+// checked out.
+func (m *Manager) WriteFile(ctx context.Context, path, content string) (string, error) {
+	defer m.LockRepo()()
+
+	branch, err := m.checkBranch(ctx)
+	if err != nil {
+		return "", err
 	}
-	return os.WriteFile(fullPath, []byte(content), 0644)
+	return branch, writeRepoFile(m.repoDir, path, content)
 }
 
-// ListFiles lists files in the repository
-func (m *Manager) ListFiles(ctx context.Context, pattern string) ([]string, error) {
-	var files []string
-	err := filepath.Walk(m.repoDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
-			if info.Name() == ".git" {
-				return filepath.SkipDir
-			}
-			return nil
-		}
+// Commit commits changes with a message. Like WriteFile it requires
+// self-improve's branch to be checked out.
+func (m *Manager) Commit(ctx context.Context, message string) (*Commit, error) {
+	defer m.LockRepo()()
 
-		relPath, _ := filepath.Rel(m.repoDir, path)
-		if pattern == "" || strings.Contains(relPath, pattern) {
-			files = append(files, relPath)
-		}
-		return nil
-	})
-	return files, err
-}
+	branch, err := m.checkBranch(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-// Commit commits changes with a message
-func (m *Manager) Commit(ctx context.Context, message string) (*Commit, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -178,6 +190,7 @@ func (m *Manager) Commit(ctx context.Context, message string) (*Commit, error) {
 		Hash:      strings.TrimSpace(string(hashOutput)),
 		Message:   message,
 		Timestamp: time.Now(),
+		Branch:    branch,
 	}
 
 	m.history = append(m.history, *commit)
@@ -186,16 +199,20 @@ func (m *Manager) Commit(ctx context.Context, message string) (*Commit, error) {
 	return commit, nil
 }
 
-// Push pushes changes to remote
+// Push pushes changes straight to main, which deploys them. Prefer a work
+// branch and OpenPR.
 func (m *Manager) Push(ctx context.Context) error {
+	defer m.LockRepo()()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	return m.runGit(ctx, "push", "origin", "main")
+	log.Warn("Pushing self-modified code directly to main without review; use create_branch and open_pr instead", "repo", m.repoURL)
+	return m.runGit(ctx, "push", "origin", "HEAD:"+baseBranch)
 }
 
 // Rollback rolls back to a previous commit
 func (m *Manager) Rollback(ctx context.Context, commitHash string) error {
+	defer m.LockRepo()()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -284,23 +301,97 @@ func (m *Manager) ToJSON() string {
 	return string(data)
 }
 
-// VerifyBuild tests if the code compiles successfully
-func (m *Manager) VerifyBuild(ctx context.Context) error {
-	cmd := exec.CommandContext(ctx, "go", "build", "-o", "/dev/null", ".")
-	cmd.Dir = m.repoDir
-	output, err := cmd.CombinedOutput()
+// VerifyBuild tests if the code compiles successfully.
+// Only one verification runs at a time; concurrent calls get ErrAlreadyBuilding.
+func (m *Manager) VerifyBuild(ctx context.Context, progress ProgressFunc) error {
+	if err := m.beginVerify(); err != nil {
+		return err
+	}
+	defer m.endVerify()
+	defer m.LockRepo()()
+
+	if err := GoBuild(ctx, m.repoDir, os.DevNull, nil, progress); err != nil {
+		return fmt.Errorf("build verification failed: %w", err)
+	}
+	progress.Report(StageVerify, "build succeeded")
+	return nil
+}
+
+// VerifyTests runs the test suite for pattern (default "./..."). Failing
+// tests are returned as an error naming the packages and tests, alongside
+// the report. It shares VerifyBuild's in-flight guard.
+func (m *Manager) VerifyTests(ctx context.Context, pattern string) (*TestReport, error) {
+	if err := m.beginVerify(); err != nil {
+		return nil, err
+	}
+	defer m.endVerify()
+	defer m.LockRepo()()
+
+	report, err := GoTest(ctx, m.repoDir, pattern)
 	if err != nil {
-		return fmt.Errorf("build verification failed: %s - %w", string(output), err)
+		return nil, fmt.Errorf("test verification failed: %w", err)
+	}
+	if !report.OK() {
+		return report, fmt.Errorf("tests failed: %s", report.Summary())
 	}
+	return report, nil
+}
+
+func (m *Manager) beginVerify() error {
+	m.verifyMu.Lock()
+	defer m.verifyMu.Unlock()
+	if m.verifying {
+		return ErrAlreadyBuilding
+	}
+	m.verifying = true
 	return nil
 }
 
-// SafePush pushes only if the code builds successfully
-func (m *Manager) SafePush(ctx context.Context) error {
+func (m *Manager) endVerify() {
+	m.verifyMu.Lock()
+	m.verifying = false
+	m.verifyMu.Unlock()
+}
+
+// SetNotifier sends SafePush results to n
+func (m *Manager) SetNotifier(n *notify.Dispatcher) {
+	m.notifier = n
+}
+
+// SafePush pushes only if the code builds and, unless skipTests is set,
+// the test suite passes. The outcome is sent to the notifier.
+func (m *Manager) SafePush(ctx context.Context, skipTests bool, progress ProgressFunc) error {
+	started := time.Now()
+	err := m.safePush(ctx, skipTests, progress)
+
+	e := notify.Event{
+		Type:      notify.PushSucceeded,
+		StartedAt: started,
+		Message:   "Pushed a verified build to " + baseBranch,
+		Refs:      map[string]string{"repo": m.repoURL, "branch": baseBranch},
+	}
+	if err != nil {
+		e.Type, e.Message, e.Error = notify.PushFailed, "Safe push failed", err.Error()
+	} else {
+		e.Refs["commit"] = m.GetLastKnownGood()
+	}
+	m.notifier.Emit(e)
+	return err
+}
+
+func (m *Manager) safePush(ctx context.Context, skipTests bool, progress ProgressFunc) error {
 	// First verify the build
-	if err := m.VerifyBuild(ctx); err != nil {
+	if err := m.VerifyBuild(ctx, progress); err != nil {
 		return fmt.Errorf("cannot push: %w", err)
 	}
+	if !skipTests {
+		progress.Report(StageTest, "go test ./...")
+		report, err := m.VerifyTests(ctx, "")
+		if err != nil {
+			return fmt.Errorf("cannot push: %w", err)
+		}
+		progress.Report(StageVerify, fmt.Sprintf("tests passed (%s)", report.Summary()))
+	}
 
 	// Push to remote
 	if err := m.Push(ctx); err != nil {
@@ -317,7 +408,11 @@ func (m *Manager) MarkAsGood(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
-	hash := strings.TrimSpace(string(output))
+	return m.MarkCommitGood(strings.TrimSpace(string(output)))
+}
+
+// MarkCommitGood marks hash as last known good
+func (m *Manager) MarkCommitGood(hash string) error {
 	m.lastKnownGood = hash
 	return os.WriteFile(m.safeCommitFile, []byte(hash), 0644)
 }
@@ -329,6 +424,7 @@ func (m *Manager) GetLastKnownGood() string {
 
 // RollbackToCommit rolls back to a specific commit by hash
 func (m *Manager) RollbackToCommit(ctx context.Context, hash string) error {
+	defer m.LockRepo()()
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
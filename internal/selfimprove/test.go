@@ -0,0 +1,172 @@
+package selfimprove
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// StageTest is reported while the test suite runs
+const StageTest = "test"
+
+// testTimeout bounds a whole `go test` run
+const testTimeout = "5m"
+
+// maxFailureOutput caps the output kept for the first failure; later
+// failures are only listed by name
+const maxFailureOutput = 4000
+
+// TestFailure is a failing test, or a package that failed without a
+// failing test (e.g. it did not compile)
+type TestFailure struct {
+	Package string `json:"package"`
+	Test    string `json:"test,omitempty"` // empty for package failures
+	Output  string `json:"output,omitempty"`
+}
+
+// TestReport summarizes a `go test` run
+type TestReport struct {
+	Passed   int           `json:"passed"`
+	Failed   int           `json:"failed"`
+	Skipped  int           `json:"skipped"`
+	Failures []TestFailure `json:"failures,omitempty"`
+}
+
+// OK reports whether nothing failed
+func (r *TestReport) OK() bool {
+	return len(r.Failures) == 0
+}
+
+// Summary renders the counts, the failing packages and tests, and the
+// output of the first failure
+func (r *TestReport) Summary() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d passed, %d failed, %d skipped", r.Passed, r.Failed, r.Skipped)
+	for _, f := range r.Failures {
+		if f.Test != "" {
+			fmt.Fprintf(&b, "\nFAIL %s %s", f.Package, f.Test)
+		} else {
+			fmt.Fprintf(&b, "\nFAIL %s (package failed)", f.Package)
+		}
+	}
+	if len(r.Failures) > 0 && r.Failures[0].Output != "" {
+		first := r.Failures[0]
+		name := first.Package
+		if first.Test != "" {
+			name = first.Test + " in " + first.Package
+		}
+		fmt.Fprintf(&b, "\n\nOutput of %s:\n%s", name, first.Output)
+	}
+	return b.String()
+}
+
+// testEvent is one line of `go test -json` output
+type testEvent struct {
+	Action     string `json:"Action"`
+	Package    string `json:"Package"`
+	Test       string `json:"Test"`
+	Output     string `json:"Output"`
+	ImportPath string `json:"ImportPath"` // build-output events
+}
+
+// GoTest runs `go test -json` for pattern (default "./...") in dir and
+// collects the results. The error is only set if the tests could not be
+// run; failing tests are reported in the TestReport.
+func GoTest(ctx context.Context, dir, pattern string) (*TestReport, error) {
+	if pattern == "" {
+		pattern = "./..."
+	}
+	if strings.HasPrefix(pattern, "-") || strings.ContainsAny(pattern, " \t\n") {
+		return nil, fmt.Errorf("invalid package pattern %q", pattern)
+	}
+
+	cmd := execCommand(ctx, "go", "test", "-json", "-count=1", "-timeout", testTimeout, pattern)
+	cmd.Dir = dir
+	cmd.Env = os.Environ()
+
+	pipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to capture test output: %w", err)
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start tests: %w", err)
+	}
+
+	report := &TestReport{}
+	outputs := make(map[string]*strings.Builder) // keyed by package and test
+	failedTests := make(map[string]bool)         // packages with a failing test
+	var raw strings.Builder                      // lines that are not test events
+
+	appendOutput := func(key, text string) {
+		b := outputs[key]
+		if b == nil {
+			b = &strings.Builder{}
+			outputs[key] = b
+		}
+		b.WriteString(text)
+	}
+
+	scanner := bufio.NewScanner(pipe)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var ev testEvent
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &ev) != nil {
+			raw.WriteString(line + "\n")
+			continue
+		}
+
+		key := ev.Package + " " + ev.Test
+		switch ev.Action {
+		case "output":
+			appendOutput(key, ev.Output)
+		case "build-output":
+			// ImportPath looks like "pkg [pkg.test]" for test builds
+			pkg, _, _ := strings.Cut(ev.ImportPath, " ")
+			appendOutput(pkg+" ", ev.Output)
+		case "pass":
+			if ev.Test != "" {
+				report.Passed++
+			}
+		case "skip":
+			if ev.Test != "" {
+				report.Skipped++
+			}
+		case "fail":
+			if ev.Test != "" {
+				report.Failed++
+				failedTests[ev.Package] = true
+				report.Failures = append(report.Failures, TestFailure{Package: ev.Package, Test: ev.Test, Output: outputOf(outputs[key])})
+			} else if !failedTests[ev.Package] {
+				report.Failures = append(report.Failures, TestFailure{Package: ev.Package, Output: outputOf(outputs[key])})
+			}
+		}
+	}
+
+	waitErr := cmd.Wait()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("tests interrupted: %w", ctx.Err())
+	}
+	if waitErr != nil && report.OK() {
+		// go test failed before running anything, e.g. no matching packages
+		return nil, fmt.Errorf("%s - %w", strings.TrimSpace(raw.String()), waitErr)
+	}
+	return report, nil
+}
+
+// outputOf returns collected output, keeping the end where failures are
+// reported
+func outputOf(b *strings.Builder) string {
+	if b == nil {
+		return ""
+	}
+	out := strings.TrimSpace(b.String())
+	if len(out) > maxFailureOutput {
+		out = "...\n" + out[len(out)-maxFailureOutput:]
+	}
+	return out
+}
@@ -0,0 +1,110 @@
+package selfimprove
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModule creates a Go module in a temp dir from a map of file contents
+func writeModule(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("go toolchain not available")
+	}
+	dir := t.TempDir()
+	files["go.mod"] = "module example.com/m\n\ngo 1.21\n"
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestVerifyTestsReportsFailures(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"m.go": "package m\n\nfunc Add(a, b int) int { return a + b }\n",
+		"m_test.go": `package m
+
+import "testing"
+
+func TestAddPasses(t *testing.T) {
+	if Add(1, 2) != 3 {
+		t.Fatal("wrong sum")
+	}
+}
+
+func TestAddFails(t *testing.T) {
+	t.Fatalf("expected 5, got %d", Add(2, 2))
+}
+`,
+		"broken/broken.go":      "package broken\n",
+		"broken/broken_test.go": "package broken\n\nimport \"testing\"\n\nfunc TestX(t *testing.T) { undefinedCall() }\n",
+	})
+	m := &Manager{repoDir: dir}
+
+	report, err := m.VerifyTests(context.Background(), "")
+	if err == nil {
+		t.Fatal("Expected failing tests to return an error")
+	}
+	if report.Passed != 1 || report.Failed != 1 {
+		t.Errorf("Expected 1 passed and 1 failed, got %d and %d", report.Passed, report.Failed)
+	}
+	if len(report.Failures) != 2 {
+		t.Fatalf("Expected a failing test and a failing package, got %+v", report.Failures)
+	}
+	for _, want := range []string{
+		"FAIL example.com/m TestAddFails",
+		"FAIL example.com/m/broken (package failed)",
+		"expected 5, got 4",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to contain %q, got:\n%s", want, err)
+		}
+	}
+	if broken := report.Failures[1]; !strings.Contains(broken.Output, "undefined: undefinedCall") {
+		t.Errorf("Expected compile error in package output, got %q", broken.Output)
+	}
+
+	// A pattern limits the run to the matching packages
+	report, err = m.VerifyTests(context.Background(), "./broken/...")
+	if err == nil || report.Failed != 0 {
+		t.Errorf("Expected only a package failure, got %+v, %v", report, err)
+	}
+}
+
+func TestVerifyTestsPasses(t *testing.T) {
+	dir := writeModule(t, map[string]string{
+		"m_test.go": "package m\n\nimport \"testing\"\n\nfunc TestOK(t *testing.T) {}\n\nfunc TestSkip(t *testing.T) { t.Skip() }\n",
+	})
+	m := &Manager{repoDir: dir}
+
+	report, err := m.VerifyTests(context.Background(), "./...")
+	if err != nil {
+		t.Fatalf("VerifyTests failed: %v", err)
+	}
+	if report.Passed != 1 || report.Skipped != 1 || !report.OK() {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestVerifyTestsRejectsFlags(t *testing.T) {
+	m := &Manager{repoDir: t.TempDir()}
+	for _, pattern := range []string{"-exec=rm", "./... -run x"} {
+		if _, err := m.VerifyTests(context.Background(), pattern); err == nil {
+			t.Errorf("Expected %q to be rejected", pattern)
+		}
+	}
+
+	m.verifying = true
+	if _, err := m.VerifyTests(context.Background(), ""); !errors.Is(err, ErrAlreadyBuilding) {
+		t.Errorf("Expected ErrAlreadyBuilding, got %v", err)
+	}
+}
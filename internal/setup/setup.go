@@ -0,0 +1,146 @@
+package setup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"groq-go/internal/client"
+	"groq-go/internal/config"
+)
+
+var (
+	// ErrAlreadyConfigured is returned when a config file exists and reconfigure was not requested
+	ErrAlreadyConfigured = errors.New("configuration already exists; run with --reconfigure to change it")
+	// ErrNoValidKeys is returned when none of the supplied keys passed the health check
+	ErrNoValidKeys = errors.New("no valid API keys provided")
+)
+
+// checkKey validates a provider key; swapped out in tests
+var checkKey = client.CheckKey
+
+// Request is the input to a setup run. Keys are indexed by provider name.
+type Request struct {
+	Keys  map[string]string `json:"keys"`
+	Model string            `json:"model"`
+}
+
+// ProviderStatus reports the health check result for one provider
+type ProviderStatus struct {
+	Provider string `json:"provider"`
+	Valid    bool   `json:"valid"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Result summarizes a setup run. It never contains API keys.
+type Result struct {
+	Providers []ProviderStatus `json:"providers"`
+	Models    []string         `json:"models"`
+	Model     string           `json:"model"`
+}
+
+// Validate checks every non-empty key and returns the statuses and the keys that passed
+func Validate(ctx context.Context, keys map[string]string) ([]ProviderStatus, map[string]string) {
+	var statuses []ProviderStatus
+	valid := make(map[string]string)
+
+	for _, provider := range client.Providers {
+		key := strings.TrimSpace(keys[provider])
+		if key == "" {
+			continue
+		}
+		status := ProviderStatus{Provider: provider}
+		if err := checkKey(ctx, provider, key); err != nil {
+			status.Error = err.Error()
+		} else {
+			status.Valid = true
+			valid[provider] = key
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, valid
+}
+
+// Models returns the suggested models for the given providers, in provider order
+func Models(keys map[string]string) []string {
+	var models []string
+	for _, provider := range client.Providers {
+		if keys[provider] != "" {
+			models = append(models, client.Catalog.ProviderModels(provider)...)
+		}
+	}
+	return models
+}
+
+// Write merges the validated keys and model into the config file.
+// An empty model selects the first suggested model.
+func Write(keys map[string]string, model string, reconfigure bool) (*config.Config, error) {
+	cfg := &config.Config{}
+	if config.Exists() {
+		if !reconfigure {
+			return nil, ErrAlreadyConfigured
+		}
+		existing, err := config.LoadFile()
+		if err != nil {
+			return nil, err
+		}
+		cfg = existing
+	}
+
+	for provider, key := range keys {
+		if err := cfg.SetProviderKey(provider, key); err != nil {
+			return nil, err
+		}
+	}
+	if !cfg.HasKeys() {
+		return nil, ErrNoValidKeys
+	}
+
+	models := Models(cfg.ProviderKeys())
+	switch {
+	case model != "":
+		if !contains(models, model) {
+			return nil, fmt.Errorf("model %s is not available with the configured providers", model)
+		}
+		cfg.Model = model
+	case !contains(models, cfg.Model) && len(models) > 0:
+		cfg.Model = models[0]
+	}
+
+	if err := config.Save(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Apply validates the request keys and writes the configuration
+func Apply(ctx context.Context, req Request, reconfigure bool) (*Result, *config.Config, error) {
+	if config.Exists() && !reconfigure {
+		return nil, nil, ErrAlreadyConfigured
+	}
+
+	statuses, valid := Validate(ctx, req.Keys)
+	result := &Result{Providers: statuses, Models: Models(valid)}
+	if len(valid) == 0 {
+		return result, nil, ErrNoValidKeys
+	}
+
+	cfg, err := Write(valid, req.Model, reconfigure)
+	if err != nil {
+		return result, nil, err
+	}
+	result.Models = Models(cfg.ProviderKeys())
+	result.Model = cfg.Model
+	return result, cfg, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package setup
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"groq-go/internal/config"
+)
+
+// fakeKeys accepts only keys starting with "good-"
+func fakeKeys(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	orig := checkKey
+	checkKey = func(ctx context.Context, provider, key string) error {
+		if strings.HasPrefix(key, "good-") {
+			return nil
+		}
+		return fmt.Errorf("%s rejected the API key (status 401)", provider)
+	}
+	t.Cleanup(func() { checkKey = orig })
+}
+
+func TestApplyWritesConfig(t *testing.T) {
+	fakeKeys(t)
+
+	req := Request{Keys: map[string]string{"groq": "good-groq", "openai": "bad-openai"}}
+	result, cfg, err := Apply(context.Background(), req, false)
+	if err != nil {
+		t.Fatalf("Apply failed: %v", err)
+	}
+
+	if cfg.APIKey != "good-groq" || cfg.OpenAIKey != "" {
+		t.Errorf("Expected only the valid key to be saved, got %+v", cfg)
+	}
+	if result.Model != "llama-3.3-70b-versatile" {
+		t.Errorf("Expected default groq model, got %s", result.Model)
+	}
+	if len(result.Providers) != 2 {
+		t.Errorf("Expected 2 provider statuses, got %d", len(result.Providers))
+	}
+
+	// Keys must never be echoed back
+	data, _ := json.Marshal(result)
+	if strings.Contains(string(data), "good-groq") || strings.Contains(string(data), "bad-openai") {
+		t.Errorf("Result leaks API keys: %s", data)
+	}
+
+	info, err := os.Stat(config.Path())
+	if err != nil {
+		t.Fatalf("Config not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected config mode 0600, got %o", info.Mode().Perm())
+	}
+}
+
+func TestApplyRefusesExistingConfig(t *testing.T) {
+	fakeKeys(t)
+
+	req := Request{Keys: map[string]string{"groq": "good-groq"}}
+	if _, _, err := Apply(context.Background(), req, false); err != nil {
+		t.Fatalf("First Apply failed: %v", err)
+	}
+	if _, _, err := Apply(context.Background(), req, false); !errors.Is(err, ErrAlreadyConfigured) {
+		t.Errorf("Expected ErrAlreadyConfigured, got %v", err)
+	}
+
+	// Reconfigure keeps existing keys and adds new ones
+	req = Request{Keys: map[string]string{"anthropic": "good-claude"}, Model: "claude-sonnet-4-20250514"}
+	_, cfg, err := Apply(context.Background(), req, true)
+	if err != nil {
+		t.Fatalf("Reconfigure failed: %v", err)
+	}
+	if cfg.APIKey != "good-groq" || cfg.ClaudeKey != "good-claude" {
+		t.Errorf("Expected merged keys, got %+v", cfg)
+	}
+	if cfg.Model != "claude-sonnet-4-20250514" {
+		t.Errorf("Expected selected model, got %s", cfg.Model)
+	}
+}
+
+func TestApplyNoValidKeys(t *testing.T) {
+	fakeKeys(t)
+
+	req := Request{Keys: map[string]string{"groq": "bad"}}
+	result, _, err := Apply(context.Background(), req, false)
+	if !errors.Is(err, ErrNoValidKeys) {
+		t.Fatalf("Expected ErrNoValidKeys, got %v", err)
+	}
+	if len(result.Providers) != 1 || result.Providers[0].Valid {
+		t.Errorf("Expected one invalid provider status, got %+v", result.Providers)
+	}
+	if config.Exists() {
+		t.Error("Config should not be written when no key is valid")
+	}
+}
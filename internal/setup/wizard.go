@@ -0,0 +1,137 @@
+package setup
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/chzyer/readline"
+
+	"groq-go/internal/client"
+	"groq-go/internal/config"
+)
+
+// providerLabels are the display names used by the wizard
+var providerLabels = map[string]string{
+	"groq":      "Groq",
+	"anthropic": "Anthropic (Claude)",
+	"openai":    "OpenAI",
+	"moonshot":  "Moonshot (Kimi)",
+	"gemini":    "Google (Gemini)",
+}
+
+// Prompter reads answers from the user
+type Prompter interface {
+	// ReadLine prompts for a visible answer
+	ReadLine(prompt string) (string, error)
+	// ReadSecret prompts for an answer without echoing it
+	ReadSecret(prompt string) (string, error)
+}
+
+// terminalPrompter reads from the terminal, hiding secrets
+type terminalPrompter struct {
+	reader *bufio.Reader
+	out    io.Writer
+}
+
+// NewTerminalPrompter returns a Prompter backed by stdin
+func NewTerminalPrompter() Prompter {
+	return &terminalPrompter{reader: bufio.NewReader(os.Stdin), out: os.Stdout}
+}
+
+func (p *terminalPrompter) ReadLine(prompt string) (string, error) {
+	fmt.Fprint(p.out, prompt)
+	line, err := p.reader.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimSpace(line), nil
+}
+
+func (p *terminalPrompter) ReadSecret(prompt string) (string, error) {
+	secret, err := readline.Password(prompt)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(secret)), nil
+}
+
+// RunWizard guides the user through configuring provider keys and a default model
+func RunWizard(ctx context.Context, p Prompter, out io.Writer, reconfigure bool) (*config.Config, error) {
+	if config.Exists() && !reconfigure {
+		return nil, ErrAlreadyConfigured
+	}
+
+	fmt.Fprintln(out, "Welcome to groq-go! Let's set up your API keys.")
+	fmt.Fprintln(out, "Enter a key for each provider you use, or leave it empty to skip.")
+	fmt.Fprintln(out)
+
+	var valid map[string]string
+	for len(valid) == 0 {
+		keys := make(map[string]string)
+		for _, provider := range client.Providers {
+			key, err := p.ReadSecret(fmt.Sprintf("%s API key: ", providerLabels[provider]))
+			if err != nil {
+				return nil, err
+			}
+			if key != "" {
+				keys[provider] = key
+			}
+		}
+		if len(keys) == 0 {
+			fmt.Fprintln(out, "At least one API key is required.")
+			continue
+		}
+
+		fmt.Fprintln(out, "Checking keys...")
+		var statuses []ProviderStatus
+		statuses, valid = Validate(ctx, keys)
+		for _, s := range statuses {
+			if s.Valid {
+				fmt.Fprintf(out, "  ✓ %s\n", providerLabels[s.Provider])
+			} else {
+				fmt.Fprintf(out, "  ✗ %s: %s\n", providerLabels[s.Provider], s.Error)
+			}
+		}
+		if len(valid) == 0 {
+			fmt.Fprintln(out, "None of the keys worked, please try again.")
+			fmt.Fprintln(out)
+		}
+	}
+
+	models := Models(valid)
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "Choose a default model:")
+	for i, m := range models {
+		fmt.Fprintf(out, "  %d) %s\n", i+1, m)
+	}
+
+	model := models[0]
+	for {
+		answer, err := p.ReadLine(fmt.Sprintf("Model [1-%d, default 1]: ", len(models)))
+		if err != nil {
+			return nil, err
+		}
+		if answer == "" {
+			break
+		}
+		n, err := strconv.Atoi(answer)
+		if err == nil && n >= 1 && n <= len(models) {
+			model = models[n-1]
+			break
+		}
+		fmt.Fprintln(out, "Please enter a number from the list.")
+	}
+
+	cfg, err := Write(valid, model, reconfigure)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(out, "\nSaved configuration to %s (default model: %s)\n\n", config.Path(), cfg.Model)
+	return cfg, nil
+}
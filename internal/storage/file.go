@@ -4,11 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
 	"sync"
-	"time"
 )
 
 // FileStorage implements Storage using JSON files
@@ -45,28 +45,7 @@ func (s *FileStorage) SaveSession(ctx context.Context, session *Session) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Set timestamps
-	now := time.Now()
-	if session.CreatedAt.IsZero() {
-		session.CreatedAt = now
-	}
-	session.UpdatedAt = now
-
-	// Generate title from first user message if not set
-	if session.Title == "" && len(session.Messages) > 0 {
-		for _, msg := range session.Messages {
-			if msg.Role == "user" {
-				// Content can be string or []ContentPart
-				if title, ok := msg.Content.(string); ok {
-					if len(title) > 50 {
-						title = title[:50] + "..."
-					}
-					session.Title = title
-				}
-				break
-			}
-		}
-	}
+	touchSession(session)
 
 	data, err := json.MarshalIndent(session, "", "  ")
 	if err != nil {
@@ -136,6 +115,7 @@ func (s *FileStorage) ListSessions(ctx context.Context) ([]*SessionMeta, error)
 			Title:     session.Title,
 			CreatedAt: session.CreatedAt,
 			UpdatedAt: session.UpdatedAt,
+			OwnerID:   session.OwnerID,
 		})
 	}
 
@@ -147,6 +127,71 @@ func (s *FileStorage) ListSessions(ctx context.Context) ([]*SessionMeta, error)
 	return sessions, nil
 }
 
+// SearchSessions scans the session files for every word of query. Files
+// are checked for the words before they are decoded, so sessions that
+// cannot match are never loaded.
+func (s *FileStorage) SearchSessions(ctx context.Context, query, owner string, limit int) ([]*SessionMatch, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	raw := rawTerms(terms)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read storage directory: %w", err)
+	}
+
+	var matches []scoredMatch
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		session, err := s.searchFile(filepath.Join(s.dir, entry.Name()), raw)
+		if err != nil || session == nil || owner != "" && session.OwnerID != owner {
+			continue
+		}
+		if m, ok := scoreSession(session, terms); ok {
+			matches = append(matches, m)
+		}
+	}
+	return rankMatches(matches, limit), nil
+}
+
+// searchFile decodes the session at path if it holds every raw term, and
+// returns nil otherwise
+func (s *FileStorage) searchFile(path string, raw [][]byte) (*Session, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if ok, err := containsAll(f, raw); !ok || err != nil {
+		return nil, err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	var session Session
+	if err := json.NewDecoder(f).Decode(&session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
 // DeleteSession deletes a session by ID
 func (s *FileStorage) DeleteSession(ctx context.Context, id string) error {
 	s.mu.Lock()
@@ -237,6 +282,58 @@ func (s *FileStorage) IncrementShareViewCount(ctx context.Context, shareID strin
 	return nil
 }
 
+// ListSharesByOwner returns the shares created by an owner, newest first
+func (s *FileStorage) ListSharesByOwner(ctx context.Context, ownerID string) ([]*SharedConversation, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries, err := os.ReadDir(filepath.Join(s.dir, "shares"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read shares directory: %w", err)
+	}
+
+	var shares []*SharedConversation
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(s.dir, "shares", entry.Name()))
+		if err != nil {
+			continue
+		}
+
+		var share SharedConversation
+		if err := json.Unmarshal(data, &share); err != nil {
+			continue
+		}
+		if share.OwnerID == ownerID {
+			shares = append(shares, &share)
+		}
+	}
+
+	sort.Slice(shares, func(i, j int) bool {
+		return shares[i].CreatedAt.After(shares[j].CreatedAt)
+	})
+
+	return shares, nil
+}
+
+// DeleteShare deletes a share by share ID
+func (s *FileStorage) DeleteShare(ctx context.Context, shareID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.Remove(s.sharePath(shareID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete share file: %w", err)
+	}
+
+	return nil
+}
+
 // Close closes the storage (no-op for file storage)
 func (s *FileStorage) Close() error {
 	return nil
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"groq-go/internal/client"
+)
+
+// SessionMatch is a session found by SearchSessions
+type SessionMatch struct {
+	SessionMeta
+	Snippet string `json:"snippet"` // Text around the first match, with the terms in **bold**
+}
+
+// DefaultSearchLimit is the number of matches returned when no limit is given
+const DefaultSearchLimit = 20
+
+// Match tiers: sessions that match in the title or in user and assistant
+// text rank above those that only match with the help of tool results
+const (
+	tierTool = iota + 1
+	tierChat
+)
+
+// snippetRadius is roughly the characters kept before a snippet's match;
+// twice as many are kept after it
+const snippetRadius = 60
+
+// searchTerms splits a query into lowercase words, all of which must match
+func searchTerms(query string) []string {
+	return strings.Fields(strings.ToLower(query))
+}
+
+// sessionText splits the text of msgs into what the user and assistant
+// wrote and what tools returned
+func sessionText(msgs []client.Message) (chat, tools string) {
+	var c, t strings.Builder
+	for _, msg := range msgs {
+		var b *strings.Builder
+		switch msg.Role {
+		case "user", "assistant":
+			b = &c
+		case "tool":
+			b = &t
+		default:
+			continue
+		}
+		if text := msg.Text(); text != "" {
+			b.WriteString(text)
+			b.WriteString("\n")
+		}
+	}
+	return c.String(), t.String()
+}
+
+// scoredMatch is a match with what it is ranked by
+type scoredMatch struct {
+	*SessionMatch
+	tier int
+	hits int // Term occurrences in the title and chat text
+}
+
+// scoreSession matches session against terms. ok is false unless every
+// term is found in the title, the chat text or the tool results.
+func scoreSession(session *Session, terms []string) (m scoredMatch, ok bool) {
+	chat, tools := sessionText(session.Messages)
+	title, chatLower, toolsLower := strings.ToLower(session.Title), strings.ToLower(chat), strings.ToLower(tools)
+
+	m.tier = tierChat
+	for _, term := range terms {
+		n := strings.Count(title, term) + strings.Count(chatLower, term)
+		switch {
+		case n > 0:
+			m.hits += n
+		case strings.Contains(toolsLower, term):
+			m.tier = tierTool
+		default:
+			return m, false
+		}
+	}
+
+	m.SessionMatch = &SessionMatch{SessionMeta: SessionMeta{
+		ID:        session.ID,
+		Title:     session.Title,
+		CreatedAt: session.CreatedAt,
+		UpdatedAt: session.UpdatedAt,
+		OwnerID:   session.OwnerID,
+	}}
+	for _, text := range []string{chat, tools, session.Title} {
+		if m.Snippet = snippet(text, terms); m.Snippet != "" {
+			break
+		}
+	}
+	return m, true
+}
+
+// rankMatches sorts matches best first and keeps the first limit
+func rankMatches(matches []scoredMatch, limit int) []*SessionMatch {
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.tier != b.tier {
+			return a.tier > b.tier
+		}
+		if a.hits != b.hits {
+			return a.hits > b.hits
+		}
+		return a.UpdatedAt.After(b.UpdatedAt)
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	out := make([]*SessionMatch, len(matches))
+	for i, m := range matches {
+		out[i] = m.SessionMatch
+	}
+	return out
+}
+
+// snippet returns the text around the first term found in text, on one
+// line with the terms marked, or "" if none is found
+func snippet(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		text = lower // Offsets must agree
+	}
+	at := -1
+	for _, term := range terms {
+		if i := strings.Index(lower, term); i >= 0 && (at < 0 || i < at) {
+			at = i
+		}
+	}
+	if at < 0 {
+		return ""
+	}
+
+	from, to := max(at-snippetRadius, 0), min(at+2*snippetRadius, len(text))
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+	s := highlight(strings.Join(strings.Fields(text[from:to]), " "), terms)
+	if from > 0 {
+		s = "…" + s
+	}
+	if to < len(text) {
+		s += "…"
+	}
+	return s
+}
+
+// highlight wraps each occurrence of the terms in text in **
+func highlight(text string, terms []string) string {
+	lower := strings.ToLower(text)
+	if len(lower) != len(text) {
+		text = lower
+	}
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		n := 0
+		for _, term := range terms {
+			if len(term) > n && strings.HasPrefix(lower[i:], term) {
+				n = len(term)
+			}
+		}
+		if n == 0 {
+			b.WriteByte(text[i])
+			i++
+			continue
+		}
+		b.WriteString("**" + text[i:i+n] + "**")
+		i += n
+	}
+	return b.String()
+}
+
+// searchChunk is how much of a session file is read at a time while
+// looking for a query's terms
+const searchChunk = 64 << 10
+
+// rawTerms returns terms as they appear in JSON, so session files can be
+// checked before they are decoded
+func rawTerms(terms []string) [][]byte {
+	raw := make([][]byte, len(terms))
+	for i, term := range terms {
+		data, _ := json.Marshal(term)
+		raw[i] = bytes.ToLower(data[1 : len(data)-1])
+	}
+	return raw
+}
+
+// containsAll reports whether r holds every one of terms, ignoring case.
+// It reads a chunk at a time and stops as soon as the last term is found,
+// so sessions that cannot match are never held whole.
+func containsAll(r io.Reader, terms [][]byte) (bool, error) {
+	left := make([][]byte, len(terms))
+	copy(left, terms)
+	overlap := 0
+	for _, term := range terms {
+		overlap = max(overlap, len(term)-1)
+	}
+
+	buf := make([]byte, 0, searchChunk+overlap)
+	chunk := make([]byte, searchChunk)
+	for len(left) > 0 {
+		n, err := r.Read(chunk)
+		buf = append(buf, chunk[:n]...)
+		lower := bytes.ToLower(buf)
+		for i := 0; i < len(left); {
+			if bytes.Contains(lower, left[i]) {
+				left = append(left[:i], left[i+1:]...)
+				continue
+			}
+			i++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return false, err
+		}
+		// Keep enough of the end to find a term split across chunks
+		if keep := min(overlap, len(buf)); keep < len(buf) {
+			buf = append(buf[:0], buf[len(buf)-keep:]...)
+		}
+	}
+	return len(left) == 0, nil
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+// saveSearchFixtures stores sessions that match "websocket bug" in
+// different places, and some that do not
+func saveSearchFixtures(t *testing.T, s Storage) {
+	t.Helper()
+	fixtures := []*Session{
+		{ID: "tool", Messages: []client.Message{
+			client.NewTextMessage("user", "list the files"),
+			{Role: "tool", Content: "websocket_handler.go\nbug-report.txt"},
+			client.NewTextMessage("assistant", "There are two files."),
+		}},
+		{ID: "chat", Messages: []client.Message{
+			client.NewTextMessage("user", "The websocket drops after a minute"),
+			client.NewTextMessage("assistant", "Fixed the websocket bug in the keepalive loop."),
+		}},
+		{ID: "title", Title: "Websocket bug notes", OwnerID: "alice", Messages: []client.Message{
+			client.NewTextMessage("user", "remember this for later"),
+		}},
+		{ID: "partial", Messages: []client.Message{
+			client.NewTextMessage("user", "websocket timeout"),
+		}},
+		{ID: "other", Messages: []client.Message{
+			client.NewTextMessage("user", "hello world"),
+		}},
+	}
+	for _, session := range fixtures {
+		if err := s.SaveSession(context.Background(), session); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func testSearchSessions(t *testing.T, s Storage) {
+	saveSearchFixtures(t, s)
+	ctx := context.Background()
+
+	matches, err := s.SearchSessions(ctx, "WebSocket bug", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range matches {
+		ids = append(ids, m.ID)
+	}
+	if len(ids) != 3 || ids[2] != "tool" || !strings.Contains(strings.Join(ids[:2], " "), "chat") {
+		t.Fatalf("Expected chat and title matches before the tool result match, got %v", ids)
+	}
+	for _, m := range matches {
+		switch m.ID {
+		case "chat":
+			if !strings.Contains(m.Snippet, "**websocket**") || !strings.Contains(m.Snippet, "**bug**") {
+				t.Errorf("Expected the terms marked in the snippet, got %q", m.Snippet)
+			}
+		case "tool":
+			if !strings.Contains(m.Snippet, "**websocket**") {
+				t.Errorf("Expected a snippet of the tool result, got %q", m.Snippet)
+			}
+		}
+	}
+
+	if matches, _ := s.SearchSessions(ctx, "websocket bug", "", 1); len(matches) != 1 || matches[0].ID == "tool" {
+		t.Errorf("Expected the limit to keep the best match, got %v", matches)
+	}
+	if matches, _ := s.SearchSessions(ctx, "websocket bug", "alice", 0); len(matches) != 1 || matches[0].ID != "title" || matches[0].OwnerID != "alice" {
+		t.Errorf("Expected only the session of alice, got %v", matches)
+	}
+	if matches, _ := s.SearchSessions(ctx, "  ", "", 0); len(matches) != 0 {
+		t.Errorf("Expected no matches for an empty query, got %v", matches)
+	}
+	if matches, _ := s.SearchSessions(ctx, "nothing-like-this", "", 0); len(matches) != 0 {
+		t.Errorf("Expected no matches, got %v", matches)
+	}
+
+	if err := s.DeleteSession(ctx, "chat"); err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range mustSearch(t, s, "websocket bug") {
+		if m.ID == "chat" {
+			t.Error("Expected a deleted session not to be found")
+		}
+	}
+}
+
+func mustSearch(t *testing.T, s Storage, query string) []*SessionMatch {
+	t.Helper()
+	matches, err := s.SearchSessions(context.Background(), query, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestFileSearchSessions(t *testing.T) {
+	s, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSearchSessions(t, s)
+}
+
+func TestSQLiteSearchSessions(t *testing.T) {
+	testSearchSessions(t, newTestSQLite(t))
+}
+
+func TestSQLiteIndexesExistingSessions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), SQLiteFile)
+	s, err := NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveSearchFixtures(t, s)
+	// Back to the schema before search, as an older version left it
+	if _, err := s.db.Exec(`DROP TABLE sessions_fts; DROP INDEX sessions_owner_id;
+		ALTER TABLE sessions DROP COLUMN owner_id; PRAGMA user_version = 2`); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s, err = NewSQLiteStorage(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if matches := mustSearch(t, s, "websocket bug"); len(matches) != 3 {
+		t.Errorf("Expected the migration to index saved sessions, got %d matches", len(matches))
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	text := strings.Repeat("filler ", 40) + "the Bug is here\nand\tthere" + strings.Repeat(" more", 60)
+	got := snippet(text, []string{"bug"})
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") {
+		t.Errorf("Expected a cut snippet, got %q", got)
+	}
+	if !strings.Contains(got, "the **Bug** is here and there") {
+		t.Errorf("Expected the match marked on one line, got %q", got)
+	}
+	if got := snippet("short text", []string{"missing"}); got != "" {
+		t.Errorf("Expected no snippet without a match, got %q", got)
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestContainsAllStopsEarly(t *testing.T) {
+	huge := strings.Repeat("x", 10<<20)
+	terms := rawTerms([]string{"websocket", "bug"})
+
+	r := &countingReader{r: strings.NewReader(`{"title":"WebSocket bug"}` + huge)}
+	if ok, err := containsAll(r, terms); !ok || err != nil {
+		t.Fatalf("Expected a match, got %v, %v", ok, err)
+	}
+	if r.n > searchChunk {
+		t.Errorf("Expected reading to stop at the first chunk, read %d bytes", r.n)
+	}
+
+	// A term split across chunks is still found
+	split := strings.Repeat("x", searchChunk-4) + "websocket bug"
+	if ok, _ := containsAll(strings.NewReader(split), terms); !ok {
+		t.Error("Expected a term across a chunk boundary to be found")
+	}
+	if ok, _ := containsAll(strings.NewReader(huge+"websocket"), terms); ok {
+		t.Error("Expected no match without every term")
+	}
+}
@@ -0,0 +1,474 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	_ "modernc.org/sqlite"
+)
+
+// SQLiteFile is the database file name inside the storage directory
+const SQLiteFile = "sessions.db"
+
+// migrations are applied in order; PRAGMA user_version records how many ran
+var migrations = []string{
+	`CREATE TABLE sessions (
+		id         TEXT PRIMARY KEY,
+		title      TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL,
+		data       BLOB NOT NULL
+	);
+	CREATE INDEX sessions_updated_at ON sessions (updated_at DESC);
+	CREATE TABLE shares (
+		share_id   TEXT PRIMARY KEY,
+		session_id TEXT NOT NULL DEFAULT '',
+		created_at INTEGER NOT NULL,
+		view_count INTEGER NOT NULL DEFAULT 0,
+		data       BLOB NOT NULL
+	);`,
+	`ALTER TABLE shares ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX shares_owner_id ON shares (owner_id, created_at DESC);`,
+	// Full-text index for SearchSessions; rows share their session's rowid
+	`CREATE VIRTUAL TABLE sessions_fts USING fts5(title, chat, tools);`,
+	`ALTER TABLE sessions ADD COLUMN owner_id TEXT NOT NULL DEFAULT '';
+	CREATE INDEX sessions_owner_id ON sessions (owner_id, updated_at DESC);`,
+}
+
+// searchMigration is the schema version that added sessions_fts; sessions
+// saved before it are indexed when it is applied
+const searchMigration = 3
+
+// SQLiteStorage implements Storage using a SQLite database. Session and
+// share bodies are stored as JSON blobs next to the columns used for listing.
+type SQLiteStorage struct {
+	db *sql.DB
+}
+
+// NewSQLiteStorage opens the database at path and migrates its schema
+func NewSQLiteStorage(path string) (*SQLiteStorage, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	// SQLite allows one writer at a time; a single connection serializes
+	// writes in-process instead of failing with SQLITE_BUSY
+	db.SetMaxOpenConns(1)
+
+	s := &SQLiteStorage{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// migrate applies any migrations newer than the database's user_version
+func (s *SQLiteStorage) migrate() error {
+	var version int
+	if err := s.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > len(migrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		tx, err := s.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf("PRAGMA user_version = %d", i+1)); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to apply migration %d: %w", i+1, err)
+		}
+	}
+	if version < searchMigration {
+		return s.indexSessions(context.Background())
+	}
+	return nil
+}
+
+// SaveSession saves or updates a session
+func (s *SQLiteStorage) SaveSession(ctx context.Context, session *Session) error {
+	touchSession(session)
+	return s.putSession(ctx, session)
+}
+
+// putSession writes a session without touching its timestamps
+func (s *SQLiteStorage) putSession(ctx context.Context, session *Session) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session: %w", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	defer tx.Rollback()
+
+	var rowid int64
+	err = tx.QueryRowContext(ctx, `INSERT INTO sessions (id, title, created_at, updated_at, owner_id, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE SET
+			title = excluded.title,
+			created_at = excluded.created_at,
+			updated_at = excluded.updated_at,
+			owner_id = excluded.owner_id,
+			data = excluded.data
+		RETURNING rowid`,
+		session.ID, session.Title, session.CreatedAt.UnixNano(), session.UpdatedAt.UnixNano(), session.OwnerID, data).Scan(&rowid)
+	if err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := indexSession(ctx, tx, rowid, session); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+	return nil
+}
+
+// indexSession replaces the search index row of the session at rowid
+func indexSession(ctx context.Context, tx *sql.Tx, rowid int64, session *Session) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions_fts WHERE rowid = ?", rowid); err != nil {
+		return err
+	}
+	chat, tools := sessionText(session.Messages)
+	_, err := tx.ExecContext(ctx, "INSERT INTO sessions_fts (rowid, title, chat, tools) VALUES (?, ?, ?, ?)",
+		rowid, session.Title, chat, tools)
+	return err
+}
+
+// indexSessions adds the sessions saved before searchMigration to the
+// search index, a page at a time as the only connection cannot write
+// while rows are open. Sessions that do not decode are left out.
+func (s *SQLiteStorage) indexSessions(ctx context.Context) error {
+	const page = 100
+	var after int64
+	for {
+		type row struct {
+			rowid int64
+			data  []byte
+		}
+		var batch []row
+		rows, err := s.db.QueryContext(ctx, "SELECT rowid, data FROM sessions WHERE rowid > ? ORDER BY rowid LIMIT ?", after, page)
+		if err != nil {
+			return fmt.Errorf("failed to index sessions: %w", err)
+		}
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.rowid, &r.data); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to index sessions: %w", err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if len(batch) == 0 {
+			return rows.Err()
+		}
+
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return fmt.Errorf("failed to index sessions: %w", err)
+		}
+		for _, r := range batch {
+			var session Session
+			if json.Unmarshal(r.data, &session) != nil {
+				continue
+			}
+			if err := indexSession(ctx, tx, r.rowid, &session); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to index sessions: %w", err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to index sessions: %w", err)
+		}
+		after = batch[len(batch)-1].rowid
+	}
+}
+
+// LoadSession loads a session by ID
+func (s *SQLiteStorage) LoadSession(ctx context.Context, id string) (*Session, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, "SELECT data FROM sessions WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+
+	var session Session
+	if err := json.Unmarshal(data, &session); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal session: %w", err)
+	}
+	return &session, nil
+}
+
+// ListSessions returns all session metadata without reading message bodies
+func (s *SQLiteStorage) ListSessions(ctx context.Context) ([]*SessionMeta, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, title, created_at, updated_at, owner_id FROM sessions ORDER BY updated_at DESC")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*SessionMeta
+	for rows.Next() {
+		var meta SessionMeta
+		var created, updated int64
+		if err := rows.Scan(&meta.ID, &meta.Title, &created, &updated, &meta.OwnerID); err != nil {
+			return nil, fmt.Errorf("failed to list sessions: %w", err)
+		}
+		meta.CreatedAt = time.Unix(0, created)
+		meta.UpdatedAt = time.Unix(0, updated)
+		sessions = append(sessions, &meta)
+	}
+	return sessions, rows.Err()
+}
+
+// DeleteSession deletes a session by ID
+func (s *SQLiteStorage) DeleteSession(ctx context.Context, id string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions_fts WHERE rowid = (SELECT rowid FROM sessions WHERE id = ?)", id); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sessions WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// SearchSessions queries the full-text index, where each word of query
+// matches words starting with it. Only the listing columns and snippets
+// are read, never the session bodies.
+func (s *SQLiteStorage) SearchSessions(ctx context.Context, query, owner string, limit int) ([]*SessionMatch, error) {
+	terms := searchTerms(query)
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = DefaultSearchLimit
+	}
+	match := ftsQuery(terms)
+	if match == "" {
+		return nil, nil
+	}
+
+	// Matching without the tools column decides the tier, bm25 (lower is
+	// better) orders within it
+	rows, err := s.db.QueryContext(ctx, `WITH chat AS (
+			SELECT rowid FROM sessions_fts WHERE sessions_fts MATCH ?
+		)
+		SELECT s.id, s.title, s.created_at, s.updated_at, s.owner_id,
+			snippet(sessions_fts, 1, '**', '**', '…', 16),
+			snippet(sessions_fts, 2, '**', '**', '…', 16),
+			snippet(sessions_fts, 0, '**', '**', '…', 16)
+		FROM sessions_fts JOIN sessions s ON s.rowid = sessions_fts.rowid
+		WHERE sessions_fts MATCH ? AND (? = '' OR s.owner_id = ?)
+		ORDER BY sessions_fts.rowid IN chat DESC, bm25(sessions_fts, 10.0, 5.0, 1.0), s.updated_at DESC
+		LIMIT ?`, "{title chat} : ("+match+")", match, owner, owner, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var matches []*SessionMatch
+	for rows.Next() {
+		var m SessionMatch
+		var created, updated int64
+		var snippets [3]string
+		if err := rows.Scan(&m.ID, &m.Title, &created, &updated, &m.OwnerID, &snippets[0], &snippets[1], &snippets[2]); err != nil {
+			return nil, fmt.Errorf("failed to search sessions: %w", err)
+		}
+		m.CreatedAt = time.Unix(0, created)
+		m.UpdatedAt = time.Unix(0, updated)
+		for _, snip := range snippets {
+			if strings.Contains(snip, "**") {
+				m.Snippet = strings.Join(strings.Fields(snip), " ")
+				break
+			}
+		}
+		matches = append(matches, &m)
+	}
+	return matches, rows.Err()
+}
+
+// ftsQuery builds an FTS5 query matching every term as a word prefix.
+// Terms without letters or digits hold no words and are left out.
+func ftsQuery(terms []string) string {
+	var quoted []string
+	for _, term := range terms {
+		if strings.IndexFunc(term, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) < 0 {
+			continue
+		}
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"*`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// SaveShare saves a shared conversation
+func (s *SQLiteStorage) SaveShare(ctx context.Context, share *SharedConversation) error {
+	data, err := json.Marshal(share)
+	if err != nil {
+		return fmt.Errorf("failed to marshal share: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `INSERT INTO shares (share_id, session_id, owner_id, created_at, view_count, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (share_id) DO UPDATE SET
+			session_id = excluded.session_id,
+			owner_id = excluded.owner_id,
+			created_at = excluded.created_at,
+			view_count = excluded.view_count,
+			data = excluded.data`,
+		share.ShareID, share.SessionID, share.OwnerID, share.CreatedAt.UnixNano(), share.ViewCount, data)
+	if err != nil {
+		return fmt.Errorf("failed to save share: %w", err)
+	}
+	return nil
+}
+
+// LoadShare loads a shared conversation by share ID
+func (s *SQLiteStorage) LoadShare(ctx context.Context, shareID string) (*SharedConversation, error) {
+	var data []byte
+	var views int
+	err := s.db.QueryRowContext(ctx, "SELECT data, view_count FROM shares WHERE share_id = ?", shareID).Scan(&data, &views)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load share: %w", err)
+	}
+
+	return decodeShare(data, views)
+}
+
+// decodeShare unmarshals a share blob. The view_count column is
+// authoritative; the blob holds the count at save time.
+func decodeShare(data []byte, views int) (*SharedConversation, error) {
+	var share SharedConversation
+	if err := json.Unmarshal(data, &share); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal share: %w", err)
+	}
+	share.ViewCount = views
+	return &share, nil
+}
+
+// IncrementShareViewCount increments the view count for a share
+func (s *SQLiteStorage) IncrementShareViewCount(ctx context.Context, shareID string) error {
+	res, err := s.db.ExecContext(ctx, "UPDATE shares SET view_count = view_count + 1 WHERE share_id = ?", shareID)
+	if err != nil {
+		return fmt.Errorf("failed to update view count: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("share not found: %s", shareID)
+	}
+	return nil
+}
+
+// ListSharesByOwner returns the shares created by an owner, newest first
+func (s *SQLiteStorage) ListSharesByOwner(ctx context.Context, ownerID string) ([]*SharedConversation, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT data, view_count FROM shares WHERE owner_id = ? ORDER BY created_at DESC", ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shares: %w", err)
+	}
+	defer rows.Close()
+
+	var shares []*SharedConversation
+	for rows.Next() {
+		var data []byte
+		var views int
+		if err := rows.Scan(&data, &views); err != nil {
+			return nil, fmt.Errorf("failed to list shares: %w", err)
+		}
+		share, err := decodeShare(data, views)
+		if err != nil {
+			continue
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// DeleteShare deletes a share by share ID
+func (s *SQLiteStorage) DeleteShare(ctx context.Context, shareID string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM shares WHERE share_id = ?", shareID); err != nil {
+		return fmt.Errorf("failed to delete share: %w", err)
+	}
+	return nil
+}
+
+// Close closes the database
+func (s *SQLiteStorage) Close() error {
+	return s.db.Close()
+}
+
+// ImportFiles copies the sessions and shares of a FileStorage into the
+// database, keeping their timestamps. Unreadable files are skipped.
+func (s *SQLiteStorage) ImportFiles(ctx context.Context, fs *FileStorage) (sessions, shares int, err error) {
+	metas, err := fs.ListSessions(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+	for _, meta := range metas {
+		session, err := fs.LoadSession(ctx, meta.ID)
+		if err != nil || session == nil {
+			continue
+		}
+		if err := s.putSession(ctx, session); err != nil {
+			return sessions, shares, err
+		}
+		sessions++
+	}
+
+	entries, err := os.ReadDir(filepath.Join(fs.dir, "shares"))
+	if err != nil && !os.IsNotExist(err) {
+		return sessions, shares, fmt.Errorf("failed to read shares directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		share, err := fs.LoadShare(ctx, entry.Name()[:len(entry.Name())-len(".json")])
+		if err != nil || share == nil {
+			continue
+		}
+		if err := s.SaveShare(ctx, share); err != nil {
+			return sessions, shares, err
+		}
+		shares++
+	}
+	return sessions, shares, nil
+}
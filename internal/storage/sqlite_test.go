@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+func newTestSQLite(t *testing.T) *SQLiteStorage {
+	t.Helper()
+	s, err := NewSQLiteStorage(filepath.Join(t.TempDir(), SQLiteFile))
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSQLiteSessionCRUD(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+
+	session := &Session{ID: "s1", Messages: []client.Message{
+		client.NewTextMessage("user", "How do I configure the proxy?"),
+		client.NewTextMessage("assistant", "Set MAIN_DOMAIN."),
+	}}
+	if err := s.SaveSession(ctx, session); err != nil {
+		t.Fatalf("SaveSession failed: %v", err)
+	}
+	if session.Title != "How do I configure the proxy?" || session.CreatedAt.IsZero() {
+		t.Errorf("Expected title and timestamps to be set, got %+v", session)
+	}
+
+	loaded, err := s.LoadSession(ctx, "s1")
+	if err != nil || loaded == nil {
+		t.Fatalf("LoadSession failed: %v", err)
+	}
+	if len(loaded.Messages) != 2 || loaded.Messages[1].Content != "Set MAIN_DOMAIN." {
+		t.Errorf("Unexpected messages %+v", loaded.Messages)
+	}
+
+	// Updates keep the creation time and move the session to the top
+	created := session.CreatedAt
+	s.SaveSession(ctx, &Session{ID: "s2", Title: "Other"})
+	time.Sleep(time.Millisecond)
+	session.Messages = append(session.Messages, client.NewTextMessage("user", "Thanks"))
+	s.SaveSession(ctx, session)
+
+	metas, err := s.ListSessions(ctx)
+	if err != nil {
+		t.Fatalf("ListSessions failed: %v", err)
+	}
+	if len(metas) != 2 || metas[0].ID != "s1" || !metas[0].CreatedAt.Equal(created) {
+		t.Errorf("Unexpected session list %+v", metas)
+	}
+
+	if err := s.DeleteSession(ctx, "s1"); err != nil {
+		t.Fatalf("DeleteSession failed: %v", err)
+	}
+	if loaded, _ := s.LoadSession(ctx, "s1"); loaded != nil {
+		t.Errorf("Expected deleted session to be gone, got %+v", loaded)
+	}
+}
+
+func TestSQLiteListSessionsSkipsBodies(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+	s.SaveSession(ctx, &Session{ID: "s1", Title: "Kept"})
+
+	// A corrupt body only breaks loading, not listing
+	if _, err := s.db.Exec("UPDATE sessions SET data = 'not json'"); err != nil {
+		t.Fatalf("Exec failed: %v", err)
+	}
+	metas, err := s.ListSessions(ctx)
+	if err != nil || len(metas) != 1 || metas[0].Title != "Kept" {
+		t.Errorf("Expected metadata from columns, got %+v (%v)", metas, err)
+	}
+	if _, err := s.LoadSession(ctx, "s1"); err == nil {
+		t.Error("Expected LoadSession to fail on a corrupt body")
+	}
+}
+
+func TestSQLiteConcurrentSaves(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 50)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Half the writers share an ID to exercise upserts
+			id := fmt.Sprintf("s%d", i%25)
+			errs <- s.SaveSession(ctx, &Session{ID: id, Messages: []client.Message{client.NewTextMessage("user", id)}})
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("SaveSession failed: %v", err)
+		}
+	}
+
+	metas, err := s.ListSessions(ctx)
+	if err != nil || len(metas) != 25 {
+		t.Errorf("Expected 25 sessions, got %d (%v)", len(metas), err)
+	}
+}
+
+func TestSQLiteShares(t *testing.T) {
+	s := newTestSQLite(t)
+	ctx := context.Background()
+
+	share := &SharedConversation{ShareID: "abc", SessionID: "s1", Title: "Shared", CreatedAt: time.Now()}
+	if err := s.SaveShare(ctx, share); err != nil {
+		t.Fatalf("SaveShare failed: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.IncrementShareViewCount(ctx, "abc"); err != nil {
+			t.Fatalf("IncrementShareViewCount failed: %v", err)
+		}
+	}
+	loaded, err := s.LoadShare(ctx, "abc")
+	if err != nil || loaded == nil || loaded.ViewCount != 3 || loaded.Title != "Shared" {
+		t.Errorf("Unexpected share %+v (%v)", loaded, err)
+	}
+
+	if err := s.IncrementShareViewCount(ctx, "missing"); err == nil {
+		t.Error("Expected an error for a missing share")
+	}
+	if loaded, err := s.LoadShare(ctx, "missing"); loaded != nil || err != nil {
+		t.Errorf("Expected nil for a missing share, got %+v (%v)", loaded, err)
+	}
+
+	s.SaveShare(ctx, &SharedConversation{ShareID: "mine1", OwnerID: "alice", CreatedAt: time.Now()})
+	s.SaveShare(ctx, &SharedConversation{ShareID: "mine2", OwnerID: "alice", CreatedAt: time.Now().Add(time.Second)})
+	s.SaveShare(ctx, &SharedConversation{ShareID: "theirs", OwnerID: "bob", CreatedAt: time.Now()})
+	owned, err := s.ListSharesByOwner(ctx, "alice")
+	if err != nil || len(owned) != 2 || owned[0].ShareID != "mine2" {
+		t.Errorf("Expected alice's shares newest first, got %+v (%v)", owned, err)
+	}
+	if err := s.DeleteShare(ctx, "mine1"); err != nil {
+		t.Fatalf("DeleteShare failed: %v", err)
+	}
+	if loaded, _ := s.LoadShare(ctx, "mine1"); loaded != nil {
+		t.Errorf("Expected deleted share to be gone, got %+v", loaded)
+	}
+}
+
+func TestOpenSQLiteImportsJSONSessions(t *testing.T) {
+	dir := t.TempDir()
+	ctx := context.Background()
+
+	files, err := NewFileStorage(dir)
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	files.SaveSession(ctx, &Session{ID: "old", Messages: []client.Message{client.NewTextMessage("user", "from JSON")}})
+	files.SaveShare(ctx, &SharedConversation{ShareID: "sh1", SessionID: "old", ViewCount: 7})
+	saved, _ := files.LoadSession(ctx, "old")
+
+	store, err := Open(ctx, BackendSQLite, dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer store.Close()
+
+	loaded, err := store.LoadSession(ctx, "old")
+	if err != nil || loaded == nil || loaded.Title != "from JSON" {
+		t.Fatalf("Expected the JSON session to be imported, got %+v (%v)", loaded, err)
+	}
+	if !loaded.UpdatedAt.Equal(saved.UpdatedAt) {
+		t.Errorf("Expected the import to keep timestamps, got %v want %v", loaded.UpdatedAt, saved.UpdatedAt)
+	}
+	if share, _ := store.LoadShare(ctx, "sh1"); share == nil || share.ViewCount != 7 {
+		t.Errorf("Expected the share to be imported, got %+v", share)
+	}
+
+	if _, err := Open(ctx, "postgres", dir); err == nil {
+		t.Error("Expected an error for an unknown backend")
+	}
+}
@@ -2,11 +2,18 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 
 	"groq-go/internal/client"
+	"groq-go/internal/logging"
+	"groq-go/internal/tool"
 )
 
+var log = logging.WithComponent("storage")
+
 // Session represents a conversation session
 type Session struct {
 	ID        string           `json:"id"`
@@ -15,6 +22,10 @@ type Session struct {
 	Files     []FileEntry      `json:"files,omitempty"`
 	CreatedAt time.Time        `json:"created_at"`
 	UpdatedAt time.Time        `json:"updated_at"`
+	OwnerID   string           `json:"owner_id,omitempty"` // User the web server saved it for; empty for the REPL
+
+	// ApprovalRules are the session's auto-approve rules, see tool.WithApproval
+	ApprovalRules []tool.ApprovalRule `json:"approval_rules,omitempty"`
 }
 
 // FileEntry represents a file in a session
@@ -30,6 +41,7 @@ type SessionMeta struct {
 	Title     string    `json:"title"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	OwnerID   string    `json:"owner_id,omitempty"`
 }
 
 // SharedConversation represents a shared conversation link
@@ -41,6 +53,8 @@ type SharedConversation struct {
 	CreatedAt time.Time        `json:"created_at"`
 	ExpiresAt time.Time        `json:"expires_at,omitempty"`
 	ViewCount int              `json:"view_count"`
+	OwnerID   string           `json:"owner_id,omitempty"` // account or IP-derived user ID
+	Password  string           `json:"password,omitempty"` // bcrypt hash; empty means public
 }
 
 // Storage defines the interface for session storage
@@ -57,6 +71,12 @@ type Storage interface {
 	// DeleteSession deletes a session by ID
 	DeleteSession(ctx context.Context, id string) error
 
+	// SearchSessions returns up to limit sessions whose title or messages
+	// contain every word of query, best first. Matches in the title or in
+	// user and assistant text rank above matches in tool results. A
+	// non-empty owner searches only the sessions of that owner.
+	SearchSessions(ctx context.Context, query, owner string, limit int) ([]*SessionMatch, error)
+
 	// SaveShare saves a shared conversation
 	SaveShare(ctx context.Context, share *SharedConversation) error
 
@@ -66,6 +86,77 @@ type Storage interface {
 	// IncrementShareViewCount increments the view count for a share
 	IncrementShareViewCount(ctx context.Context, shareID string) error
 
+	// ListSharesByOwner returns the shares created by an owner, newest first
+	ListSharesByOwner(ctx context.Context, ownerID string) ([]*SharedConversation, error)
+
+	// DeleteShare deletes a share by share ID
+	DeleteShare(ctx context.Context, shareID string) error
+
 	// Close closes the storage
 	Close() error
 }
+
+// touchSession sets the session timestamps and derives a title from the
+// first user message if none is set
+func touchSession(session *Session) {
+	now := time.Now()
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = now
+	}
+	session.UpdatedAt = now
+
+	if session.Title == "" && len(session.Messages) > 0 {
+		for _, msg := range session.Messages {
+			if msg.Role == "user" {
+				// Content can be string or []ContentPart
+				if title, ok := msg.Content.(string); ok {
+					if len(title) > 50 {
+						title = title[:50] + "..."
+					}
+					session.Title = title
+				}
+				break
+			}
+		}
+	}
+}
+
+// Storage backends selectable with STORAGE_BACKEND
+const (
+	BackendFile   = "file"
+	BackendSQLite = "sqlite"
+)
+
+// Open returns the storage backend for dir. When a SQLite database is
+// created for the first time, existing JSON sessions in dir are imported.
+func Open(ctx context.Context, backend, dir string) (Storage, error) {
+	switch backend {
+	case "", BackendFile:
+		return NewFileStorage(dir)
+	case BackendSQLite:
+		path := filepath.Join(dir, SQLiteFile)
+		_, statErr := os.Stat(path)
+		store, err := NewSQLiteStorage(path)
+		if err != nil {
+			return nil, err
+		}
+		if os.IsNotExist(statErr) {
+			files, err := NewFileStorage(dir)
+			if err != nil {
+				store.Close()
+				return nil, err
+			}
+			sessions, shares, err := store.ImportFiles(ctx, files)
+			if err != nil {
+				store.Close()
+				os.Remove(path) // retry the import on the next start
+				return nil, fmt.Errorf("failed to import JSON sessions: %w", err)
+			}
+			if sessions > 0 || shares > 0 {
+				log.Info("Imported JSON sessions into SQLite", "sessions", sessions, "shares", shares)
+			}
+		}
+		return store, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend: %s", backend)
+}
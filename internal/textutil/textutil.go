@@ -0,0 +1,21 @@
+// Package textutil cuts text by byte length without splitting a UTF-8
+// sequence, so truncated model and tool output stays valid text.
+package textutil
+
+import "unicode/utf8"
+
+// RuneStart moves i back to the start of the rune it falls in
+func RuneStart(s string, i int) int {
+	for i > 0 && i < len(s) && !utf8.RuneStart(s[i]) {
+		i--
+	}
+	return i
+}
+
+// TruncateBytes cuts s to at most n bytes without splitting a rune
+func TruncateBytes(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:RuneStart(s, n)]
+}
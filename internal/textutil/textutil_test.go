@@ -0,0 +1,29 @@
+package textutil
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateBytes(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"日本語", 4, "日"},
+		{"日本語", 6, "日本"},
+		{"日本語", 2, ""},
+		{"a😀", 4, "a"},
+	} {
+		got := TruncateBytes(tc.s, tc.n)
+		if got != tc.want || !utf8.ValidString(got) {
+			t.Errorf("TruncateBytes(%q, %d) = %q, want %q", tc.s, tc.n, got, tc.want)
+		}
+	}
+	if i := RuneStart("日本語", 7); i != 6 {
+		t.Errorf("Expected RuneStart to back up to 6, got %d", i)
+	}
+}
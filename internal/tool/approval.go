@@ -0,0 +1,283 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/ids"
+)
+
+// ApprovalTimeout bounds how long a call waits for the user's approval; a
+// call not approved in time is denied
+var ApprovalTimeout = 2 * time.Minute
+
+// ApprovalDefaults are the tools whose every call needs approval once the
+// gate is on, see Registry.RequireApproval
+var ApprovalDefaults = []string{"Bash", "CodeExec", "SelfImprove", "Version"}
+
+// Approvable is implemented by tools that decide which of their calls need
+// the user's approval, such as Git for push
+type Approvable interface {
+	NeedsApproval(ctx context.Context, args json.RawMessage) bool
+}
+
+// ApprovalSubjecter is implemented by tools whose auto-approve patterns
+// match one argument, such as Bash's command, instead of the arguments JSON.
+// No pattern matches an empty subject, so a tool returns "" for calls a
+// pattern cannot vouch for, such as chained shell commands.
+type ApprovalSubjecter interface {
+	ApprovalSubject(args json.RawMessage) string
+}
+
+// ApprovalRequest is a tool call waiting for the user's approval
+type ApprovalRequest struct {
+	ID      string `json:"id"`
+	Tool    string `json:"tool"`
+	Args    string `json:"args"`
+	Subject string `json:"subject"` // What auto-approve patterns are matched against
+}
+
+// Decision is the user's answer to an ApprovalRequest
+type Decision int
+
+const (
+	Deny Decision = iota
+	Approve
+	// ApproveAlways approves the call and every later call to the tool in
+	// the session
+	ApproveAlways
+)
+
+// ApproveFunc presents a call to the user and blocks until it is decided
+// or ctx ends
+type ApproveFunc func(ctx context.Context, req ApprovalRequest) (Decision, error)
+
+// ApprovalRule approves calls to Tool without asking when Pattern, a
+// regular expression, matches the call's subject. An empty pattern
+// approves every call to the tool.
+type ApprovalRule struct {
+	Tool    string `json:"tool"`
+	Pattern string `json:"pattern,omitempty"`
+}
+
+// ParseApprovalRule parses "Tool [pattern]", e.g. "Bash ^go test"
+func ParseApprovalRule(s string) (ApprovalRule, error) {
+	name, pattern, _ := strings.Cut(strings.TrimSpace(s), " ")
+	rule := ApprovalRule{Tool: name, Pattern: strings.TrimSpace(pattern)}
+	if _, err := rule.compile(); err != nil {
+		return ApprovalRule{}, err
+	}
+	return rule, nil
+}
+
+func (r ApprovalRule) String() string {
+	if r.Pattern == "" {
+		return r.Tool
+	}
+	return r.Tool + " " + r.Pattern
+}
+
+func (r ApprovalRule) compile() (*regexp.Regexp, error) {
+	if r.Tool == "" {
+		return nil, errors.New("approval rule needs a tool name")
+	}
+	if r.Pattern == "" {
+		return nil, nil
+	}
+	re, err := regexp.Compile(r.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern for %s: %w", r.Tool, err)
+	}
+	return re, nil
+}
+
+// ApprovalRules are the auto-approve rules of one session. A nil
+// *ApprovalRules has no rules and ignores changes.
+type ApprovalRules struct {
+	mu       sync.RWMutex
+	rules    []ApprovalRule
+	patterns []*regexp.Regexp // Compiled Pattern of each rule, nil when empty
+}
+
+// NewApprovalRules returns a rule set holding the valid rules of rules,
+// as loaded from a stored session
+func NewApprovalRules(rules []ApprovalRule) *ApprovalRules {
+	r := &ApprovalRules{}
+	for _, rule := range rules {
+		r.Add(rule)
+	}
+	return r
+}
+
+// Add appends rule, unless it is invalid or already present
+func (r *ApprovalRules) Add(rule ApprovalRule) error {
+	if r == nil {
+		return nil
+	}
+	re, err := rule.compile()
+	if err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.rules {
+		if existing == rule {
+			return nil
+		}
+	}
+	r.rules = append(r.rules, rule)
+	r.patterns = append(r.patterns, re)
+	return nil
+}
+
+// Set replaces the rules. Nothing changes if any rule is invalid.
+func (r *ApprovalRules) Set(rules []ApprovalRule) error {
+	if r == nil {
+		return nil
+	}
+	patterns := make([]*regexp.Regexp, len(rules))
+	for i, rule := range rules {
+		re, err := rule.compile()
+		if err != nil {
+			return err
+		}
+		patterns[i] = re
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.rules = append([]ApprovalRule(nil), rules...)
+	r.patterns = patterns
+	return nil
+}
+
+// Remove deletes the i-th rule of List and reports whether it existed
+func (r *ApprovalRules) Remove(i int) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i < 0 || i >= len(r.rules) {
+		return false
+	}
+	r.rules = append(r.rules[:i], r.rules[i+1:]...)
+	r.patterns = append(r.patterns[:i], r.patterns[i+1:]...)
+	return true
+}
+
+// List returns a copy of the rules
+func (r *ApprovalRules) List() []ApprovalRule {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]ApprovalRule(nil), r.rules...)
+}
+
+// Allows reports whether a rule approves a call to name with subject
+func (r *ApprovalRules) Allows(name, subject string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i, rule := range r.rules {
+		if rule.Tool == name && (r.patterns[i] == nil || subject != "" && r.patterns[i].MatchString(subject)) {
+			return true
+		}
+	}
+	return false
+}
+
+type approvalKey struct{}
+
+type approval struct {
+	fn    ApproveFunc
+	rules *ApprovalRules
+	mu    sync.Mutex // One request at a time, parallel calls wait their turn
+}
+
+// WithApproval returns a context whose tool calls that need approval are
+// checked against rules and otherwise put to the user with fn. Without
+// it, such calls are denied.
+func WithApproval(ctx context.Context, fn ApproveFunc, rules *ApprovalRules) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, approvalKey{}, &approval{fn: fn, rules: rules})
+}
+
+// RequireApproval turns the approval gate on. Calls to the tools listed,
+// to ApprovalDefaults, and calls an Approvable tool reports then need the
+// user's approval, see WithApproval.
+func (r *Registry) RequireApproval(tools []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approval = append(append([]string{}, ApprovalDefaults...), tools...)
+}
+
+// needsApproval reports whether the gate holds a call to t
+func (r *Registry) needsApproval(ctx context.Context, t Tool, args json.RawMessage) bool {
+	r.mu.RLock()
+	required := r.approval
+	r.mu.RUnlock()
+	if required == nil {
+		return false
+	}
+	if containsTool(required, t.Name()) {
+		return true
+	}
+	a, ok := t.(Approvable)
+	return ok && a.NeedsApproval(ctx, args)
+}
+
+// approveCall asks for approval of a call to t. It returns the result to
+// send the model instead of running the call, or ok if the call may run.
+func approveCall(ctx context.Context, t Tool, args string) (denied Result, ok bool) {
+	name := t.Name()
+	subject := args
+	if s, isSubjecter := t.(ApprovalSubjecter); isSubjecter {
+		subject = s.ApprovalSubject(json.RawMessage(args))
+	}
+
+	a, _ := ctx.Value(approvalKey{}).(*approval)
+	if a == nil {
+		return NewErrorResult(fmt.Sprintf("%s needs the user's approval, but no interactive user is available to give it; the call was not run", name)), false
+	}
+	if a.rules.Allows(name, subject) {
+		return Result{}, true
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.rules.Allows(name, subject) { // Approved for always while waiting
+		return Result{}, true
+	}
+	askCtx, cancel := context.WithTimeout(ctx, ApprovalTimeout)
+	defer cancel()
+	decision, err := a.fn(askCtx, ApprovalRequest{
+		ID:      ids.Prefixed("approval-", 12),
+		Tool:    name,
+		Args:    args,
+		Subject: subject,
+	})
+	switch {
+	case err != nil && errors.Is(askCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil:
+		return NewErrorResult(fmt.Sprintf("The user did not approve this %s call within %s, so it was not run.", name, ApprovalTimeout)), false
+	case err != nil:
+		return NewErrorResult(fmt.Sprintf("This %s call was not approved: %v", name, err)), false
+	case decision == ApproveAlways:
+		a.rules.Add(ApprovalRule{Tool: name})
+		return Result{}, true
+	case decision == Approve:
+		return Result{}, true
+	}
+	return NewErrorResult(fmt.Sprintf("The user declined this %s call, so it was not run. Do not retry it unchanged; ask the user or take another approach.", name)), false
+}
@@ -0,0 +1,134 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedApprover answers approval requests with decisions in order and
+// records what it was asked. With no decisions left it waits for ctx.
+type scriptedApprover struct {
+	decisions []Decision
+	asked     []ApprovalRequest
+}
+
+func (s *scriptedApprover) approve(ctx context.Context, req ApprovalRequest) (Decision, error) {
+	s.asked = append(s.asked, req)
+	if len(s.decisions) == 0 {
+		<-ctx.Done()
+		return Deny, ctx.Err()
+	}
+	d := s.decisions[0]
+	s.decisions = s.decisions[1:]
+	return d, nil
+}
+
+func newApprovalExecutor() *Executor {
+	r := newPolicyRegistry(nil)
+	r.RequireApproval([]string{"Git"})
+	return NewExecutor(r)
+}
+
+func TestApprovalApproveAndDeny(t *testing.T) {
+	e := newApprovalExecutor()
+	approver := &scriptedApprover{decisions: []Decision{Approve, Deny}}
+	ctx := WithApproval(context.Background(), approver.approve, NewApprovalRules(nil))
+
+	if result, _ := e.ExecuteToolCall(ctx, calls("Bash", "ls")[0]); result.Content != "Bash:ls" {
+		t.Errorf("Expected the approved call to run, got %+v", result)
+	}
+	result, _ := e.ExecuteToolCall(ctx, calls("Git", "push")[0])
+	if !result.IsError || !strings.Contains(result.Content, "declined") {
+		t.Errorf("Expected a declined result, got %+v", result)
+	}
+	if result, _ := e.ExecuteToolCall(ctx, calls("Read", "a")[0]); result.Content != "Read:a" {
+		t.Errorf("Expected Read to run without asking, got %+v", result)
+	}
+
+	if len(approver.asked) != 2 {
+		t.Fatalf("Expected 2 requests, got %+v", approver.asked)
+	}
+	req := approver.asked[0]
+	if req.Tool != "Bash" || req.Args != `"ls"` || !strings.HasPrefix(req.ID, "approval-") {
+		t.Errorf("Unexpected request %+v", req)
+	}
+}
+
+func TestApprovalTimeout(t *testing.T) {
+	defer func(d time.Duration) { ApprovalTimeout = d }(ApprovalTimeout)
+	ApprovalTimeout = 20 * time.Millisecond
+
+	e := newApprovalExecutor()
+	ctx := WithApproval(context.Background(), (&scriptedApprover{}).approve, nil)
+	result, _ := e.ExecuteToolCall(ctx, calls("Bash", "ls")[0])
+	if !result.IsError || !strings.Contains(result.Content, "did not approve") {
+		t.Errorf("Expected a timeout result, got %+v", result)
+	}
+}
+
+func TestApprovalAlwaysAndRules(t *testing.T) {
+	e := newApprovalExecutor()
+	rules := NewApprovalRules([]ApprovalRule{{Tool: "Bash", Pattern: `^"go test`}, {Tool: "Bash", Pattern: "("}})
+	if got := rules.List(); len(got) != 1 {
+		t.Fatalf("Expected the invalid rule to be dropped, got %v", got)
+	}
+	approver := &scriptedApprover{decisions: []Decision{ApproveAlways}}
+	ctx := WithApproval(context.Background(), approver.approve, rules)
+
+	if result, _ := e.ExecuteToolCall(ctx, calls("Bash", "go test ./...")[0]); result.IsError {
+		t.Errorf("Expected the rule to approve the call, got %+v", result)
+	}
+	if len(approver.asked) != 0 {
+		t.Errorf("Expected no request for a call a rule approves, got %+v", approver.asked)
+	}
+
+	e.ExecuteToolCall(ctx, calls("Git", "push")[0])
+	if result, _ := e.ExecuteToolCall(ctx, calls("Git", "push --force")[0]); result.IsError {
+		t.Errorf("Expected always to approve later calls, got %+v", result)
+	}
+	if len(approver.asked) != 1 || len(rules.List()) != 2 {
+		t.Errorf("Expected one request and a rule for Git, got %+v and %v", approver.asked, rules.List())
+	}
+}
+
+func TestApprovalRulesIgnoreEmptySubject(t *testing.T) {
+	rules := NewApprovalRules([]ApprovalRule{{Tool: "Bash", Pattern: ".*"}})
+	if rules.Allows("Bash", "") {
+		t.Error("Expected a pattern not to approve a call without a subject")
+	}
+	rules.Add(ApprovalRule{Tool: "Bash"})
+	if !rules.Allows("Bash", "") {
+		t.Error("Expected a rule for the whole tool to approve it")
+	}
+}
+
+func TestApprovalWithoutApprover(t *testing.T) {
+	e := newApprovalExecutor()
+	result, _ := e.ExecuteToolCall(context.Background(), calls("Bash", "ls")[0])
+	if !result.IsError || !strings.Contains(result.Content, "no interactive user") {
+		t.Errorf("Expected the call to be denied, got %+v", result)
+	}
+
+	off := NewExecutor(newPolicyRegistry(nil))
+	if result, _ := off.ExecuteToolCall(context.Background(), calls("Bash", "ls")[0]); result.Content != "Bash:ls" {
+		t.Errorf("Expected no gate unless it is required, got %+v", result)
+	}
+}
+
+func TestParseApprovalRule(t *testing.T) {
+	rule, err := ParseApprovalRule("  Bash ^go (test|vet) ")
+	if err != nil || rule.Tool != "Bash" || rule.Pattern != "^go (test|vet)" {
+		t.Errorf("Unexpected rule %+v, %v", rule, err)
+	}
+	if rule.String() != "Bash ^go (test|vet)" {
+		t.Errorf("Unexpected string %q", rule.String())
+	}
+	if _, err := ParseApprovalRule("Bash ["); err == nil {
+		t.Error("Expected an invalid pattern to fail")
+	}
+	if _, err := ParseApprovalRule(" "); err == nil {
+		t.Error("Expected a rule without a tool to fail")
+	}
+}
@@ -0,0 +1,75 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// MaxQuestionsPerTurn caps how many clarification questions one turn may ask
+const MaxQuestionsPerTurn = 3
+
+// AskTimeout bounds how long a turn waits for the user's answer
+var AskTimeout = 5 * time.Minute
+
+var (
+	// ErrNoUser is returned when no interactive user can answer
+	ErrNoUser = errors.New("no interactive user available")
+	// ErrTooManyQuestions is returned once a turn has used its question budget
+	ErrTooManyQuestions = errors.New("question limit reached for this turn")
+	// ErrQuestionCancelled is returned when the user dismisses a question
+	ErrQuestionCancelled = errors.New("question cancelled by user")
+)
+
+// Question is a clarification request from the model to the user
+type Question struct {
+	Text    string   `json:"text"`
+	Choices []string `json:"choices,omitempty"`
+}
+
+// Resolve maps a numbered reply ("2") to the matching choice
+func (q Question) Resolve(answer string) string {
+	answer = strings.TrimSpace(answer)
+	if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(q.Choices) {
+		return q.Choices[n-1]
+	}
+	return answer
+}
+
+// AskFunc presents a question to the user and blocks until it is answered
+type AskFunc func(ctx context.Context, q Question) (string, error)
+
+type askKey struct{}
+
+type asker struct {
+	fn    AskFunc
+	count atomic.Int32
+}
+
+// WithAsk returns a context that lets tools ask the user questions.
+// Call it once per turn: the question budget is tracked per context.
+func WithAsk(ctx context.Context, fn AskFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, askKey{}, &asker{fn: fn})
+}
+
+// Ask asks the user a question through the context's AskFunc, enforcing
+// the per-turn budget and AskTimeout
+func Ask(ctx context.Context, q Question) (string, error) {
+	a, _ := ctx.Value(askKey{}).(*asker)
+	if a == nil {
+		return "", ErrNoUser
+	}
+	if a.count.Add(1) > MaxQuestionsPerTurn {
+		return "", ErrTooManyQuestions
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, AskTimeout)
+	defer cancel()
+	return a.fn(ctx, q)
+}
@@ -0,0 +1,28 @@
+package tool
+
+import "context"
+
+// Billing lets tools that call a paid API themselves, such as Transcribe,
+// bill the user of the turn. Check reports why a request to model can't
+// be afforded, or nil; Charge records one request after it succeeded.
+type Billing interface {
+	Check(model string) error
+	Charge(model string)
+}
+
+type billingKey struct{}
+
+// WithBilling returns a context whose tools bill paid calls to b
+func WithBilling(ctx context.Context, b Billing) context.Context {
+	if b == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, billingKey{}, b)
+}
+
+// BillingFromContext returns the Billing set with WithBilling, or nil
+// where calls are not billed, such as in the REPL
+func BillingFromContext(ctx context.Context) Billing {
+	b, _ := ctx.Value(billingKey{}).(Billing)
+	return b
+}
@@ -0,0 +1,142 @@
+package tool
+
+import (
+	"encoding/json"
+	"sort"
+
+	"groq-go/internal/client"
+	"groq-go/internal/logging"
+)
+
+var log = logging.WithComponent("tool")
+
+// CoreTools are always offered to the model, whatever the budget
+var CoreTools = []string{"Read", "Write", "Edit", "Bash", "Grep", "Glob"}
+
+// maxTrimmedDescription is the longest description kept for a tool that
+// is shortened to fit a budget
+const maxTrimmedDescription = 120
+
+// ToClientToolsBudgeted returns the tools ToClientTools would, fitted to
+// budgetTokens for model with FitToolBudget
+func (r *Registry) ToClientToolsBudgeted(model string, budgetTokens int) []client.Tool {
+	return FitToolBudget(model, r.ToClientTools(), budgetTokens)
+}
+
+// FitToolBudget keeps the estimated size of tools' schemas within
+// budgetTokens. Core tools are always kept as they are. When the rest do
+// not fit, their descriptions are cut to the first line and the
+// descriptions of their parameters dropped; tools that still do not fit
+// are left out, and logged. A budget of zero or less keeps everything.
+// Models the catalog marks as not supporting tools get none.
+func FitToolBudget(model string, tools []client.Tool, budgetTokens int) []client.Tool {
+	if !client.SupportsTools(model) {
+		return nil
+	}
+	if budgetTokens <= 0 || estimateToolTokens(tools) <= budgetTokens {
+		return tools
+	}
+
+	core := make(map[string]bool, len(CoreTools))
+	for _, name := range CoreTools {
+		core[name] = true
+	}
+	var fitted, rest []client.Tool
+	used := 0
+	for _, t := range tools {
+		if core[t.Function.Name] {
+			fitted = append(fitted, t)
+			used += estimateToolTokens([]client.Tool{t})
+		} else {
+			rest = append(rest, trimTool(t))
+		}
+	}
+	// Smallest first, so as many tools as possible survive
+	sizes := make(map[string]int, len(rest))
+	for _, t := range rest {
+		sizes[t.Function.Name] = estimateToolTokens([]client.Tool{t})
+	}
+	sort.SliceStable(rest, func(i, j int) bool {
+		a, b := rest[i].Function.Name, rest[j].Function.Name
+		if sizes[a] != sizes[b] {
+			return sizes[a] < sizes[b]
+		}
+		return a < b
+	})
+
+	var omitted []string
+	for _, t := range rest {
+		if n := sizes[t.Function.Name]; used+n <= budgetTokens {
+			fitted = append(fitted, t)
+			used += n
+		} else {
+			omitted = append(omitted, t.Function.Name)
+		}
+	}
+	log.Info("Trimmed tool schemas to fit the model", "model", model, "budget_tokens", budgetTokens,
+		"estimated_tokens", used, "trimmed", len(rest)-len(omitted), "omitted", omitted)
+	return fitted
+}
+
+// trimTool returns t with a one-line description and no parameter
+// descriptions. The schema is copied; t's maps may be shared with the tool.
+func trimTool(t client.Tool) client.Tool {
+	desc := t.Function.Description
+	for i, c := range desc {
+		if c == '\n' {
+			desc = desc[:i]
+			break
+		}
+	}
+	if len(desc) > maxTrimmedDescription {
+		desc = desc[:maxTrimmedDescription]
+	}
+	t.Function.Description = desc
+	if params, ok := withoutDescriptions(t.Function.Parameters).(map[string]any); ok {
+		t.Function.Parameters = params
+	}
+	return t
+}
+
+// withoutDescriptions copies a JSON schema, dropping "description" keys at
+// every level except inside "properties", where they name parameters
+func withoutDescriptions(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, val := range v {
+			switch k {
+			case "description":
+				continue
+			case "properties":
+				if props, ok := val.(map[string]any); ok {
+					copied := make(map[string]any, len(props))
+					for name, prop := range props {
+						copied[name] = withoutDescriptions(prop)
+					}
+					out[k] = copied
+					continue
+				}
+			}
+			out[k] = withoutDescriptions(val)
+		}
+		return out
+	case []any:
+		out := make([]any, len(v))
+		for i := range v {
+			out[i] = withoutDescriptions(v[i])
+		}
+		return out
+	}
+	return v
+}
+
+// estimateToolTokens estimates the tokens tools take in a request, at 4
+// bytes of JSON per token as for messages
+func estimateToolTokens(tools []client.Tool) int {
+	data, err := json.Marshal(tools)
+	if err != nil {
+		return 0
+	}
+	return (len(data) + 3) / 4
+}
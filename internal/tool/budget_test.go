@@ -0,0 +1,114 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+// schemaTool is a tool with a sizeable description and schema
+type schemaTool struct{ name string }
+
+func (t *schemaTool) Name() string { return t.name }
+func (t *schemaTool) Description() string {
+	return "Does " + t.name + ".\n" + strings.Repeat("Long usage notes for the model. ", 20)
+}
+func (t *schemaTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"description": map[string]any{"type": "string", "description": strings.Repeat("A parameter named description. ", 5)},
+			"path":        map[string]any{"type": "string", "description": "Where to work"},
+		},
+		"required": []string{"path"},
+	}
+}
+func (t *schemaTool) Execute(ctx context.Context, args json.RawMessage) (Result, error) {
+	return NewResult(t.name), nil
+}
+
+func newBudgetRegistry(n int) *Registry {
+	r := NewRegistry()
+	for _, name := range CoreTools {
+		r.Register(&schemaTool{name: name})
+	}
+	for i := len(CoreTools); i < n; i++ {
+		r.Register(&schemaTool{name: fmt.Sprintf("mcp_tool_%02d", i)})
+	}
+	return r
+}
+
+func TestToolBudgetRespected(t *testing.T) {
+	r := newBudgetRegistry(60)
+	all := r.ToClientTools()
+	total := estimateToolTokens(all)
+
+	// A budget that fits everything leaves the tools alone
+	if got := r.ToClientToolsBudgeted("big-model", total); estimateToolTokens(got) != total || len(got) != 60 {
+		t.Errorf("Expected all 60 tools untouched, got %d", len(got))
+	}
+
+	for _, budget := range []int{total / 2, total / 4, 3000} {
+		got := r.ToClientToolsBudgeted("small-model", budget)
+		if n := estimateToolTokens(got); n > budget {
+			t.Errorf("Budget %d: estimated %d tokens", budget, n)
+		}
+		names := toolNames(got)
+		for _, core := range CoreTools {
+			if !names[core] {
+				t.Errorf("Budget %d: expected core tool %s kept", budget, core)
+			}
+		}
+		if len(got) <= len(CoreTools) {
+			t.Errorf("Budget %d: expected some other tools kept, got %d", budget, len(got))
+		}
+		for _, tl := range got {
+			full := strings.Contains(tl.Function.Description, "Long usage notes")
+			if !isCore(tl.Function.Name) && full {
+				t.Errorf("Budget %d: expected %s shortened", budget, tl.Function.Name)
+			}
+			if isCore(tl.Function.Name) && !full {
+				t.Errorf("Budget %d: expected core tool %s kept as is", budget, tl.Function.Name)
+			}
+		}
+	}
+
+	// Core tools survive even a budget they alone exceed
+	got := r.ToClientToolsBudgeted("tiny-model", 10)
+	if len(got) != len(CoreTools) {
+		t.Errorf("Expected only the core tools, got %d", len(got))
+	}
+}
+
+func TestTrimToolCopiesSchema(t *testing.T) {
+	tl := &schemaTool{name: "mcp_x"}
+	orig := client.Tool{Type: "function", Function: client.FunctionSchema{Name: tl.Name(), Description: tl.Description(), Parameters: tl.Parameters()}}
+	trimmed := trimTool(orig)
+
+	if trimmed.Function.Description != "Does mcp_x." {
+		t.Errorf("Expected the first line, got %q", trimmed.Function.Description)
+	}
+	props := trimmed.Function.Parameters["properties"].(map[string]any)
+	if _, ok := props["description"]; !ok {
+		t.Error("Expected a parameter named description kept")
+	}
+	if _, ok := props["path"].(map[string]any)["description"]; ok {
+		t.Error("Expected parameter descriptions dropped")
+	}
+	if _, ok := orig.Function.Parameters["properties"].(map[string]any)["path"].(map[string]any)["description"]; !ok {
+		t.Error("Expected the original schema untouched")
+	}
+}
+
+func isCore(name string) bool {
+	for _, c := range CoreTools {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
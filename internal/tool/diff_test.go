@@ -0,0 +1,59 @@
+package tool
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiffMultiHunk(t *testing.T) {
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, "line"+string(rune('a'+i%26)))
+	}
+	oldText := strings.Join(lines, "\n") + "\n"
+	changed := append([]string(nil), lines...)
+	changed[2] = "first change"
+	changed[25] = "second change"
+	newText := strings.Join(changed, "\n") + "\n"
+
+	diff := UnifiedDiff("f.txt", oldText, newText)
+	if !strings.HasPrefix(diff, "--- a/f.txt\n+++ b/f.txt\n") {
+		t.Fatalf("Expected file header, got %q", diff)
+	}
+	if n := strings.Count(diff, "\n@@ "); n != 2 {
+		t.Errorf("Expected 2 hunks, got %d:\n%s", n, diff)
+	}
+	if !strings.Contains(diff, "@@ -1,6 +1,6 @@\n") {
+		t.Errorf("Expected first hunk header, got:\n%s", diff)
+	}
+	if !strings.Contains(diff, "@@ -23,7 +23,7 @@\n") {
+		t.Errorf("Expected second hunk header, got:\n%s", diff)
+	}
+	if !strings.Contains(diff, "+first change\n") || !strings.Contains(diff, "+second change\n") {
+		t.Errorf("Expected both changes, got:\n%s", diff)
+	}
+
+	added, removed := DiffStats(diff)
+	if added != 2 || removed != 2 {
+		t.Errorf("Expected +2 -2, got +%d -%d", added, removed)
+	}
+}
+
+func TestUnifiedDiffNewFile(t *testing.T) {
+	diff := UnifiedDiff("new.txt", "", "a\nb\n")
+	if !strings.Contains(diff, "@@ -0,0 +1,2 @@\n+a\n+b\n") {
+		t.Errorf("Unexpected diff for new file:\n%s", diff)
+	}
+}
+
+func TestUnifiedDiffSkipsBinary(t *testing.T) {
+	if diff := UnifiedDiff("f.bin", "abc\x00def", "abc\x00xyz"); diff != "" {
+		t.Errorf("Expected no diff for NUL bytes, got %q", diff)
+	}
+	if diff := UnifiedDiff("f.bin", "text\n", "\xff\xfe\n"); diff != "" {
+		t.Errorf("Expected no diff for invalid UTF-8, got %q", diff)
+	}
+	if diff := UnifiedDiff("f.txt", "same\n", "same\n"); diff != "" {
+		t.Errorf("Expected no diff for equal content, got %q", diff)
+	}
+}
@@ -4,36 +4,191 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sync"
+	"time"
 
 	"groq-go/internal/client"
 )
 
+// DefaultMaxConcurrency bounds parallel tool execution in the REPL and web server
+const DefaultMaxConcurrency = 4
+
+// DefaultTimeout bounds a single tool call unless the tool hints otherwise
+const DefaultTimeout = 60 * time.Second
+
+// cancelGrace is how long a canceled tool may take to return partial output
+const cancelGrace = 100 * time.Millisecond
+
+// CallOutputFunc receives incremental output from one call of a batch
+type CallOutputFunc func(tc client.ToolCall, stage, detail string)
+
+// CallProgressFunc receives progress reports from one call of a batch
+type CallProgressFunc func(tc client.ToolCall, stage string, pct int)
+
 // Executor handles tool execution
 type Executor struct {
 	registry *Registry
+	timeout  time.Duration
+
+	resultLimit int // bytes per result sent to the model, see fitResult
+	turnLimit   int // bytes of results per turn, see StartTurn
+
+	hooksMu sync.RWMutex
+	hooks   []Hook
 }
 
 // NewExecutor creates a new tool executor
 func NewExecutor(registry *Registry) *Executor {
 	return &Executor{
-		registry: registry,
+		registry:    registry,
+		timeout:     DefaultTimeout,
+		resultLimit: MaxResultBytes,
+		turnLimit:   MaxTurnResultBytes,
 	}
 }
 
-// ExecuteToolCall executes a single tool call and returns the result
+// SetTimeout changes the default per-call timeout; 0 disables it
+func (e *Executor) SetTimeout(d time.Duration) {
+	e.timeout = d
+}
+
+// timeoutFor returns the limit for a tool: its hint if it has one,
+// otherwise the executor default
+func (e *Executor) timeoutFor(t Tool) time.Duration {
+	if h, ok := t.(TimeoutHinter); ok && h.Timeout() > 0 {
+		return h.Timeout()
+	}
+	return e.timeout
+}
+
+// ExecuteToolCall executes a single tool call and returns the result.
+// The call's context is canceled when it times out or ctx is canceled; a
+// tool that ignores cancellation is abandoned and an error result returned.
+// Registered hooks run around the call. Results over the executor's size
+// limits are cut down, see fitResult.
 func (e *Executor) ExecuteToolCall(ctx context.Context, tc client.ToolCall) (Result, error) {
+	hooks := e.currentHooks()
+	if len(hooks) == 0 {
+		return e.executeFitted(ctx, tc)
+	}
+
+	ctxs := make([]context.Context, len(hooks))
+	for i, h := range hooks {
+		ctx = h.BeforeExecute(ctx, tc)
+		ctxs[i] = ctx
+	}
+	start := time.Now()
+	result, err := e.executeFitted(ctx, tc)
+	duration := time.Since(start)
+	for i := len(hooks) - 1; i >= 0; i-- {
+		hooks[i].AfterExecute(ctxs[i], tc, result, err, duration)
+	}
+	return result, err
+}
+
+// executeFitted runs a tool call without hooks and applies the result caps
+func (e *Executor) executeFitted(ctx context.Context, tc client.ToolCall) (Result, error) {
+	result, err := e.execute(ctx, tc)
+	if err != nil {
+		return result, err
+	}
+	return e.fitResult(ctx, tc, result), nil
+}
+
+// execute runs a tool call without hooks
+func (e *Executor) execute(ctx context.Context, tc client.ToolCall) (Result, error) {
 	tool, ok := e.registry.Get(tc.Function.Name)
 	if !ok {
+		if e.registry.WasRemoved(tc.Function.Name) {
+			return NewErrorResult(fmt.Sprintf("tool %s is no longer available", tc.Function.Name)), nil
+		}
 		return NewErrorResult(fmt.Sprintf("unknown tool: %s", tc.Function.Name)), nil
 	}
+	if e.registry.ReadOnly() && blockedReadOnly(tool, json.RawMessage(tc.Function.Arguments)) {
+		return ReadOnlyResult(tc.Function.Name), nil
+	}
+	if !e.registry.Enabled(ModeFromContext(ctx), tc.Function.Name) {
+		return NewErrorResult(fmt.Sprintf("tool %s is disabled by the server's tool policy", tc.Function.Name)), nil
+	}
+	if e.registry.needsApproval(ctx, tool, json.RawMessage(tc.Function.Arguments)) {
+		if denied, ok := approveCall(ctx, tool, tc.Function.Arguments); !ok {
+			return denied, nil
+		}
+	}
 
-	args := json.RawMessage(tc.Function.Arguments)
-	result, err := tool.Execute(ctx, args)
-	if err != nil {
-		return NewErrorResult(fmt.Sprintf("tool execution error: %v", err)), nil
+	timeout := e.timeoutFor(tool)
+	callCtx, cancel := context.WithCancel(ctx)
+	if timeout > 0 {
+		callCtx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer cancel()
+
+	type outcome struct {
+		result Result
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- outcome{result: NewErrorResult(fmt.Sprintf("tool %s panicked: %v", tc.Function.Name, p))}
+			}
+		}()
+		result, err := tool.Execute(callCtx, json.RawMessage(tc.Function.Arguments))
+		done <- outcome{result, err}
+	}()
+
+	var o outcome
+	select {
+	case o = <-done:
+	case <-callCtx.Done():
+		// Give the tool a moment to return what it has after cancellation
+		select {
+		case o = <-done:
+		case <-time.After(cancelGrace):
+		}
 	}
 
-	return result, nil
+	if callCtx.Err() != nil {
+		result := e.interrupted(ctx, tc, timeout)
+		if o.result.Content != "" {
+			result.Content += "\nPartial output:\n" + o.result.Content
+		}
+		return result, nil
+	}
+	if o.err != nil {
+		return NewErrorResult(fmt.Sprintf("tool execution error: %v", o.err)), nil
+	}
+	recordPaths(ctx, tool, tc, o.result)
+	return o.result, nil
+}
+
+// recordPaths notes the files a successful call showed in the session's
+// ReadTracker
+func recordPaths(ctx context.Context, t Tool, tc client.ToolCall, result Result) {
+	tracker := ReadTrackerFromContext(ctx)
+	reporter, ok := t.(PathReporter)
+	if tracker == nil || !ok || result.IsError {
+		return
+	}
+	// Relative paths in a project are relative to its root
+	sandbox := SandboxFromContext(ctx)
+	for _, path := range reporter.ReportedPaths(json.RawMessage(tc.Function.Arguments), result) {
+		tracker.MarkRead(sandbox.Abs(path))
+	}
+}
+
+// interrupted describes a call stopped by its timeout or by cancellation of ctx
+func (e *Executor) interrupted(ctx context.Context, tc client.ToolCall, timeout time.Duration) Result {
+	if ctx.Err() != nil {
+		return NewErrorResult(fmt.Sprintf("tool %s was canceled", tc.Function.Name))
+	}
+	return NewErrorResult(fmt.Sprintf("tool %s timed out after %s", tc.Function.Name, timeout))
+}
+
+// ExecuteToolCallWithOutput executes a tool call, forwarding incremental output to out
+func (e *Executor) ExecuteToolCallWithOutput(ctx context.Context, tc client.ToolCall, out OutputFunc) (Result, error) {
+	return e.ExecuteToolCall(WithOutput(ctx, out), tc)
 }
 
 // ExecuteToolCalls executes multiple tool calls and returns messages with results
@@ -53,3 +208,89 @@ func (e *Executor) ExecuteToolCalls(ctx context.Context, toolCalls []client.Tool
 
 	return messages
 }
+
+// ExecuteToolCallsParallel runs tool calls concurrently with at most
+// maxConcurrency at a time. results[i] belongs to toolCalls[i].
+func (e *Executor) ExecuteToolCallsParallel(ctx context.Context, toolCalls []client.ToolCall, maxConcurrency int) []Result {
+	return e.ExecuteToolCallsParallelWithOutput(ctx, toolCalls, maxConcurrency, nil)
+}
+
+// ExecuteToolCallsParallelWithOutput is ExecuteToolCallsParallel with
+// incremental output. Calls to out are serialized. Exclusive tools, and
+// calls that change things such as Write, Edit and Bash, run alone: after
+// the calls before them finish and before the calls after them start, so
+// an Edit never races another on the same file and a Write sees the
+// directory an earlier Bash call made. A panicking tool yields an error
+// result instead of failing the batch.
+func (e *Executor) ExecuteToolCallsParallelWithOutput(ctx context.Context, toolCalls []client.ToolCall, maxConcurrency int, out CallOutputFunc) []Result {
+	return e.ExecuteToolCallsParallelWithProgress(ctx, toolCalls, maxConcurrency, out, nil)
+}
+
+// ExecuteToolCallsParallelWithProgress is ExecuteToolCallsParallelWithOutput
+// that also forwards progress reports to progress. Calls to out and
+// progress are serialized together.
+func (e *Executor) ExecuteToolCallsParallelWithProgress(ctx context.Context, toolCalls []client.ToolCall, maxConcurrency int, out CallOutputFunc, progress CallProgressFunc) []Result {
+	if maxConcurrency < 1 {
+		maxConcurrency = 1
+	}
+	results := make([]Result, len(toolCalls))
+
+	var outMu sync.Mutex
+	run := func(i int) {
+		tc := toolCalls[i]
+		defer func() {
+			if p := recover(); p != nil {
+				results[i] = NewErrorResult(fmt.Sprintf("tool %s panicked: %v", tc.Function.Name, p))
+			}
+		}()
+		callCtx := ctx
+		if out != nil {
+			callCtx = WithOutput(ctx, func(stage, detail string) {
+				outMu.Lock()
+				defer outMu.Unlock()
+				out(tc, stage, detail)
+			})
+		}
+		if progress != nil {
+			callCtx = WithProgress(callCtx, ProgressFunc(func(stage string, pct int) {
+				outMu.Lock()
+				defer outMu.Unlock()
+				progress(tc, stage, pct)
+			}))
+		}
+		results[i], _ = e.ExecuteToolCall(callCtx, tc)
+	}
+
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i, tc := range toolCalls {
+		if e.runsAlone(tc) {
+			wg.Wait()
+			run(i)
+			continue
+		}
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			run(i)
+		}(i)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// runsAlone reports whether tc must not overlap other calls of a batch:
+// it is Exclusive, or read-only mode would block it as a change
+func (e *Executor) runsAlone(tc client.ToolCall) bool {
+	t, ok := e.registry.Get(tc.Function.Name)
+	if !ok {
+		return false
+	}
+	if ex, ok := t.(Exclusive); ok && ex.Exclusive() {
+		return true
+	}
+	return blockedReadOnly(t, json.RawMessage(tc.Function.Arguments))
+}
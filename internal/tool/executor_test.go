@@ -0,0 +1,255 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+// slowTool sleeps, then echoes its argument, tracking how many run at once
+type slowTool struct {
+	name      string
+	delay     time.Duration
+	exclusive bool
+	active    *atomic.Int32
+	peak      *atomic.Int32
+}
+
+func (t *slowTool) Name() string               { return t.name }
+func (t *slowTool) Description() string        { return "" }
+func (t *slowTool) Parameters() map[string]any { return nil }
+func (t *slowTool) Exclusive() bool            { return t.exclusive }
+
+func (t *slowTool) Execute(ctx context.Context, args json.RawMessage) (Result, error) {
+	n := t.active.Add(1)
+	defer t.active.Add(-1)
+	for {
+		p := t.peak.Load()
+		if n <= p || t.peak.CompareAndSwap(p, n) {
+			break
+		}
+	}
+	if out := OutputFromContext(ctx); out != nil {
+		out("running", string(args))
+	}
+	time.Sleep(t.delay)
+	var v string
+	json.Unmarshal(args, &v)
+	if v == "panic" {
+		panic("boom")
+	}
+	return NewResult(t.name + ":" + v), nil
+}
+
+func newTestExecutor(delay time.Duration) (*Executor, *atomic.Int32) {
+	active, peak := &atomic.Int32{}, &atomic.Int32{}
+	r := NewRegistry()
+	r.Register(&slowTool{name: "Slow", delay: delay, active: active, peak: peak})
+	r.Register(&slowTool{name: "Ask", delay: delay, exclusive: true, active: active, peak: peak})
+	return NewExecutor(r), peak
+}
+
+func calls(specs ...string) []client.ToolCall {
+	var tcs []client.ToolCall
+	for i := 0; i+1 < len(specs); i += 2 {
+		tcs = append(tcs, client.ToolCall{
+			ID:       fmt.Sprintf("call_%d", i/2),
+			Function: client.FunctionCall{Name: specs[i], Arguments: fmt.Sprintf("%q", specs[i+1])},
+		})
+	}
+	return tcs
+}
+
+func TestExecuteToolCallsParallelSpeedupAndOrder(t *testing.T) {
+	e, peak := newTestExecutor(100 * time.Millisecond)
+	tcs := calls("Slow", "a", "Slow", "b", "Slow", "c", "Slow", "d", "Slow", "e", "Slow", "f", "Slow", "g", "Slow", "h")
+
+	start := time.Now()
+	results := e.ExecuteToolCallsParallel(context.Background(), tcs, 4)
+	elapsed := time.Since(start)
+
+	// 8 calls of 100ms, 4 at a time: about 200ms instead of 800ms
+	if elapsed > 500*time.Millisecond {
+		t.Errorf("Expected concurrent execution, took %v", elapsed)
+	}
+	if p := peak.Load(); p != 4 {
+		t.Errorf("Expected at most 4 concurrent calls, peak was %d", p)
+	}
+	for i, want := range []string{"a", "b", "c", "d", "e", "f", "g", "h"} {
+		if results[i].Content != "Slow:"+want {
+			t.Errorf("Result %d: expected Slow:%s, got %q", i, want, results[i].Content)
+		}
+	}
+}
+
+func TestExecuteToolCallsParallelRecoversPanics(t *testing.T) {
+	e, _ := newTestExecutor(10 * time.Millisecond)
+	results := e.ExecuteToolCallsParallel(context.Background(), calls("Slow", "a", "Slow", "panic", "Slow", "c"), 4)
+
+	if !results[1].IsError {
+		t.Errorf("Expected the panicking call to fail, got %+v", results[1])
+	}
+	if results[0].Content != "Slow:a" || results[2].Content != "Slow:c" {
+		t.Errorf("Expected the other calls to succeed, got %+v", results)
+	}
+}
+
+func TestExecuteToolCallsParallelExclusive(t *testing.T) {
+	e, peak := newTestExecutor(20 * time.Millisecond)
+	var outputs atomic.Int32
+	results := e.ExecuteToolCallsParallelWithOutput(context.Background(), calls("Ask", "q1", "Ask", "q2", "Unknown", "x"), 4,
+		func(tc client.ToolCall, stage, detail string) { outputs.Add(1) })
+
+	if p := peak.Load(); p != 1 {
+		t.Errorf("Expected exclusive tools to run alone, peak was %d", p)
+	}
+	if results[0].Content != "Ask:q1" || results[1].Content != "Ask:q2" || !results[2].IsError {
+		t.Errorf("Unexpected results %+v", results)
+	}
+	if outputs.Load() != 2 {
+		t.Errorf("Expected 2 output callbacks, got %d", outputs.Load())
+	}
+}
+
+func TestExecuteToolCallsParallelSerializesChanges(t *testing.T) {
+	active, peak := &atomic.Int32{}, &atomic.Int32{}
+	r := NewRegistry()
+	r.Register(&slowTool{name: "Slow", delay: 20 * time.Millisecond, active: active, peak: peak})
+	r.Register(&slowTool{name: "Write", delay: 20 * time.Millisecond, active: active, peak: peak})
+	results := NewExecutor(r).ExecuteToolCallsParallel(context.Background(), calls("Slow", "a", "Write", "b", "Write", "c", "Slow", "d"), 4)
+
+	if p := peak.Load(); p != 1 {
+		t.Errorf("Expected changing calls to run alone, peak was %d", p)
+	}
+	for i, want := range []string{"Slow:a", "Write:b", "Write:c", "Slow:d"} {
+		if results[i].Content != want {
+			t.Errorf("Result %d: expected %s, got %q", i, want, results[i].Content)
+		}
+	}
+}
+
+// stuckTool sleeps without watching its context unless it honors cancellation
+type stuckTool struct {
+	sleep   time.Duration
+	hint    time.Duration
+	honors  bool
+	stopped chan struct{}
+}
+
+func (t *stuckTool) Name() string               { return "Stuck" }
+func (t *stuckTool) Description() string        { return "" }
+func (t *stuckTool) Parameters() map[string]any { return nil }
+func (t *stuckTool) Timeout() time.Duration     { return t.hint }
+
+func (t *stuckTool) Execute(ctx context.Context, args json.RawMessage) (Result, error) {
+	if t.honors {
+		select {
+		case <-ctx.Done():
+			close(t.stopped)
+			return NewResult("partial"), ctx.Err()
+		case <-time.After(t.sleep):
+		}
+	} else {
+		time.Sleep(t.sleep)
+	}
+	return NewResult("finished"), nil
+}
+
+func stuckExecutor(st *stuckTool, timeout time.Duration) *Executor {
+	r := NewRegistry()
+	r.Register(st)
+	e := NewExecutor(r)
+	e.SetTimeout(timeout)
+	return e
+}
+
+func TestExecuteToolCallTimeout(t *testing.T) {
+	e := stuckExecutor(&stuckTool{sleep: 5 * time.Second}, 50*time.Millisecond)
+
+	start := time.Now()
+	result, _ := e.ExecuteToolCall(context.Background(), calls("Stuck", "x")[0])
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the executor to give up, took %v", elapsed)
+	}
+	if !result.IsError || result.Content != "tool Stuck timed out after 50ms" {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestExecuteToolCallTimeoutHint(t *testing.T) {
+	e := stuckExecutor(&stuckTool{sleep: 100 * time.Millisecond, hint: time.Second}, 20*time.Millisecond)
+	if result, _ := e.ExecuteToolCall(context.Background(), calls("Stuck", "x")[0]); result.Content != "finished" {
+		t.Errorf("Expected the hint to extend the timeout, got %+v", result)
+	}
+}
+
+func TestExecuteToolCallCanceled(t *testing.T) {
+	st := &stuckTool{sleep: 5 * time.Second, honors: true, stopped: make(chan struct{})}
+	e := stuckExecutor(st, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	result, _ := e.ExecuteToolCall(ctx, calls("Stuck", "x")[0])
+
+	select {
+	case <-st.stopped:
+	default:
+		t.Error("Expected the tool's context to be canceled")
+	}
+	if !result.IsError || result.Content != "tool Stuck was canceled\nPartial output:\npartial" {
+		t.Errorf("Unexpected result %q", result.Content)
+	}
+}
+
+// progressTool reports each stage in its argument, in order
+type progressTool struct{}
+
+func (progressTool) Name() string               { return "Progress" }
+func (progressTool) Description() string        { return "" }
+func (progressTool) Parameters() map[string]any { return nil }
+
+func (progressTool) Execute(ctx context.Context, args json.RawMessage) (Result, error) {
+	var stages []string
+	json.Unmarshal(args, &stages)
+	for i, stage := range stages {
+		ReportProgress(ctx, stage, 100*(i+1)/len(stages))
+	}
+	return NewResult("ok"), nil
+}
+
+func TestExecuteToolCallsParallelWithProgress(t *testing.T) {
+	e, _ := newTestExecutor(time.Millisecond)
+	e.registry.Register(progressTool{})
+
+	tcs := []client.ToolCall{
+		{ID: "call_0", Function: client.FunctionCall{Name: "Progress", Arguments: `["starting","running","saving output"]`}},
+		{ID: "call_1", Function: client.FunctionCall{Name: "Progress", Arguments: `["starting","running"]`}},
+		{ID: "call_2", Function: client.FunctionCall{Name: "Slow", Arguments: `"x"`}},
+	}
+	got := make(map[string][]string)
+	results := e.ExecuteToolCallsParallelWithProgress(context.Background(), tcs, 4, nil,
+		func(tc client.ToolCall, stage string, pct int) {
+			got[tc.ID] = append(got[tc.ID], fmt.Sprintf("%s:%d", stage, pct))
+		})
+
+	if want := "[starting:33 running:66 saving output:100]"; fmt.Sprint(got["call_0"]) != want {
+		t.Errorf("Expected %s for call_0, got %v", want, got["call_0"])
+	}
+	if want := "[starting:50 running:100]"; fmt.Sprint(got["call_1"]) != want {
+		t.Errorf("Expected %s for call_1, got %v", want, got["call_1"])
+	}
+	// Tools that don't report progress run unchanged
+	if len(got["call_2"]) != 0 || results[2].Content != "Slow:x" {
+		t.Errorf("Expected the plain tool to run without progress, got %v and %+v", got["call_2"], results[2])
+	}
+}
+
+func TestReportProgressWithoutReporter(t *testing.T) {
+	// Must not panic
+	ReportProgress(context.Background(), "running", -1)
+}
@@ -0,0 +1,40 @@
+package tool
+
+import "context"
+
+// Format says which files Write and Edit reformat after writing
+type Format int
+
+const (
+	// FormatOff writes content exactly as given
+	FormatOff Format = iota
+	// FormatCode formats source code, leaving data files byte for byte
+	FormatCode
+	// FormatAll also reformats JSON and YAML data files
+	FormatAll
+)
+
+func (f Format) String() string {
+	switch f {
+	case FormatCode:
+		return "code"
+	case FormatAll:
+		return "all"
+	}
+	return "off"
+}
+
+type formatKey struct{}
+
+// WithFormat returns a context in which file tools format what they write
+// as f says
+func WithFormat(ctx context.Context, f Format) context.Context {
+	return context.WithValue(ctx, formatKey{}, f)
+}
+
+// FormatFromContext returns the format set with WithFormat. Without one,
+// files are written as given.
+func FormatFromContext(ctx context.Context) Format {
+	f, _ := ctx.Value(formatKey{}).(Format)
+	return f
+}
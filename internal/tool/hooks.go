@@ -0,0 +1,54 @@
+package tool
+
+import (
+	"context"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+// Hook observes every call the executor runs, including calls refused for
+// an unknown or disabled tool. Hooks must be safe for concurrent use,
+// since parallel calls run them at the same time.
+type Hook interface {
+	// BeforeExecute runs before the tool and returns the context for the
+	// call, e.g. ctx itself or one carrying a span
+	BeforeExecute(ctx context.Context, call client.ToolCall) context.Context
+	// AfterExecute runs once the call has finished, with the context
+	// BeforeExecute returned
+	AfterExecute(ctx context.Context, call client.ToolCall, result Result, err error, duration time.Duration)
+}
+
+// AddHook registers h. BeforeExecute hooks run in the order they were
+// added and AfterExecute hooks in reverse, so the first hook wraps the rest.
+func (e *Executor) AddHook(h Hook) {
+	e.hooksMu.Lock()
+	defer e.hooksMu.Unlock()
+	e.hooks = append(e.hooks, h)
+}
+
+// currentHooks returns a snapshot of the registered hooks
+func (e *Executor) currentHooks() []Hook {
+	e.hooksMu.RLock()
+	defer e.hooksMu.RUnlock()
+	return e.hooks
+}
+
+// Caller identifies who a tool call runs for, for hooks such as audit logs
+type Caller struct {
+	User      string
+	SessionID string
+}
+
+type callerKey struct{}
+
+// WithCaller records who the tool calls made with ctx run for
+func WithCaller(ctx context.Context, c Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, c)
+}
+
+// CallerFromContext returns the caller set with WithCaller, if any
+func CallerFromContext(ctx context.Context) (Caller, bool) {
+	c, ok := ctx.Value(callerKey{}).(Caller)
+	return c, ok
+}
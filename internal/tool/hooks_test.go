@@ -0,0 +1,73 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+type hookKey struct{}
+
+// recordingHook logs its calls to a shared list and tags the context
+type recordingHook struct {
+	name string
+	mu   *sync.Mutex
+	log  *[]string
+}
+
+func (h *recordingHook) BeforeExecute(ctx context.Context, call client.ToolCall) context.Context {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	*h.log = append(*h.log, "before "+h.name)
+	return context.WithValue(ctx, hookKey{}, h.name)
+}
+
+func (h *recordingHook) AfterExecute(ctx context.Context, call client.ToolCall, result Result, err error, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	seen, _ := ctx.Value(hookKey{}).(string)
+	*h.log = append(*h.log, "after "+h.name+" ctx="+seen+" result="+result.Content+" timed="+
+		map[bool]string{true: "yes", false: "no"}[duration >= 20*time.Millisecond])
+}
+
+func TestHooksRunInOrder(t *testing.T) {
+	e, _ := newTestExecutor(20 * time.Millisecond)
+	var mu sync.Mutex
+	var log []string
+	e.AddHook(&recordingHook{name: "a", mu: &mu, log: &log})
+	e.AddHook(&recordingHook{name: "b", mu: &mu, log: &log})
+
+	if _, err := e.ExecuteToolCall(context.Background(), calls("Slow", "x")[0]); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"before a",
+		"before b",
+		"after b ctx=b result=Slow:x timed=yes",
+		"after a ctx=a result=Slow:x timed=yes",
+	}
+	if strings.Join(log, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s", strings.Join(want, "\n"), strings.Join(log, "\n"))
+	}
+
+	// Refused calls are observed too
+	log = nil
+	e.ExecuteToolCall(context.Background(), client.ToolCall{ID: "call_9", Function: client.FunctionCall{Name: "Missing"}})
+	if len(log) != 4 || !strings.Contains(log[2], "unknown tool: Missing") {
+		t.Errorf("Expected hooks around an unknown tool, got %v", log)
+	}
+}
+
+func TestCallerContext(t *testing.T) {
+	if _, ok := CallerFromContext(context.Background()); ok {
+		t.Error("Expected no caller by default")
+	}
+	ctx := WithCaller(context.Background(), Caller{User: "alice", SessionID: "ws-1"})
+	if c, ok := CallerFromContext(ctx); !ok || c.User != "alice" || c.SessionID != "ws-1" {
+		t.Errorf("Unexpected caller %+v", c)
+	}
+}
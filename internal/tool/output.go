@@ -0,0 +1,22 @@
+package tool
+
+import "context"
+
+// OutputFunc receives incremental output from a running tool
+type OutputFunc func(stage, detail string)
+
+type outputKey struct{}
+
+// WithOutput returns a context that carries an output callback for tools
+func WithOutput(ctx context.Context, fn OutputFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, outputKey{}, fn)
+}
+
+// OutputFromContext returns the output callback, or nil if none is set
+func OutputFromContext(ctx context.Context) OutputFunc {
+	fn, _ := ctx.Value(outputKey{}).(OutputFunc)
+	return fn
+}
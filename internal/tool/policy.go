@@ -0,0 +1,76 @@
+package tool
+
+import (
+	"context"
+	"strings"
+)
+
+// Policy restricts which tools are offered to the model and executed.
+// Deny wins over Allow; an empty Allow list allows every tool not denied.
+// Modes adds further restrictions for web chat modes ("tools", "improve").
+type Policy struct {
+	Allow []string          `mapstructure:"allow" yaml:"allow,omitempty" json:"allow,omitempty"`
+	Deny  []string          `mapstructure:"deny" yaml:"deny,omitempty" json:"deny,omitempty"`
+	Modes map[string]Policy `mapstructure:"modes" yaml:"modes,omitempty" json:"modes,omitempty"`
+}
+
+// ParseToolList splits a comma-separated list of tool names, as used by
+// the TOOLS_ALLOW and TOOLS_DENY environment variables
+func ParseToolList(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// Allows reports whether the tool may be used in mode. An empty mode
+// checks only the server-wide lists. A nil policy allows everything.
+func (p *Policy) Allows(mode, name string) bool {
+	if p == nil {
+		return true
+	}
+	if !p.allows(name) {
+		return false
+	}
+	if mp, ok := p.Modes[mode]; ok && mode != "" {
+		return mp.allows(name)
+	}
+	return true
+}
+
+func (p *Policy) allows(name string) bool {
+	if containsTool(p.Deny, name) {
+		return false
+	}
+	return len(p.Allow) == 0 || containsTool(p.Allow, name)
+}
+
+// IsZero reports whether the policy restricts nothing
+func (p *Policy) IsZero() bool {
+	return p == nil || (len(p.Allow) == 0 && len(p.Deny) == 0 && len(p.Modes) == 0)
+}
+
+func containsTool(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+type modeKey struct{}
+
+// WithMode records the chat mode a tool call runs in, for policy checks
+func WithMode(ctx context.Context, mode string) context.Context {
+	return context.WithValue(ctx, modeKey{}, mode)
+}
+
+// ModeFromContext returns the chat mode set with WithMode, or ""
+func ModeFromContext(ctx context.Context) string {
+	mode, _ := ctx.Value(modeKey{}).(string)
+	return mode
+}
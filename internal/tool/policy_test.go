@@ -0,0 +1,76 @@
+package tool
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+func newPolicyRegistry(p *Policy) *Registry {
+	active, peak := &atomic.Int32{}, &atomic.Int32{}
+	r := NewRegistry()
+	for _, name := range []string{"Read", "Bash", "Git", "SelfImprove"} {
+		r.Register(&slowTool{name: name, delay: time.Millisecond, active: active, peak: peak})
+	}
+	r.SetPolicy(p)
+	return r
+}
+
+func toolNames(tools []client.Tool) map[string]bool {
+	names := make(map[string]bool)
+	for _, t := range tools {
+		names[t.Function.Name] = true
+	}
+	return names
+}
+
+func TestPolicyDenyAtRegistry(t *testing.T) {
+	r := newPolicyRegistry(&Policy{
+		Deny:  ParseToolList(" bash, Git ,"),
+		Modes: map[string]Policy{"tools": {Deny: []string{"SelfImprove"}}},
+	})
+
+	names := toolNames(r.ToClientTools())
+	if names["Bash"] || names["Git"] || !names["Read"] || !names["SelfImprove"] {
+		t.Errorf("Unexpected server-wide tools %v", names)
+	}
+	if names := toolNames(r.ToClientToolsForMode("tools")); names["SelfImprove"] || !names["Read"] {
+		t.Errorf("Unexpected tools in tools mode %v", names)
+	}
+	if names := toolNames(r.ToClientToolsFiltered([]string{"Bash", "Read"})); len(names) != 1 || !names["Read"] {
+		t.Errorf("Expected the filter to respect the policy, got %v", names)
+	}
+
+	allowOnly := newPolicyRegistry(&Policy{Allow: []string{"Read", "Bash"}, Deny: []string{"Bash"}})
+	if names := toolNames(allowOnly.ToClientTools()); len(names) != 1 || !names["Read"] {
+		t.Errorf("Expected deny to win over allow, got %v", names)
+	}
+}
+
+func TestPolicyDenyAtExecutor(t *testing.T) {
+	r := newPolicyRegistry(&Policy{
+		Deny:  []string{"Bash"},
+		Modes: map[string]Policy{"improve": {Allow: []string{"SelfImprove"}}},
+	})
+	e := NewExecutor(r)
+
+	result, _ := e.ExecuteToolCall(context.Background(), calls("Bash", "rm -rf /")[0])
+	if !result.IsError || !strings.Contains(result.Content, "disabled by the server's tool policy") {
+		t.Errorf("Expected a policy rejection, got %+v", result)
+	}
+	if result, _ := e.ExecuteToolCall(context.Background(), calls("Read", "a")[0]); result.Content != "Read:a" {
+		t.Errorf("Expected allowed tools to run, got %+v", result)
+	}
+
+	ctx := WithMode(context.Background(), "improve")
+	if result, _ := e.ExecuteToolCall(ctx, calls("Read", "a")[0]); !result.IsError {
+		t.Errorf("Expected the mode policy to reject Read, got %+v", result)
+	}
+	if result, _ := e.ExecuteToolCall(ctx, calls("SelfImprove", "a")[0]); result.Content != "SelfImprove:a" {
+		t.Errorf("Expected SelfImprove in improve mode, got %+v", result)
+	}
+}
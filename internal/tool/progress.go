@@ -0,0 +1,42 @@
+package tool
+
+import "context"
+
+// ProgressReporter receives coarse progress from a running tool. stage
+// names what the tool is doing ("starting", "running", "saving output");
+// pct is a completion percentage, or -1 when the tool can't tell.
+type ProgressReporter interface {
+	ReportProgress(stage string, pct int)
+}
+
+// ProgressFunc adapts a function to a ProgressReporter
+type ProgressFunc func(stage string, pct int)
+
+// ReportProgress calls f
+func (f ProgressFunc) ReportProgress(stage string, pct int) {
+	f(stage, pct)
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context that carries a progress reporter for tools
+func WithProgress(ctx context.Context, r ProgressReporter) context.Context {
+	if r == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, progressKey{}, r)
+}
+
+// ProgressFromContext returns the progress reporter, or nil if none is set
+func ProgressFromContext(ctx context.Context) ProgressReporter {
+	r, _ := ctx.Value(progressKey{}).(ProgressReporter)
+	return r
+}
+
+// ReportProgress reports progress to the reporter in ctx, if any. Tools
+// call it unconditionally; without a reporter it does nothing.
+func ReportProgress(ctx context.Context, stage string, pct int) {
+	if r := ProgressFromContext(ctx); r != nil {
+		r.ReportProgress(stage, pct)
+	}
+}
@@ -0,0 +1,54 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ReadOnlyBlocked are the tools hidden in read-only mode: they write
+// files, run commands, change the deployment or spend money, as
+// Transcribe does on every call. Tools not
+// listed, such as MCP and plugin tools, stay available; deny them with
+// the tool policy if they can write.
+var ReadOnlyBlocked = []string{
+	"Write", "Edit", "Bash", "BashOutput", "CodeExec", "Browser",
+	"SelfImprove", "Version", "ImageGen", "Transcribe", "Schedule",
+}
+
+// Mutator is implemented by tools that change things only for some
+// arguments, such as Git. In read-only mode they stay available, and
+// calls for which Mutates reports true get the read-only stub result.
+type Mutator interface {
+	Mutates(args json.RawMessage) bool
+}
+
+// SetReadOnly turns read-only mode on or off: ReadOnlyBlocked tools are
+// no longer offered to the model, and calls to them, or mutating calls
+// to a Mutator, return ReadOnlyResult instead of running
+func (r *Registry) SetReadOnly(on bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readOnly = on
+	r.notifyLocked()
+}
+
+// ReadOnly reports whether read-only mode is on
+func (r *Registry) ReadOnly() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.readOnly
+}
+
+// ReadOnlyResult is what blocked calls return in read-only mode
+func ReadOnlyResult(name string) Result {
+	return NewErrorResult(fmt.Sprintf("%s is disabled in read-only mode", name))
+}
+
+// blockedReadOnly reports whether read-only mode stops the call
+func blockedReadOnly(t Tool, args json.RawMessage) bool {
+	if containsTool(ReadOnlyBlocked, t.Name()) {
+		return true
+	}
+	m, ok := t.(Mutator)
+	return ok && m.Mutates(args)
+}
@@ -0,0 +1,51 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// gitLikeTool mutates only when asked to "push"
+type gitLikeTool struct{ slowTool }
+
+func (t *gitLikeTool) Mutates(args json.RawMessage) bool {
+	var v string
+	json.Unmarshal(args, &v)
+	return v == "push"
+}
+
+func TestReadOnlyHidesAndBlocksTools(t *testing.T) {
+	r := newPolicyRegistry(nil)
+	active, peak := &atomic.Int32{}, &atomic.Int32{}
+	r.Register(&gitLikeTool{slowTool{name: "Git2", delay: time.Millisecond, active: active, peak: peak}})
+	r.SetReadOnly(true)
+	e := NewExecutor(r)
+
+	names := toolNames(r.ToClientTools())
+	if names["Bash"] || names["SelfImprove"] || !names["Read"] || !names["Git"] || !names["Git2"] {
+		t.Errorf("Unexpected tools in read-only mode %v", names)
+	}
+	if r.Enabled("", "Bash") {
+		t.Error("Expected Bash to be disabled in read-only mode")
+	}
+
+	result, _ := e.ExecuteToolCall(context.Background(), calls("Bash", "rm -rf /")[0])
+	if !result.IsError || !strings.Contains(result.Content, "disabled in read-only mode") {
+		t.Errorf("Expected the read-only stub, got %+v", result)
+	}
+	if result, _ := e.ExecuteToolCall(context.Background(), calls("Git2", "push")[0]); !result.IsError {
+		t.Errorf("Expected a mutating call to be blocked, got %+v", result)
+	}
+	if result, _ := e.ExecuteToolCall(context.Background(), calls("Git2", "status")[0]); result.Content != "Git2:status" {
+		t.Errorf("Expected a read-only call to run, got %+v", result)
+	}
+
+	r.SetReadOnly(false)
+	if result, _ := e.ExecuteToolCall(context.Background(), calls("Bash", "ls")[0]); result.Content != "Bash:ls" {
+		t.Errorf("Expected Bash to run once read-only mode is off, got %+v", result)
+	}
+}
@@ -0,0 +1,82 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+)
+
+// ReadTracker records the files a session has seen, so tools that change
+// files can tell an informed overwrite from a blind one. Share one per
+// session. Safe for concurrent use; a nil tracker records nothing.
+type ReadTracker struct {
+	mu    sync.Mutex
+	paths map[string]bool
+}
+
+// NewReadTracker creates an empty tracker
+func NewReadTracker() *ReadTracker {
+	return &ReadTracker{paths: make(map[string]bool)}
+}
+
+// MarkRead records that path was seen
+func (t *ReadTracker) MarkRead(path string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paths[cleanTrackedPath(path)] = true
+}
+
+// WasRead reports whether path was seen
+func (t *ReadTracker) WasRead(path string) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paths[cleanTrackedPath(path)]
+}
+
+// Reset forgets every path, e.g. when a conversation is cleared
+func (t *ReadTracker) Reset() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.paths = make(map[string]bool)
+}
+
+func cleanTrackedPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// PathReporter is implemented by tools that show the model files, by
+// content or by name. After a successful call the executor records the
+// paths in the session's ReadTracker.
+type PathReporter interface {
+	// ReportedPaths returns the files a call with args showed
+	ReportedPaths(args json.RawMessage, result Result) []string
+}
+
+type readTrackerKey struct{}
+
+// WithReadTracker returns a context that carries the session's tracker
+func WithReadTracker(ctx context.Context, t *ReadTracker) context.Context {
+	if t == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, readTrackerKey{}, t)
+}
+
+// ReadTrackerFromContext returns the session's tracker, or nil if none is set
+func ReadTrackerFromContext(ctx context.Context) *ReadTracker {
+	t, _ := ctx.Value(readTrackerKey{}).(*ReadTracker)
+	return t
+}
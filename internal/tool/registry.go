@@ -9,14 +9,24 @@ import (
 
 // Registry manages tool registration and lookup
 type Registry struct {
-	mu    sync.RWMutex
-	tools map[string]Tool
+	mu       sync.RWMutex
+	tools    map[string]Tool
+	policy   *Policy
+	readOnly bool     // see SetReadOnly
+	approval []string // tools needing approval, nil when the gate is off; see RequireApproval
+	// removed remembers unregistered names so calls to them can be told
+	// apart from calls to tools that never existed
+	removed map[string]bool
+	// subscribers are notified when the set of tools changes
+	subscribers map[chan struct{}]struct{}
 }
 
 // NewRegistry creates a new tool registry
 func NewRegistry() *Registry {
 	return &Registry{
-		tools: make(map[string]Tool),
+		tools:       make(map[string]Tool),
+		removed:     make(map[string]bool),
+		subscribers: make(map[chan struct{}]struct{}),
 	}
 }
 
@@ -31,9 +41,79 @@ func (r *Registry) Register(tool Tool) error {
 	}
 
 	r.tools[name] = tool
+	delete(r.removed, name)
+	r.notifyLocked()
 	return nil
 }
 
+// Replace registers a tool, overwriting any tool with the same name
+func (r *Registry) Replace(tool Tool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := tool.Name()
+	r.tools[name] = tool
+	delete(r.removed, name)
+	r.notifyLocked()
+}
+
+// Unregister removes a tool, reporting whether it was registered
+func (r *Registry) Unregister(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tools[name]; !ok {
+		return false
+	}
+	delete(r.tools, name)
+	r.removed[name] = true
+	r.notifyLocked()
+	return true
+}
+
+// Subscribe returns a channel that receives a value after the registered
+// tools or the policy change. Notifications are coalesced: a subscriber
+// that falls behind sees one pending value. Call cancel to unsubscribe.
+func (r *Registry) Subscribe() (changes <-chan struct{}, cancel func()) {
+	ch := make(chan struct{}, 1)
+	r.mu.Lock()
+	r.subscribers[ch] = struct{}{}
+	r.mu.Unlock()
+
+	return ch, func() {
+		r.mu.Lock()
+		delete(r.subscribers, ch)
+		r.mu.Unlock()
+	}
+}
+
+// NotifyChanged tells subscribers the tool list changed for a reason the
+// registry cannot see, such as a plugin being disabled
+func (r *Registry) NotifyChanged() {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	r.notifyLocked()
+}
+
+// notifyLocked signals subscribers without blocking. The caller must hold
+// r.mu.
+func (r *Registry) notifyLocked() {
+	for ch := range r.subscribers {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// WasRemoved reports whether the named tool was unregistered and not
+// registered again
+func (r *Registry) WasRemoved(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.removed[name]
+}
+
 // Get retrieves a tool by name
 func (r *Registry) Get(name string) (Tool, bool) {
 	r.mu.RLock()
@@ -55,47 +135,80 @@ func (r *Registry) List() []Tool {
 	return tools
 }
 
-// ToClientTools converts registered tools to client.Tool format
-func (r *Registry) ToClientTools() []client.Tool {
+// SetPolicy restricts the tools offered to the model and executed; nil
+// removes all restrictions
+func (r *Registry) SetPolicy(p *Policy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = p
+	r.notifyLocked()
+}
+
+// Policy returns the current policy, or nil when unrestricted
+func (r *Registry) Policy() *Policy {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
+	return r.policy
+}
 
-	tools := make([]client.Tool, 0, len(r.tools))
-	for _, t := range r.tools {
-		tools = append(tools, client.Tool{
-			Type: "function",
-			Function: client.FunctionSchema{
-				Name:        t.Name(),
-				Description: t.Description(),
-				Parameters:  t.Parameters(),
-			},
-		})
+// Enabled reports whether the policy allows the named tool in mode, and
+// read-only mode does not hide it
+func (r *Registry) Enabled(mode, name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.readOnly && containsTool(ReadOnlyBlocked, name) {
+		return false
 	}
-	return tools
+	return r.policy.Allows(mode, name)
+}
+
+// ToClientTools converts registered tools to client.Tool format, leaving
+// out tools the policy denies
+func (r *Registry) ToClientTools() []client.Tool {
+	return r.ToClientToolsForMode("")
 }
 
 // ToClientToolsFiltered returns only specified tools
 func (r *Registry) ToClientToolsFiltered(names []string) []client.Tool {
+	return r.ToClientToolsForMode("", names...)
+}
+
+// ToClientToolsForMode returns the available tools the policy allows in
+// mode, limited to names when any are given
+func (r *Registry) ToClientToolsForMode(mode string, names ...string) []client.Tool {
+	// Snapshot under the lock; tool methods run without it
 	r.mu.RLock()
-	defer r.mu.RUnlock()
+	snapshot := make([]Tool, 0, len(r.tools))
+	for _, t := range r.tools {
+		snapshot = append(snapshot, t)
+	}
+	policy, readOnly := r.policy, r.readOnly
+	r.mu.RUnlock()
 
 	nameSet := make(map[string]bool)
 	for _, n := range names {
 		nameSet[n] = true
 	}
 
-	tools := make([]client.Tool, 0)
-	for _, t := range r.tools {
-		if nameSet[t.Name()] {
-			tools = append(tools, client.Tool{
-				Type: "function",
-				Function: client.FunctionSchema{
-					Name:        t.Name(),
-					Description: t.Description(),
-					Parameters:  t.Parameters(),
-				},
-			})
+	tools := make([]client.Tool, 0, len(snapshot))
+	for _, t := range snapshot {
+		if len(names) > 0 && !nameSet[t.Name()] {
+			continue
+		}
+		if !policy.Allows(mode, t.Name()) || (readOnly && containsTool(ReadOnlyBlocked, t.Name())) {
+			continue
+		}
+		if a, ok := t.(Availability); ok && !a.Available() {
+			continue
 		}
+		tools = append(tools, client.Tool{
+			Type: "function",
+			Function: client.FunctionSchema{
+				Name:        t.Name(),
+				Description: t.Description(),
+				Parameters:  t.Parameters(),
+			},
+		})
 	}
 	return tools
 }
@@ -0,0 +1,120 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+)
+
+type namedTool struct{ name, desc string }
+
+func (t *namedTool) Name() string               { return t.name }
+func (t *namedTool) Description() string        { return t.desc }
+func (t *namedTool) Parameters() map[string]any { return map[string]any{"type": "object"} }
+
+func (t *namedTool) Execute(ctx context.Context, args json.RawMessage) (Result, error) {
+	return NewResult(t.name), nil
+}
+
+func TestRegistryConcurrentChanges(t *testing.T) {
+	r := NewRegistry()
+	changes, cancel := r.Subscribe()
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				name := fmt.Sprintf("tool_%d_%d", w, i%10)
+				r.Replace(&namedTool{name: name})
+				r.Unregister(name)
+				r.Register(&namedTool{name: name})
+			}
+		}(w)
+	}
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				for _, tl := range r.ToClientTools() {
+					if tl.Function.Name == "" {
+						t.Error("Expected named tools in snapshot")
+					}
+				}
+				r.List()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := len(r.ToClientTools()); n != 40 {
+		t.Errorf("Expected 40 tools after the churn, got %d", n)
+	}
+	select {
+	case <-changes:
+	default:
+		t.Error("Expected a change notification")
+	}
+	// Notifications coalesce into one pending value
+	select {
+	case <-changes:
+		t.Error("Expected notifications to be coalesced")
+	default:
+	}
+}
+
+func TestRegistryReplaceAndSubscribe(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&namedTool{name: "A", desc: "old"})
+
+	changes, cancel := r.Subscribe()
+	r.Replace(&namedTool{name: "A", desc: "new"})
+	select {
+	case <-changes:
+	case <-time.After(time.Second):
+		t.Fatal("Expected notification on Replace")
+	}
+	if tl, _ := r.Get("A"); tl.Description() != "new" {
+		t.Errorf("Expected replaced tool, got %q", tl.Description())
+	}
+
+	cancel()
+	r.Unregister("A")
+	select {
+	case <-changes:
+		t.Error("Expected no notification after cancel")
+	default:
+	}
+	if r.Unregister("A") {
+		t.Error("Expected second Unregister to report false")
+	}
+}
+
+func TestExecuteUnregisteredTool(t *testing.T) {
+	r := NewRegistry()
+	r.Register(&namedTool{name: "Gone"})
+	e := NewExecutor(r)
+	r.Unregister("Gone")
+
+	call := func(name string) Result {
+		res, err := e.ExecuteToolCall(context.Background(), client.ToolCall{ID: "1", Function: client.FunctionCall{Name: name}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	if res := call("Gone"); !res.IsError || res.Content != "tool Gone is no longer available" {
+		t.Errorf("Expected no longer available result, got %+v", res)
+	}
+	if res := call("Never"); !res.IsError || res.Content != "unknown tool: Never" {
+		t.Errorf("Expected unknown tool result, got %+v", res)
+	}
+}
@@ -0,0 +1,171 @@
+package tool
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/ids"
+	"groq-go/internal/textutil"
+)
+
+// Defaults for the size of tool results sent to the model. Variables so
+// the config can change them before executors are created.
+var (
+	// MaxResultBytes caps one result; longer output keeps its head and tail
+	MaxResultBytes = 16 << 10
+	// MaxTurnResultBytes caps the results of all calls in one turn; once it
+	// is reached, further results are replaced by a note
+	MaxTurnResultBytes = 128 << 10
+)
+
+const (
+	// smallResultBytes is the size under which a result is kept even when
+	// the turn budget is used up: confirmations like "File written" are
+	// shorter than the note that would replace them
+	smallResultBytes = 512
+	// spoolRetention is how long full outputs are kept for follow-up Reads
+	spoolRetention = 24 * time.Hour
+)
+
+// turnBudget is the tool output one turn may add to the history, shared
+// by its calls, see Executor.StartTurn
+type turnBudget struct {
+	mu    sync.Mutex
+	limit int
+	used  int
+}
+
+type turnBudgetKey struct{}
+
+// StartTurn returns a context whose tool calls share one output budget of
+// the executor's turn limit. Call it once per user message.
+func (e *Executor) StartTurn(ctx context.Context) context.Context {
+	if e == nil || e.turnLimit <= 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, turnBudgetKey{}, &turnBudget{limit: e.turnLimit})
+}
+
+// admit charges n bytes to the budget. Once the budget is used up only
+// small results are admitted; the result that crosses the limit still is.
+func (b *turnBudget) admit(n int) bool {
+	if b == nil {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.used >= b.limit && n > smallResultBytes {
+		return false
+	}
+	b.used += n
+	return true
+}
+
+// SetResultLimits changes the per-result and per-turn caps on tool output
+// sent to the model; 0 disables a cap
+func (e *Executor) SetResultLimits(perResult, perTurn int) {
+	e.resultLimit, e.turnLimit = perResult, perTurn
+}
+
+// fitResult applies the result caps. Output that is cut or replaced is
+// saved whole to a spool file whose path is given to the model, so it can
+// Read the parts it needs.
+func (e *Executor) fitResult(ctx context.Context, tc client.ToolCall, result Result) Result {
+	full := result.Content
+	var path string
+	if e.resultLimit > 0 && len(full) > e.resultLimit {
+		path = spoolResult(ctx, tc.Function.Name, full)
+		result.Content = elide(full, e.resultLimit, path)
+	}
+
+	budget, _ := ctx.Value(turnBudgetKey{}).(*turnBudget)
+	if budget.admit(len(result.Content)) {
+		return result
+	}
+	if path == "" {
+		path = spoolResult(ctx, tc.Function.Name, full)
+	}
+	note := fmt.Sprintf("[%s returned %d bytes, but the tool output budget for this turn (%d bytes) is used up. "+
+		"Narrow the request, e.g. a more specific pattern or path, or Read with offset and limit.", tc.Function.Name, len(full), budget.limit)
+	if path != "" {
+		note += " The full output is in " + path + "."
+	}
+	result.Content = note + "]"
+	return result
+}
+
+// elide cuts content to about limit bytes, keeping its head and tail at
+// line breaks where possible, with a marker naming the spool file between
+func elide(content string, limit int, path string) string {
+	marker := func(elided int) string {
+		m := fmt.Sprintf("\n\n[... %d bytes elided", elided)
+		if path != "" {
+			m += "; the full output is in " + path + ", Read it with offset and limit for the rest"
+		}
+		return m + " ...]\n\n"
+	}
+
+	// Size the cut for the longest marker, the one naming every byte
+	keep := max(limit-len(marker(len(content))), 0)
+	headEnd := textutil.RuneStart(content, keep/2)
+	if i := strings.LastIndexByte(content[:headEnd], '\n'); i > headEnd/2 {
+		headEnd = i + 1
+	}
+	tailStart := textutil.RuneStart(content, len(content)-(keep-keep/2))
+	if i := strings.IndexByte(content[tailStart:], '\n'); i >= 0 && i < (len(content)-tailStart)/2 {
+		tailStart += i + 1
+	}
+	return content[:headEnd] + marker(tailStart-headEnd) + content[tailStart:]
+}
+
+// spoolDir is where full outputs are saved: inside the project when file
+// tools are confined to one, so Read may open them, the temp dir otherwise
+func spoolDir(ctx context.Context) string {
+	if root := SandboxFromContext(ctx).Root(); root != "" {
+		return filepath.Join(root, ".groq-go", "results")
+	}
+	return filepath.Join(os.TempDir(), "groq-go-results")
+}
+
+// spoolResult saves content and returns its path, or "" if it could not
+// be saved. Outputs older than spoolRetention are removed on the way.
+func spoolResult(ctx context.Context, toolName, content string) string {
+	dir := spoolDir(ctx)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		log.Warn("Failed to save full tool output", "tool", toolName, "error", err)
+		return ""
+	}
+	// Keep spooled outputs out of the project's git status
+	ignore := filepath.Join(dir, ".gitignore")
+	if _, err := os.Stat(ignore); os.IsNotExist(err) {
+		os.WriteFile(ignore, []byte("*\n"), 0600)
+	}
+	if entries, err := os.ReadDir(dir); err == nil {
+		cutoff := time.Now().Add(-spoolRetention)
+		for _, entry := range entries {
+			if entry.Name() == ".gitignore" {
+				continue
+			}
+			if info, err := entry.Info(); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(filepath.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	name := strings.ToLower(toolName)
+	if strings.ContainsAny(name, `/\`) || name == "" {
+		name = "tool"
+	}
+	path := filepath.Join(dir, name+"-"+ids.Lower(10)+".txt")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		log.Warn("Failed to save full tool output", "tool", toolName, "error", err)
+		return ""
+	}
+	return path
+}
@@ -0,0 +1,143 @@
+package tool
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"groq-go/internal/client"
+)
+
+// bigTool returns the number of numbered lines given as its argument
+type bigTool struct{}
+
+func (bigTool) Name() string               { return "Big" }
+func (bigTool) Description() string        { return "" }
+func (bigTool) Parameters() map[string]any { return nil }
+
+func (bigTool) Execute(ctx context.Context, args json.RawMessage) (Result, error) {
+	var n int
+	json.Unmarshal(args, &n)
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "line %05d: héllo wörld\n", i)
+	}
+	return NewResult(b.String()), nil
+}
+
+func newResultsTestExecutor(t *testing.T, perResult, perTurn int) (*Executor, context.Context) {
+	t.Helper()
+	r := NewRegistry()
+	r.Register(bigTool{})
+	e := NewExecutor(r)
+	e.SetResultLimits(perResult, perTurn)
+	sandbox, err := NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e, WithSandbox(context.Background(), sandbox)
+}
+
+func bigCall(lines int) client.ToolCall {
+	return client.ToolCall{ID: "call_big", Function: client.FunctionCall{Name: "Big", Arguments: fmt.Sprint(lines)}}
+}
+
+var spoolPathPattern = regexp.MustCompile(`\S+/\.groq-go/results/big-\w+\.txt`)
+
+// spooled returns the file named in content and its contents
+func spooled(t *testing.T, content string) (string, string) {
+	t.Helper()
+	path := spoolPathPattern.FindString(content)
+	if path == "" {
+		t.Fatalf("Expected a spool file path in %q", content)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path, string(data)
+}
+
+func TestResultIsElided(t *testing.T) {
+	e, ctx := newResultsTestExecutor(t, 4<<10, 0)
+	full, _ := bigTool{}.Execute(ctx, json.RawMessage("2000"))
+
+	result, err := e.ExecuteToolCall(ctx, bigCall(2000))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := result.Content
+	if len(got) > 4<<10 {
+		t.Errorf("Expected at most 4 KB, got %d bytes", len(got))
+	}
+	if !strings.HasPrefix(got, "line 00001:") || !strings.HasSuffix(got, "line 02000: héllo wörld\n") {
+		t.Errorf("Expected the head and tail kept, got %q...%q", got[:40], got[len(got)-40:])
+	}
+	if !strings.Contains(got, "bytes elided") || !utf8.ValidString(got) {
+		t.Errorf("Expected a marker and valid UTF-8, got %q", got)
+	}
+	// Cuts fall on line breaks, so no line is kept in part
+	head, _, _ := strings.Cut(got, "\n\n[...")
+	if !strings.HasSuffix(head, "wörld\n") {
+		t.Errorf("Expected the head to end at a line break, got %q", head[len(head)-40:])
+	}
+
+	path, data := spooled(t, got)
+	if data != full.Content {
+		t.Errorf("Expected the whole output in %s, got %d of %d bytes", path, len(data), len(full.Content))
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), ".gitignore")); err != nil {
+		t.Errorf("Expected the spool dir ignored by git: %v", err)
+	}
+
+	small, _ := e.ExecuteToolCall(ctx, bigCall(3))
+	if strings.Contains(small.Content, "elided") {
+		t.Errorf("Expected a small result untouched, got %q", small.Content)
+	}
+}
+
+func TestTurnBudget(t *testing.T) {
+	e, ctx := newResultsTestExecutor(t, 4<<10, 10<<10)
+	turn := e.StartTurn(ctx)
+
+	// Three results of about 4 KB fit the 10 KB budget up to the one that
+	// crosses it; the next is replaced by a note
+	for i := range 3 {
+		if r, _ := e.ExecuteToolCall(turn, bigCall(500)); !strings.HasPrefix(r.Content, "line 00001") {
+			t.Fatalf("Expected call %d within the budget, got %q", i+1, r.Content)
+		}
+	}
+	over, _ := e.ExecuteToolCall(turn, bigCall(500))
+	if !strings.Contains(over.Content, "budget for this turn") || strings.Contains(over.Content, "line 00001") {
+		t.Errorf("Expected a note instead of the result, got %q", over.Content)
+	}
+	if _, data := spooled(t, over.Content); !strings.HasPrefix(data, "line 00001") {
+		t.Errorf("Expected the replaced output spooled, got %q", data)
+	}
+
+	// Short results still get through
+	if r, _ := e.ExecuteToolCall(turn, bigCall(2)); !strings.HasPrefix(r.Content, "line 00001") {
+		t.Errorf("Expected a small result kept, got %q", r.Content)
+	}
+	// A new turn starts over
+	if r, _ := e.ExecuteToolCall(e.StartTurn(ctx), bigCall(500)); !strings.HasPrefix(r.Content, "line 00001") {
+		t.Errorf("Expected a fresh budget for the next turn, got %q", r.Content)
+	}
+}
+
+func TestResultLimitsDisabled(t *testing.T) {
+	e, ctx := newResultsTestExecutor(t, 0, 0)
+	r, _ := e.ExecuteToolCall(e.StartTurn(ctx), bigCall(2000))
+	if strings.Contains(r.Content, "elided") || strings.Count(r.Content, "\n") != 2000 {
+		t.Errorf("Expected the whole output with limits off, got %d bytes", len(r.Content))
+	}
+	if _, err := os.Stat(filepath.Join(SandboxFromContext(ctx).Root(), ".groq-go")); !os.IsNotExist(err) {
+		t.Error("Expected nothing spooled")
+	}
+}
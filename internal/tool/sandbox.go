@@ -0,0 +1,152 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// SandboxRootEnv advertises the sandbox root to commands run by Bash
+const SandboxRootEnv = "GROQ_PROJECT_ROOT"
+
+// ErrOutsideSandbox is returned for paths that leave the sandbox root
+var ErrOutsideSandbox = errors.New("path is outside the project directory")
+
+// Sandbox confines file tools to a project's directory tree. Paths are
+// resolved against the root and rejected when they, or the symlinks they
+// pass through, lead outside it. A nil Sandbox allows every path.
+type Sandbox struct {
+	root     string // Absolute and clean, as configured
+	realRoot string // root with symlinks resolved
+}
+
+// NewSandbox returns a sandbox rooted at dir, which must exist
+func NewSandbox(dir string) (*Sandbox, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox root: %w", err)
+	}
+	info, err := os.Stat(realRoot)
+	if err != nil {
+		return nil, fmt.Errorf("sandbox root: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("sandbox root %s is not a directory", root)
+	}
+	return &Sandbox{root: root, realRoot: realRoot}, nil
+}
+
+// Root returns the sandbox root, or "" for a nil sandbox
+func (s *Sandbox) Root() string {
+	if s == nil {
+		return ""
+	}
+	return s.root
+}
+
+// Abs joins a relative path to the root; absolute paths are only cleaned.
+// It does not check the result, see Resolve.
+func (s *Sandbox) Abs(path string) string {
+	if s == nil || filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(s.root, path)
+}
+
+// Resolve returns path made absolute against the root, or an error
+// wrapping ErrOutsideSandbox when it leads outside the root. Symlinks in
+// the existing part of the path are followed before checking, so a link
+// to ~/.ssh is caught; the rest may not exist yet, as for a new file.
+// A nil sandbox returns path unchanged.
+func (s *Sandbox) Resolve(path string) (string, error) {
+	if s == nil {
+		return path, nil
+	}
+	abs := s.Abs(path)
+	real, err := resolveExisting(abs, 0)
+	if err != nil {
+		return "", err
+	}
+	if !within(s.realRoot, real) {
+		return "", fmt.Errorf("%w: %s is not under %s", ErrOutsideSandbox, path, s.root)
+	}
+	return abs, nil
+}
+
+// Contains reports whether path resolves to a place under the root
+func (s *Sandbox) Contains(path string) bool {
+	_, err := s.Resolve(path)
+	return err == nil
+}
+
+// Command runs cmd in the root and sets SandboxRootEnv in its environment.
+// A nil sandbox leaves cmd alone.
+func (s *Sandbox) Command(cmd *exec.Cmd) {
+	if s == nil {
+		return
+	}
+	cmd.Dir = s.root
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, SandboxRootEnv+"="+s.root)
+}
+
+// resolveExisting evaluates the symlinks in the longest existing prefix
+// of path and appends the remainder. A dangling symlink is followed by
+// hand so its target is still checked.
+func resolveExisting(path string, depth int) (string, error) {
+	var rest []string
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(append([]string{real}, rest...)...), nil
+		}
+		if target, err := os.Readlink(dir); err == nil {
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(filepath.Dir(dir), target)
+			}
+			if depth >= 40 {
+				return "", fmt.Errorf("too many levels of symbolic links in %s", path)
+			}
+			return resolveExisting(filepath.Join(append([]string{target}, rest...)...), depth+1)
+		}
+		if dir == filepath.Dir(dir) {
+			return path, nil
+		}
+		rest = append([]string{filepath.Base(dir)}, rest...)
+	}
+}
+
+// within reports whether path is root or below it
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+type sandboxKey struct{}
+
+// WithSandbox returns a context whose tool calls are confined to s. A nil
+// sandbox leaves ctx unconfined.
+func WithSandbox(ctx context.Context, s *Sandbox) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, sandboxKey{}, s)
+}
+
+// SandboxFromContext returns the sandbox set with WithSandbox, or nil
+func SandboxFromContext(ctx context.Context) *Sandbox {
+	s, _ := ctx.Value(sandboxKey{}).(*Sandbox)
+	return s
+}
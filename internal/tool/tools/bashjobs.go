@@ -0,0 +1,310 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/tool"
+)
+
+// headTailBuffer keeps the start and the end of a command's output within
+// a size limit and counts what falls in between. Safe for concurrent use,
+// so a background job can be read while it writes.
+type headTailBuffer struct {
+	mu      sync.Mutex
+	headCap int
+	tailCap int
+	head    []byte
+	tail    []byte
+	total   int
+}
+
+// newHeadTailBuffer returns a buffer holding at most limit bytes, split
+// evenly between head and tail
+func newHeadTailBuffer(limit int) *headTailBuffer {
+	return &headTailBuffer{headCap: limit / 2, tailCap: limit - limit/2}
+}
+
+func (b *headTailBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.total += len(p)
+	rest := p
+	if room := b.headCap - len(b.head); room > 0 {
+		n := min(room, len(rest))
+		b.head = append(b.head, rest[:n]...)
+		rest = rest[n:]
+	}
+	if len(rest) >= b.tailCap {
+		b.tail = append(b.tail[:0], rest[len(rest)-b.tailCap:]...)
+	} else if len(rest) > 0 {
+		b.tail = append(b.tail, rest...)
+		if over := len(b.tail) - b.tailCap; over > 0 {
+			b.tail = b.tail[:copy(b.tail, b.tail[over:])]
+		}
+	}
+	return len(p), nil
+}
+
+// Len returns how many bytes were written in total
+func (b *headTailBuffer) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.total
+}
+
+// String returns the output, with a marker where the middle was dropped
+func (b *headTailBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	elided := b.total - len(b.head) - len(b.tail)
+	if elided == 0 {
+		return string(b.head) + string(b.tail)
+	}
+	marker := fmt.Sprintf("\n... (%d bytes of output elided) ...\n", elided)
+	return strings.ToValidUTF8(string(b.head)+marker+string(b.tail), "")
+}
+
+// Background jobs. The table is per process; StopBackgroundJobs kills
+// what is still running when the process shuts down.
+const (
+	// maxRunningJobs bounds background jobs running at once
+	maxRunningJobs = 8
+	// maxKeptJobs bounds jobs remembered, finished ones are dropped oldest first
+	maxKeptJobs = 32
+	// jobOutputLimit is the output kept per job
+	jobOutputLimit = 30000
+	// backgroundJobTimeout is how long a job may run before it is killed
+	backgroundJobTimeout = time.Hour
+	// jobKillWait bounds waiting for a killed job to exit
+	jobKillWait = 5 * time.Second
+)
+
+var errTooManyJobs = fmt.Errorf("too many background jobs running (max %d); wait for one or kill it with BashOutput", maxRunningJobs)
+
+// bashJob is a command running in the background
+type bashJob struct {
+	id      string
+	owner   string // Who started the job, see jobOwner
+	command string
+	started time.Time
+	output  *headTailBuffer
+	cancel  context.CancelFunc
+	done    chan struct{} // Closed once the command has exited
+
+	mu       sync.Mutex
+	finished time.Time
+	err      error
+	killed   bool
+	timedOut bool
+}
+
+// status describes the job's state for the model
+func (j *bashJob) status() string {
+	select {
+	case <-j.done:
+	default:
+		return fmt.Sprintf("running for %s", time.Since(j.started).Round(time.Second))
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	took := j.finished.Sub(j.started).Round(time.Millisecond)
+	switch {
+	case j.killed:
+		return fmt.Sprintf("killed after %s", took)
+	case j.timedOut:
+		return fmt.Sprintf("timed out after %s", took)
+	case j.err != nil:
+		var exitErr *exec.ExitError
+		if errors.As(j.err, &exitErr) {
+			return fmt.Sprintf("exited with code %d after %s", exitErr.ExitCode(), took)
+		}
+		return fmt.Sprintf("failed after %s: %v", took, j.err)
+	default:
+		return fmt.Sprintf("exited with code 0 after %s", took)
+	}
+}
+
+// running reports whether the command has not exited yet
+func (j *bashJob) running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// kill stops the job's process group and waits briefly for it to exit
+func (j *bashJob) kill() {
+	if !j.running() {
+		return
+	}
+	j.mu.Lock()
+	j.killed = true
+	j.mu.Unlock()
+	j.cancel()
+	select {
+	case <-j.done:
+	case <-time.After(jobKillWait):
+	}
+}
+
+// jobTable tracks the background jobs of this process
+type jobTable struct {
+	mu    sync.Mutex
+	jobs  map[string]*bashJob
+	order []string // IDs in start order
+	next  int
+}
+
+var bashJobs = &jobTable{jobs: make(map[string]*bashJob)}
+
+// jobOwner returns who tool calls made with ctx run for: the user, or
+// the session when there is no user. Jobs are only visible to their
+// owner; without a caller (the REPL) the owner is empty.
+func jobOwner(ctx context.Context) string {
+	c, _ := tool.CallerFromContext(ctx)
+	if c.User != "" {
+		return c.User
+	}
+	return c.SessionID
+}
+
+// start runs command in the background for owner, in the sandbox's root
+// if there is one
+func (t *jobTable) start(owner, command string, timeout time.Duration, sandbox *tool.Sandbox) (*bashJob, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	running := 0
+	for _, j := range t.jobs {
+		if j.running() {
+			running++
+		}
+	}
+	if running >= maxRunningJobs {
+		return nil, errTooManyJobs
+	}
+
+	// Jobs outlive the tool call that started them
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	cmd := exec.CommandContext(ctx, "bash", "-c", command)
+	cmd.WaitDelay = time.Second
+	sandbox.Command(cmd)
+	setProcessGroup(cmd)
+	out := newHeadTailBuffer(jobOutputLimit)
+	// One writer for both streams keeps them interleaved as in a terminal
+	cmd.Stdout = out
+	cmd.Stderr = out
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+
+	t.next++
+	j := &bashJob{
+		id:      fmt.Sprintf("bash_%d", t.next),
+		owner:   owner,
+		command: command,
+		started: time.Now(),
+		output:  out,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	go func() {
+		err := cmd.Wait()
+		j.mu.Lock()
+		j.finished = time.Now()
+		j.err = err
+		j.timedOut = ctx.Err() == context.DeadlineExceeded
+		j.mu.Unlock()
+		cancel()
+		close(j.done)
+	}()
+
+	t.jobs[j.id] = j
+	t.order = append(t.order, j.id)
+	t.prune()
+	return j, nil
+}
+
+// prune forgets the oldest finished jobs beyond maxKeptJobs
+func (t *jobTable) prune() {
+	for i := 0; len(t.order) > maxKeptJobs && i < len(t.order); {
+		id := t.order[i]
+		if t.jobs[id].running() {
+			i++
+			continue
+		}
+		delete(t.jobs, id)
+		t.order = append(t.order[:i], t.order[i+1:]...)
+	}
+}
+
+// get returns owner's job by ID; other owners' jobs are not found
+func (t *jobTable) get(id, owner string) (*bashJob, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	j, ok := t.jobs[id]
+	if !ok || j.owner != owner {
+		return nil, false
+	}
+	return j, true
+}
+
+// list returns owner's jobs in start order
+func (t *jobTable) list(owner string) []*bashJob {
+	var jobs []*bashJob
+	for _, j := range t.all() {
+		if j.owner == owner {
+			jobs = append(jobs, j)
+		}
+	}
+	return jobs
+}
+
+// all returns every job in start order, whoever owns it
+func (t *jobTable) all() []*bashJob {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	jobs := make([]*bashJob, 0, len(t.order))
+	for _, id := range t.order {
+		jobs = append(jobs, t.jobs[id])
+	}
+	return jobs
+}
+
+// stopAll kills every running job and returns how many there were
+func (t *jobTable) stopAll() int {
+	var wg sync.WaitGroup
+	n := 0
+	for _, j := range t.all() {
+		if !j.running() {
+			continue
+		}
+		n++
+		wg.Add(1)
+		go func(j *bashJob) {
+			defer wg.Done()
+			j.kill()
+		}(j)
+	}
+	wg.Wait()
+	return n
+}
+
+// StopBackgroundJobs kills the Bash tool's background jobs and returns
+// how many were running. Call it when the process shuts down so no
+// commands are left behind.
+func StopBackgroundJobs() int {
+	return bashJobs.stopAll()
+}
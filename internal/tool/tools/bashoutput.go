@@ -0,0 +1,104 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"groq-go/internal/tool"
+)
+
+// BashOutputTool reports on, and kills, jobs started by Bash with
+// run_in_background
+type BashOutputTool struct{}
+
+type BashOutputArgs struct {
+	JobID string `json:"job_id,omitempty"`
+	Kill  bool   `json:"kill,omitempty"`
+}
+
+func NewBashOutputTool() *BashOutputTool {
+	return &BashOutputTool{}
+}
+
+func (t *BashOutputTool) Name() string {
+	return "BashOutput"
+}
+
+func (t *BashOutputTool) Description() string {
+	return "Shows the status and output so far of a background job started by Bash with run_in_background, or kills it. Without a job_id, lists the background jobs."
+}
+
+func (t *BashOutputTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"job_id": map[string]any{
+				"type":        "string",
+				"description": "The job ID returned by Bash",
+			},
+			"kill": map[string]any{
+				"type":        "boolean",
+				"description": "Kill the job before reporting on it",
+			},
+		},
+	}
+}
+
+func (t *BashOutputTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.Result, error) {
+	var args BashOutputArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("invalid arguments: %v", err)), nil
+	}
+
+	owner := jobOwner(ctx)
+	if args.JobID == "" {
+		if args.Kill {
+			return tool.NewErrorResult("job_id is required to kill a job"), nil
+		}
+		return tool.NewResult(listJobs(owner)), nil
+	}
+
+	j, ok := bashJobs.get(args.JobID, owner)
+	if !ok {
+		return tool.NewErrorResult(fmt.Sprintf("no background job %s\n%s", args.JobID, listJobs(owner))), nil
+	}
+	if args.Kill {
+		j.kill()
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Job %s: %s\n", j.id, j.status())
+	fmt.Fprintf(&sb, "Command: %s\n", j.command)
+	if j.output.Len() == 0 {
+		sb.WriteString("(no output yet)")
+	} else {
+		sb.WriteString("Output:\n")
+		sb.WriteString(j.output.String())
+	}
+	return tool.NewResult(sb.String()), nil
+}
+
+// listJobs describes owner's background jobs, one per line
+func listJobs(owner string) string {
+	jobs := bashJobs.list(owner)
+	if len(jobs) == 0 {
+		return "No background jobs"
+	}
+	var sb strings.Builder
+	sb.WriteString("Background jobs:")
+	for _, j := range jobs {
+		fmt.Fprintf(&sb, "\n%s: %s: %s", j.id, j.status(), truncateCommand(j.command))
+	}
+	return sb.String()
+}
+
+// truncateCommand shortens a command for job listings
+func truncateCommand(command string) string {
+	command = strings.ReplaceAll(command, "\n", " ")
+	if len(command) > 80 {
+		return command[:80] + "..."
+	}
+	return command
+}
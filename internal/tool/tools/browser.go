@@ -4,33 +4,52 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
 	"strings"
 	"time"
 
+	"groq-go/internal/ids"
 	"groq-go/internal/tool"
 )
 
-type BrowserTool struct{}
+// BrowserTool drives Chromium through a persistent Playwright process,
+// falling back to one npx run per call when that cannot start
+type BrowserTool struct {
+	driver *browserDriver
+}
 
 type BrowserArgs struct {
-	URL        string `json:"url"`
+	URL        string `json:"url,omitempty"`
 	Action     string `json:"action"`
+	Session    string `json:"session,omitempty"`
 	Selector   string `json:"selector,omitempty"`
+	Text       string `json:"text,omitempty"`
+	Script     string `json:"script,omitempty"`
 	OutputPath string `json:"output_path,omitempty"`
 }
 
 func NewBrowserTool() *BrowserTool {
-	return &BrowserTool{}
+	return &BrowserTool{driver: sharedBrowser}
 }
 
+// browserTimeout bounds one Browser call
+const browserTimeout = 60 * time.Second
+
+// maxBrowserContent caps the text returned by content and evaluate
+const maxBrowserContent = 50000
+
+// browserSelectorActions need a selector
+var browserSelectorActions = map[string]bool{"click": true, "type": true, "wait_for": true}
+
 func (t *BrowserTool) Name() string {
 	return "Browser"
 }
 
 func (t *BrowserTool) Description() string {
-	return "Control a browser using Playwright. Can take screenshots, get page content with JavaScript rendering, or interact with elements."
+	return "Control a browser using Playwright. Can take screenshots, get page content with JavaScript rendering, click, type, wait for elements and run JavaScript. " +
+		"Pass the same session name across calls to keep one page open for multi-step flows (log in, fill a form, then read the result); close it with action 'close'."
 }
 
 func (t *BrowserTool) Parameters() map[string]any {
@@ -39,74 +58,187 @@ func (t *BrowserTool) Parameters() map[string]any {
 		"properties": map[string]any{
 			"url": map[string]any{
 				"type":        "string",
-				"description": "The URL to navigate to",
+				"description": "The URL to navigate to before the action. Optional with a session, which stays on its current page.",
 			},
 			"action": map[string]any{
 				"type":        "string",
-				"description": "Action to perform: 'screenshot', 'content', 'pdf'",
-				"enum":        []string{"screenshot", "content", "pdf"},
+				"description": "Action to perform: 'screenshot', 'content', 'pdf', 'click', 'type' (fill an input), 'wait_for' (wait until an element is visible), 'evaluate' (run JavaScript and return its result), 'close' (close the session)",
+				"enum":        []string{"screenshot", "content", "pdf", "click", "type", "wait_for", "evaluate", "close"},
+			},
+			"session": map[string]any{
+				"type":        "string",
+				"description": "Name of a page to keep open between calls. Without it each call uses a fresh page.",
 			},
 			"selector": map[string]any{
 				"type":        "string",
-				"description": "CSS selector for screenshot of specific element",
+				"description": "CSS selector: the element to click, type into or wait for, or to screenshot alone",
+			},
+			"text": map[string]any{
+				"type":        "string",
+				"description": "Text to type (for 'type')",
+			},
+			"script": map[string]any{
+				"type":        "string",
+				"description": "JavaScript expression to evaluate in the page (for 'evaluate'), e.g. document.title",
 			},
 			"output_path": map[string]any{
 				"type":        "string",
 				"description": "Output file path for screenshot/pdf (default: /tmp/browser_output.*)",
 			},
 		},
-		"required": []string{"url", "action"},
+		"required": []string{"action"},
 	}
 }
 
+// Timeout outlasts the browser's own 60 second limit
+func (t *BrowserTool) Timeout() time.Duration {
+	return 70 * time.Second
+}
+
 func (t *BrowserTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.Result, error) {
 	var args BrowserArgs
 	if err := json.Unmarshal(argsJSON, &args); err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("invalid arguments: %v", err)), nil
 	}
 
-	if args.URL == "" {
-		return tool.NewErrorResult("url is required"), nil
-	}
-	if args.Action == "" {
+	switch {
+	case args.Action == "":
 		return tool.NewErrorResult("action is required"), nil
+	case args.Action == "close":
+		if args.Session == "" {
+			return tool.NewErrorResult("session is required for close"), nil
+		}
+	case args.URL == "" && args.Session == "":
+		return tool.NewErrorResult("url is required without a session"), nil
+	case browserSelectorActions[args.Action] && args.Selector == "":
+		return tool.NewErrorResult(fmt.Sprintf("selector is required for %s", args.Action)), nil
+	case args.Action == "evaluate" && args.Script == "":
+		return tool.NewErrorResult("script is required for evaluate"), nil
 	}
 
-	// Check if npx is available
-	if _, err := exec.LookPath("npx"); err != nil {
-		return tool.NewErrorResult("npx not found. Please install Node.js to use the Browser tool."), nil
+	switch args.Action {
+	case "screenshot":
+		if args.OutputPath == "" {
+			args.OutputPath = fmt.Sprintf("/tmp/screenshot_%d.png", time.Now().Unix())
+		}
+	case "pdf":
+		if args.OutputPath == "" {
+			args.OutputPath = fmt.Sprintf("/tmp/page_%d.pdf", time.Now().Unix())
+		}
+	case "content", "click", "type", "wait_for", "evaluate", "close":
+	default:
+		return tool.NewErrorResult(fmt.Sprintf("unknown action: %s", args.Action)), nil
 	}
 
-	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	ctx, cancel := context.WithTimeout(ctx, browserTimeout)
 	defer cancel()
+	tool.ReportProgress(ctx, "starting", -1)
+
+	result, err := t.drive(ctx, args)
+	if errors.Is(err, errBrowserUnavailable) {
+		if args.Session != "" || browserSelectorActions[args.Action] || args.Action == "evaluate" {
+			return tool.NewErrorResult(fmt.Sprintf("%s needs the persistent browser, which is not available: %v", args.Action, err)), nil
+		}
+		return t.spawn(ctx, args)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return tool.NewErrorResult(fmt.Sprintf("%s failed: %v", args.Action, err)), nil
+	}
+	return t.format(args, result), nil
+}
+
+// drive runs the action on the persistent driver. Named sessions are kept
+// per caller so users never share pages; without one the call gets a page
+// of its own that is closed afterwards.
+func (t *BrowserTool) drive(ctx context.Context, args BrowserArgs) (json.RawMessage, error) {
+	session := args.Session
+	if session == "" {
+		session = "call-" + ids.Lower(10)
+	}
+	if c, ok := tool.CallerFromContext(ctx); ok {
+		session = c.User + "/" + c.SessionID + "/" + session
+	}
+
+	tool.ReportProgress(ctx, "running "+args.Action, -1)
+	result, err := t.driver.call(ctx, browserRequest{
+		Action:    args.Action,
+		Session:   session,
+		URL:       args.URL,
+		Selector:  args.Selector,
+		Text:      args.Text,
+		Script:    args.Script,
+		Path:      args.OutputPath,
+		TimeoutMs: browserTimeout.Milliseconds(),
+	})
+	if args.Session == "" && !errors.Is(err, errBrowserUnavailable) && !errors.Is(err, errBrowserExited) {
+		// Not bounded by ctx, which may be what ended the call
+		closeCtx, cancel := context.WithTimeout(context.Background(), browserStopTimeout)
+		defer cancel()
+		t.driver.call(closeCtx, browserRequest{Action: "close", Session: session})
+	}
+	return result, err
+}
+
+// format turns the driver's result into the tool's output
+func (t *BrowserTool) format(args BrowserArgs, result json.RawMessage) tool.Result {
+	switch args.Action {
+	case "screenshot":
+		return tool.NewResult(fmt.Sprintf("Screenshot saved to: %s", args.OutputPath))
+	case "pdf":
+		return tool.NewResult(fmt.Sprintf("PDF saved to: %s", args.OutputPath))
+	case "close":
+		return tool.NewResult(fmt.Sprintf("Closed session %s", args.Session))
+	}
+
+	var text string
+	if json.Unmarshal(result, &text) != nil {
+		// evaluate may return any JSON value; undefined comes back as nothing
+		var indented bytes.Buffer
+		if len(result) == 0 || json.Indent(&indented, result, "", "  ") != nil {
+			text = "undefined"
+		} else {
+			text = indented.String()
+		}
+	}
+	if len(text) > maxBrowserContent {
+		text = text[:maxBrowserContent] + "\n... (truncated)"
+	}
+	return tool.NewResult(text)
+}
+
+// spawn runs a one-off action with its own npx process, for when the
+// persistent browser cannot start
+func (t *BrowserTool) spawn(ctx context.Context, args BrowserArgs) (tool.Result, error) {
+	if _, err := exec.LookPath("npx"); err != nil {
+		return tool.NewErrorResult("npx not found. Please install Node.js to use the Browser tool."), nil
+	}
 
 	switch args.Action {
 	case "screenshot":
 		return t.screenshot(ctx, args)
 	case "content":
 		return t.getContent(ctx, args)
-	case "pdf":
-		return t.pdf(ctx, args)
 	default:
-		return tool.NewErrorResult(fmt.Sprintf("unknown action: %s", args.Action)), nil
+		return t.pdf(ctx, args)
 	}
 }
 
 func (t *BrowserTool) screenshot(ctx context.Context, args BrowserArgs) (tool.Result, error) {
 	outputPath := args.OutputPath
-	if outputPath == "" {
-		outputPath = fmt.Sprintf("/tmp/screenshot_%d.png", time.Now().Unix())
-	}
-
 	cmdArgs := []string{"-y", "playwright", "screenshot", args.URL, outputPath}
 	if args.Selector != "" {
 		cmdArgs = append(cmdArgs, "--selector", args.Selector)
 	}
 
 	cmd := exec.CommandContext(ctx, "npx", cmdArgs...)
+	cmd.WaitDelay = time.Second
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
+	tool.ReportProgress(ctx, "capturing screenshot", -1)
 	if err := cmd.Run(); err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("screenshot failed: %v\n%s", err, stderr.String())), nil
 	}
@@ -129,18 +261,20 @@ const { chromium } = require('playwright');
 `, strings.ReplaceAll(args.URL, "'", "\\'"))
 
 	cmd := exec.CommandContext(ctx, "npx", "-y", "-p", "playwright", "node", "-e", script)
+	cmd.WaitDelay = time.Second
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
+	tool.ReportProgress(ctx, "loading page", -1)
 	if err := cmd.Run(); err != nil {
 		// Fallback to simple fetch if playwright fails
 		return tool.NewErrorResult(fmt.Sprintf("content fetch failed: %v\n%s\nTry using WebFetch instead for simple pages.", err, stderr.String())), nil
 	}
 
 	content := stdout.String()
-	if len(content) > 50000 {
-		content = content[:50000] + "\n... (truncated)"
+	if len(content) > maxBrowserContent {
+		content = content[:maxBrowserContent] + "\n... (truncated)"
 	}
 
 	return tool.NewResult(content), nil
@@ -148,14 +282,12 @@ const { chromium } = require('playwright');
 
 func (t *BrowserTool) pdf(ctx context.Context, args BrowserArgs) (tool.Result, error) {
 	outputPath := args.OutputPath
-	if outputPath == "" {
-		outputPath = fmt.Sprintf("/tmp/page_%d.pdf", time.Now().Unix())
-	}
-
 	cmd := exec.CommandContext(ctx, "npx", "-y", "playwright", "pdf", args.URL, outputPath)
+	cmd.WaitDelay = time.Second
 	var stderr bytes.Buffer
 	cmd.Stderr = &stderr
 
+	tool.ReportProgress(ctx, "rendering pdf", -1)
 	if err := cmd.Run(); err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("pdf generation failed: %v\n%s", err, stderr.String())), nil
 	}
@@ -0,0 +1,105 @@
+// Persistent Playwright driver for the Browser tool. It launches Chromium
+// once, prints {"ready":true}, then answers one JSON request per stdin line
+// with one JSON response per stdout line, matched by id. Pages are kept per
+// session so multi-step flows work. Closing stdin shuts it down.
+const readline = require('readline');
+const { chromium } = require('playwright');
+
+const maxSessions = 10;
+
+function send(msg) {
+  process.stdout.write(JSON.stringify(msg) + '\n');
+}
+
+(async () => {
+  let browser;
+  try {
+    browser = await chromium.launch();
+  } catch (e) {
+    send({ ready: false, error: String((e && e.message) || e) });
+    process.exit(1);
+  }
+
+  // Least recently used first
+  const sessions = new Map();
+
+  async function page(name) {
+    let p = sessions.get(name);
+    sessions.delete(name);
+    if (!p || p.isClosed()) {
+      if (sessions.size >= maxSessions) {
+        const [oldest, old] = sessions.entries().next().value;
+        sessions.delete(oldest);
+        await old.context().close().catch(() => {});
+      }
+      const context = await browser.newContext();
+      p = await context.newPage();
+    }
+    sessions.set(name, p);
+    return p;
+  }
+
+  async function handle(req) {
+    if (req.action === 'close') {
+      const p = sessions.get(req.session);
+      sessions.delete(req.session);
+      if (p) await p.context().close();
+      return 'closed';
+    }
+
+    const p = await page(req.session);
+    const timeout = req.timeout_ms || 30000;
+    p.setDefaultTimeout(timeout);
+    if (req.url) {
+      await p.goto(req.url, { waitUntil: 'networkidle', timeout });
+    }
+
+    switch (req.action) {
+      case 'screenshot':
+        if (req.selector) {
+          await p.locator(req.selector).screenshot({ path: req.path });
+        } else {
+          await p.screenshot({ path: req.path, fullPage: true });
+        }
+        return req.path;
+      case 'content':
+        return await p.evaluate(() => document.body.innerText);
+      case 'pdf':
+        await p.pdf({ path: req.path });
+        return req.path;
+      case 'click':
+        await p.click(req.selector);
+        return 'clicked ' + req.selector;
+      case 'type':
+        await p.fill(req.selector, req.text);
+        return 'typed into ' + req.selector;
+      case 'wait_for':
+        await p.waitForSelector(req.selector, { state: 'visible' });
+        return req.selector + ' is visible';
+      case 'evaluate':
+        return await p.evaluate(req.script);
+      default:
+        throw new Error('unknown action: ' + req.action);
+    }
+  }
+
+  send({ ready: true });
+  const rl = readline.createInterface({ input: process.stdin });
+  rl.on('line', async (line) => {
+    let req;
+    try {
+      req = JSON.parse(line);
+    } catch (e) {
+      return;
+    }
+    try {
+      send({ id: req.id, ok: true, result: await handle(req) });
+    } catch (e) {
+      send({ id: req.id, ok: false, error: String((e && e.message) || e) });
+    }
+  });
+  rl.on('close', async () => {
+    await browser.close().catch(() => {});
+    process.exit(0);
+  });
+})();
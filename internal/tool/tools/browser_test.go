@@ -0,0 +1,205 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/tool"
+)
+
+// newFakeBrowser returns a Browser tool backed by testdata/fake_browser_driver.js
+func newFakeBrowser(t *testing.T) *BrowserTool {
+	t.Helper()
+	node, err := exec.LookPath("node")
+	if err != nil {
+		t.Skip("node not available")
+	}
+	script, err := filepath.Abs("testdata/fake_browser_driver.js")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &BrowserTool{driver: newBrowserDriver(node, script)}
+	t.Cleanup(b.driver.stop)
+	return b
+}
+
+func newTestPage(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><h1>Sign in</h1><input id="name"><button id="go">Go</button></body></html>`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func runBrowser(t *testing.T, ctx context.Context, b *BrowserTool, args BrowserArgs) tool.Result {
+	t.Helper()
+	data, _ := json.Marshal(args)
+	result, err := b.Execute(ctx, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestBrowserReusesDriver(t *testing.T) {
+	b := newFakeBrowser(t)
+	srv := newTestPage(t)
+	dir := t.TempDir()
+
+	for i := range 3 {
+		out := filepath.Join(dir, fmt.Sprintf("shot%d.png", i))
+		result := runBrowser(t, context.Background(), b, BrowserArgs{URL: srv.URL, Action: "screenshot", OutputPath: out})
+		if result.IsError {
+			t.Fatal(result.Content)
+		}
+		if _, err := os.Stat(out); err != nil {
+			t.Fatalf("Expected %s written: %v", out, err)
+		}
+	}
+	result := runBrowser(t, context.Background(), b, BrowserArgs{URL: srv.URL, Action: "content"})
+	if result.Content != "Sign inGo" {
+		t.Errorf("Expected the page text, got %q", result.Content)
+	}
+	if n := b.driver.startCount(); n != 1 {
+		t.Errorf("Expected one driver process for all calls, got %d", n)
+	}
+}
+
+func TestBrowserSessions(t *testing.T) {
+	b := newFakeBrowser(t)
+	srv := newTestPage(t)
+	alice := tool.WithCaller(context.Background(), tool.Caller{User: "alice", SessionID: "ws-1"})
+	bob := tool.WithCaller(context.Background(), tool.Caller{User: "bob", SessionID: "ws-2"})
+
+	for _, args := range []BrowserArgs{
+		{URL: srv.URL, Action: "wait_for", Session: "login", Selector: "#name"},
+		{Action: "type", Session: "login", Selector: "#name", Text: "Ada"},
+		{Action: "click", Session: "login", Selector: "#go"},
+	} {
+		if result := runBrowser(t, alice, b, args); result.IsError {
+			t.Fatalf("%s: %s", args.Action, result.Content)
+		}
+	}
+	result := runBrowser(t, alice, b, BrowserArgs{Action: "content", Session: "login"})
+	if !strings.Contains(result.Content, "type #name=Ada\nclick #go") {
+		t.Errorf("Expected the session to keep its page, got %q", result.Content)
+	}
+	result = runBrowser(t, bob, b, BrowserArgs{Action: "content", Session: "login"})
+	if strings.Contains(result.Content, "Ada") {
+		t.Errorf("Expected sessions kept per caller, got %q", result.Content)
+	}
+
+	result = runBrowser(t, alice, b, BrowserArgs{Action: "evaluate", Session: "login", Script: "page.events.length * 2"})
+	if result.Content != "4" {
+		t.Errorf("Expected the script's result, got %q", result.Content)
+	}
+	result = runBrowser(t, alice, b, BrowserArgs{Action: "evaluate", Session: "login", Script: "({title: 'x', n: [1]})"})
+	if !strings.Contains(result.Content, `"title": "x"`) {
+		t.Errorf("Expected the object as JSON, got %q", result.Content)
+	}
+	if result := runBrowser(t, alice, b, BrowserArgs{Action: "wait_for", Session: "login", Selector: "#missing"}); !result.IsError {
+		t.Error("Expected a wait for a missing element to fail")
+	}
+
+	runBrowser(t, alice, b, BrowserArgs{Action: "close", Session: "login"})
+	result = runBrowser(t, alice, b, BrowserArgs{Action: "content", Session: "login"})
+	if strings.Contains(result.Content, "Ada") {
+		t.Errorf("Expected close to drop the page, got %q", result.Content)
+	}
+	if n := b.driver.startCount(); n != 1 {
+		t.Errorf("Expected one driver process, got %d", n)
+	}
+}
+
+func TestBrowserRestartsDriver(t *testing.T) {
+	b := newFakeBrowser(t)
+	srv := newTestPage(t)
+	if result := runBrowser(t, context.Background(), b, BrowserArgs{URL: srv.URL, Action: "content"}); result.IsError {
+		t.Fatal(result.Content)
+	}
+
+	b.driver.mu.Lock()
+	done := b.driver.done
+	b.driver.cmd.Process.Kill()
+	b.driver.mu.Unlock()
+	<-done
+
+	if result := runBrowser(t, context.Background(), b, BrowserArgs{URL: srv.URL, Action: "content"}); result.IsError {
+		t.Fatal(result.Content)
+	}
+	if n := b.driver.startCount(); n != 2 {
+		t.Errorf("Expected the driver restarted once, got %d starts", n)
+	}
+}
+
+func TestBrowserDriverUnavailable(t *testing.T) {
+	b := newFakeBrowser(t)
+	t.Setenv("FAKE_BROWSER_FAIL", "1")
+	t.Setenv("PATH", "") // No npx for the fallback either
+
+	result := runBrowser(t, context.Background(), b, BrowserArgs{URL: "http://example.com", Action: "content"})
+	if !result.IsError || !strings.Contains(result.Content, "npx not found") {
+		t.Errorf("Expected the spawn-per-call fallback, got %q", result.Content)
+	}
+	result = runBrowser(t, context.Background(), b, BrowserArgs{URL: "http://example.com", Action: "click", Selector: "#go"})
+	if !result.IsError || !strings.Contains(result.Content, "persistent browser") {
+		t.Errorf("Expected click refused without the driver, got %q", result.Content)
+	}
+	if n := b.driver.startCount(); n != 1 {
+		t.Errorf("Expected the failed start remembered, got %d starts", n)
+	}
+}
+
+func TestBrowserValidatesArgs(t *testing.T) {
+	b := &BrowserTool{driver: newBrowserDriver()}
+	for _, args := range []BrowserArgs{
+		{URL: "http://example.com"},
+		{Action: "content"},
+		{Action: "close"},
+		{URL: "http://example.com", Action: "click"},
+		{Session: "s", Action: "evaluate"},
+		{URL: "http://example.com", Action: "scroll"},
+	} {
+		if result := runBrowser(t, context.Background(), b, args); !result.IsError {
+			t.Errorf("%+v: expected an error, got %q", args, result.Content)
+		}
+	}
+}
+
+// TestBrowserPlaywright runs the real driver when Playwright is installed
+func TestBrowserPlaywright(t *testing.T) {
+	if err := exec.Command("node", "-e", "require.resolve('playwright')").Run(); err != nil {
+		t.Skip("playwright not installed")
+	}
+	b := &BrowserTool{driver: newBrowserDriver("node", "-e", browserDriverScript)}
+	t.Cleanup(b.driver.stop)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><input id="name"><button id="go" onclick="document.title = 'Hi ' + document.querySelector('#name').value">Go</button></body></html>`)
+	}))
+	defer srv.Close()
+
+	for _, args := range []BrowserArgs{
+		{URL: srv.URL, Action: "type", Session: "s", Selector: "#name", Text: "Ada"},
+		{Action: "click", Session: "s", Selector: "#go"},
+	} {
+		if result := runBrowser(t, context.Background(), b, args); result.IsError {
+			t.Fatalf("%s: %s", args.Action, result.Content)
+		}
+	}
+	result := runBrowser(t, context.Background(), b, BrowserArgs{Action: "evaluate", Session: "s", Script: "document.title"})
+	if result.Content != "Hi Ada" {
+		t.Errorf("Expected the click to run, got %q", result.Content)
+	}
+	if n := b.driver.startCount(); n != 1 {
+		t.Errorf("Expected one driver process, got %d", n)
+	}
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"sync"
+	"time"
+
+	"groq-go/internal/logging"
+)
+
+var log = logging.WithComponent("tools")
+
+//go:embed browser_driver.js
+var browserDriverScript string
+
+const (
+	// browserStartTimeout bounds resolving Playwright and launching Chromium
+	browserStartTimeout = 90 * time.Second
+	// browserRetryAfter is how long a driver that failed to start is not
+	// tried again; calls use the spawn-per-call path meanwhile
+	browserRetryAfter = 5 * time.Minute
+	// browserStopTimeout is how long the driver gets to close Chromium
+	browserStopTimeout = 5 * time.Second
+)
+
+// errBrowserExited is returned for calls in flight when the driver dies
+var errBrowserExited = errors.New("browser driver exited")
+
+// sharedBrowser is the driver behind NewBrowserTool, see StopBrowser
+var sharedBrowser = newBrowserDriver("npx", "-y", "-p", "playwright", "node", "-e", browserDriverScript)
+
+// StopBrowser shuts down the Browser tool's Chromium, if it is running.
+// Call it when the process shuts down.
+func StopBrowser() {
+	sharedBrowser.stop()
+}
+
+// browserRequest is one line sent to the driver
+type browserRequest struct {
+	ID        int64  `json:"id"`
+	Action    string `json:"action"`
+	Session   string `json:"session"`
+	URL       string `json:"url,omitempty"`
+	Selector  string `json:"selector,omitempty"`
+	Text      string `json:"text,omitempty"`
+	Script    string `json:"script,omitempty"`
+	Path      string `json:"path,omitempty"`
+	TimeoutMs int64  `json:"timeout_ms,omitempty"`
+}
+
+// browserResponse is one line received from the driver: the ready
+// message, then the answer to each request
+type browserResponse struct {
+	ID     int64           `json:"id"`
+	Ready  bool            `json:"ready"`
+	OK     bool            `json:"ok"`
+	Result json.RawMessage `json:"result"`
+	Error  string          `json:"error"`
+}
+
+// browserDriver runs a long-lived driver process, started on first use and
+// again after it exits, and multiplexes calls over its stdio
+type browserDriver struct {
+	command []string
+
+	mu        sync.Mutex
+	cmd       *exec.Cmd
+	stdin     io.WriteCloser
+	done      chan struct{} // Closed when the running process exits
+	pending   map[int64]chan browserResponse
+	nextID    int64
+	starts    int // Processes started, for tests
+	failedAt  time.Time
+	failedErr error
+}
+
+func newBrowserDriver(command ...string) *browserDriver {
+	return &browserDriver{command: command}
+}
+
+// call sends req and waits for its answer. It starts the driver if needed,
+// returning an error wrapping errBrowserUnavailable if that fails.
+func (d *browserDriver) call(ctx context.Context, req browserRequest) (json.RawMessage, error) {
+	d.mu.Lock()
+	if err := d.ensureStarted(ctx); err != nil {
+		d.mu.Unlock()
+		return nil, err
+	}
+	d.nextID++
+	req.ID = d.nextID
+	reply := make(chan browserResponse, 1)
+	d.pending[req.ID] = reply
+	stdin, done := d.stdin, d.done
+	line, _ := json.Marshal(req)
+	_, err := stdin.Write(append(line, '\n'))
+	d.mu.Unlock()
+	if err != nil {
+		d.forget(req.ID)
+		return nil, fmt.Errorf("%w: %v", errBrowserExited, err)
+	}
+
+	select {
+	case resp := <-reply:
+		if !resp.OK {
+			return nil, errors.New(resp.Error)
+		}
+		return resp.Result, nil
+	case <-done:
+		d.forget(req.ID)
+		return nil, errBrowserExited
+	case <-ctx.Done():
+		// The driver still answers; the reply is dropped
+		d.forget(req.ID)
+		return nil, ctx.Err()
+	}
+}
+
+func (d *browserDriver) forget(id int64) {
+	d.mu.Lock()
+	delete(d.pending, id)
+	d.mu.Unlock()
+}
+
+// errBrowserUnavailable means the driver could not be started
+var errBrowserUnavailable = errors.New("persistent browser unavailable")
+
+// ensureStarted starts the driver unless it is running or failed to start
+// recently. The caller holds d.mu.
+func (d *browserDriver) ensureStarted(ctx context.Context) error {
+	if d.stdin != nil {
+		return nil
+	}
+	if d.failedErr != nil && time.Since(d.failedAt) < browserRetryAfter {
+		return d.failedErr
+	}
+	if err := d.start(ctx); err != nil {
+		d.failedAt, d.failedErr = time.Now(), fmt.Errorf("%w: %v", errBrowserUnavailable, err)
+		log.Warn("Persistent browser failed to start, using one browser per call", "error", err)
+		return d.failedErr
+	}
+	d.failedErr = nil
+	return nil
+}
+
+// start launches the driver and waits for it to report ready. The caller
+// holds d.mu.
+func (d *browserDriver) start(ctx context.Context) error {
+	if len(d.command) == 0 {
+		return errors.New("no driver command")
+	}
+	if _, err := exec.LookPath(d.command[0]); err != nil {
+		return err
+	}
+	// The process outlives the call that starts it
+	cmd := exec.Command(d.command[0], d.command[1:]...)
+	cmd.WaitDelay = time.Second
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	stderr := newHeadTailBuffer(4 << 10)
+	cmd.Stderr = stderr
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	d.starts++
+
+	lines := bufio.NewScanner(stdout)
+	lines.Buffer(make([]byte, 64<<10), 64<<20)
+	ready := make(chan error, 1)
+	go func() {
+		if !lines.Scan() {
+			ready <- fmt.Errorf("driver exited before it was ready: %s", stderr.String())
+			return
+		}
+		var resp browserResponse
+		if err := json.Unmarshal(lines.Bytes(), &resp); err != nil || !resp.Ready {
+			ready <- fmt.Errorf("driver did not start: %s %s", resp.Error, stderr.String())
+			return
+		}
+		ready <- nil
+	}()
+
+	timer := time.NewTimer(browserStartTimeout)
+	defer timer.Stop()
+	select {
+	case err = <-ready:
+	case <-timer.C:
+		err = errors.New("timed out waiting for the driver")
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if err != nil {
+		stdin.Close()
+		cmd.Process.Kill()
+		cmd.Wait()
+		return err
+	}
+
+	done := make(chan struct{})
+	d.cmd, d.stdin, d.done, d.pending = cmd, stdin, done, make(map[int64]chan browserResponse)
+	go d.read(cmd, lines, stdin, done)
+	return nil
+}
+
+// read dispatches the driver's answers until it exits, then fails the
+// calls still waiting and lets the next call start a new driver
+func (d *browserDriver) read(cmd *exec.Cmd, lines *bufio.Scanner, stdin io.WriteCloser, done chan struct{}) {
+	for lines.Scan() {
+		var resp browserResponse
+		if json.Unmarshal(lines.Bytes(), &resp) != nil {
+			continue
+		}
+		d.mu.Lock()
+		if reply, ok := d.pending[resp.ID]; ok {
+			delete(d.pending, resp.ID)
+			reply <- resp
+		}
+		d.mu.Unlock()
+	}
+	cmd.Wait()
+
+	d.mu.Lock()
+	if d.stdin == stdin {
+		d.cmd, d.stdin, d.pending = nil, nil, nil
+	}
+	d.mu.Unlock()
+	close(done)
+}
+
+// stop closes the driver's stdin, which makes it close Chromium and exit,
+// and kills it if it takes too long
+func (d *browserDriver) stop() {
+	d.mu.Lock()
+	cmd, stdin, done := d.cmd, d.stdin, d.done
+	d.mu.Unlock()
+	if stdin == nil {
+		return
+	}
+	stdin.Close()
+	select {
+	case <-done:
+	case <-time.After(browserStopTimeout):
+		log.Warn("Browser driver did not exit in time, killing it")
+		cmd.Process.Kill()
+		<-done
+	}
+}
+
+// startCount returns how many driver processes were started
+func (d *browserDriver) startCount() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.starts
+}
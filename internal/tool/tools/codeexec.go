@@ -15,10 +15,12 @@ import (
 )
 
 // CodeExecTool executes code in a sandboxed environment
-type CodeExecTool struct{}
+type CodeExecTool struct {
+	limits ResourceLimits
+}
 
 func NewCodeExecTool() *CodeExecTool {
-	return &CodeExecTool{}
+	return &CodeExecTool{limits: DefaultResourceLimits}
 }
 
 func (t *CodeExecTool) Name() string {
@@ -26,7 +28,7 @@ func (t *CodeExecTool) Name() string {
 }
 
 func (t *CodeExecTool) Description() string {
-	return "Execute code in a sandboxed environment. Supports JavaScript (Node.js), Python, Go, and shell scripts. Use for testing code snippets, running calculations, or executing simple programs."
+	return "Execute code in a sandboxed environment. Supports JavaScript (Node.js), Python, Go, and shell scripts. Use for testing code snippets, running calculations, or executing simple programs. Code runs with CPU, memory, process and output limits, and without network access unless allow_network is true; where network isolation is unavailable, code only runs with allow_network."
 }
 
 func (t *CodeExecTool) Parameters() map[string]any {
@@ -46,6 +48,10 @@ func (t *CodeExecTool) Parameters() map[string]any {
 				"type":        "integer",
 				"description": "Maximum execution time in seconds (default: 10, max: 30)",
 			},
+			"allow_network": map[string]any{
+				"type":        "boolean",
+				"description": "Allow network access, e.g. to call an API (default: false)",
+			},
 		},
 		"required": []string{"language", "code"},
 	}
@@ -56,6 +62,8 @@ func (t *CodeExecTool) Execute(ctx context.Context, args json.RawMessage) (tool.
 		Language string `json:"language"`
 		Code     string `json:"code"`
 		Timeout  int    `json:"timeout"`
+		// AllowNetwork keeps the network and proxy settings
+		AllowNetwork bool `json:"allow_network"`
 	}
 
 	if err := json.Unmarshal(args, &params); err != nil {
@@ -84,18 +92,21 @@ func (t *CodeExecTool) Execute(ctx context.Context, args json.RawMessage) (tool.
 	}
 	defer os.RemoveAll(tmpDir)
 
+	run := sandboxRun{limits: t.limits, allowNetwork: params.AllowNetwork, timeout: timeout}
+	tool.ReportProgress(ctx, "starting", -1)
+
 	var result string
 	var execErr error
 
 	switch params.Language {
 	case "javascript":
-		result, execErr = executeJavaScript(ctx, tmpDir, params.Code, timeout)
+		result, execErr = executeJavaScript(ctx, tmpDir, params.Code, run)
 	case "python":
-		result, execErr = executePython(ctx, tmpDir, params.Code, timeout)
+		result, execErr = executePython(ctx, tmpDir, params.Code, run)
 	case "go":
-		result, execErr = executeGo(ctx, tmpDir, params.Code, timeout)
+		result, execErr = executeGo(ctx, tmpDir, params.Code, run)
 	case "shell":
-		result, execErr = executeShell(ctx, tmpDir, params.Code, timeout)
+		result, execErr = executeShell(ctx, tmpDir, params.Code, run)
 	}
 
 	if execErr != nil {
@@ -105,7 +116,7 @@ func (t *CodeExecTool) Execute(ctx context.Context, args json.RawMessage) (tool.
 	return tool.Result{Content: result}, nil
 }
 
-func executeJavaScript(ctx context.Context, dir, code string, timeout int) (string, error) {
+func executeJavaScript(ctx context.Context, dir, code string, run sandboxRun) (string, error) {
 	// Write code to file
 	filePath := filepath.Join(dir, "script.js")
 	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
@@ -118,10 +129,10 @@ func executeJavaScript(ctx context.Context, dir, code string, timeout int) (stri
 		return "", fmt.Errorf("Node.js not installed")
 	}
 
-	return runCommand(ctx, dir, nodePath, []string{filePath}, timeout)
+	return run.command(ctx, dir, nodePath, []string{filePath})
 }
 
-func executePython(ctx context.Context, dir, code string, timeout int) (string, error) {
+func executePython(ctx context.Context, dir, code string, run sandboxRun) (string, error) {
 	// Write code to file
 	filePath := filepath.Join(dir, "script.py")
 	if err := os.WriteFile(filePath, []byte(code), 0644); err != nil {
@@ -137,10 +148,10 @@ func executePython(ctx context.Context, dir, code string, timeout int) (string,
 		}
 	}
 
-	return runCommand(ctx, dir, pythonPath, []string{filePath}, timeout)
+	return run.command(ctx, dir, pythonPath, []string{filePath})
 }
 
-func executeGo(ctx context.Context, dir, code string, timeout int) (string, error) {
+func executeGo(ctx context.Context, dir, code string, run sandboxRun) (string, error) {
 	// Wrap code in main package if needed
 	if !strings.Contains(code, "package ") {
 		code = "package main\n\n" + code
@@ -161,12 +172,15 @@ func executeGo(ctx context.Context, dir, code string, timeout int) (string, erro
 		return "", fmt.Errorf("Go not installed")
 	}
 
-	return runCommand(ctx, dir, goPath, []string{"run", filePath}, timeout)
+	return run.command(ctx, dir, goPath, []string{"run", filePath})
 }
 
-func executeShell(ctx context.Context, dir, code string, timeout int) (string, error) {
+func executeShell(ctx context.Context, dir, code string, run sandboxRun) (string, error) {
 	// Security: restrict dangerous commands
-	dangerous := []string{"rm -rf", "sudo", "chmod", "chown", "mkfs", "dd if=", "> /dev/", "curl", "wget", "nc ", "netcat"}
+	dangerous := []string{"rm -rf", "sudo", "chmod", "chown", "mkfs", "dd if=", "> /dev/"}
+	if !run.allowNetwork {
+		dangerous = append(dangerous, "curl", "wget", "nc ", "netcat")
+	}
 	codeLower := strings.ToLower(code)
 	for _, d := range dangerous {
 		if strings.Contains(codeLower, d) {
@@ -180,15 +194,31 @@ func executeShell(ctx context.Context, dir, code string, timeout int) (string, e
 		return "", err
 	}
 
-	return runCommand(ctx, dir, "/bin/bash", []string{filePath}, timeout)
+	return run.command(ctx, dir, "/bin/bash", []string{filePath})
 }
 
-func runCommand(ctx context.Context, dir, command string, args []string, timeout int) (string, error) {
-	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+// sandboxRun holds the settings for running one snippet
+type sandboxRun struct {
+	limits       ResourceLimits
+	allowNetwork bool
+	timeout      int
+}
+
+// proxyEnvVars are passed through only when network access is allowed
+var proxyEnvVars = []string{"http_proxy", "https_proxy", "no_proxy", "HTTP_PROXY", "HTTPS_PROXY", "NO_PROXY"}
+
+func (r sandboxRun) command(ctx context.Context, dir, command string, args []string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(r.timeout)*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, command, args...)
+	name, argv, err := sandboxCommand(r.limits, r.allowNetwork, command, args)
+	if err != nil {
+		return "", err
+	}
+	cmd := exec.CommandContext(ctx, name, argv...)
 	cmd.Dir = dir
+	cmd.WaitDelay = time.Second // don't wait on children still holding the pipes
+	setProcessGroup(cmd)
 
 	// Restrict environment
 	cmd.Env = []string{
@@ -196,12 +226,22 @@ func runCommand(ctx context.Context, dir, command string, args []string, timeout
 		"HOME=" + dir,
 		"TMPDIR=" + dir,
 	}
+	if r.allowNetwork {
+		for _, name := range proxyEnvVars {
+			if v, ok := os.LookupEnv(name); ok {
+				cmd.Env = append(cmd.Env, name+"="+v)
+			}
+		}
+	}
 
+	// Output is capped as it streams; a process that keeps writing is killed
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	capped := newCappedOutput(r.limits.MaxOutputBytes, cancel)
+	cmd.Stdout = capped.Writer(&stdout)
+	cmd.Stderr = capped.Writer(&stderr)
 
-	err := cmd.Run()
+	tool.ReportProgress(ctx, "running", -1)
+	err = cmd.Run()
 
 	output := stdout.String()
 	if stderr.Len() > 0 {
@@ -211,14 +251,11 @@ func runCommand(ctx context.Context, dir, command string, args []string, timeout
 		output += stderr.String()
 	}
 
-	// Trim output if too long
-	const maxOutput = 10000
-	if len(output) > maxOutput {
-		output = output[:maxOutput] + "\n... (output truncated)"
+	if capped.Exceeded() {
+		return output + "\n... (output truncated)", fmt.Errorf("%w: killed after %d bytes", errOutputLimit, r.limits.MaxOutputBytes)
 	}
-
 	if ctx.Err() == context.DeadlineExceeded {
-		return output, fmt.Errorf("execution timed out after %d seconds", timeout)
+		return output, fmt.Errorf("execution timed out after %d seconds", r.timeout)
 	}
 
 	return output, err
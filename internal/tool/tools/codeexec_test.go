@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func requirePython(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("python3"); err != nil {
+		t.Skip("python3 not installed")
+	}
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are only enforced on Linux")
+	}
+}
+
+func TestCodeExecRunsPython(t *testing.T) {
+	requirePython(t)
+	result := runTool(t, context.Background(), NewCodeExecTool(), map[string]any{
+		"language": "python",
+		"code":     "print(6 * 7)",
+	})
+	if result.IsError || strings.TrimSpace(result.Content) != "42" {
+		t.Errorf("Expected 42, got %q (error=%v)", result.Content, result.IsError)
+	}
+}
+
+func TestCodeExecKillsMemoryHog(t *testing.T) {
+	requirePython(t)
+	result := runTool(t, context.Background(), NewCodeExecTool(), map[string]any{
+		"language": "python",
+		"code":     "data = bytearray(2 * 1024 * 1024 * 1024)\nprint('allocated')",
+	})
+	if !result.IsError {
+		t.Fatalf("Expected the allocation to fail, got %q", result.Content)
+	}
+	if strings.Contains(result.Content, "allocated") {
+		t.Errorf("Expected no output after the allocation, got %q", result.Content)
+	}
+}
+
+func TestCodeExecBlocksNetwork(t *testing.T) {
+	requirePython(t)
+	if netUnshare() == "" {
+		t.Skip("network namespaces unavailable")
+	}
+	code := "import socket\nsocket.create_connection(('1.1.1.1', 80), timeout=2)\nprint('connected')"
+
+	result := runTool(t, context.Background(), NewCodeExecTool(), map[string]any{
+		"language": "python",
+		"code":     code,
+	})
+	if !result.IsError || strings.Contains(result.Content, "connected") {
+		t.Errorf("Expected the connection to fail, got %q", result.Content)
+	}
+}
+
+func TestCodeExecCapsOutputWhileStreaming(t *testing.T) {
+	requirePython(t)
+	result := runTool(t, context.Background(), NewCodeExecTool(), map[string]any{
+		"language": "python",
+		"code":     "while True:\n    print('x' * 100)",
+		"timeout":  20,
+	})
+	if !result.IsError || !strings.Contains(result.Content, "output limit exceeded") {
+		t.Fatalf("Expected output limit error, got %q", result.Content)
+	}
+	if len(result.Content) > DefaultResourceLimits.MaxOutputBytes+200 {
+		t.Errorf("Expected output capped near %d bytes, got %d", DefaultResourceLimits.MaxOutputBytes, len(result.Content))
+	}
+}
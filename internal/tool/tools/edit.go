@@ -17,6 +17,9 @@ type EditArgs struct {
 	OldString  string `json:"old_string"`
 	NewString  string `json:"new_string"`
 	ReplaceAll bool   `json:"replace_all,omitempty"`
+	// ExpectedSHA256 guards against editing a file that changed since it
+	// was last written, e.g. by post-write formatting
+	ExpectedSHA256 string `json:"expected_sha256,omitempty"`
 }
 
 func NewEditTool() *EditTool {
@@ -51,6 +54,10 @@ func (t *EditTool) Parameters() map[string]any {
 				"type":        "boolean",
 				"description": "Replace all occurrences (default false)",
 			},
+			"expected_sha256": map[string]any{
+				"type":        "string",
+				"description": "Optional sha256 reported by the last Write/Edit; the edit fails if the file has changed since",
+			},
 		},
 		"required": []string{"file_path", "old_string", "new_string"},
 	}
@@ -65,6 +72,13 @@ func (t *EditTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 	if args.FilePath == "" {
 		return tool.NewErrorResult("file_path is required"), nil
 	}
+
+	// Inside a project, stay within its root
+	path, err := tool.SandboxFromContext(ctx).Resolve(args.FilePath)
+	if err != nil {
+		return tool.NewErrorResult(err.Error()), nil
+	}
+	args.FilePath = path
 	if args.OldString == "" {
 		return tool.NewErrorResult("old_string is required"), nil
 	}
@@ -77,6 +91,10 @@ func (t *EditTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		return tool.NewErrorResult(fmt.Sprintf("failed to read file: %v", err)), nil
 	}
 
+	if args.ExpectedSHA256 != "" && !strings.EqualFold(args.ExpectedSHA256, contentHash(content)) {
+		return tool.NewErrorResult("file has changed since the expected_sha256 was reported; re-read it before editing"), nil
+	}
+
 	contentStr := string(content)
 	count := strings.Count(contentStr, args.OldString)
 
@@ -95,38 +113,35 @@ func (t *EditTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		newContent = strings.Replace(contentStr, args.OldString, args.NewString, 1)
 	}
 
-	if err := os.WriteFile(args.FilePath, []byte(newContent), 0644); err != nil {
+	written, note, err := writeFormatted(ctx, args.FilePath, []byte(newContent))
+	if err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("failed to write file: %v", err)), nil
 	}
+	newContent = string(written)
 
-	// Build result with diff information for UI
-	result := EditResult{
-		FilePath:   args.FilePath,
-		OldContent: contentStr,
-		NewContent: newContent,
-		Count:      count,
-		ReplaceAll: args.ReplaceAll,
-	}
-
-	resultJSON, _ := json.Marshal(result)
-
+	result := tool.NewResult(fmt.Sprintf("Successfully edited %s\n%s", args.FilePath, note))
 	if args.ReplaceAll {
-		return tool.Result{
-			Content: fmt.Sprintf("Successfully replaced %d occurrences in %s\n---DIFF_DATA---\n%s", count, args.FilePath, string(resultJSON)),
-			IsError: false,
-		}, nil
+		result.Content = fmt.Sprintf("Successfully replaced %d occurrences in %s\n%s", count, args.FilePath, note)
+	}
+	if diff := tool.UnifiedDiff(args.FilePath, contentStr, newContent); diff != "" {
+		result.Metadata = map[string]string{tool.MetadataDiff: diff}
 	}
-	return tool.Result{
-		Content: fmt.Sprintf("Successfully edited %s\n---DIFF_DATA---\n%s", args.FilePath, string(resultJSON)),
-		IsError: false,
-	}, nil
+	return result, nil
 }
 
-// EditResult contains diff information for the UI
-type EditResult struct {
-	FilePath   string `json:"file_path"`
-	OldContent string `json:"old_content"`
-	NewContent string `json:"new_content"`
-	Count      int    `json:"count"`
-	ReplaceAll bool   `json:"replace_all"`
+// NeedsApproval implements tool.Approvable: edits outside the working
+// tree need approval
+func (t *EditTool) NeedsApproval(ctx context.Context, argsJSON json.RawMessage) bool {
+	var args EditArgs
+	return json.Unmarshal(argsJSON, &args) != nil || outsideWorkingTree(ctx, args.FilePath)
+}
+
+// ReportedPaths implements tool.PathReporter; a successful edit means the
+// model knows the file
+func (t *EditTool) ReportedPaths(argsJSON json.RawMessage, result tool.Result) []string {
+	var args EditArgs
+	if json.Unmarshal(argsJSON, &args) != nil || args.FilePath == "" {
+		return nil
+	}
+	return []string{args.FilePath}
 }
@@ -0,0 +1,107 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"groq-go/internal/tool"
+)
+
+// formatter rewrites file content into canonical form
+type formatter struct {
+	name string
+	fn   func([]byte) ([]byte, error)
+	data bool // Data files are only reformatted with tool.FormatAll
+}
+
+// formatters maps file extensions to in-process formatters
+var formatters = map[string]formatter{
+	".go":   {"gofmt", format.Source, false},
+	".json": {"json", formatJSON, true},
+	".yaml": {"yaml", formatYAML, true},
+	".yml":  {"yaml", formatYAML, true},
+}
+
+func formatJSON(src []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, bytes.TrimSpace(src), "", "  "); err != nil {
+		return nil, err
+	}
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func formatYAML(src []byte) ([]byte, error) {
+	// Round-trip through yaml.Node to keep comments and key order
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	for {
+		var doc yaml.Node
+		if err := dec.Decode(&doc); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, err
+		}
+		if err := enc.Encode(&doc); err != nil {
+			return nil, err
+		}
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// contentHash returns the hex SHA-256 of data
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeFormatted formats content for recognized file types as the context's
+// tool.Format allows and writes it to path. A formatter error never loses content: the unformatted
+// text is written and the note carries a warning. It returns the bytes
+// actually written and a note for the tool result.
+func writeFormatted(ctx context.Context, path string, content []byte) ([]byte, string, error) {
+	data := content
+	var note string
+
+	mode := tool.FormatFromContext(ctx)
+	if f, ok := formatters[strings.ToLower(filepath.Ext(path))]; ok && (mode == tool.FormatAll || mode == tool.FormatCode && !f.data) {
+		formatted, err := f.fn(content)
+		switch {
+		case err != nil:
+			note = fmt.Sprintf("Warning: %s failed, saved unformatted: %v", f.name, err)
+		case bytes.Equal(formatted, content):
+			note = fmt.Sprintf("Formatted with %s: no changes", f.name)
+		default:
+			data = formatted
+			note = fmt.Sprintf("Formatted with %s: content changed, re-read before editing", f.name)
+		}
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return nil, "", err
+	}
+
+	hash := "sha256: " + contentHash(data)
+	if note == "" {
+		return data, hash, nil
+	}
+	return data, note + "\n" + hash, nil
+}
@@ -0,0 +1,115 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/tool"
+)
+
+func runTool(t *testing.T, ctx context.Context, tl tool.Tool, args any) tool.Result {
+	t.Helper()
+	data, _ := json.Marshal(args)
+	result, err := tl.Execute(ctx, data)
+	if err != nil {
+		t.Fatalf("%s failed: %v", tl.Name(), err)
+	}
+	return result
+}
+
+func TestWriteFormatsGo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.go")
+	ctx := tool.WithFormat(context.Background(), tool.FormatCode)
+
+	result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: path, Content: "package main\nfunc main(){\nx:=1\n_ = x}\n"})
+	if !strings.Contains(result.Content, "content changed") {
+		t.Errorf("Expected formatting note, got %q", result.Content)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "\tx := 1") {
+		t.Errorf("Expected gofmt output, got %q", data)
+	}
+	if !strings.Contains(result.Content, contentHash(data)) {
+		t.Error("Expected hash of the on-disk content in result")
+	}
+
+	// Editing with a stale hash is rejected; the reported hash works
+	stale := runTool(t, ctx, NewEditTool(), EditArgs{FilePath: path, OldString: "x := 1", NewString: "x := 2", ExpectedSHA256: contentHash([]byte("old"))})
+	if !stale.IsError {
+		t.Error("Expected stale hash to be rejected")
+	}
+	ok := runTool(t, ctx, NewEditTool(), EditArgs{FilePath: path, OldString: "x := 1", NewString: "x := 2", ExpectedSHA256: contentHash(data)})
+	if ok.IsError {
+		t.Errorf("Edit failed: %s", ok.Content)
+	}
+}
+
+func TestWriteFormatFallback(t *testing.T) {
+	dir := t.TempDir()
+	ctx := tool.WithFormat(context.Background(), tool.FormatCode)
+
+	// Invalid Go is kept as written, with a warning
+	broken := "package main\nfunc {"
+	path := filepath.Join(dir, "broken.go")
+	result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: path, Content: broken})
+	if result.IsError || !strings.Contains(result.Content, "Warning: gofmt failed") {
+		t.Errorf("Expected warning, got %q", result.Content)
+	}
+	if data, _ := os.ReadFile(path); string(data) != broken {
+		t.Errorf("Expected unformatted content to be kept, got %q", data)
+	}
+
+	// Off unless the session asks for it
+	path = filepath.Join(dir, "main.go")
+	unformatted := "package main\nfunc main(){}\n"
+	runTool(t, context.Background(), NewWriteTool(), WriteArgs{FilePath: path, Content: unformatted})
+	if data, _ := os.ReadFile(path); string(data) != unformatted {
+		t.Errorf("Expected no formatting by default, got %q", data)
+	}
+
+	// Data files are kept byte for byte unless all files are formatted
+	path = filepath.Join(dir, "data.json")
+	for _, f := range []tool.Format{tool.FormatOff, tool.FormatCode} {
+		runTool(t, tool.WithFormat(ctx, f), NewWriteTool(), WriteArgs{FilePath: path, Content: `{"a":1}`})
+		if data, _ := os.ReadFile(path); string(data) != `{"a":1}` {
+			t.Errorf("Expected JSON kept as written with format %s, got %q", f, data)
+		}
+	}
+
+	runTool(t, tool.WithFormat(ctx, tool.FormatAll), NewWriteTool(), WriteArgs{FilePath: path, Content: `{"a":1}`})
+	if data, _ := os.ReadFile(path); string(data) != "{\n  \"a\": 1\n}\n" {
+		t.Errorf("Expected indented JSON, got %q", data)
+	}
+}
+
+func TestEditReportsDiffMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	ctx := context.Background()
+
+	result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: path, Content: "one\ntwo\nthree\n"})
+	if diff := result.Metadata[tool.MetadataDiff]; !strings.Contains(diff, "+two\n") {
+		t.Errorf("Expected write diff against empty file, got %q", diff)
+	}
+	if strings.Contains(result.Content, tool.DiffSentinel) {
+		t.Error("Expected no sentinel in result content")
+	}
+
+	result = runTool(t, ctx, NewEditTool(), EditArgs{FilePath: path, OldString: "two", NewString: "2"})
+	diff := result.Metadata[tool.MetadataDiff]
+	if !strings.Contains(diff, "-two\n+2\n") {
+		t.Errorf("Expected edit diff, got %q", diff)
+	}
+
+	os.WriteFile(path, []byte("bin\x00ary"), 0644)
+	result = runTool(t, ctx, NewEditTool(), EditArgs{FilePath: path, OldString: "ary", NewString: "ARY"})
+	if result.IsError {
+		t.Fatalf("Edit failed: %s", result.Content)
+	}
+	if _, ok := result.Metadata[tool.MetadataDiff]; ok {
+		t.Error("Expected no diff for binary content")
+	}
+}
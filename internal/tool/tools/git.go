@@ -3,25 +3,63 @@ package tools
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"slices"
 	"strings"
 
 	"groq-go/internal/tool"
 )
 
-type GitTool struct{}
+// GitTool runs git commands in the working directory, or the project root
+// inside a project
+type GitTool struct {
+	githubToken string // Sent to github.com by network commands; never shown
+}
 
 type GitArgs struct {
-	Command string `json:"command"`
-	Args    string `json:"args,omitempty"`
-	Message string `json:"message,omitempty"`
-	Path    string `json:"path,omitempty"`
+	Command        string `json:"command"`
+	Args           string `json:"args,omitempty"`
+	Message        string `json:"message,omitempty"`
+	Path           string `json:"path,omitempty"`
+	URL            string `json:"url,omitempty"`
+	Dir            string `json:"dir,omitempty"`
+	Format         string `json:"format,omitempty"`
+	AllowDangerous bool   `json:"allow_dangerous,omitempty"`
+}
+
+// GitFileStatus is an entry of `status` with format "json". A file
+// changed both in the index and the working tree has an entry for each.
+type GitFileStatus struct {
+	File   string `json:"file"`
+	From   string `json:"from,omitempty"` // Original path of a rename or copy
+	Staged bool   `json:"staged"`
+	State  string `json:"state"` // modified, added, deleted, renamed, copied, type_changed, conflicted or untracked
+}
+
+// GitCommit is an entry of `log` with format "json"
+type GitCommit struct {
+	Hash    string `json:"hash"`
+	Author  string `json:"author"`
+	Date    string `json:"date"` // RFC 3339
+	Subject string `json:"subject"`
+}
+
+// GitBranch is an entry of `branch` with format "json"
+type GitBranch struct {
+	Name    string `json:"name"`
+	Current bool   `json:"current"`
+	Remote  bool   `json:"remote"`
+	Commit  string `json:"commit"`
 }
 
-func NewGitTool() *GitTool {
-	return &GitTool{}
+// NewGitTool creates the Git tool. A GitHub token, if given, lets clone,
+// fetch, pull and push reach private github.com repositories.
+func NewGitTool(githubToken string) *GitTool {
+	return &GitTool{githubToken: githubToken}
 }
 
 func (t *GitTool) Name() string {
@@ -33,13 +71,21 @@ func (t *GitTool) Description() string {
 - status: Show working tree status
 - diff: Show changes (use args for specific files)
 - log: Show commit logs (default: last 10)
+- show: Show a commit with its diff (args: commit, default HEAD)
 - add: Stage files (use args for file paths, or "." for all)
 - commit: Create commit (use message parameter)
+- reset: Unstage changes or move HEAD (args, e.g. "HEAD~1" or a file path)
 - push: Push to remote
 - pull: Pull from remote
+- fetch: Fetch from remote (args for remote and refs)
+- clone: Clone the repository at url, into dir if given
+- remote: List remotes, or add one with args "add <name> <url>"
 - branch: List or create branches (use args for branch name)
 - checkout: Switch branches (use args for branch name)
-- stash: Stash changes`
+- stash: Stash changes
+
+Set format to "json" for status, log and branch to get structured output instead of text.
+Force pushes, push --prune, reset --hard, stash drop and stash clear discard work and are refused unless allow_dangerous is true; only set it when the user asked for exactly that.`
 }
 
 func (t *GitTool) Parameters() map[string]any {
@@ -48,7 +94,7 @@ func (t *GitTool) Parameters() map[string]any {
 		"properties": map[string]any{
 			"command": map[string]any{
 				"type":        "string",
-				"enum":        []string{"status", "diff", "log", "add", "commit", "push", "pull", "branch", "checkout", "stash"},
+				"enum":        []string{"status", "diff", "log", "show", "add", "commit", "reset", "push", "pull", "fetch", "clone", "remote", "branch", "checkout", "stash"},
 				"description": "The git command to execute",
 			},
 			"args": map[string]any{
@@ -63,6 +109,23 @@ func (t *GitTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Working directory path (defaults to current directory)",
 			},
+			"url": map[string]any{
+				"type":        "string",
+				"description": "Repository URL (for clone command)",
+			},
+			"dir": map[string]any{
+				"type":        "string",
+				"description": "Directory to clone into (for clone command; defaults to the repository name)",
+			},
+			"format": map[string]any{
+				"type":        "string",
+				"enum":        []string{"text", "json"},
+				"description": "Output format for status, log and branch (default text)",
+			},
+			"allow_dangerous": map[string]any{
+				"type":        "boolean",
+				"description": "Allow push --force or --prune, reset --hard and stash drop or clear",
+			},
 		},
 		"required": []string{"command"},
 	}
@@ -77,13 +140,28 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 	if args.Command == "" {
 		return tool.NewErrorResult("command is required"), nil
 	}
+	if args.Format != "" && args.Format != "text" && args.Format != "json" {
+		return tool.NewErrorResult(fmt.Sprintf("unknown format %q: use text or json", args.Format)), nil
+	}
+	asJSON := args.Format == "json"
+	if asJSON && args.Command != "status" && args.Command != "log" && args.Command != "branch" {
+		return tool.NewErrorResult("format json is supported for status, log and branch"), nil
+	}
+	extra := strings.Fields(args.Args)
+	if reason := dangerousGit(args.Command, extra); reason != "" && !args.AllowDangerous {
+		return tool.NewErrorResult(fmt.Sprintf("refusing git %s %s: %s. Set allow_dangerous only if the user asked for this.", args.Command, args.Args, reason)), nil
+	}
 
 	// Build git command
 	var gitArgs []string
+	var parse func(string) (any, error)
 
 	switch args.Command {
 	case "status":
 		gitArgs = []string{"status", "--short"}
+		if asJSON {
+			gitArgs, parse = []string{"status", "--porcelain=v1", "-z"}, parseGitStatus
+		}
 
 	case "diff":
 		gitArgs = []string{"diff"}
@@ -93,10 +171,20 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 
 	case "log":
 		gitArgs = []string{"log", "--oneline", "-n", "10"}
+		if asJSON {
+			gitArgs, parse = []string{"log", "-n", "10", "--format=" + gitLogFormat}, parseGitLog
+		}
 		if args.Args != "" {
 			gitArgs = append(gitArgs, strings.Fields(args.Args)...)
 		}
 
+	case "show":
+		gitArgs = []string{"show", "--stat", "--patch"}
+		if args.Args == "" {
+			gitArgs = append(gitArgs, "HEAD")
+		}
+		gitArgs = append(gitArgs, extra...)
+
 	case "add":
 		if args.Args == "" {
 			return tool.NewErrorResult("args required for add command (e.g., '.' or file paths)"), nil
@@ -110,6 +198,9 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 		}
 		gitArgs = []string{"commit", "-m", args.Message}
 
+	case "reset":
+		gitArgs = append([]string{"reset"}, extra...)
+
 	case "push":
 		gitArgs = []string{"push"}
 		if args.Args != "" {
@@ -122,9 +213,39 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 			gitArgs = append(gitArgs, strings.Fields(args.Args)...)
 		}
 
+	case "fetch":
+		gitArgs = append([]string{"fetch"}, extra...)
+
+	case "clone":
+		if args.URL == "" {
+			return tool.NewErrorResult("url required for clone command"), nil
+		}
+		// "--" keeps a URL or dir starting with a dash from being read as an option
+		gitArgs = []string{"clone", "--", args.URL}
+		if args.Dir != "" {
+			dir, err := tool.SandboxFromContext(ctx).Resolve(args.Dir)
+			if err != nil {
+				return tool.NewErrorResult(err.Error()), nil
+			}
+			gitArgs = append(gitArgs, dir)
+		}
+
+	case "remote":
+		switch {
+		case args.Args == "":
+			gitArgs = []string{"remote", "-v"}
+		case len(extra) == 3 && extra[0] == "add":
+			gitArgs = []string{"remote", "add", "--", extra[1], extra[2]}
+		default:
+			return tool.NewErrorResult(`remote takes no args to list remotes, or "add <name> <url>"`), nil
+		}
+
 	case "branch":
 		if args.Args == "" {
 			gitArgs = []string{"branch", "-a"}
+			if asJSON {
+				gitArgs, parse = []string{"branch", "-a", "--format=" + gitBranchFormat}, parseGitBranches
+			}
 		} else {
 			gitArgs = []string{"branch", args.Args}
 		}
@@ -145,10 +266,24 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 		return tool.NewErrorResult(fmt.Sprintf("unknown command: %s", args.Command)), nil
 	}
 
-	// Execute git command
+	// Execute git command, inside a project in its root
 	cmd := exec.CommandContext(ctx, "git", gitArgs...)
+	sandbox := tool.SandboxFromContext(ctx)
+	sandbox.Command(cmd)
 	if args.Path != "" {
-		cmd.Dir = args.Path
+		dir, err := sandbox.Resolve(args.Path)
+		if err != nil {
+			return tool.NewErrorResult(err.Error()), nil
+		}
+		cmd.Dir = dir
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	// Fail rather than wait for a password nobody will type
+	cmd.Env = append(cmd.Env, "GIT_TERMINAL_PROMPT=0")
+	if gitNetworkCommands[args.Command] {
+		cmd.Env = append(cmd.Env, t.authEnv()...)
 	}
 
 	var stdout, stderr bytes.Buffer
@@ -157,6 +292,15 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 
 	err := cmd.Run()
 
+	if err == nil && parse != nil {
+		parsed, perr := parse(stdout.String())
+		if perr != nil {
+			return tool.NewErrorResult(fmt.Sprintf("git %s: %v", args.Command, perr)), nil
+		}
+		data, _ := json.MarshalIndent(parsed, "", "  ")
+		return tool.NewResult(string(data)), nil
+	}
+
 	output := stdout.String()
 	if stderr.Len() > 0 {
 		if output != "" {
@@ -164,6 +308,7 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 		}
 		output += stderr.String()
 	}
+	output = t.redact(output)
 
 	if err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("git %s failed: %s\n%s", args.Command, err.Error(), output)), nil
@@ -175,3 +320,233 @@ func (t *GitTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.R
 
 	return tool.NewResult(output), nil
 }
+
+// gitNetworkCommands are the commands that talk to a remote
+var gitNetworkCommands = map[string]bool{"clone": true, "fetch": true, "pull": true, "push": true}
+
+// authEnv returns the environment that sends the GitHub token to
+// github.com. It is passed as config in the environment, so it is neither
+// written to the clone's .git/config nor visible in the process list.
+func (t *GitTool) authEnv() []string {
+	if t.githubToken == "" {
+		return nil
+	}
+	return []string{
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=http.https://github.com/.extraheader",
+		"GIT_CONFIG_VALUE_0=AUTHORIZATION: basic " + t.basicAuth(),
+	}
+}
+
+func (t *GitTool) basicAuth() string {
+	return base64.StdEncoding.EncodeToString([]byte("x-access-token:" + t.githubToken))
+}
+
+// redact removes the GitHub token, plain or encoded, from output
+func (t *GitTool) redact(output string) string {
+	if t.githubToken == "" {
+		return output
+	}
+	return strings.NewReplacer(t.githubToken, "[REDACTED]", t.basicAuth(), "[REDACTED]").Replace(output)
+}
+
+// dangerousGit returns why a command would destroy work, or "" if it is
+// safe: force pushes, deletions ("+ref", ":ref") and --prune rewrite or
+// remove the remote's history, reset --hard discards uncommitted changes,
+// stash drop and clear discard stashes and clean -f deletes untracked files
+func dangerousGit(command string, args []string) string {
+	if command == "stash" && len(args) > 0 && (args[0] == "drop" || args[0] == "clear") {
+		return "it deletes stashed changes"
+	}
+	for _, a := range args {
+		switch command {
+		case "push":
+			if a == "--force" || strings.HasPrefix(a, "--force-") || a == "--mirror" || a == "--delete" || a == "--prune" ||
+				strings.HasPrefix(a, "+") || strings.HasPrefix(a, ":") || isShortFlag(a, 'f') || isShortFlag(a, 'd') {
+				return "it can overwrite or delete history on the remote"
+			}
+		case "reset":
+			if a == "--hard" || a == "--merge" || a == "--keep" {
+				return "it discards uncommitted changes"
+			}
+		case "clean":
+			if a == "--force" || isShortFlag(a, 'f') {
+				return "it deletes untracked files"
+			}
+		}
+	}
+	return ""
+}
+
+// gitBranchListFlags only change how branch lists branches
+var gitBranchListFlags = []string{"-a", "--all", "-r", "--remotes", "-v", "-vv", "--verbose", "--list", "--show-current"}
+
+// Mutates reports whether a call changes the repository, the working
+// tree or a remote; read-only mode refuses those, see tool.Mutator
+func (t *GitTool) Mutates(argsJSON json.RawMessage) bool {
+	var args GitArgs
+	if json.Unmarshal(argsJSON, &args) != nil {
+		return true
+	}
+	extra := strings.Fields(args.Args)
+	switch args.Command {
+	case "status", "diff", "log", "show":
+		return slices.ContainsFunc(extra, writesOutput)
+	case "remote":
+		return len(extra) > 0 && !slices.Contains([]string{"-v", "--verbose", "show", "get-url"}, extra[0])
+	case "branch":
+		for _, a := range extra {
+			if !slices.Contains(gitBranchListFlags, a) {
+				return true
+			}
+		}
+		return false
+	case "stash":
+		return len(extra) == 0 || (extra[0] != "list" && extra[0] != "show")
+	}
+	return true
+}
+
+// writesOutput reports whether arg is -o or --output, which make diff,
+// log and show write to a file. git accepts long options cut short, such
+// as --outp=file, so those count too.
+func writesOutput(arg string) bool {
+	name, _, _ := strings.Cut(arg, "=")
+	return arg == "-o" || len(name) > len("--o") && strings.HasPrefix("--output", name)
+}
+
+// NeedsApproval implements tool.Approvable: pushes and calls allowed to
+// run dangerous commands need approval
+func (t *GitTool) NeedsApproval(ctx context.Context, argsJSON json.RawMessage) bool {
+	var args GitArgs
+	if json.Unmarshal(argsJSON, &args) != nil {
+		return true
+	}
+	return args.Command == "push" || args.AllowDangerous
+}
+
+// ApprovalSubject implements tool.ApprovalSubjecter: the command line
+// after "git", such as "push origin main"
+func (t *GitTool) ApprovalSubject(argsJSON json.RawMessage) string {
+	var args GitArgs
+	json.Unmarshal(argsJSON, &args)
+	return strings.TrimSpace(args.Command + " " + args.Args)
+}
+
+// isShortFlag reports whether arg is a group of short options including c,
+// like -f or -uf
+func isShortFlag(arg string, c byte) bool {
+	return len(arg) > 1 && arg[0] == '-' && arg[1] != '-' && strings.IndexByte(arg[1:], c) >= 0
+}
+
+// parseGitStatus parses `git status --porcelain=v1 -z`
+func parseGitStatus(out string) (any, error) {
+	entries := strings.Split(out, "\x00")
+	files := []GitFileStatus{}
+	for i := 0; i < len(entries); i++ {
+		e := entries[i]
+		if e == "" {
+			continue
+		}
+		if len(e) < 4 || e[2] != ' ' {
+			return nil, fmt.Errorf("unexpected status line %q", e)
+		}
+		x, y, file := e[0], e[1], e[3:]
+		var from string
+		// Renames and copies are followed by their original path
+		if x == 'R' || x == 'C' || y == 'R' || y == 'C' {
+			if i+1 < len(entries) {
+				i++
+				from = entries[i]
+			}
+		}
+
+		switch {
+		case x == '?':
+			files = append(files, GitFileStatus{File: file, State: "untracked"})
+		case x == 'U' || y == 'U' || x == 'A' && y == 'A' || x == 'D' && y == 'D':
+			files = append(files, GitFileStatus{File: file, State: "conflicted"})
+		default:
+			if x != ' ' {
+				files = append(files, GitFileStatus{File: file, From: fromFor(x, from), Staged: true, State: gitStates[x]})
+			}
+			if y != ' ' {
+				files = append(files, GitFileStatus{File: file, From: fromFor(y, from), State: gitStates[y]})
+			}
+		}
+	}
+	return files, nil
+}
+
+// gitStates names the porcelain status letters
+var gitStates = map[byte]string{
+	'M': "modified",
+	'A': "added",
+	'D': "deleted",
+	'R': "renamed",
+	'C': "copied",
+	'T': "type_changed",
+}
+
+// fromFor returns the original path for renames and copies
+func fromFor(state byte, from string) string {
+	if state == 'R' || state == 'C' {
+		return from
+	}
+	return ""
+}
+
+// gitLogFormat separates fields with the unit separator and commits with
+// the record separator, neither of which appears in names or subjects
+const gitLogFormat = "%H%x1f%an%x1f%aI%x1f%s%x1e"
+
+// parseGitLog parses `git log --format=gitLogFormat`
+func parseGitLog(out string) (any, error) {
+	commits := []GitCommit{}
+	for _, rec := range strings.Split(out, "\x1e") {
+		rec = strings.TrimSpace(rec)
+		if rec == "" {
+			continue
+		}
+		fields := strings.Split(rec, "\x1f")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected log record %q", rec)
+		}
+		commits = append(commits, GitCommit{Hash: fields[0], Author: fields[1], Date: fields[2], Subject: fields[3]})
+	}
+	return commits, nil
+}
+
+// gitBranchFormat is the --format of `branch` with format "json"
+const gitBranchFormat = "%(HEAD)%1f%(refname)%1f%(objectname:short)"
+
+// parseGitBranches parses `git branch -a --format=gitBranchFormat`
+func parseGitBranches(out string) (any, error) {
+	branches := []GitBranch{}
+	for _, line := range strings.Split(out, "\n") {
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\x1f")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("unexpected branch line %q", line)
+		}
+		ref := fields[1]
+		// Skip origin/HEAD, an alias of the remote's default branch
+		if strings.HasPrefix(ref, "refs/remotes/") && strings.HasSuffix(ref, "/HEAD") {
+			continue
+		}
+		b := GitBranch{Current: fields[0] == "*", Commit: fields[2]}
+		switch {
+		case strings.HasPrefix(ref, "refs/heads/"):
+			b.Name = strings.TrimPrefix(ref, "refs/heads/")
+		case strings.HasPrefix(ref, "refs/remotes/"):
+			b.Name, b.Remote = strings.TrimPrefix(ref, "refs/remotes/"), true
+		default:
+			// A detached HEAD is listed as "(HEAD detached at ...)"
+			b.Name = ref
+		}
+		branches = append(branches, b)
+	}
+	return branches, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"sort"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/bmatcuk/doublestar/v4"
 
+	"groq-go/internal/ignore"
 	"groq-go/internal/tool"
 )
 
@@ -19,6 +21,8 @@ type GlobTool struct{}
 type GlobArgs struct {
 	Pattern string `json:"pattern"`
 	Path    string `json:"path,omitempty"`
+	// NoIgnore includes files excluded by .gitignore and ignore.DefaultExcludes
+	NoIgnore bool `json:"no_ignore,omitempty"`
 }
 
 func NewGlobTool() *GlobTool {
@@ -30,7 +34,8 @@ func (t *GlobTool) Name() string {
 }
 
 func (t *GlobTool) Description() string {
-	return "Fast file pattern matching. Supports glob patterns like \"**/*.js\" or \"src/**/*.ts\". Returns matching file paths."
+	return "Fast file pattern matching. Supports glob patterns like \"**/*.js\" or \"src/**/*.ts\". Returns matching file paths, newest first. " +
+		"Skips files excluded by .gitignore, and node_modules, .git, dist, target and *.min.js, unless no_ignore is set."
 }
 
 func (t *GlobTool) Parameters() map[string]any {
@@ -45,6 +50,10 @@ func (t *GlobTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "The directory to search in. Defaults to current working directory.",
 			},
+			"no_ignore": map[string]any{
+				"type":        "boolean",
+				"description": "Include files excluded by .gitignore and the default exclusions",
+			},
 		},
 		"required": []string{"pattern"},
 	}
@@ -65,7 +74,12 @@ func (t *GlobTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		return tool.NewErrorResult("pattern is required"), nil
 	}
 
+	// Inside a project, paths are relative to its root and stay within it
+	sandbox := tool.SandboxFromContext(ctx)
 	searchPath := args.Path
+	if sandbox != nil {
+		searchPath = sandbox.Abs(searchPath)
+	}
 	if searchPath == "" {
 		var err error
 		searchPath, err = os.Getwd()
@@ -83,31 +97,46 @@ func (t *GlobTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		searchPath = filepath.Join(cwd, searchPath)
 	}
 
-	pattern := filepath.Join(searchPath, args.Pattern)
-
-	matches, err := doublestar.FilepathGlob(pattern)
-	if err != nil {
-		return tool.NewErrorResult(fmt.Sprintf("glob error: %v", err)), nil
+	// Walk only below the pattern's fixed prefix
+	pattern := filepath.ToSlash(filepath.Join(searchPath, args.Pattern))
+	if !doublestar.ValidatePattern(pattern) {
+		return tool.NewErrorResult(fmt.Sprintf("glob error: %v", doublestar.ErrBadPattern)), nil
 	}
-
-	if len(matches) == 0 {
+	base, rest := doublestar.SplitPattern(pattern)
+	root := filepath.FromSlash(base)
+	if _, err := sandbox.Resolve(root); err != nil {
+		return tool.NewErrorResult(err.Error()), nil
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
 		return tool.NewResult("No files matched the pattern"), nil
 	}
 
-	// Get file info for sorting by modification time
 	var files []fileInfo
-	for _, match := range matches {
-		info, err := os.Stat(match)
+	err := ignore.Walk(ctx, root, args.NoIgnore, func(path string, d fs.DirEntry) error {
+		rel, err := filepath.Rel(root, path)
 		if err != nil {
-			continue
+			return nil
 		}
-		if info.IsDir() {
-			continue
+		if ok, _ := doublestar.Match(rest, filepath.ToSlash(rel)); !ok {
+			return nil
+		}
+		// Stat follows links, so the time is the target's
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil
 		}
 		files = append(files, fileInfo{
-			path:    match,
+			path:    path,
 			modTime: info.ModTime().Unix(),
 		})
+		return nil
+	})
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("glob error: %v", err)), nil
+	}
+
+	if len(files) == 0 {
+		return tool.NewResult("No files matched the pattern"), nil
 	}
 
 	// Sort by modification time (newest first)
@@ -133,3 +162,14 @@ func (t *GlobTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 
 	return tool.NewResult(result), nil
 }
+
+// ReportedPaths implements tool.PathReporter with the files listed
+func (t *GlobTool) ReportedPaths(args json.RawMessage, result tool.Result) []string {
+	var paths []string
+	for _, line := range strings.Split(result.Content, "\n") {
+		if filepath.IsAbs(line) {
+			paths = append(paths, line)
+		}
+	}
+	return paths
+}
@@ -2,19 +2,31 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/bmatcuk/doublestar/v4"
 
+	"groq-go/internal/ignore"
 	"groq-go/internal/tool"
 )
 
+// GrepMaxFileSize is the largest file Grep searches when walking a
+// directory; larger files are skipped. Set from grep_max_file_size in config.
+var GrepMaxFileSize int64 = 2 << 20
+
 type GrepTool struct{}
 
 type GrepArgs struct {
@@ -24,6 +36,8 @@ type GrepArgs struct {
 	OutputMode string `json:"output_mode,omitempty"`
 	Context    int    `json:"context,omitempty"`
 	HeadLimit  int    `json:"head_limit,omitempty"`
+	// NoIgnore includes files excluded by .gitignore and ignore.DefaultExcludes
+	NoIgnore bool `json:"no_ignore,omitempty"`
 }
 
 func NewGrepTool() *GrepTool {
@@ -35,7 +49,8 @@ func (t *GrepTool) Name() string {
 }
 
 func (t *GrepTool) Description() string {
-	return "Search for patterns in files using regular expressions. Supports glob filters for file types."
+	return "Search for patterns in files using regular expressions. Supports glob filters for file types. " +
+		"Skips binary files, files excluded by .gitignore, and node_modules, .git, dist, target and *.min.js, unless no_ignore is set."
 }
 
 func (t *GrepTool) Parameters() map[string]any {
@@ -65,7 +80,11 @@ func (t *GrepTool) Parameters() map[string]any {
 			},
 			"head_limit": map[string]any{
 				"type":        "integer",
-				"description": "Limit output to first N matches",
+				"description": "Limit output to the first N matching lines, or N files in files_with_matches mode (default 100)",
+			},
+			"no_ignore": map[string]any{
+				"type":        "boolean",
+				"description": "Include files excluded by .gitignore and the default exclusions",
 			},
 		},
 		"required": []string{"pattern"},
@@ -76,6 +95,7 @@ type grepMatch struct {
 	file    string
 	line    int
 	content string
+	match   bool // false for context lines
 }
 
 func (t *GrepTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.Result, error) {
@@ -93,7 +113,12 @@ func (t *GrepTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		return tool.NewErrorResult(fmt.Sprintf("invalid regex pattern: %v", err)), nil
 	}
 
+	// Inside a project, paths are relative to its root and stay within it
+	sandbox := tool.SandboxFromContext(ctx)
 	searchPath := args.Path
+	if sandbox != nil {
+		searchPath = sandbox.Abs(searchPath)
+	}
 	if searchPath == "" {
 		searchPath, _ = os.Getwd()
 	}
@@ -102,6 +127,9 @@ func (t *GrepTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		cwd, _ := os.Getwd()
 		searchPath = filepath.Join(cwd, searchPath)
 	}
+	if _, err := sandbox.Resolve(searchPath); err != nil {
+		return tool.NewErrorResult(err.Error()), nil
+	}
 
 	outputMode := args.OutputMode
 	if outputMode == "" {
@@ -109,7 +137,7 @@ func (t *GrepTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 	}
 
 	headLimit := args.HeadLimit
-	if headLimit == 0 {
+	if headLimit <= 0 {
 		headLimit = 100
 	}
 
@@ -125,43 +153,38 @@ func (t *GrepTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		if args.Glob != "" {
 			globPattern = "**/" + args.Glob
 		}
-		pattern := filepath.Join(searchPath, globPattern)
-		matches, err := doublestar.FilepathGlob(pattern)
-		if err != nil {
-			return tool.NewErrorResult(fmt.Sprintf("glob error: %v", err)), nil
+		if !doublestar.ValidatePattern(globPattern) {
+			return tool.NewErrorResult(fmt.Sprintf("glob error: %v", doublestar.ErrBadPattern)), nil
 		}
-		for _, m := range matches {
-			info, err := os.Stat(m)
-			if err == nil && !info.IsDir() {
-				files = append(files, m)
+		err := ignore.Walk(ctx, searchPath, args.NoIgnore, func(path string, d fs.DirEntry) error {
+			rel, err := filepath.Rel(searchPath, path)
+			if err != nil {
+				return nil
+			}
+			if ok, _ := doublestar.Match(globPattern, filepath.ToSlash(rel)); !ok {
+				return nil
+			}
+			if info, err := d.Info(); err == nil && info.Size() > GrepMaxFileSize {
+				return nil
 			}
+			// Links may point out of the project
+			if d.Type()&fs.ModeSymlink != 0 && !sandbox.Contains(path) {
+				return nil
+			}
+			files = append(files, path)
+			return nil
+		})
+		if err != nil {
+			return tool.NewErrorResult(fmt.Sprintf("search error: %v", err)), nil
 		}
 	} else {
 		files = []string{searchPath}
 	}
 
-	var matches []grepMatch
-	matchedFiles := make(map[string]bool)
-	matchCount := 0
-
-	for _, file := range files {
-		if matchCount >= headLimit {
-			break
-		}
-
-		fileMatches, err := searchFile(file, re, args.Context)
-		if err != nil {
-			continue
-		}
-
-		for _, m := range fileMatches {
-			if matchCount >= headLimit {
-				break
-			}
-			matches = append(matches, m)
-			matchedFiles[m.file] = true
-			matchCount++
-		}
+	filesOnly := outputMode == "files_with_matches"
+	matches, err := grepFiles(ctx, files, re, args.Context, headLimit, filesOnly)
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("search error: %v", err)), nil
 	}
 
 	if len(matches) == 0 {
@@ -169,9 +192,9 @@ func (t *GrepTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 	}
 
 	var result strings.Builder
-	if outputMode == "files_with_matches" {
-		for file := range matchedFiles {
-			result.WriteString(file)
+	if filesOnly {
+		for _, m := range matches {
+			result.WriteString(m.file)
 			result.WriteString("\n")
 		}
 	} else {
@@ -191,52 +214,158 @@ func (t *GrepTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 	return tool.NewResult(strings.TrimSpace(result.String())), nil
 }
 
-func searchFile(path string, re *regexp.Regexp, contextLines int) ([]grepMatch, error) {
-	file, err := os.Open(path)
-	if err != nil {
+// grepWorkers bounds the files searched at once
+var grepWorkers = runtime.NumCPU()
+
+// grepFiles searches files with a pool of grepWorkers and returns, ordered
+// by path and line, up to limit matching lines with their context, or in
+// filesOnly mode one match from each of up to limit files. Files are
+// handed out in path order and dispatch stops once limit matches are in,
+// so the files searched always start the list and the result is the same
+// as a sequential search.
+func grepFiles(ctx context.Context, files []string, re *regexp.Regexp, contextLines, limit int, filesOnly bool) ([]grepMatch, error) {
+	files = slices.Clone(files)
+	slices.Sort(files)
+
+	// A file never needs to give more than the whole result
+	perFile := limit
+	if filesOnly {
+		perFile, contextLines = 1, 0
+	}
+
+	results := make([][]grepMatch, len(files))
+	var found atomic.Int64
+	jobs := make(chan int, grepWorkers)
+	var wg sync.WaitGroup
+	for w := 0; w < min(grepWorkers, len(files)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fileMatches, err := searchFile(ctx, files[i], re, contextLines, perFile)
+				if err != nil || len(fileMatches) == 0 {
+					continue
+				}
+				results[i] = fileMatches
+				found.Add(int64(countMatches(fileMatches)))
+			}
+		}()
+	}
+
+dispatch:
+	for i := range files {
+		if found.Load() >= int64(limit) {
+			break
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
+	// Keep the first limit matches; context lines after the last one stay,
+	// those leading up to the next one go
 	var matches []grepMatch
-	var lines []string
-	scanner := bufio.NewScanner(file)
+	count, lastLine := 0, 0
+	for _, fileMatches := range results {
+		for _, m := range fileMatches {
+			if m.match {
+				if count == limit {
+					return matches, nil
+				}
+				count++
+				lastLine = m.line
+			} else if count == limit && m.line > lastLine+contextLines {
+				return matches, nil
+			}
+			matches = append(matches, m)
+		}
+		if count == limit && len(fileMatches) > 0 {
+			return matches, nil
+		}
+	}
+	return matches, nil
+}
 
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+// countMatches counts the matching lines, not the context
+func countMatches(matches []grepMatch) int {
+	n := 0
+	for _, m := range matches {
+		if m.match {
+			n++
+		}
 	}
+	return n
+}
 
-	if err := scanner.Err(); err != nil {
+// searchFile streams path and returns up to maxMatches matching lines,
+// each with contextLines lines around it. Only the context window is held
+// in memory, and the file is closed as soon as maxMatches are found.
+func searchFile(ctx context.Context, path string, re *regexp.Regexp, contextLines, maxMatches int) ([]grepMatch, error) {
+	file, err := os.Open(path)
+	if err != nil {
 		return nil, err
 	}
+	defer file.Close()
+
+	// Binary files are skipped; the sniffed bytes are searched with the rest
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	if isBinary(head) {
+		return nil, nil
+	}
+
+	var matches []grepMatch
+	var before []grepMatch // Up to contextLines lines before the current one
+	after := 0             // Context lines still owed to the last match
+	found := 0
+	lineNum := 0
+	scanner := bufio.NewScanner(io.MultiReader(bytes.NewReader(head), file))
+	for scanner.Scan() {
+		lineNum++
+		if lineNum%1024 == 0 && ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		line := scanner.Text()
 
-	for i, line := range lines {
 		if re.MatchString(line) {
-			if contextLines > 0 {
-				start := i - contextLines
-				if start < 0 {
-					start = 0
-				}
-				end := i + contextLines + 1
-				if end > len(lines) {
-					end = len(lines)
-				}
-				for j := start; j < end; j++ {
-					matches = append(matches, grepMatch{
-						file:    path,
-						line:    j + 1,
-						content: lines[j],
-					})
-				}
-			} else {
-				matches = append(matches, grepMatch{
-					file:    path,
-					line:    i + 1,
-					content: line,
-				})
+			if found == maxMatches {
+				break
 			}
+			matches = append(matches, before...)
+			before = before[:0]
+			matches = append(matches, grepMatch{file: path, line: lineNum, content: line, match: true})
+			found++
+			after = contextLines
+			continue
+		}
+		if after > 0 {
+			matches = append(matches, grepMatch{file: path, line: lineNum, content: line})
+			after--
+			continue
+		}
+		if found == maxMatches {
+			break
+		}
+		if contextLines > 0 {
+			if len(before) == contextLines {
+				before = append(before[:0], before[1:]...)
+			}
+			before = append(before, grepMatch{file: path, line: lineNum, content: line})
 		}
 	}
-
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
 	return matches, nil
 }
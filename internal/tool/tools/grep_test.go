@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// grepCorpus builds a fixed tree with matches spread over many files
+func grepCorpus(t testing.TB, files, lines int) string {
+	tree := make(map[string]string)
+	for f := 0; f < files; f++ {
+		var sb strings.Builder
+		for l := 1; l <= lines; l++ {
+			if (f+l)%7 == 0 {
+				fmt.Fprintf(&sb, "func handler%d() { // TODO\n", l)
+			} else {
+				fmt.Fprintf(&sb, "\tx := %d\n", l)
+			}
+		}
+		tree[fmt.Sprintf("pkg%d/file%d.go", f%5, f)] = sb.String()
+	}
+	return makeTree(t, tree)
+}
+
+// withGrepWorkers runs Grep with n workers
+func withGrepWorkers(t *testing.T, n int, args GrepArgs) string {
+	t.Helper()
+	orig := grepWorkers
+	grepWorkers = n
+	defer func() { grepWorkers = orig }()
+	result := runTool(t, context.Background(), NewGrepTool(), args)
+	if result.IsError {
+		t.Fatalf("Unexpected error: %s", result.Content)
+	}
+	return result.Content
+}
+
+func TestGrepParallelMatchesSequential(t *testing.T) {
+	root := grepCorpus(t, 40, 50)
+	cases := []GrepArgs{
+		{Pattern: "TODO", Path: root},
+		{Pattern: "TODO", Path: root, HeadLimit: 7},
+		{Pattern: "TODO", Path: root, OutputMode: "content"},
+		{Pattern: "TODO", Path: root, OutputMode: "content", Context: 2},
+		{Pattern: "TODO", Path: root, OutputMode: "content", Context: 3, HeadLimit: 25},
+		{Pattern: `x := 4\d`, Path: root, OutputMode: "content", HeadLimit: 1000},
+		{Pattern: "nothing matches this", Path: root},
+	}
+	for _, args := range cases {
+		want := withGrepWorkers(t, 1, args)
+		for _, workers := range []int{2, 8, 32} {
+			// Repeat, since scheduling differs from run to run
+			for i := 0; i < 5; i++ {
+				if got := withGrepWorkers(t, workers, args); got != want {
+					t.Fatalf("%+v with %d workers differs from sequential:\n%s\n---\n%s", args, workers, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestGrepOrderingAndContext(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		"b.txt":     "one\nmatch\ntwo\nthree\nmatch\nmatch\nfour\nfive\nsix\n",
+		"a.txt":     "match\n",
+		"c/d.txt":   "nope\n",
+		"a/inner.c": "x\nmatch\n",
+	})
+
+	got := withGrepWorkers(t, 4, GrepArgs{Pattern: "match", Path: root, OutputMode: "content", Context: 1})
+	want := strings.Join([]string{
+		"=== " + filepath.Join(root, "a.txt") + " ===",
+		"1: match",
+		"",
+		"=== " + filepath.Join(root, "a", "inner.c") + " ===",
+		"1: x",
+		"2: match",
+		"",
+		"=== " + filepath.Join(root, "b.txt") + " ===",
+		"1: one",
+		"2: match",
+		"3: two",
+		"4: three",
+		"5: match",
+		"6: match",
+		"7: four",
+	}, "\n")
+	if got != want {
+		t.Errorf("Expected\n%s\ngot\n%s", want, got)
+	}
+
+	// The limit counts matching lines; trailing context of the last one stays
+	got = withGrepWorkers(t, 4, GrepArgs{Pattern: "match", Path: root, OutputMode: "content", Context: 1, HeadLimit: 3})
+	if !strings.HasSuffix(got, "1: one\n2: match\n3: two") {
+		t.Errorf("Expected the output cut after the third match, got\n%s", got)
+	}
+
+	got = withGrepWorkers(t, 4, GrepArgs{Pattern: "match", Path: root, HeadLimit: 2})
+	if want := filepath.Join(root, "a.txt") + "\n" + filepath.Join(root, "a", "inner.c"); got != want {
+		t.Errorf("Expected the first two files in path order, got %q", got)
+	}
+}
+
+func BenchmarkGrepWorkers(b *testing.B) {
+	root := grepCorpus(b, 400, 2000)
+	grep := NewGrepTool()
+	for name, workers := range map[string]int{"sequential": 1, "parallel": grepWorkers} {
+		b.Run(name, func(b *testing.B) {
+			orig := grepWorkers
+			grepWorkers = workers
+			defer func() { grepWorkers = orig }()
+			args := fmt.Sprintf(`{"pattern":"handler1\\d+","path":%q,"output_mode":"content","head_limit":100000}`, root)
+			for i := 0; i < b.N; i++ {
+				if _, err := grep.Execute(context.Background(), []byte(args)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestGrepAndGlobStayInSandbox(t *testing.T) {
+	ctx, root, _ := sandboxContext(t)
+	os.WriteFile(filepath.Join(root, "notes.txt"), []byte("token\n"), 0644)
+
+	result := runTool(t, ctx, NewGrepTool(), GrepArgs{Pattern: "token"})
+	if result.Content != filepath.Join(root, "notes.txt") {
+		t.Errorf("Expected only the project's own match, not the linked secret, got %q", result.Content)
+	}
+	for _, path := range []string{"..", "link.txt"} {
+		if result := runTool(t, ctx, NewGrepTool(), GrepArgs{Pattern: "token", Path: path}); !result.IsError {
+			t.Errorf("Expected Grep in %s refused, got %q", path, result.Content)
+		}
+	}
+
+	if result := runTool(t, ctx, NewGlobTool(), GlobArgs{Pattern: "*.go"}); result.Content != filepath.Join(root, "main.go") {
+		t.Errorf("Expected Glob to default to the project root, got %q", result.Content)
+	}
+	if result := runTool(t, ctx, NewGlobTool(), GlobArgs{Pattern: "../*.txt"}); !result.IsError {
+		t.Errorf("Expected a pattern leaving the project refused, got %q", result.Content)
+	}
+}
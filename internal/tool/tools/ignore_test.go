@@ -0,0 +1,125 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// makeTree creates files, by slash-separated relative path, in a temp dir
+func makeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestGlobRespectsIgnores(t *testing.T) {
+	root := makeTree(t, map[string]string{
+		".gitignore":          "generated/\n",
+		"src/app.js":          "",
+		"src/app.min.js":      "",
+		"generated/api.js":    "",
+		"node_modules/lib.js": "",
+	})
+
+	result := runTool(t, context.Background(), NewGlobTool(), GlobArgs{Pattern: "**/*.js", Path: root})
+	if result.Content != filepath.Join(root, "src", "app.js") {
+		t.Errorf("Expected only src/app.js, got %q", result.Content)
+	}
+
+	result = runTool(t, context.Background(), NewGlobTool(), GlobArgs{Pattern: "**/*.js", Path: root, NoIgnore: true})
+	if n := len(strings.Split(result.Content, "\n")); n != 4 {
+		t.Errorf("Expected all 4 files with no_ignore, got %q", result.Content)
+	}
+
+	// Patterns without ** only look where they point
+	result = runTool(t, context.Background(), NewGlobTool(), GlobArgs{Pattern: "src/*.js", Path: root})
+	if result.Content != filepath.Join(root, "src", "app.js") {
+		t.Errorf("Expected src/app.js, got %q", result.Content)
+	}
+}
+
+func TestGrepSkipsIgnoredBinaryAndLargeFiles(t *testing.T) {
+	orig := GrepMaxFileSize
+	GrepMaxFileSize = 100
+	t.Cleanup(func() { GrepMaxFileSize = orig })
+
+	root := makeTree(t, map[string]string{
+		".gitignore":          "*.log\n",
+		"main.go":             "needle\n",
+		"server.log":          "needle\n",
+		"data.bin":            "needle\x00\x01",
+		"large.txt":           "needle\n" + strings.Repeat("x", 200),
+		"node_modules/lib.js": "needle\n",
+	})
+
+	result := runTool(t, context.Background(), NewGrepTool(), GrepArgs{Pattern: "needle", Path: root})
+	if result.Content != filepath.Join(root, "main.go") {
+		t.Errorf("Expected only main.go, got %q", result.Content)
+	}
+
+	result = runTool(t, context.Background(), NewGrepTool(), GrepArgs{Pattern: "needle", Path: root, NoIgnore: true})
+	for _, name := range []string{"main.go", "server.log", "node_modules/lib.js"} {
+		if !strings.Contains(result.Content, filepath.Join(root, filepath.FromSlash(name))) {
+			t.Errorf("Expected %s with no_ignore, got %q", name, result.Content)
+		}
+	}
+	if strings.Contains(result.Content, "data.bin") || strings.Contains(result.Content, "large.txt") {
+		t.Errorf("Expected binary and large files skipped even with no_ignore, got %q", result.Content)
+	}
+}
+
+// benchTree builds a small project next to a large ignored dependency tree
+func benchTree(b *testing.B) string {
+	files := map[string]string{".gitignore": "/deps/\n"}
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("src/file%d.go", i)] = "package src\n\n// needle\n"
+	}
+	for i := 0; i < 2000; i++ {
+		files[fmt.Sprintf("node_modules/pkg%d/index.js", i)] = strings.Repeat("module.exports = {}\n", 50)
+		files[fmt.Sprintf("deps/lib%d/lib.go", i)] = strings.Repeat("package lib\n", 50)
+	}
+	return makeTree(b, files)
+}
+
+func BenchmarkGrepIgnoredTree(b *testing.B) {
+	root := benchTree(b)
+	grep := NewGrepTool()
+	for _, noIgnore := range []bool{false, true} {
+		b.Run(fmt.Sprintf("no_ignore=%v", noIgnore), func(b *testing.B) {
+			args := fmt.Sprintf(`{"pattern":"needle","path":%q,"no_ignore":%v}`, root, noIgnore)
+			for i := 0; i < b.N; i++ {
+				if _, err := grep.Execute(context.Background(), []byte(args)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkGlobIgnoredTree(b *testing.B) {
+	root := benchTree(b)
+	glob := NewGlobTool()
+	for _, noIgnore := range []bool{false, true} {
+		b.Run(fmt.Sprintf("no_ignore=%v", noIgnore), func(b *testing.B) {
+			args := fmt.Sprintf(`{"pattern":"**/*.go","path":%q,"no_ignore":%v}`, root, noIgnore)
+			for i := 0; i < b.N; i++ {
+				if _, err := glob.Execute(context.Background(), []byte(args)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
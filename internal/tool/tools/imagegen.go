@@ -5,26 +5,65 @@ import (
 	"context"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	_ "image/gif" // Decoders for thumbnails
+	_ "image/jpeg"
+	"image/png"
 	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"groq-go/internal/tool"
 )
 
+// thumbSize bounds the longer side of an image preview, in pixels
+const thumbSize = 256
+
+// Provider endpoints, replaced in tests
+var (
+	stabilityAPIURL = "https://api.stability.ai/v1/generation"
+	openAIImagesURL = "https://api.openai.com/v1/images/generations"
+	falAPIURL       = "https://fal.run"
+)
+
+// imageProvider is a text-to-image backend
+type imageProvider struct {
+	name         string
+	keyEnv       string
+	defaultModel string
+	generate     func(t *ImageGenTool, ctx context.Context, key, model string, args ImageGenArgs) ([]byte, error)
+}
+
+// imageProviders are tried in this order when no provider is given
+var imageProviders = []imageProvider{
+	{"stability", "STABILITY_API_KEY", "stable-diffusion-xl-1024-v1-0", (*ImageGenTool).generateWithStability},
+	{"openai", "OPENAI_API_KEY", "dall-e-3", (*ImageGenTool).generateWithOpenAI},
+	{"fal", "FAL_API_KEY", "fal-ai/flux/schnell", (*ImageGenTool).generateWithFal},
+}
+
+// ImageDir returns the directory generated images are saved to by default
+func ImageDir() string {
+	home, _ := os.UserHomeDir()
+	return filepath.Join(home, ".config", "groq-go", "images")
+}
+
 type ImageGenTool struct {
 	client *http.Client
 }
 
 type ImageGenArgs struct {
-	Prompt   string `json:"prompt"`
-	Style    string `json:"style,omitempty"`
-	Width    int    `json:"width,omitempty"`
-	Height   int    `json:"height,omitempty"`
+	Prompt     string `json:"prompt"`
+	Style      string `json:"style,omitempty"`
+	Width      int    `json:"width,omitempty"`
+	Height     int    `json:"height,omitempty"`
 	OutputPath string `json:"output_path,omitempty"`
+	Provider   string `json:"provider,omitempty"`
+	Model      string `json:"model,omitempty"`
 }
 
 func NewImageGenTool() *ImageGenTool {
@@ -38,9 +77,9 @@ func (t *ImageGenTool) Name() string {
 }
 
 func (t *ImageGenTool) Description() string {
-	return `Generate images from text prompts using Stability AI or OpenAI DALL-E.
+	return `Generate images from text prompts using Stability AI, OpenAI (dall-e-3, gpt-image-1) or fal.ai (FLUX).
 
-Requires STABILITY_API_KEY or OPENAI_API_KEY environment variable.
+Requires STABILITY_API_KEY, OPENAI_API_KEY or FAL_API_KEY. Without a provider, the configured ones are tried in that order.
 
 Example prompts:
 - "A futuristic city at sunset, cyberpunk style"
@@ -72,6 +111,15 @@ func (t *ImageGenTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "Path to save the image (default: auto-generated)",
 			},
+			"provider": map[string]any{
+				"type":        "string",
+				"description": "Image provider (default: the first configured)",
+				"enum":        []string{"stability", "openai", "fal"},
+			},
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Provider model, e.g. 'stable-diffusion-xl-1024-v1-0', 'dall-e-3', 'gpt-image-1' or 'fal-ai/flux/dev'",
+			},
 		},
 		"required": []string{"prompt"},
 	}
@@ -95,81 +143,183 @@ func (t *ImageGenTool) Execute(ctx context.Context, argsJSON json.RawMessage) (t
 		args.Height = 1024
 	}
 
-	// Try Stability AI first, then OpenAI
-	stabilityKey := os.Getenv("STABILITY_API_KEY")
-	openaiKey := os.Getenv("OPENAI_API_KEY")
+	candidates, err := imageCandidates(args.Provider, args.Model)
+	if err != nil {
+		return tool.NewErrorResult(err.Error()), nil
+	}
 
+	// Fall back to the next provider when one fails
+	tool.ReportProgress(ctx, "generating", -1)
 	var imageData []byte
-	var err error
-
-	if stabilityKey != "" {
-		imageData, err = t.generateWithStability(ctx, stabilityKey, args)
-	} else if openaiKey != "" {
-		imageData, err = t.generateWithOpenAI(ctx, openaiKey, args)
-	} else {
-		return tool.NewErrorResult("No API key found. Set STABILITY_API_KEY or OPENAI_API_KEY"), nil
+	var used imageProvider
+	var failures []string
+	for _, p := range candidates {
+		model := args.Model
+		if model == "" {
+			model = p.defaultModel
+		}
+		imageData, err = p.generate(t, ctx, os.Getenv(p.keyEnv), model, args)
+		if err == nil {
+			used = p
+			args.Model = model
+			break
+		}
+		if ctx.Err() != nil {
+			return tool.NewErrorResult(fmt.Sprintf("Image generation canceled: %v", ctx.Err())), nil
+		}
+		failures = append(failures, fmt.Sprintf("%s: %v", p.name, err))
 	}
-
-	if err != nil {
-		return tool.NewErrorResult(fmt.Sprintf("Image generation failed: %v", err)), nil
+	if imageData == nil {
+		return tool.NewErrorResult("Image generation failed: " + strings.Join(failures, "; ")), nil
 	}
 
 	// Determine output path
 	outputPath := args.OutputPath
 	if outputPath == "" {
-		home, _ := os.UserHomeDir()
-		outputDir := filepath.Join(home, ".config", "groq-go", "images")
+		outputDir := ImageDir()
 		os.MkdirAll(outputDir, 0755)
-		outputPath = filepath.Join(outputDir, fmt.Sprintf("image_%d.png", time.Now().UnixNano()))
+		outputPath = filepath.Join(outputDir, fmt.Sprintf("image_%d%s", time.Now().UnixNano(), imageExtension(imageData)))
 	}
 
 	// Save image
+	tool.ReportProgress(ctx, "saving output", 100)
 	if err := os.WriteFile(outputPath, imageData, 0644); err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("Failed to save image: %v", err)), nil
 	}
 
-	return tool.NewResult(fmt.Sprintf("Image generated and saved to: %s", outputPath)), nil
+	result := tool.NewResult(fmt.Sprintf("Image generated with %s (%s) and saved to: %s", used.name, args.Model, outputPath))
+	result.Metadata = map[string]string{tool.MetadataImagePath: outputPath}
+	if thumb := imageThumbnail(imageData); thumb != "" {
+		result.Metadata[tool.MetadataImageThumb] = thumb
+	}
+	return result, nil
 }
 
-func (t *ImageGenTool) generateWithStability(ctx context.Context, apiKey string, args ImageGenArgs) ([]byte, error) {
-	// Use Stability AI's text-to-image endpoint
-	url := "https://api.stability.ai/v1/generation/stable-diffusion-xl-1024-v1-0/text-to-image"
+// imageCandidates returns the providers to try, in order. An explicit
+// provider is the only candidate; a model name implies its provider.
+func imageCandidates(provider, model string) ([]imageProvider, error) {
+	if provider == "" {
+		switch {
+		case model == "dall-e-2" || model == "dall-e-3" || strings.HasPrefix(model, "gpt-image"):
+			provider = "openai"
+		case strings.HasPrefix(model, "fal-ai/"):
+			provider = "fal"
+		case strings.HasPrefix(model, "stable-diffusion"):
+			provider = "stability"
+		}
+	}
 
-	reqBody := map[string]any{
-		"text_prompts": []map[string]any{
-			{"text": args.Prompt, "weight": 1},
-		},
-		"cfg_scale": 7,
-		"width":     args.Width,
-		"height":    args.Height,
-		"samples":   1,
-		"steps":     30,
+	var candidates []imageProvider
+	for _, p := range imageProviders {
+		if provider != "" && p.name != provider {
+			continue
+		}
+		if os.Getenv(p.keyEnv) == "" {
+			if provider != "" {
+				return nil, fmt.Errorf("provider %s needs %s", provider, p.keyEnv)
+			}
+			continue
+		}
+		candidates = append(candidates, p)
+	}
+	if provider != "" && len(candidates) == 0 {
+		return nil, fmt.Errorf("unknown provider %q (use stability, openai or fal)", provider)
 	}
+	if len(candidates) == 0 {
+		return nil, errors.New("No API key found. Set STABILITY_API_KEY, OPENAI_API_KEY or FAL_API_KEY")
+	}
+	return candidates, nil
+}
 
-	if args.Style != "" {
-		reqBody["style_preset"] = args.Style
+// imageExtension picks a file extension from the image's content
+func imageExtension(data []byte) string {
+	switch http.DetectContentType(data) {
+	case "image/jpeg":
+		return ".jpg"
+	case "image/webp":
+		return ".webp"
+	case "image/gif":
+		return ".gif"
+	default:
+		return ".png"
 	}
+}
 
-	bodyBytes, _ := json.Marshal(reqBody)
+// imageThumbnail returns a base64 PNG at most thumbSize pixels on a side,
+// or "" for formats that cannot be decoded
+func imageThumbnail(data []byte) string {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return ""
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w == 0 || h == 0 {
+		return ""
+	}
+	tw, th := w, h
+	if w > thumbSize || h > thumbSize {
+		if w >= h {
+			tw, th = thumbSize, max(1, h*thumbSize/w)
+		} else {
+			tw, th = max(1, w*thumbSize/h), thumbSize
+		}
+	}
+
+	// Nearest neighbour is plenty for a preview
+	dst := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		for x := 0; x < tw; x++ {
+			dst.Set(x, y, src.At(b.Min.X+x*w/tw, b.Min.Y+y*h/th))
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, dst); err != nil {
+		return ""
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// postJSON sends body to url and decodes a successful JSON response into out
+func (t *ImageGenTool) postJSON(ctx context.Context, url string, body any, headers map[string]string, out any) error {
+	bodyBytes, _ := json.Marshal(body)
 
 	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(bodyBytes))
 	if err != nil {
-		return nil, err
+		return err
 	}
-
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
-	req.Header.Set("Accept", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
 
 	resp, err := t.client.Do(req)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (t *ImageGenTool) generateWithStability(ctx context.Context, apiKey, model string, args ImageGenArgs) ([]byte, error) {
+	reqBody := map[string]any{
+		"text_prompts": []map[string]any{
+			{"text": args.Prompt, "weight": 1},
+		},
+		"cfg_scale": 7,
+		"width":     args.Width,
+		"height":    args.Height,
+		"samples":   1,
+		"steps":     30,
+	}
+
+	if args.Style != "" {
+		reqBody["style_preset"] = args.Style
 	}
 
 	var result struct {
@@ -177,8 +327,9 @@ func (t *ImageGenTool) generateWithStability(ctx context.Context, apiKey string,
 			Base64 string `json:"base64"`
 		} `json:"artifacts"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	url := stabilityAPIURL + "/" + model + "/text-to-image"
+	headers := map[string]string{"Authorization": "Bearer " + apiKey, "Accept": "application/json"}
+	if err := t.postJSON(ctx, url, reqBody, headers, &result); err != nil {
 		return nil, err
 	}
 
@@ -189,62 +340,96 @@ func (t *ImageGenTool) generateWithStability(ctx context.Context, apiKey string,
 	return base64.StdEncoding.DecodeString(result.Artifacts[0].Base64)
 }
 
-func (t *ImageGenTool) generateWithOpenAI(ctx context.Context, apiKey string, args ImageGenArgs) ([]byte, error) {
-	// Use OpenAI's DALL-E endpoint
-	url := "https://api.openai.com/v1/images/generations"
-
-	// DALL-E 3 only supports certain sizes
-	size := "1024x1024"
-	if args.Width >= 1792 || args.Height >= 1792 {
-		size = "1792x1024"
-	}
-
+func (t *ImageGenTool) generateWithOpenAI(ctx context.Context, apiKey, model string, args ImageGenArgs) ([]byte, error) {
 	reqBody := map[string]any{
-		"model":           "dall-e-3",
-		"prompt":          args.Prompt,
-		"n":               1,
-		"size":            size,
-		"response_format": "b64_json",
+		"model":  model,
+		"prompt": args.Prompt,
+		"n":      1,
+		"size":   openAIImageSize(model, args.Width, args.Height),
 	}
 
-	if args.Style != "" {
-		reqBody["style"] = args.Style
+	// gpt-image-1 always returns base64 and has no style option
+	if !strings.HasPrefix(model, "gpt-image") {
+		reqBody["response_format"] = "b64_json"
+		if args.Style != "" {
+			reqBody["style"] = args.Style
+		}
 	}
 
-	bodyBytes, _ := json.Marshal(reqBody)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(bodyBytes))
-	if err != nil {
+	var result struct {
+		Data []struct {
+			B64JSON string `json:"b64_json"`
+		} `json:"data"`
+	}
+	if err := t.postJSON(ctx, openAIImagesURL, reqBody, map[string]string{"Authorization": "Bearer " + apiKey}, &result); err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	if len(result.Data) == 0 {
+		return nil, fmt.Errorf("no image generated")
+	}
 
-	resp, err := t.client.Do(req)
-	if err != nil {
-		return nil, err
+	return base64.StdEncoding.DecodeString(result.Data[0].B64JSON)
+}
+
+// openAIImageSize maps the requested size to one the model supports
+func openAIImageSize(model string, width, height int) string {
+	wide, tall := "1792x1024", "1024x1792"
+	if strings.HasPrefix(model, "gpt-image") {
+		wide, tall = "1536x1024", "1024x1536"
 	}
-	defer resp.Body.Close()
+	switch {
+	case width > height:
+		return wide
+	case height > width:
+		return tall
+	default:
+		return "1024x1024"
+	}
+}
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+func (t *ImageGenTool) generateWithFal(ctx context.Context, apiKey, model string, args ImageGenArgs) ([]byte, error) {
+	reqBody := map[string]any{
+		"prompt":     args.Prompt,
+		"image_size": map[string]int{"width": args.Width, "height": args.Height},
+		"num_images": 1,
+		"sync_mode":  true,
 	}
 
 	var result struct {
-		Data []struct {
-			B64JSON string `json:"b64_json"`
-		} `json:"data"`
+		Images []struct {
+			URL string `json:"url"`
+		} `json:"images"`
 	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+	if err := t.postJSON(ctx, falAPIURL+"/"+model, reqBody, map[string]string{"Authorization": "Key " + apiKey}, &result); err != nil {
 		return nil, err
 	}
 
-	if len(result.Data) == 0 {
+	if len(result.Images) == 0 {
 		return nil, fmt.Errorf("no image generated")
 	}
 
-	return base64.StdEncoding.DecodeString(result.Data[0].B64JSON)
+	// sync_mode returns a data URL; otherwise the image is hosted
+	url := result.Images[0].URL
+	if rest, ok := strings.CutPrefix(url, "data:"); ok {
+		_, data, ok := strings.Cut(rest, ";base64,")
+		if !ok {
+			return nil, fmt.Errorf("unsupported image data URL")
+		}
+		return base64.StdEncoding.DecodeString(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("image download failed: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
 }
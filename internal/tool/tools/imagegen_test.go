@@ -0,0 +1,186 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/tool"
+)
+
+// testPNG encodes a w x h image
+func testPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		img.Set(x, 0, color.RGBA{R: 255, A: 255})
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// imageStubs points the providers at one stub server. Each provider's
+// handler is looked up by path prefix; requests are recorded by path.
+func imageStubs(t *testing.T, handlers map[string]http.HandlerFunc) map[string][]byte {
+	t.Helper()
+	requests := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests[r.URL.Path] = body
+		for prefix, h := range handlers {
+			if strings.HasPrefix(r.URL.Path, prefix) {
+				h(w, r)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(srv.Close)
+
+	origStability, origOpenAI, origFal := stabilityAPIURL, openAIImagesURL, falAPIURL
+	stabilityAPIURL, openAIImagesURL, falAPIURL = srv.URL+"/stability", srv.URL+"/openai", srv.URL+"/fal"
+	t.Cleanup(func() { stabilityAPIURL, openAIImagesURL, falAPIURL = origStability, origOpenAI, origFal })
+
+	// Generated images land in a temp home
+	t.Setenv("HOME", t.TempDir())
+	for _, env := range []string{"STABILITY_API_KEY", "OPENAI_API_KEY", "FAL_API_KEY"} {
+		t.Setenv(env, "")
+	}
+	return requests
+}
+
+func TestImageGenFallsBackToNextProvider(t *testing.T) {
+	pngData := testPNG(t, 600, 300)
+	requests := imageStubs(t, map[string]http.HandlerFunc{
+		"/stability": func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "overloaded", http.StatusServiceUnavailable)
+		},
+		"/openai": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{"data": []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(pngData)}}})
+		},
+	})
+	t.Setenv("STABILITY_API_KEY", "sk-stability")
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+
+	result := runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "a red line", Width: 1792})
+	if result.IsError || !strings.Contains(result.Content, "openai (dall-e-3)") {
+		t.Fatalf("Expected OpenAI after Stability failed, got %q", result.Content)
+	}
+	if _, ok := requests["/stability/stable-diffusion-xl-1024-v1-0/text-to-image"]; !ok {
+		t.Errorf("Expected Stability tried first, got %v", requests)
+	}
+	var sent map[string]any
+	json.Unmarshal(requests["/openai"], &sent)
+	if sent["size"] != "1792x1024" || sent["response_format"] != "b64_json" {
+		t.Errorf("Unexpected OpenAI request %v", sent)
+	}
+
+	path := result.Metadata[tool.MetadataImagePath]
+	if filepath.Dir(path) != ImageDir() || filepath.Ext(path) != ".png" {
+		t.Errorf("Expected the image saved in %s, got %q", ImageDir(), path)
+	}
+	if saved, _ := os.ReadFile(path); !bytes.Equal(saved, pngData) {
+		t.Error("Expected the generated image saved as is")
+	}
+
+	thumb, err := base64.StdEncoding.DecodeString(result.Metadata[tool.MetadataImageThumb])
+	if err != nil {
+		t.Fatalf("Expected a base64 thumbnail: %v", err)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(thumb))
+	if err != nil || cfg.Width != 256 || cfg.Height != 128 {
+		t.Errorf("Expected a 256x128 PNG thumbnail, got %+v (%v)", cfg, err)
+	}
+}
+
+func TestImageGenProviderAndModel(t *testing.T) {
+	pngData := testPNG(t, 8, 8)
+	requests := imageStubs(t, map[string]http.HandlerFunc{
+		"/openai": func(w http.ResponseWriter, r *http.Request) {
+			json.NewEncoder(w).Encode(map[string]any{"data": []map[string]string{{"b64_json": base64.StdEncoding.EncodeToString(pngData)}}})
+		},
+		"/fal/fal-ai/flux/dev": func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") != "Key fal-key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			url := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData)
+			json.NewEncoder(w).Encode(map[string]any{"images": []map[string]string{{"url": url}}})
+		},
+		"/fal/fal-ai/flux/schnell": func(w http.ResponseWriter, r *http.Request) {
+			url := "http://" + r.Host + "/files/out.png"
+			json.NewEncoder(w).Encode(map[string]any{"images": []map[string]string{{"url": url}}})
+		},
+		"/files/": func(w http.ResponseWriter, r *http.Request) {
+			w.Write(pngData)
+		},
+	})
+	t.Setenv("STABILITY_API_KEY", "sk-stability")
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("FAL_API_KEY", "fal-key")
+
+	// The model picks the provider
+	result := runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "cat", Model: "gpt-image-1", Height: 1536})
+	if result.IsError || !strings.Contains(result.Content, "openai (gpt-image-1)") {
+		t.Fatalf("Expected gpt-image-1 on OpenAI, got %q", result.Content)
+	}
+	var sent map[string]any
+	json.Unmarshal(requests["/openai"], &sent)
+	if sent["size"] != "1024x1536" || sent["response_format"] != nil {
+		t.Errorf("Unexpected gpt-image-1 request %v", sent)
+	}
+
+	result = runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "cat", Provider: "fal", Model: "fal-ai/flux/dev"})
+	if result.IsError || !strings.Contains(result.Content, "fal (fal-ai/flux/dev)") {
+		t.Fatalf("Expected FLUX dev on fal, got %q", result.Content)
+	}
+
+	// Hosted results are downloaded
+	result = runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "cat", Provider: "fal"})
+	if result.IsError {
+		t.Fatalf("Expected the hosted image downloaded, got %q", result.Content)
+	}
+	if saved, _ := os.ReadFile(result.Metadata[tool.MetadataImagePath]); !bytes.Equal(saved, pngData) {
+		t.Error("Expected the downloaded image saved")
+	}
+}
+
+func TestImageGenProviderErrors(t *testing.T) {
+	imageStubs(t, nil)
+
+	result := runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "cat"})
+	if !result.IsError || !strings.Contains(result.Content, "No API key found") {
+		t.Errorf("Expected a missing key error, got %q", result.Content)
+	}
+
+	t.Setenv("STABILITY_API_KEY", "sk-stability")
+	result = runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "cat", Provider: "fal"})
+	if !result.IsError || !strings.Contains(result.Content, "FAL_API_KEY") {
+		t.Errorf("Expected the chosen provider's key to be required, got %q", result.Content)
+	}
+
+	result = runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "cat", Provider: "midjourney"})
+	if !result.IsError || !strings.Contains(result.Content, "unknown provider") {
+		t.Errorf("Expected an unknown provider error, got %q", result.Content)
+	}
+
+	// Every failure is reported when no provider succeeds
+	result = runTool(t, context.Background(), NewImageGenTool(), ImageGenArgs{Prompt: "cat"})
+	if !result.IsError || !strings.Contains(result.Content, "stability: API error 404") {
+		t.Errorf("Expected the provider error, got %q", result.Content)
+	}
+}
@@ -39,6 +39,10 @@ func (t *KnowledgeSearchTool) Parameters() map[string]any {
 				"type":        "integer",
 				"description": "Maximum number of results to return (default: 5, max: 20)",
 			},
+			"project_id": map[string]any{
+				"type":        "string",
+				"description": "Only search files ingested from this project (default: all documents)",
+			},
 		},
 		"required": []string{"query"},
 	}
@@ -52,6 +56,7 @@ func (t *KnowledgeSearchTool) Execute(ctx context.Context, args json.RawMessage)
 	var params struct {
 		Query      string `json:"query"`
 		MaxResults int    `json:"max_results"`
+		ProjectID  string `json:"project_id"`
 	}
 
 	if err := json.Unmarshal(args, &params); err != nil {
@@ -69,7 +74,7 @@ func (t *KnowledgeSearchTool) Execute(ctx context.Context, args json.RawMessage)
 		params.MaxResults = 20
 	}
 
-	results := t.kb.Search(ctx, params.Query, params.MaxResults)
+	results := t.kb.SearchProject(ctx, params.ProjectID, params.Query, params.MaxResults)
 
 	if len(results) == 0 {
 		return tool.Result{Content: "No relevant information found in the knowledge base."}, nil
@@ -79,7 +84,7 @@ func (t *KnowledgeSearchTool) Execute(ctx context.Context, args json.RawMessage)
 	sb.WriteString(fmt.Sprintf("Found %d relevant results:\n\n", len(results)))
 
 	for i, r := range results {
-		sb.WriteString(fmt.Sprintf("--- Result %d (from: %s, score: %.2f) ---\n", i+1, r.DocName, r.Score))
+		sb.WriteString(fmt.Sprintf("--- Result %d (from: %s, doc: %s, chunk: %d, score: %.2f) ---\n", i+1, r.DocName, r.DocID, r.Chunk.Position, r.Score))
 		sb.WriteString(r.Chunk.Text)
 		sb.WriteString("\n\n")
 	}
@@ -127,6 +132,10 @@ func (t *KnowledgeListTool) Execute(ctx context.Context, args json.RawMessage) (
 	sb.WriteString(fmt.Sprintf("Knowledge base contains %d documents:\n\n", len(docs)))
 
 	for _, doc := range docs {
+		if doc.ProjectID != "" {
+			sb.WriteString(fmt.Sprintf("- %s (ID: %s, project: %s, added: %s)\n", doc.Name, doc.ID, doc.ProjectID, doc.CreatedAt.Format("2006-01-02 15:04")))
+			continue
+		}
 		sb.WriteString(fmt.Sprintf("- %s (ID: %s, added: %s)\n", doc.Name, doc.ID, doc.CreatedAt.Format("2006-01-02 15:04")))
 	}
 
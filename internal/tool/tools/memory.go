@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"groq-go/internal/memory"
+	"groq-go/internal/tool"
+)
+
+// MemoryTool lets the model remember the user's preferences across
+// sessions. Memories belong to the caller's user, see tool.Caller.
+type MemoryTool struct {
+	store *memory.Store
+}
+
+func NewMemoryTool(store *memory.Store) *MemoryTool {
+	return &MemoryTool{store: store}
+}
+
+func (t *MemoryTool) Name() string {
+	return "Memory"
+}
+
+func (t *MemoryTool) Description() string {
+	return `Remember the user's preferences and facts about their work across sessions, such as the package manager, where tests live or the language to answer in.
+
+## Actions
+- "set": Remember value under key, replacing what was there (requires key and value)
+- "get": Read one memory (requires key)
+- "list": List all memories
+- "delete": Forget a memory (requires key)
+
+## Notes
+- Memories are shown at the start of every conversation, so only store what will matter later
+- Store a memory when the user states a lasting preference or asks you to remember something, not for one-off details
+- Keys are short names like "package_manager"; values are one or two sentences`
+}
+
+func (t *MemoryTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"description": "Action to perform",
+				"enum":        []string{"set", "get", "list", "delete"},
+			},
+			"key": map[string]any{
+				"type":        "string",
+				"description": fmt.Sprintf("Name of the memory, at most %d bytes", memory.MaxKeyLen),
+			},
+			"value": map[string]any{
+				"type":        "string",
+				"description": fmt.Sprintf("What to remember, at most %d bytes (required for set)", memory.MaxValueLen),
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+type memoryArgs struct {
+	Action string `json:"action"`
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+}
+
+// changes reports whether a call changes the stored memories
+func (a memoryArgs) changes() bool {
+	return a.Action == "set" || a.Action == "delete"
+}
+
+// NeedsApproval implements tool.Approvable: the user confirms what the
+// model wants to remember or forget
+func (t *MemoryTool) NeedsApproval(ctx context.Context, argsJSON json.RawMessage) bool {
+	var args memoryArgs
+	if json.Unmarshal(argsJSON, &args) != nil {
+		return true
+	}
+	return args.changes()
+}
+
+// ApprovalSubject implements tool.ApprovalSubjecter: the action and key,
+// such as "set package_manager"
+func (t *MemoryTool) ApprovalSubject(argsJSON json.RawMessage) string {
+	var args memoryArgs
+	json.Unmarshal(argsJSON, &args)
+	return strings.TrimSpace(args.Action + " " + args.Key)
+}
+
+// Mutates implements tool.Mutator: read-only mode allows get and list
+func (t *MemoryTool) Mutates(argsJSON json.RawMessage) bool {
+	var args memoryArgs
+	if json.Unmarshal(argsJSON, &args) != nil {
+		return true
+	}
+	return args.changes()
+}
+
+func (t *MemoryTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.Result, error) {
+	if t.store == nil {
+		return tool.Result{Content: "Memory not available", IsError: true}, nil
+	}
+
+	var args memoryArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return tool.Result{Content: err.Error(), IsError: true}, nil
+	}
+	caller, _ := tool.CallerFromContext(ctx)
+	ns := caller.User
+
+	if args.Action != "list" && strings.TrimSpace(args.Key) == "" {
+		return tool.Result{Content: "key is required for " + args.Action, IsError: true}, nil
+	}
+	switch args.Action {
+	case "set":
+		m, err := t.store.Set(ns, args.Key, args.Value, memory.FromModel)
+		if err != nil {
+			return tool.Result{Content: err.Error(), IsError: true}, nil
+		}
+		return tool.Result{Content: fmt.Sprintf("Remembered %s: %s", m.Key, m.Value)}, nil
+
+	case "get":
+		m, err := t.store.Get(ns, args.Key)
+		if errors.Is(err, memory.ErrNotFound) {
+			return tool.Result{Content: fmt.Sprintf("Nothing remembered under %q", args.Key)}, nil
+		}
+		if err != nil {
+			return tool.Result{Content: err.Error(), IsError: true}, nil
+		}
+		return tool.Result{Content: m.Value}, nil
+
+	case "list":
+		return tool.Result{Content: formatMemories(t.store.List(ns))}, nil
+
+	case "delete":
+		if err := t.store.Delete(ns, args.Key); err != nil {
+			return tool.Result{Content: err.Error(), IsError: true}, nil
+		}
+		return tool.Result{Content: "Forgot " + args.Key}, nil
+
+	default:
+		return tool.Result{Content: "Unknown action: " + args.Action, IsError: true}, nil
+	}
+}
+
+func formatMemories(memories []memory.Memory) string {
+	if len(memories) == 0 {
+		return "No memories. Use 'set' to add one."
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Memories (%d), most recently used first:\n", len(memories))
+	for _, m := range memories {
+		fmt.Fprintf(&sb, "  %s: %s", m.Key, m.Value)
+		if m.Origin == memory.FromModel {
+			sb.WriteString(" (saved by you)")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
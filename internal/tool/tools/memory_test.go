@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/memory"
+	"groq-go/internal/tool"
+)
+
+func newMemoryExecutor(t *testing.T) (*tool.Executor, *tool.Registry, *memory.Store) {
+	t.Helper()
+	store, err := memory.Open(filepath.Join(t.TempDir(), "memory.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := tool.NewRegistry()
+	registry.Register(NewMemoryTool(store))
+	return tool.NewExecutor(registry), registry, store
+}
+
+func callMemory(t *testing.T, ctx context.Context, e *tool.Executor, args string) tool.Result {
+	t.Helper()
+	result, err := e.ExecuteToolCall(ctx, client.ToolCall{ID: "call_1", Function: client.FunctionCall{Name: "Memory", Arguments: args}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestMemoryToolNeedsApprovalToChange(t *testing.T) {
+	e, registry, store := newMemoryExecutor(t)
+	registry.RequireApproval(nil)
+
+	var asked []string
+	decision := tool.Deny
+	ctx := tool.WithApproval(context.Background(), func(ctx context.Context, req tool.ApprovalRequest) (tool.Decision, error) {
+		asked = append(asked, req.Subject)
+		return decision, nil
+	}, nil)
+	set := `{"action": "set", "key": "package_manager", "value": "pnpm"}`
+
+	if result := callMemory(t, ctx, e, set); !result.IsError || len(store.List("")) != 0 {
+		t.Errorf("Expected a denied set to store nothing, got %+v", result)
+	}
+	decision = tool.Approve
+	if result := callMemory(t, ctx, e, set); result.IsError {
+		t.Fatalf("Expected an approved set to run, got %+v", result)
+	}
+	if m, err := store.Get("", "package_manager"); err != nil || m.Origin != memory.FromModel {
+		t.Errorf("Expected the memory marked as the model's, got %+v, %v", m, err)
+	}
+
+	if result := callMemory(t, ctx, e, `{"action": "list"}`); !strings.Contains(result.Content, "package_manager: pnpm (saved by you)") {
+		t.Errorf("Expected the memory listed, got %+v", result)
+	}
+	if strings.Join(asked, "|") != "set package_manager|set package_manager" {
+		t.Errorf("Expected only the sets to ask, got %q", asked)
+	}
+}
+
+func TestMemoryToolUsesCallerNamespace(t *testing.T) {
+	e, _, store := newMemoryExecutor(t)
+	alice := tool.WithCaller(context.Background(), tool.Caller{User: "alice"})
+
+	callMemory(t, alice, e, `{"action": "set", "key": "language", "value": "Japanese"}`)
+	if got := store.List("alice"); len(got) != 1 || got[0].Value != "Japanese" {
+		t.Errorf("Expected the memory in alice's namespace, got %+v", got)
+	}
+	if result := callMemory(t, context.Background(), e, `{"action": "get", "key": "language"}`); !strings.Contains(result.Content, "Nothing remembered") {
+		t.Errorf("Expected other namespaces not to see it, got %+v", result)
+	}
+	if result := callMemory(t, alice, e, `{"action": "delete"}`); !result.IsError {
+		t.Errorf("Expected delete without key to fail, got %+v", result)
+	}
+}
+
+func TestMemoryToolReadOnly(t *testing.T) {
+	e, registry, store := newMemoryExecutor(t)
+	store.Set("", "tests", "in /e2e", memory.FromUser)
+	registry.SetReadOnly(true)
+
+	if result := callMemory(t, context.Background(), e, `{"action": "get", "key": "tests"}`); result.Content != "in /e2e" {
+		t.Errorf("Expected reads in read-only mode, got %+v", result)
+	}
+	if result := callMemory(t, context.Background(), e, `{"action": "delete", "key": "tests"}`); !result.IsError || len(store.List("")) != 1 {
+		t.Errorf("Expected delete blocked in read-only mode, got %+v", result)
+	}
+}
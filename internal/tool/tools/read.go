@@ -2,9 +2,13 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"strings"
 
@@ -19,6 +23,25 @@ type ReadArgs struct {
 	Limit    int    `json:"limit,omitempty"`
 }
 
+const (
+	// readDefaultLimit is the number of lines read when no limit is given
+	readDefaultLimit = 2000
+	// readMaxLineLength is where long lines are cut
+	readMaxLineLength = 2000
+	// sniffLen is how much of a file is checked for binary content
+	sniffLen = 8000
+	// readMaxImage is the largest image passed on to vision models
+	readMaxImage = 5 << 20
+)
+
+// readImageTypes are the image types passed on to vision models
+var readImageTypes = map[string]bool{
+	"image/png":  true,
+	"image/jpeg": true,
+	"image/gif":  true,
+	"image/webp": true,
+}
+
 func NewReadTool() *ReadTool {
 	return &ReadTool{}
 }
@@ -28,7 +51,9 @@ func (t *ReadTool) Name() string {
 }
 
 func (t *ReadTool) Description() string {
-	return "Reads a file from the filesystem. Returns the file content with line numbers."
+	return "Reads a file from the filesystem. Returns the file content with line numbers. " +
+		"Reads up to 2000 lines by default; for large files, use offset and limit to read only the lines you need. " +
+		"Binary files are summarized instead of shown, and images are shown to models that support vision."
 }
 
 func (t *ReadTool) Parameters() map[string]any {
@@ -62,8 +87,18 @@ func (t *ReadTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 		return tool.NewErrorResult("file_path is required"), nil
 	}
 
+	// Inside a project, stay within its root
+	path, err := tool.SandboxFromContext(ctx).Resolve(args.FilePath)
+	if err != nil {
+		return tool.NewErrorResult(err.Error()), nil
+	}
+	args.FilePath = path
+	if args.Offset < 0 || args.Limit < 0 {
+		return tool.NewErrorResult("offset and limit must not be negative"), nil
+	}
+
 	if args.Limit == 0 {
-		args.Limit = 2000
+		args.Limit = readDefaultLimit
 	}
 	if args.Offset == 0 {
 		args.Offset = 1
@@ -75,34 +110,110 @@ func (t *ReadTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.
 	}
 	defer file.Close()
 
+	info, err := file.Stat()
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("failed to stat file: %v", err)), nil
+	}
+	if info.IsDir() {
+		return tool.NewErrorResult(fmt.Sprintf("%s is a directory; use Glob to list files", args.FilePath)), nil
+	}
+
+	reader := bufio.NewReader(file)
+	head, _ := reader.Peek(sniffLen)
+	if isBinary(head) {
+		return readBinary(file, args.FilePath, info.Size(), http.DetectContentType(head))
+	}
+
 	var lines []string
-	scanner := bufio.NewScanner(file)
 	lineNum := 0
-
-	for scanner.Scan() {
+	more := false
+	for {
+		line, err := reader.ReadString('\n')
+		if line == "" && err != nil {
+			if err != io.EOF {
+				return tool.NewErrorResult(fmt.Sprintf("error reading file: %v", err)), nil
+			}
+			break
+		}
 		lineNum++
 		if lineNum < args.Offset {
 			continue
 		}
 		if lineNum >= args.Offset+args.Limit {
+			more = true
 			break
 		}
 
-		line := scanner.Text()
+		line = strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r")
 		// Truncate long lines
-		if len(line) > 2000 {
-			line = line[:2000] + "... (truncated)"
+		if len(line) > readMaxLineLength {
+			line = strings.ToValidUTF8(line[:readMaxLineLength], "") + "... (truncated)"
 		}
 		lines = append(lines, fmt.Sprintf("%6d\t%s", lineNum, line))
 	}
 
-	if err := scanner.Err(); err != nil {
-		return tool.NewErrorResult(fmt.Sprintf("error reading file: %v", err)), nil
+	if len(lines) == 0 {
+		if lineNum == 0 {
+			return tool.NewResult("(empty file)"), nil
+		}
+		return tool.NewResult(fmt.Sprintf("(no lines in range: the file has %d lines)", lineNum)), nil
 	}
 
-	if len(lines) == 0 {
-		return tool.NewResult("(empty file or no lines in range)"), nil
+	content := strings.Join(lines, "\n")
+	if more {
+		next := args.Offset + args.Limit
+		content += fmt.Sprintf("\n... (more lines follow; use offset=%d to continue)", next)
+	}
+	return tool.NewResult(content), nil
+}
+
+// ReportedPaths implements tool.PathReporter
+func (t *ReadTool) ReportedPaths(argsJSON json.RawMessage, result tool.Result) []string {
+	var args ReadArgs
+	if json.Unmarshal(argsJSON, &args) != nil || args.FilePath == "" {
+		return nil
+	}
+	return []string{args.FilePath}
+}
+
+// isBinary reports whether the start of a file looks binary
+func isBinary(head []byte) bool {
+	return bytes.IndexByte(head, 0) >= 0
+}
+
+// readBinary summarizes a binary file. Images small enough are attached
+// as a data URL under tool.MetadataImage.
+func readBinary(file *os.File, path string, size int64, contentType string) (tool.Result, error) {
+	summary := fmt.Sprintf("Binary file %s (%s, %s); contents not shown", path, contentType, formatSize(size))
+	if !readImageTypes[contentType] {
+		return tool.NewResult(summary), nil
 	}
+	if size > readMaxImage {
+		return tool.NewResult(fmt.Sprintf("Image %s (%s, %s) is too large to show; the limit is %s", path, contentType, formatSize(size), formatSize(readMaxImage))), nil
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("error reading file: %v", err)), nil
+	}
+	data, err := io.ReadAll(io.LimitReader(file, readMaxImage))
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("error reading file: %v", err)), nil
+	}
+	result := tool.NewResult(fmt.Sprintf("[image: %s (%s, %s)]", path, contentType, formatSize(size)))
+	result.Metadata = map[string]string{
+		tool.MetadataImage: "data:" + contentType + ";base64," + base64.StdEncoding.EncodeToString(data),
+	}
+	return result, nil
+}
 
-	return tool.NewResult(strings.Join(lines, "\n")), nil
+// formatSize formats a byte count for people
+func formatSize(n int64) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d bytes", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	default:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	}
 }
@@ -0,0 +1,154 @@
+package tools
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/tool"
+)
+
+// writeTestFile writes content to a new file in a temp dir
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadLineRanges(t *testing.T) {
+	var sb strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&sb, "line %d\n", i)
+	}
+	path := writeTestFile(t, "ten.txt", sb.String())
+
+	tests := []struct {
+		name          string
+		offset, limit int
+		want          string
+	}{
+		{"whole file", 0, 0, "     1\tline 1\n"},
+		{"first lines", 1, 2, "     1\tline 1\n     2\tline 2\n... (more lines follow; use offset=3 to continue)"},
+		{"middle", 4, 2, "     4\tline 4\n     5\tline 5\n... (more lines follow; use offset=6 to continue)"},
+		{"last line", 10, 5, "    10\tline 10"},
+		{"exactly to the end", 9, 2, "     9\tline 9\n    10\tline 10"},
+		{"past the end", 11, 5, "(no lines in range: the file has 10 lines)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: path, Offset: tt.offset, Limit: tt.limit})
+			if result.IsError {
+				t.Fatalf("Unexpected error: %s", result.Content)
+			}
+			if tt.offset == 0 {
+				if !strings.HasPrefix(result.Content, tt.want) || !strings.HasSuffix(result.Content, "    10\tline 10") {
+					t.Errorf("Expected all lines, got %q", result.Content)
+				}
+				return
+			}
+			if result.Content != tt.want {
+				t.Errorf("Expected %q, got %q", tt.want, result.Content)
+			}
+		})
+	}
+
+	result := runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: path, Offset: -1})
+	if !result.IsError {
+		t.Errorf("Expected a negative offset to fail, got %q", result.Content)
+	}
+}
+
+func TestReadCRLFAndEdgeCases(t *testing.T) {
+	path := writeTestFile(t, "dos.txt", "one\r\ntwo\r\nthree")
+	result := runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: path})
+	if want := "     1\tone\n     2\ttwo\n     3\tthree"; result.Content != want {
+		t.Errorf("Expected %q, got %q", want, result.Content)
+	}
+
+	// Lines beyond the scanner's old 64 KiB limit are cut, not an error
+	path = writeTestFile(t, "long.txt", strings.Repeat("x", 100000)+"\nend\n")
+	result = runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: path})
+	if result.IsError || !strings.HasSuffix(result.Content, "... (truncated)\n     2\tend") {
+		t.Errorf("Expected the long line cut, got %.100q (error=%v)", result.Content, result.IsError)
+	}
+
+	path = writeTestFile(t, "empty.txt", "")
+	if result = runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: path}); result.Content != "(empty file)" {
+		t.Errorf("Expected an empty file note, got %q", result.Content)
+	}
+}
+
+func TestReadBinary(t *testing.T) {
+	path := writeTestFile(t, "data.bin", "\x7fELF\x02\x01\x01\x00\x00\x00garbage")
+	result := runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: path})
+	if result.IsError || !strings.HasPrefix(result.Content, "Binary file "+path+" (application/octet-stream, 17 bytes)") {
+		t.Errorf("Expected a binary summary, got %q", result.Content)
+	}
+	if result.Metadata[tool.MetadataImage] != "" {
+		t.Error("Expected no image for a non-image binary")
+	}
+}
+
+func TestReadImage(t *testing.T) {
+	png := pngHeader + "\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00"
+	path := writeTestFile(t, "pixel.png", png)
+	result := runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: path})
+	if result.IsError || result.Content != fmt.Sprintf("[image: %s (image/png, %d bytes)]", path, len(png)) {
+		t.Errorf("Expected an image marker, got %q", result.Content)
+	}
+	want := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte(png))
+	if got := result.Metadata[tool.MetadataImage]; got != want {
+		t.Errorf("Expected the image as a data URL, got %q", got)
+	}
+}
+
+// pngHeader starts every PNG file
+const pngHeader = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+// sandboxContext confines tools to a new project directory. A secret file
+// sits next to the project, linked from inside it as link.txt.
+func sandboxContext(t *testing.T) (ctx context.Context, root, secret string) {
+	t.Helper()
+	base := t.TempDir()
+	root = filepath.Join(base, "project")
+	secret = filepath.Join(base, "secret.txt")
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(secret, []byte("token\n"), 0600)
+	os.WriteFile(filepath.Join(root, "main.go"), []byte("package main\n"), 0644)
+	if err := os.Symlink(secret, filepath.Join(root, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	sb, err := tool.NewSandbox(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tool.WithReadTracker(tool.WithSandbox(context.Background(), sb), tool.NewReadTracker()), root, secret
+}
+
+func TestReadStaysInSandbox(t *testing.T) {
+	ctx, root, secret := sandboxContext(t)
+
+	if result := runTool(t, ctx, NewReadTool(), ReadArgs{FilePath: "main.go"}); result.IsError || !strings.Contains(result.Content, "package main") {
+		t.Errorf("Expected a relative path read from the project root, got %q", result.Content)
+	}
+	for _, path := range []string{secret, "../secret.txt", "link.txt", filepath.Join(root, "..", "secret.txt")} {
+		result := runTool(t, ctx, NewReadTool(), ReadArgs{FilePath: path})
+		if !result.IsError || !strings.Contains(result.Content, "outside the project directory") || strings.Contains(result.Content, "token") {
+			t.Errorf("Expected %s refused, got %q", path, result.Content)
+		}
+	}
+
+	// Without a sandbox the link is followed as before
+	if result := runTool(t, context.Background(), NewReadTool(), ReadArgs{FilePath: filepath.Join(root, "link.txt")}); !strings.Contains(result.Content, "token") {
+		t.Errorf("Expected an unconfined read, got %q", result.Content)
+	}
+}
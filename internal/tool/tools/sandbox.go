@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+)
+
+// ResourceLimits constrains processes started by CodeExec. Zero disables
+// a limit.
+type ResourceLimits struct {
+	// CPUSeconds caps CPU time; the kernel kills the process past it
+	CPUSeconds int
+	// MemoryMB caps the data segment, which covers heap allocations
+	MemoryMB int
+	// MaxProcesses caps processes and threads. The kernel counts all of
+	// the user's processes against it, and does not apply it to root.
+	MaxProcesses int
+	// MaxOutputBytes caps combined stdout and stderr; the process is
+	// killed once it writes more
+	MaxOutputBytes int
+}
+
+// DefaultResourceLimits are the limits CodeExec applies unless configured
+var DefaultResourceLimits = ResourceLimits{
+	CPUSeconds:     30,
+	MemoryMB:       512,
+	MaxProcesses:   256,
+	MaxOutputBytes: 10000,
+}
+
+// errOutputLimit is reported when a process is killed for writing too much
+var errOutputLimit = errors.New("output limit exceeded")
+
+// errNoNetworkIsolation is reported instead of running code with network
+// access it was not allowed
+var errNoNetworkIsolation = errors.New("network isolation is unavailable on this system; set allow_network to true to run with network access")
+
+// cappedOutput is shared by a process's stdout and stderr writers to
+// enforce one limit on their combined size while the process runs
+type cappedOutput struct {
+	mu      sync.Mutex
+	limit   int
+	written int
+	// exceeded is called once, when the limit is first passed
+	exceeded func()
+	over     bool
+}
+
+// newCappedOutput returns a cappedOutput that calls exceeded, typically
+// a context's cancel, when the limit is passed
+func newCappedOutput(limit int, exceeded func()) *cappedOutput {
+	return &cappedOutput{limit: limit, exceeded: exceeded}
+}
+
+// Writer returns a writer that appends to buf within the shared limit
+func (c *cappedOutput) Writer(buf *bytes.Buffer) *cappedWriter {
+	return &cappedWriter{out: c, buf: buf}
+}
+
+type cappedWriter struct {
+	out *cappedOutput
+	buf *bytes.Buffer
+}
+
+func (w *cappedWriter) Write(p []byte) (int, error) {
+	c := w.out
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.over {
+		return len(p), nil
+	}
+	room := c.limit - c.written
+	if c.limit <= 0 || len(p) <= room {
+		w.buf.Write(p)
+		c.written += len(p)
+		return len(p), nil
+	}
+	w.buf.Write(p[:room])
+	c.written += room
+	c.over = true
+	if c.exceeded != nil {
+		c.exceeded()
+	}
+	// Report success so the copy goroutine drains the pipe until exit
+	return len(p), nil
+}
+
+// Exceeded reports whether the limit was passed
+func (c *cappedOutput) Exceeded() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.over
+}
@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+var (
+	unshareOnce sync.Once
+	unsharePath string
+)
+
+// netUnshare returns the unshare binary if it can create a network
+// namespace for this user, or "" if isolation is unavailable
+func netUnshare() string {
+	unshareOnce.Do(func() {
+		path, err := exec.LookPath("unshare")
+		if err != nil {
+			return
+		}
+		if exec.Command(path, "--net", "--map-root-user", "true").Run() == nil {
+			unsharePath = path
+		}
+	})
+	return unsharePath
+}
+
+// sandboxCommand returns the command and arguments that run command
+// under limits, in an empty network namespace unless allowNetwork is set.
+// Without namespaces it fails with errNoNetworkIsolation rather than run
+// with the network.
+func sandboxCommand(limits ResourceLimits, allowNetwork bool, command string, args []string) (string, []string, error) {
+	argv := append([]string{command}, args...)
+
+	if !allowNetwork {
+		unshare := netUnshare()
+		if unshare == "" {
+			return "", nil, errNoNetworkIsolation
+		}
+		argv = append([]string{unshare, "--net", "--map-root-user", "--"}, argv...)
+	}
+
+	// Limits are set by the shell before exec so they apply to the child
+	// only. Lowering a limit fails solely when the hard limit is already
+	// lower, so failures are ignored.
+	var ulimits []string
+	if limits.CPUSeconds > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -t %d", limits.CPUSeconds))
+	}
+	if limits.MemoryMB > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -d %d", limits.MemoryMB*1024))
+	}
+	if limits.MaxProcesses > 0 {
+		ulimits = append(ulimits, fmt.Sprintf("ulimit -u %d", limits.MaxProcesses))
+	}
+	if len(ulimits) == 0 {
+		return argv[0], argv[1:], nil
+	}
+	script := strings.Join(ulimits, " 2>/dev/null; ") + ` 2>/dev/null; exec "$@"`
+	return "/bin/bash", append([]string{"-c", script, "codeexec"}, argv...), nil
+}
+
+// setProcessGroup makes cmd the leader of a new process group and kills
+// the whole group on cancellation, so forked children die with it
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package tools
+
+import "os/exec"
+
+// sandboxCommand runs command unchanged when network access is allowed;
+// resource limits and network isolation are only implemented on Linux,
+// so code without network access is refused
+func sandboxCommand(limits ResourceLimits, allowNetwork bool, command string, args []string) (string, []string, error) {
+	if !allowNetwork {
+		return "", nil, errNoNetworkIsolation
+	}
+	return command, args, nil
+}
+
+func setProcessGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"groq-go/internal/scheduler"
+	"groq-go/internal/tool"
+)
+
+// ScheduleTool lets the model manage recurring jobs
+type ScheduleTool struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewScheduleTool(s *scheduler.Scheduler) *ScheduleTool {
+	return &ScheduleTool{scheduler: s}
+}
+
+func (t *ScheduleTool) Name() string {
+	return "Schedule"
+}
+
+func (t *ScheduleTool) Description() string {
+	return `Manage recurring jobs that run a prompt on a cron schedule while the server is up.
+
+## Actions
+- "create": Create a job (requires cron and prompt; optional name, model, tools, max_turns)
+- "list": List jobs with their next run and the result of the last one
+- "remove": Remove a job (requires id)
+
+## Notes
+- cron has five fields: minute hour day-of-month month day-of-week, e.g. "0 8 * * 1-5" for 8:00 on weekdays; @hourly, @daily and @weekly also work
+- A job can only call the tools listed in tools; with none it just answers the prompt
+- Each run is saved as a session that can be opened in the web UI`
+}
+
+func (t *ScheduleTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"action": map[string]any{
+				"type":        "string",
+				"description": "Action to perform",
+				"enum":        []string{"create", "list", "remove"},
+			},
+			"id": map[string]any{
+				"type":        "string",
+				"description": "Job ID (required for remove)",
+			},
+			"name": map[string]any{
+				"type":        "string",
+				"description": "Short name of the job",
+			},
+			"cron": map[string]any{
+				"type":        "string",
+				"description": "Cron expression (required for create)",
+			},
+			"prompt": map[string]any{
+				"type":        "string",
+				"description": "What the agent should do on each run (required for create)",
+			},
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Model to use (default: the server's model)",
+			},
+			"tools": map[string]any{
+				"type":        "array",
+				"items":       map[string]any{"type": "string"},
+				"description": "Tools the job may call, e.g. [\"Bash\", \"Read\"]",
+			},
+			"max_turns": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("API calls allowed per run (default: %d)", scheduler.DefaultMaxTurns),
+			},
+		},
+		"required": []string{"action"},
+	}
+}
+
+func (t *ScheduleTool) Execute(ctx context.Context, args json.RawMessage) (tool.Result, error) {
+	if t.scheduler == nil {
+		return tool.Result{Content: "Scheduling not available", IsError: true}, nil
+	}
+
+	var params struct {
+		Action   string   `json:"action"`
+		ID       string   `json:"id"`
+		Name     string   `json:"name"`
+		Cron     string   `json:"cron"`
+		Prompt   string   `json:"prompt"`
+		Model    string   `json:"model"`
+		Tools    []string `json:"tools"`
+		MaxTurns int      `json:"max_turns"`
+	}
+	if err := json.Unmarshal(args, &params); err != nil {
+		return tool.Result{Content: err.Error(), IsError: true}, nil
+	}
+
+	switch params.Action {
+	case "create":
+		job, err := t.scheduler.Add(scheduler.Job{
+			Name:     params.Name,
+			Cron:     params.Cron,
+			Prompt:   params.Prompt,
+			Model:    params.Model,
+			Tools:    params.Tools,
+			MaxTurns: params.MaxTurns,
+		})
+		if err != nil {
+			return tool.Result{Content: err.Error(), IsError: true}, nil
+		}
+		return tool.Result{Content: fmt.Sprintf("Created job %s (ID: %s), next run %s", job.Name, job.ID, job.NextRun.Format("2006-01-02 15:04 MST"))}, nil
+
+	case "list":
+		return tool.Result{Content: formatJobs(t.scheduler.List())}, nil
+
+	case "remove":
+		if params.ID == "" {
+			return tool.Result{Content: "id is required for remove action", IsError: true}, nil
+		}
+		if err := t.scheduler.Remove(params.ID); err != nil {
+			return tool.Result{Content: err.Error(), IsError: true}, nil
+		}
+		return tool.Result{Content: "Removed job " + params.ID}, nil
+
+	default:
+		return tool.Result{Content: "Unknown action: " + params.Action, IsError: true}, nil
+	}
+}
+
+func formatJobs(jobs []scheduler.Job) string {
+	if len(jobs) == 0 {
+		return "No scheduled jobs. Use 'create' to add one."
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Scheduled jobs (%d):\n", len(jobs))
+	for _, job := range jobs {
+		fmt.Fprintf(&sb, "  %s %s [%s] next %s", job.ID, job.Name, job.Cron, job.NextRun.Format("2006-01-02 15:04"))
+		if job.Running {
+			sb.WriteString(" (running)")
+		}
+		sb.WriteString("\n")
+		if run := job.LastRun; run != nil {
+			status := "ok"
+			if run.Error != "" {
+				status = "failed: " + run.Error
+			}
+			fmt.Fprintf(&sb, "      last run %s, %s", run.StartedAt.Format("2006-01-02 15:04"), status)
+			if run.SessionID != "" {
+				fmt.Fprintf(&sb, " (session %s)", run.SessionID)
+			}
+			sb.WriteString("\n")
+		}
+		if job.Skipped > 0 {
+			fmt.Fprintf(&sb, "      %d overlapping runs skipped\n", job.Skipped)
+		}
+	}
+	return sb.String()
+}
@@ -3,6 +3,7 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -27,17 +28,24 @@ func (t *SelfImproveTool) Description() string {
 	return `Modify the groq-go source code to improve this AI system.
 
 ## Basic Actions
-- "list": List source files (use pattern to filter)
-- "read": Read a source file
-- "write": Write/modify a source file
+- "list": List source files with sizes (use pattern to filter); set tree for a directory tree, collapsed below depth
+- "read": Read a source file; use start_line and end_line for part of a large file
+- "write": Write/modify a source file; when enabled, Go files are checked with gofmt and go vet and the result is reported (skip_check to skip)
 - "status": Show git status
 - "diff": Show uncommitted changes
 - "commit": Commit changes with a message
 - "history": Show commit history
 
+## Pull Request Workflow (preferred)
+- "create_branch": Start a work branch (use "branch" for its name); later commits land there
+- "open_pr": Push the work branch and open a pull request (optional "title" and "body")
+- "pr_status": Show checks and mergeability of a pull request ("number", default: last opened)
+- "merge_pr": Squash-merge a pull request (only if SELF_IMPROVE_AUTO_MERGE is enabled)
+
 ## Safe Deployment
 - "verify_build": Test if code compiles (ALWAYS do this before pushing!)
-- "safe_push": Push only if build succeeds + mark as known good
+- "verify_tests": Run the test suite (use pattern to limit packages, e.g. "./internal/tool/...")
+- "safe_push": Push only if build and tests pass + mark as known good (skip_tests to skip tests)
 - "mark_good": Mark current deployed version as known good
 
 ## Rollback Options (in order of preference)
@@ -47,12 +55,14 @@ func (t *SelfImproveTool) Description() string {
 - "fly_rollback": Get Fly.io rollback instructions (last resort)
 
 ## Safety Protocol
-1. Make changes with "write"
-2. Check with "diff"
-3. Verify with "verify_build"
-4. Commit with "commit"
-5. Deploy with "safe_push" (NOT "push")
-6. If broken: "rollback_safe" or "fly_rollback"`
+1. Start a branch with "create_branch"
+2. Make changes with "write"
+3. Check with "diff"
+4. Verify with "verify_build" and "verify_tests"
+5. Commit with "commit"
+6. Open a pull request with "open_pr" and follow it with "pr_status"
+7. "safe_push" deploys straight to main; use it only when asked to (NEVER "push")
+8. If broken: "rollback_safe" or "fly_rollback"`
 }
 
 func (t *SelfImproveTool) Parameters() map[string]any {
@@ -62,28 +72,68 @@ func (t *SelfImproveTool) Parameters() map[string]any {
 			"action": map[string]any{
 				"type":        "string",
 				"description": "Action to perform",
-				"enum":        []string{"list", "read", "write", "status", "diff", "commit", "push", "safe_push", "verify_build", "mark_good", "rollback", "rollback_to", "rollback_safe", "fly_rollback", "history"},
+				"enum":        []string{"list", "read", "write", "status", "diff", "commit", "push", "safe_push", "verify_build", "verify_tests", "mark_good", "rollback", "rollback_to", "rollback_safe", "fly_rollback", "history", "create_branch", "open_pr", "pr_status", "merge_pr"},
 			},
 			"path": map[string]any{
 				"type":        "string",
 				"description": "File path (relative to repo root) for read/write actions",
 			},
+			"start_line": map[string]any{
+				"type":        "integer",
+				"description": "First line to read (1-based) for read action; default: the first line",
+			},
+			"end_line": map[string]any{
+				"type":        "integer",
+				"description": "Last line to read (inclusive) for read action; default: the last line",
+			},
+			"tree": map[string]any{
+				"type":        "boolean",
+				"description": "Show list action output as a directory tree with file counts and sizes",
+			},
+			"depth": map[string]any{
+				"type":        "integer",
+				"description": "Directory levels expanded by a tree listing (default 3); deeper directories are summarized",
+			},
 			"content": map[string]any{
 				"type":        "string",
 				"description": "File content for write action",
 			},
+			"skip_check": map[string]any{
+				"type":        "boolean",
+				"description": "Skip the gofmt and go vet check after a write action, e.g. for a file that is not Go",
+			},
 			"message": map[string]any{
 				"type":        "string",
 				"description": "Commit message for commit action",
 			},
 			"pattern": map[string]any{
 				"type":        "string",
-				"description": "Filter pattern for list action (e.g., '.go', 'internal/')",
+				"description": "Filter pattern for list action (e.g., '.go', 'internal/'), or package pattern for verify_tests (default './...')",
 			},
 			"hash": map[string]any{
 				"type":        "string",
 				"description": "Commit hash for rollback_to action",
 			},
+			"branch": map[string]any{
+				"type":        "string",
+				"description": "Short name for create_branch action (e.g. 'fix-glob-sorting')",
+			},
+			"title": map[string]any{
+				"type":        "string",
+				"description": "Pull request title for open_pr action (default: from commits)",
+			},
+			"body": map[string]any{
+				"type":        "string",
+				"description": "Pull request description for open_pr action (default: from commits)",
+			},
+			"number": map[string]any{
+				"type":        "integer",
+				"description": "Pull request number for pr_status and merge_pr actions (default: last opened)",
+			},
+			"skip_tests": map[string]any{
+				"type":        "boolean",
+				"description": "Push after only a build check for safe_push action. Use only when tests cannot pass for reasons unrelated to the change.",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -95,44 +145,85 @@ func (t *SelfImproveTool) Execute(ctx context.Context, args json.RawMessage) (to
 	}
 
 	var params struct {
-		Action  string `json:"action"`
-		Path    string `json:"path"`
-		Content string `json:"content"`
-		Message string `json:"message"`
-		Pattern string `json:"pattern"`
-		Hash    string `json:"hash"`
+		Action    string `json:"action"`
+		Path      string `json:"path"`
+		Content   string `json:"content"`
+		Message   string `json:"message"`
+		Pattern   string `json:"pattern"`
+		Hash      string `json:"hash"`
+		SkipTests bool   `json:"skip_tests"`
+		Branch    string `json:"branch"`
+		Title     string `json:"title"`
+		Body      string `json:"body"`
+		Number    int    `json:"number"`
+		StartLine int    `json:"start_line"`
+		EndLine   int    `json:"end_line"`
+		Tree      bool   `json:"tree"`
+		Depth     int    `json:"depth"`
+		SkipCheck bool   `json:"skip_check"`
 	}
 
 	if err := json.Unmarshal(args, &params); err != nil {
 		return tool.Result{Content: err.Error(), IsError: true}, nil
 	}
 
+	// Long-running build steps stream their progress to the caller
+	progress := selfimprove.ProgressFunc(tool.OutputFromContext(ctx))
+
 	switch params.Action {
 	case "list":
 		files, err := t.manager.ListFiles(ctx, params.Pattern)
 		if err != nil {
 			return tool.Result{Content: err.Error(), IsError: true}, nil
 		}
-		return tool.Result{Content: fmt.Sprintf("Files (%d):\n%s", len(files), strings.Join(files, "\n"))}, nil
+		if params.Tree {
+			return tool.Result{Content: selfimprove.RenderTree(files, params.Depth)}, nil
+		}
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "Files (%d):\n", len(files))
+		for _, f := range files {
+			fmt.Fprintf(&sb, "%s (%s, modified %s)\n", f.Path, formatSize(f.Size), f.ModTime.Format("2006-01-02 15:04"))
+		}
+		return tool.Result{Content: sb.String()}, nil
 
 	case "read":
 		if params.Path == "" {
 			return tool.Result{Content: "path is required for read action", IsError: true}, nil
 		}
-		content, err := t.manager.ReadFile(ctx, params.Path)
+		if params.StartLine == 0 && params.EndLine == 0 {
+			content, err := t.manager.ReadFile(ctx, params.Path)
+			if err != nil {
+				return tool.Result{Content: err.Error(), IsError: true}, nil
+			}
+			return tool.Result{Content: content}, nil
+		}
+		r, err := t.manager.ReadFileRange(ctx, params.Path, params.StartLine, params.EndLine)
 		if err != nil {
 			return tool.Result{Content: err.Error(), IsError: true}, nil
 		}
-		return tool.Result{Content: content}, nil
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "%s lines %d-%d of %d:\n", params.Path, r.Start, r.End, r.Total)
+		for i, line := range r.Lines {
+			fmt.Fprintf(&sb, "%6d\t%s\n", r.Start+i, line)
+		}
+		return tool.Result{Content: sb.String()}, nil
 
 	case "write":
 		if params.Path == "" || params.Content == "" {
 			return tool.Result{Content: "path and content are required for write action", IsError: true}, nil
 		}
-		if err := t.manager.WriteFile(ctx, params.Path, params.Content); err != nil {
+		branch, err := t.manager.WriteFile(ctx, params.Path, params.Content)
+		if err != nil {
 			return tool.Result{Content: err.Error(), IsError: true}, nil
 		}
-		return tool.Result{Content: fmt.Sprintf("Successfully wrote to %s", params.Path)}, nil
+		content := fmt.Sprintf("Successfully wrote to %s on branch %s", params.Path, branch)
+		// Report problems now rather than at the next verify_build; the
+		// write stands either way
+		if t.manager.ChecksWrites() && !params.SkipCheck && strings.HasSuffix(params.Path, ".go") {
+			summary, _ := t.manager.CheckGoFile(ctx, params.Path)
+			content += "\n" + summary
+		}
+		return tool.Result{Content: content}, nil
 
 	case "status":
 		status, err := t.manager.GetStatus(ctx)
@@ -159,25 +250,72 @@ func (t *SelfImproveTool) Execute(ctx context.Context, args json.RawMessage) (to
 		if err != nil {
 			return tool.Result{Content: err.Error(), IsError: true}, nil
 		}
-		return tool.Result{Content: fmt.Sprintf("Committed: %s - %s", commit.Hash[:8], commit.Message)}, nil
+		return tool.Result{Content: fmt.Sprintf("Committed on %s: %s - %s", commit.Branch, commit.Hash[:8], commit.Message)}, nil
 
 	case "verify_build":
-		if err := t.manager.VerifyBuild(ctx); err != nil {
+		if err := t.manager.VerifyBuild(ctx, progress); err != nil {
+			if errors.Is(err, selfimprove.ErrAlreadyBuilding) {
+				return tool.Result{Content: fmt.Sprintf("Build verification is %v", err)}, nil
+			}
 			return tool.Result{Content: fmt.Sprintf("❌ Build failed: %v", err), IsError: true}, nil
 		}
 		return tool.Result{Content: "✅ Build verification passed. Safe to push."}, nil
 
+	case "verify_tests":
+		report, err := t.manager.VerifyTests(ctx, params.Pattern)
+		if err != nil {
+			if errors.Is(err, selfimprove.ErrAlreadyBuilding) {
+				return tool.Result{Content: fmt.Sprintf("Verification is %v", err)}, nil
+			}
+			return tool.Result{Content: fmt.Sprintf("❌ %v", err), IsError: true}, nil
+		}
+		return tool.Result{Content: fmt.Sprintf("✅ Tests passed: %s", report.Summary())}, nil
+
 	case "push":
 		if err := t.manager.Push(ctx); err != nil {
 			return tool.Result{Content: err.Error(), IsError: true}, nil
 		}
-		return tool.Result{Content: "⚠️ Pushed to GitHub (without build verification). Consider using 'safe_push' instead."}, nil
+		return tool.Result{Content: "⚠️ Pushed straight to main without build verification or review. Use 'create_branch' and 'open_pr' instead."}, nil
 
 	case "safe_push":
-		if err := t.manager.SafePush(ctx); err != nil {
+		if err := t.manager.SafePush(ctx, params.SkipTests, progress); err != nil {
+			if errors.Is(err, selfimprove.ErrAlreadyBuilding) {
+				return tool.Result{Content: fmt.Sprintf("Build verification is %v", err)}, nil
+			}
 			return tool.Result{Content: fmt.Sprintf("❌ Safe push failed: %v", err), IsError: true}, nil
 		}
-		return tool.Result{Content: "✅ Build verified and pushed to GitHub. Marked as known good. Auto-deploy will start shortly. Check https://groq-go-yuki.fly.dev/ in 2-3 minutes."}, nil
+		if params.SkipTests {
+			return tool.Result{Content: "✅ Build verified (tests skipped) and pushed to GitHub. Marked as known good. Auto-deploy will start shortly."}, nil
+		}
+		return tool.Result{Content: "✅ Build and tests verified and pushed to GitHub. Marked as known good. Auto-deploy will start shortly. Check https://groq-go-yuki.fly.dev/ in 2-3 minutes."}, nil
+
+	case "create_branch":
+		branch, err := t.manager.CreateWorkBranch(ctx, params.Branch)
+		if err != nil {
+			return tool.Result{Content: err.Error(), IsError: true}, nil
+		}
+		return tool.Result{Content: fmt.Sprintf("Switched to new branch %s. Commits will land on it; use 'open_pr' when done.", branch)}, nil
+
+	case "open_pr":
+		pr, err := t.manager.OpenPR(ctx, params.Title, params.Body)
+		if err != nil {
+			return tool.Result{Content: fmt.Sprintf("❌ Failed to open pull request: %v", err), IsError: true}, nil
+		}
+		return tool.Result{Content: fmt.Sprintf("✅ Opened pull request #%d: %s\n%s", pr.Number, pr.Title, pr.HTMLURL)}, nil
+
+	case "pr_status":
+		status, err := t.manager.PRStatus(ctx, params.Number)
+		if err != nil {
+			return tool.Result{Content: err.Error(), IsError: true}, nil
+		}
+		return tool.Result{Content: status.Summary()}, nil
+
+	case "merge_pr":
+		sha, err := t.manager.MergePR(ctx, params.Number)
+		if err != nil {
+			return tool.Result{Content: fmt.Sprintf("❌ Merge failed: %v", err), IsError: true}, nil
+		}
+		return tool.Result{Content: fmt.Sprintf("✅ Squash-merged as %s. Auto-deploy will start shortly.", sha)}, nil
 
 	case "mark_good":
 		if err := t.manager.MarkAsGood(ctx); err != nil {
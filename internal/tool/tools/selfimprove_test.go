@@ -0,0 +1,73 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/config"
+	"groq-go/internal/selfimprove"
+)
+
+// newCheckedSelfImproveTool returns the tool over a fresh Go module on main,
+// with writes checked
+func newCheckedSelfImproveTool(t *testing.T) *SelfImproveTool {
+	t.Helper()
+	for _, bin := range []string{"git", "go", "gofmt"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skipf("%s not available", bin)
+		}
+	}
+	t.Setenv("HOME", t.TempDir())
+	m, err := selfimprove.NewManager(config.SelfImproveConfig{CheckWrites: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := m.GetRepoDir()
+	if out, err := exec.Command("git", "init", "-q", "-b", "main", dir).CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v: %s", err, out)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "go.mod"), []byte("module example.com/m\n\ngo 1.21\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewSelfImproveTool(m)
+}
+
+func writeAction(t *testing.T, tool *SelfImproveTool, path, content string, skipCheck bool) string {
+	t.Helper()
+	args, _ := json.Marshal(map[string]any{"action": "write", "path": path, "content": content, "skip_check": skipCheck})
+	result, err := tool.Execute(context.Background(), args)
+	if err != nil || result.IsError {
+		t.Fatalf("Expected the write to succeed, got %v %q", err, result.Content)
+	}
+	return result.Content
+}
+
+func TestSelfImproveWriteIsChecked(t *testing.T) {
+	tool := newCheckedSelfImproveTool(t)
+
+	got := writeAction(t, tool, "calc/calc.go", "package calc\n\nfunc Add(a, b int) int { return a + b }\n", false)
+	if !strings.HasPrefix(got, "Successfully wrote to calc/calc.go on branch main\n✅ gofmt and go vet ./calc passed") {
+		t.Errorf("Expected a passing check appended, got %q", got)
+	}
+
+	got = writeAction(t, tool, "calc/sub.go", "package calc\n\nfunc Sub(a, b int) int {\n\treturn a -\n", false)
+	if !strings.Contains(got, "Successfully wrote") || !strings.Contains(got, "❌ gofmt: syntax errors in calc/sub.go") {
+		t.Errorf("Expected a failing check appended, got %q", got)
+	}
+	// A failed check keeps the write
+	if _, err := os.Stat(filepath.Join(tool.manager.GetRepoDir(), "calc", "sub.go")); err != nil {
+		t.Errorf("Expected the file written despite the failure: %v", err)
+	}
+
+	if got := writeAction(t, tool, "calc/sub.go", "package calc\n", true); strings.Contains(got, "gofmt") {
+		t.Errorf("Expected skip_check to skip the check, got %q", got)
+	}
+	if got := writeAction(t, tool, "README.md", "# calc\n", false); strings.Contains(got, "gofmt") {
+		t.Errorf("Expected non-Go files unchecked, got %q", got)
+	}
+}
@@ -0,0 +1,76 @@
+// Stand-in for browser_driver.js that speaks the same protocol without
+// Playwright. Pages are fetched with http and kept per session; click and
+// type are recorded. FAKE_BROWSER_FAIL=1 makes startup fail.
+const fs = require('fs');
+const http = require('http');
+const readline = require('readline');
+
+if (process.env.FAKE_BROWSER_FAIL) {
+  process.stdout.write(JSON.stringify({ ready: false, error: 'no chromium' }) + '\n');
+  process.exit(1);
+}
+
+function send(msg) {
+  process.stdout.write(JSON.stringify(msg) + '\n');
+}
+
+function get(url) {
+  return new Promise((resolve, reject) => {
+    http.get(url, (res) => {
+      let body = '';
+      res.on('data', (d) => (body += d));
+      res.on('end', () => resolve(body));
+    }).on('error', reject);
+  });
+}
+
+const sessions = new Map();
+
+async function handle(req) {
+  if (req.action === 'close') {
+    sessions.delete(req.session);
+    return 'closed';
+  }
+  let page = sessions.get(req.session);
+  if (!page) {
+    page = { url: '', body: '', events: [] };
+    sessions.set(req.session, page);
+  }
+  if (req.url) {
+    page.url = req.url;
+    page.body = await get(req.url);
+  }
+  switch (req.action) {
+    case 'screenshot':
+    case 'pdf':
+      fs.writeFileSync(req.path, page.body);
+      return req.path;
+    case 'content':
+      return [page.body.replace(/<[^>]*>/g, '').trim(), ...page.events].join('\n');
+    case 'click':
+      page.events.push('click ' + req.selector);
+      return 'clicked ' + req.selector;
+    case 'type':
+      page.events.push('type ' + req.selector + '=' + req.text);
+      return 'typed into ' + req.selector;
+    case 'wait_for':
+      if (!page.body.includes(req.selector.replace('#', 'id="'))) {
+        throw new Error('timeout waiting for ' + req.selector);
+      }
+      return req.selector + ' is visible';
+    case 'evaluate':
+      return eval(req.script);
+    default:
+      throw new Error('unknown action: ' + req.action);
+  }
+}
+
+send({ ready: true, pid: process.pid });
+readline.createInterface({ input: process.stdin }).on('line', async (line) => {
+  const req = JSON.parse(line);
+  try {
+    send({ id: req.id, ok: true, result: await handle(req) });
+  } catch (e) {
+    send({ id: req.id, ok: false, error: String(e.message || e) });
+  }
+});
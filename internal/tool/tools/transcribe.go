@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// TranscribeTool turns audio files into text with Groq's Whisper models
+type TranscribeTool struct {
+	client *client.Client
+}
+
+func NewTranscribeTool(c *client.Client) *TranscribeTool {
+	return &TranscribeTool{client: c}
+}
+
+func (t *TranscribeTool) Name() string {
+	return "Transcribe"
+}
+
+func (t *TranscribeTool) Description() string {
+	return fmt.Sprintf(`Transcribe speech in an audio file to text with Whisper on Groq.
+
+Give either file_path for a file on disk or upload_id for a file the user uploaded. Supported formats: flac, mp3, mp4, mpeg, mpga, m4a, ogg, opus, wav and webm, up to %d MB.
+
+The language is detected unless given. Set timestamps to get the text in timed segments, such as for subtitles or finding where something was said.`, client.MaxTranscriptionBytes>>20)
+}
+
+func (t *TranscribeTool) Parameters() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "Path of the audio file",
+			},
+			"upload_id": map[string]any{
+				"type":        "string",
+				"description": "ID of an audio file the user uploaded",
+			},
+			"language": map[string]any{
+				"type":        "string",
+				"description": "ISO-639-1 code of the spoken language, such as \"en\" or \"ja\" (default: detected)",
+			},
+			"timestamps": map[string]any{
+				"type":        "boolean",
+				"description": "Return timed segments instead of plain text (default: false)",
+			},
+			"model": map[string]any{
+				"type":        "string",
+				"description": "Whisper model (default: " + client.DefaultTranscriptionModel + ")",
+				"enum":        client.TranscriptionModels,
+			},
+		},
+	}
+}
+
+type transcribeArgs struct {
+	FilePath   string `json:"file_path"`
+	UploadID   string `json:"upload_id"`
+	Language   string `json:"language"`
+	Timestamps bool   `json:"timestamps"`
+	Model      string `json:"model"`
+}
+
+func (t *TranscribeTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool.Result, error) {
+	var args transcribeArgs
+	if err := json.Unmarshal(argsJSON, &args); err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("invalid arguments: %v", err)), nil
+	}
+
+	path, name, err := t.audioFile(ctx, args)
+	if err != nil {
+		return tool.NewErrorResult(err.Error()), nil
+	}
+	if !client.CanTranscribe(name) {
+		return tool.NewErrorResult(fmt.Sprintf("%s is not a supported audio format", name)), nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("failed to open audio: %v", err)), nil
+	}
+	if info.IsDir() {
+		return tool.NewErrorResult(fmt.Sprintf("%s is a directory", path)), nil
+	}
+	if info.Size() > client.MaxTranscriptionBytes {
+		return tool.NewErrorResult(fmt.Sprintf("%s is %.1f MB; the limit is %d MB", name, float64(info.Size())/(1<<20), client.MaxTranscriptionBytes>>20)), nil
+	}
+	audio, err := os.ReadFile(path)
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("failed to read audio: %v", err)), nil
+	}
+
+	// Each transcription is billed as one request to the Whisper model
+	model := args.Model
+	if model == "" {
+		model = client.DefaultTranscriptionModel
+	}
+	billing := tool.BillingFromContext(ctx)
+	if billing != nil {
+		if err := billing.Check(model); err != nil {
+			return tool.NewErrorResult(err.Error()), nil
+		}
+	}
+	result, err := t.client.Transcribe(ctx, audio, name, model, args.Language, client.TranscribeOptions{Timestamps: args.Timestamps})
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("transcription failed: %v", err)), nil
+	}
+	if billing != nil {
+		billing.Charge(model)
+	}
+	if !args.Timestamps {
+		if strings.TrimSpace(result.Text) == "" {
+			return tool.NewResult("(no speech detected)"), nil
+		}
+		return tool.NewResult(strings.TrimSpace(result.Text)), nil
+	}
+	return tool.NewResult(formatSegments(result)), nil
+}
+
+// audioFile returns the path of the audio to transcribe and the name that
+// tells its format
+func (t *TranscribeTool) audioFile(ctx context.Context, args transcribeArgs) (path, name string, err error) {
+	switch {
+	case args.FilePath != "" && args.UploadID != "":
+		return "", "", fmt.Errorf("give file_path or upload_id, not both")
+	case args.UploadID != "":
+		uploads := tool.UploadsFromContext(ctx)
+		if uploads == nil {
+			return "", "", fmt.Errorf("uploads are not available here; use file_path")
+		}
+		path, name, err := uploads(args.UploadID)
+		if err != nil {
+			return "", "", fmt.Errorf("upload %s: %w", args.UploadID, err)
+		}
+		return path, name, nil
+	case args.FilePath != "":
+		// Inside a project, stay within its root
+		path, err := tool.SandboxFromContext(ctx).Resolve(args.FilePath)
+		if err != nil {
+			return "", "", err
+		}
+		return path, filepath.Base(path), nil
+	}
+	return "", "", fmt.Errorf("file_path or upload_id is required")
+}
+
+// formatSegments lists the timed segments of r under a summary line
+func formatSegments(r *client.Transcription) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Language: %s, duration: %s\n", r.Language, formatTimestamp(r.Duration))
+	if len(r.Segments) == 0 {
+		b.WriteString("(no speech detected)")
+		return b.String()
+	}
+	for _, seg := range r.Segments {
+		fmt.Fprintf(&b, "\n[%s - %s] %s", formatTimestamp(seg.Start), formatTimestamp(seg.End), strings.TrimSpace(seg.Text))
+	}
+	return b.String()
+}
+
+// formatTimestamp writes seconds as m:ss.s, or h:mm:ss.s past an hour
+func formatTimestamp(seconds float64) string {
+	tenths := int(seconds*10 + 0.5)
+	h, m, s := tenths/36000, tenths/600%60, float64(tenths%600)/10
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%04.1f", h, m, s)
+	}
+	return fmt.Sprintf("%d:%04.1f", m, s)
+}
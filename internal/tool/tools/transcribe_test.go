@@ -0,0 +1,168 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// whisperStub answers transcription requests and keeps the last upload
+type whisperStub struct {
+	filename string
+	audio    []byte
+	language string
+}
+
+func (s *whisperStub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.filename = header.Filename
+	s.audio, _ = io.ReadAll(f)
+	s.language = r.FormValue("language")
+	if r.FormValue("response_format") == "verbose_json" {
+		fmt.Fprint(w, `{"text": "Hello. Testing.", "language": "english", "duration": 65.25,
+			"segments": [{"id": 0, "start": 0, "end": 1.04, "text": " Hello."}, {"id": 1, "start": 59.96, "end": 65.25, "text": " Testing."}]}`)
+		return
+	}
+	fmt.Fprint(w, `{"text": " Hello. Testing."}`)
+}
+
+func newTranscribeTool(t *testing.T) (*TranscribeTool, *whisperStub) {
+	t.Helper()
+	stub := &whisperStub{}
+	srv := httptest.NewServer(stub)
+	t.Cleanup(srv.Close)
+	return NewTranscribeTool(client.New("groq-key", client.WithBaseURL(srv.URL))), stub
+}
+
+func runTranscribe(t *testing.T, ctx context.Context, tt *TranscribeTool, args map[string]any) tool.Result {
+	t.Helper()
+	raw, _ := json.Marshal(args)
+	result, err := tt.Execute(ctx, raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestTranscribeFile(t *testing.T) {
+	tt, stub := newTranscribeTool(t)
+	fixture, err := os.ReadFile("testdata/hello.wav")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	result := runTranscribe(t, context.Background(), tt, map[string]any{"file_path": "testdata/hello.wav", "language": "en"})
+	if result.IsError || result.Content != "Hello. Testing." {
+		t.Fatalf("Expected the trimmed text, got %+v", result)
+	}
+	if stub.filename != "hello.wav" || !bytes.Equal(stub.audio, fixture) || stub.language != "en" {
+		t.Errorf("Expected the fixture sent as hello.wav in English, got %q (%d bytes) in %q", stub.filename, len(stub.audio), stub.language)
+	}
+
+	result = runTranscribe(t, context.Background(), tt, map[string]any{"file_path": "testdata/hello.wav", "timestamps": true})
+	want := "Language: english, duration: 1:05.3\n\n[0:00.0 - 0:01.0] Hello.\n[1:00.0 - 1:05.3] Testing."
+	if result.Content != want {
+		t.Errorf("Expected timed segments:\n%s\ngot:\n%s", want, result.Content)
+	}
+}
+
+func TestTranscribeUpload(t *testing.T) {
+	tt, stub := newTranscribeTool(t)
+	uploads := func(id string) (string, string, error) {
+		if id != "abc" {
+			return "", "", fmt.Errorf("upload not found")
+		}
+		return "testdata/hello.wav", "voice memo.wav", nil
+	}
+
+	if result := runTranscribe(t, context.Background(), tt, map[string]any{"upload_id": "abc"}); !result.IsError || !strings.Contains(result.Content, "not available") {
+		t.Errorf("Expected uploads to need the web UI, got %+v", result)
+	}
+	ctx := tool.WithUploads(context.Background(), uploads)
+	if result := runTranscribe(t, ctx, tt, map[string]any{"upload_id": "abc"}); result.IsError || stub.filename != "voice memo.wav" {
+		t.Errorf("Expected the upload sent under its name, got %+v as %q", result, stub.filename)
+	}
+	if result := runTranscribe(t, ctx, tt, map[string]any{"upload_id": "missing"}); !result.IsError || !strings.Contains(result.Content, "not found") {
+		t.Errorf("Expected an unknown upload to fail, got %+v", result)
+	}
+}
+
+func TestTranscribeRejects(t *testing.T) {
+	tt, _ := newTranscribeTool(t)
+	notes := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(notes, []byte("not audio"), 0644)
+
+	for _, args := range []map[string]any{
+		{},
+		{"file_path": "testdata/hello.wav", "upload_id": "abc"},
+		{"file_path": notes},
+		{"file_path": "testdata/missing.wav"},
+	} {
+		if result := runTranscribe(t, context.Background(), tt, args); !result.IsError {
+			t.Errorf("Expected %v to fail, got %+v", args, result)
+		}
+	}
+
+	sandbox, err := tool.NewSandbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	abs, _ := filepath.Abs("testdata/hello.wav")
+	if result := runTranscribe(t, tool.WithSandbox(context.Background(), sandbox), tt, map[string]any{"file_path": abs}); !result.IsError {
+		t.Errorf("Expected files outside the project to be refused, got %+v", result)
+	}
+}
+
+// fakeBilling allows calls while credits last and counts the charges
+type fakeBilling struct {
+	credits int
+	charged []string
+}
+
+func (b *fakeBilling) Check(model string) error {
+	if len(b.charged) >= b.credits {
+		return fmt.Errorf("Insufficient credits")
+	}
+	return nil
+}
+
+func (b *fakeBilling) Charge(model string) {
+	b.charged = append(b.charged, model)
+}
+
+func TestTranscribeBilling(t *testing.T) {
+	tt, stub := newTranscribeTool(t)
+	billing := &fakeBilling{credits: 1}
+	ctx := tool.WithBilling(context.Background(), billing)
+
+	if result := runTranscribe(t, ctx, tt, map[string]any{"file_path": "testdata/hello.wav"}); result.IsError {
+		t.Fatalf("Expected the first transcription to run, got %+v", result)
+	}
+	if len(billing.charged) != 1 || billing.charged[0] != client.DefaultTranscriptionModel {
+		t.Errorf("Expected one request to the default model charged, got %v", billing.charged)
+	}
+
+	stub.audio = nil
+	result := runTranscribe(t, ctx, tt, map[string]any{"file_path": "testdata/hello.wav"})
+	if !result.IsError || !strings.Contains(result.Content, "Insufficient credits") || stub.audio != nil {
+		t.Errorf("Expected the call refused before reaching Groq, got %+v", result)
+	}
+	if len(billing.charged) != 1 {
+		t.Errorf("Expected a refused call not to be charged, got %v", billing.charged)
+	}
+}
@@ -3,13 +3,19 @@ package tools
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
+	"groq-go/internal/selfimprove"
 	"groq-go/internal/tool"
 	"groq-go/internal/version"
 )
 
+// followFor is how long the logs action collects new output with follow
+var followFor = 30 * time.Second
+
 // VersionTool allows the AI to manage agent versions
 type VersionTool struct {
 	manager *version.Manager
@@ -35,8 +41,9 @@ func (t *VersionTool) Description() string {
 - "stop": Stop a running version (requires id)
 - "restart": Restart a version (requires id)
 - "delete": Delete a version (requires id)
-- "logs": Get version logs (requires id, optional lines)
+- "logs": Get version logs (requires id, optional lines; follow collects up to 30s of new output)
 - "apply_changes": Apply code changes to a version's branch (requires id, path, content)
+- "promote": Merge a running version into main after it stays healthy for a soak window (requires id)
 
 ## Workflow
 1. Create a new version with "create"
@@ -44,7 +51,7 @@ func (t *VersionTool) Description() string {
 3. Build with "build"
 4. Start with "start" to run on a different port
 5. Users can switch to test the new version
-6. If good, the version can be promoted to main
+6. If good, promote it to main with "promote"; it must be running and pass health checks
 
 ## Notes
 - Each version runs on a different port (8081-8090)
@@ -59,11 +66,11 @@ func (t *VersionTool) Parameters() map[string]any {
 			"action": map[string]any{
 				"type":        "string",
 				"description": "Action to perform",
-				"enum":        []string{"create", "list", "get", "build", "start", "stop", "restart", "delete", "logs", "apply_changes"},
+				"enum":        []string{"create", "list", "get", "build", "start", "stop", "restart", "delete", "logs", "apply_changes", "promote"},
 			},
 			"id": map[string]any{
 				"type":        "string",
-				"description": "Version ID (required for get, build, start, stop, restart, delete, logs, apply_changes)",
+				"description": "Version ID (required for get, build, start, stop, restart, delete, logs, apply_changes, promote)",
 			},
 			"name": map[string]any{
 				"type":        "string",
@@ -85,6 +92,10 @@ func (t *VersionTool) Parameters() map[string]any {
 				"type":        "integer",
 				"description": "Number of log lines to return (default: 50)",
 			},
+			"follow": map[string]any{
+				"type":        "boolean",
+				"description": "For logs: also wait up to 30 seconds and return new output",
+			},
 		},
 		"required": []string{"action"},
 	}
@@ -103,6 +114,7 @@ func (t *VersionTool) Execute(ctx context.Context, args json.RawMessage) (tool.R
 		Path        string `json:"path"`
 		Content     string `json:"content"`
 		Lines       int    `json:"lines"`
+		Follow      bool   `json:"follow"`
 	}
 
 	if err := json.Unmarshal(args, &params); err != nil {
@@ -139,11 +151,14 @@ func (t *VersionTool) Execute(ctx context.Context, args json.RawMessage) (tool.R
 		if lines <= 0 {
 			lines = 50
 		}
-		return t.handleLogs(params.ID, lines)
+		return t.handleLogs(ctx, params.ID, lines, params.Follow)
 
 	case "apply_changes":
 		return t.handleApplyChanges(ctx, params.ID, params.Path, params.Content)
 
+	case "promote":
+		return t.handlePromote(ctx, params.ID)
+
 	default:
 		return tool.Result{Content: "Unknown action: " + params.Action, IsError: true}, nil
 	}
@@ -203,7 +218,11 @@ func (t *VersionTool) handleBuild(ctx context.Context, id string) (tool.Result,
 		return tool.Result{Content: "id is required for build action", IsError: true}, nil
 	}
 
-	if err := t.manager.BuildVersion(ctx, id); err != nil {
+	progress := selfimprove.ProgressFunc(tool.OutputFromContext(ctx))
+	if err := t.manager.BuildVersion(ctx, id, progress); err != nil {
+		if errors.Is(err, selfimprove.ErrAlreadyBuilding) {
+			return tool.Result{Content: fmt.Sprintf("Version %s is %v", id, err)}, nil
+		}
 		return tool.Result{Content: fmt.Sprintf("Build failed: %v", err), IsError: true}, nil
 	}
 
@@ -261,17 +280,43 @@ func (t *VersionTool) handleDelete(ctx context.Context, id string) (tool.Result,
 	return tool.Result{Content: fmt.Sprintf("Deleted version %s", id)}, nil
 }
 
-func (t *VersionTool) handleLogs(id string, lines int) (tool.Result, error) {
+func (t *VersionTool) handleLogs(ctx context.Context, id string, lines int, follow bool) (tool.Result, error) {
 	if id == "" {
 		return tool.Result{Content: "id is required for logs action", IsError: true}, nil
 	}
 
+	var stream <-chan string
+	if follow {
+		// Start following first so nothing is lost between the two reads
+		followCtx, cancel := context.WithTimeout(ctx, followFor)
+		defer cancel()
+		var err error
+		if stream, err = t.manager.StreamVersionLogs(followCtx, id); err != nil {
+			return tool.Result{Content: fmt.Sprintf("Failed to follow logs: %v", err), IsError: true}, nil
+		}
+	}
+
 	logs, err := t.manager.GetVersionLogs(id, lines)
 	if err != nil {
 		return tool.Result{Content: fmt.Sprintf("Failed to get logs: %v", err), IsError: true}, nil
 	}
+	if !follow {
+		return tool.Result{Content: logs}, nil
+	}
 
-	return tool.Result{Content: logs}, nil
+	var sb strings.Builder
+	sb.WriteString(logs)
+	if logs != "" && !strings.HasSuffix(logs, "\n") {
+		sb.WriteString("\n")
+	}
+	fmt.Fprintf(&sb, "--- following for %s ---\n", followFor)
+	n := 0
+	for line := range stream {
+		sb.WriteString(line + "\n")
+		n++
+	}
+	fmt.Fprintf(&sb, "--- %d new lines ---", n)
+	return tool.Result{Content: sb.String()}, nil
 }
 
 func (t *VersionTool) handleApplyChanges(ctx context.Context, id, path, content string) (tool.Result, error) {
@@ -292,18 +337,26 @@ func (t *VersionTool) handleApplyChanges(ctx context.Context, id, path, content
 		return tool.Result{Content: "Self-improve not available", IsError: true}, nil
 	}
 
-	// Checkout the version's branch
-	repoDir := t.manager.GetRepoDir()
-	if err := runGit(ctx, repoDir, "checkout", v.Branch); err != nil {
-		return tool.Result{Content: fmt.Sprintf("Failed to checkout branch: %v", err), IsError: true}, nil
+	// Commit the file on the version's branch; the checked-out branch is left alone
+	hash, err := sim.ApplyToBranch(ctx, v.Branch, path, content, "Update "+path)
+	if err != nil {
+		return tool.Result{Content: fmt.Sprintf("Failed to apply changes: %v", err), IsError: true}, nil
+	}
+
+	return tool.Result{Content: fmt.Sprintf("Applied changes to %s on branch %s (commit %s)\nNext: Use 'build' to compile the changes.", path, v.Branch, hash[:8])}, nil
+}
+
+func (t *VersionTool) handlePromote(ctx context.Context, id string) (tool.Result, error) {
+	if id == "" {
+		return tool.Result{Content: "id is required for promote action", IsError: true}, nil
 	}
 
-	// Write the file
-	if err := sim.WriteFile(ctx, path, content); err != nil {
-		return tool.Result{Content: fmt.Sprintf("Failed to write file: %v", err), IsError: true}, nil
+	v, err := t.manager.PromoteVersion(ctx, id)
+	if err != nil {
+		return tool.Result{Content: fmt.Sprintf("Promotion failed, main is unchanged: %v", err), IsError: true}, nil
 	}
 
-	return tool.Result{Content: fmt.Sprintf("Applied changes to %s on branch %s\nNext: Use 'build' to compile the changes.", path, v.Branch)}, nil
+	return tool.Result{Content: fmt.Sprintf("Promoted version %s (%s): %s merged into main and marked as known good.\nNext: Use SelfImprove 'safe_push' or 'open_pr' to deploy it.", v.Name, v.ID, v.Branch)}, nil
 }
 
 func getStatusIcon(s version.Status) string {
@@ -324,8 +377,3 @@ func getStatusIcon(s version.Status) string {
 		return "❓"
 	}
 }
-
-// runGit is a helper to run git commands
-func runGit(ctx context.Context, dir string, args ...string) error {
-	return version.RunGitCmd(ctx, dir, args...)
-}
@@ -1,12 +1,17 @@
 package tools
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,19 +20,49 @@ import (
 
 type WebFetchTool struct {
 	client *http.Client
+	// maxRedirects is the default redirect budget per request
+	maxRedirects int
 }
 
 type WebFetchArgs struct {
-	URL     string `json:"url"`
-	Method  string `json:"method,omitempty"`
+	URL     string            `json:"url"`
+	Method  string            `json:"method,omitempty"`
 	Headers map[string]string `json:"headers,omitempty"`
+	// Body is sent with POST and PUT requests
+	Body        string `json:"body,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	// MaxRedirects overrides the redirect budget; 0 uses the default
+	MaxRedirects int `json:"max_redirects,omitempty"`
+	// OutputPath saves the raw response body to a file, with the same
+	// checks as Write
+	OutputPath string `json:"output_path,omitempty"`
+	// Overwrite allows replacing an output file not read in this session
+	Overwrite     bool `json:"overwrite,omitempty"`
+	ReturnHeaders bool `json:"return_headers,omitempty"`
 }
 
+const (
+	// defaultMaxRedirects matches net/http's own limit
+	defaultMaxRedirects = 10
+	maxRedirectsCap     = 20
+
+	// Bodies are read up to a limit that depends on what is done with
+	// them: HTML shrinks a lot when converted to text, binaries are only
+	// counted unless saved
+	htmlReadLimit   = 1 << 20
+	textReadLimit   = 100 << 10
+	binaryReadLimit = 50 << 20
+
+	// maxContentChars bounds the text returned to the model
+	maxContentChars = 50000
+)
+
 func NewWebFetchTool() *WebFetchTool {
 	return &WebFetchTool{
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
+		maxRedirects: defaultMaxRedirects,
 	}
 }
 
@@ -36,7 +71,7 @@ func (t *WebFetchTool) Name() string {
 }
 
 func (t *WebFetchTool) Description() string {
-	return "Fetches content from a URL. Returns the response body. HTML is converted to readable text."
+	return "Fetches content from a URL. Returns the response body. HTML is converted to readable text, JSON is pretty-printed, and binary content is summarized (use output_path to save it). Supports request bodies for POST and PUT."
 }
 
 func (t *WebFetchTool) Parameters() map[string]any {
@@ -56,6 +91,30 @@ func (t *WebFetchTool) Parameters() map[string]any {
 				"type":        "object",
 				"description": "Optional HTTP headers",
 			},
+			"body": map[string]any{
+				"type":        "string",
+				"description": "Request body for POST or PUT",
+			},
+			"content_type": map[string]any{
+				"type":        "string",
+				"description": "Content-Type of the body (default: application/json if the body is JSON, otherwise text/plain)",
+			},
+			"max_redirects": map[string]any{
+				"type":        "integer",
+				"description": fmt.Sprintf("Maximum redirects to follow (default: %d, max: %d)", defaultMaxRedirects, maxRedirectsCap),
+			},
+			"output_path": map[string]any{
+				"type":        "string",
+				"description": "Save the raw response body to this file, e.g. for images or archives",
+			},
+			"overwrite": map[string]any{
+				"type":        "boolean",
+				"description": "Replace an existing output_path file even though it was not read in this session",
+			},
+			"return_headers": map[string]any{
+				"type":        "boolean",
+				"description": "Include the response headers in the result",
+			},
 		},
 		"required": []string{"url"},
 	}
@@ -71,12 +130,26 @@ func (t *WebFetchTool) Execute(ctx context.Context, argsJSON json.RawMessage) (t
 		return tool.NewErrorResult("url is required"), nil
 	}
 
-	method := args.Method
+	method := strings.ToUpper(args.Method)
 	if method == "" {
 		method = "GET"
 	}
 
-	req, err := http.NewRequestWithContext(ctx, method, args.URL, nil)
+	// Check the output file before anything is sent
+	var outputPath string
+	if args.OutputPath != "" {
+		path, _, err := writablePath(ctx, args.OutputPath, args.Overwrite)
+		if err != nil {
+			return tool.NewErrorResult(err.Error()), nil
+		}
+		outputPath = path
+	}
+
+	var body io.Reader
+	if args.Body != "" {
+		body = strings.NewReader(args.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, args.URL, body)
 	if err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("failed to create request: %v", err)), nil
 	}
@@ -84,40 +157,160 @@ func (t *WebFetchTool) Execute(ctx context.Context, argsJSON json.RawMessage) (t
 	// Set default headers
 	req.Header.Set("User-Agent", "groq-go/1.0")
 	req.Header.Set("Accept", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8")
+	if args.Body != "" {
+		contentType := args.ContentType
+		if contentType == "" {
+			contentType = "text/plain; charset=utf-8"
+			if json.Valid([]byte(args.Body)) {
+				contentType = "application/json"
+			}
+		}
+		req.Header.Set("Content-Type", contentType)
+	}
 
 	// Add custom headers
 	for k, v := range args.Headers {
 		req.Header.Set(k, v)
 	}
 
-	resp, err := t.client.Do(req)
+	maxRedirects := t.maxRedirects
+	if args.MaxRedirects > 0 {
+		maxRedirects = min(args.MaxRedirects, maxRedirectsCap)
+	}
+	var redirects []string
+	client := *t.client
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxRedirects {
+			return fmt.Errorf("stopped after %d redirects", maxRedirects)
+		}
+		redirects = append(redirects, req.URL.String())
+		return nil
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("request failed: %v", err)), nil
 	}
 	defer resp.Body.Close()
 
-	// Limit response size
-	limitedReader := io.LimitReader(resp.Body, 100*1024) // 100KB limit
-	body, err := io.ReadAll(limitedReader)
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	limit := int64(textReadLimit)
+	switch {
+	case args.OutputPath != "" || !isTextMedia(mediaType):
+		limit = binaryReadLimit
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
+		limit = htmlReadLimit
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
 	if err != nil {
 		return tool.NewErrorResult(fmt.Sprintf("failed to read response: %v", err)), nil
 	}
+	truncated := int64(len(data)) > limit
+	if truncated {
+		data = data[:limit]
+	}
+	if mediaType == "" {
+		mediaType, _, _ = mime.ParseMediaType(http.DetectContentType(data))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Status: %d\nURL: %s\n", resp.StatusCode, resp.Request.URL.String())
+	if len(redirects) > 0 {
+		fmt.Fprintf(&b, "Redirects: %s -> %s\n", args.URL, strings.Join(redirects, " -> "))
+	}
+	if args.ReturnHeaders {
+		b.WriteString("Headers:\n")
+		names := make([]string, 0, len(resp.Header))
+		for name := range resp.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(&b, "  %s: %s\n", name, strings.Join(resp.Header.Values(name), ", "))
+		}
+	}
+	b.WriteString("\n")
 
-	content := string(body)
+	if outputPath != "" {
+		path := outputPath
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return tool.NewErrorResult(fmt.Sprintf("failed to create directory: %v", err)), nil
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return tool.NewErrorResult(fmt.Sprintf("failed to save response: %v", err)), nil
+		}
+		fmt.Fprintf(&b, "Saved %d bytes of %s to %s", len(data), mediaType, path)
+		if truncated {
+			fmt.Fprintf(&b, " (truncated at the %d byte limit)", limit)
+		}
+		return tool.NewResult(b.String()), nil
+	}
 
-	// Convert HTML to text if needed
-	contentType := resp.Header.Get("Content-Type")
-	if strings.Contains(contentType, "text/html") {
+	if !isTextMedia(mediaType) {
+		fmt.Fprintf(&b, "%d bytes of %s", len(data), mediaType)
+		if truncated {
+			b.WriteString(" (or more)")
+		}
+		b.WriteString(". Use output_path to save it.")
+		return tool.NewResult(b.String()), nil
+	}
+
+	content := string(data)
+	switch {
+	case mediaType == "text/html" || mediaType == "application/xhtml+xml":
 		content = htmlToText(content)
+	case isJSONMedia(mediaType):
+		var pretty bytes.Buffer
+		if json.Indent(&pretty, data, "", "  ") == nil {
+			content = pretty.String()
+		}
 	}
 
 	// Truncate if too long
-	if len(content) > 50000 {
-		content = content[:50000] + "\n... (truncated)"
+	if truncated || len(content) > maxContentChars {
+		if len(content) > maxContentChars {
+			content = content[:maxContentChars]
+		}
+		content += "\n... (truncated)"
 	}
 
-	result := fmt.Sprintf("Status: %d\nURL: %s\n\n%s", resp.StatusCode, resp.Request.URL.String(), content)
-	return tool.NewResult(result), nil
+	b.WriteString(content)
+	return tool.NewResult(b.String()), nil
+}
+
+// Mutates implements tool.Mutator: saving a file or sending anything but
+// GET changes things
+func (t *WebFetchTool) Mutates(argsJSON json.RawMessage) bool {
+	var args WebFetchArgs
+	if json.Unmarshal(argsJSON, &args) != nil {
+		return true
+	}
+	return args.OutputPath != "" || (args.Method != "" && !strings.EqualFold(args.Method, http.MethodGet))
+}
+
+// NeedsApproval implements tool.Approvable: calls that Mutates reports
+// need approval
+func (t *WebFetchTool) NeedsApproval(ctx context.Context, argsJSON json.RawMessage) bool {
+	return t.Mutates(argsJSON)
+}
+
+// isTextMedia reports whether a media type can be shown to the model as text
+func isTextMedia(mediaType string) bool {
+	if mediaType == "" || strings.HasPrefix(mediaType, "text/") || isJSONMedia(mediaType) {
+		return true
+	}
+	if strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	switch mediaType {
+	case "application/xml", "application/javascript", "application/x-www-form-urlencoded", "application/x-yaml", "application/yaml":
+		return true
+	}
+	return false
+}
+
+func isJSONMedia(mediaType string) bool {
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
 }
 
 // htmlToText converts HTML to readable plain text
@@ -0,0 +1,241 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+func newFetchServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("X-Request-Id", "abc")
+		io.WriteString(w, `{"name":"groq","tags":["a","b"]}`)
+	})
+	mux.HandleFunc("/html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		io.WriteString(w, "<html><script>x()</script><h1>Title</h1><p>Hello &amp; welcome</p></html>")
+	})
+	mux.HandleFunc("/image", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		w.Write([]byte("\x89PNG\r\n\x1a\n\x00\x00binary"))
+	})
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		io.WriteString(w, r.Method+" "+r.Header.Get("Content-Type")+" "+string(body))
+	})
+	mux.HandleFunc("/r1", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/r2", http.StatusFound)
+	})
+	mux.HandleFunc("/r2", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/html", http.StatusFound)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func fetch(t *testing.T, args WebFetchArgs) string {
+	t.Helper()
+	result := runTool(t, context.Background(), NewWebFetchTool(), args)
+	if result.IsError {
+		t.Fatalf("WebFetch failed: %s", result.Content)
+	}
+	return result.Content
+}
+
+func TestWebFetchContentTypes(t *testing.T) {
+	srv := newFetchServer(t)
+
+	out := fetch(t, WebFetchArgs{URL: srv.URL + "/json", ReturnHeaders: true})
+	if !strings.Contains(out, "{\n  \"name\": \"groq\",") {
+		t.Errorf("Expected pretty-printed JSON, got %q", out)
+	}
+	if !strings.Contains(out, "X-Request-Id: abc") {
+		t.Errorf("Expected response headers, got %q", out)
+	}
+
+	out = fetch(t, WebFetchArgs{URL: srv.URL + "/html"})
+	if !strings.Contains(out, "Title") || !strings.Contains(out, "Hello & welcome") || strings.Contains(out, "x()") {
+		t.Errorf("Expected HTML converted to text, got %q", out)
+	}
+	if strings.Contains(out, "Headers:") {
+		t.Error("Expected no headers unless requested")
+	}
+
+	out = fetch(t, WebFetchArgs{URL: srv.URL + "/image"})
+	if !strings.Contains(out, "16 bytes of image/png") || strings.Contains(out, "PNG") {
+		t.Errorf("Expected binary summary, got %q", out)
+	}
+
+	path := filepath.Join(t.TempDir(), "sub", "image.png")
+	out = fetch(t, WebFetchArgs{URL: srv.URL + "/image", OutputPath: path})
+	if !strings.Contains(out, "Saved 16 bytes of image/png") {
+		t.Errorf("Expected save summary, got %q", out)
+	}
+	if data, _ := os.ReadFile(path); !strings.HasPrefix(string(data), "\x89PNG") {
+		t.Errorf("Expected raw body saved, got %q", data)
+	}
+}
+
+func TestWebFetchSendsBody(t *testing.T) {
+	srv := newFetchServer(t)
+
+	out := fetch(t, WebFetchArgs{URL: srv.URL + "/echo", Method: "POST", Body: `{"q":1}`})
+	if !strings.Contains(out, `POST application/json {"q":1}`) {
+		t.Errorf("Expected JSON body echoed, got %q", out)
+	}
+
+	out = fetch(t, WebFetchArgs{URL: srv.URL + "/echo", Method: "put", Body: "a=1", ContentType: "application/x-www-form-urlencoded"})
+	if !strings.Contains(out, "PUT application/x-www-form-urlencoded a=1") {
+		t.Errorf("Expected form body echoed, got %q", out)
+	}
+}
+
+func TestWebFetchOutputPathChecks(t *testing.T) {
+	srv := newFetchServer(t)
+	ctx := tool.WithReadTracker(context.Background(), tool.NewReadTracker())
+	dir := t.TempDir()
+
+	existing := filepath.Join(dir, "image.png")
+	os.WriteFile(existing, []byte("old"), 0644)
+	for _, path := range []string{filepath.Join(dir, ".bashrc"), filepath.Join(dir, "authorized_keys"), existing} {
+		if result := runTool(t, ctx, NewWebFetchTool(), WebFetchArgs{URL: srv.URL + "/image", OutputPath: path}); !result.IsError {
+			t.Errorf("Expected saving to %s to be refused, got %q", path, result.Content)
+		}
+	}
+	if data, _ := os.ReadFile(existing); string(data) != "old" {
+		t.Errorf("Expected the unread file untouched, got %q", data)
+	}
+	if result := runTool(t, ctx, NewWebFetchTool(), WebFetchArgs{URL: srv.URL + "/image", OutputPath: existing, Overwrite: true}); result.IsError {
+		t.Errorf("Expected overwrite to replace the file, got %q", result.Content)
+	}
+}
+
+func TestWebFetchOutputPathStaysInSandbox(t *testing.T) {
+	srv := newFetchServer(t)
+	base := t.TempDir()
+	root := filepath.Join(base, "project")
+	os.Mkdir(root, 0755)
+	sandbox, err := tool.NewSandbox(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := tool.WithSandbox(context.Background(), sandbox)
+
+	for _, path := range []string{"../escape.png", filepath.Join(base, "escape.png")} {
+		if result := runTool(t, ctx, NewWebFetchTool(), WebFetchArgs{URL: srv.URL + "/image", OutputPath: path}); !result.IsError {
+			t.Errorf("Expected %s to be refused, got %q", path, result.Content)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(base, "escape.png")); !os.IsNotExist(err) {
+		t.Error("Expected nothing written outside the project")
+	}
+
+	out := runTool(t, ctx, NewWebFetchTool(), WebFetchArgs{URL: srv.URL + "/image", OutputPath: "assets/image.png"})
+	if out.IsError {
+		t.Fatalf("Expected a relative path to save under the root, got %q", out.Content)
+	}
+	if _, err := os.Stat(filepath.Join(root, "assets", "image.png")); err != nil {
+		t.Errorf("Expected the file under the project root: %v", err)
+	}
+}
+
+func TestWebFetchInReadOnlyMode(t *testing.T) {
+	srv := newFetchServer(t)
+	registry := tool.NewRegistry()
+	registry.Register(NewWebFetchTool())
+	registry.Register(NewTranscribeTool(nil))
+	registry.SetReadOnly(true)
+	executor := tool.NewExecutor(registry)
+
+	offered := make(map[string]bool)
+	for _, t := range registry.ToClientTools() {
+		offered[t.Function.Name] = true
+	}
+	if !offered["WebFetch"] || offered["Transcribe"] {
+		t.Errorf("Expected WebFetch offered and Transcribe hidden, got %v", offered)
+	}
+
+	call := func(name string, args any) tool.Result {
+		t.Helper()
+		data, _ := json.Marshal(args)
+		result, err := executor.ExecuteToolCall(context.Background(), client.ToolCall{
+			ID:       "call_0",
+			Function: client.FunctionCall{Name: name, Arguments: string(data)},
+		})
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		return result
+	}
+	path := filepath.Join(t.TempDir(), "image.png")
+	for _, args := range []WebFetchArgs{
+		{URL: srv.URL + "/image", OutputPath: path},
+		{URL: srv.URL + "/echo", Method: "POST", Body: "x"},
+	} {
+		if result := call("WebFetch", args); !strings.Contains(result.Content, "disabled in read-only mode") {
+			t.Errorf("Expected %+v to be blocked, got %q", args, result.Content)
+		}
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("Expected nothing saved in read-only mode")
+	}
+	if result := call("WebFetch", WebFetchArgs{URL: srv.URL + "/html"}); result.IsError {
+		t.Errorf("Expected a GET to run in read-only mode, got %q", result.Content)
+	}
+	if result := call("Transcribe", map[string]string{"file_path": "memo.wav"}); !strings.Contains(result.Content, "disabled in read-only mode") {
+		t.Errorf("Expected Transcribe to be blocked, got %q", result.Content)
+	}
+}
+
+func TestWebFetchMutates(t *testing.T) {
+	fetch := NewWebFetchTool()
+	for _, tc := range []struct {
+		args WebFetchArgs
+		want bool
+	}{
+		{WebFetchArgs{URL: "https://example.com"}, false},
+		{WebFetchArgs{URL: "https://example.com", Method: "get"}, false},
+		{WebFetchArgs{URL: "https://example.com", Method: "POST", Body: "x"}, true},
+		{WebFetchArgs{URL: "https://example.com", Method: "DELETE"}, true},
+		{WebFetchArgs{URL: "https://example.com", OutputPath: "out.bin"}, true},
+	} {
+		args, _ := json.Marshal(tc.args)
+		if got := fetch.Mutates(args); got != tc.want {
+			t.Errorf("Mutates(%+v) = %v, want %v", tc.args, got, tc.want)
+		}
+		if got := fetch.NeedsApproval(context.Background(), args); got != tc.want {
+			t.Errorf("NeedsApproval(%+v) = %v, want %v", tc.args, got, tc.want)
+		}
+	}
+}
+
+func TestWebFetchRedirects(t *testing.T) {
+	srv := newFetchServer(t)
+
+	out := fetch(t, WebFetchArgs{URL: srv.URL + "/r1"})
+	if !strings.Contains(out, "URL: "+srv.URL+"/html\n") {
+		t.Errorf("Expected final URL, got %q", out)
+	}
+	if !strings.Contains(out, "Redirects: "+srv.URL+"/r1 -> "+srv.URL+"/r2 -> "+srv.URL+"/html") {
+		t.Errorf("Expected redirect chain, got %q", out)
+	}
+
+	result := runTool(t, context.Background(), NewWebFetchTool(), WebFetchArgs{URL: srv.URL + "/r1", MaxRedirects: 1})
+	if !result.IsError || !strings.Contains(result.Content, "stopped after 1 redirects") {
+		t.Errorf("Expected redirect budget error, got %q", result.Content)
+	}
+}
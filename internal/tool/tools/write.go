@@ -15,6 +15,10 @@ type WriteTool struct{}
 type WriteArgs struct {
 	FilePath string `json:"file_path"`
 	Content  string `json:"content"`
+	// Overwrite allows replacing a file not read in this session
+	Overwrite bool `json:"overwrite,omitempty"`
+	// CreateDirs creates missing parent directories; nil means true
+	CreateDirs *bool `json:"create_dirs,omitempty"`
 }
 
 func NewWriteTool() *WriteTool {
@@ -26,7 +30,8 @@ func (t *WriteTool) Name() string {
 }
 
 func (t *WriteTool) Description() string {
-	return "Writes content to a file. Creates the file if it doesn't exist, overwrites if it does."
+	return "Writes content to a file. Creates the file, and its parent directories, if it doesn't exist. " +
+		"An existing file is only overwritten if it was read in this session, or with overwrite set; prefer Edit for changes to existing files."
 }
 
 func (t *WriteTool) Parameters() map[string]any {
@@ -41,6 +46,14 @@ func (t *WriteTool) Parameters() map[string]any {
 				"type":        "string",
 				"description": "The content to write to the file",
 			},
+			"overwrite": map[string]any{
+				"type":        "boolean",
+				"description": "Replace an existing file even though it was not read in this session",
+			},
+			"create_dirs": map[string]any{
+				"type":        "boolean",
+				"description": "Create missing parent directories (default true)",
+			},
 		},
 		"required": []string{"file_path", "content"},
 	}
@@ -56,33 +69,103 @@ func (t *WriteTool) Execute(ctx context.Context, argsJSON json.RawMessage) (tool
 		return tool.NewErrorResult("file_path is required"), nil
 	}
 
-	// Security: validate and clean the path
-	cleanPath := filepath.Clean(args.FilePath)
+	cleanPath, exists, err := writablePath(ctx, args.FilePath, args.Overwrite)
+	if err != nil {
+		return tool.NewErrorResult(err.Error()), nil
+	}
+
+	dir := filepath.Dir(cleanPath)
+	if args.CreateDirs == nil || *args.CreateDirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return tool.NewErrorResult(fmt.Sprintf("failed to create directory: %v", err)), nil
+		}
+	} else if _, err := os.Stat(dir); err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("directory %s does not exist; pass create_dirs=true to create it", dir)), nil
+	}
+
+	// A missing file diffs as empty
+	previous, _ := os.ReadFile(cleanPath)
+
+	written, note, err := writeFormatted(ctx, cleanPath, []byte(args.Content))
+	if err != nil {
+		return tool.NewErrorResult(fmt.Sprintf("failed to write file: %v", err)), nil
+	}
+
+	summary := fmt.Sprintf("Created %s (%d bytes)", cleanPath, len(written))
+	if exists {
+		summary = fmt.Sprintf("Overwrote %s (%d bytes, was %d bytes)", cleanPath, len(written), len(previous))
+	}
+	result := tool.NewResult(summary + "\n" + note)
+	if diff := tool.UnifiedDiff(cleanPath, string(previous), string(written)); diff != "" {
+		result.Metadata = map[string]string{tool.MetadataDiff: diff}
+	}
+	return result, nil
+}
+
+// writablePath resolves path for a tool that writes a file. Inside a
+// project it stays within the root; system paths and shell startup files
+// are refused, and an existing file is only replaced if the session read
+// it or overwrite is set.
+func writablePath(ctx context.Context, path string, overwrite bool) (cleanPath string, exists bool, err error) {
+	path, err = tool.SandboxFromContext(ctx).Resolve(path)
+	if err != nil {
+		return "", false, err
+	}
+	cleanPath = filepath.Clean(path)
 
 	// Block dangerous paths
 	dangerousPaths := []string{"/etc/", "/usr/", "/bin/", "/sbin/", "/boot/", "/sys/", "/proc/"}
 	for _, dp := range dangerousPaths {
 		if filepath.HasPrefix(cleanPath, dp) {
-			return tool.NewErrorResult(fmt.Sprintf("writing to system path %s is not allowed", dp)), nil
+			return "", false, fmt.Errorf("writing to system path %s is not allowed", dp)
 		}
 	}
 
 	// Block hidden config files that could be dangerous
 	baseName := filepath.Base(cleanPath)
 	if baseName == ".bashrc" || baseName == ".zshrc" || baseName == ".profile" ||
-	   baseName == ".ssh" || baseName == "authorized_keys" {
-		return tool.NewErrorResult(fmt.Sprintf("writing to %s is not allowed for security", baseName)), nil
+		baseName == ".ssh" || baseName == "authorized_keys" {
+		return "", false, fmt.Errorf("writing to %s is not allowed for security", baseName)
 	}
 
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(cleanPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return tool.NewErrorResult(fmt.Sprintf("failed to create directory: %v", err)), nil
+	// Only overwrite files the model has seen, when the session tracks them
+	info, statErr := os.Stat(cleanPath)
+	exists = statErr == nil
+	if exists && info.IsDir() {
+		return "", false, fmt.Errorf("%s is a directory", cleanPath)
 	}
+	if tracker := tool.ReadTrackerFromContext(ctx); exists && !overwrite && tracker != nil && !tracker.WasRead(cleanPath) {
+		return "", false, fmt.Errorf("file %s exists and was not read in this session; read it first or pass overwrite=true", cleanPath)
+	}
+	return cleanPath, exists, nil
+}
 
-	if err := os.WriteFile(cleanPath, []byte(args.Content), 0644); err != nil {
-		return tool.NewErrorResult(fmt.Sprintf("failed to write file: %v", err)), nil
+// NeedsApproval implements tool.Approvable: writes outside the working
+// tree need approval
+func (t *WriteTool) NeedsApproval(ctx context.Context, argsJSON json.RawMessage) bool {
+	var args WriteArgs
+	return json.Unmarshal(argsJSON, &args) != nil || outsideWorkingTree(ctx, args.FilePath)
+}
+
+// outsideWorkingTree reports whether path leads outside the current
+// directory. In a sandbox Resolve already refuses such paths.
+func outsideWorkingTree(ctx context.Context, path string) bool {
+	if tool.SandboxFromContext(ctx) != nil {
+		return false
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return true
 	}
+	sb, err := tool.NewSandbox(wd)
+	return err != nil || !sb.Contains(path)
+}
 
-	return tool.NewResult(fmt.Sprintf("Successfully wrote %d bytes to %s", len(args.Content), cleanPath)), nil
+// ReportedPaths implements tool.PathReporter; the model knows what it wrote
+func (t *WriteTool) ReportedPaths(argsJSON json.RawMessage, result tool.Result) []string {
+	var args WriteArgs
+	if json.Unmarshal(argsJSON, &args) != nil || args.FilePath == "" {
+		return nil
+	}
+	return []string{filepath.Clean(args.FilePath)}
 }
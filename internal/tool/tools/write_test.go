@@ -0,0 +1,170 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+func TestWriteCreatesFileAndDirectories(t *testing.T) {
+	ctx := tool.WithReadTracker(context.Background(), tool.NewReadTracker())
+	path := filepath.Join(t.TempDir(), "a", "b", "new.txt")
+
+	result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: path, Content: "hello\n"})
+	if result.IsError || !strings.HasPrefix(result.Content, "Created "+path+" (6 bytes)") {
+		t.Fatalf("Expected the file created, got %q", result.Content)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "hello\n" {
+		t.Errorf("Expected the content written, got %q", data)
+	}
+
+	noDirs := false
+	missing := filepath.Join(t.TempDir(), "missing", "new.txt")
+	result = runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: missing, Content: "x", CreateDirs: &noDirs})
+	if !result.IsError || !strings.Contains(result.Content, "does not exist") {
+		t.Errorf("Expected create_dirs=false to fail for a missing directory, got %q", result.Content)
+	}
+	if _, err := os.Stat(filepath.Dir(missing)); !os.IsNotExist(err) {
+		t.Error("Expected the directory not created")
+	}
+}
+
+func TestWriteRefusesUnreadOverwrite(t *testing.T) {
+	ctx := tool.WithReadTracker(context.Background(), tool.NewReadTracker())
+	path := writeTestFile(t, "existing.txt", "original\n")
+
+	result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: path, Content: "replaced\n"})
+	if !result.IsError || !strings.Contains(result.Content, "was not read in this session") {
+		t.Errorf("Expected an unread overwrite refused, got %q", result.Content)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "original\n" {
+		t.Errorf("Expected the file untouched, got %q", data)
+	}
+
+	result = runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: path, Content: "replaced\n", Overwrite: true})
+	if result.IsError || !strings.HasPrefix(result.Content, "Overwrote "+path+" (9 bytes, was 9 bytes)") {
+		t.Errorf("Expected an explicit overwrite, got %q", result.Content)
+	}
+
+	// Without a tracker, e.g. a tool used outside a session, writes go through
+	path = writeTestFile(t, "untracked.txt", "original\n")
+	if result = runTool(t, context.Background(), NewWriteTool(), WriteArgs{FilePath: path, Content: "x"}); result.IsError {
+		t.Errorf("Expected a write without a tracker to succeed, got %q", result.Content)
+	}
+}
+
+func TestWriteAfterReadThroughExecutor(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(NewReadTool())
+	registry.Register(NewWriteTool())
+	executor := tool.NewExecutor(registry)
+	ctx := tool.WithReadTracker(context.Background(), tool.NewReadTracker())
+	path := writeTestFile(t, "seen.txt", "original\n")
+
+	call := func(name string, args any) tool.Result {
+		t.Helper()
+		data, _ := json.Marshal(args)
+		result, err := executor.ExecuteToolCall(ctx, client.ToolCall{
+			ID:       "call_0",
+			Function: client.FunctionCall{Name: name, Arguments: string(data)},
+		})
+		if err != nil {
+			t.Fatalf("%s failed: %v", name, err)
+		}
+		return result
+	}
+
+	if result := call("Write", WriteArgs{FilePath: path, Content: "new\n"}); !result.IsError {
+		t.Fatalf("Expected the write refused before a read, got %q", result.Content)
+	}
+	call("Read", ReadArgs{FilePath: path})
+	if result := call("Write", WriteArgs{FilePath: path, Content: "new\n"}); result.IsError {
+		t.Errorf("Expected the write allowed after a read, got %q", result.Content)
+	}
+
+	// A file the session created may be rewritten without reading it
+	created := filepath.Join(t.TempDir(), "created.txt")
+	call("Write", WriteArgs{FilePath: created, Content: "one\n"})
+	if result := call("Write", WriteArgs{FilePath: created, Content: "two\n"}); result.IsError {
+		t.Errorf("Expected a created file to be rewritable, got %q", result.Content)
+	}
+}
+
+func TestWriteAndEditStayInSandbox(t *testing.T) {
+	ctx, root, secret := sandboxContext(t)
+
+	if result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: "pkg/new.go", Content: "package pkg\n"}); result.IsError {
+		t.Fatalf("Expected a write inside the project, got %q", result.Content)
+	}
+	if _, err := os.Stat(filepath.Join(root, "pkg", "new.go")); err != nil {
+		t.Errorf("Expected the file created under the root: %v", err)
+	}
+
+	escape := filepath.Join(filepath.Dir(root), "escape.txt")
+	for _, path := range []string{"../escape.txt", escape, "link.txt"} {
+		if result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: path, Content: "x", Overwrite: true}); !result.IsError {
+			t.Errorf("Expected a write to %s refused, got %q", path, result.Content)
+		}
+	}
+	if result := runTool(t, ctx, NewEditTool(), EditArgs{FilePath: "link.txt", OldString: "token", NewString: "stolen"}); !result.IsError {
+		t.Errorf("Expected an edit through a link refused, got %q", result.Content)
+	}
+	if _, err := os.Stat(escape); err == nil {
+		t.Error("Expected nothing written outside the project")
+	}
+	if data, _ := os.ReadFile(secret); string(data) != "token\n" {
+		t.Errorf("Expected the secret untouched, got %q", data)
+	}
+}
+
+func TestSandboxedReadTracksRelativePaths(t *testing.T) {
+	ctx, root, _ := sandboxContext(t)
+	registry := tool.NewRegistry()
+	registry.Register(NewReadTool())
+	executor := tool.NewExecutor(registry)
+
+	data, _ := json.Marshal(ReadArgs{FilePath: "main.go"})
+	executor.ExecuteToolCall(ctx, client.ToolCall{ID: "call_0", Function: client.FunctionCall{Name: "Read", Arguments: string(data)}})
+
+	// Read relative to the root, so an absolute overwrite counts as informed
+	result := runTool(t, ctx, NewWriteTool(), WriteArgs{FilePath: filepath.Join(root, "main.go"), Content: "package main\n\nfunc main() {}\n"})
+	if result.IsError {
+		t.Errorf("Expected the overwrite allowed after a relative read, got %q", result.Content)
+	}
+}
+
+func TestParallelEditsOnOneFileAllApply(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(NewEditTool())
+	executor := tool.NewExecutor(registry)
+
+	var lines []string
+	for i := range 20 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	path := writeTestFile(t, "shared.txt", strings.Join(lines, "\n")+"\n")
+	var tcs []client.ToolCall
+	for i := range lines {
+		data, _ := json.Marshal(EditArgs{FilePath: path, OldString: fmt.Sprintf("line %d\n", i), NewString: fmt.Sprintf("edited %d\n", i)})
+		tcs = append(tcs, client.ToolCall{ID: fmt.Sprintf("call_%d", i), Function: client.FunctionCall{Name: "Edit", Arguments: string(data)}})
+	}
+
+	// Each call reads, changes and writes the whole file; overlapping
+	// calls would lose each other's edits
+	for i, result := range executor.ExecuteToolCallsParallel(context.Background(), tcs, 8) {
+		if result.IsError {
+			t.Errorf("Edit %d failed: %s", i, result.Content)
+		}
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "line ") {
+		t.Errorf("Expected every edit applied, got:\n%s", data)
+	}
+}
@@ -3,14 +3,29 @@ package tool
 import (
 	"context"
 	"encoding/json"
+	"time"
 )
 
 // Result represents the result of a tool execution
 type Result struct {
 	Content string `json:"content"`
 	IsError bool   `json:"is_error"`
+	// Metadata carries structured data for clients, such as MetadataDiff.
+	// It is not sent to the model.
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
+// MetadataImage is the Result.Metadata key holding an image the tool read,
+// as a base64 data URL. Clients may show it to vision models.
+const MetadataImage = "image"
+
+// MetadataImagePath is the Result.Metadata key holding the path of an image
+// the tool created, with MetadataImageThumb holding a small base64 PNG of it
+const (
+	MetadataImagePath  = "image_path"
+	MetadataImageThumb = "image_base64_thumb"
+)
+
 // Tool is the interface that all tools must implement
 type Tool interface {
 	// Name returns the tool name
@@ -26,6 +41,25 @@ type Tool interface {
 	Execute(ctx context.Context, args json.RawMessage) (Result, error)
 }
 
+// Exclusive is implemented by tools that must not run alongside other tool
+// calls, such as AskUser which waits on the user
+type Exclusive interface {
+	Exclusive() bool
+}
+
+// TimeoutHinter is implemented by tools that need a different limit than
+// the executor's default timeout, such as Bash with its own timeout argument
+type TimeoutHinter interface {
+	Timeout() time.Duration
+}
+
+// Availability is implemented by tools that can become temporarily
+// unusable, such as plugin tools whose plugin was disabled. Unavailable
+// tools are not offered to the model.
+type Availability interface {
+	Available() bool
+}
+
 // NewResult creates a successful result
 func NewResult(content string) Result {
 	return Result{
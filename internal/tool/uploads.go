@@ -0,0 +1,25 @@
+package tool
+
+import "context"
+
+// UploadFunc returns the path and original name of the file uploaded with
+// id
+type UploadFunc func(id string) (path, name string, err error)
+
+type uploadsKey struct{}
+
+// WithUploads returns a context whose tools can open files the user
+// uploaded, by upload ID
+func WithUploads(ctx context.Context, fn UploadFunc) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, uploadsKey{}, fn)
+}
+
+// UploadsFromContext returns the function set with WithUploads, or nil
+// where there are no uploads, such as in the REPL
+func UploadsFromContext(ctx context.Context) UploadFunc {
+	fn, _ := ctx.Value(uploadsKey{}).(UploadFunc)
+	return fn
+}
@@ -0,0 +1,208 @@
+package tts
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultCacheMaxBytes caps the cache directory
+const DefaultCacheMaxBytes = 100 << 20
+
+// audioExts name cached files by type, so the type survives a restart
+var audioExts = map[string]string{
+	"audio/mpeg": ".mp3",
+	"audio/wav":  ".wav",
+	"audio/ogg":  ".ogg",
+	"audio/webm": ".webm",
+	"audio/aac":  ".aac",
+	"audio/flac": ".flac",
+	"audio/mp4":  ".m4a",
+}
+
+// CacheKey identifies synthesized speech by everything that shapes it
+func CacheKey(provider string, opts Options, text string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%g\x00%s\x00%s", provider, opts.Voice, opts.Speed, opts.Model, text)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// cacheEntry is one cached file
+type cacheEntry struct {
+	key  string
+	name string // File name in the cache directory
+	size int64
+}
+
+// Cache is a content-addressed disk cache of audio, bounded in size and
+// evicting the least recently used entries first. Safe for concurrent use.
+type Cache struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // By key; values are *cacheEntry
+	lru     *list.List               // Most recently used first
+	size    int64
+}
+
+// NewCache opens the cache in dir, creating it if needed. Files from
+// earlier runs are kept, ordered by modification time.
+func NewCache(dir string, maxBytes int64) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create TTS cache: %w", err)
+	}
+	c := &Cache{dir: dir, maxBytes: maxBytes, entries: make(map[string]*list.Element), lru: list.New()}
+
+	dirEntries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read TTS cache: %w", err)
+	}
+	type found struct {
+		entry   *cacheEntry
+		modTime time.Time
+	}
+	var files []found
+	for _, de := range dirEntries {
+		key, _, ok := strings.Cut(de.Name(), ".")
+		if !ok || len(key) != sha256.Size*2 || !de.Type().IsRegular() {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, found{&cacheEntry{key: key, name: de.Name(), size: info.Size()}, info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.After(files[j].modTime) })
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range files {
+		c.entries[f.entry.key] = c.lru.PushBack(f.entry)
+		c.size += f.entry.size
+	}
+	c.evictLocked()
+	return c, nil
+}
+
+// Get returns the cached audio for key and its MIME type
+func (c *Cache) Get(key string) ([]byte, string, bool) {
+	c.mu.Lock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.mu.Unlock()
+		return nil, "", false
+	}
+	c.lru.MoveToFront(el)
+	entry := el.Value.(*cacheEntry)
+	c.mu.Unlock()
+
+	path := filepath.Join(c.dir, entry.name)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		c.remove(key)
+		return nil, "", false
+	}
+	// Keep recency across restarts
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return data, mimeForName(entry.name), true
+}
+
+// Put stores audio under key, evicting old entries to stay under the cap.
+// Audio larger than the whole cache is not stored.
+func (c *Cache) Put(key string, data []byte, mimeType string) error {
+	if int64(len(data)) > c.maxBytes {
+		return nil
+	}
+	name := key + extForMIME(mimeType)
+
+	// Write under a temporary name so readers never see a partial file
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.removeLocked(el)
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(c.dir, name)); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, name: name, size: int64(len(data))})
+	c.size += int64(len(data))
+	c.evictLocked()
+	return nil
+}
+
+// Size returns the bytes held by the cache
+func (c *Cache) Size() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.size
+}
+
+// remove forgets key and deletes its file
+func (c *Cache) remove(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		c.removeLocked(el)
+	}
+}
+
+func (c *Cache) removeLocked(el *list.Element) {
+	entry := el.Value.(*cacheEntry)
+	c.lru.Remove(el)
+	delete(c.entries, entry.key)
+	c.size -= entry.size
+	os.Remove(filepath.Join(c.dir, entry.name))
+}
+
+// evictLocked drops least recently used entries until the cache fits
+func (c *Cache) evictLocked() {
+	for c.size > c.maxBytes && c.lru.Len() > 0 {
+		c.removeLocked(c.lru.Back())
+	}
+}
+
+func extForMIME(mimeType string) string {
+	if mediaType, _, err := mime.ParseMediaType(mimeType); err == nil {
+		mimeType = mediaType
+	}
+	if ext, ok := audioExts[mimeType]; ok {
+		return ext
+	}
+	return ".bin"
+}
+
+func mimeForName(name string) string {
+	ext := filepath.Ext(name)
+	for mimeType, e := range audioExts {
+		if e == ext {
+			return mimeType
+		}
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,172 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxAudioBytes bounds a provider response
+const maxAudioBytes = 20 << 20
+
+var defaultClient = &http.Client{Timeout: 30 * time.Second}
+
+// Kokoro speaks through fal.ai's Kokoro model, which is good at Japanese
+type Kokoro struct {
+	APIKey   string
+	Endpoint string       // Defaults to the Japanese Kokoro model on fal.run
+	Client   *http.Client // Defaults to a client with a 30 second timeout
+}
+
+func (k *Kokoro) Name() string { return "kokoro" }
+
+func (k *Kokoro) Synthesize(ctx context.Context, text string, opts Options) ([]byte, string, error) {
+	voice := opts.Voice
+	if voice == "" {
+		voice = "jf_alpha"
+	}
+	speed := opts.Speed
+	if speed == 0 {
+		speed = 1.0
+	}
+	endpoint := k.Endpoint
+	if endpoint == "" {
+		endpoint = "https://fal.run/fal-ai/kokoro/japanese"
+	}
+
+	body, _ := json.Marshal(map[string]any{"prompt": text, "voice": voice, "speed": speed})
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Key "+k.APIKey)
+
+	var result struct {
+		Audio struct {
+			URL string `json:"url"`
+		} `json:"audio"`
+	}
+	client := k.Client
+	if client == nil {
+		client = defaultClient
+	}
+	if err := doJSON(client, req, &result); err != nil {
+		return nil, "", fmt.Errorf("kokoro: %w", err)
+	}
+	if result.Audio.URL == "" {
+		return nil, "", fmt.Errorf("kokoro: no audio returned")
+	}
+
+	// The audio is hosted; fetch it so it can be cached
+	req, err = http.NewRequestWithContext(ctx, "GET", result.Audio.URL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	audio, mime, err := doAudio(client, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("kokoro: %w", err)
+	}
+	return audio, mime, nil
+}
+
+// ElevenLabs speaks with ElevenLabs voices, including custom ones
+type ElevenLabs struct {
+	APIKey  string
+	Voice   string       // Default voice ID, Rachel if empty
+	BaseURL string       // Defaults to https://api.elevenlabs.io
+	Client  *http.Client // Defaults to a client with a 30 second timeout
+}
+
+func (e *ElevenLabs) Name() string { return "elevenlabs" }
+
+func (e *ElevenLabs) Synthesize(ctx context.Context, text string, opts Options) ([]byte, string, error) {
+	voice := opts.Voice
+	if voice == "" {
+		voice = e.Voice
+	}
+	if voice == "" {
+		voice = "21m00Tcm4TlvDq8ikWAM" // Rachel
+	}
+	model := opts.Model
+	if model == "" {
+		model = "eleven_multilingual_v2"
+	}
+	baseURL := e.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.elevenlabs.io"
+	}
+
+	settings := map[string]any{
+		"stability":        0.5,
+		"similarity_boost": 0.75,
+	}
+	if opts.Speed != 0 {
+		settings["speed"] = opts.Speed
+	}
+	body, _ := json.Marshal(map[string]any{
+		"text":           text,
+		"model_id":       model,
+		"voice_settings": settings,
+	})
+	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/v1/text-to-speech/"+voice, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("xi-api-key", e.APIKey)
+	req.Header.Set("Accept", "audio/mpeg")
+
+	client := e.Client
+	if client == nil {
+		client = defaultClient
+	}
+	audio, mime, err := doAudio(client, req)
+	if err != nil {
+		return nil, "", fmt.Errorf("elevenlabs: %w", err)
+	}
+	return audio, mime, nil
+}
+
+// doJSON sends req and decodes a successful JSON response into out
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("API error %d: %s", resp.StatusCode, body)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// doAudio sends req and returns the audio in a successful response
+func doAudio(client *http.Client, req *http.Request) ([]byte, string, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, "", fmt.Errorf("API error %d: %s", resp.StatusCode, body)
+	}
+	audio, err := io.ReadAll(io.LimitReader(resp.Body, maxAudioBytes+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(audio) > maxAudioBytes {
+		return nil, "", fmt.Errorf("audio larger than %d bytes", maxAudioBytes)
+	}
+	mime := resp.Header.Get("Content-Type")
+	if mime == "" || mime == "application/octet-stream" {
+		mime = http.DetectContentType(audio)
+	}
+	return audio, mime, nil
+}
@@ -0,0 +1,127 @@
+// Package tts turns text into speech through pluggable providers and keeps
+// the results in a disk cache, so repeated phrases do not hit paid APIs.
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"groq-go/internal/config"
+)
+
+// ErrUnavailable is returned when no configured provider can speak; clients
+// fall back to the browser's own speech synthesis
+var ErrUnavailable = errors.New("tts: no provider configured")
+
+// Options select the voice and delivery. Zero values take the provider's
+// defaults.
+type Options struct {
+	Voice string  `json:"voice,omitempty"`
+	Speed float64 `json:"speed,omitempty"`
+	Model string  `json:"model,omitempty"`
+}
+
+// Provider synthesizes speech
+type Provider interface {
+	// Name identifies the provider in requests and cache keys
+	Name() string
+	// Synthesize returns the audio for text and its MIME type
+	Synthesize(ctx context.Context, text string, opts Options) (audio []byte, mime string, err error)
+}
+
+// Noop is the fallback provider when none is configured
+type Noop struct{}
+
+func (Noop) Name() string { return "none" }
+
+func (Noop) Synthesize(ctx context.Context, text string, opts Options) ([]byte, string, error) {
+	return nil, "", ErrUnavailable
+}
+
+// Audio is synthesized speech
+type Audio struct {
+	Data   []byte
+	MIME   string
+	Cached bool // Served from the cache without calling the provider
+}
+
+// Service dispatches to providers by name and caches what they return
+type Service struct {
+	providers map[string]Provider
+	order     []string // Registration order; the first is the default
+	cache     *Cache   // nil disables caching
+}
+
+// NewService creates a service over providers, the first being the default.
+// Without providers every request fails with ErrUnavailable.
+func NewService(cache *Cache, providers ...Provider) *Service {
+	s := &Service{providers: make(map[string]Provider), cache: cache}
+	for _, p := range providers {
+		s.providers[p.Name()] = p
+		s.order = append(s.order, p.Name())
+	}
+	return s
+}
+
+// Providers returns the provider names, default first
+func (s *Service) Providers() []string {
+	return append([]string(nil), s.order...)
+}
+
+// Synthesize speaks text with the named provider, or the default one when
+// name is empty. Unknown or unconfigured providers yield ErrUnavailable.
+func (s *Service) Synthesize(ctx context.Context, name, text string, opts Options) (Audio, error) {
+	if name == "" {
+		if len(s.order) == 0 {
+			return Audio{}, ErrUnavailable
+		}
+		name = s.order[0]
+	}
+	p, ok := s.providers[name]
+	if !ok {
+		return Audio{}, fmt.Errorf("%w: %s", ErrUnavailable, name)
+	}
+
+	key := CacheKey(name, opts, text)
+	if s.cache != nil {
+		if data, mime, ok := s.cache.Get(key); ok {
+			return Audio{Data: data, MIME: mime, Cached: true}, nil
+		}
+	}
+
+	data, mime, err := p.Synthesize(ctx, text, opts)
+	if err != nil {
+		return Audio{}, err
+	}
+	if s.cache != nil {
+		// A failed write only costs a later cache miss
+		s.cache.Put(key, data, mime)
+	}
+	return Audio{Data: data, MIME: mime}, nil
+}
+
+// NewProviders returns the providers with API keys in cfg, ElevenLabs
+// before Kokoro on fal.ai, or Noop when there are none
+func NewProviders(cfg config.TTSConfig) []Provider {
+	var providers []Provider
+	if cfg.ElevenLabsKey != "" {
+		providers = append(providers, &ElevenLabs{APIKey: cfg.ElevenLabsKey, Voice: cfg.ElevenLabsVoice})
+	}
+	if cfg.FalKey != "" {
+		providers = append(providers, &Kokoro{APIKey: cfg.FalKey})
+	}
+	if len(providers) == 0 {
+		providers = append(providers, Noop{})
+	}
+	return providers
+}
+
+// CacheMaxBytes returns the configured cache size in bytes, or
+// DefaultCacheMaxBytes when unset
+func CacheMaxBytes(cfg config.TTSConfig) int64 {
+	if cfg.CacheMaxMB > 0 {
+		return int64(cfg.CacheMaxMB) << 20
+	}
+	return DefaultCacheMaxBytes
+}
@@ -0,0 +1,174 @@
+package tts
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// stubProvider returns canned audio and counts calls
+type stubProvider struct {
+	name  string
+	calls atomic.Int32
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) Synthesize(ctx context.Context, text string, opts Options) ([]byte, string, error) {
+	p.calls.Add(1)
+	return []byte(fmt.Sprintf("%s:%s:%s", p.name, opts.Voice, text)), "audio/mpeg", nil
+}
+
+func TestServiceCachesByProviderVoiceAndText(t *testing.T) {
+	cache, err := NewCache(t.TempDir(), DefaultCacheMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, second := &stubProvider{name: "first"}, &stubProvider{name: "second"}
+	svc := NewService(cache, first, second)
+
+	audio, err := svc.Synthesize(context.Background(), "", "hello", Options{})
+	if err != nil || string(audio.Data) != "first::hello" || audio.Cached {
+		t.Fatalf("Expected the default provider, got %q cached=%v (%v)", audio.Data, audio.Cached, err)
+	}
+	audio, _ = svc.Synthesize(context.Background(), "first", "hello", Options{})
+	if !audio.Cached || audio.MIME != "audio/mpeg" || string(audio.Data) != "first::hello" || first.calls.Load() != 1 {
+		t.Errorf("Expected a cache hit, got cached=%v %q after %d calls", audio.Cached, audio.MIME, first.calls.Load())
+	}
+
+	// Anything that changes the audio misses
+	svc.Synthesize(context.Background(), "first", "hello", Options{Voice: "v2"})
+	svc.Synthesize(context.Background(), "first", "hello!", Options{})
+	svc.Synthesize(context.Background(), "second", "hello", Options{})
+	if first.calls.Load() != 3 || second.calls.Load() != 1 {
+		t.Errorf("Expected separate entries, got %d and %d calls", first.calls.Load(), second.calls.Load())
+	}
+
+	if _, err := svc.Synthesize(context.Background(), "missing", "hello", Options{}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable for an unknown provider, got %v", err)
+	}
+	if _, err := NewService(nil, Noop{}).Synthesize(context.Background(), "", "hello", Options{}); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Expected ErrUnavailable from Noop, got %v", err)
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	dir := t.TempDir()
+	cache, err := NewCache(dir, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunk := []byte(strings.Repeat("x", 40))
+	cache.Put("a", chunk, "audio/mpeg")
+	cache.Put("b", chunk, "audio/mpeg")
+	cache.Get("a") // b is now the oldest
+	cache.Put("c", chunk, "audio/wav")
+
+	if _, _, ok := cache.Get("b"); ok {
+		t.Error("Expected b evicted")
+	}
+	if _, _, ok := cache.Get("a"); !ok {
+		t.Error("Expected a kept after its recent use")
+	}
+	if _, mime, ok := cache.Get("c"); !ok || mime != "audio/wav" {
+		t.Errorf("Expected c kept as audio/wav, got %q", mime)
+	}
+
+	var total int64
+	entries, _ := os.ReadDir(dir)
+	for _, e := range entries {
+		info, _ := e.Info()
+		total += info.Size()
+	}
+	if total > 100 || cache.Size() != 80 || len(entries) != 2 {
+		t.Errorf("Expected 2 files within the cap, got %d files, %d bytes on disk, %d counted", len(entries), total, cache.Size())
+	}
+
+	// Larger than the whole cache: not stored, nothing evicted
+	cache.Put("huge", []byte(strings.Repeat("x", 101)), "audio/mpeg")
+	if _, _, ok := cache.Get("huge"); ok || cache.Size() != 80 {
+		t.Errorf("Expected oversized audio skipped, size %d", cache.Size())
+	}
+}
+
+func TestCacheSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	key := CacheKey("kokoro", Options{Voice: "jf_alpha"}, "こんにちは")
+	cache, _ := NewCache(dir, DefaultCacheMaxBytes)
+	cache.Put(key, []byte("audio"), "audio/mpeg; charset=binary")
+
+	reopened, err := NewCache(dir, DefaultCacheMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, mime, ok := reopened.Get(key); !ok || string(data) != "audio" || mime != "audio/mpeg" {
+		t.Errorf("Expected the entry back after a restart, got %q %q %v", data, mime, ok)
+	}
+
+	// A smaller cap trims the directory on open
+	os.WriteFile(dir+"/"+CacheKey("x", Options{}, "y")+".mp3", []byte(strings.Repeat("x", 10)), 0644)
+	if trimmed, _ := NewCache(dir, 12); trimmed.Size() > 12 {
+		t.Errorf("Expected the cache trimmed to 12 bytes, got %d", trimmed.Size())
+	}
+}
+
+func TestKokoroAndElevenLabs(t *testing.T) {
+	var kokoroReq, elevenReq map[string]any
+	var elevenPath string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/kokoro":
+			if r.Header.Get("Authorization") != "Key fal-key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			json.NewDecoder(r.Body).Decode(&kokoroReq)
+			json.NewEncoder(w).Encode(map[string]any{"audio": map[string]string{"url": "http://" + r.Host + "/files/out.wav"}})
+		case r.URL.Path == "/files/out.wav":
+			w.Header().Set("Content-Type", "audio/wav")
+			io.WriteString(w, "RIFF-wav")
+		case strings.HasPrefix(r.URL.Path, "/v1/text-to-speech/"):
+			if r.Header.Get("xi-api-key") != "el-key" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			elevenPath = r.URL.Path
+			json.NewDecoder(r.Body).Decode(&elevenReq)
+			w.Header().Set("Content-Type", "audio/mpeg")
+			io.WriteString(w, "ID3-mp3")
+		}
+	}))
+	defer srv.Close()
+
+	kokoro := &Kokoro{APIKey: "fal-key", Endpoint: srv.URL + "/kokoro"}
+	audio, mime, err := kokoro.Synthesize(context.Background(), "こんにちは", Options{})
+	if err != nil || string(audio) != "RIFF-wav" || mime != "audio/wav" {
+		t.Fatalf("Unexpected Kokoro result %q %q (%v)", audio, mime, err)
+	}
+	if kokoroReq["voice"] != "jf_alpha" || kokoroReq["speed"] != 1.0 || kokoroReq["prompt"] != "こんにちは" {
+		t.Errorf("Unexpected Kokoro request %v", kokoroReq)
+	}
+
+	eleven := &ElevenLabs{APIKey: "el-key", Voice: "custom", BaseURL: srv.URL}
+	audio, mime, err = eleven.Synthesize(context.Background(), "hello", Options{Speed: 1.1})
+	if err != nil || string(audio) != "ID3-mp3" || mime != "audio/mpeg" {
+		t.Fatalf("Unexpected ElevenLabs result %q %q (%v)", audio, mime, err)
+	}
+	settings, _ := elevenReq["voice_settings"].(map[string]any)
+	if elevenPath != "/v1/text-to-speech/custom" || elevenReq["model_id"] != "eleven_multilingual_v2" || settings["speed"] != 1.1 {
+		t.Errorf("Unexpected ElevenLabs request %s %v", elevenPath, elevenReq)
+	}
+
+	bad := &ElevenLabs{APIKey: "wrong", BaseURL: srv.URL}
+	if _, _, err := bad.Synthesize(context.Background(), "hello", Options{}); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Expected the API error, got %v", err)
+	}
+}
@@ -0,0 +1,433 @@
+package upload
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/logging"
+)
+
+var log = logging.WithComponent("upload")
+
+// Defaults for chunked uploads
+const (
+	DefaultChunkSize     = 4 << 20   // 4MB per chunk
+	DefaultMaxFileSize   = 512 << 20 // 512MB per file
+	DefaultMaxConcurrent = 3         // In-progress uploads per user
+	DefaultMaxUserBytes  = 1 << 30   // Total bytes of a user's uploads, in progress or completed
+
+	// PartialDirName holds incomplete uploads inside the uploads directory
+	PartialDirName = ".partial"
+
+	// completedFile records who completed which upload, in PartialDirName
+	completedFile = "completed.json"
+)
+
+var (
+	ErrNotFound         = errors.New("upload not found")
+	ErrQuotaExceeded    = errors.New("upload quota exceeded")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrInvalidChunk     = errors.New("invalid chunk")
+	ErrIncomplete       = errors.New("upload incomplete")
+)
+
+// Session describes an in-progress chunked upload
+type Session struct {
+	ID          string    `json:"id"`
+	UserID      string    `json:"user_id"`
+	Name        string    `json:"name"`
+	Size        int64     `json:"size"`
+	SHA256      string    `json:"sha256"`
+	ChunkSize   int64     `json:"chunk_size"`
+	TotalChunks int       `json:"total_chunks"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+
+	// busy is held shared while chunks are stored, and exclusively to
+	// complete or abort the upload
+	busy sync.RWMutex
+}
+
+// chunkLen returns the expected length of chunk n
+func (s *Session) chunkLen(n int) int64 {
+	if n == s.TotalChunks-1 {
+		return s.Size - int64(n)*s.ChunkSize
+	}
+	return s.ChunkSize
+}
+
+// Manager handles chunked uploads, streaming every chunk straight to disk
+type Manager struct {
+	dir           string // Final uploads directory
+	partialDir    string // Incomplete uploads, one directory per session
+	ChunkSize     int64
+	MaxFileSize   int64
+	MaxConcurrent int
+	MaxUserBytes  int64
+
+	mu        sync.Mutex
+	sessions  map[string]*Session
+	completed map[string]string // Final path -> user ID, counted in MaxUserBytes
+}
+
+// NewManager creates a chunked upload manager storing files in dir
+func NewManager(dir string) (*Manager, error) {
+	partialDir := filepath.Join(dir, PartialDirName)
+	if err := os.MkdirAll(partialDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %w", err)
+	}
+
+	m := &Manager{
+		dir:           dir,
+		partialDir:    partialDir,
+		ChunkSize:     DefaultChunkSize,
+		MaxFileSize:   DefaultMaxFileSize,
+		MaxConcurrent: DefaultMaxConcurrent,
+		MaxUserBytes:  DefaultMaxUserBytes,
+		sessions:      make(map[string]*Session),
+		completed:     make(map[string]string),
+	}
+	if data, err := os.ReadFile(filepath.Join(partialDir, completedFile)); err == nil {
+		json.Unmarshal(data, &m.completed)
+	}
+
+	// Reload incomplete uploads so they can resume after a restart
+	entries, _ := os.ReadDir(partialDir)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(partialDir, entry.Name(), "meta.json"))
+		if err != nil {
+			continue
+		}
+		var s Session
+		if err := json.Unmarshal(data, &s); err != nil || s.ID != entry.Name() {
+			continue
+		}
+		m.sessions[s.ID] = &s
+	}
+
+	return m, nil
+}
+
+// Init starts a new upload after checking the user's quotas
+func (m *Manager) Init(userID, name string, size int64, sha string) (*Session, error) {
+	name = filepath.Base(strings.TrimSpace(name))
+	if name == "." || name == "/" || name == "" || strings.HasPrefix(name, ".") {
+		return nil, fmt.Errorf("invalid file name")
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("size must be positive")
+	}
+	if size > m.MaxFileSize {
+		return nil, fmt.Errorf("%w: file exceeds %d bytes", ErrQuotaExceeded, m.MaxFileSize)
+	}
+	sha = strings.ToLower(sha)
+	if !isSHA256(sha) {
+		return nil, fmt.Errorf("sha256 must be a hex-encoded SHA-256 digest")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	var pending int64
+	for _, s := range m.sessions {
+		if s.UserID == userID {
+			count++
+			pending += s.Size
+		}
+	}
+	if count >= m.MaxConcurrent {
+		return nil, fmt.Errorf("%w: %d uploads already in progress", ErrQuotaExceeded, count)
+	}
+	if pending+m.completedBytesLocked(userID)+size > m.MaxUserBytes {
+		return nil, fmt.Errorf("%w: uploads would exceed %d bytes", ErrQuotaExceeded, m.MaxUserBytes)
+	}
+
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	s := &Session{
+		ID:          id,
+		UserID:      userID,
+		Name:        name,
+		Size:        size,
+		SHA256:      sha,
+		ChunkSize:   m.ChunkSize,
+		TotalChunks: int((size + m.ChunkSize - 1) / m.ChunkSize),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := os.MkdirAll(m.sessionDir(id), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create upload: %w", err)
+	}
+	if err := m.saveSession(s); err != nil {
+		os.RemoveAll(m.sessionDir(id))
+		return nil, err
+	}
+	m.sessions[id] = s
+
+	return s, nil
+}
+
+// WriteChunk streams chunk n from r to disk and verifies its SHA-256.
+// Re-sending a chunk replaces it, so interrupted chunks can be retried.
+func (m *Manager) WriteChunk(id, userID string, n int, sha string, r io.Reader) error {
+	s, err := m.get(id, userID)
+	if err != nil {
+		return err
+	}
+	s.busy.RLock()
+	defer s.busy.RUnlock()
+	if !m.active(s) {
+		return ErrNotFound
+	}
+	if n < 0 || n >= s.TotalChunks {
+		return fmt.Errorf("%w: chunk %d out of range (0-%d)", ErrInvalidChunk, n, s.TotalChunks-1)
+	}
+	sha = strings.ToLower(sha)
+	if !isSHA256(sha) {
+		return fmt.Errorf("%w: missing or malformed chunk sha256", ErrInvalidChunk)
+	}
+
+	tmp, err := os.CreateTemp(m.sessionDir(id), "chunk-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create chunk: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	want := s.chunkLen(n)
+	h := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, h), io.LimitReader(r, want+1))
+	closeErr := tmp.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+	if closeErr != nil {
+		return fmt.Errorf("failed to write chunk: %w", closeErr)
+	}
+	if written != want {
+		return fmt.Errorf("%w: chunk %d is %d bytes, expected %d", ErrInvalidChunk, n, written, want)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != sha {
+		return fmt.Errorf("%w: chunk %d", ErrChecksumMismatch, n)
+	}
+
+	if err := os.Rename(tmp.Name(), m.chunkPath(id, n)); err != nil {
+		return fmt.Errorf("failed to store chunk: %w", err)
+	}
+
+	m.mu.Lock()
+	s.UpdatedAt = time.Now()
+	err = m.saveSession(s)
+	m.mu.Unlock()
+	return err
+}
+
+// Status returns the session and the sorted indexes of received chunks
+func (m *Manager) Status(id, userID string) (*Session, []int, error) {
+	s, err := m.get(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return s, m.receivedChunks(s), nil
+}
+
+// Complete assembles all chunks, verifies the total SHA-256 and moves the
+// file into the uploads directory, returning its final path. Concurrent
+// calls for one upload complete it once; the others get ErrNotFound.
+func (m *Manager) Complete(id, userID string) (string, error) {
+	s, err := m.get(id, userID)
+	if err != nil {
+		return "", err
+	}
+	s.busy.Lock()
+	defer s.busy.Unlock()
+	if !m.active(s) {
+		return "", ErrNotFound
+	}
+
+	received := m.receivedChunks(s)
+	if len(received) != s.TotalChunks {
+		return "", fmt.Errorf("%w: %d of %d chunks received", ErrIncomplete, len(received), s.TotalChunks)
+	}
+
+	out, err := os.CreateTemp(m.sessionDir(id), "assemble-*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	defer os.Remove(out.Name())
+
+	h := sha256.New()
+	w := io.MultiWriter(out, h)
+	for n := 0; n < s.TotalChunks; n++ {
+		f, err := os.Open(m.chunkPath(id, n))
+		if err != nil {
+			out.Close()
+			return "", fmt.Errorf("failed to read chunk %d: %w", n, err)
+		}
+		_, err = io.Copy(w, f)
+		f.Close()
+		if err != nil {
+			out.Close()
+			return "", fmt.Errorf("failed to assemble chunk %d: %w", n, err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != s.SHA256 {
+		return "", fmt.Errorf("%w: file hash does not match", ErrChecksumMismatch)
+	}
+
+	finalPath := filepath.Join(m.dir, s.ID+"_"+s.Name)
+	if err := os.Rename(out.Name(), finalPath); err != nil {
+		return "", fmt.Errorf("failed to finalize upload: %w", err)
+	}
+
+	m.mu.Lock()
+	delete(m.sessions, id)
+	m.completed[finalPath] = s.UserID
+	err = m.saveCompletedLocked()
+	m.mu.Unlock()
+	os.RemoveAll(m.sessionDir(id))
+	if err != nil {
+		log.Warn("Failed to record completed upload", "id", id, "error", err)
+	}
+
+	return finalPath, nil
+}
+
+// Abort discards an in-progress upload
+func (m *Manager) Abort(id, userID string) error {
+	s, err := m.get(id, userID)
+	if err != nil {
+		return err
+	}
+	s.busy.Lock()
+	defer s.busy.Unlock()
+	if !m.active(s) {
+		return ErrNotFound
+	}
+	m.mu.Lock()
+	delete(m.sessions, id)
+	m.mu.Unlock()
+	return os.RemoveAll(m.sessionDir(id))
+}
+
+// active reports whether s is still in progress, once its lock is held
+func (m *Manager) active(s *Session) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.sessions[s.ID] == s
+}
+
+// completedBytesLocked returns the size of the user's completed uploads
+// still on disk, forgetting those that were removed. The caller holds m.mu.
+func (m *Manager) completedBytesLocked(userID string) int64 {
+	var total int64
+	for path, owner := range m.completed {
+		info, err := os.Stat(path)
+		if err != nil {
+			delete(m.completed, path)
+			continue
+		}
+		if owner == userID {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// saveCompletedLocked writes the completed uploads record. The caller
+// holds m.mu.
+func (m *Manager) saveCompletedLocked() error {
+	data, err := json.Marshal(m.completed)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(m.partialDir, completedFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// get returns a session owned by userID
+func (m *Manager) get(id, userID string) (*Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || s.UserID != userID {
+		return nil, ErrNotFound
+	}
+	// The janitor may have removed a stale upload behind our back
+	if _, err := os.Stat(filepath.Join(m.sessionDir(id), "meta.json")); err != nil {
+		delete(m.sessions, id)
+		return nil, ErrNotFound
+	}
+	return s, nil
+}
+
+func (m *Manager) receivedChunks(s *Session) []int {
+	var received []int
+	for n := 0; n < s.TotalChunks; n++ {
+		if info, err := os.Stat(m.chunkPath(s.ID, n)); err == nil && info.Size() == s.chunkLen(n) {
+			received = append(received, n)
+		}
+	}
+	sort.Ints(received)
+	return received
+}
+
+func (m *Manager) saveSession(s *Session) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal upload: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.sessionDir(s.ID), "meta.json"), data, 0644); err != nil {
+		return fmt.Errorf("failed to save upload: %w", err)
+	}
+	return nil
+}
+
+func (m *Manager) sessionDir(id string) string {
+	return filepath.Join(m.partialDir, id)
+}
+
+func (m *Manager) chunkPath(id string, n int) string {
+	return filepath.Join(m.sessionDir(id), fmt.Sprintf("%06d.part", n))
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func isSHA256(s string) bool {
+	if len(s) != 64 {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
@@ -0,0 +1,217 @@
+package upload
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+)
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestManager(t *testing.T) *Manager {
+	m, err := NewManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	m.ChunkSize = 4
+	return m
+}
+
+func TestChunkedUploadRoundTrip(t *testing.T) {
+	m := newTestManager(t)
+	data := []byte("hello chunked world")
+
+	s, err := m.Init("alice", "notes.txt", int64(len(data)), digest(data))
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if s.TotalChunks != 5 {
+		t.Fatalf("Expected 5 chunks, got %d", s.TotalChunks)
+	}
+
+	// Upload out of order, skipping one to simulate an interruption
+	for _, n := range []int{4, 0, 2, 3} {
+		chunk := data[n*4 : min((n+1)*4, len(data))]
+		if err := m.WriteChunk(s.ID, "alice", n, digest(chunk), bytes.NewReader(chunk)); err != nil {
+			t.Fatalf("WriteChunk %d failed: %v", n, err)
+		}
+	}
+
+	if _, err := m.Complete(s.ID, "alice"); !errors.Is(err, ErrIncomplete) {
+		t.Errorf("Expected ErrIncomplete, got %v", err)
+	}
+
+	// Resume from a fresh manager, as after a restart
+	m2, err := NewManager(m.dir)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	_, received, err := m2.Status(s.ID, "alice")
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if len(received) != 4 {
+		t.Errorf("Expected 4 received chunks, got %v", received)
+	}
+
+	chunk := data[4:8]
+	if err := m2.WriteChunk(s.ID, "alice", 1, digest(chunk), bytes.NewReader(chunk)); err != nil {
+		t.Fatalf("WriteChunk 1 failed: %v", err)
+	}
+
+	path, err := m2.Complete(s.ID, "alice")
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read final file: %v", err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Errorf("Expected %q, got %q", data, got)
+	}
+	if _, _, err := m2.Status(s.ID, "alice"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected completed upload to be gone, got %v", err)
+	}
+}
+
+func TestChunkChecksumMismatch(t *testing.T) {
+	m := newTestManager(t)
+	data := []byte("abcdefgh")
+
+	s, err := m.Init("alice", "data.bin", int64(len(data)), digest(data))
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	err = m.WriteChunk(s.ID, "alice", 0, digest([]byte("zzzz")), bytes.NewReader(data[:4]))
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("Expected ErrChecksumMismatch, got %v", err)
+	}
+
+	err = m.WriteChunk(s.ID, "alice", 0, digest(data[:5]), bytes.NewReader(data[:5]))
+	if !errors.Is(err, ErrInvalidChunk) {
+		t.Errorf("Expected ErrInvalidChunk for oversized chunk, got %v", err)
+	}
+
+	if err := m.WriteChunk(s.ID, "bob", 0, digest(data[:4]), bytes.NewReader(data[:4])); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected other users to get ErrNotFound, got %v", err)
+	}
+}
+
+func TestUploadQuotas(t *testing.T) {
+	m := newTestManager(t)
+	m.MaxConcurrent = 2
+	m.MaxUserBytes = 100
+	sum := digest([]byte("x"))
+
+	if _, err := m.Init("alice", "a", 10, sum); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := m.Init("alice", "b", 95, sum); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected size quota error, got %v", err)
+	}
+	if _, err := m.Init("alice", "b", 10, sum); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	if _, err := m.Init("alice", "c", 10, sum); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected concurrency quota error, got %v", err)
+	}
+	if _, err := m.Init("bob", "c", 10, sum); err != nil {
+		t.Errorf("Quota should be per user: %v", err)
+	}
+	if _, err := m.Init("bob", "../../etc/passwd", 10, sum); err != nil {
+		t.Errorf("Expected path to be reduced to its base name: %v", err)
+	}
+}
+
+// uploadAll starts an upload of data and sends every chunk
+func uploadAll(t *testing.T, m *Manager, userID, name string, data []byte) *Session {
+	t.Helper()
+	s, err := m.Init(userID, name, int64(len(data)), digest(data))
+	if err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+	for n := 0; n < s.TotalChunks; n++ {
+		chunk := data[n*4 : min((n+1)*4, len(data))]
+		if err := m.WriteChunk(s.ID, userID, n, digest(chunk), bytes.NewReader(chunk)); err != nil {
+			t.Fatalf("WriteChunk %d failed: %v", n, err)
+		}
+	}
+	return s
+}
+
+func TestCompleteConcurrently(t *testing.T) {
+	m := newTestManager(t)
+	data := bytes.Repeat([]byte("concurrent "), 50)
+	s := uploadAll(t, m, "alice", "big.txt", data)
+
+	var wg sync.WaitGroup
+	paths := make([]string, 4)
+	errs := make([]error, 4)
+	for i := range paths {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			paths[i], errs[i] = m.Complete(s.ID, "alice")
+		}(i)
+	}
+	wg.Wait()
+
+	completed := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			completed++
+			if got, _ := os.ReadFile(paths[i]); !bytes.Equal(got, data) {
+				t.Errorf("Expected the assembled file, got %d bytes", len(got))
+			}
+		case !errors.Is(err, ErrNotFound):
+			t.Errorf("Expected ErrNotFound for a repeated Complete, got %v", err)
+		}
+	}
+	if completed != 1 {
+		t.Errorf("Expected the upload completed once, got %d", completed)
+	}
+}
+
+func TestQuotaCountsCompletedUploads(t *testing.T) {
+	m := newTestManager(t)
+	m.MaxUserBytes = 30
+	data := []byte("twenty bytes of data")
+	path, err := m.Complete(uploadAll(t, m, "alice", "a.txt", data).ID, "alice")
+	if err != nil {
+		t.Fatalf("Complete failed: %v", err)
+	}
+
+	sum := digest([]byte("x"))
+	if _, err := m.Init("alice", "b", 15, sum); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected completed bytes to count against the quota, got %v", err)
+	}
+	if _, err := m.Init("bob", "b", 15, sum); err != nil {
+		t.Errorf("Quota should be per user: %v", err)
+	}
+
+	// The record survives a restart
+	m2, err := NewManager(m.dir)
+	if err != nil {
+		t.Fatalf("NewManager failed: %v", err)
+	}
+	m2.MaxUserBytes = 30
+	if _, err := m2.Init("alice", "b", 15, sum); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected completed bytes counted after a restart, got %v", err)
+	}
+
+	// Removed files, e.g. by the janitor, free their quota
+	os.Remove(path)
+	if _, err := m2.Init("alice", "b", 15, sum); err != nil {
+		t.Errorf("Expected the quota freed once the file is gone: %v", err)
+	}
+}
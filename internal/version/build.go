@@ -7,10 +7,13 @@ import (
 	"os/exec"
 	"strings"
 	"time"
+
+	"groq-go/internal/notify"
+	"groq-go/internal/selfimprove"
 )
 
-// BuildVersion compiles the version's binary
-func (m *Manager) BuildVersion(ctx context.Context, id string) error {
+// BuildVersion compiles the version's binary, reporting checkpoints to progress (may be nil)
+func (m *Manager) BuildVersion(ctx context.Context, id string, progress selfimprove.ProgressFunc) error {
 	m.mu.Lock()
 	v, ok := m.versions[id]
 	if !ok {
@@ -18,6 +21,11 @@ func (m *Manager) BuildVersion(ctx context.Context, id string) error {
 		return fmt.Errorf("version %s not found", id)
 	}
 
+	if m.building[id] {
+		m.mu.Unlock()
+		return selfimprove.ErrAlreadyBuilding
+	}
+
 	if !v.CanBuild() && v.Status != StatusReady {
 		m.mu.Unlock()
 		return fmt.Errorf("version cannot be built (status: %s)", v.Status)
@@ -25,19 +33,23 @@ func (m *Manager) BuildVersion(ctx context.Context, id string) error {
 
 	v.Status = StatusBuilding
 	v.Error = ""
+	m.building[id] = true
 	m.storage.Save(v)
 	m.mu.Unlock()
 
 	// Do the build without holding the lock
-	err := m.doBuild(ctx, v)
+	started := time.Now()
+	err := m.doBuild(ctx, v, progress)
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	delete(m.building, id)
 
 	if err != nil {
 		v.Status = StatusFailed
 		v.Error = err.Error()
 		m.storage.Save(v)
+		m.notify(notify.VersionBuildFailed, v, fmt.Sprintf("Build of version %s failed", v.Name), err, started)
 		return err
 	}
 
@@ -47,34 +59,33 @@ func (m *Manager) BuildVersion(ctx context.Context, id string) error {
 
 	// Update commit hash after build
 	if m.selfimprove != nil {
-		v.CommitHash = m.getCurrentCommit(ctx)
+		v.CommitHash = m.branchCommit(ctx, v.Branch)
 	}
+	m.notify(notify.VersionBuilt, v, fmt.Sprintf("Version %s built in %s", v.Name, time.Since(started).Round(time.Second)), nil, started)
 
 	return m.storage.Save(v)
 }
 
-func (m *Manager) doBuild(ctx context.Context, v *AgentVersion) error {
-	repoDir := m.GetRepoDir()
-	if repoDir == "" {
+func (m *Manager) doBuild(ctx context.Context, v *AgentVersion, progress selfimprove.ProgressFunc) error {
+	if m.GetRepoDir() == "" {
 		return fmt.Errorf("repo not initialized")
 	}
 
-	// Checkout the version's branch
-	if err := runGit(ctx, repoDir, "checkout", v.Branch); err != nil {
-		return fmt.Errorf("failed to checkout branch %s: %w", v.Branch, err)
-	}
-
-	// Build the binary
-	cmd := exec.CommandContext(ctx, "go", "build", "-o", v.BinaryPath, ".")
-	cmd.Dir = repoDir
-	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
-
-	output, err := cmd.CombinedOutput()
+	// Build on the version's branch while holding the repo lock, so
+	// self-improve writes never land on it
+	progress.Report(selfimprove.StageCheckout, v.Branch)
+	err := m.selfimprove.OnBranch(ctx, v.Branch, func(repoDir string) error {
+		if err := selfimprove.GoBuild(ctx, repoDir, v.BinaryPath, []string{"CGO_ENABLED=0"}, progress); err != nil {
+			return fmt.Errorf("build failed: %w", err)
+		}
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("build failed: %s - %w", string(output), err)
+		return err
 	}
 
 	// Verify binary exists and is executable
+	progress.Report(selfimprove.StageVerify, v.BinaryPath)
 	info, err := os.Stat(v.BinaryPath)
 	if err != nil {
 		return fmt.Errorf("binary not created: %w", err)
@@ -104,20 +115,11 @@ func (m *Manager) RebuildVersion(ctx context.Context, id string) error {
 	}
 	m.mu.Unlock()
 
-	return m.BuildVersion(ctx, id)
+	return m.BuildVersion(ctx, id, nil)
 }
 
 // Helper functions for git operations
 
-func runGit(ctx context.Context, dir string, args ...string) error {
-	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("git %s failed: %s - %w", args[0], string(output), err)
-	}
-	return nil
-}
-
 func runGitOutput(ctx context.Context, dir string, args ...string) (string, error) {
 	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
 	output, err := cmd.Output()
@@ -126,8 +128,3 @@ func runGitOutput(ctx context.Context, dir string, args ...string) (string, erro
 	}
 	return strings.TrimSpace(string(output)), nil
 }
-
-// RunGitCmd is an exported version of runGit for use by other packages
-func RunGitCmd(ctx context.Context, dir string, args ...string) error {
-	return runGit(ctx, dir, args...)
-}
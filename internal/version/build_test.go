@@ -0,0 +1,175 @@
+package version
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/selfimprove"
+)
+
+// newTestManager sets up a self-improve repo holding a buildable module
+// under a temporary home directory
+func newTestManager(t *testing.T) (*Manager, *selfimprove.Manager) {
+	t.Helper()
+	for _, bin := range []string{"git", "go"} {
+		if _, err := exec.LookPath(bin); err != nil {
+			t.Skip(bin + " not available")
+		}
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	repo := filepath.Join(home, ".groq-go-repo")
+	os.MkdirAll(repo, 0755)
+	os.WriteFile(filepath.Join(repo, "go.mod"), []byte("module example.com/agent\n\ngo 1.21\n"), 0644)
+	os.WriteFile(filepath.Join(repo, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0644)
+	for _, args := range [][]string{
+		{"init", "-b", "main"},
+		{"config", "user.name", "test"},
+		{"config", "user.email", "test@example.com"},
+		{"add", "-A"},
+		{"commit", "-m", "initial"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", repo}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+
+	sim, err := selfimprove.NewManager(config.SelfImproveConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage, err := NewStorage(filepath.Join(home, "versions"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Manager{
+		baseDir:     filepath.Join(home, "versions"),
+		versions:    make(map[string]*AgentVersion),
+		selfimprove: sim,
+		storage:     storage,
+		building:    make(map[string]bool),
+	}, sim
+}
+
+func gitFiles(t *testing.T, repo, ref string) string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", repo, "ls-tree", "-r", "--name-only", ref).Output()
+	if err != nil {
+		t.Fatalf("git ls-tree %s: %v", ref, err)
+	}
+	return string(out)
+}
+
+// TestBuildAndSelfImproveWritesInterleave builds a version while
+// self-improve writes and commits, and checks every write lands on main
+func TestBuildAndSelfImproveWritesInterleave(t *testing.T) {
+	m, sim := newTestManager(t)
+	ctx := context.Background()
+
+	v, err := m.CreateVersion(ctx, "experiment", "")
+	if err != nil {
+		t.Fatalf("CreateVersion failed: %v", err)
+	}
+	if branch, _ := sim.CurrentBranch(ctx); branch != "main" {
+		t.Fatalf("Expected CreateVersion to leave main checked out, got %s", branch)
+	}
+	if _, err := sim.ApplyToBranch(ctx, v.Branch, "version.txt", "experiment", "Version change"); err != nil {
+		t.Fatalf("ApplyToBranch failed: %v", err)
+	}
+
+	const writes = 8
+	var wg sync.WaitGroup
+	builds := 0
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 3; i++ {
+			err := m.BuildVersion(ctx, v.ID, nil)
+			for errors.Is(err, selfimprove.ErrDirtyWorktree) {
+				// A write is waiting to be committed; try again
+				time.Sleep(5 * time.Millisecond)
+				err = m.BuildVersion(ctx, v.ID, nil)
+			}
+			if err != nil {
+				t.Errorf("BuildVersion failed: %v", err)
+				return
+			}
+			builds++
+			m.mu.Lock()
+			v.Status = StatusPending
+			m.mu.Unlock()
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < writes; i++ {
+			branch, err := sim.WriteFile(ctx, fmt.Sprintf("notes/%d.txt", i), "note")
+			if err != nil {
+				t.Errorf("WriteFile failed: %v", err)
+				return
+			}
+			if branch != "main" {
+				t.Errorf("Write %d went to %s", i, branch)
+			}
+			commit, err := sim.Commit(ctx, fmt.Sprintf("Add note %d", i))
+			if err != nil {
+				t.Errorf("Commit failed: %v", err)
+				return
+			}
+			if commit.Branch != "main" {
+				t.Errorf("Commit %d went to %s", i, commit.Branch)
+			}
+		}
+	}()
+	wg.Wait()
+
+	if builds != 3 {
+		t.Errorf("Expected 3 builds, got %d", builds)
+	}
+	repo := sim.GetRepoDir()
+	mainFiles := gitFiles(t, repo, "main")
+	if n := strings.Count(mainFiles, "notes/"); n != writes {
+		t.Errorf("Expected %d notes on main, got %d:\n%s", writes, n, mainFiles)
+	}
+	if strings.Contains(mainFiles, "version.txt") {
+		t.Error("Version change leaked onto main")
+	}
+	versionFiles := gitFiles(t, repo, v.Branch)
+	if strings.Contains(versionFiles, "notes/") || !strings.Contains(versionFiles, "version.txt") {
+		t.Errorf("Unexpected files on %s:\n%s", v.Branch, versionFiles)
+	}
+	if branch, _ := sim.CurrentBranch(ctx); branch != "main" {
+		t.Errorf("Expected main checked out afterwards, got %s", branch)
+	}
+}
+
+func TestSelfImproveRefusesWrongBranch(t *testing.T) {
+	m, sim := newTestManager(t)
+	ctx := context.Background()
+
+	v, err := m.CreateVersion(ctx, "experiment", "")
+	if err != nil {
+		t.Fatalf("CreateVersion failed: %v", err)
+	}
+	// Something outside the lock switched branches
+	exec.Command("git", "-C", sim.GetRepoDir(), "checkout", v.Branch).Run()
+
+	if _, err := sim.WriteFile(ctx, "x.txt", "x"); !errors.Is(err, selfimprove.ErrWrongBranch) {
+		t.Errorf("Expected ErrWrongBranch from WriteFile, got %v", err)
+	}
+	if _, err := sim.Commit(ctx, "x"); !errors.Is(err, selfimprove.ErrWrongBranch) {
+		t.Errorf("Expected ErrWrongBranch from Commit, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(sim.GetRepoDir(), "x.txt")); err == nil {
+		t.Error("File was written despite the wrong branch")
+	}
+}
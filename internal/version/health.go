@@ -0,0 +1,88 @@
+package version
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Readiness polling after a version process starts. Variables so tests can
+// shorten them.
+var (
+	readyTimeout  = 15 * time.Second
+	readyInterval = 200 * time.Millisecond
+)
+
+// probeClient sends the /healthz probes
+var probeClient = &http.Client{Timeout: 5 * time.Second}
+
+// errNoHealthz means the version answered but has no /healthz endpoint,
+// as is the case for builds that predate it
+var errNoHealthz = errors.New("no /healthz endpoint")
+
+// healthzURL is where a version on port answers health checks
+func healthzURL(port int) string {
+	return fmt.Sprintf("http://127.0.0.1:%d/healthz", port)
+}
+
+// probeHealthz requires GET /healthz on port to answer 200
+func probeHealthz(ctx context.Context, port int) error {
+	url := healthzURL(port)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := probeClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return errNoHealthz
+	default:
+		return fmt.Errorf("GET %s returned %d", url, resp.StatusCode)
+	}
+}
+
+// processAlive reports whether pid exists, using signal 0
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// waitReady polls /healthz on port until it answers, the process exits
+// (exited is closed) or readyTimeout passes
+func waitReady(ctx context.Context, port int, exited <-chan struct{}) error {
+	deadline := time.NewTimer(readyTimeout)
+	defer deadline.Stop()
+	tick := time.NewTicker(readyInterval)
+	defer tick.Stop()
+
+	var lastErr error
+	for {
+		lastErr = probeHealthz(ctx, port)
+		if lastErr == nil || errors.Is(lastErr, errNoHealthz) {
+			// Listening; older builds without /healthz are ready once they answer
+			return nil
+		}
+		select {
+		case <-exited:
+			return fmt.Errorf("process exited before becoming ready")
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return fmt.Errorf("not ready on port %d after %s: %w", port, readyTimeout, lastErr)
+		case <-tick.C:
+		}
+	}
+}
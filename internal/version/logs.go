@@ -0,0 +1,175 @@
+package version
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// logPollInterval is how often StreamVersionLogs checks the log for new
+// output. A variable so tests can shorten it.
+var logPollInterval = 250 * time.Millisecond
+
+// tailChunk is how much of a log is read at a time, from the end
+const tailChunk = 8 * 1024
+
+// logPath returns the path of a version's output log
+func (m *Manager) logPath(id string) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	v, ok := m.versions[id]
+	if !ok {
+		return "", fmt.Errorf("version %s not found", id)
+	}
+	return filepath.Join(m.baseDir, v.ID, "output.log"), nil
+}
+
+// GetVersionLogs returns the last lines of a version's log output, or all
+// of it if lines is 0
+func (m *Manager) GetVersionLogs(id string, lines int) (string, error) {
+	path, err := m.logPath(id)
+	if err != nil {
+		return "", err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "(no logs)", nil
+		}
+		return "", err
+	}
+	defer f.Close()
+
+	if lines <= 0 {
+		data, err := io.ReadAll(f)
+		return string(data), err
+	}
+	data, err := tailLines(f, lines)
+	return string(data), err
+}
+
+// tailLines returns the last n lines of f, reading it in chunks from the
+// end so large logs are not read whole. A final line without a newline
+// counts as a line.
+func tailLines(f *os.File, n int) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf []byte
+	offset := info.Size()
+	for offset > 0 {
+		size := int64(tailChunk)
+		if size > offset {
+			size = offset
+		}
+		offset -= size
+		chunk := make([]byte, size)
+		if _, err := f.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return nil, err
+		}
+		buf = append(chunk, buf...)
+
+		// n lines start after the n-th newline from the end, not counting
+		// the one that ends the last line
+		body := bytes.TrimSuffix(buf, []byte("\n"))
+		if bytes.Count(body, []byte("\n")) >= n {
+			start := len(body)
+			for i := 0; i < n; i++ {
+				start = bytes.LastIndexByte(body[:start], '\n')
+			}
+			return buf[start+1:], nil
+		}
+	}
+	return buf, nil
+}
+
+// StreamVersionLogs follows a version's log and sends each new line,
+// without its newline, until ctx is cancelled. Output written before the
+// call is skipped; use GetVersionLogs for it. A log that does not exist
+// yet is waited for, and a truncated one is followed from its start.
+func (m *Manager) StreamVersionLogs(ctx context.Context, id string) (<-chan string, error) {
+	path, err := m.logPath(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+
+	lines := make(chan string)
+	go func() {
+		defer close(lines)
+		ticker := time.NewTicker(logPollInterval)
+		defer ticker.Stop()
+
+		var partial []byte
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			data, size, err := readFrom(path, offset)
+			if err != nil {
+				continue
+			}
+			if size < offset {
+				// Truncated, e.g. by a restart
+				offset, partial = 0, nil
+				continue
+			}
+			offset += int64(len(data))
+
+			partial = append(partial, data...)
+			for {
+				i := bytes.IndexByte(partial, '\n')
+				if i < 0 {
+					break
+				}
+				line := string(bytes.TrimSuffix(partial[:i], []byte("\r")))
+				partial = partial[i+1:]
+				select {
+				case lines <- line:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return lines, nil
+}
+
+// readFrom reads path from offset to its end and returns what it read
+// along with the file's size
+func readFrom(path string, offset int64) ([]byte, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+	if info.Size() <= offset {
+		return nil, info.Size(), nil
+	}
+	data := make([]byte, info.Size()-offset)
+	n, err := f.ReadAt(data, offset)
+	if err != nil && err != io.EOF {
+		return nil, 0, err
+	}
+	return data[:n], info.Size(), nil
+}
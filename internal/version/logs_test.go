@@ -0,0 +1,119 @@
+package version
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newLogVersion registers a version and returns the path of its log
+func newLogVersion(t *testing.T) (*Manager, string) {
+	t.Helper()
+	dir := t.TempDir()
+	m := &Manager{baseDir: dir, versions: map[string]*AgentVersion{"v1": {ID: "v1"}}}
+	os.MkdirAll(filepath.Join(dir, "v1"), 0755)
+	return m, filepath.Join(dir, "v1", "output.log")
+}
+
+func TestGetVersionLogsLastLines(t *testing.T) {
+	m, path := newLogVersion(t)
+
+	if logs, err := m.GetVersionLogs("v1", 10); err != nil || logs != "(no logs)" {
+		t.Errorf("Expected (no logs) before the log exists, got %q, %v", logs, err)
+	}
+
+	// Lines from empty to several chunks long
+	var lines []string
+	for i := 0; i < 200; i++ {
+		lines = append(lines, fmt.Sprintf("%d:%s", i, strings.Repeat("x", (i*397)%(3*tailChunk))))
+	}
+	lines[150] = ""
+	content := strings.Join(lines, "\n")
+
+	for _, trailing := range []string{"\n", ""} {
+		os.WriteFile(path, []byte(content+trailing), 0644)
+		for _, n := range []int{1, 2, 7, 50, 51, 199, 200} {
+			logs, err := m.GetVersionLogs("v1", n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := strings.Join(lines[len(lines)-n:], "\n") + trailing
+			if logs != want {
+				got := strings.Split(strings.TrimSuffix(logs, "\n"), "\n")
+				t.Errorf("lines=%d trailing=%q: got %d lines starting %.20q, want %d", n, trailing, len(got), got[0], n)
+			}
+		}
+		if logs, _ := m.GetVersionLogs("v1", 500); logs != content+trailing {
+			t.Errorf("trailing=%q: expected the whole log when asking for more lines than it has", trailing)
+		}
+		if logs, _ := m.GetVersionLogs("v1", 0); logs != content+trailing {
+			t.Errorf("trailing=%q: expected the whole log for lines=0", trailing)
+		}
+	}
+
+	if _, err := m.GetVersionLogs("missing", 10); err == nil {
+		t.Error("Expected an error for an unknown version")
+	}
+}
+
+func TestStreamVersionLogs(t *testing.T) {
+	orig := logPollInterval
+	logPollInterval = 10 * time.Millisecond
+	t.Cleanup(func() { logPollInterval = orig })
+
+	m, path := newLogVersion(t)
+	os.WriteFile(path, []byte("before\n"), 0644)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	lines, err := m.StreamVersionLogs(ctx, "v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	next := func() string {
+		t.Helper()
+		select {
+		case line := <-lines:
+			return line
+		case <-time.After(2 * time.Second):
+			t.Fatal("Timed out waiting for a log line")
+			return ""
+		}
+	}
+
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	f.WriteString("first\nsec")
+	if got := next(); got != "first" {
+		t.Errorf("Expected the first appended line, got %q", got)
+	}
+	time.Sleep(30 * time.Millisecond)
+	f.WriteString("ond\r\nthird\n")
+	f.Close()
+	if got := next(); got != "second" {
+		t.Errorf("Expected a line split across writes to arrive whole, got %q", got)
+	}
+	if got := next(); got != "third" {
+		t.Errorf("Expected third, got %q", got)
+	}
+
+	// A restart truncates the log
+	os.WriteFile(path, []byte("restarted\n"), 0644)
+	if got := next(); got != "restarted" {
+		t.Errorf("Expected the truncated log to be followed from its start, got %q", got)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-lines:
+		if ok {
+			t.Error("Expected no more lines after cancel")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the stream to close after cancel")
+	}
+}
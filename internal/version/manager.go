@@ -10,6 +10,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"groq-go/internal/notify"
 	"groq-go/internal/selfimprove"
 )
 
@@ -29,6 +30,10 @@ type Manager struct {
 	selfimprove *selfimprove.Manager      // For git operations
 	mu          sync.RWMutex
 	storage     *Storage
+	building    map[string]bool // Version IDs with a build in flight
+	promoteSoak time.Duration   // How long a version must stay healthy before promotion
+
+	notifier *notify.Dispatcher // Build and lifecycle events; nil sends none
 }
 
 // NewManager creates a new version manager
@@ -53,6 +58,8 @@ func NewManager(sim *selfimprove.Manager) (*Manager, error) {
 		versions:    make(map[string]*AgentVersion),
 		selfimprove: sim,
 		storage:     storage,
+		building:    make(map[string]bool),
+		promoteSoak: promoteSoakFromEnv(),
 	}
 
 	// Load existing versions from storage
@@ -73,6 +80,28 @@ func NewManager(sim *selfimprove.Manager) (*Manager, error) {
 	return m, nil
 }
 
+// SetNotifier sends build, start, stop and promotion events to n
+func (m *Manager) SetNotifier(n *notify.Dispatcher) {
+	m.notifier = n
+}
+
+// notify emits an event about v; m.notifier never blocks
+func (m *Manager) notify(typ string, v *AgentVersion, message string, err error, started time.Time) {
+	e := notify.Event{
+		Type:      typ,
+		StartedAt: started,
+		Message:   message,
+		Refs:      map[string]string{"version_id": v.ID, "name": v.Name, "branch": v.Branch},
+	}
+	if v.CommitHash != "" {
+		e.Refs["commit"] = v.CommitHash
+	}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	m.notifier.Emit(e)
+}
+
 // CreateVersion creates a new version with a git branch
 func (m *Manager) CreateVersion(ctx context.Context, name, description string) (*AgentVersion, error) {
 	m.mu.Lock()
@@ -100,7 +129,7 @@ func (m *Manager) CreateVersion(ctx context.Context, name, description string) (
 			os.RemoveAll(versionDir)
 			return nil, fmt.Errorf("failed to create branch: %w", err)
 		}
-		commitHash = m.getCurrentCommit(ctx)
+		commitHash = m.branchCommit(ctx, branch)
 	}
 
 	version := &AgentVersion{
@@ -121,25 +150,29 @@ func (m *Manager) CreateVersion(ctx context.Context, name, description string) (
 		return nil, fmt.Errorf("failed to save version: %w", err)
 	}
 
-	return version, nil
+	return version.clone(), nil
 }
 
-// GetVersion returns a version by ID
+// GetVersion returns a copy of a version by ID. Builds and processes
+// change the stored version under m.mu, so callers never see it directly.
 func (m *Manager) GetVersion(id string) (*AgentVersion, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	v, ok := m.versions[id]
-	return v, ok
+	if !ok {
+		return nil, false
+	}
+	return v.clone(), true
 }
 
-// ListVersions returns all versions
+// ListVersions returns copies of all versions
 func (m *Manager) ListVersions() []*AgentVersion {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	result := make([]*AgentVersion, 0, len(m.versions))
 	for _, v := range m.versions {
-		result = append(result, v)
+		result = append(result, v.clone())
 	}
 	return result
 }
@@ -207,39 +240,44 @@ func (m *Manager) GetSelfImprove() *selfimprove.Manager {
 	return m.selfimprove
 }
 
-// UpdateVersion updates a version and persists changes
+// UpdateVersion replaces the stored version with v, such as a changed
+// copy from GetVersion, and persists it
 func (m *Manager) UpdateVersion(v *AgentVersion) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	return m.storage.Save(v)
+	stored, ok := m.versions[v.ID]
+	if !ok {
+		return fmt.Errorf("version %s not found", v.ID)
+	}
+	*stored = *v
+	return m.storage.Save(stored)
 }
 
 // Helper functions
 
+// createBranch creates the version's branch without checking it out, so
+// the branch self-improve works on stays checked out
 func (m *Manager) createBranch(ctx context.Context, branch string) error {
-	repoDir := m.selfimprove.GetRepoDir()
-	if repoDir == "" {
+	if m.selfimprove.GetRepoDir() == "" {
 		return fmt.Errorf("repo not initialized")
 	}
-	return runGit(ctx, repoDir, "checkout", "-b", branch)
+	return m.selfimprove.AddBranch(ctx, branch)
 }
 
 func (m *Manager) deleteBranch(ctx context.Context, branch string) error {
-	repoDir := m.selfimprove.GetRepoDir()
-	if repoDir == "" {
+	if m.selfimprove.GetRepoDir() == "" {
 		return nil
 	}
-	// Switch to main first if on the branch being deleted
-	runGit(ctx, repoDir, "checkout", "main")
-	return runGit(ctx, repoDir, "branch", "-D", branch)
+	return m.selfimprove.DeleteBranch(ctx, branch)
 }
 
-func (m *Manager) getCurrentCommit(ctx context.Context) string {
+// branchCommit returns the commit ref points to
+func (m *Manager) branchCommit(ctx context.Context, ref string) string {
 	repoDir := m.selfimprove.GetRepoDir()
 	if repoDir == "" {
 		return ""
 	}
-	output, err := runGitOutput(ctx, repoDir, "rev-parse", "HEAD")
+	output, err := runGitOutput(ctx, repoDir, "rev-parse", ref)
 	if err != nil {
 		return ""
 	}
@@ -258,6 +296,7 @@ func (m *Manager) stopVersionLocked(v *AgentVersion) error {
 	v.Status = StatusStopped
 	v.PID = 0
 	v.Port = 0
+	m.notify(notify.VersionStopped, v, fmt.Sprintf("Version %s stopped", v.Name), nil, time.Time{})
 	return m.storage.Save(v)
 }
 
@@ -0,0 +1,109 @@
+package version
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"groq-go/internal/notify"
+)
+
+// DefaultPromoteSoak is how long a version must stay healthy before it is
+// promoted, unless VERSION_PROMOTE_SOAK is set
+const DefaultPromoteSoak = 30 * time.Second
+
+// probeInterval is the time between health probes during a soak.
+// A variable so tests can shorten it.
+var probeInterval = 2 * time.Second
+
+// ErrSoakFailed is returned by PromoteVersion when a health probe fails
+var ErrSoakFailed = errors.New("health soak failed")
+
+// promoteSoakFromEnv reads VERSION_PROMOTE_SOAK, e.g. "2m"
+func promoteSoakFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("VERSION_PROMOTE_SOAK")); err == nil && d >= 0 {
+		return d
+	}
+	return DefaultPromoteSoak
+}
+
+// PromoteVersion merges a running version's branch into main once it has
+// stayed healthy for the soak window, and marks the result as known good.
+// Each probe checks that the process is alive and that GET /healthz on
+// its port answers 200. If any probe fails main is left untouched.
+func (m *Manager) PromoteVersion(ctx context.Context, id string) (_ *AgentVersion, err error) {
+	if m.selfimprove == nil {
+		return nil, fmt.Errorf("self-improve not available")
+	}
+
+	m.mu.RLock()
+	stored, ok := m.versions[id]
+	var v *AgentVersion
+	if ok {
+		v = stored.clone()
+	}
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("version %s not found", id)
+	}
+	if !v.IsActive() {
+		return nil, fmt.Errorf("version must be running to be promoted (status: %s)", v.Status)
+	}
+	port := v.Port
+
+	start := time.Now()
+	defer func() {
+		if err != nil {
+			m.notify(notify.VersionPromoteFailed, v, fmt.Sprintf("Promotion of version %s failed", v.Name), err, start)
+		}
+	}()
+	deadline := start.Add(m.promoteSoak)
+	for probes := 1; ; probes++ {
+		if err := m.probe(ctx, id, port); err != nil {
+			return nil, fmt.Errorf("%w after %s (probe %d): %w", ErrSoakFailed, time.Since(start).Round(time.Millisecond), probes, err)
+		}
+		if !time.Now().Add(probeInterval).Before(deadline) {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(probeInterval):
+		}
+	}
+
+	hash, err := m.selfimprove.MergeBranch(ctx, v.Branch, fmt.Sprintf("Promote version %s (%s)", v.Name, v.ID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to merge %s: %w", v.Branch, err)
+	}
+	if err := m.selfimprove.MarkCommitGood(hash); err != nil {
+		return nil, fmt.Errorf("merged %s but failed to mark it as known good: %w", hash, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stored.PromotedAt = time.Now()
+	m.notify(notify.VersionPromoted, stored, fmt.Sprintf("Version %s promoted to main (%s)", stored.Name, hash), nil, start)
+	if err := m.storage.Save(stored); err != nil {
+		return nil, err
+	}
+	return stored.clone(), nil
+}
+
+// probe checks a version once: its process and its /healthz endpoint,
+// which must exist
+func (m *Manager) probe(ctx context.Context, id string, port int) error {
+	m.mu.RLock()
+	v := m.versions[id]
+	pid, active := v.PID, v.IsActive()
+	m.mu.RUnlock()
+	if !active || !processAlive(pid) {
+		return fmt.Errorf("process probe: version is not running")
+	}
+	if err := probeHealthz(ctx, port); err != nil {
+		return fmt.Errorf("healthz probe: %w", err)
+	}
+	return nil
+}
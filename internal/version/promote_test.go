@@ -0,0 +1,172 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/notify"
+)
+
+// fakeInstance stands in for a running version: the test process is the
+// "process" and an httptest server answers its health checks
+func fakeInstance(t *testing.T, m *Manager, v *AgentVersion, healthz http.HandlerFunc) {
+	t.Helper()
+	srv := httptest.NewServer(healthz)
+	t.Cleanup(srv.Close)
+	u, _ := url.Parse(srv.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	m.mu.Lock()
+	stored := m.versions[v.ID]
+	stored.Status = StatusRunning
+	stored.PID = os.Getpid()
+	stored.Port = port
+	m.mu.Unlock()
+}
+
+func shortSoak(t *testing.T, m *Manager) {
+	orig := probeInterval
+	probeInterval = 10 * time.Millisecond
+	t.Cleanup(func() { probeInterval = orig })
+	m.promoteSoak = 50 * time.Millisecond
+}
+
+// recordEvents points m's notifier at a receiver and returns the events
+// it gets
+func recordEvents(t *testing.T, m *Manager) <-chan notify.Event {
+	t.Helper()
+	events := make(chan notify.Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e notify.Event
+		json.NewDecoder(r.Body).Decode(&e)
+		events <- e
+	}))
+	t.Cleanup(srv.Close)
+	n := notify.New(config.NotificationsConfig{Targets: []config.NotifyTarget{{URL: srv.URL}}},
+		notify.WithDeadLetter(filepath.Join(t.TempDir(), "dead.jsonl")))
+	t.Cleanup(n.Close)
+	m.SetNotifier(n)
+	return events
+}
+
+func nextEvent(t *testing.T, events <-chan notify.Event) notify.Event {
+	t.Helper()
+	select {
+	case e := <-events:
+		return e
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for a notification")
+		return notify.Event{}
+	}
+}
+
+func mainHead(t *testing.T, repo string) string {
+	t.Helper()
+	out, err := exec.Command("git", "-C", repo, "rev-parse", "main").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestPromoteVersion(t *testing.T) {
+	m, sim := newTestManager(t)
+	shortSoak(t, m)
+	ctx := context.Background()
+
+	v, err := m.CreateVersion(ctx, "faster", "")
+	if err != nil {
+		t.Fatalf("CreateVersion failed: %v", err)
+	}
+	if _, err := sim.ApplyToBranch(ctx, v.Branch, "fast.txt", "fast", "Make it faster"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.PromoteVersion(ctx, v.ID); err == nil || !strings.Contains(err.Error(), "must be running") {
+		t.Errorf("Expected an error for a version that is not running, got %v", err)
+	}
+
+	events := recordEvents(t, m)
+	var probes atomic.Int32
+	fakeInstance(t, m, v, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/healthz" {
+			http.NotFound(w, r)
+			return
+		}
+		probes.Add(1)
+	})
+
+	promoted, err := m.PromoteVersion(ctx, v.ID)
+	if err != nil {
+		t.Fatalf("PromoteVersion failed: %v", err)
+	}
+	if probes.Load() < 3 {
+		t.Errorf("Expected repeated probes during the soak, got %d", probes.Load())
+	}
+	if promoted.PromotedAt.IsZero() {
+		t.Error("Expected PromotedAt to be set")
+	}
+	repo := sim.GetRepoDir()
+	if !strings.Contains(gitFiles(t, repo, "main"), "fast.txt") {
+		t.Error("Expected the version's change on main")
+	}
+	if sim.GetLastKnownGood() != mainHead(t, repo) {
+		t.Errorf("Expected main %s to be marked known good, got %s", mainHead(t, repo), sim.GetLastKnownGood())
+	}
+	if e := nextEvent(t, events); e.Type != notify.VersionPromoted || e.Refs["version_id"] != v.ID || e.StartedAt.IsZero() {
+		t.Errorf("Unexpected event %+v", e)
+	}
+}
+
+func TestPromoteVersionFailedSoakLeavesMain(t *testing.T) {
+	m, sim := newTestManager(t)
+	shortSoak(t, m)
+	ctx := context.Background()
+
+	v, err := m.CreateVersion(ctx, "flaky", "")
+	if err != nil {
+		t.Fatalf("CreateVersion failed: %v", err)
+	}
+	if _, err := sim.ApplyToBranch(ctx, v.Branch, "flaky.txt", "flaky", "Flaky change"); err != nil {
+		t.Fatal(err)
+	}
+	before := mainHead(t, sim.GetRepoDir())
+	events := recordEvents(t, m)
+
+	var probes atomic.Int32
+	fakeInstance(t, m, v, func(w http.ResponseWriter, r *http.Request) {
+		if probes.Add(1) > 2 {
+			http.Error(w, "degraded", http.StatusServiceUnavailable)
+		}
+	})
+
+	_, err = m.PromoteVersion(ctx, v.ID)
+	if !errors.Is(err, ErrSoakFailed) {
+		t.Fatalf("Expected ErrSoakFailed, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "healthz probe") || !strings.Contains(err.Error(), "503") {
+		t.Errorf("Expected the failing probe in the error, got %v", err)
+	}
+	if after := mainHead(t, sim.GetRepoDir()); after != before {
+		t.Errorf("Expected main unchanged, moved from %s to %s", before, after)
+	}
+	if got, _ := m.GetVersion(v.ID); !got.PromotedAt.IsZero() {
+		t.Error("Expected PromotedAt to stay unset")
+	}
+	if e := nextEvent(t, events); e.Type != notify.VersionPromoteFailed || !strings.Contains(e.Error, "healthz probe") {
+		t.Errorf("Unexpected event %+v", e)
+	}
+}
@@ -1,22 +1,42 @@
 package version
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/gorilla/websocket"
+
+	"groq-go/internal/origin"
+)
+
+// RouteMode is how a request names the version it is for
+type RouteMode string
+
+const (
+	// RouteSubdomain routes abc123.chatweb.ai to version abc123
+	RouteSubdomain RouteMode = "subdomain"
+	// RoutePath routes /v/abc123/... to version abc123, for deployments
+	// without wildcard DNS and TLS
+	RoutePath RouteMode = "path"
 )
 
-// Proxy handles subdomain-based routing to version instances
+// PathPrefix starts the paths routed to versions in path mode
+const PathPrefix = "/v/"
+
+// Proxy handles subdomain- and path-based routing to version instances
 type Proxy struct {
 	manager    *Manager
-	mainDomain string // e.g., "chatweb.ai"
+	mainDomain string         // e.g., "chatweb.ai"
+	origins    *origin.Policy // WebSocket origins; nil allows the same host only
 	proxies    map[string]*httputil.ReverseProxy
 	mu         sync.RWMutex
 }
@@ -30,6 +50,11 @@ func NewProxy(manager *Manager, mainDomain string) *Proxy {
 	}
 }
 
+// SetOriginPolicy sets the origins allowed to open WebSockets to versions
+func (p *Proxy) SetOriginPolicy(policy *origin.Policy) {
+	p.origins = policy
+}
+
 // GetVersionFromHost extracts version ID from subdomain
 // e.g., "abc123.chatweb.ai" -> "abc123"
 // e.g., "chatweb.ai" -> "" (main)
@@ -53,8 +78,31 @@ func (p *Proxy) GetVersionFromHost(host string) string {
 	return subdomain
 }
 
-// GetProxyForVersion returns a reverse proxy for the given version
-func (p *Proxy) GetProxyForVersion(versionID string) (*httputil.ReverseProxy, int, error) {
+// GetVersionFromPath extracts version ID and the backend path from a
+// path-mode URL path
+// e.g., "/v/abc123/api/versions" -> "abc123", "/api/versions"
+// e.g., "/v/abc123" -> "abc123", "" (needs a trailing slash)
+// e.g., "/api/versions" -> "", ""
+func GetVersionFromPath(path string) (versionID, rest string) {
+	if !strings.HasPrefix(path, PathPrefix) {
+		return "", ""
+	}
+	versionID, rest, found := strings.Cut(strings.TrimPrefix(path, PathPrefix), "/")
+	if found {
+		rest = "/" + rest
+	}
+	return versionID, rest
+}
+
+// versionPrefix is the path prefix of a version in path mode
+func versionPrefix(versionID string) string {
+	return PathPrefix + versionID
+}
+
+// GetProxyForVersion returns a reverse proxy for the given version. Path
+// mode proxies strip the version's prefix and rewrite HTML and redirects
+// to keep the UI under it, so each mode has its own cached proxy.
+func (p *Proxy) GetProxyForVersion(versionID string, mode RouteMode) (*httputil.ReverseProxy, int, error) {
 	v, ok := p.manager.GetVersion(versionID)
 	if !ok {
 		return nil, 0, nil
@@ -68,7 +116,7 @@ func (p *Proxy) GetProxyForVersion(versionID string) (*httputil.ReverseProxy, in
 	defer p.mu.Unlock()
 
 	// Check cache
-	key := versionID
+	key := versionID + "|" + string(mode)
 	if proxy, exists := p.proxies[key]; exists {
 		return proxy, v.Port, nil
 	}
@@ -83,19 +131,74 @@ func (p *Proxy) GetProxyForVersion(versionID string) (*httputil.ReverseProxy, in
 		originalDirector(req)
 		req.Host = req.URL.Host
 	}
+	if mode == RoutePath {
+		prefix := versionPrefix(versionID)
+		pathDirector := proxy.Director
+		proxy.Director = func(req *http.Request) {
+			pathDirector(req)
+			_, rest := GetVersionFromPath(req.URL.Path)
+			req.URL.Path = rest
+			req.URL.RawPath = ""
+			req.Header.Set("X-Forwarded-Prefix", prefix)
+			// Plain bodies, so HTML can be rewritten
+			req.Header.Del("Accept-Encoding")
+		}
+		proxy.ModifyResponse = func(resp *http.Response) error {
+			return rewriteForPrefix(resp, prefix)
+		}
+	}
 
 	p.proxies[key] = proxy
 	return proxy, v.Port, nil
 }
 
-// ProxyRequest proxies an HTTP request to the appropriate version
+// absoluteRef matches quoted absolute references to the API and the
+// WebSocket endpoint, e.g. '/api/versions', "/ws" or `/ws?token=`
+var absoluteRef = regexp.MustCompile("([\"'`])/(api/|ws[\"'`?])")
+
+// rewriteForPrefix keeps a proxied version's UI under its path prefix:
+// absolute /api/ and /ws references in HTML and redirect locations get
+// the prefix
+func rewriteForPrefix(resp *http.Response, prefix string) error {
+	if loc := resp.Header.Get("Location"); strings.HasPrefix(loc, "/") && !strings.HasPrefix(loc, "//") {
+		resp.Header.Set("Location", prefix+loc)
+	}
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/html") || resp.Header.Get("Content-Encoding") != "" {
+		return nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return err
+	}
+	body = absoluteRef.ReplaceAll(body, []byte("${1}"+prefix+"/${2}"))
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	resp.Header.Set("Content-Length", strconv.Itoa(len(body)))
+	return nil
+}
+
+// ProxyRequest proxies an HTTP request to the appropriate version, named
+// by subdomain or by path prefix
 func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) bool {
+	mode := RouteSubdomain
+	backendPath := r.URL.Path
 	versionID := p.GetVersionFromHost(r.Host)
 	if versionID == "" {
-		return false // Not a version subdomain
+		mode = RoutePath
+		versionID, backendPath = GetVersionFromPath(r.URL.Path)
+		if versionID == "" {
+			return false // Not a version subdomain or path
+		}
+		if backendPath == "" {
+			// Relative references need the trailing slash
+			http.Redirect(w, r, versionPrefix(versionID)+"/", http.StatusMovedPermanently)
+			return true
+		}
 	}
 
-	proxy, port, _ := p.GetProxyForVersion(versionID)
+	proxy, port, _ := p.GetProxyForVersion(versionID, mode)
 	if proxy == nil {
 		http.Error(w, "Version not found or not running", http.StatusNotFound)
 		return true
@@ -103,7 +206,7 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) bool {
 
 	// Handle WebSocket upgrade
 	if isWebSocketRequest(r) {
-		p.proxyWebSocket(w, r, port)
+		p.proxyWebSocket(w, r, port, backendPath)
 		return true
 	}
 
@@ -111,11 +214,11 @@ func (p *Proxy) ProxyRequest(w http.ResponseWriter, r *http.Request) bool {
 	return true
 }
 
-// proxyWebSocket handles WebSocket proxying
-func (p *Proxy) proxyWebSocket(w http.ResponseWriter, r *http.Request, port int) {
+// proxyWebSocket handles WebSocket proxying to path on the backend
+func (p *Proxy) proxyWebSocket(w http.ResponseWriter, r *http.Request, port int, path string) {
 	// Upgrade client connection
 	upgrader := websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool { return true },
+		CheckOrigin: p.origins.Allow,
 	}
 	clientConn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
@@ -124,7 +227,10 @@ func (p *Proxy) proxyWebSocket(w http.ResponseWriter, r *http.Request, port int)
 	defer clientConn.Close()
 
 	// Connect to backend
-	backendURL := "ws://localhost:" + fmt.Sprintf("%d", port) + r.URL.Path
+	backendURL := "ws://localhost:" + fmt.Sprintf("%d", port) + path
+	if r.URL.RawQuery != "" {
+		backendURL += "?" + r.URL.RawQuery
+	}
 	backendConn, _, err := websocket.DefaultDialer.Dial(backendURL, nil)
 	if err != nil {
 		return
@@ -163,7 +269,8 @@ func isWebSocketRequest(r *http.Request) bool {
 func (p *Proxy) ClearProxyCache(versionID string) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
-	delete(p.proxies, versionID)
+	delete(p.proxies, versionID+"|"+string(RouteSubdomain))
+	delete(p.proxies, versionID+"|"+string(RoutePath))
 }
 
 // ProxyHandler returns an http.Handler that proxies to versions
@@ -0,0 +1,223 @@
+package version
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/origin"
+)
+
+// newProxyBackend runs a stand-in version that echoes the path, headers
+// and WebSocket messages it receives and registers it as running
+func newProxyBackend(t *testing.T) (*Proxy, *AgentVersion) {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Backend-Path", r.URL.Path)
+		w.Header().Set("X-Backend-Prefix", r.Header.Get("X-Forwarded-Prefix"))
+		io.WriteString(w, "api")
+	})
+	mux.HandleFunc("/index.html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, `<script>fetch('/api/versions'); fetch("/api/auth/status"); new WebSocket(host + '/ws?token=' + t); const other = '/wsx';</script>`)
+	})
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/setup", http.StatusFound)
+	})
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			msgType, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			conn.WriteMessage(msgType, []byte(r.URL.RawQuery+":"+string(msg)))
+		}
+	})
+	backend := httptest.NewServer(mux)
+	t.Cleanup(backend.Close)
+	u, _ := url.Parse(backend.URL)
+	port, _ := strconv.Atoi(u.Port())
+
+	v := &AgentVersion{ID: "abc123", Status: StatusRunning, Port: port}
+	m := &Manager{versions: map[string]*AgentVersion{v.ID: v}}
+	return NewProxy(m, "example.com"), v
+}
+
+func TestGetVersionFromPath(t *testing.T) {
+	for path, want := range map[string][2]string{
+		"/v/abc123/api/versions": {"abc123", "/api/versions"},
+		"/v/abc123/":             {"abc123", "/"},
+		"/v/abc123":              {"abc123", ""},
+		"/api/versions":          {"", ""},
+		"/version":               {"", ""},
+	} {
+		id, rest := GetVersionFromPath(path)
+		if id != want[0] || rest != want[1] {
+			t.Errorf("GetVersionFromPath(%q) = %q, %q, want %q, %q", path, id, rest, want[0], want[1])
+		}
+	}
+}
+
+func TestProxyRouting(t *testing.T) {
+	p, _ := newProxyBackend(t)
+	handler := p.ProxyHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "main")
+	}))
+
+	serve := func(host, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Host = host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := serve("abc123.example.com", "/api/versions")
+	if got := rec.Header().Get("X-Backend-Path"); got != "/api/versions" {
+		t.Errorf("Subdomain mode: expected backend path /api/versions, got %q", got)
+	}
+	if got := rec.Header().Get("X-Backend-Prefix"); got != "" {
+		t.Errorf("Subdomain mode: expected no forwarded prefix, got %q", got)
+	}
+
+	rec = serve("localhost:8080", "/v/abc123/api/versions")
+	if got := rec.Header().Get("X-Backend-Path"); got != "/api/versions" {
+		t.Errorf("Path mode: expected backend path /api/versions, got %q", got)
+	}
+	if got := rec.Header().Get("X-Backend-Prefix"); got != "/v/abc123" {
+		t.Errorf("Path mode: expected X-Forwarded-Prefix /v/abc123, got %q", got)
+	}
+
+	rec = serve("localhost:8080", "/v/abc123/index.html")
+	body := rec.Body.String()
+	for _, want := range []string{`'/v/abc123/api/versions'`, `"/v/abc123/api/auth/status"`, `'/v/abc123/ws?token='`, `'/wsx'`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in rewritten HTML:\n%s", want, body)
+		}
+	}
+	if rec.Header().Get("Content-Length") != strconv.Itoa(len(body)) {
+		t.Errorf("Expected Content-Length %d, got %s", len(body), rec.Header().Get("Content-Length"))
+	}
+
+	rec = serve("abc123.example.com", "/index.html")
+	if !strings.Contains(rec.Body.String(), `'/api/versions'`) {
+		t.Errorf("Subdomain mode should not rewrite HTML:\n%s", rec.Body.String())
+	}
+
+	rec = serve("localhost:8080", "/v/abc123/login")
+	if got := rec.Header().Get("Location"); got != "/v/abc123/setup" {
+		t.Errorf("Expected redirect to /v/abc123/setup, got %q", got)
+	}
+
+	rec = serve("localhost:8080", "/v/abc123")
+	if rec.Code != http.StatusMovedPermanently || rec.Header().Get("Location") != "/v/abc123/" {
+		t.Errorf("Expected a redirect to the trailing slash, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+
+	if rec = serve("localhost:8080", "/v/missing/"); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown version, got %d", rec.Code)
+	}
+	if rec = serve("localhost:8080", "/api/versions"); rec.Body.String() != "main" {
+		t.Errorf("Expected other requests to reach the main handler, got %q", rec.Body.String())
+	}
+}
+
+func TestProxyCachesPerMode(t *testing.T) {
+	p, v := newProxyBackend(t)
+	sub, _, _ := p.GetProxyForVersion(v.ID, RouteSubdomain)
+	path, _, _ := p.GetProxyForVersion(v.ID, RoutePath)
+	if sub == nil || path == nil || sub == path {
+		t.Fatal("Expected distinct proxies per mode")
+	}
+	if again, _, _ := p.GetProxyForVersion(v.ID, RoutePath); again != path {
+		t.Error("Expected the path proxy to be cached")
+	}
+	p.ClearProxyCache(v.ID)
+	if len(p.proxies) != 0 {
+		t.Errorf("Expected ClearProxyCache to drop both modes, %d left", len(p.proxies))
+	}
+}
+
+func TestProxyWebSocket(t *testing.T) {
+	p, _ := newProxyBackend(t)
+	front := httptest.NewServer(p.ProxyHandler(http.NotFoundHandler()))
+	t.Cleanup(front.Close)
+	wsBase := "ws" + strings.TrimPrefix(front.URL, "http")
+
+	for _, tc := range []struct {
+		name string
+		url  string
+		host string
+	}{
+		{"path", wsBase + "/v/abc123/ws?token=t1", ""},
+		{"subdomain", wsBase + "/ws?token=t1", "abc123.example.com"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			header := http.Header{}
+			if tc.host != "" {
+				header.Set("Host", tc.host)
+			}
+			conn, _, err := websocket.DefaultDialer.Dial(tc.url, header)
+			if err != nil {
+				t.Fatalf("Dial failed: %v", err)
+			}
+			defer conn.Close()
+			if err := conn.WriteMessage(websocket.TextMessage, []byte("hello")); err != nil {
+				t.Fatal(err)
+			}
+			_, msg, err := conn.ReadMessage()
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if string(msg) != "token=t1:hello" {
+				t.Errorf("Expected echo with query, got %q", msg)
+			}
+		})
+	}
+}
+
+func TestProxyWebSocketChecksOrigin(t *testing.T) {
+	p, _ := newProxyBackend(t)
+	front := httptest.NewServer(p.ProxyHandler(http.NotFoundHandler()))
+	t.Cleanup(front.Close)
+	wsURL := "ws" + strings.TrimPrefix(front.URL, "http") + "/v/abc123/ws"
+
+	dial := func(origin string) error {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {origin}})
+		if err == nil {
+			conn.Close()
+		}
+		return err
+	}
+
+	if err := dial(front.URL); err != nil {
+		t.Errorf("Expected the same host to connect, got %v", err)
+	}
+	if err := dial(front.URL + ".evil.com"); err == nil {
+		t.Error("Expected a foreign origin to be rejected")
+	}
+
+	policy, err := origin.NewPolicy([]string{"app.example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.SetOriginPolicy(policy)
+	if err := dial("https://app.example.com"); err != nil {
+		t.Errorf("Expected an allowed origin to connect, got %v", err)
+	}
+	if err := dial("https://app.example.com.evil.com"); err == nil {
+		t.Error("Expected a lookalike origin to be rejected")
+	}
+}
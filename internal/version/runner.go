@@ -2,40 +2,89 @@ package version
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"syscall"
 	"time"
+
+	"groq-go/internal/notify"
 )
 
-// StartVersion starts a version on an available port
+// StartVersion starts a version on an available port and waits until it
+// answers on /healthz. A version that never becomes ready is stopped and
+// marked failed with the tail of its log.
 func (m *Manager) StartVersion(ctx context.Context, id string) error {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	v, ok := m.versions[id]
 	if !ok {
+		m.mu.Unlock()
 		return fmt.Errorf("version %s not found", id)
 	}
+	cmd, exited, err := m.startProcessLocked(v)
+	port := v.Port
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	readyErr := waitReady(ctx, port, exited)
+	if readyErr == nil {
+		select {
+		case <-exited:
+			readyErr = fmt.Errorf("process exited right after becoming ready")
+		default:
+		}
+	}
+	var logs string
+	if readyErr != nil {
+		// Stop it first so the log is complete
+		cmd.Process.Kill()
+		<-exited
+		logs, _ = m.GetVersionLogs(id, 20)
+	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if readyErr != nil {
+		v.Status = StatusFailed
+		v.Error = fmt.Sprintf("%v\n%s", readyErr, logs)
+		v.PID = 0
+		v.Port = 0
+		m.storage.Save(v)
+		return fmt.Errorf("version failed to start: %w", readyErr)
+	}
+	v.Status = StatusRunning
+	v.StartedAt = time.Now()
+	m.notify(notify.VersionStarted, v, fmt.Sprintf("Version %s started on port %d", v.Name, v.Port), nil, time.Time{})
+	return m.storage.Save(v)
+}
+
+// startProcessLocked launches the version's binary on a free port. The port
+// is reserved but the status is only set to running once the version is
+// ready. exited is closed when the process ends. m.mu must be held.
+func (m *Manager) startProcessLocked(v *AgentVersion) (*exec.Cmd, <-chan struct{}, error) {
 	if v.IsActive() {
-		return fmt.Errorf("version is already running on port %d", v.Port)
+		return nil, nil, fmt.Errorf("version is already running on port %d", v.Port)
+	}
+	if v.PID > 0 {
+		return nil, nil, fmt.Errorf("version is already starting on port %d", v.Port)
 	}
 
 	if !v.CanStart() {
-		return fmt.Errorf("version cannot be started (status: %s)", v.Status)
+		return nil, nil, fmt.Errorf("version cannot be started (status: %s)", v.Status)
 	}
 
 	// Verify binary exists
 	if _, err := os.Stat(v.BinaryPath); err != nil {
-		return fmt.Errorf("binary not found: %w", err)
+		return nil, nil, fmt.Errorf("binary not found: %w", err)
 	}
 
 	// Allocate port
 	port := m.AllocatePort()
 	if port == 0 {
-		return fmt.Errorf("no available ports (all %d-%d in use)", BasePort, MaxPort)
+		return nil, nil, fmt.Errorf("no available ports (all %d-%d in use)", BasePort, MaxPort)
 	}
 
 	// Start the process
@@ -48,7 +97,7 @@ func (m *Manager) StartVersion(ctx context.Context, id string) error {
 	}
 
 	// Redirect output to log files
-	versionDir := m.baseDir + "/" + id
+	versionDir := m.baseDir + "/" + v.ID
 	logFile, err := os.OpenFile(versionDir+"/output.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err == nil {
 		cmd.Stdout = logFile
@@ -56,26 +105,28 @@ func (m *Manager) StartVersion(ctx context.Context, id string) error {
 	}
 
 	if err := cmd.Start(); err != nil {
-		return fmt.Errorf("failed to start version: %w", err)
+		return nil, nil, fmt.Errorf("failed to start version: %w", err)
 	}
 
 	v.PID = cmd.Process.Pid
 	v.Port = port
-	v.Status = StatusRunning
-	v.StartedAt = time.Now()
 	v.Error = ""
 
 	// Save state
 	if err := m.storage.Save(v); err != nil {
 		// Try to kill the process if we can't save state
 		cmd.Process.Kill()
-		return fmt.Errorf("failed to save version state: %w", err)
+		cmd.Wait()
+		v.PID = 0
+		v.Port = 0
+		return nil, nil, fmt.Errorf("failed to save version state: %w", err)
 	}
 
 	// Monitor process in background
-	go m.monitorProcess(v, cmd)
+	exited := make(chan struct{})
+	go m.monitorProcess(v, cmd, exited)
 
-	return nil
+	return cmd, exited, nil
 }
 
 // StopVersion stops a running version
@@ -95,10 +146,12 @@ func (m *Manager) StopVersion(ctx context.Context, id string) error {
 	return m.stopVersionLocked(v)
 }
 
-// monitorProcess monitors a running version process
-func (m *Manager) monitorProcess(v *AgentVersion, cmd *exec.Cmd) {
+// monitorProcess monitors a running version process and closes exited
+// once it ends
+func (m *Manager) monitorProcess(v *AgentVersion, cmd *exec.Cmd, exited chan<- struct{}) {
 	// Wait for process to exit
 	err := cmd.Wait()
+	close(exited)
 
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -117,46 +170,19 @@ func (m *Manager) monitorProcess(v *AgentVersion, cmd *exec.Cmd) {
 	}
 }
 
-// GetVersionLogs returns the log output of a version
-func (m *Manager) GetVersionLogs(id string, lines int) (string, error) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	v, ok := m.versions[id]
-	if !ok {
-		return "", fmt.Errorf("version %s not found", id)
-	}
-
-	logPath := m.baseDir + "/" + v.ID + "/output.log"
-	data, err := os.ReadFile(logPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return "(no logs)", nil
-		}
-		return "", err
-	}
-
-	content := string(data)
-	if lines > 0 && len(content) > lines*100 {
-		// Rough approximation: take last N*100 bytes
-		content = content[len(content)-lines*100:]
-	}
-
-	return content, nil
-}
-
 // RestartVersion stops and starts a version
 func (m *Manager) RestartVersion(ctx context.Context, id string) error {
 	// Stop if running
 	m.mu.RLock()
 	v, ok := m.versions[id]
+	active := ok && v.IsActive()
 	m.mu.RUnlock()
 
 	if !ok {
 		return fmt.Errorf("version %s not found", id)
 	}
 
-	if v.IsActive() {
+	if active {
 		if err := m.StopVersion(ctx, id); err != nil {
 			return fmt.Errorf("failed to stop: %w", err)
 		}
@@ -167,27 +193,29 @@ func (m *Manager) RestartVersion(ctx context.Context, id string) error {
 	return m.StartVersion(ctx, id)
 }
 
-// CheckHealth checks if a version is responding
+// CheckHealth checks if a version is responding on /healthz. Builds
+// without the endpoint count as healthy while their process is alive.
 func (m *Manager) CheckHealth(ctx context.Context, id string) (bool, error) {
 	m.mu.RLock()
 	v, ok := m.versions[id]
+	var pid, port int
+	var active bool
+	if ok {
+		pid, port, active = v.PID, v.Port, v.IsActive()
+	}
 	m.mu.RUnlock()
 
 	if !ok {
 		return false, fmt.Errorf("version %s not found", id)
 	}
 
-	if !v.IsActive() {
+	if !active {
 		return false, nil
 	}
 
-	// Check if process is still running
-	proc, err := os.FindProcess(v.PID)
-	if err != nil {
-		return false, nil
+	err := probeHealthz(ctx, port)
+	if errors.Is(err, errNoHealthz) {
+		return processAlive(pid), nil
 	}
-
-	// Send signal 0 to check if process exists
-	err = proc.Signal(syscall.Signal(0))
 	return err == nil, nil
 }
@@ -0,0 +1,185 @@
+package version
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestMain lets the test binary act as a version when started by one of
+// the tests, behaving as FAKE_VERSION says: "ready" listens after a delay,
+// "stuck" never listens and "crash" exits at once
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("FAKE_VERSION"); mode != "" {
+		runFakeVersion(mode)
+		return
+	}
+	os.Exit(m.Run())
+}
+
+func runFakeVersion(mode string) {
+	var addr string
+	for i, arg := range os.Args {
+		if arg == "-addr" && i+1 < len(os.Args) {
+			addr = os.Args[i+1]
+		}
+	}
+	switch mode {
+	case "ready":
+		time.Sleep(300 * time.Millisecond)
+		http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `{"status":"ok"}`)
+		})
+		http.ListenAndServe("127.0.0.1"+addr, nil)
+	case "stuck":
+		fmt.Println("loading configuration...")
+		select {}
+	case "crash":
+		fmt.Println("panic: boom")
+		os.Exit(1)
+	}
+}
+
+// newFakeVersion registers a built version whose binary is the test binary
+// running in mode
+func newFakeVersion(t *testing.T, mode string) (*Manager, *AgentVersion) {
+	t.Helper()
+	t.Setenv("FAKE_VERSION", mode)
+	origTimeout, origInterval := readyTimeout, readyInterval
+	readyTimeout, readyInterval = 3*time.Second, 20*time.Millisecond
+	t.Cleanup(func() { readyTimeout, readyInterval = origTimeout, origInterval })
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	storage, err := NewStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := &Manager{
+		baseDir:  dir,
+		versions: make(map[string]*AgentVersion),
+		storage:  storage,
+		building: make(map[string]bool),
+	}
+	v := &AgentVersion{ID: "v-" + mode, Name: mode, Status: StatusReady, BinaryPath: exe}
+	os.MkdirAll(filepath.Join(dir, v.ID), 0755)
+	m.versions[v.ID] = v
+	return m, v
+}
+
+func TestStartVersionWaitsForHealthz(t *testing.T) {
+	m, v := newFakeVersion(t, "ready")
+	ctx := context.Background()
+
+	start := time.Now()
+	if err := m.StartVersion(ctx, v.ID); err != nil {
+		t.Fatalf("StartVersion failed: %v", err)
+	}
+	t.Cleanup(func() { m.StopVersion(ctx, v.ID) })
+
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("Expected StartVersion to wait for readiness, returned after %s", elapsed)
+	}
+	got, _ := m.GetVersion(v.ID)
+	if got.Status != StatusRunning || got.StartedAt.IsZero() {
+		t.Errorf("Expected a running version, got status %s", got.Status)
+	}
+	if healthy, err := m.CheckHealth(ctx, v.ID); err != nil || !healthy {
+		t.Errorf("Expected CheckHealth to pass, got %v, %v", healthy, err)
+	}
+}
+
+func TestStartVersionNeverReady(t *testing.T) {
+	for _, tc := range []struct {
+		mode, reason, log string
+	}{
+		{"stuck", "not ready on port", "loading configuration"},
+		{"crash", "exited", "panic: boom"},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			m, v := newFakeVersion(t, tc.mode)
+			ctx := context.Background()
+
+			err := m.StartVersion(ctx, v.ID)
+			if err == nil || !strings.Contains(err.Error(), tc.reason) {
+				t.Fatalf("Expected a start failure mentioning %q, got %v", tc.reason, err)
+			}
+			got, _ := m.GetVersion(v.ID)
+			if got.Status != StatusFailed {
+				t.Errorf("Expected status failed, got %s", got.Status)
+			}
+			if !strings.Contains(got.Error, tc.log) {
+				t.Errorf("Expected the log tail in the error, got %q", got.Error)
+			}
+			if got.PID != 0 || got.Port != 0 {
+				t.Errorf("Expected PID and port to be released, got %d and %d", got.PID, got.Port)
+			}
+			if healthy, _ := m.CheckHealth(ctx, v.ID); healthy {
+				t.Error("Expected CheckHealth to fail")
+			}
+		})
+	}
+}
+
+// TestStartStopListConcurrently lists and reads versions while one starts,
+// stops and restarts; run with -race
+func TestStartStopListConcurrently(t *testing.T) {
+	m, v := newFakeVersion(t, "ready")
+	ctx := context.Background()
+	t.Cleanup(func() { m.StopVersion(ctx, v.ID) })
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	stop := sync.OnceFunc(func() {
+		close(done)
+		wg.Wait()
+	})
+	defer stop()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				for _, got := range m.ListVersions() {
+					if _, err := json.Marshal(got); err != nil {
+						t.Error(err)
+					}
+				}
+				if got, ok := m.GetVersion(v.ID); ok {
+					got.Status = StatusFailed // A copy; the manager's stays as is
+				}
+				m.CheckHealth(ctx, v.ID)
+			}
+		}()
+	}
+
+	if err := m.StartVersion(ctx, v.ID); err != nil {
+		t.Fatalf("StartVersion failed: %v", err)
+	}
+	if err := m.RestartVersion(ctx, v.ID); err != nil {
+		t.Fatalf("RestartVersion failed: %v", err)
+	}
+	if err := m.StopVersion(ctx, v.ID); err != nil {
+		t.Fatalf("StopVersion failed: %v", err)
+	}
+	stop()
+
+	if got, _ := m.GetVersion(v.ID); got.Status != StatusStopped {
+		t.Errorf("Expected the version stopped, got %s", got.Status)
+	}
+}
@@ -31,6 +31,14 @@ type AgentVersion struct {
 	CreatedAt   time.Time `json:"created_at"`   // When version was created
 	BuildAt     time.Time `json:"built_at"`     // When version was built
 	StartedAt   time.Time `json:"started_at"`   // When version was started
+	PromotedAt  time.Time `json:"promoted_at"`  // When version was merged into main
+}
+
+// clone returns a copy of v. AgentVersion holds only values, so the copy
+// shares nothing with v.
+func (v *AgentVersion) clone() *AgentVersion {
+	c := *v
+	return &c
 }
 
 // IsActive returns true if the version process is running
@@ -0,0 +1,334 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"groq-go/internal/audit"
+	"groq-go/internal/backup"
+)
+
+// requireAdmin checks that the request may use admin endpoints.
+// When users are configured a valid token is required; otherwise only
+// loopback clients are allowed.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if s.auth != nil && s.auth.HasUsers() {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if _, err := s.auth.ValidateToken(token); err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return false
+		}
+		return true
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() || r.Header.Get("X-Forwarded-For") != "" {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// requireAdminUser checks that the request carries a token of a user named
+// in web.admin_users (ADMIN_USERS, comma-separated). Unlike requireAdmin there is no
+// loopback fallback, so it guards endpoints that move money.
+func (s *Server) requireAdminUser(w http.ResponseWriter, r *http.Request) bool {
+	if s.auth == nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	user, err := s.auth.ValidateToken(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "))
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	if s.cfg != nil {
+		for _, name := range s.cfg.Web.AdminUsers {
+			if name == user.Username {
+				return true
+			}
+		}
+	}
+	http.Error(w, "Forbidden", http.StatusForbidden)
+	return false
+}
+
+// handleAdminGC triggers a janitor sweep
+func (s *Server) handleAdminGC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.janitor == nil {
+		http.Error(w, "Janitor not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+	report := s.janitor.Sweep(r.Context(), dryRun)
+	log.Info("Manual GC sweep", "dry_run", dryRun, "reclaimed_bytes", report.TotalBytes())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// handleAdminCreditsReload re-reads the credits pricing config
+func (s *Server) handleAdminCreditsReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.credits == nil {
+		http.Error(w, "Credits not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	if err := s.credits.ReloadPricing(); err != nil {
+		log.Warn("Failed to reload pricing", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	pricing := s.credits.Pricing()
+	log.Info("Reloaded pricing", "models", len(pricing.ModelCosts), "free_credits", pricing.FreeCredits)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":  "reloaded",
+		"pricing": pricing,
+	})
+}
+
+// handleConfig returns the effective configuration with secrets redacted
+func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.cfg == nil {
+		http.Error(w, "Config not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.cfg.Redacted())
+}
+
+// handleAudit returns the latest tool executions, newest first; limit
+// defaults to 100 and is capped at 1000
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.audit == nil {
+		http.Error(w, "Audit log not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	limit := 100
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 1000)
+	}
+	entries, err := s.audit.Recent(limit)
+	if err != nil {
+		log.Error("Failed to read audit log", "error", err)
+		http.Error(w, "Failed to read audit log", http.StatusInternalServerError)
+		return
+	}
+	if entries == nil {
+		entries = []audit.Entry{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"entries": entries})
+}
+
+// watchReloadSignal reloads hot-reloadable configuration on SIGHUP
+func (s *Server) watchReloadSignal() {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP)
+	go func() {
+		for range ch {
+			if s.credits == nil {
+				continue
+			}
+			if err := s.credits.ReloadPricing(); err != nil {
+				log.Warn("Failed to reload pricing on SIGHUP", "error", err)
+				continue
+			}
+			log.Info("Reloaded pricing on SIGHUP")
+		}
+	}()
+}
+
+// handleAdminCreditsLimit temporarily raises a user's daily caps until the
+// next UTC midnight
+func (s *Server) handleAdminCreditsLimit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.credits == nil {
+		http.Error(w, "Credits not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	var req struct {
+		UserID   string `json:"user_id"`
+		Credits  int    `json:"credits"`
+		Requests int    `json:"requests"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.UserID == "" {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	expires, err := s.credits.SetDailyOverride(req.UserID, req.Credits, req.Requests)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Info("Daily limit override", "user_id", req.UserID, "credits", req.Credits, "requests", req.Requests)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":     "ok",
+		"user_id":    req.UserID,
+		"expires_at": expires,
+	})
+}
+
+// handleCreditsSummary reports credit spend per model and user over the
+// last days UTC days, today included; days defaults to 30 and is capped at
+// 365. top limits the user breakdown and defaults to 20.
+func (s *Server) handleCreditsSummary(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.credits == nil {
+		http.Error(w, "Credits not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	days, top := 30, 20
+	for name, dst := range map[string]*int{"days": &days, "top": &top} {
+		v := r.URL.Query().Get(name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid "+name, http.StatusBadRequest)
+			return
+		}
+		*dst = n
+	}
+	days = min(days, 365)
+
+	// Whole days keep the window stable, so repeated calls hit the cache
+	y, mo, d := time.Now().UTC().Date()
+	to := time.Date(y, mo, d+1, 0, 0, 0, 0, time.UTC)
+	summary := s.credits.UsageSummary(to.AddDate(0, 0, -days), to)
+	users := len(summary.Users)
+	if users > top {
+		summary.Users = summary.Users[:top]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"days":         days,
+		"summary":      summary,
+		"active_users": users,
+	})
+}
+
+// handleAdminBackup streams a backup archive of the data directory
+func (s *Server) handleAdminBackup(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+
+	name := fmt.Sprintf("groq-go-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+
+	opts := backup.Options{IncludeSecrets: r.URL.Query().Get("secrets") != "false"}
+	manifest, err := backup.Create(w, opts)
+	if err != nil {
+		// Headers are already sent; the client sees a truncated archive
+		log.Error("Backup failed", "error", err)
+		return
+	}
+	log.Info("Backup created", "files", manifest.Files, "secrets", manifest.IncludesSecrets)
+}
+
+// handleAdminRestore restores a backup archive posted as the request body.
+// The server must be restarted to pick up the restored data.
+func (s *Server) handleAdminRestore(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+
+	opts := backup.RestoreOptions{Force: r.URL.Query().Get("force") == "true"}
+	manifest, err := backup.Restore(r.Body, opts)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, backup.ErrNotEmpty) {
+			status = http.StatusConflict
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	log.Info("Backup restored", "files", manifest.Files, "created_at", manifest.CreatedAt)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":   "restored",
+		"manifest": manifest,
+		"note":     "restart the server to load the restored data",
+	})
+}
@@ -0,0 +1,190 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/audit"
+	"groq-go/internal/client"
+	"groq-go/internal/config"
+	"groq-go/internal/credits"
+	"groq-go/internal/version"
+)
+
+func TestHandleConfigRedactsSecrets(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{
+		APIKey: "gsk_secret_value_1234",
+		Model:  config.DefaultModel,
+		Web:    config.WebConfig{Addr: ":8080", AdminUsers: []string{"alice"}},
+		TTS:    config.TTSConfig{FalKey: "fal_secret_value_5678"},
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+	r.Header.Set("Authorization", "Bearer "+login(t, s, "10.0.0.1"))
+	rec := httptest.NewRecorder()
+	s.handleConfig(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for an admin, got %d", rec.Code)
+	}
+	if body := rec.Body.String(); strings.Contains(body, "secret_value") {
+		t.Errorf("Expected secrets redacted, got %s", body)
+	}
+
+	var got config.Config
+	if err := json.NewDecoder(rec.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.APIKey != "****1234" || got.TTS.FalKey != "****5678" || got.Web.Addr != ":8080" || got.Model != config.DefaultModel {
+		t.Errorf("Unexpected config %+v", got)
+	}
+	if s.cfg.APIKey != "gsk_secret_value_1234" {
+		t.Error("Expected the server's config untouched")
+	}
+}
+
+func TestHandleAudit(t *testing.T) {
+	logger, err := audit.NewLogger(filepath.Join(t.TempDir(), "audit.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"Read", "Grep", "Bash"} {
+		if err := logger.Write(audit.Entry{Tool: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{Web: config.WebConfig{AdminUsers: []string{"alice"}}}
+	s.audit = logger
+	token := login(t, s, "10.0.0.1")
+
+	get := func(target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.handleAudit(rec, r)
+		return rec
+	}
+	if rec := get("/api/audit?limit=0"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad limit, got %d", rec.Code)
+	}
+
+	rec := get("/api/audit?limit=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var body struct {
+		Entries []audit.Entry `json:"entries"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Entries) != 2 || body.Entries[0].Tool != "Bash" || body.Entries[1].Tool != "Grep" {
+		t.Errorf("Expected the two newest entries, got %+v", body.Entries)
+	}
+
+	s.audit = nil
+	if rec := get("/api/audit"); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without an audit log, got %d", rec.Code)
+	}
+}
+
+func TestHandleCreditsSummary(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{Web: config.WebConfig{AdminUsers: []string{"alice"}}}
+	token := login(t, s, "10.0.0.1")
+	for _, id := range []string{"alice", "bob", "carol"} {
+		s.credits.GetOrCreateUser(id, "")
+	}
+	for i, id := range []string{"alice", "alice", "bob"} {
+		if err := s.credits.UseCredits(id, "", "gpt-4o", client.Usage{PromptTokens: 1000 * (i + 1), TotalTokens: 1000 * (i + 1)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		s.handleCreditsSummary(rec, r)
+		return rec
+	}
+	if rec := get("/api/credits/summary?days=-1"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for bad days, got %d", rec.Code)
+	}
+
+	rec := get("/api/credits/summary?days=7&top=1")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", rec.Code)
+	}
+	var body struct {
+		Days        int                  `json:"days"`
+		ActiveUsers int                  `json:"active_users"`
+		Summary     credits.UsageSummary `json:"summary"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Days != 7 || body.ActiveUsers != 2 || body.Summary.Requests != 3 || body.Summary.Tokens != 6000 {
+		t.Errorf("Unexpected summary %+v", body)
+	}
+	if len(body.Summary.Users) != 1 || body.Summary.Users[0].UserID != "alice" {
+		t.Errorf("Expected only the top user, got %+v", body.Summary.Users)
+	}
+	if len(body.Summary.Models) != 1 || body.Summary.Models[0].Model != "gpt-4o" || body.Summary.Models[0].Credits != body.Summary.Credits {
+		t.Errorf("Unexpected model breakdown %+v", body.Summary.Models)
+	}
+}
+
+func TestAdminEndpointsRequireAdminUser(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{Web: config.WebConfig{AdminUsers: []string{"root"}}}
+	vm, err := version.NewManager(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.versions = vm
+	token := login(t, s, "10.0.0.1")
+
+	for _, tc := range []struct {
+		method, target string
+		handler        http.HandlerFunc
+	}{
+		{http.MethodPost, "/api/admin/gc", s.handleAdminGC},
+		{http.MethodGet, "/api/config", s.handleConfig},
+		{http.MethodGet, "/api/admin/backup", s.handleAdminBackup},
+		{http.MethodPost, "/api/admin/restore?force=true", s.handleAdminRestore},
+		{http.MethodPost, "/api/admin/credits/limit", s.handleAdminCreditsLimit},
+		{http.MethodGet, "/api/audit", s.handleAudit},
+		{http.MethodGet, "/api/credits/summary", s.handleCreditsSummary},
+		{http.MethodPost, "/api/admin/credits/reload", s.handleAdminCreditsReload},
+		{http.MethodPost, "/api/mcp/servers", s.handleMCPServers},
+		{http.MethodDelete, "/api/mcp/servers/fs", s.handleMCPServer},
+		{http.MethodPost, "/api/mcp/reload", s.handleMCPReload},
+		{http.MethodPost, "/api/schedules", s.handleSchedules},
+		{http.MethodDelete, "/api/schedules/job-1", s.handleSchedule},
+		{http.MethodPost, "/api/versions/v1/promote", s.handleVersion},
+		{http.MethodGet, "/api/admin/ratelimits", s.handleAdminRateLimits},
+		{http.MethodPost, "/api/notifications/test", s.handleNotificationTest},
+	} {
+		// Loopback is no substitute for an admin account
+		r := httptest.NewRequest(tc.method, tc.target, strings.NewReader("not an archive"))
+		r.RemoteAddr = "127.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		tc.handler(rec, r)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s without a token: expected 401, got %d", tc.target, rec.Code)
+		}
+
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec = httptest.NewRecorder()
+		tc.handler(rec, r)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("%s as a regular user: expected 403, got %d", tc.target, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package web
+
+import (
+	"context"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/tool"
+)
+
+// approve sends an "approval_request" and waits for the "approval_response"
+// with its request ID. When ctx ends first, an "approval_expired" tells the
+// page to withdraw the request.
+func (a *wsAsker) approve(ctx context.Context, req tool.ApprovalRequest) (tool.Decision, error) {
+	replies := make(chan WSMessage, 1)
+	a.mu.Lock()
+	if a.approvals == nil {
+		a.approvals = make(map[string]chan WSMessage)
+	}
+	a.approvals[req.ID] = replies
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		delete(a.approvals, req.ID)
+		a.mu.Unlock()
+	}()
+
+	if err := a.server.sendMessage(a.conn, WSMessage{
+		Type:      "approval_request",
+		RequestID: req.ID,
+		Tool:      req.Tool,
+		Args:      req.Args,
+		Content:   req.Subject,
+	}); err != nil {
+		return tool.Deny, err
+	}
+
+	select {
+	case reply := <-replies:
+		switch reply.Decision {
+		case "approve":
+			return tool.Approve, nil
+		case "always":
+			return tool.ApproveAlways, nil
+		}
+		return tool.Deny, nil
+	case <-ctx.Done():
+		a.server.sendMessage(a.conn, WSMessage{Type: "approval_expired", RequestID: req.ID})
+		return tool.Deny, ctx.Err()
+	}
+}
+
+// approvalReply delivers an "approval_response"; it reports false if no
+// request with its ID is open
+func (a *wsAsker) approvalReply(msg WSMessage) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	replies, ok := a.approvals[msg.RequestID]
+	if !ok {
+		return false
+	}
+	select {
+	case replies <- msg:
+	default:
+	}
+	return true
+}
+
+// setApprovalRules handles "approval_rules": with rules it replaces the
+// session's auto-approve rules and saves them, and either way it answers
+// with the rules in force
+func (s *Server) setApprovalRules(ctx context.Context, conn *websocket.Conn, sess *chatSession, msg WSMessage) {
+	if msg.Rules != nil {
+		if err := sess.approvals.Set(msg.Rules); err != nil {
+			s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+			return
+		}
+		s.saveSession(ctx, sess)
+	}
+	s.sendMessage(conn, WSMessage{Type: "approval_rules", Rules: sess.approvals.List()})
+}
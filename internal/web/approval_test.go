@@ -0,0 +1,76 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+	"groq-go/internal/tool/tools"
+)
+
+// newApprovalTestServer is newTestServer with approval required for AskUser
+func newApprovalTestServer(t *testing.T, up *scriptedUpstream) string {
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+
+	registry := tool.NewRegistry()
+	registry.Register(tools.NewAskUserTool())
+	registry.RequireApproval([]string{"AskUser"})
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func TestApprovalOverWebSocket(t *testing.T) {
+	up := &scriptedUpstream{replies: []client.Delta{
+		askCall("call_1", "First?"),
+		askCall("call_2", "Second?"),
+		askCall("call_3", "Third?"),
+	}}
+	conn := dialTestServer(t, newApprovalTestServer(t, up))
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "go"})
+	req := readUntil(t, conn, "approval_request")
+	if req.Tool != "AskUser" || req.RequestID == "" || !strings.Contains(req.Content, "First?") {
+		t.Fatalf("Unexpected approval request %+v", req)
+	}
+	conn.WriteJSON(WSMessage{Type: "approval_response", RequestID: req.RequestID, Decision: "deny"})
+
+	req = readUntil(t, conn, "approval_request")
+	conn.WriteJSON(WSMessage{Type: "approval_response", RequestID: req.RequestID, Decision: "always"})
+	readUntil(t, conn, "question")
+	conn.WriteJSON(WSMessage{Type: "answer", Content: "ok"})
+
+	// Approved for the session, so the third call asks no more
+	readUntil(t, conn, "question")
+	conn.WriteJSON(WSMessage{Type: "answer", Content: "ok"})
+	readUntil(t, conn, "done")
+
+	up.mu.Lock()
+	last := up.requests[len(up.requests)-1]
+	up.mu.Unlock()
+	if got := toolResult(last, "call_1"); !strings.Contains(got, "declined this AskUser call") {
+		t.Errorf("Expected a declined result, got %q", got)
+	}
+	if got := toolResult(last, "call_2"); got != "User answered: ok" {
+		t.Errorf("Expected the approved call to run, got %q", got)
+	}
+
+	conn.WriteJSON(WSMessage{Type: "approval_rules"})
+	rules := readUntil(t, conn, "approval_rules")
+	if len(rules.Rules) != 1 || rules.Rules[0].Tool != "AskUser" {
+		t.Errorf("Expected an AskUser rule, got %+v", rules.Rules)
+	}
+	conn.WriteJSON(WSMessage{Type: "approval_rules", Rules: []tool.ApprovalRule{{Tool: "Bash", Pattern: "("}}})
+	if msg := readUntil(t, conn, "error"); !strings.Contains(msg.Error, "invalid pattern") {
+		t.Errorf("Expected an invalid rule to be rejected, got %+v", msg)
+	}
+}
@@ -0,0 +1,66 @@
+package web
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/tool"
+)
+
+// wsAsker relays AskUser questions over a WebSocket connection. The turn
+// blocks in ask while the read loop delivers the reply.
+type wsAsker struct {
+	server *Server
+	conn   *websocket.Conn
+
+	mu        sync.Mutex
+	pending   chan WSMessage
+	approvals map[string]chan WSMessage // Open approval requests by ID
+}
+
+// ask sends a "question" message and waits for an "answer" or "answer_cancel"
+func (a *wsAsker) ask(ctx context.Context, q tool.Question) (string, error) {
+	replies := make(chan WSMessage, 1)
+	a.mu.Lock()
+	a.pending = replies
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.pending = nil
+		a.mu.Unlock()
+	}()
+
+	if err := a.server.sendMessage(a.conn, WSMessage{
+		Type:    "question",
+		Content: q.Text,
+		Choices: q.Choices,
+	}); err != nil {
+		return "", err
+	}
+
+	select {
+	case reply := <-replies:
+		if reply.Type == "answer_cancel" {
+			return "", tool.ErrQuestionCancelled
+		}
+		return q.Resolve(reply.Content), nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+// reply delivers a client reply; it reports false if no question is open
+func (a *wsAsker) reply(msg WSMessage) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.pending == nil {
+		return false
+	}
+	select {
+	case a.pending <- msg:
+	default:
+	}
+	return true
+}
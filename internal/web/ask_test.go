@@ -0,0 +1,208 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/client"
+	"groq-go/internal/storage"
+	"groq-go/internal/tool"
+	"groq-go/internal/tool/tools"
+)
+
+// scriptedUpstream is a fake chat completions API that replays one
+// scripted reply per request and records the messages it was sent
+type scriptedUpstream struct {
+	mu       sync.Mutex
+	replies  []client.Delta
+	requests [][]client.Message
+	models   []string
+}
+
+func (u *scriptedUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req client.ChatCompletionRequest
+	json.NewDecoder(r.Body).Decode(&req)
+
+	u.mu.Lock()
+	n := len(u.requests)
+	u.requests = append(u.requests, req.Messages)
+	u.models = append(u.models, req.Model)
+	u.mu.Unlock()
+
+	delta := client.Delta{Content: "done"}
+	if n < len(u.replies) {
+		delta = u.replies[n]
+	}
+	finish := "stop"
+	if len(delta.ToolCalls) > 0 {
+		finish = "tool_calls"
+	}
+	chunk := client.StreamChunk{Choices: []client.Choice{{Delta: &delta, FinishReason: finish}}}
+	data, _ := json.Marshal(chunk)
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+}
+
+func askCall(id, question string, choices ...string) client.Delta {
+	args, _ := json.Marshal(map[string]any{"question": question, "choices": choices})
+	return client.Delta{ToolCalls: []client.ToolCall{{
+		ID:       id,
+		Type:     "function",
+		Function: client.FunctionCall{Name: "AskUser", Arguments: string(args)},
+	}}}
+}
+
+// newTestServer starts a web server backed by the scripted upstream and
+// returns its WebSocket URL
+func newTestServer(t *testing.T, up *scriptedUpstream) string {
+	return newTestServerWithStorage(t, up, nil)
+}
+
+// newTestServerWithStorage is newTestServer with session storage
+func newTestServerWithStorage(t *testing.T, up *scriptedUpstream, store storage.Storage) string {
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+
+	registry := tool.NewRegistry()
+	registry.Register(tools.NewAskUserTool())
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+		storage:  store,
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+func dialTestServer(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial failed: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// readUntil reads messages until one of the given type arrives
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) WSMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Waiting for %q: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func toolResult(msgs []client.Message, id string) string {
+	for _, m := range msgs {
+		if m.Role == "tool" && m.ToolCallID == id {
+			content, _ := m.Content.(string)
+			return content
+		}
+	}
+	return ""
+}
+
+func TestAskUserMidTurn(t *testing.T) {
+	up := &scriptedUpstream{replies: []client.Delta{
+		askCall("call_1", "Which branch?", "main", "dev"),
+		{Content: "Deploying dev"},
+	}}
+	conn := dialTestServer(t, newTestServer(t, up))
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "deploy it"})
+	q := readUntil(t, conn, "question")
+	if q.Content != "Which branch?" || len(q.Choices) != 2 {
+		t.Fatalf("Unexpected question: %+v", q)
+	}
+
+	// Answer by choice number
+	conn.WriteJSON(WSMessage{Type: "answer", Content: "2"})
+	readUntil(t, conn, "done")
+
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	if len(up.requests) != 2 {
+		t.Fatalf("Expected the turn to continue with one more request, got %d", len(up.requests))
+	}
+	history := up.requests[1]
+	if got := toolResult(history, "call_1"); got != "User answered: dev" {
+		t.Errorf("Expected answer in history, got %q", got)
+	}
+	if !strings.Contains(history[len(history)-2].ToolCalls[0].Function.Arguments, "Which branch?") {
+		t.Error("Expected question to be recorded in history")
+	}
+}
+
+func TestAskUserCancelAndCap(t *testing.T) {
+	up := &scriptedUpstream{replies: []client.Delta{
+		askCall("call_1", "First?"),
+		askCall("call_2", "Second?"),
+		askCall("call_3", "Third?"),
+		askCall("call_4", "Fourth?"),
+	}}
+	conn := dialTestServer(t, newTestServer(t, up))
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "go"})
+	readUntil(t, conn, "question")
+	conn.WriteJSON(WSMessage{Type: "answer_cancel"})
+	for i := 0; i < tool.MaxQuestionsPerTurn-1; i++ {
+		readUntil(t, conn, "question")
+		conn.WriteJSON(WSMessage{Type: "answer", Content: "ok"})
+	}
+	readUntil(t, conn, "done")
+
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	last := up.requests[len(up.requests)-1]
+	if got := toolResult(last, "call_1"); !strings.Contains(got, "declined") {
+		t.Errorf("Expected cancelled question result, got %q", got)
+	}
+	if got := toolResult(last, "call_4"); !strings.Contains(got, tool.ErrTooManyQuestions.Error()) {
+		t.Errorf("Expected question cap result, got %q", got)
+	}
+}
+
+func TestToolsChangedPushed(t *testing.T) {
+	upstream := httptest.NewServer(&scriptedUpstream{})
+	t.Cleanup(upstream.Close)
+
+	registry := tool.NewRegistry()
+	registry.Register(tools.NewAskUserTool())
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	conn := dialTestServer(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	readUntil(t, conn, "system")
+
+	registry.Register(tools.NewGlobTool())
+	msg := readUntil(t, conn, "tools_changed")
+	if strings.Join(msg.Tools, ",") != "AskUser,Glob" {
+		t.Errorf("Expected both tools listed, got %v", msg.Tools)
+	}
+
+	registry.Unregister("Glob")
+	msg = readUntil(t, conn, "tools_changed")
+	if strings.Join(msg.Tools, ",") != "AskUser" {
+		t.Errorf("Expected Glob removed, got %v", msg.Tools)
+	}
+}
@@ -0,0 +1,185 @@
+package web
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// newAuthTestServer serves the full mux behind authMiddleware
+func newAuthTestServer(t *testing.T, withUser bool) (*Server, *httptest.Server) {
+	t.Helper()
+	s := newIdentityTestServer(t, withUser)
+	s.storage = newShareTestServer(t).storage
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ws", s.handleWebSocket)
+	mux.HandleFunc("/api/sessions", s.handleSessions)
+	mux.HandleFunc("/api/credits", s.handleCredits)
+	mux.HandleFunc("/api/auth/login", s.handleLogin)
+	mux.HandleFunc("/api/auth/status", s.handleAuthStatus)
+	mux.HandleFunc("/share/", s.handleSharedView)
+	mux.HandleFunc("/api/whoami", func(w http.ResponseWriter, r *http.Request) {
+		username, _ := requestUsername(r)
+		w.Write([]byte(username))
+	})
+	srv := httptest.NewServer(s.authMiddleware(mux))
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func authGet(t *testing.T, url, token string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	s, srv := newAuthTestServer(t, true)
+	token := login(t, s, "127.0.0.1")
+
+	s.auth.SetTokenTTL(-time.Minute)
+	expired := login(t, s, "127.0.0.1")
+
+	tests := []struct {
+		path  string
+		token string
+		want  int
+	}{
+		{"/api/sessions", "", http.StatusUnauthorized},
+		{"/api/sessions", "bogus", http.StatusUnauthorized},
+		{"/api/sessions", expired, http.StatusUnauthorized},
+		{"/api/sessions", token, http.StatusOK},
+		{"/api/credits", "", http.StatusUnauthorized},
+		{"/api/credits", expired, http.StatusUnauthorized},
+		{"/api/credits", token, http.StatusOK},
+		{"/api/auth/status", "", http.StatusOK},
+		{"/share/missing", "", http.StatusNotFound}, // public, reaches the handler
+	}
+	for _, tt := range tests {
+		if resp := authGet(t, srv.URL+tt.path, tt.token); resp.StatusCode != tt.want {
+			t.Errorf("GET %s with token %q: got %d, want %d", tt.path, tt.token, resp.StatusCode, tt.want)
+		}
+	}
+
+	resp := authGet(t, srv.URL+"/api/sessions", "")
+	if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Error("Expected WWW-Authenticate header on 401")
+	}
+}
+
+func TestAuthMiddlewareStoresUsername(t *testing.T) {
+	s, srv := newAuthTestServer(t, true)
+	token := login(t, s, "127.0.0.1")
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/whoami", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	buf := new(strings.Builder)
+	if _, err := io.Copy(buf, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "alice" {
+		t.Errorf("Expected username alice in context, got %q", buf.String())
+	}
+}
+
+func TestAuthMiddlewareWebSocket(t *testing.T) {
+	s, srv := newAuthTestServer(t, true)
+	s.client = client.New("test-key")
+	s.registry = tool.NewRegistry()
+	token := login(t, s, "127.0.0.1")
+	s.auth.SetTokenTTL(-time.Minute)
+	expired := login(t, s, "127.0.0.1")
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	for _, bad := range []string{"", "?token=bogus", "?token=" + expired} {
+		_, resp, err := websocket.DefaultDialer.Dial(url+bad, nil)
+		if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+			t.Errorf("Dial %q: expected 401, got err=%v", bad, err)
+		}
+	}
+
+	conn := dialTestServer(t, url+"?token="+token)
+	readUntil(t, conn, "system")
+	settle(t, conn)
+}
+
+func TestAuthMiddlewareOpenWithoutUsers(t *testing.T) {
+	_, srv := newAuthTestServer(t, false)
+	if resp := authGet(t, srv.URL+"/api/sessions", ""); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected open API without users, got %d", resp.StatusCode)
+	}
+}
+
+func TestAuthRefreshAndPasswordEndpoints(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	token := login(t, s, "127.0.0.1")
+
+	post := func(handler http.HandlerFunc, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	if rec := post(s.handleAuthRefresh, token, ""); rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "expires_at") {
+		t.Errorf("Refresh: %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(s.handleAuthRefresh, "bogus", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 refreshing an unknown token, got %d", rec.Code)
+	}
+
+	if rec := post(s.handleAuthPassword, token, `{"old_password":"wrong","new_password":"x"}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a wrong old password, got %d", rec.Code)
+	}
+	rec := post(s.handleAuthPassword, token, `{"old_password":"secret","new_password":"rotated"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Change password: %d %s", rec.Code, rec.Body.String())
+	}
+	if _, err := s.auth.ValidateToken(token); err == nil {
+		t.Error("Expected the old token to be revoked")
+	}
+	if _, err := s.auth.Authenticate("alice", "rotated"); err != nil {
+		t.Errorf("Expected the new password to work, got %v", err)
+	}
+}
+
+func TestWebSocketChecksOrigin(t *testing.T) {
+	url := newTestServer(t, &scriptedUpstream{})
+	httpURL := "http" + strings.TrimPrefix(url, "ws")
+
+	for _, origin := range []string{httpURL + ".evil.com", "http://evil.com", "null", "http://user@" + strings.TrimPrefix(httpURL, "http://")} {
+		_, resp, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {origin}})
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Origin %q: expected 403, got err=%v", origin, err)
+		}
+	}
+
+	conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {httpURL}})
+	if err != nil {
+		t.Fatalf("Expected the same host to connect, got %v", err)
+	}
+	conn.Close()
+}
@@ -0,0 +1,61 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// blockingTool runs until its context is canceled
+type blockingTool struct {
+	canceled chan struct{}
+}
+
+func (t *blockingTool) Name() string               { return "Block" }
+func (t *blockingTool) Description() string        { return "" }
+func (t *blockingTool) Parameters() map[string]any { return nil }
+
+func (t *blockingTool) Execute(ctx context.Context, args json.RawMessage) (tool.Result, error) {
+	<-ctx.Done()
+	close(t.canceled)
+	return tool.NewErrorResult("canceled"), nil
+}
+
+func TestDisconnectCancelsRunningTool(t *testing.T) {
+	up := &scriptedUpstream{replies: []client.Delta{{ToolCalls: []client.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: client.FunctionCall{Name: "Block", Arguments: "{}"},
+	}}}}}
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+
+	bt := &blockingTool{canceled: make(chan struct{})}
+	registry := tool.NewRegistry()
+	registry.Register(bt)
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+
+	conn := dialTestServer(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "block"})
+	readUntil(t, conn, "tool_call")
+	conn.Close()
+
+	select {
+	case <-bt.canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the tool to be canceled when the client disconnected")
+	}
+}
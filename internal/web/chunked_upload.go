@@ -0,0 +1,173 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"groq-go/internal/upload"
+)
+
+// handleChunkedUpload routes the chunked upload API:
+//
+//	POST /api/upload/init
+//	PUT  /api/upload/{id}/chunk/{n}
+//	POST /api/upload/{id}/complete
+//	GET  /api/upload/{id}/status
+//	DELETE /api/upload/{id}
+func (s *Server) handleChunkedUpload(w http.ResponseWriter, r *http.Request) {
+	if s.uploads == nil {
+		http.Error(w, "Chunked uploads not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/api/upload/")
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	// Uploads belong to the account when signed in, so they resume on
+	// another network
+	userID, err := s.resolveUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case len(parts) == 1 && parts[0] == "init":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.handleUploadInit(w, r, userID)
+
+	case len(parts) == 3 && parts[1] == "chunk":
+		if r.Method != http.MethodPut {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			http.Error(w, "Invalid chunk number", http.StatusBadRequest)
+			return
+		}
+		s.handleUploadChunk(w, r, userID, parts[0], n)
+
+	case len(parts) == 2 && parts[1] == "complete":
+		if r.Method != http.MethodPost {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		finalPath, err := s.uploads.Complete(parts[0], userID)
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		log.Info("Chunked upload completed", "id", parts[0], "path", finalPath)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "completed",
+			"path":   finalPath,
+		})
+
+	case len(parts) == 2 && parts[1] == "status":
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		session, received, err := s.uploads.Status(parts[0], userID)
+		if err != nil {
+			uploadError(w, err)
+			return
+		}
+		if received == nil {
+			received = []int{}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"upload_id":    session.ID,
+			"name":         session.Name,
+			"size":         session.Size,
+			"chunk_size":   session.ChunkSize,
+			"total_chunks": session.TotalChunks,
+			"received":     received,
+		})
+
+	case len(parts) == 1 && parts[0] != "":
+		if r.Method != http.MethodDelete {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if err := s.uploads.Abort(parts[0], userID); err != nil {
+			uploadError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "aborted"})
+
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
+func (s *Server) handleUploadInit(w http.ResponseWriter, r *http.Request, userID string) {
+	var req struct {
+		Name   string `json:"name"`
+		Size   int64  `json:"size"`
+		SHA256 string `json:"sha256"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	session, err := s.uploads.Init(userID, req.Name, req.Size, req.SHA256)
+	if err != nil {
+		uploadError(w, err)
+		return
+	}
+	log.Info("Chunked upload started", "id", session.ID, "user_id", userID, "size", session.Size)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"upload_id":    session.ID,
+		"chunk_size":   session.ChunkSize,
+		"total_chunks": session.TotalChunks,
+	})
+}
+
+func (s *Server) handleUploadChunk(w http.ResponseWriter, r *http.Request, userID, id string, n int) {
+	// The chunk hash comes from a header so the body can be streamed as-is
+	sha := r.Header.Get("X-Chunk-SHA256")
+	if sha == "" {
+		sha = r.URL.Query().Get("sha256")
+	}
+
+	body := http.MaxBytesReader(w, r.Body, s.uploads.ChunkSize+1)
+	if err := s.uploads.WriteChunk(id, userID, n, sha, body); err != nil {
+		uploadError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status": "received",
+		"chunk":  n,
+	})
+}
+
+// uploadError maps upload errors to HTTP status codes
+func uploadError(w http.ResponseWriter, err error) {
+	status := http.StatusBadRequest
+	switch {
+	case errors.Is(err, upload.ErrNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, upload.ErrQuotaExceeded):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, upload.ErrIncomplete):
+		status = http.StatusConflict
+	case errors.Is(err, upload.ErrChecksumMismatch), errors.Is(err, upload.ErrInvalidChunk):
+		status = http.StatusUnprocessableEntity
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"groq-go/internal/upload"
+)
+
+func TestChunkedUploadResumesAfterIPChange(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	uploads, err := upload.NewManager(filepath.Join(t.TempDir(), "uploads"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads.ChunkSize = 4
+	s.uploads = uploads
+	token := login(t, s, "10.0.0.1")
+
+	content := []byte("hello, world")
+	sum := sha256.Sum256(content)
+	do := func(method, target, ip, token string, body []byte) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		r.RemoteAddr = ip + ":1234"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		if strings.Contains(target, "/chunk/") {
+			chunkSum := sha256.Sum256(body)
+			r.Header.Set("X-Chunk-SHA256", hex.EncodeToString(chunkSum[:]))
+		}
+		rec := httptest.NewRecorder()
+		s.handleChunkedUpload(rec, r)
+		return rec
+	}
+
+	initBody, _ := json.Marshal(map[string]any{"name": "notes.txt", "size": len(content), "sha256": hex.EncodeToString(sum[:])})
+	if rec := do(http.MethodPost, "/api/upload/init", "10.0.0.1", "", initBody); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", rec.Code)
+	}
+	rec := do(http.MethodPost, "/api/upload/init", "10.0.0.1", token, initBody)
+	var started struct {
+		UploadID string `json:"upload_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&started); err != nil || started.UploadID == "" {
+		t.Fatalf("Expected an upload ID, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPut, "/api/upload/"+started.UploadID+"/chunk/0", "10.0.0.1", token, content[:4]); rec.Code != http.StatusOK {
+		t.Fatalf("Chunk 0: %d %s", rec.Code, rec.Body.String())
+	}
+
+	// The client moves to another network and picks up where it left off
+	const newIP = "203.0.113.9"
+	rec = do(http.MethodGet, "/api/upload/"+started.UploadID+"/status", newIP, token, nil)
+	var status struct {
+		Received []int `json:"received"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&status); err != nil || len(status.Received) != 1 {
+		t.Fatalf("Expected the upload found from the new IP, got %d %+v", rec.Code, status)
+	}
+	for n, chunk := range [][]byte{content[4:8], content[8:]} {
+		target := "/api/upload/" + started.UploadID + "/chunk/" + strconv.Itoa(n+1)
+		if rec := do(http.MethodPut, target, newIP, token, chunk); rec.Code != http.StatusOK {
+			t.Fatalf("Chunk %d: %d %s", n+1, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := do(http.MethodPost, "/api/upload/"+started.UploadID+"/complete", newIP, token, nil); rec.Code != http.StatusOK {
+		t.Errorf("Expected the upload to complete, got %d %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"groq-go/internal/conversation"
+	"groq-go/internal/storage"
+)
+
+// maxImportBytes caps a conversation uploaded to POST /api/sessions/import
+const maxImportBytes = 16 << 20
+
+// handleSessionExport serves GET /api/sessions/{id}/export?format=md|json
+// as a download of a session of userID
+func (s *Server) handleSessionExport(w http.ResponseWriter, r *http.Request, id, userID string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !validSessionID(id) {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = conversation.FormatMarkdown
+	}
+	contentType := map[string]string{
+		conversation.FormatMarkdown: "text/markdown; charset=utf-8",
+		conversation.FormatJSON:     "application/json",
+	}[format]
+	if contentType == "" {
+		http.Error(w, "format must be md or json", http.StatusBadRequest)
+		return
+	}
+
+	session, err := s.loadOwnedSession(r.Context(), id, userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if session == nil {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return
+	}
+	data, err := conversation.Export(session.Messages, format, session.Title)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+"."+format))
+	w.Write(data)
+}
+
+// handleSessionImport serves POST /api/sessions/import, storing a JSON
+// export as a new session of userID that their WebSocket can resume
+func (s *Server) handleSessionImport(w http.ResponseWriter, r *http.Request, userID string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Import exceeds %d bytes", maxImportBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return
+	}
+	t, err := conversation.Import(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	session := &storage.Session{ID: newSessionID(), Title: t.Title, Messages: t.Messages, OwnerID: userID}
+	if session.Title == "" {
+		session.Title = "Imported conversation"
+	}
+	if err := s.storage.SaveSession(r.Context(), session); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]any{"id": session.ID, "title": session.Title, "messages": len(session.Messages)})
+}
@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+	"groq-go/internal/storage"
+)
+
+func TestSessionExportImport(t *testing.T) {
+	s := newShareTestServer(t)
+	messages := []client.Message{
+		client.NewVisionMessage("user", "what is this?", "data:image/png;base64,iVBORw0KGgo="),
+		{Role: "assistant", ToolCalls: []client.ToolCall{{ID: "call_1", Type: "function", Function: client.FunctionCall{Name: "Read", Arguments: `{"file_path":"a.txt"}`}}}},
+		{Role: "tool", ToolCallID: "call_1", Content: "hello"},
+		client.NewTextMessage("assistant", "A greeting."),
+	}
+	if err := s.storage.SaveSession(context.Background(), &storage.Session{ID: "ws-abc", Title: "Greeting", Messages: messages, OwnerID: testUserID}); err != nil {
+		t.Fatal(err)
+	}
+
+	call := func(method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleSession(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := call(http.MethodGet, "/api/sessions/ws-abc/export", "")
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/markdown") {
+		t.Fatalf("Expected markdown by default, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	if body := rec.Body.String(); !strings.HasPrefix(body, "# Greeting\n") || !strings.Contains(body, "Tool call: Read (call_1)") {
+		t.Errorf("Unexpected markdown:\n%s", body)
+	}
+	if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename="ws-abc.md"` {
+		t.Errorf("Unexpected Content-Disposition %q", got)
+	}
+
+	rec = call(http.MethodGet, "/api/sessions/ws-abc/export?format=json", "")
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected JSON, got %d %s", rec.Code, rec.Header().Get("Content-Type"))
+	}
+	exported := rec.Body.String()
+	var transcript conversation.Transcript
+	if err := json.Unmarshal([]byte(exported), &transcript); err != nil || transcript.Version != conversation.TranscriptVersion {
+		t.Errorf("Expected a versioned transcript, got %v: %s", err, exported)
+	}
+
+	// Importing the export creates a new session with the same messages
+	rec = call(http.MethodPost, "/api/sessions/import", exported)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID       string `json:"id"`
+		Messages int    `json:"messages"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	if created.ID == "ws-abc" || !validSessionID(created.ID) || created.Messages != len(messages) {
+		t.Fatalf("Unexpected import response %+v", created)
+	}
+	imported, err := s.storage.LoadSession(context.Background(), created.ID)
+	if err != nil || imported == nil {
+		t.Fatalf("Expected the imported session stored, got %v", err)
+	}
+	if imported.Title != "Greeting" || !reflect.DeepEqual(imported.Messages, messages) {
+		t.Errorf("Imported session differs: %+v", imported)
+	}
+
+	for _, tt := range []struct {
+		method, target, body string
+		want                 int
+	}{
+		{http.MethodGet, "/api/sessions/ws-abc/export?format=pdf", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/sessions/ws-missing/export", "", http.StatusNotFound},
+		{http.MethodGet, "/api/sessions/bad.id/export", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/sessions/ws-abc/export", "", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/api/sessions/import", "", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/api/sessions/import", "# Greeting", http.StatusBadRequest},
+		{http.MethodPost, "/api/sessions/import", `[{"role": "tool", "tool_call_id": "x", "content": "y"}]`, http.StatusBadRequest},
+	} {
+		if rec := call(tt.method, tt.target, tt.body); rec.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.target, tt.want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// buildInfo describes the running binary from its embedded build settings
+func buildInfo() map[string]string {
+	info := map[string]string{}
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	info["go"] = bi.GoVersion
+	if bi.Main.Version != "" {
+		info["version"] = bi.Main.Version
+	}
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info["revision"] = s.Value
+		case "vcs.time":
+			info["commit_time"] = s.Value
+		case "vcs.modified":
+			info["modified"] = s.Value
+		}
+	}
+	return info
+}
+
+// handleHealthz reports that the server is up: GET /healthz
+func (s *Server) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]any{
+		"status":         "ok",
+		"build":          buildInfo(),
+		"started_at":     s.startedAt.UTC().Format(time.RFC3339),
+		"uptime_seconds": int64(time.Since(s.startedAt).Seconds()),
+	})
+}
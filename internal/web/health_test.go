@@ -0,0 +1,42 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHealthz(t *testing.T) {
+	s := &Server{startedAt: time.Now().Add(-time.Minute)}
+
+	rec := httptest.NewRecorder()
+	s.handleHealthz(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", rec.Code)
+	}
+	var body struct {
+		Status        string            `json:"status"`
+		Build         map[string]string `json:"build"`
+		UptimeSeconds int64             `json:"uptime_seconds"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Status != "ok" {
+		t.Errorf("Expected status ok, got %q", body.Status)
+	}
+	if body.UptimeSeconds < 60 {
+		t.Errorf("Expected at least 60s uptime, got %d", body.UptimeSeconds)
+	}
+	if body.Build["go"] == "" {
+		t.Error("Expected the Go version in the build info")
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleHealthz(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405 for POST, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,147 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/auth"
+)
+
+// errUnauthenticated is returned when users are configured but the request
+// carries no valid token
+var errUnauthenticated = errors.New("authentication required")
+
+// requestClientIP returns the client address. Forwarding headers are only
+// believed from a proxy on a private or loopback address: Fly-Client-IP,
+// which Fly's proxy sets, or else the last X-Forwarded-For hop, the one the
+// nearest proxy appended. Earlier hops are whatever the client sent. The
+// port is dropped so the IP scheme is stable across connections.
+func requestClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if ip := net.ParseIP(host); ip == nil || !(ip.IsPrivate() || ip.IsLoopback()) {
+		return host
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("Fly-Client-IP"))); ip != nil {
+		return ip.String()
+	}
+	if fwdFor := r.Header.Get("X-Forwarded-For"); fwdFor != "" {
+		hops := strings.Split(fwdFor, ",")
+		if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); ip != nil {
+			return ip.String()
+		}
+	}
+	return host
+}
+
+// ipUserID derives the anonymous user ID used when auth has no users
+func ipUserID(clientIP string) string {
+	return "user_" + strings.ReplaceAll(strings.ReplaceAll(clientIP, ".", "_"), ":", "_")
+}
+
+// accountUserID is the credits user ID of an authenticated account. The
+// username is escaped since it becomes a file name.
+func accountUserID(username string) string {
+	return "acct_" + url.PathEscape(username)
+}
+
+// wsAuthProtocol is the WebSocket subprotocol that carries a token as
+// the second protocol, e.g. new WebSocket(url, ["bearer", token])
+const wsAuthProtocol = "bearer"
+
+// requestToken returns the Bearer token. WebSocket handshakes may send it
+// as the token query parameter or the bearer subprotocol instead, since
+// browsers cannot set headers there.
+func requestToken(r *http.Request) string {
+	if token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "); token != "" {
+		return token
+	}
+	if !websocket.IsWebSocketUpgrade(r) {
+		return ""
+	}
+	if token := r.URL.Query().Get("token"); token != "" {
+		return token
+	}
+	if protocols := websocket.Subprotocols(r); len(protocols) == 2 && protocols[0] == wsAuthProtocol {
+		return protocols[1]
+	}
+	return ""
+}
+
+type contextKey int
+
+const usernameKey contextKey = iota
+
+// withUsername stores the authenticated username in ctx
+func withUsername(ctx context.Context, username string) context.Context {
+	return context.WithValue(ctx, usernameKey, username)
+}
+
+// requestUsername returns the username authMiddleware stored, if any
+func requestUsername(r *http.Request) (string, bool) {
+	username, ok := r.Context().Value(usernameKey).(string)
+	return username, ok
+}
+
+// authRequired reports whether requests must carry a valid token
+func (s *Server) authRequired() bool {
+	return s.auth != nil && s.auth.HasUsers()
+}
+
+// authenticate validates the request's token
+func (s *Server) authenticate(r *http.Request) (*auth.User, error) {
+	token := requestToken(r)
+	if s.auth == nil || token == "" {
+		return nil, errUnauthenticated
+	}
+	user, err := s.auth.ValidateToken(token)
+	if err != nil {
+		return nil, errUnauthenticated
+	}
+	return user, nil
+}
+
+// resolveUserID returns the credits user ID for a request. When users are
+// configured a valid token is required; otherwise the IP scheme is used.
+func (s *Server) resolveUserID(r *http.Request) (string, error) {
+	if username, ok := requestUsername(r); ok {
+		return accountUserID(username), nil
+	}
+	if !s.authRequired() {
+		return ipUserID(requestClientIP(r)), nil
+	}
+	user, err := s.authenticate(r)
+	if err != nil {
+		return "", err
+	}
+	return accountUserID(user.Username), nil
+}
+
+// migrateAnonymousCredits moves the IP-based balance of the client into
+// username's account the first time the account logs in
+func (s *Server) migrateAnonymousCredits(r *http.Request, username string) {
+	if s.credits == nil {
+		return
+	}
+	toID := accountUserID(username)
+	if s.credits.GetUserInfo(toID) != nil {
+		return
+	}
+	fromID := ipUserID(requestClientIP(r))
+	moved, err := s.credits.MergeUsers(fromID, toID)
+	if err != nil {
+		log.Warn("Failed to migrate anonymous credits", "from", fromID, "to", toID, "error", err)
+		return
+	}
+	if moved > 0 {
+		log.Info("Migrated anonymous credits", "from", fromID, "to", toID, "credits", moved)
+	}
+}
@@ -0,0 +1,204 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/auth"
+	"groq-go/internal/client"
+	"groq-go/internal/credits"
+	"groq-go/internal/tool"
+)
+
+// newIdentityTestServer returns a server with credits and, when withUser is
+// set, an auth manager holding the user alice
+func newIdentityTestServer(t *testing.T, withUser bool) *Server {
+	t.Helper()
+	t.Setenv("HOME", t.TempDir())
+	authManager, err := auth.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if withUser {
+		if err := authManager.CreateUser("alice", "secret"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	creditsManager, err := credits.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Server{auth: authManager, credits: creditsManager}
+}
+
+func login(t *testing.T, s *Server, ip string) string {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/login", strings.NewReader(`{"username":"alice","password":"secret"}`))
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	s.handleLogin(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Login: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Token string `json:"token"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	return resp.Token
+}
+
+func TestRequestClientIP(t *testing.T) {
+	for _, tc := range []struct {
+		name, remote, fly, fwdFor, want string
+	}{
+		{"direct", "203.0.113.5:1234", "", "", "203.0.113.5"},
+		{"direct with spoofed headers", "203.0.113.5:1234", "198.51.100.1", "198.51.100.2", "203.0.113.5"},
+		{"fly proxy", "172.16.0.2:1234", "203.0.113.5", "198.51.100.2, 203.0.113.5", "203.0.113.5"},
+		{"proxy appended hop", "10.0.0.2:1234", "", "198.51.100.2, 203.0.113.5", "203.0.113.5"},
+		{"proxy with bad header", "127.0.0.1:1234", "", "not-an-ip", "127.0.0.1"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.RemoteAddr = tc.remote
+		if tc.fly != "" {
+			r.Header.Set("Fly-Client-IP", tc.fly)
+		}
+		if tc.fwdFor != "" {
+			r.Header.Set("X-Forwarded-For", tc.fwdFor)
+		}
+		if got := requestClientIP(r); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func getCredits(s *Server, ip, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/api/credits", nil)
+	req.RemoteAddr = ip + ":1234"
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.handleCredits(rec, req)
+	return rec
+}
+
+func TestCreditsFollowToken(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+
+	// Anonymous usage before the account logs in
+	s.credits.GetOrCreateUser(ipUserID("10.0.0.1"), "")
+	if err := s.credits.AddCredits(ipUserID("10.0.0.1"), 25, "buy", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec := getCredits(s, "10.0.0.1", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := getCredits(s, "10.0.0.1", "bogus"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an invalid token, got %d", rec.Code)
+	}
+
+	token := login(t, s, "10.0.0.1")
+
+	// The same account from another address sees the migrated balance
+	rec := getCredits(s, "10.0.0.2", token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Credits: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		UserID  string `json:"user_id"`
+		Balance int    `json:"balance"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.UserID != "acct_alice" {
+		t.Errorf("Expected acct_alice, got %q", resp.UserID)
+	}
+	if want := credits.FreeCreditsForNewUser + 25; resp.Balance != want {
+		t.Errorf("Expected migrated balance %d, got %d", want, resp.Balance)
+	}
+
+	// Logging in again does not migrate a second anonymous balance
+	s.credits.GetOrCreateUser(ipUserID("10.0.0.3"), "")
+	login(t, s, "10.0.0.3")
+	if got := s.credits.GetBalance("acct_alice"); got != resp.Balance {
+		t.Errorf("Expected balance to stay %d, got %d", resp.Balance, got)
+	}
+}
+
+func TestCreditsFallBackToIP(t *testing.T) {
+	s := newIdentityTestServer(t, false)
+
+	rec := getCredits(s, "10.0.0.9", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Credits: status %d", rec.Code)
+	}
+	var resp struct {
+		UserID string `json:"user_id"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.UserID != "user_10_0_0_9" {
+		t.Errorf("Expected IP-based user ID, got %q", resp.UserID)
+	}
+}
+
+func TestAccountUserIDEscapesUsername(t *testing.T) {
+	if got := accountUserID("../evil"); strings.Contains(got, "/") {
+		t.Errorf("Expected no path separator in %q", got)
+	}
+}
+
+func TestWebSocketToken(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	s.client = client.New("test-key")
+	s.registry = tool.NewRegistry()
+	token := login(t, s, "127.0.0.1")
+
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	// Token in the query string
+	conn := dialTestServer(t, url+"?token="+token)
+	if msg := readUntil(t, conn, "system"); !strings.Contains(msg.Content, "Credits:") {
+		t.Errorf("Expected welcome with credits, got %q", msg.Content)
+	}
+	settle(t, conn)
+	if s.credits.GetUserInfo("acct_alice") == nil {
+		t.Error("Expected credits under the account")
+	}
+
+	// Token as the bearer subprotocol
+	dialer := websocket.Dialer{Subprotocols: []string{wsAuthProtocol, token}}
+	conn, _, err := dialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("Dial with subprotocol failed: %v", err)
+	}
+	defer conn.Close()
+	if conn.Subprotocol() != wsAuthProtocol {
+		t.Errorf("Expected %q subprotocol, got %q", wsAuthProtocol, conn.Subprotocol())
+	}
+	readUntil(t, conn, "system")
+	settle(t, conn)
+
+	// No token: the upgrade is refused
+	_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 handshake, got err=%v resp=%v", err, resp)
+	}
+}
+
+// settle waits until the connection's worker handles a message, so the
+// handler is past its setup before the test ends
+func settle(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	if err := conn.WriteJSON(WSMessage{Type: "clear"}); err != nil {
+		t.Fatal(err)
+	}
+	for readUntil(t, conn, "system").Content != "Conversation cleared" {
+	}
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"groq-go/internal/tool"
+)
+
+// imageTypes are the generated image formats served by /api/images/
+var imageTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// imageName validates a file name from /api/images/{name}: a plain image
+// file name, never a path
+func imageName(name string) (string, bool) {
+	if name == "" || name != filepath.Base(name) || strings.ContainsAny(name, `/\`) || strings.HasPrefix(name, ".") {
+		return "", false
+	}
+	contentType, ok := imageTypes[strings.ToLower(filepath.Ext(name))]
+	return contentType, ok
+}
+
+// handleImage serves an image generated by ImageGen from the images directory
+func (s *Server) handleImage(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/api/images/")
+	contentType, ok := imageName(name)
+	if !ok {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+
+	// Only regular files; a link could point outside the directory
+	path := filepath.Join(s.imageDir, name)
+	if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "Image not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "Failed to read image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+// imageMessage turns a tool result carrying a generated image into an
+// "image" message. Images in the images directory are linked through
+// /api/images/; others, saved elsewhere with output_path, fall back to
+// the inline thumbnail.
+func (s *Server) imageMessage(toolName string, result tool.Result) (WSMessage, bool) {
+	path := result.Metadata[tool.MetadataImagePath]
+	if path == "" || result.IsError {
+		return WSMessage{}, false
+	}
+
+	msg := WSMessage{Type: "image", Tool: toolName, Content: filepath.Base(path)}
+	if _, ok := imageName(filepath.Base(path)); ok && filepath.Dir(filepath.Clean(path)) == filepath.Clean(s.imageDir) {
+		msg.URL = "/api/images/" + filepath.Base(path)
+	} else if thumb := result.Metadata[tool.MetadataImageThumb]; thumb != "" {
+		msg.URL = "data:image/png;base64," + thumb
+	} else {
+		return WSMessage{}, false
+	}
+	return msg, true
+}
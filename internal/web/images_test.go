@@ -0,0 +1,82 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"groq-go/internal/tool"
+)
+
+func TestHandleImage(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{imageDir: filepath.Join(dir, "images")}
+	os.MkdirAll(s.imageDir, 0755)
+	os.WriteFile(filepath.Join(s.imageDir, "image_1.png"), []byte("\x89PNG\r\n\x1a\npixels"), 0644)
+	os.WriteFile(filepath.Join(s.imageDir, ".hidden.png"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(s.imageDir, "notes.txt"), []byte("x"), 0644)
+	os.WriteFile(filepath.Join(dir, "secret.png"), []byte("secret"), 0644)
+	os.Symlink(filepath.Join(dir, "secret.png"), filepath.Join(s.imageDir, "link.png"))
+
+	rec := httptest.NewRecorder()
+	s.handleImage(rec, httptest.NewRequest("GET", "/api/images/image_1.png", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("Content-Type") != "image/png" || rec.Body.String() != "\x89PNG\r\n\x1a\npixels" {
+		t.Errorf("Expected the image served, got %d %q", rec.Code, rec.Header().Get("Content-Type"))
+	}
+
+	for _, path := range []string{
+		"/api/images/",
+		"/api/images/../secret.png",
+		"/api/images/..%2Fsecret.png",
+		"/api/images/sub/image_1.png",
+		"/api/images/..\\secret.png",
+		"/api/images/.hidden.png",
+		"/api/images/notes.txt",
+		"/api/images/link.png",
+		"/api/images/missing.png",
+	} {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", "/api/images/x.png", nil)
+		req.URL.Path = path // Bypass client-side cleaning, as a raw request would
+		s.handleImage(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: expected 404, got %d %q", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	s.handleImage(rec, httptest.NewRequest("DELETE", "/api/images/image_1.png", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected DELETE to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestImageMessage(t *testing.T) {
+	s := &Server{imageDir: "/home/u/.config/groq-go/images"}
+	result := tool.NewResult("Image generated")
+	result.Metadata = map[string]string{
+		tool.MetadataImagePath:  "/home/u/.config/groq-go/images/image_42.png",
+		tool.MetadataImageThumb: "dGh1bWI=",
+	}
+
+	msg, ok := s.imageMessage("ImageGen", result)
+	if !ok || msg.Type != "image" || msg.URL != "/api/images/image_42.png" || msg.Content != "image_42.png" {
+		t.Errorf("Expected a link to the served image, got %+v", msg)
+	}
+
+	// Saved elsewhere with output_path: the thumbnail is all the client gets
+	result.Metadata[tool.MetadataImagePath] = "/tmp/out/cat.png"
+	if msg, ok = s.imageMessage("ImageGen", result); !ok || msg.URL != "data:image/png;base64,dGh1bWI=" {
+		t.Errorf("Expected the thumbnail inline, got %+v", msg)
+	}
+
+	delete(result.Metadata, tool.MetadataImageThumb)
+	if _, ok = s.imageMessage("ImageGen", result); ok {
+		t.Error("Expected no message without a servable image or thumbnail")
+	}
+	if _, ok = s.imageMessage("Read", tool.NewResult("text")); ok {
+		t.Error("Expected no message for results without an image")
+	}
+}
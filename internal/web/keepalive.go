@@ -0,0 +1,54 @@
+package web
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket keepalive. Proxies drop idle connections without closing
+// them, so the server pings and gives up on clients that stop answering.
+// Variables so tests can shorten them.
+var (
+	// pingInterval is the time between pings
+	pingInterval = 30 * time.Second
+	// pongWait is how long a connection may stay silent, pongs included
+	pongWait = 75 * time.Second
+	// writeWait bounds every write to a client
+	writeWait = 10 * time.Second
+)
+
+// keepAlive pings conn every pingInterval until stop is closed. Each pong
+// extends the read deadline, so the read loop fails once the client has
+// been silent for pongWait. A failed ping closes conn right away. Call
+// the returned function to extend the deadline after other messages.
+func keepAlive(conn *websocket.Conn, stop <-chan struct{}) func() {
+	interval, wait, deadline := pingInterval, pongWait, writeWait
+	extend := func() {
+		conn.SetReadDeadline(time.Now().Add(wait))
+	}
+	extend()
+	conn.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				// WriteControl may run concurrently with other writes
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(deadline)); err != nil {
+					log.Debug("WebSocket ping failed", "error", err)
+					conn.Close()
+					return
+				}
+			}
+		}
+	}()
+	return extend
+}
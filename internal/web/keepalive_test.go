@@ -0,0 +1,98 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+func shortKeepAlive(t *testing.T) {
+	origPing, origPong := pingInterval, pongWait
+	pingInterval, pongWait = 20*time.Millisecond, 150*time.Millisecond
+	t.Cleanup(func() { pingInterval, pongWait = origPing, origPong })
+}
+
+// waitConnections waits until the server tracks n WebSocket connections
+func waitConnections(t *testing.T, s *Server, n int64, within time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(within)
+	for s.metrics.connections.Load() != n {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected %d connections within %s, have %d", n, within, s.metrics.connections.Load())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestKeepAliveDropsUnresponsiveClient(t *testing.T) {
+	shortKeepAlive(t)
+
+	up := &scriptedUpstream{replies: []client.Delta{{ToolCalls: []client.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: client.FunctionCall{Name: "Block", Arguments: "{}"},
+	}}}}}
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+
+	bt := &blockingTool{canceled: make(chan struct{})}
+	registry := tool.NewRegistry()
+	registry.Register(bt)
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+
+	conn := dialTestServer(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "block"})
+	readUntil(t, conn, "tool_call")
+
+	// The client stops reading, so pings go unanswered, like a
+	// connection a proxy dropped
+	start := time.Now()
+	select {
+	case <-bt.canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the running tool to be canceled once the client stopped answering")
+	}
+	if elapsed := time.Since(start); elapsed < pongWait/2 {
+		t.Errorf("Expected the connection to get about %s, torn down after %s", pongWait, elapsed)
+	}
+	waitConnections(t, s, 0, time.Second)
+}
+
+func TestKeepAliveKeepsResponsiveClient(t *testing.T) {
+	shortKeepAlive(t)
+	s := &Server{client: client.New("test-key"), registry: tool.NewRegistry()}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+
+	conn := dialTestServer(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	closed := make(chan error, 1)
+	go func() {
+		// Reading answers pings
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				closed <- err
+				return
+			}
+		}
+	}()
+
+	select {
+	case err := <-closed:
+		t.Fatalf("Expected an idle but responsive client to stay connected, got %v", err)
+	case <-time.After(4 * pongWait):
+	}
+	if n := s.metrics.connections.Load(); n != 1 {
+		t.Errorf("Expected 1 connection, have %d", n)
+	}
+}
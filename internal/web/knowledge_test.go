@@ -0,0 +1,101 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/knowledge"
+	"groq-go/internal/project"
+)
+
+func TestUpdateKnowledgeDocument(t *testing.T) {
+	kb, err := knowledge.NewKnowledgeBase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, _ := kb.AddDocument(context.Background(), "notes.md", "old content")
+	s := &Server{knowledge: kb}
+
+	put := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleKnowledgeDocument(rec, httptest.NewRequest(http.MethodPut, "/api/knowledge/"+id, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := put(doc.ID, `{"content":"new content"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated knowledge.Document
+	json.NewDecoder(rec.Body).Decode(&updated)
+	if updated.ID != doc.ID || updated.Name != "notes.md" || updated.Content != "new content" || updated.UpdatedAt.IsZero() {
+		t.Errorf("Unexpected document %+v", updated)
+	}
+
+	if rec := put("missing", `{"content":"x"}`); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown document, got %d", rec.Code)
+	}
+	if rec := put(doc.ID, `{"name":"only-name.md"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 without content, got %d", rec.Code)
+	}
+}
+
+func TestIngestProject(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm, err := project.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	for name, content := range map[string]string{"main.go": "package main", "README.md": "# Demo"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	proj, err := pm.Create("demo", root, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kb, err := knowledge.NewKnowledgeBase(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{knowledge: kb, projects: pm}
+
+	ingest := func(id, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleProject(rec, httptest.NewRequest(http.MethodPost, "/api/projects/"+id+"/ingest", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := ingest(proj.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var result knowledge.IngestResult
+	json.NewDecoder(rec.Body).Decode(&result)
+	if result != (knowledge.IngestResult{Added: 2}) {
+		t.Errorf("Unexpected result %+v", result)
+	}
+
+	rec = ingest(proj.ID, `{"include":["*.go"]}`)
+	json.NewDecoder(rec.Body).Decode(&result)
+	if result != (knowledge.IngestResult{Skipped: 1, Removed: 1}) {
+		t.Errorf("Expected README.md dropped by the include glob, got %+v", result)
+	}
+
+	if rec := ingest("missing", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for an unknown project, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.handleProject(rec, httptest.NewRequest(http.MethodGet, "/api/projects/"+proj.ID+"/ingest", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rec.Code)
+	}
+}
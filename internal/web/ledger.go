@@ -0,0 +1,72 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"groq-go/internal/credits"
+)
+
+// handleCreditHistory pages through a user's ledger, newest first. limit
+// defaults to 50 and is capped at 500; from and to are optional
+// YYYY-MM-DD dates in UTC, to inclusive.
+func (s *Server) handleCreditHistory(w http.ResponseWriter, r *http.Request, userID string) {
+	q := r.URL.Query()
+	limit, offset := 50, 0
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, 500)
+	}
+	if v := q.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid offset", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+	var from, to time.Time
+	for name, dst := range map[string]*time.Time{"from": &from, "to": &to} {
+		v := q.Get(name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			http.Error(w, "Invalid "+name+" date, want YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+		*dst = t
+	}
+	if !to.IsZero() {
+		to = to.AddDate(0, 0, 1)
+	}
+
+	txs, err := s.credits.GetLedger(userID, from, to)
+	if err != nil {
+		log.Error("Failed to read ledger", "user_id", userID, "error", err)
+		http.Error(w, "Failed to read transaction history", http.StatusInternalServerError)
+		return
+	}
+	slices.Reverse(txs)
+	total := len(txs)
+	page := txs[min(offset, total):min(offset+limit, total)]
+	if page == nil {
+		page = []credits.Transaction{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"transactions": page,
+		"total":        total,
+		"offset":       offset,
+		"limit":        limit,
+	})
+}
@@ -0,0 +1,62 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/credits"
+)
+
+func TestCreditHistoryPagination(t *testing.T) {
+	s := newIdentityTestServer(t, false)
+	userID := ipUserID("10.0.0.1")
+	s.credits.GetOrCreateUser(userID, "")
+	for i := 0; i < 5; i++ {
+		if err := s.credits.UseCredits(userID, "10.0.0.1", "gpt-4o", client.Usage{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(target string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		s.handleCreditAction(rec, r)
+		return rec
+	}
+	type page struct {
+		Transactions []credits.Transaction `json:"transactions"`
+		Total        int                   `json:"total"`
+	}
+	decode := func(rec *httptest.ResponseRecorder) page {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var p page
+		if err := json.NewDecoder(rec.Body).Decode(&p); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+
+	first := decode(get("/api/credits/history?limit=4"))
+	if first.Total != 6 || len(first.Transactions) != 4 || first.Transactions[0].Type != "use" {
+		t.Errorf("Expected the 4 newest of 6 entries, got %+v", first)
+	}
+	rest := decode(get("/api/credits/history?limit=4&offset=4"))
+	if len(rest.Transactions) != 2 || rest.Transactions[1].Type != "free" {
+		t.Errorf("Expected the welcome bonus last, got %+v", rest.Transactions)
+	}
+	if past := decode(get("/api/credits/history?to=2020-01-01")); past.Total != 0 || past.Transactions == nil {
+		t.Errorf("Expected an empty page before any activity, got %+v", past)
+	}
+	for _, bad := range []string{"?limit=0", "?offset=-1", "?from=yesterday"} {
+		if rec := get("/api/credits/history" + bad); rec.Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s, got %d", bad, rec.Code)
+		}
+	}
+}
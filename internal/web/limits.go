@@ -0,0 +1,364 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"groq-go/internal/client"
+	"groq-go/internal/credits"
+	"groq-go/internal/ids"
+	"groq-go/internal/textutil"
+	"groq-go/internal/tool"
+)
+
+// Resource bounds for web mode. Variables so tests can shrink them.
+var (
+	// maxConnections caps concurrent WebSocket connections per instance
+	maxConnections int64 = 50
+	// maxHistoryBytes caps the in-memory history of a single connection
+	maxHistoryBytes int64 = 2 << 20
+	// maxToolResultBytes is the size tool results are cut to when compaction alone is not enough
+	maxToolResultBytes = 4096
+)
+
+// connRetryAfter is the Retry-After value (seconds) sent when the connection cap is reached
+const connRetryAfter = "30"
+
+// uploadRefPrefix marks image references spilled to the upload directory
+const uploadRefPrefix = "upload://"
+
+// missingImageText stands in for a spilled image whose file is gone
+const missingImageText = "[image no longer available]"
+
+// wsMetrics tracks aggregate WebSocket resource usage
+type wsMetrics struct {
+	connections  atomic.Int64
+	historyBytes atomic.Int64
+}
+
+// acquire reserves a connection slot, returning false if the cap is reached
+func (m *wsMetrics) acquire() bool {
+	for {
+		n := m.connections.Load()
+		if n >= maxConnections {
+			return false
+		}
+		if m.connections.CompareAndSwap(n, n+1) {
+			return true
+		}
+	}
+}
+
+// release frees a connection slot
+func (m *wsMetrics) release() {
+	m.connections.Add(-1)
+}
+
+// connHistory is the bounded message history of one WebSocket connection
+type connHistory struct {
+	messages []client.Message
+	bytes    int64
+	maxBytes int64
+	metrics  *wsMetrics
+}
+
+func newConnHistory(metrics *wsMetrics, system client.Message) *connHistory {
+	h := &connHistory{maxBytes: maxHistoryBytes, metrics: metrics}
+	h.Append(system)
+	return h
+}
+
+// Messages returns the current history
+func (h *connHistory) Messages() []client.Message {
+	return h.messages
+}
+
+// SetSystem replaces the system prompt
+func (h *connHistory) SetSystem(msg client.Message) {
+	h.add(-messageBytes(h.messages[0]))
+	h.messages[0] = msg
+	h.add(messageBytes(msg))
+}
+
+// Append adds messages and compacts the history if it exceeds its cap
+func (h *connHistory) Append(msgs ...client.Message) {
+	for _, msg := range msgs {
+		h.messages = append(h.messages, msg)
+		h.add(messageBytes(msg))
+	}
+	if h.bytes > h.maxBytes {
+		h.compact()
+	}
+}
+
+// Replace swaps in msgs, e.g. a summarized history. The first message must
+// be the system prompt.
+func (h *connHistory) Replace(msgs []client.Message) {
+	h.add(-h.bytes)
+	h.messages = msgs
+	for _, msg := range msgs {
+		h.add(messageBytes(msg))
+	}
+}
+
+// Clear drops everything but the system prompt
+func (h *connHistory) Clear() {
+	for _, msg := range h.messages[1:] {
+		h.add(-messageBytes(msg))
+	}
+	h.messages = h.messages[:1]
+}
+
+// Release removes this history from the aggregate metrics
+func (h *connHistory) Release() {
+	h.add(-h.bytes)
+	h.messages = nil
+}
+
+// Bytes returns the approximate in-memory size of the history
+func (h *connHistory) Bytes() int64 {
+	return h.bytes
+}
+
+func (h *connHistory) add(n int64) {
+	h.bytes += n
+	if h.metrics != nil {
+		h.metrics.historyBytes.Add(n)
+	}
+}
+
+// compact drops the oldest turns (keeping the system prompt and the latest
+// turn intact so tool calls stay paired with their results), then truncates
+// oversized tool results if the history is still too large.
+func (h *connHistory) compact() {
+	before := h.bytes
+	for h.bytes > h.maxBytes {
+		next := -1
+		for i := 2; i < len(h.messages); i++ {
+			if h.messages[i].Role == "user" {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		for _, msg := range h.messages[1:next] {
+			h.add(-messageBytes(msg))
+		}
+		h.messages = append(h.messages[:1], h.messages[next:]...)
+	}
+
+	if h.bytes > h.maxBytes {
+		for i, msg := range h.messages {
+			content, ok := msg.Content.(string)
+			if msg.Role != "tool" || !ok || len(content) <= maxToolResultBytes {
+				continue
+			}
+			h.add(-messageBytes(msg))
+			msg.Content = textutil.TruncateBytes(content, maxToolResultBytes) + "\n... [truncated to save memory]"
+			h.messages[i] = msg
+			h.add(messageBytes(msg))
+		}
+	}
+
+	log.Info("Compacted connection history", "before_bytes", before, "after_bytes", h.bytes, "messages", len(h.messages))
+}
+
+// messageBytes approximates the memory held by a message
+func messageBytes(msg client.Message) int64 {
+	n := len(msg.Role) + len(msg.ToolCallID)
+	switch c := msg.Content.(type) {
+	case string:
+		n += len(c)
+	case []client.ContentPart:
+		for _, part := range c {
+			n += len(part.Text)
+			if part.ImageURL != nil {
+				n += len(part.ImageURL.URL)
+			}
+		}
+	}
+	for _, tc := range msg.ToolCalls {
+		n += len(tc.ID) + len(tc.Function.Name) + len(tc.Function.Arguments)
+	}
+	return int64(n)
+}
+
+// spillImage writes a base64 data URL to the upload directory and returns a reference to it
+func (s *Server) spillImage(dataURL string) (string, error) {
+	// References are only created by the server; uploads are sent by ID
+	if strings.HasPrefix(dataURL, uploadRefPrefix) {
+		return "", fmt.Errorf("invalid image data")
+	}
+	header, data, ok := strings.Cut(dataURL, ",")
+	if !ok || !strings.HasPrefix(header, "data:") || !strings.HasSuffix(header, ";base64") {
+		// Remote URLs are small; keep them as they are
+		return dataURL, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", fmt.Errorf("invalid image data: %w", err)
+	}
+
+	mime := strings.TrimSuffix(strings.TrimPrefix(header, "data:"), ";base64")
+	ext := ".bin"
+	if sub, ok := strings.CutPrefix(mime, "image/"); ok && sub != "" && !strings.ContainsAny(sub, "/\\.") {
+		ext = "." + sub
+	}
+
+	name := fmt.Sprintf("wsimg_%d_%s%s", timeNow().UnixNano(), ids.New(12), ext)
+	if err := os.WriteFile(filepath.Join(s.uploadDir, name), raw, 0644); err != nil {
+		return "", fmt.Errorf("failed to save image: %w", err)
+	}
+	return uploadRefPrefix + mime + ";" + name, nil
+}
+
+// resolveImages returns a copy of messages with spilled image references
+// inlined as data URLs for the provider request; references to missing
+// files become a text placeholder
+func (s *Server) resolveImages(messages []client.Message) []client.Message {
+	resolved := messages
+	copied := false
+	for i, msg := range messages {
+		parts, ok := msg.Content.([]client.ContentPart)
+		if !ok {
+			continue
+		}
+		var newParts []client.ContentPart
+		for j, part := range parts {
+			if part.ImageURL == nil || !strings.HasPrefix(part.ImageURL.URL, uploadRefPrefix) {
+				continue
+			}
+			ref := strings.TrimPrefix(part.ImageURL.URL, uploadRefPrefix)
+			mime, name, _ := strings.Cut(ref, ";")
+			if newParts == nil {
+				newParts = append([]client.ContentPart(nil), parts...)
+			}
+			data, err := os.ReadFile(filepath.Join(s.uploadDir, filepath.Base(name)))
+			if err != nil {
+				// The provider would reject the whole request over the raw reference
+				log.Warn("Spilled image missing", "name", name, "error", err)
+				newParts[j] = client.ContentPart{Type: "text", Text: missingImageText}
+				continue
+			}
+			newParts[j].ImageURL = &client.ImageURL{
+				URL:    "data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(data),
+				Detail: part.ImageURL.Detail,
+			}
+		}
+		if newParts == nil {
+			continue
+		}
+		if !copied {
+			resolved = append([]client.Message(nil), messages...)
+			copied = true
+		}
+		resolved[i].Content = newParts
+	}
+	return resolved
+}
+
+// rejectConnection tells a client the connection cap is reached
+func rejectConnection(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", connRetryAfter)
+	http.Error(w, fmt.Sprintf("Server is at capacity (%d connections), please retry later", maxConnections), http.StatusServiceUnavailable)
+}
+
+// handleMetrics reports resource usage of the web server
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"ws_connections":       s.metrics.connections.Load(),
+		"ws_max_connections":   maxConnections,
+		"ws_history_bytes":     s.metrics.historyBytes.Load(),
+		"ws_max_history_bytes": maxHistoryBytes,
+		"ws_parked_sessions":   s.parked.len(),
+	})
+}
+
+// creditError returns why userID can't afford a request to model with a
+// prompt of promptBytes, as a user-facing message, or "" if they can
+func (s *Server) creditError(userID, clientIP, model string, promptBytes int) string {
+	hasCredits, balance, cost, err := s.credits.CheckCredits(userID, clientIP, model, promptBytes)
+	if err != nil {
+		if !errors.Is(err, credits.ErrDailyLimit) {
+			log.Warn("Failed to check daily limits", "user_id", userID, "error", err)
+		}
+		return limitMessage(err)
+	}
+	if !hasCredits {
+		return fmt.Sprintf("Insufficient credits: need at least %d, have %d. Please add more credits.", cost, balance)
+	}
+	return ""
+}
+
+// userBilling bills paid calls, such as transcriptions, to a user
+type userBilling struct {
+	s                *Server
+	userID, clientIP string
+}
+
+func (b userBilling) Check(model string) error {
+	if msg := b.s.creditError(b.userID, b.clientIP, model, 0); msg != "" {
+		return errors.New(msg)
+	}
+	return nil
+}
+
+func (b userBilling) Charge(model string) {
+	if err := b.s.credits.UseCredits(b.userID, b.clientIP, model, client.Usage{}); err != nil {
+		log.Warn("Failed to deduct credits", "user_id", b.userID, "model", model, "error", err)
+	}
+}
+
+// limitMessage turns a CheckCredits error into a user-facing message
+func limitMessage(err error) string {
+	var limitErr *credits.LimitError
+	if errors.As(err, &limitErr) {
+		return fmt.Sprintf("Daily limit reached, resets at %s UTC.", limitErr.ResetAt.UTC().Format("15:04"))
+	}
+	return "Unable to check usage limits. Please try again later."
+}
+
+// toolImageMessage collects the images tools returned under
+// tool.MetadataImage into a user message for vision models. Images are
+// spilled to disk like uploaded ones. It reports false when there are none
+// or the model can't see them.
+func (s *Server) toolImageMessage(model string, calls []client.ToolCall, results []tool.Result) (client.Message, bool) {
+	if !client.SupportsVision(model) {
+		return client.Message{}, false
+	}
+	var refs, names []string
+	for i, result := range results {
+		img := result.Metadata[tool.MetadataImage]
+		if img == "" {
+			continue
+		}
+		ref, err := s.spillImage(img)
+		if err != nil {
+			log.Warn("Failed to keep tool image", "tool", calls[i].Function.Name, "error", err)
+			continue
+		}
+		refs = append(refs, ref)
+		names = append(names, calls[i].Function.Name)
+	}
+	if len(refs) == 0 {
+		return client.Message{}, false
+	}
+	text := fmt.Sprintf("Images returned by %s, in order:", strings.Join(names, ", "))
+	return client.NewVisionMessage("user", text, refs...), true
+}
@@ -0,0 +1,229 @@
+package web
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+func TestConnectionCap(t *testing.T) {
+	s := &Server{}
+	orig := maxConnections
+	maxConnections = 2
+	t.Cleanup(func() { maxConnections = orig })
+
+	if !s.metrics.acquire() || !s.metrics.acquire() {
+		t.Fatal("Expected first two connections to be accepted")
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleWebSocket(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected status 503, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("Expected Retry-After header")
+	}
+	if n := s.metrics.connections.Load(); n != 2 {
+		t.Errorf("Rejected connection changed the count: %d", n)
+	}
+
+	s.metrics.release()
+	if !s.metrics.acquire() {
+		t.Error("Expected a slot after release")
+	}
+}
+
+func TestHistoryCompactionKeepsLatestTurn(t *testing.T) {
+	orig := maxHistoryBytes
+	maxHistoryBytes = 1000
+	t.Cleanup(func() { maxHistoryBytes = orig })
+
+	var metrics wsMetrics
+	h := newConnHistory(&metrics, client.Message{Role: "system", Content: "sys"})
+	for i := 0; i < 10; i++ {
+		h.Append(client.Message{Role: "user", Content: fmt.Sprintf("question %d %s", i, strings.Repeat("x", 200))})
+		h.Append(client.Message{Role: "assistant", ToolCalls: []client.ToolCall{{ID: fmt.Sprintf("call_%d", i)}}})
+		h.Append(client.Message{Role: "tool", ToolCallID: fmt.Sprintf("call_%d", i), Content: strings.Repeat("r", 200)})
+	}
+
+	if h.Bytes() > maxHistoryBytes {
+		t.Errorf("History is %d bytes, cap is %d", h.Bytes(), maxHistoryBytes)
+	}
+	msgs := h.Messages()
+	if msgs[0].Role != "system" || msgs[1].Role != "user" {
+		t.Errorf("Expected system prompt followed by a user turn, got %s, %s", msgs[0].Role, msgs[1].Role)
+	}
+	last := msgs[len(msgs)-1]
+	if last.ToolCallID != "call_9" {
+		t.Errorf("Expected latest tool result to be kept, got %q", last.ToolCallID)
+	}
+	if metrics.historyBytes.Load() != h.Bytes() {
+		t.Errorf("Metrics out of sync: %d vs %d", metrics.historyBytes.Load(), h.Bytes())
+	}
+
+	h.Release()
+	if metrics.historyBytes.Load() != 0 {
+		t.Errorf("Expected metrics to drop to 0 after release, got %d", metrics.historyBytes.Load())
+	}
+}
+
+func TestSpilledImagesResolve(t *testing.T) {
+	s := &Server{uploadDir: t.TempDir()}
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("fake png"))
+
+	ref, err := s.spillImage(dataURL)
+	if err != nil {
+		t.Fatalf("spillImage failed: %v", err)
+	}
+	if !strings.HasPrefix(ref, uploadRefPrefix) {
+		t.Fatalf("Expected upload reference, got %q", ref)
+	}
+
+	history := []client.Message{client.NewVisionMessage("user", "look", ref)}
+	resolved := s.resolveImages(history)
+
+	parts := resolved[0].Content.([]client.ContentPart)
+	if parts[1].ImageURL.URL != dataURL {
+		t.Errorf("Expected data URL to be restored, got %q", parts[1].ImageURL.URL)
+	}
+	// The stored history keeps only the reference
+	if history[0].Content.([]client.ContentPart)[1].ImageURL.URL != ref {
+		t.Error("resolveImages modified the stored history")
+	}
+}
+
+func TestMissingSpilledImageBecomesText(t *testing.T) {
+	s := &Server{uploadDir: t.TempDir()}
+	history := []client.Message{client.NewVisionMessage("user", "look", uploadRefPrefix+"image/png;wsimg_gone.png")}
+
+	parts := s.resolveImages(history)[0].Content.([]client.ContentPart)
+	if parts[1].ImageURL != nil || parts[1].Type != "text" || parts[1].Text != missingImageText {
+		t.Errorf("Expected a placeholder for the missing image, got %+v", parts[1])
+	}
+	if history[0].Content.([]client.ContentPart)[1].ImageURL == nil {
+		t.Error("resolveImages modified the stored history")
+	}
+}
+
+func TestToolImageMessage(t *testing.T) {
+	s := &Server{uploadDir: t.TempDir()}
+	dataURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("fake png"))
+	calls := []client.ToolCall{
+		{Function: client.FunctionCall{Name: "Read"}},
+		{Function: client.FunctionCall{Name: "Glob"}},
+	}
+	image := tool.NewResult("[image: /tmp/a.png]")
+	image.Metadata = map[string]string{tool.MetadataImage: dataURL}
+	results := []tool.Result{image, tool.NewResult("a.png")}
+
+	if _, ok := s.toolImageMessage("llama-3.3-70b-versatile", calls, results); ok {
+		t.Error("Expected no image message for a text-only model")
+	}
+	msg, ok := s.toolImageMessage("claude-sonnet-4-20250514", calls, results)
+	if !ok || msg.Role != "user" || !msg.HasImages() {
+		t.Fatalf("Expected a user message with the image, got %+v", msg)
+	}
+	parts := s.resolveImages([]client.Message{msg})[0].Content.([]client.ContentPart)
+	if len(parts) != 2 || parts[0].Text != "Images returned by Read, in order:" || parts[1].ImageURL.URL != dataURL {
+		t.Errorf("Unexpected message parts %+v", parts)
+	}
+	if _, ok := s.toolImageMessage("claude-sonnet-4-20250514", calls[1:], results[1:]); ok {
+		t.Error("Expected no image message without images")
+	}
+}
+
+// TestSoakMemoryBounded simulates many connections sending large messages
+// and images, and checks the heap stays within the configured bounds.
+func TestSoakMemoryBounded(t *testing.T) {
+	const (
+		conns    = 20
+		turns    = 40
+		msgSize  = 64 << 10
+		imgSize  = 96 << 10
+		capBytes = 256 << 10
+	)
+
+	orig := maxHistoryBytes
+	maxHistoryBytes = capBytes
+	t.Cleanup(func() { maxHistoryBytes = orig })
+
+	s := &Server{uploadDir: t.TempDir()}
+	image := "data:image/png;base64," + base64.StdEncoding.EncodeToString(make([]byte, imgSize))
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	histories := make([]*connHistory, conns)
+	for c := range histories {
+		histories[c] = newConnHistory(&s.metrics, client.Message{Role: "system", Content: "sys"})
+	}
+	for turn := 0; turn < turns; turn++ {
+		for _, h := range histories {
+			ref, err := s.spillImage(image)
+			if err != nil {
+				t.Fatalf("spillImage failed: %v", err)
+			}
+			h.Append(client.NewVisionMessage("user", strings.Repeat("u", msgSize), ref))
+			h.Append(client.Message{Role: "assistant", Content: strings.Repeat("a", msgSize)})
+		}
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+
+	total := s.metrics.historyBytes.Load()
+	if total > conns*capBytes {
+		t.Errorf("Aggregate history is %d bytes, expected at most %d", total, conns*capBytes)
+	}
+
+	// Unbounded, this would retain conns*turns*(2*msgSize+base64 image) ≈ 150MB
+	var grown int64
+	if after.HeapAlloc > before.HeapAlloc {
+		grown = int64(after.HeapAlloc - before.HeapAlloc)
+	}
+	if limit := int64(4 * conns * capBytes); grown > limit {
+		t.Errorf("Heap grew by %d bytes, expected under %d", grown, limit)
+	}
+
+	for _, h := range histories {
+		h.Release()
+	}
+	if n := s.metrics.historyBytes.Load(); n != 0 {
+		t.Errorf("Expected 0 history bytes after release, got %d", n)
+	}
+}
+
+func TestToolResultTruncationKeepsRunes(t *testing.T) {
+	origTool, origHistory := maxToolResultBytes, maxHistoryBytes
+	maxToolResultBytes, maxHistoryBytes = 7, 200
+	t.Cleanup(func() { maxToolResultBytes, maxHistoryBytes = origTool, origHistory })
+
+	// Two-byte runes put byte 7 in the middle of one
+	result := strings.Repeat("é", 150)
+	h := newConnHistory(&wsMetrics{}, client.Message{Role: "system", Content: "sys"})
+	h.Append(client.Message{Role: "user", Content: "question"})
+	h.Append(client.Message{Role: "assistant", ToolCalls: []client.ToolCall{{ID: "call_1"}}})
+	h.Append(client.Message{Role: "tool", ToolCallID: "call_1", Content: result})
+
+	msgs := h.Messages()
+	got := msgs[len(msgs)-1].Content.(string)
+	if !utf8.ValidString(got) || !strings.HasPrefix(got, "ééé\n") {
+		t.Errorf("Expected the result cut between runes, got %q", got)
+	}
+
+	saved := sessionMessages(msgs[:3], 100)
+	saved = sessionMessages(append(saved, client.Message{Role: "tool", ToolCallID: "call_1", Content: result}), 100)
+	if got := saved[len(saved)-1].Content.(string); !utf8.ValidString(got) || !strings.HasPrefix(got, "ééé\n") {
+		t.Errorf("Expected the saved result cut between runes, got %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"groq-go/internal/mcp"
+)
+
+// SetMCPManager enables the /api/mcp endpoints for adding and removing
+// MCP servers at runtime
+func (s *Server) SetMCPManager(m *mcp.Manager) {
+	s.mcp = m
+}
+
+// handleMCPServers lists and adds MCP servers. Starting a server runs an
+// arbitrary command, so every method requires a user listed in
+// web.admin_users.
+func (s *Server) handleMCPServers(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.mcp == nil {
+		http.Error(w, "MCP manager not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		servers := s.mcp.Servers()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"servers": servers,
+			"count":   len(servers),
+		})
+
+	case http.MethodPost:
+		var req struct {
+			Name string `json:"name"`
+			mcp.ServerConfig
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Name == "" || req.Command == "" {
+			http.Error(w, "Server name and command are required", http.StatusBadRequest)
+			return
+		}
+
+		if err := s.mcp.AddServer(r.Context(), req.Name, req.ServerConfig); err != nil {
+			log.Warn("Failed to add MCP server", "name", req.Name, "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info("Added MCP server", "name", req.Name)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"status": "added"})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMCPServer removes a server: DELETE /api/mcp/servers/{name}
+func (s *Server) handleMCPServer(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.mcp == nil {
+		http.Error(w, "MCP manager not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/api/mcp/servers/")
+	if name == "" {
+		http.Error(w, "Server name required", http.StatusBadRequest)
+		return
+	}
+	if err := s.mcp.RemoveServer(name); err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	log.Info("Removed MCP server", "name", name)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// handleMCPReload re-reads mcp.json and applies the differences
+func (s *Server) handleMCPReload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.mcp == nil {
+		http.Error(w, "MCP manager not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	result, err := s.mcp.Reload(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Info("Reloaded MCP config", "added", result.Added, "removed", result.Removed, "restarted", result.Restarted)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,88 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"groq-go/internal/memory"
+)
+
+// SetMemory enables the /api/memory endpoints and adds each user's
+// memories to their system prompt
+func (s *Server) SetMemory(store *memory.Store) {
+	s.memory = store
+}
+
+// handleMemories lists the memories of the requesting user:
+// GET /api/memory
+func (s *Server) handleMemories(w http.ResponseWriter, r *http.Request) {
+	if s.memory == nil {
+		http.Error(w, "Memory not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	userID, err := s.resolveUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"memories": s.memory.List(userID)})
+}
+
+// handleMemory reads or forgets one memory of the requesting user:
+// /api/memory/{key}
+func (s *Server) handleMemory(w http.ResponseWriter, r *http.Request) {
+	if s.memory == nil {
+		http.Error(w, "Memory not available", http.StatusServiceUnavailable)
+		return
+	}
+	userID, err := s.resolveUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, "/api/memory/")
+	if key == "" {
+		http.Error(w, "Memory key required", http.StatusBadRequest)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		m, err := s.memory.Get(userID, key)
+		if errors.Is(err, memory.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m)
+
+	case http.MethodDelete:
+		err := s.memory.Delete(userID, key)
+		if errors.Is(err, memory.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to delete memory", "user_id", userID, "key", key, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Info("Deleted memory", "user_id", userID, "key", key)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
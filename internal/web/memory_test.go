@@ -0,0 +1,72 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/memory"
+)
+
+func newMemoryTestServer(t *testing.T) (*Server, *memory.Store) {
+	t.Helper()
+	store, err := memory.Open(filepath.Join(t.TempDir(), "memory.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{}
+	s.SetMemory(store)
+	return s, store
+}
+
+func TestMemoryEndpoints(t *testing.T) {
+	s, store := newMemoryTestServer(t)
+	alice, bob := ipUserID("10.0.0.1"), ipUserID("10.0.0.2")
+	store.Set(alice, "package_manager", "pnpm", memory.FromUser)
+	store.Set(alice, "language", "Japanese", memory.FromModel)
+	store.Set(bob, "language", "English", memory.FromUser)
+
+	rec := shareRequest(s, s.handleMemories, http.MethodGet, "/api/memory", "10.0.0.1", "")
+	var resp struct {
+		Memories []memory.Memory `json:"memories"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Memories) != 2 || resp.Memories[0].Key != "language" || resp.Memories[0].Origin != memory.FromModel {
+		t.Errorf("Expected alice's memories, most recent first, got %+v", resp.Memories)
+	}
+
+	if rec := shareRequest(s, s.handleMemory, http.MethodGet, "/api/memory/language", "10.0.0.2", ""); !strings.Contains(rec.Body.String(), "English") {
+		t.Errorf("Expected bob's own memory, got %d %s", rec.Code, rec.Body.String())
+	}
+	if rec := shareRequest(s, s.handleMemory, http.MethodDelete, "/api/memory/package_manager", "10.0.0.2", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected bob not to reach alice's memory, got %d", rec.Code)
+	}
+	if rec := shareRequest(s, s.handleMemory, http.MethodDelete, "/api/memory/package_manager", "10.0.0.1", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rec.Code)
+	}
+	if got := store.List(alice); len(got) != 1 {
+		t.Errorf("Expected one memory left, got %+v", got)
+	}
+	if rec := shareRequest(s, s.handleMemories, http.MethodPost, "/api/memory", "10.0.0.1", "{}"); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
+
+func TestSystemPromptIncludesMemory(t *testing.T) {
+	s, store := newMemoryTestServer(t)
+	store.Set("alice", "tests", "live in /e2e", memory.FromUser)
+
+	for _, mode := range []string{"tools", "improve"} {
+		prompt := s.getSystemPrompt(&chatSession{mode: mode, userID: "alice"})
+		if !strings.HasSuffix(prompt, "\n\n"+store.PromptSection("alice", 0)) {
+			t.Errorf("%s: expected the memory section last, got ...%s", mode, prompt[len(prompt)-200:])
+		}
+	}
+	if prompt := s.getSystemPrompt(&chatSession{mode: "tools", userID: "bob"}); strings.Contains(prompt, "User Memory") {
+		t.Error("Expected no memory section for a user without memories")
+	}
+}
@@ -0,0 +1,74 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/config"
+)
+
+func TestHandleModelsListsConfiguredProviders(t *testing.T) {
+	s := &Server{client: client.New("groq-key", client.WithProviderKey("anthropic", "claude-key"))}
+	rec := httptest.NewRecorder()
+	s.handleModels(rec, httptest.NewRequest(http.MethodGet, "/api/models", nil))
+
+	var body struct {
+		Models  []client.ModelInfo `json:"models"`
+		Current string             `json:"current"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Current != client.DefaultModel {
+		t.Errorf("Expected current model %s, got %s", client.DefaultModel, body.Current)
+	}
+	providers := map[string]bool{}
+	for _, m := range body.Models {
+		providers[m.Provider] = true
+	}
+	if !providers["groq"] || !providers["anthropic"] {
+		t.Errorf("Expected Groq and Anthropic models, got %v", providers)
+	}
+	if providers["openai"] || providers["gemini"] || providers["moonshot"] {
+		t.Errorf("Expected models without a key to be left out, got %v", providers)
+	}
+}
+
+func TestReadOnlyLimitsModelsAndTurns(t *testing.T) {
+	s := &Server{
+		client: client.New("groq-key", client.WithProviderKey("anthropic", "claude-key")),
+		cfg:    &config.Config{ReadOnly: config.ReadOnlyConfig{Enabled: true, Models: []string{"llama-3.1-8b-instant"}, MaxTurns: 2}},
+	}
+	rec := httptest.NewRecorder()
+	s.handleModels(rec, httptest.NewRequest(http.MethodGet, "/api/models", nil))
+
+	var body struct {
+		Models   []client.ModelInfo `json:"models"`
+		ReadOnly bool               `json:"read_only"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !body.ReadOnly || len(body.Models) != 1 || body.Models[0].Name != "llama-3.1-8b-instant" {
+		t.Errorf("Expected only the allowed model, got %+v", body)
+	}
+	if s.modelAllowed("claude-sonnet-4-20250514") {
+		t.Error("Expected a model off the list to be refused")
+	}
+
+	history := []client.Message{
+		client.NewTextMessage("system", "prompt"),
+		client.NewTextMessage("user", "one"),
+		client.NewTextMessage("assistant", "reply"),
+	}
+	if msg := s.turnLimitError(history); msg != "" {
+		t.Errorf("Expected a second message to be allowed, got %q", msg)
+	}
+	history = append(history, client.NewTextMessage("user", "two"))
+	if msg := s.turnLimitError(history); msg == "" {
+		t.Error("Expected the third message to be refused")
+	}
+}
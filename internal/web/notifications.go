@@ -0,0 +1,39 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"groq-go/internal/notify"
+)
+
+// SetNotifier enables /api/notifications/test
+func (s *Server) SetNotifier(n *notify.Dispatcher) {
+	s.notifier = n
+}
+
+// handleNotificationTest sends a test event to every configured target
+// and reports how each delivery went, retries included: POST
+// /api/notifications/test, for admin users
+func (s *Server) handleNotificationTest(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if !s.notifier.Enabled() {
+		http.Error(w, "No notification targets configured", http.StatusServiceUnavailable)
+		return
+	}
+
+	event := notify.Event{
+		Type:    notify.Test,
+		Message: "Test notification from groq-go",
+		Refs:    map[string]string{"host": r.Host},
+	}
+	deliveries := s.notifier.Send(r.Context(), event)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{"deliveries": deliveries})
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/notify"
+)
+
+func TestNotificationTest(t *testing.T) {
+	var got notify.Event
+	var signed bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		signed = notify.Verify("hook-secret", r, body, time.Minute)
+		json.Unmarshal(body, &got)
+	}))
+	defer receiver.Close()
+
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{Web: config.WebConfig{AdminUsers: []string{"root"}}}
+	token := login(t, s, "10.0.0.1")
+	do := func(method, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, "/api/notifications/test", nil)
+		r.RemoteAddr = "127.0.0.1:1234"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.handleNotificationTest(rec, r)
+		return rec
+	}
+
+	if rec := do(http.MethodGet, token); rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405 for GET, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, even from loopback, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, token); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user who is not an admin, got %d", rec.Code)
+	}
+	s.cfg.Web.AdminUsers = []string{"alice"}
+	if rec := do(http.MethodPost, token); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without targets, got %d", rec.Code)
+	}
+
+	notifier := notify.New(config.NotificationsConfig{Targets: []config.NotifyTarget{{Name: "ops", URL: receiver.URL, Secret: "hook-secret"}}},
+		notify.WithDeadLetter(filepath.Join(t.TempDir(), "dead.jsonl")))
+	defer notifier.Close()
+	s.SetNotifier(notifier)
+
+	rec := do(http.MethodPost, token)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Deliveries []notify.Delivery `json:"deliveries"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if len(resp.Deliveries) != 1 || resp.Deliveries[0].Target != "ops" || resp.Deliveries[0].Error != "" {
+		t.Errorf("Unexpected deliveries %+v", resp.Deliveries)
+	}
+	if got.Type != notify.Test || !signed {
+		t.Errorf("Expected a signed test event, got %+v (signed %v)", got, signed)
+	}
+}
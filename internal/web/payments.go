@@ -0,0 +1,153 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"groq-go/internal/payments"
+)
+
+// maxWebhookBytes bounds the webhook payload; Stripe events are small
+const maxWebhookBytes = 64 * 1024
+
+// handleCreditCheckout lists credit packs (GET) or starts a Stripe Checkout
+// Session for one (POST {"pack": "..."}) and returns its URL
+func (s *Server) handleCreditCheckout(w http.ResponseWriter, r *http.Request) {
+	if s.credits == nil || !s.payments.Enabled() {
+		http.Error(w, "Payments not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"packs": s.payments.Packs()})
+
+	case http.MethodPost:
+		userID, err := s.resolveUserID(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Pack string `json:"pack"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		pack, ok := s.payments.Pack(req.Pack)
+		if !ok {
+			http.Error(w, "Unknown credit pack: "+req.Pack, http.StatusBadRequest)
+			return
+		}
+
+		origin := requestOrigin(r)
+		session, err := s.payments.CreateCheckoutSession(r.Context(), pack, userID,
+			origin+"/?checkout=success", origin+"/?checkout=cancel")
+		if err != nil {
+			log.Error("Failed to create checkout session", "user_id", userID, "pack", pack.Name, "error", err)
+			http.Error(w, "Failed to start checkout", http.StatusBadGateway)
+			return
+		}
+		log.Info("Checkout started", "user_id", userID, "pack", pack.Name, "session_id", session.ID)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"id": session.ID, "url": session.URL})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCreditWebhook receives Stripe events. Paid checkout sessions credit
+// the buyer once per session ID, so redelivered events are acknowledged
+// without crediting again.
+func (s *Server) handleCreditWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.credits == nil || s.payments == nil {
+		http.Error(w, "Payments not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBytes))
+	if err != nil {
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+	event, err := s.payments.ParseWebhook(payload, r.Header.Get("Stripe-Signature"))
+	if err != nil {
+		if errors.Is(err, payments.ErrNotConfigured) {
+			http.Error(w, "Payments not available", http.StatusServiceUnavailable)
+			return
+		}
+		log.Warn("Rejected webhook", "error", err)
+		http.Error(w, "Invalid webhook", http.StatusBadRequest)
+		return
+	}
+
+	switch event.Type {
+	case payments.EventCheckoutCompleted, payments.EventCheckoutAsyncSucceeded:
+		if err := s.applyCheckout(event); err != nil {
+			// A non-2xx response makes Stripe retry the event
+			log.Error("Failed to apply checkout", "event_id", event.ID, "error", err)
+			http.Error(w, "Failed to apply checkout", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]bool{"received": true})
+}
+
+// applyCheckout credits the buyer of a paid checkout session. The session
+// ID is the idempotency key, since a delayed payment method sends a second
+// event for the same purchase.
+func (s *Server) applyCheckout(event *payments.Event) error {
+	var session payments.CheckoutSession
+	if err := json.Unmarshal(event.Data.Object, &session); err != nil {
+		return err
+	}
+	// Delayed payment methods complete the session before the money
+	// arrives; their async_payment_succeeded event credits the buyer
+	if session.PaymentStatus != "paid" {
+		log.Info("Checkout completed without payment", "event_id", event.ID, "session_id", session.ID, "status", session.PaymentStatus)
+		return nil
+	}
+
+	userID := session.Metadata["user_id"]
+	if userID == "" {
+		userID = session.ClientReferenceID
+	}
+	amount, err := strconv.Atoi(session.Metadata["credits"])
+	if session.ID == "" || userID == "" || err != nil || amount <= 0 {
+		return errors.New("checkout session is missing its ID, user or credits metadata")
+	}
+
+	note := "Stripe " + session.Metadata["pack"] + " pack (" + session.ID + ")"
+	added, err := s.credits.AddCreditsOnce(session.ID, userID, amount, "buy", note)
+	if err != nil {
+		return err
+	}
+	if added {
+		log.Info("Credits purchased", "user_id", userID, "credits", amount, "event_id", event.ID, "session_id", session.ID)
+	} else {
+		log.Info("Duplicate webhook ignored", "event_id", event.ID, "session_id", session.ID)
+	}
+	return nil
+}
+
+// requestOrigin returns the scheme and host the client used
+func requestOrigin(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
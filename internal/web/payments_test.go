@@ -0,0 +1,166 @@
+package web
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groq-go/internal/config"
+	"groq-go/internal/payments"
+)
+
+const testWebhookSecret = "whsec_test"
+
+func checkoutEvent(eventID, sessionID, userID string, credits int) string {
+	return fmt.Sprintf(`{"id":%q,"type":"checkout.session.completed","data":{"object":{"id":%q,"payment_status":"paid","metadata":{"user_id":%q,"pack":"small","credits":"%d"}}}}`,
+		eventID, sessionID, userID, credits)
+}
+
+func postWebhook(s *Server, payload, signature string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/api/credits/webhook", strings.NewReader(payload))
+	req.Header.Set("Stripe-Signature", signature)
+	rec := httptest.NewRecorder()
+	s.handleCreditWebhook(rec, req)
+	return rec
+}
+
+func signed(payload string) string {
+	ts := time.Now().Unix()
+	return fmt.Sprintf("t=%d,v1=%s", ts, payments.Sign([]byte(payload), testWebhookSecret, ts))
+}
+
+func TestCreditWebhookIdempotent(t *testing.T) {
+	s := newIdentityTestServer(t, false)
+	s.payments = payments.NewStripe(payments.Config{SecretKey: "sk_test", WebhookSecret: testWebhookSecret})
+
+	payload := checkoutEvent("evt_1", "cs_1", "acct_alice", 500)
+
+	if rec := postWebhook(s, payload, "t=1,v1=bogus"); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad signature, got %d", rec.Code)
+	}
+	if s.credits.GetBalance("acct_alice") != 0 {
+		t.Fatal("Unsigned webhook must not add credits")
+	}
+
+	// Stripe redelivers the same event; only the first one counts
+	for i := 0; i < 3; i++ {
+		if rec := postWebhook(s, payload, signed(payload)); rec.Code != http.StatusOK {
+			t.Fatalf("Delivery %d: status %d: %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	user := s.credits.GetUserInfo("acct_alice")
+	if user == nil || user.Balance != 500 || user.TotalBought != 500 {
+		t.Fatalf("Expected 500 bought credits, got %+v", user)
+	}
+
+	// A different event for the same user is applied
+	payload = checkoutEvent("evt_2", "cs_2", "acct_alice", 100)
+	postWebhook(s, payload, signed(payload))
+	if got := s.credits.GetBalance("acct_alice"); got != 600 {
+		t.Errorf("Expected 600 after a second purchase, got %d", got)
+	}
+
+	// Unpaid sessions and other events are acknowledged without credits
+	payload = strings.Replace(checkoutEvent("evt_3", "cs_3", "acct_alice", 100), `"paid"`, `"unpaid"`, 1)
+	if rec := postWebhook(s, payload, signed(payload)); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for unpaid session, got %d", rec.Code)
+	}
+	payload = `{"id":"evt_4","type":"customer.created","data":{"object":{}}}`
+	if rec := postWebhook(s, payload, signed(payload)); rec.Code != http.StatusOK {
+		t.Errorf("Expected 200 for unrelated event, got %d", rec.Code)
+	}
+	if got := s.credits.GetBalance("acct_alice"); got != 600 {
+		t.Errorf("Expected balance to stay 600, got %d", got)
+	}
+}
+
+func TestCreditWebhookDelayedPayment(t *testing.T) {
+	s := newIdentityTestServer(t, false)
+	s.payments = payments.NewStripe(payments.Config{SecretKey: "sk_test", WebhookSecret: testWebhookSecret})
+
+	// A konbini payment completes the session unpaid and settles later
+	completed := strings.Replace(checkoutEvent("evt_1", "cs_1", "acct_alice", 500), `"paid"`, `"unpaid"`, 1)
+	postWebhook(s, completed, signed(completed))
+	if got := s.credits.GetBalance("acct_alice"); got != 0 {
+		t.Fatalf("Expected no credits before the payment settles, got %d", got)
+	}
+
+	succeeded := strings.Replace(checkoutEvent("evt_2", "cs_1", "acct_alice", 500), "checkout.session.completed", payments.EventCheckoutAsyncSucceeded, 1)
+	for i := 0; i < 2; i++ {
+		if rec := postWebhook(s, succeeded, signed(succeeded)); rec.Code != http.StatusOK {
+			t.Fatalf("Delivery %d: status %d: %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	if got := s.credits.GetBalance("acct_alice"); got != 500 {
+		t.Errorf("Expected 500 credits once the payment settled, got %d", got)
+	}
+
+	// Another event for the same session does not credit it again
+	paid := checkoutEvent("evt_3", "cs_1", "acct_alice", 500)
+	postWebhook(s, paid, signed(paid))
+	if got := s.credits.GetBalance("acct_alice"); got != 500 {
+		t.Errorf("Expected the session to be credited once, got %d", got)
+	}
+}
+
+func TestCreditCheckout(t *testing.T) {
+	api := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		fmt.Fprintf(w, `{"id":"cs_9","url":"https://checkout.example/%s"}`, r.Form.Get("metadata[user_id]"))
+	}))
+	defer api.Close()
+
+	s := newIdentityTestServer(t, false)
+	s.payments = payments.NewStripe(payments.Config{
+		SecretKey: "sk_test",
+		Packs:     []payments.Pack{{Name: "small", PriceID: "price_1", Credits: 500}},
+		BaseURL:   api.URL,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/credits/checkout", strings.NewReader(`{"pack":"small"}`))
+	req.RemoteAddr = "10.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	s.handleCreditCheckout(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "https://checkout.example/user_10_0_0_1") {
+		t.Errorf("Unexpected checkout response %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/credits/checkout", strings.NewReader(`{"pack":"huge"}`))
+	rec = httptest.NewRecorder()
+	s.handleCreditCheckout(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for unknown pack, got %d", rec.Code)
+	}
+}
+
+func TestAddCreditsRequiresAdminUser(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{Web: config.WebConfig{AdminUsers: []string{"root", "alice"}}}
+
+	add := func(token string) int {
+		req := httptest.NewRequest(http.MethodPost, "/api/credits/add", strings.NewReader(`{"user_id":"acct_bob","amount":50}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		s.handleCreditAction(rec, req)
+		return rec.Code
+	}
+
+	if code := add(""); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+	token := login(t, s, "10.0.0.1")
+	s.credits.GetOrCreateUser("acct_bob", "")
+	if code := add(token); code != http.StatusOK {
+		t.Errorf("Expected admin to add credits, got %d", code)
+	}
+
+	s.cfg.Web.AdminUsers = []string{"root"}
+	if code := add(token); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-admin user, got %d", code)
+	}
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"sync"
+	"time"
+)
+
+// progressInterval is the least time between progress messages for one
+// tool call. A variable so tests can change it.
+var progressInterval = 250 * time.Millisecond
+
+// progressThrottle forwards tool progress to a client, at most one report
+// per tool call every progressInterval. A new stage is always forwarded,
+// since tools report only a handful of them; only repeats of the current
+// stage, such as percentage updates, are dropped.
+type progressThrottle struct {
+	send func(WSMessage)
+	now  func() time.Time // Replaced by tests to travel in time
+
+	mu    sync.Mutex
+	calls map[string]progressState // Keyed by tool call ID
+}
+
+type progressState struct {
+	stage  string
+	sentAt time.Time
+}
+
+func newProgressThrottle(send func(WSMessage)) *progressThrottle {
+	return &progressThrottle{send: send, now: time.Now, calls: make(map[string]progressState)}
+}
+
+// report forwards a progress report from call id of tool name unless one
+// was sent for the same call and stage within progressInterval
+func (p *progressThrottle) report(id, name, stage string, pct int) {
+	// Held while sending so reports reach the client in order
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	last, seen := p.calls[id]
+	if seen && last.stage == stage && now.Sub(last.sentAt) < progressInterval {
+		return
+	}
+	p.calls[id] = progressState{stage: stage, sentAt: now}
+
+	msg := WSMessage{Type: "tool_progress", Tool: name, Content: stage}
+	if pct >= 0 {
+		msg.Progress = &pct
+	}
+	p.send(msg)
+}
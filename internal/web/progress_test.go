@@ -0,0 +1,93 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// chattyTool reports a burst of percentages for each of its stages
+type chattyTool struct{}
+
+func (chattyTool) Name() string               { return "Chatty" }
+func (chattyTool) Description() string        { return "" }
+func (chattyTool) Parameters() map[string]any { return nil }
+
+func (chattyTool) Execute(ctx context.Context, args json.RawMessage) (tool.Result, error) {
+	tool.ReportProgress(ctx, "starting", -1)
+	for pct := 0; pct <= 100; pct += 10 {
+		tool.ReportProgress(ctx, "running", pct)
+	}
+	tool.ReportProgress(ctx, "saving output", -1)
+	return tool.NewResult("ok"), nil
+}
+
+// recordingSink collects the messages a throttle sends
+type recordingSink struct {
+	msgs []WSMessage
+}
+
+func (r *recordingSink) send(m WSMessage) {
+	r.msgs = append(r.msgs, m)
+}
+
+func (r *recordingSink) String() string {
+	s := ""
+	for _, m := range r.msgs {
+		s += m.Content
+		if m.Progress != nil {
+			s += fmt.Sprintf("@%d", *m.Progress)
+		}
+		s += " "
+	}
+	return s
+}
+
+func TestProgressThrottle(t *testing.T) {
+	sink := &recordingSink{}
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	p := newProgressThrottle(sink.send)
+	p.now = clock.Now
+
+	p.report("call_1", "CodeExec", "starting", -1)
+	p.report("call_1", "CodeExec", "running", 10)
+	p.report("call_1", "CodeExec", "running", 20) // Dropped, too soon
+	p.report("call_2", "Browser", "running", 5)   // Another call has its own budget
+	clock.Advance(progressInterval)
+	p.report("call_1", "CodeExec", "running", 30)
+	p.report("call_1", "CodeExec", "saving output", -1) // New stages always pass
+
+	if got, want := sink.String(), "starting running@10 running@5 running@30 saving output "; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if m := sink.msgs[3]; m.Type != "tool_progress" || m.Tool != "CodeExec" {
+		t.Errorf("Expected a tool_progress message for CodeExec, got %+v", m)
+	}
+}
+
+func TestToolProgressForwarded(t *testing.T) {
+	registry := tool.NewRegistry()
+	registry.Register(chattyTool{})
+	e := tool.NewExecutor(registry)
+
+	sink := &recordingSink{}
+	p := newProgressThrottle(sink.send)
+	calls := []client.ToolCall{{ID: "call_1", Function: client.FunctionCall{Name: "Chatty", Arguments: "{}"}}}
+	results := e.ExecuteToolCallsParallelWithProgress(context.Background(), calls, tool.DefaultMaxConcurrency, nil,
+		func(tc client.ToolCall, stage string, pct int) {
+			p.report(tc.ID, tc.Function.Name, stage, pct)
+		})
+
+	if results[0].Content != "ok" {
+		t.Fatalf("Unexpected result %+v", results[0])
+	}
+	// The burst of percentages collapses to the first; stages stay in order
+	if got, want := sink.String(), "starting running@0 saving output "; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
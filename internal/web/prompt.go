@@ -0,0 +1,63 @@
+package web
+
+import (
+	"os"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+	"groq-go/internal/memory"
+)
+
+// SetSystemPrompt customizes the system prompt of new and running chats
+func (s *Server) SetSystemPrompt(p *conversation.Prompt) {
+	s.prompt = p
+}
+
+// getSystemPrompt returns a session's system prompt. Improvement mode
+// keeps its built-in instructions; only the appended text and the
+// session's own instructions are added to it. The user's memories come
+// last in both.
+func (s *Server) getSystemPrompt(sess *chatSession) string {
+	base := builtinSystemPrompt(sess.mode)
+	vars := conversation.NewPromptVars(s.workingDir(sess), s.promptTools(sess.mode))
+	var prompt string
+	if sess.mode == "improve" {
+		prompt = s.prompt.Extend(base, vars, sess.systemPrompt)
+	} else {
+		prompt = s.prompt.Render(base, vars, sess.systemPrompt)
+	}
+	return conversation.AppendSection(prompt, s.memory.PromptSection(sess.userID, memory.DefaultPromptBytes))
+}
+
+// workingDir is the root of the session's project, or the server's
+// directory without one
+func (s *Server) workingDir(sess *chatSession) string {
+	if sess.projectID != "" && s.projects != nil {
+		if proj, err := s.projects.Get(sess.projectID); err == nil {
+			return proj.RootPath
+		}
+	}
+	wd, _ := os.Getwd()
+	return wd
+}
+
+func (s *Server) promptTools(mode string) []client.Tool {
+	if s.registry == nil {
+		return nil
+	}
+	return s.registry.ToClientToolsForMode(mode)
+}
+
+// setSessionPrompt replaces the instructions a session adds to its system
+// prompt and rewrites history[0], as a mode change does. Empty text
+// removes them.
+func (s *Server) setSessionPrompt(sess *chatSession, text string) error {
+	if err := conversation.ValidateSessionPrompt(text); err != nil {
+		return err
+	}
+	sess.systemPrompt = text
+	if sess.history != nil {
+		sess.history.SetSystem(client.Message{Role: "system", Content: s.getSystemPrompt(sess)})
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package web
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/conversation"
+)
+
+func TestSessionSystemPrompt(t *testing.T) {
+	p, err := conversation.LoadPrompt(filepath.Join(t.TempDir(), "missing.md"), "Answer in Japanese.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{}
+	s.SetSystemPrompt(p)
+	sess := &chatSession{mode: "tools"}
+	sess.history = newConnHistory(&s.metrics, client.Message{Role: "system", Content: s.getSystemPrompt(sess)})
+	sess.history.Append(client.Message{Role: "user", Content: "hello"})
+
+	if err := s.setSessionPrompt(sess, "Never push to main."); err != nil {
+		t.Fatal(err)
+	}
+	msgs := sess.history.Messages()
+	system := msgs[0].Content.(string)
+	if !strings.HasPrefix(system, builtinSystemPrompt("tools")) ||
+		!strings.HasSuffix(system, "Answer in Japanese.\n\n## Session Instructions\nNever push to main.") {
+		t.Errorf("Unexpected system prompt ...%s", system[len(system)-100:])
+	}
+	if len(msgs) != 2 || msgs[1].Content != "hello" {
+		t.Errorf("Expected the conversation kept, got %d messages", len(msgs))
+	}
+
+	// Too long is rejected and leaves the prompt alone
+	if err := s.setSessionPrompt(sess, strings.Repeat("x", conversation.MaxSessionPromptLen+1)); err == nil {
+		t.Error("Expected an oversized prompt to be rejected")
+	}
+	if sess.systemPrompt != "Never push to main." {
+		t.Errorf("Expected the previous prompt kept, got %q", sess.systemPrompt)
+	}
+
+	// Improvement mode keeps its instructions and gains the session's
+	sess.mode = "improve"
+	if err := s.setSessionPrompt(sess, ""); err != nil {
+		t.Fatal(err)
+	}
+	want := builtinSystemPrompt("improve") + "\n\nAnswer in Japanese."
+	if got := sess.history.Messages()[0].Content; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
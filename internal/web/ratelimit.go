@@ -0,0 +1,277 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"groq-go/internal/config"
+)
+
+// limitClass groups endpoints that share a rate limit budget
+type limitClass string
+
+const (
+	// limitRead covers GET and HEAD requests
+	limitRead limitClass = "read"
+	// limitWrite covers requests that change state: uploads, logins, builds
+	limitWrite limitClass = "write"
+	// limitTTS covers speech synthesis and transcription, which cost money
+	// per request
+	limitTTS limitClass = "tts"
+)
+
+// rateLimitWindow is the window every budget applies to
+const rateLimitWindow = time.Minute
+
+// rateLimitEvictInterval is how often expired clients are dropped
+const rateLimitEvictInterval = 5 * time.Minute
+
+// rateLimitConfig holds requests per minute for each class, set by
+// web.rate_limits in the config. Authenticated requests are counted per
+// user unless per_user is false.
+type rateLimitConfig struct {
+	Budgets map[limitClass]int
+	PerUser bool
+}
+
+func defaultRateLimitConfig() rateLimitConfig {
+	return rateLimitConfig{
+		Budgets: map[limitClass]int{
+			limitRead:  120,
+			limitWrite: 30,
+			limitTTS:   10,
+		},
+		PerUser: true,
+	}
+}
+
+// rateLimitConfigFrom applies the configured budgets over the defaults;
+// unset (zero) budgets keep their default
+func rateLimitConfigFrom(limits config.RateLimits) rateLimitConfig {
+	cfg := defaultRateLimitConfig()
+	for class, n := range map[limitClass]int{limitRead: limits.Read, limitWrite: limits.Write, limitTTS: limits.TTS} {
+		if n > 0 {
+			cfg.Budgets[class] = n
+		}
+	}
+	cfg.PerUser = limits.IsPerUser()
+	return cfg
+}
+
+// rateLimiter is a fixed-window limiter keyed by client
+type rateLimiter struct {
+	mu       sync.Mutex
+	clients  map[string]*clientRate
+	maxReqs  int
+	window   time.Duration
+	rejected int64
+	now      func() time.Time // Replaced by tests to travel in time
+}
+
+type clientRate struct {
+	count   int
+	resetAt time.Time
+}
+
+func newRateLimiter(maxReqs int, window time.Duration) *rateLimiter {
+	return &rateLimiter{
+		clients: make(map[string]*clientRate),
+		maxReqs: maxReqs,
+		window:  window,
+		now:     time.Now,
+	}
+}
+
+// allow counts a request from key. It returns whether the request is
+// allowed, how many more are left in the window and when it resets.
+func (rl *rateLimiter) allow(key string) (bool, int, time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	client, exists := rl.clients[key]
+	if !exists || !now.Before(client.resetAt) {
+		client = &clientRate{resetAt: now.Add(rl.window)}
+		rl.clients[key] = client
+	}
+	if client.count >= rl.maxReqs {
+		rl.rejected++
+		return false, 0, client.resetAt
+	}
+	client.count++
+	return true, rl.maxReqs - client.count, client.resetAt
+}
+
+// exhausted reports whether key has used up its budget, without counting
+// a request
+func (rl *rateLimiter) exhausted(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	client, ok := rl.clients[key]
+	return ok && rl.now().Before(client.resetAt) && client.count >= rl.maxReqs
+}
+
+// evict drops clients whose window has passed and returns how many
+func (rl *rateLimiter) evict() int {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	n := 0
+	for key, client := range rl.clients {
+		if !now.Before(client.resetAt) {
+			delete(rl.clients, key)
+			n++
+		}
+	}
+	return n
+}
+
+// limiterStats describes one limiter for the admin endpoint
+type limiterStats struct {
+	Limit         int   `json:"limit"`
+	WindowSeconds int   `json:"window_seconds"`
+	Clients       int   `json:"clients"`
+	Limited       int   `json:"limited"` // Clients that used up their budget
+	Rejected      int64 `json:"rejected"`
+}
+
+func (rl *rateLimiter) stats() limiterStats {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	st := limiterStats{
+		Limit:         rl.maxReqs,
+		WindowSeconds: int(rl.window.Seconds()),
+		Clients:       len(rl.clients),
+		Rejected:      rl.rejected,
+	}
+	for _, client := range rl.clients {
+		if client.count >= rl.maxReqs && now.Before(client.resetAt) {
+			st.Limited++
+		}
+	}
+	return st
+}
+
+// rateLimits holds one limiter per endpoint class
+type rateLimits struct {
+	limiters map[limitClass]*rateLimiter
+	perUser  bool
+}
+
+func newRateLimits(cfg rateLimitConfig) *rateLimits {
+	rl := &rateLimits{limiters: make(map[limitClass]*rateLimiter), perUser: cfg.PerUser}
+	for class, n := range cfg.Budgets {
+		rl.limiters[class] = newRateLimiter(n, rateLimitWindow)
+	}
+	return rl
+}
+
+// maxShareKeyFailures bounds wrong keys tried on one password-protected
+// share per window, whichever clients they come from
+const maxShareKeyFailures = 10
+
+// shareKeyFailures counts wrong keys by share ID
+var shareKeyFailures = newRateLimiter(maxShareKeyFailures, rateLimitWindow)
+
+// classify picks the budget a request counts against
+func classify(r *http.Request) limitClass {
+	if strings.HasPrefix(r.URL.Path, "/api/tts") || strings.HasPrefix(r.URL.Path, "/api/transcribe") {
+		return limitTTS
+	}
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return limitRead
+	}
+	return limitWrite
+}
+
+// key identifies the client of a request: the user once authMiddleware
+// has authenticated it, the IP otherwise
+func (rl *rateLimits) key(r *http.Request) string {
+	if rl.perUser {
+		if username, ok := requestUsername(r); ok {
+			return "user:" + username
+		}
+	}
+	return "ip:" + requestClientIP(r)
+}
+
+// startEviction drops expired clients every interval until ctx is done
+func (rl *rateLimits) startEviction(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				n := 0
+				for _, limiter := range rl.limiters {
+					n += limiter.evict()
+				}
+				n += shareKeyFailures.evict()
+				if n > 0 {
+					log.Debug("Evicted rate limit entries", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+// stats returns the state of each limiter
+func (rl *rateLimits) stats() map[limitClass]limiterStats {
+	st := make(map[limitClass]limiterStats, len(rl.limiters))
+	for class, limiter := range rl.limiters {
+		st[class] = limiter.stats()
+	}
+	return st
+}
+
+// rateLimitMiddleware wraps handlers with rate limiting. Each request
+// counts against its endpoint class's budget in limits and reports the
+// rest in X-RateLimit-* headers.
+func rateLimitMiddleware(limits *rateLimits, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		class := classify(r)
+		limiter := limits.limiters[class]
+		key := limits.key(r)
+		ok, remaining, resetAt := limiter.allow(key)
+
+		h := w.Header()
+		h.Set("X-RateLimit-Limit", strconv.Itoa(limiter.maxReqs))
+		h.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		h.Set("X-RateLimit-Reset", strconv.FormatInt(resetAt.Unix(), 10))
+		if !ok {
+			retry := int(resetAt.Sub(limiter.now()).Seconds()) + 1
+			h.Set("Retry-After", strconv.Itoa(retry))
+			log.Warn("Rate limit exceeded", "client", key, "class", class)
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// handleAdminRateLimits reports limiter state: GET /api/admin/ratelimits
+func (s *Server) handleAdminRateLimits(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"per_user": s.limits.perUser,
+		"limits":   s.limits.stats(),
+	})
+}
@@ -0,0 +1,233 @@
+package web
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"groq-go/internal/config"
+)
+
+// testRateLimits returns limiters with the given budgets
+func testRateLimits(read, write, tts int) *rateLimits {
+	cfg := defaultRateLimitConfig()
+	cfg.Budgets[limitRead], cfg.Budgets[limitWrite], cfg.Budgets[limitTTS] = read, write, tts
+	return newRateLimits(cfg)
+}
+
+// fakeClock lets a test move a limiter's time forward
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestRateLimiterConcurrent(t *testing.T) {
+	rl := newRateLimiter(100, time.Minute)
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for g := 0; g < 20; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				// Half the goroutines share one key
+				key := "shared"
+				if g%2 == 1 {
+					key = fmt.Sprintf("own-%d", g)
+				}
+				if ok, _, _ := rl.allow(key); ok {
+					allowed.Add(1)
+				}
+				rl.stats()
+				rl.evict()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	// 10 goroutines x 50 on the shared key get 100; 10 own keys get 50 each
+	if got := allowed.Load(); got != 100+10*50 {
+		t.Errorf("Expected %d requests allowed, got %d", 100+10*50, got)
+	}
+	if st := rl.stats(); st.Rejected != 400 || st.Clients != 11 || st.Limited != 1 {
+		t.Errorf("Unexpected stats: %+v", st)
+	}
+}
+
+func TestRateLimiterEviction(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	rl := newRateLimiter(2, time.Minute)
+	rl.now = clock.Now
+
+	for i := 0; i < 100; i++ {
+		rl.allow(fmt.Sprintf("scanner-%d", i))
+	}
+	rl.allow("busy")
+	rl.allow("busy")
+	if ok, remaining, _ := rl.allow("busy"); ok || remaining != 0 {
+		t.Error("Expected the third request in the window to be rejected")
+	}
+
+	clock.Advance(30 * time.Second)
+	if n := rl.evict(); n != 0 {
+		t.Errorf("Expected nothing evicted mid-window, got %d", n)
+	}
+	clock.Advance(30 * time.Second)
+	if n := rl.evict(); n != 101 {
+		t.Errorf("Expected all 101 clients evicted, got %d", n)
+	}
+	if st := rl.stats(); st.Clients != 0 {
+		t.Errorf("Expected no clients left, got %d", st.Clients)
+	}
+	if ok, remaining, _ := rl.allow("busy"); !ok || remaining != 1 {
+		t.Errorf("Expected a fresh budget after the window, got %v with %d left", ok, remaining)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	limits := testRateLimits(3, 1, 1)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	for _, l := range limits.limiters {
+		l.now = clock.Now
+	}
+	h := rateLimitMiddleware(limits, func(w http.ResponseWriter, r *http.Request) {})
+
+	do := func(method, path, ip, user string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, nil)
+		r.RemoteAddr = ip + ":1234"
+		if user != "" {
+			r = r.WithContext(withUsername(r.Context(), user))
+		}
+		rec := httptest.NewRecorder()
+		h(rec, r)
+		return rec
+	}
+
+	// An uploader using up the write budget can still read and speak
+	if rec := do(http.MethodPost, "/api/upload", "10.0.0.1", ""); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Expected the first upload to pass with 0 remaining, got %d %q", rec.Code, rec.Header().Get("X-RateLimit-Remaining"))
+	}
+	rec := do(http.MethodPost, "/api/upload", "10.0.0.1", "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 for the second upload, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "61" {
+		t.Errorf("Expected Retry-After 61, got %q", rec.Header().Get("Retry-After"))
+	}
+	if rec := do(http.MethodGet, "/api/sessions", "10.0.0.1", ""); rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "3" {
+		t.Errorf("Expected reads to have their own budget, got %d limit %q", rec.Code, rec.Header().Get("X-RateLimit-Limit"))
+	}
+	if rec := do(http.MethodPost, "/api/tts", "10.0.0.1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected TTS to have its own budget, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/tts", "10.0.0.1", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the second TTS request to be limited, got %d", rec.Code)
+	}
+
+	// Authenticated users are counted per user, not per shared IP
+	if rec := do(http.MethodPost, "/api/upload", "10.0.0.1", "alice"); rec.Code != http.StatusOK {
+		t.Errorf("Expected alice to have her own budget behind the same IP, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/upload", "10.0.0.2", "alice"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected alice's budget to follow her to another IP, got %d", rec.Code)
+	}
+
+	limits.perUser = false
+	if rec := do(http.MethodPost, "/api/upload", "10.0.0.3", "bob"); rec.Code != http.StatusOK {
+		t.Errorf("Expected a fresh IP budget with per-user keys off, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/upload", "10.0.0.3", "carol"); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected users behind one IP to share a budget with per-user keys off, got %d", rec.Code)
+	}
+
+	// A client cannot pick a fresh IP budget with forwarding headers
+	r := httptest.NewRequest(http.MethodPost, "/api/upload", nil)
+	r.RemoteAddr = "203.0.113.5:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	h(httptest.NewRecorder(), r)
+	r.Header.Set("X-Forwarded-For", "198.51.100.2")
+	r.Header.Set("Fly-Client-IP", "198.51.100.3")
+	rec = httptest.NewRecorder()
+	h(rec, r)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected spoofed headers to share the connection's budget, got %d", rec.Code)
+	}
+
+	clock.Advance(time.Minute)
+	if rec := do(http.MethodPost, "/api/upload", "10.0.0.1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the budget to reset after the window, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitConfigFrom(t *testing.T) {
+	perUser := false
+	cfg := rateLimitConfigFrom(config.RateLimits{Read: 500, PerUser: &perUser})
+	if cfg.Budgets[limitRead] != 500 {
+		t.Errorf("Expected read budget 500, got %d", cfg.Budgets[limitRead])
+	}
+	if cfg.Budgets[limitWrite] != defaultRateLimitConfig().Budgets[limitWrite] {
+		t.Errorf("Expected an unset write budget to keep its default, got %d", cfg.Budgets[limitWrite])
+	}
+	if cfg.PerUser {
+		t.Error("Expected per-user keys to be off")
+	}
+	if !rateLimitConfigFrom(config.RateLimits{}).PerUser {
+		t.Error("Expected per-user keys on by default")
+	}
+}
+
+func TestAdminRateLimits(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{Web: config.WebConfig{AdminUsers: []string{"root"}}}
+	s.limits = testRateLimits(1, 1, 1)
+	s.limits.limiters[limitRead].allow("ip:10.0.0.1")
+	s.limits.limiters[limitRead].allow("ip:10.0.0.1")
+	token := login(t, s, "10.0.0.1")
+
+	r := httptest.NewRequest(http.MethodGet, "/api/admin/ratelimits", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	rec := httptest.NewRecorder()
+	s.handleAdminRateLimits(rec, r)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, even from loopback, got %d", rec.Code)
+	}
+
+	r.Header.Set("Authorization", "Bearer "+token)
+	rec = httptest.NewRecorder()
+	s.handleAdminRateLimits(rec, r)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user who is not an admin, got %d", rec.Code)
+	}
+
+	s.cfg.Web.AdminUsers = []string{"alice"}
+	rec = httptest.NewRecorder()
+	s.handleAdminRateLimits(rec, r)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 for an admin, got %d", rec.Code)
+	}
+	var body struct {
+		Limits map[string]limiterStats `json:"limits"`
+	}
+	json.NewDecoder(rec.Body).Decode(&body)
+	if st := body.Limits["read"]; st.Clients != 1 || st.Limited != 1 || st.Rejected != 1 {
+		t.Errorf("Unexpected read stats: %+v", st)
+	}
+}
@@ -0,0 +1,50 @@
+package web
+
+import (
+	"fmt"
+
+	"groq-go/internal/client"
+	"groq-go/internal/config"
+	"groq-go/internal/conversation"
+)
+
+// readOnly returns the read-only settings, or nil when the mode is off.
+// The registry hides the tools; the server limits models and turns.
+func (s *Server) readOnly() *config.ReadOnlyConfig {
+	if s.cfg == nil || !s.cfg.ReadOnly.Enabled {
+		return nil
+	}
+	return &s.cfg.ReadOnly
+}
+
+// availableModels returns the models sessions may switch to
+func (s *Server) availableModels() []client.ModelInfo {
+	models := s.client.AvailableModels()
+	ro := s.readOnly()
+	if ro == nil {
+		return models
+	}
+	allowed := make([]client.ModelInfo, 0, len(models))
+	for _, m := range models {
+		if ro.AllowsModel(m.Name) {
+			allowed = append(allowed, m)
+		}
+	}
+	return allowed
+}
+
+// modelAllowed reports whether sessions may switch to model
+func (s *Server) modelAllowed(model string) bool {
+	ro := s.readOnly()
+	return ro == nil || ro.AllowsModel(model)
+}
+
+// turnLimitError returns why history may not grow by another message, or
+// "" if it may
+func (s *Server) turnLimitError(history []client.Message) string {
+	ro := s.readOnly()
+	if ro == nil || conversation.UserTurns(history) < ro.TurnLimit() {
+		return ""
+	}
+	return fmt.Sprintf("This demo allows %d messages per conversation. Start a new conversation to continue.", ro.TurnLimit())
+}
@@ -0,0 +1,204 @@
+package web
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/client"
+	"groq-go/internal/ids"
+)
+
+const (
+	// resumeTTL is how long the state of a closed WebSocket connection is
+	// kept for a reloaded page to reattach to
+	resumeTTL = 10 * time.Minute
+	// maxParkedSessions bounds the sessions kept after their connection
+	// closed; more are released at once
+	maxParkedSessions = 1000
+	// parkedSweepInterval is how often expired sessions are released
+	parkedSweepInterval = time.Minute
+	// maxReplayContent caps each tool call and result sent with
+	// "history_replay"; text messages are sent whole
+	maxReplayContent = 2000
+)
+
+// replayMessage is a message of a reattached conversation, sent with
+// "history_replay" so the page can draw it again
+type replayMessage struct {
+	Role    string `json:"role"` // "user", "assistant", "tool_call" or "tool"
+	Content string `json:"content,omitempty"`
+	Tool    string `json:"tool,omitempty"` // Tool called, or whose result this is
+}
+
+// parkedSessions holds chat sessions whose connection closed, keyed by the
+// resume token sent in their welcome message. A reloaded page presents the
+// token to take the session over. The zero value is ready to use.
+type parkedSessions struct {
+	mu       sync.Mutex
+	sessions map[string]parkedSession
+	ttl      time.Duration    // resumeTTL when zero
+	now      func() time.Time // time.Now when nil
+}
+
+type parkedSession struct {
+	sess    *chatSession
+	expires time.Time
+}
+
+// newResumeToken returns an unguessable token for reattaching to a session
+func newResumeToken() string {
+	return ids.New(32)
+}
+
+func (p *parkedSessions) clock() time.Time {
+	if p.now != nil {
+		return p.now()
+	}
+	return time.Now()
+}
+
+// park keeps sess for the TTL. Sessions with nothing to resume, or that
+// do not fit, are released.
+func (p *parkedSessions) park(sess *chatSession) {
+	if sess.history == nil {
+		return
+	}
+	if sess.resumeToken == "" || len(sess.history.Messages()) <= 1 {
+		sess.history.Release()
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.sessions == nil {
+		p.sessions = make(map[string]parkedSession)
+	}
+	if len(p.sessions) >= maxParkedSessions {
+		p.sweepLocked()
+	}
+	if len(p.sessions) >= maxParkedSessions {
+		log.Warn("Too many disconnected sessions, not keeping this one for resume", "client_ip", sess.clientIP)
+		sess.history.Release()
+		return
+	}
+	ttl := p.ttl
+	if ttl == 0 {
+		ttl = resumeTTL
+	}
+	p.sessions[sess.resumeToken] = parkedSession{sess: sess, expires: p.clock().Add(ttl)}
+}
+
+// take removes and returns the session parked under token, or nil if the
+// token is unknown, expired or belongs to another user
+func (p *parkedSessions) take(token, userID string) *chatSession {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	parked, ok := p.sessions[token]
+	if !ok || parked.sess.userID != userID {
+		return nil
+	}
+	delete(p.sessions, token)
+	if !p.clock().Before(parked.expires) {
+		parked.sess.history.Release()
+		return nil
+	}
+	return parked.sess
+}
+
+// sweep releases expired sessions and returns how many it dropped
+func (p *parkedSessions) sweep() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.sweepLocked()
+}
+
+func (p *parkedSessions) sweepLocked() int {
+	now := p.clock()
+	n := 0
+	for token, parked := range p.sessions {
+		if !now.Before(parked.expires) {
+			parked.sess.history.Release()
+			delete(p.sessions, token)
+			n++
+		}
+	}
+	return n
+}
+
+// len returns the number of parked sessions
+func (p *parkedSessions) len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.sessions)
+}
+
+// startSweeper releases expired sessions every interval until ctx is done
+func (p *parkedSessions) startSweeper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if n := p.sweep(); n > 0 {
+					log.Debug("Released expired sessions", "count", n)
+				}
+			}
+		}
+	}()
+}
+
+// reattach moves the state parked under token into sess, the new
+// connection's session, and replays the conversation to the client. It
+// reports false, leaving sess fresh, when there is nothing to reattach to.
+func (s *Server) reattach(conn *websocket.Conn, sess *chatSession, token string) bool {
+	old := s.parked.take(token, sess.userID)
+	if old == nil {
+		return false
+	}
+	sess.history.Release()
+	sess.history, sess.client, sess.mode = old.history, old.client, old.mode
+	sess.stored, sess.reads, sess.approvals = old.stored, old.reads, old.approvals
+	sess.projectID, sess.systemPrompt = old.projectID, old.systemPrompt
+
+	replay := WSMessage{
+		Type:     "history_replay",
+		Mode:     sess.mode,
+		Model:    sess.client.Model(),
+		Messages: replayMessages(sess.history.Messages()),
+	}
+	if sess.stored != nil {
+		replay.SessionID = sess.stored.ID
+	}
+	s.sendMessage(conn, replay)
+	return true
+}
+
+// replayMessages converts history, without its system prompt, to what the
+// page shows
+func replayMessages(history []client.Message) []replayMessage {
+	toolNames := make(map[string]string)
+	var out []replayMessage
+	for _, msg := range history {
+		switch msg.Role {
+		case "user":
+			out = append(out, replayMessage{Role: "user", Content: msg.Text()})
+		case "assistant":
+			if text := msg.Text(); text != "" {
+				out = append(out, replayMessage{Role: "assistant", Content: text})
+			}
+			for _, call := range msg.ToolCalls {
+				toolNames[call.ID] = call.Function.Name
+				out = append(out, replayMessage{Role: "tool_call", Tool: call.Function.Name, Content: truncateLog(call.Function.Arguments, maxReplayContent)})
+			}
+		case "tool":
+			out = append(out, replayMessage{Role: "tool", Tool: toolNames[msg.ToolCallID], Content: truncateLog(msg.Text(), maxReplayContent)})
+		}
+	}
+	return out
+}
@@ -0,0 +1,192 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// newReattachTestServer is newTestServer with a settable clock for the
+// parked sessions
+func newReattachTestServer(t *testing.T, up *scriptedUpstream, now func() time.Time) (*Server, string) {
+	t.Helper()
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+
+	registry := tool.NewRegistry()
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+	}
+	s.parked.now = now
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	return s, "ws" + strings.TrimPrefix(srv.URL, "http")
+}
+
+// chatAndDisconnect runs one turn, closes the connection and waits for the
+// server to park its state. It returns the connection's resume token.
+func chatAndDisconnect(t *testing.T, s *Server, url string) string {
+	t.Helper()
+	conn := dialTestServer(t, url)
+	welcome := readUntil(t, conn, "system")
+	if welcome.Resume == "" {
+		t.Fatal("Expected a resume token in the welcome message")
+	}
+	conn.WriteJSON(WSMessage{Type: "model", Model: "other-model"})
+	readUntil(t, conn, "system")
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "remember 42"})
+	readUntil(t, conn, "done")
+	conn.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for s.parked.len() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for the session to be parked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return welcome.Resume
+}
+
+// readTypes reads messages until one of type last arrives and returns the
+// types seen
+func readTypes(t *testing.T, conn *websocket.Conn, last string) []string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var types []string
+	for {
+		var msg WSMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("Waiting for %q: %v", last, err)
+		}
+		types = append(types, msg.Type)
+		if msg.Type == last {
+			return types
+		}
+	}
+}
+
+func TestReattachAfterReload(t *testing.T) {
+	up := &scriptedUpstream{replies: []client.Delta{{Content: "noted"}, {Content: "it was 42"}}}
+	s, url := newReattachTestServer(t, up, nil)
+	token := chatAndDisconnect(t, s, url)
+
+	conn := dialTestServer(t, url)
+	if welcome := readUntil(t, conn, "system"); welcome.Resume == "" || welcome.Resume == token {
+		t.Errorf("Expected a new token for the new connection, got %q", welcome.Resume)
+	}
+	conn.WriteJSON(WSMessage{Type: "resume", Resume: token})
+	replay := readUntil(t, conn, "history_replay")
+	if len(replay.Messages) != 2 || replay.Messages[0].Content != "remember 42" || replay.Messages[1].Content != "noted" {
+		t.Errorf("Unexpected replay %+v", replay.Messages)
+	}
+	if replay.Model != "other-model" || replay.Mode != "tools" {
+		t.Errorf("Expected the model and mode carried over, got %q and %q", replay.Model, replay.Mode)
+	}
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "what was it?"})
+	readUntil(t, conn, "done")
+	up.mu.Lock()
+	sent, model := up.requests[len(up.requests)-1], up.models[len(up.models)-1]
+	up.mu.Unlock()
+	if got := roles(sent); got != "system,user,assistant,user" || model != "other-model" {
+		t.Errorf("Expected the turn to continue the old history with its model, got %s on %s", got, model)
+	}
+
+	// A token is good for one reattach
+	again := dialTestServer(t, url)
+	readUntil(t, again, "system")
+	again.WriteJSON(WSMessage{Type: "chat", Content: "hi", Resume: token})
+	if types := readTypes(t, again, "done"); strings.Contains(strings.Join(types, ","), "history_replay") {
+		t.Errorf("Expected a used token to be refused, got %v", types)
+	}
+}
+
+func TestReattachAfterExpiry(t *testing.T) {
+	var mu sync.Mutex
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	clock := func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	up := &scriptedUpstream{}
+	s, url := newReattachTestServer(t, up, clock)
+	token := chatAndDisconnect(t, s, url)
+
+	mu.Lock()
+	now = now.Add(resumeTTL)
+	mu.Unlock()
+
+	conn := dialTestServer(t, url)
+	readUntil(t, conn, "system")
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "fresh start", Resume: token})
+	if types := readTypes(t, conn, "done"); strings.Contains(strings.Join(types, ","), "history_replay") {
+		t.Errorf("Expected no replay for an expired token, got %v", types)
+	}
+	up.mu.Lock()
+	sent := up.requests[len(up.requests)-1]
+	up.mu.Unlock()
+	if got := roles(sent); got != "system,user" {
+		t.Errorf("Expected a fresh history, got %s", got)
+	}
+	if n := s.parked.len(); n != 0 {
+		t.Errorf("Expected the expired session released, %d still parked", n)
+	}
+}
+
+func TestParkedSessions(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 0, 0, 0, time.UTC)
+	var metrics wsMetrics
+	p := &parkedSessions{ttl: time.Minute, now: func() time.Time { return now }}
+	parked := func(token, userID string) *chatSession {
+		h := newConnHistory(&metrics, client.Message{Role: "system", Content: "prompt"})
+		h.Append(client.Message{Role: "user", Content: "hello"})
+		sess := &chatSession{history: h, userID: userID, resumeToken: token}
+		p.park(sess)
+		return sess
+	}
+
+	// Nothing to resume: released rather than kept
+	empty := &chatSession{history: newConnHistory(&metrics, client.Message{Role: "system"}), resumeToken: "empty"}
+	p.park(empty)
+	if p.len() != 0 {
+		t.Error("Expected a session without messages not to be parked")
+	}
+
+	alice := parked("tok-a", "user:alice")
+	if got := p.take("tok-a", "user:bob"); got != nil {
+		t.Error("Expected another user's token to be refused")
+	}
+	if got := p.take("unknown", "user:alice"); got != nil {
+		t.Error("Expected an unknown token to be refused")
+	}
+	if got := p.take("tok-a", "user:alice"); got != alice {
+		t.Error("Expected the owner to reclaim the session")
+	}
+
+	parked("tok-b", "user:bob")
+	now = now.Add(30 * time.Second)
+	if n := p.sweep(); n != 0 {
+		t.Errorf("Expected nothing swept inside the window, got %d", n)
+	}
+	now = now.Add(time.Minute)
+	if n := p.sweep(); n != 1 || p.len() != 0 {
+		t.Errorf("Expected the expired session swept, got %d with %d left", n, p.len())
+	}
+
+	alice.history.Release()
+	if got := metrics.historyBytes.Load(); got != 0 {
+		t.Errorf("Expected every history released from the metrics, %d bytes left", got)
+	}
+}
@@ -0,0 +1,64 @@
+package web
+
+import (
+	"fmt"
+
+	"groq-go/internal/tool"
+)
+
+// sandboxEnabled reports whether file tools are confined to the session's
+// project; sandbox_disabled in the config turns it off
+func (s *Server) sandboxEnabled() bool {
+	return s.cfg == nil || !s.cfg.SandboxDisabled
+}
+
+// formatOnWrite returns which files Write and Edit format, from
+// auto_format in the config; off without one
+func (s *Server) formatOnWrite() tool.Format {
+	if s.cfg == nil {
+		return tool.FormatOff
+	}
+	return s.cfg.FormatOnWrite()
+}
+
+// sandboxFor returns the sandbox for the project a session selected with
+// a "project" message, or nil when it selected none
+func (s *Server) sandboxFor(sess *chatSession) (*tool.Sandbox, error) {
+	if sess.projectID == "" || s.projects == nil || !s.sandboxEnabled() {
+		return nil, nil
+	}
+	proj, err := s.projects.Get(sess.projectID)
+	if err != nil {
+		return nil, err
+	}
+	sb, err := tool.NewSandbox(proj.RootPath)
+	if err != nil {
+		return nil, fmt.Errorf("project %s is unavailable: %w", proj.Name, err)
+	}
+	return sb, nil
+}
+
+// selectProject sets the project a session's tools work in and describes
+// the result for the client. An empty id clears it.
+func (s *Server) selectProject(sess *chatSession, id string) (string, error) {
+	if id == "" {
+		sess.projectID = ""
+		return "No project selected", nil
+	}
+	if s.projects == nil {
+		return "", fmt.Errorf("projects not available")
+	}
+	proj, err := s.projects.Get(id)
+	if err != nil {
+		return "", err
+	}
+	if !s.sandboxEnabled() {
+		sess.projectID = id
+		return fmt.Sprintf("Using project %s (sandbox disabled)", proj.Name), nil
+	}
+	if _, err := tool.NewSandbox(proj.RootPath); err != nil {
+		return "", fmt.Errorf("project %s is unavailable: %w", proj.Name, err)
+	}
+	sess.projectID = id
+	return fmt.Sprintf("Using project %s; file tools are confined to %s", proj.Name, proj.RootPath), nil
+}
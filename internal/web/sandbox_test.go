@@ -0,0 +1,51 @@
+package web
+
+import (
+	"strings"
+	"testing"
+
+	"groq-go/internal/config"
+	"groq-go/internal/project"
+)
+
+func TestSessionProjectSandbox(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	pm, err := project.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := t.TempDir()
+	proj, _ := pm.Create("demo", root, "")
+	gone, _ := pm.Create("gone", root+"/missing", "")
+	s := &Server{projects: pm, cfg: &config.Config{}}
+	sess := &chatSession{}
+
+	if sb, err := s.sandboxFor(sess); sb != nil || err != nil {
+		t.Errorf("Expected no sandbox before a project is selected, got %v, %v", sb, err)
+	}
+	if _, err := s.selectProject(sess, "nope"); err == nil {
+		t.Error("Expected an unknown project rejected")
+	}
+	if _, err := s.selectProject(sess, gone.ID); err == nil || sess.projectID != "" {
+		t.Errorf("Expected a project with a missing root rejected, got %v", err)
+	}
+
+	content, err := s.selectProject(sess, proj.ID)
+	if err != nil || !strings.Contains(content, root) {
+		t.Fatalf("Expected the project selected, got %q, %v", content, err)
+	}
+	sb, err := s.sandboxFor(sess)
+	if err != nil || sb.Root() != root {
+		t.Errorf("Expected a sandbox at %s, got %v, %v", root, sb, err)
+	}
+
+	// sandbox_disabled keeps the selection but confines nothing
+	s.cfg.SandboxDisabled = true
+	if sb, err := s.sandboxFor(sess); sb != nil || err != nil {
+		t.Errorf("Expected no sandbox when disabled, got %v, %v", sb, err)
+	}
+
+	if _, err := s.selectProject(sess, ""); err != nil || sess.projectID != "" {
+		t.Errorf("Expected the selection cleared, got %q, %v", sess.projectID, err)
+	}
+}
@@ -0,0 +1,146 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"groq-go/internal/audit"
+	"groq-go/internal/scheduler"
+)
+
+// SetScheduler enables the /api/schedules endpoints. The server runs due
+// jobs from when it starts serving until it shuts down.
+func (s *Server) SetScheduler(sched *scheduler.Scheduler) {
+	s.scheduler = sched
+}
+
+// startScheduler runs scheduled jobs with the server's client, tools and
+// session storage
+func (s *Server) startScheduler() {
+	if s.scheduler == nil {
+		return
+	}
+	runner := scheduler.NewRunner(s.client, s.registry, s.storage)
+	runner.SetSystemPrompt(s.prompt)
+	if s.audit != nil {
+		runner.AddHook(audit.NewHook(s.audit, "scheduler"))
+	}
+	s.scheduler.SetRunner(runner.Run)
+	s.scheduler.Start()
+}
+
+// scheduleRequest is the body of POST /api/schedules and PUT
+// /api/schedules/{id}
+type scheduleRequest struct {
+	Name     string   `json:"name"`
+	Cron     string   `json:"cron"`
+	Prompt   string   `json:"prompt"`
+	Model    string   `json:"model"`
+	Tools    []string `json:"tools"`
+	MaxTurns int      `json:"max_turns"`
+}
+
+func (req scheduleRequest) job() scheduler.Job {
+	return scheduler.Job{
+		Name:     req.Name,
+		Cron:     req.Cron,
+		Prompt:   req.Prompt,
+		Model:    req.Model,
+		Tools:    req.Tools,
+		MaxTurns: req.MaxTurns,
+	}
+}
+
+// handleSchedules lists and creates scheduled jobs. Jobs run tools
+// unattended, so every method requires a user listed in web.admin_users.
+func (s *Server) handleSchedules(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.scheduler == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"schedules": s.scheduler.List()})
+
+	case http.MethodPost:
+		var req scheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		job, err := s.scheduler.Add(req.job())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		log.Info("Added schedule", "id", job.ID, "cron", job.Cron)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(job)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSchedule reads, replaces or removes one job:
+// /api/schedules/{id}
+func (s *Server) handleSchedule(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdminUser(w, r) {
+		return
+	}
+	if s.scheduler == nil {
+		http.Error(w, "Scheduler not available", http.StatusServiceUnavailable)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/schedules/")
+	if id == "" || strings.Contains(id, "/") {
+		http.Error(w, "Invalid schedule ID", http.StatusBadRequest)
+		return
+	}
+
+	var job scheduler.Job
+	var err error
+	switch r.Method {
+	case http.MethodGet:
+		job, err = s.scheduler.Get(id)
+
+	case http.MethodPut:
+		var req scheduleRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		job, err = s.scheduler.Update(id, req.job())
+
+	case http.MethodDelete:
+		if err = s.scheduler.Remove(id); err == nil {
+			log.Info("Removed schedule", "id", id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if errors.Is(err, scheduler.ErrNotFound) {
+		http.Error(w, "Schedule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/config"
+	"groq-go/internal/scheduler"
+)
+
+func TestScheduleEndpoints(t *testing.T) {
+	sched, err := scheduler.New(filepath.Join(t.TempDir(), "schedules.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sched.Stop()
+	s := newIdentityTestServer(t, true)
+	s.cfg = &config.Config{Web: config.WebConfig{AdminUsers: []string{"alice"}}}
+	admin := login(t, s, "10.0.0.1")
+
+	do := func(method, target, body, token string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.RemoteAddr = "10.0.0.1:1234"
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		if target == "/api/schedules" {
+			s.handleSchedules(rec, r)
+		} else {
+			s.handleSchedule(rec, r)
+		}
+		return rec
+	}
+	if rec := do(http.MethodGet, "/api/schedules", "", admin); rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a scheduler, got %d", rec.Code)
+	}
+	s.SetScheduler(sched)
+	if rec := do(http.MethodGet, "/api/schedules", "", ""); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := do(http.MethodPost, "/api/schedules", `{"cron": "bogus", "prompt": "p"}`, admin); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a bad cron expression, got %d", rec.Code)
+	}
+
+	rec := do(http.MethodPost, "/api/schedules", `{"name": "tests", "cron": "0 8 * * 1-5", "prompt": "run the tests", "tools": ["Bash"]}`, admin)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("Expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var job scheduler.Job
+	json.NewDecoder(rec.Body).Decode(&job)
+	if job.ID == "" || job.NextRun.IsZero() || len(job.Tools) != 1 {
+		t.Fatalf("Unexpected job %+v", job)
+	}
+
+	rec = do(http.MethodPut, "/api/schedules/"+job.ID, `{"name": "tests", "cron": "@daily", "prompt": "run all tests"}`, admin)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = do(http.MethodGet, "/api/schedules", "", admin)
+	var list struct {
+		Schedules []scheduler.Job `json:"schedules"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Schedules) != 1 || list.Schedules[0].Prompt != "run all tests" || list.Schedules[0].Cron != "@daily" {
+		t.Errorf("Unexpected listing %+v", list.Schedules)
+	}
+
+	if rec := do(http.MethodDelete, "/api/schedules/"+job.ID, "", admin); rec.Code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", rec.Code)
+	}
+	if rec := do(http.MethodGet, "/api/schedules/"+job.ID, "", admin); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 after removal, got %d", rec.Code)
+	}
+}
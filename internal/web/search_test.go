@@ -0,0 +1,46 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/storage"
+)
+
+func TestSessionSearch(t *testing.T) {
+	s := newShareTestServer(t)
+	for id, text := range map[string]string{"ws-1": "the websocket bug is fixed", "ws-2": "unrelated"} {
+		session := &storage.Session{ID: id, Messages: []client.Message{client.NewTextMessage("user", text)}, OwnerID: testUserID}
+		if err := s.storage.SaveSession(context.Background(), session); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	call := func(target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleSession(rec, httptest.NewRequest(http.MethodGet, target, nil))
+		return rec
+	}
+
+	rec := call("/api/sessions/search?q=websocket+bug")
+	var matches []storage.SessionMatch
+	if err := json.NewDecoder(rec.Body).Decode(&matches); err != nil {
+		t.Fatal(err)
+	}
+	if len(matches) != 1 || matches[0].ID != "ws-1" || matches[0].Snippet == "" {
+		t.Errorf("Expected ws-1 with a snippet, got %+v", matches)
+	}
+
+	if rec := call("/api/sessions/search?q=nothing"); rec.Body.String() != "[]\n" {
+		t.Errorf("Expected an empty list, got %q", rec.Body.String())
+	}
+	for _, target := range []string{"/api/sessions/search", "/api/sessions/search?q=a&limit=zero"} {
+		if rec := call(target); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d", target, rec.Code)
+		}
+	}
+}
@@ -1,33 +1,49 @@
 package web
 
 import (
-	"bytes"
 	"context"
-	"crypto/rand"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
-	"math/big"
+	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
+	"golang.org/x/crypto/bcrypt"
 
+	"groq-go/internal/audit"
 	"groq-go/internal/auth"
 	"groq-go/internal/client"
+	"groq-go/internal/config"
+	"groq-go/internal/conversation"
 	"groq-go/internal/credits"
+	"groq-go/internal/ids"
+	"groq-go/internal/janitor"
 	"groq-go/internal/knowledge"
 	"groq-go/internal/logging"
+	"groq-go/internal/mcp"
+	"groq-go/internal/memory"
+	"groq-go/internal/notify"
+	"groq-go/internal/origin"
+	"groq-go/internal/payments"
 	"groq-go/internal/plugin"
 	"groq-go/internal/project"
+	"groq-go/internal/scheduler"
+	"groq-go/internal/selfimprove"
 	"groq-go/internal/storage"
 	"groq-go/internal/tool"
+	"groq-go/internal/tts"
+	"groq-go/internal/upload"
 	"groq-go/internal/version"
 )
 
@@ -41,97 +57,65 @@ var (
 	timeHour     = time.Hour
 )
 
-// Random helper
-func randInt(max int) int {
-	n, _ := rand.Int(rand.Reader, big.NewInt(int64(max)))
-	return int(n.Int64())
-}
-
 //go:embed static/*
 var staticFiles embed.FS
 
-// Allowed origins for WebSocket connections
-var allowedOrigins = map[string]bool{
-	"localhost":            true,
-	"127.0.0.1":            true,
-	"groq-go-yuki.fly.dev": true,
-	"chatweb.ai":           true,
-}
-
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		origin := r.Header.Get("Origin")
-		if origin == "" {
-			return true // Allow non-browser clients
-		}
-		// Parse origin and check against allowlist
-		for allowed := range allowedOrigins {
-			if strings.Contains(origin, allowed) {
+// upgrader upgrades WebSocket requests from origins the server allows
+func (s *Server) upgrader() *websocket.Upgrader {
+	return &websocket.Upgrader{
+		Subprotocols: []string{wsAuthProtocol},
+		CheckOrigin: func(r *http.Request) bool {
+			if s.origins.Allow(r) {
 				return true
 			}
-		}
-		log.Warn("Blocked WebSocket connection", "origin", origin)
-		return false
-	},
-}
-
-// Rate limiter for API endpoints
-type rateLimiter struct {
-	mu       sync.Mutex
-	clients  map[string]*clientRate
-	maxReqs  int
-	window   time.Duration
-}
-
-type clientRate struct {
-	count    int
-	resetAt  time.Time
-}
-
-var apiLimiter = &rateLimiter{
-	clients: make(map[string]*clientRate),
-	maxReqs: 60,              // 60 requests
-	window:  time.Minute,     // per minute
-}
-
-func (rl *rateLimiter) allow(clientIP string) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-	client, exists := rl.clients[clientIP]
-	if !exists || now.After(client.resetAt) {
-		rl.clients[clientIP] = &clientRate{count: 1, resetAt: now.Add(rl.window)}
-		return true
-	}
-	if client.count >= rl.maxReqs {
-		return false
+			log.Warn("Blocked WebSocket connection", "origin", r.Header.Get("Origin"), "host", r.Host)
+			return false
+		},
 	}
-	client.count++
-	return true
 }
 
 // Server represents the web server
 type Server struct {
+	cfg          *config.Config
 	client       *client.Client
 	registry     *tool.Registry
 	executor     *tool.Executor
+	audit        *audit.Logger // Tool calls, served by /api/audit; nil if unavailable
 	storage      storage.Storage
 	auth         *auth.Manager
 	projects     *project.Manager
 	knowledge    *knowledge.KnowledgeBase
+	memory       *memory.Store // nil disables /api/memory and the prompt's memory section
 	plugins      *plugin.Manager
+	mcp          *mcp.Manager
+	scheduler    *scheduler.Scheduler // nil disables /api/schedules
+	parked       parkedSessions       // State of closed connections, reclaimed with a resume token
+	notifier     *notify.Dispatcher   // Served by /api/notifications/test; nil if unset
+	prompt       *conversation.Prompt // Deployment system prompt customization; nil for the built-in
 	versions     *version.Manager
 	versionProxy *version.Proxy
+	origins      *origin.Policy // WebSocket origins; nil allows the same host only
 	credits      *credits.Manager
+	payments     *payments.Stripe
+	janitor      *janitor.Janitor
+	uploads      *upload.Manager
+	setup        setupState
+	limits       *rateLimits // Budgets of every rate-limited API endpoint
+	metrics      wsMetrics
 	addr         string
 	uploadDir    string
+	imageDir     string // ImageGen output, served by /api/images/
+	tts          *tts.Service
+	startedAt    time.Time
+	conns        connRegistry
+	srvMu        sync.Mutex
+	httpServer   *http.Server
 }
 
 // NewServer creates a new web server
-func NewServer(c *client.Client, registry *tool.Registry, kb *knowledge.KnowledgeBase, pm *plugin.Manager, vm *version.Manager, addr string) *Server {
-	// Initialize storage
-	store, err := storage.NewFileStorage(storage.DefaultStorageDir())
+func NewServer(cfg *config.Config, c *client.Client, registry *tool.Registry, kb *knowledge.KnowledgeBase, pm *plugin.Manager, vm *version.Manager) *Server {
+	// Initialize storage (STORAGE_BACKEND=sqlite for the database backend)
+	store, err := storage.Open(context.Background(), os.Getenv("STORAGE_BACKEND"), storage.DefaultStorageDir())
 	if err != nil {
 		log.Warn("Failed to initialize storage", "error", err)
 	}
@@ -149,19 +133,29 @@ func NewServer(c *client.Client, registry *tool.Registry, kb *knowledge.Knowledg
 	}
 
 	// Initialize upload directory
-	home, _ := os.UserHomeDir()
-	uploadDir := filepath.Join(home, ".config", "groq-go", "uploads")
+	uploadDir := cfg.Web.UploadDir
 	os.MkdirAll(uploadDir, 0755)
 
+	// Speech is cached so repeated phrases skip the paid APIs
+	ttsCache, err := tts.NewCache(filepath.Join(config.Dir(), "tts-cache"), tts.CacheMaxBytes(cfg.TTS))
+	if err != nil {
+		log.Warn("TTS cache disabled", "error", err)
+		ttsCache = nil
+	}
+
 	// Initialize version proxy if version manager is available
 	var versionProxy *version.Proxy
 	if vm != nil {
-		// Get main domain from environment or default
-		mainDomain := os.Getenv("MAIN_DOMAIN")
-		if mainDomain == "" {
-			mainDomain = "chatweb.ai"
-		}
-		versionProxy = version.NewProxy(vm, mainDomain)
+		versionProxy = version.NewProxy(vm, cfg.Web.MainDomain)
+	}
+
+	// Without allowed origins, only pages served by this host may connect
+	origins, err := origin.NewPolicy(cfg.Web.AllowedOrigins)
+	if err != nil {
+		log.Warn("Ignoring allowed origins", "error", err)
+	}
+	if versionProxy != nil {
+		versionProxy.SetOriginPolicy(origins)
 	}
 
 	// Initialize credits manager
@@ -170,10 +164,45 @@ func NewServer(c *client.Client, registry *tool.Registry, kb *knowledge.Knowledg
 		log.Warn("Failed to initialize credits manager", "error", err)
 	}
 
+	// Initialize Stripe checkout for buying credits
+	var stripe *payments.Stripe
+	if paymentsConfig, err := payments.ConfigFromEnv(); err != nil {
+		log.Warn("Invalid payments config", "error", err)
+	} else if paymentsConfig.SecretKey != "" {
+		stripe = payments.NewStripe(paymentsConfig)
+		log.Info("Stripe checkout enabled", "packs", len(paymentsConfig.Packs))
+	}
+
+	// Initialize chunked uploads
+	uploadManager, err := upload.NewManager(uploadDir)
+	if err != nil {
+		log.Warn("Failed to initialize chunked uploads", "error", err)
+	}
+
+	// Every tool call is recorded for auditing
+	executor := tool.NewExecutor(registry)
+	auditLog, err := audit.NewLogger(audit.DefaultPath())
+	if err != nil {
+		log.Warn("Audit log disabled", "error", err)
+		auditLog = nil
+	} else {
+		executor.AddHook(audit.NewHook(auditLog, ""))
+	}
+
+	// Initialize janitor for orphaned versions, stale uploads and caches
+	gcConfig := janitor.DefaultConfig()
+	gcConfig.UploadsDir = uploadDir
+	gcConfig.PartialDir = filepath.Join(uploadDir, upload.PartialDirName)
+	if store != nil {
+		gcConfig.Sessions = store
+	}
+
 	return &Server{
+		cfg:          cfg,
 		client:       c,
 		registry:     registry,
-		executor:     tool.NewExecutor(registry),
+		executor:     executor,
+		audit:        auditLog,
 		storage:      store,
 		auth:         authManager,
 		projects:     projectManager,
@@ -181,96 +210,205 @@ func NewServer(c *client.Client, registry *tool.Registry, kb *knowledge.Knowledg
 		plugins:      pm,
 		versions:     vm,
 		versionProxy: versionProxy,
+		origins:      origins,
 		credits:      creditsManager,
-		addr:         addr,
+		payments:     stripe,
+		janitor:      janitor.New(gcConfig),
+		uploads:      uploadManager,
+		limits:       newRateLimits(rateLimitConfigFrom(cfg.Web.RateLimits)),
+		addr:         cfg.Web.Addr,
 		uploadDir:    uploadDir,
+		imageDir:     filepath.Join(config.Dir(), "images"),
+		tts:          tts.NewService(ttsCache, tts.NewProviders(cfg.TTS)...),
+		startedAt:    time.Now(),
 	}
 }
 
-// rateLimitMiddleware wraps handlers with rate limiting
-func rateLimitMiddleware(next http.HandlerFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		clientIP := r.RemoteAddr
-		if fwdFor := r.Header.Get("X-Forwarded-For"); fwdFor != "" {
-			clientIP = strings.Split(fwdFor, ",")[0]
+// authExemptPaths are reachable without a token when users are configured
+var authExemptPaths = map[string]bool{
+	"/api/auth/login":      true,
+	"/api/auth/register":   true,
+	"/api/auth/status":     true,
+	"/api/credits/webhook": true, // Verified by its Stripe signature
+}
+
+// authMiddleware requires a valid token on /api/* and /ws once users are
+// configured, and stores the username in the request context. Static files
+// and public share views stay open.
+func (s *Server) authMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		protected := strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/ws"
+		if !protected || authExemptPaths[r.URL.Path] || !s.authRequired() {
+			next.ServeHTTP(w, r)
+			return
 		}
-		if !apiLimiter.allow(clientIP) {
-			log.Warn("Rate limit exceeded", "client_ip", clientIP)
-			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+		user, err := s.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="groq-go"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
-		next(w, r)
-	}
+		next.ServeHTTP(w, r.WithContext(withUsername(r.Context(), user.Username)))
+	})
 }
 
 // Start starts the web server
 func (s *Server) Start() error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(l)
+}
+
+// handler sets up the routes and background jobs and returns the root handler
+func (s *Server) handler() (http.Handler, error) {
 	mux := http.NewServeMux()
 
 	// Serve static files with proper headers
 	staticFS, err := fs.Sub(staticFiles, "static")
 	if err != nil {
-		return err
+		return nil, err
 	}
 	fileServer := http.FileServer(http.FS(staticFS))
 	mux.Handle("/", addSecurityHeaders(fileServer))
 
+	// Liveness for the version manager and load balancers (no auth, no rate limit)
+	mux.HandleFunc("/healthz", s.handleHealthz)
+
 	// WebSocket endpoint (no rate limit - managed separately)
 	mux.HandleFunc("/ws", s.handleWebSocket)
 
 	// API endpoints with rate limiting
-	mux.HandleFunc("/api/models", rateLimitMiddleware(s.handleModels))
-	mux.HandleFunc("/api/upload", rateLimitMiddleware(s.handleUpload))
-	mux.HandleFunc("/api/sessions", rateLimitMiddleware(s.handleSessions))
-	mux.HandleFunc("/api/sessions/", rateLimitMiddleware(s.handleSession))
-	mux.HandleFunc("/api/auth/login", rateLimitMiddleware(s.handleLogin))
-	mux.HandleFunc("/api/auth/logout", rateLimitMiddleware(s.handleLogout))
-	mux.HandleFunc("/api/auth/status", rateLimitMiddleware(s.handleAuthStatus))
-	mux.HandleFunc("/api/auth/register", rateLimitMiddleware(s.handleRegister))
-	mux.HandleFunc("/api/projects", rateLimitMiddleware(s.handleProjects))
-	mux.HandleFunc("/api/projects/", rateLimitMiddleware(s.handleProject))
-	mux.HandleFunc("/api/share", rateLimitMiddleware(s.handleShare))
-	mux.HandleFunc("/share/", s.handleSharedView) // Public endpoint, no auth
-	mux.HandleFunc("/api/knowledge", rateLimitMiddleware(s.handleKnowledge))
-	mux.HandleFunc("/api/knowledge/", rateLimitMiddleware(s.handleKnowledgeDocument))
-	mux.HandleFunc("/api/plugins", rateLimitMiddleware(s.handlePlugins))
-	mux.HandleFunc("/api/plugins/", rateLimitMiddleware(s.handlePlugin))
-	mux.HandleFunc("/api/tts", rateLimitMiddleware(s.handleTTS))
-	mux.HandleFunc("/api/tts/elevenlabs", rateLimitMiddleware(s.handleElevenLabsTTS))
+	mux.HandleFunc("/api/models", rateLimitMiddleware(s.limits, s.handleModels))
+	mux.HandleFunc("/api/tools", rateLimitMiddleware(s.limits, s.handleTools))
+	mux.HandleFunc("/api/upload", rateLimitMiddleware(s.limits, s.handleUpload))
+	mux.HandleFunc("/api/upload/", rateLimitMiddleware(s.limits, s.handleChunkedUpload))
+	mux.HandleFunc("/api/uploads/", rateLimitMiddleware(s.limits, s.handleUploadFile))
+	mux.HandleFunc("/api/images/", rateLimitMiddleware(s.limits, s.handleImage))
+	mux.HandleFunc("/api/sessions", rateLimitMiddleware(s.limits, s.handleSessions))
+	mux.HandleFunc("/api/sessions/", rateLimitMiddleware(s.limits, s.handleSession))
+	mux.HandleFunc("/api/auth/login", rateLimitMiddleware(s.limits, s.handleLogin))
+	mux.HandleFunc("/api/auth/logout", rateLimitMiddleware(s.limits, s.handleLogout))
+	mux.HandleFunc("/api/auth/status", rateLimitMiddleware(s.limits, s.handleAuthStatus))
+	mux.HandleFunc("/api/auth/register", rateLimitMiddleware(s.limits, s.handleRegister))
+	mux.HandleFunc("/api/auth/refresh", rateLimitMiddleware(s.limits, s.handleAuthRefresh))
+	mux.HandleFunc("/api/auth/password", rateLimitMiddleware(s.limits, s.handleAuthPassword))
+	mux.HandleFunc("/api/projects", rateLimitMiddleware(s.limits, s.handleProjects))
+	mux.HandleFunc("/api/projects/", rateLimitMiddleware(s.limits, s.handleProject))
+	mux.HandleFunc("/api/share", rateLimitMiddleware(s.limits, s.handleShare))
+	mux.HandleFunc("/api/share/", rateLimitMiddleware(s.limits, s.handleShareItem))
+	mux.HandleFunc("/share/", rateLimitMiddleware(s.limits, s.handleSharedView)) // Public endpoint, no auth
+	mux.HandleFunc("/api/knowledge", rateLimitMiddleware(s.limits, s.handleKnowledge))
+	mux.HandleFunc("/api/knowledge/", rateLimitMiddleware(s.limits, s.handleKnowledgeDocument))
+	mux.HandleFunc("/api/memory", rateLimitMiddleware(s.limits, s.handleMemories))
+	mux.HandleFunc("/api/memory/", rateLimitMiddleware(s.limits, s.handleMemory))
+	mux.HandleFunc("/api/plugins", rateLimitMiddleware(s.limits, s.handlePlugins))
+	mux.HandleFunc("/api/plugins/", rateLimitMiddleware(s.limits, s.handlePlugin))
+	mux.HandleFunc("/api/mcp/servers", rateLimitMiddleware(s.limits, s.handleMCPServers))
+	mux.HandleFunc("/api/mcp/servers/", rateLimitMiddleware(s.limits, s.handleMCPServer))
+	mux.HandleFunc("/api/mcp/reload", rateLimitMiddleware(s.limits, s.handleMCPReload))
+	mux.HandleFunc("/api/tts", rateLimitMiddleware(s.limits, s.handleTTS))
+	mux.HandleFunc("/api/transcribe", rateLimitMiddleware(s.limits, s.handleTranscribe))
+	mux.HandleFunc("/api/schedules", rateLimitMiddleware(s.limits, s.handleSchedules))
+	mux.HandleFunc("/api/schedules/", rateLimitMiddleware(s.limits, s.handleSchedule))
+	mux.HandleFunc("/api/notifications/test", rateLimitMiddleware(s.limits, s.handleNotificationTest))
 
 	// Version management endpoints
-	mux.HandleFunc("/api/versions", rateLimitMiddleware(s.handleVersions))
-	mux.HandleFunc("/api/versions/", rateLimitMiddleware(s.handleVersion))
+	mux.HandleFunc("/api/versions", rateLimitMiddleware(s.limits, s.handleVersions))
+	mux.HandleFunc("/api/versions/", rateLimitMiddleware(s.limits, s.handleVersion))
 
 	// Credit management endpoints
-	mux.HandleFunc("/api/credits", rateLimitMiddleware(s.handleCredits))
-	mux.HandleFunc("/api/credits/", rateLimitMiddleware(s.handleCreditAction))
+	mux.HandleFunc("/api/credits", rateLimitMiddleware(s.limits, s.handleCredits))
+	mux.HandleFunc("/api/credits/", rateLimitMiddleware(s.limits, s.handleCreditAction))
+	mux.HandleFunc("/api/credits/summary", rateLimitMiddleware(s.limits, s.handleCreditsSummary))
+	mux.HandleFunc("/api/credits/checkout", rateLimitMiddleware(s.limits, s.handleCreditCheckout))
+	mux.HandleFunc("/api/credits/webhook", s.handleCreditWebhook) // Stripe retries, no rate limit
+
+	// Resource usage
+	mux.HandleFunc("/api/metrics", rateLimitMiddleware(s.limits, s.handleMetrics))
 
-	log.Info("Starting web server", "addr", s.addr)
+	// First-run setup
+	mux.HandleFunc("/setup", s.handleSetupPage)
+	mux.HandleFunc("/api/setup", rateLimitMiddleware(s.limits, s.handleSetup))
+
+	// Admin endpoints
+	mux.HandleFunc("/api/admin/gc", rateLimitMiddleware(s.limits, s.handleAdminGC))
+	mux.HandleFunc("/api/admin/credits/reload", rateLimitMiddleware(s.limits, s.handleAdminCreditsReload))
+	mux.HandleFunc("/api/admin/credits/limit", rateLimitMiddleware(s.limits, s.handleAdminCreditsLimit))
+	mux.HandleFunc("/api/admin/backup", rateLimitMiddleware(s.limits, s.handleAdminBackup))
+	mux.HandleFunc("/api/admin/restore", rateLimitMiddleware(s.limits, s.handleAdminRestore))
+	mux.HandleFunc("/api/admin/ratelimits", rateLimitMiddleware(s.limits, s.handleAdminRateLimits))
+	mux.HandleFunc("/api/config", rateLimitMiddleware(s.limits, s.handleConfig))
+	mux.HandleFunc("/api/audit", rateLimitMiddleware(s.limits, s.handleAudit))
+
+	// Reload pricing on SIGHUP
+	s.watchReloadSignal()
+
+	// Periodic garbage collection of data directories
+	s.janitor.Start(context.Background(), janitor.DefaultInterval)
+
+	// Recurring agent jobs
+	s.startScheduler()
+
+	// Release disconnected sessions nobody came back for
+	s.parked.startSweeper(context.Background(), parkedSweepInterval)
+
+	// Drop clients whose rate limit window has passed
+	s.limits.startEviction(context.Background(), rateLimitEvictInterval)
+
+	// Drop expired login tokens
+	if s.auth != nil {
+		s.auth.StartCleanup(context.Background(), auth.DefaultCleanupInterval)
+	}
+
+	// Lock the app until first-run setup has completed, then require
+	// tokens once users exist
+	var handler http.Handler = s.setupGate(s.authMiddleware(mux))
 
 	// Wrap with version proxy if available
-	var handler http.Handler = mux
 	if s.versionProxy != nil {
-		handler = s.versionProxy.ProxyHandler(mux)
-		log.Info("Version proxy enabled", "domain", os.Getenv("MAIN_DOMAIN"))
+		handler = s.versionProxy.ProxyHandler(handler)
+		log.Info("Version proxy enabled", "domain", s.cfg.Web.MainDomain, "path_prefix", version.PathPrefix)
 	}
 
-	return http.ListenAndServe(s.addr, handler)
+	return handler, nil
 }
 
 // WSMessage represents WebSocket message types
 type WSMessage struct {
-	Type     string   `json:"type"`
-	Content  string   `json:"content,omitempty"`
-	Tool     string   `json:"tool,omitempty"`
-	Args     string   `json:"args,omitempty"`
-	Result   string   `json:"result,omitempty"`
-	Error    string   `json:"error,omitempty"`
-	Model    string   `json:"model,omitempty"`
-	DiffData string   `json:"diff_data,omitempty"` // For edit tool diffs
-	Images   []string `json:"images,omitempty"`    // Base64 image data for vision
-	ShareID  string   `json:"share_id,omitempty"`  // For sharing conversations
-	Mode     string   `json:"mode,omitempty"`      // "tools" or "improve"
+	Type        string   `json:"type"`
+	Content     string   `json:"content,omitempty"`
+	Tool        string   `json:"tool,omitempty"`
+	Args        string   `json:"args,omitempty"`
+	Result      string   `json:"result,omitempty"`
+	Error       string   `json:"error,omitempty"`
+	Code        string   `json:"code,omitempty"`        // Kind of provider error, sent with "error"; see apiErrorMessage
+	RetryAfter  int      `json:"retry_after,omitempty"` // Seconds to wait, sent with "error" when rate limited
+	Model       string   `json:"model,omitempty"`
+	DiffData    string   `json:"diff_data,omitempty"`   // For edit tool diffs
+	Images      []string `json:"images,omitempty"`      // Base64 image data for vision
+	ImageIDs    []string `json:"image_ids,omitempty"`   // Uploaded images for vision, see /api/upload
+	ShareID     string   `json:"share_id,omitempty"`    // For sharing conversations
+	Mode        string   `json:"mode,omitempty"`        // "tools" or "improve"
+	Choices     []string `json:"choices,omitempty"`     // Suggested answers for a question
+	SessionID   string   `json:"session_id,omitempty"`  // Persisted chat session, see "resume"
+	MaxTokens   int      `json:"max_tokens,omitempty"`  // Optional per-message completion limit
+	Temperature *float64 `json:"temperature,omitempty"` // Optional per-message temperature
+	Tools       []string `json:"tools,omitempty"`       // Tool names, sent with "tools_changed"
+	Progress    *int     `json:"progress,omitempty"`    // Percent done, sent with "tool_progress" when known
+	URL         string   `json:"url,omitempty"`         // Image to show, sent with "image"
+	ProjectID   string   `json:"project_id,omitempty"`  // Project whose root confines file tools, sent with "project"
+	System      string   `json:"system,omitempty"`      // Session instructions for the system prompt, sent with "system", "mode" or "chat"
+	RequestID   string   `json:"request_id,omitempty"`  // Tool call awaiting approval, see "approval_request"
+	Decision    string   `json:"decision,omitempty"`    // "approve", "deny" or "always", sent with "approval_response"
+
+	Rules []tool.ApprovalRule `json:"rules,omitempty"` // Auto-approve rules, sent with "approval_rules"
+
+	// Resume is the token of this connection, sent in the welcome message.
+	// A reloaded page returns it on its first message to reattach.
+	Resume   string          `json:"resume,omitempty"`
+	Messages []replayMessage `json:"messages,omitempty"` // Prior conversation, sent with "history_replay"
 }
 
 // Store for tracking tool call args
@@ -280,115 +418,303 @@ type toolCallInfo struct {
 }
 
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+	// Enforce the global connection cap before upgrading
+	if !s.metrics.acquire() {
+		log.Warn("WebSocket connection rejected: at capacity", "connections", s.metrics.connections.Load())
+		rejectConnection(w)
+		return
+	}
+	defer s.metrics.release()
+
+	// Credits follow the account when users are configured, the IP otherwise
+	clientIP := requestClientIP(r)
+	userID, err := s.resolveUserID(r)
 	if err != nil {
-		log.Error("WebSocket upgrade failed", "error", err)
+		log.Warn("WebSocket authentication failed", "client_ip", clientIP)
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
-	defer conn.Close()
 
-	clientIP := r.RemoteAddr
-	if fwdFor := r.Header.Get("X-Forwarded-For"); fwdFor != "" {
-		clientIP = strings.Split(fwdFor, ",")[0]
+	conn, err := s.upgrader().Upgrade(w, r, nil)
+	if err != nil {
+		log.Error("WebSocket upgrade failed", "error", err)
+		return
 	}
+	defer conn.Close()
 	log.Info("New WebSocket connection", "client_ip", clientIP)
-
-	// Create or get user based on IP (can be enhanced with proper auth later)
-	userID := "user_" + strings.ReplaceAll(strings.ReplaceAll(clientIP, ".", "_"), ":", "_")
 	var userCredits *credits.UserCredits
 	if s.credits != nil {
 		userCredits = s.credits.GetOrCreateUser(userID, "")
 		log.Info("User credits", "user_id", userID, "balance", userCredits.Balance)
 	}
 
+	// Per-connection state; the model starts at the server default and
+	// can be changed for this session only
+	sess := &chatSession{
+		client:   s.client,
+		clientIP: clientIP,
+		userID:   userID,
+		mode:      "tools", // Default mode: tools
+		reads:     tool.NewReadTracker(),
+		approvals: tool.NewApprovalRules(nil),
+
+		resumeToken: newResumeToken(),
+	}
+	if s.storage != nil {
+		sess.stored = newStoredSession(sess.userID)
+	}
+
+	// Registry changes are pushed between turns; a running turn picks
+	// them up on its next request
+	toolChanges, unsubscribe := s.registry.Subscribe()
+	defer unsubscribe()
+
 	// Send welcome message with credit info
-	welcomeMsg := fmt.Sprintf("Connected to groq-go. Model: %s", s.client.Model())
+	welcomeMsg := fmt.Sprintf("Connected to groq-go. Model: %s", sess.client.Model())
 	if userCredits != nil {
 		welcomeMsg += fmt.Sprintf(" | Credits: %d", userCredits.Balance)
 	}
-	s.sendMessage(conn, WSMessage{
+	welcome := WSMessage{
 		Type:    "system",
 		Content: welcomeMsg,
-	})
-
-	// Message history for this session
-	var history []client.Message
-	currentMode := "tools" // Default mode: tools
+		Resume:  sess.resumeToken,
+	}
+	if sess.stored != nil {
+		welcome.SessionID = sess.stored.ID
+	}
+	s.sendMessage(conn, welcome)
 
-	history = append(history, client.Message{
+	// Message history for this session, bounded in memory
+	history := newConnHistory(&s.metrics, client.Message{
 		Role:    "system",
-		Content: s.getSystemPrompt(currentMode),
+		Content: s.getSystemPrompt(sess),
 	})
+	sess.history = history
+	// Kept for a while after the connection closes, see reattach
+	defer s.parked.park(sess)
+
+	// Turns run on a worker goroutine so the read loop stays free to
+	// deliver answers to questions asked mid-turn. Cancelled on disconnect.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	asker := &wsAsker{server: s, conn: conn}
+
+	// Tracked so Shutdown can notify, drain and close it
+	if !s.conns.add(conn, cancel) {
+		s.sendMessage(conn, WSMessage{Type: "error", Error: restartNotice})
+		return
+	}
+	defer s.conns.remove(conn)
+
+	// Dead connections fail the read loop below, which cancels ctx
+	stopPings := make(chan struct{})
+	defer close(stopPings)
+	extendDeadline := keepAlive(conn, stopPings)
+
+	incoming := make(chan []byte, 16)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		first := true
+		for {
+			var message []byte
+			select {
+			case m, ok := <-incoming:
+				if !ok {
+					return
+				}
+				message = m
+			case <-toolChanges:
+				s.sendToolsChanged(conn, sess.mode)
+				continue
+			}
+
+			var msg WSMessage
+			if err := json.Unmarshal(message, &msg); err != nil {
+				s.sendMessage(conn, WSMessage{Type: "error", Error: "Invalid message format"})
+				continue
+			}
+			// A reloaded page presents its old token first; an expired or
+			// unknown one leaves the fresh session in place
+			reattached := first && msg.Resume != "" && s.reattach(conn, sess, msg.Resume)
+			if reattached {
+				log.Info("Session reattached", "messages", len(sess.history.Messages())-1, "client_ip", clientIP)
+			}
+			first = false
 
-	var mu sync.Mutex
+			switch msg.Type {
+			case "system":
+				if err := s.setSessionPrompt(sess, msg.System); err != nil {
+					s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+					continue
+				}
+				s.sendMessage(conn, WSMessage{Type: "system", Content: "System prompt updated"})
+
+			case "mode":
+				if msg.System != "" {
+					if err := s.setSessionPrompt(sess, msg.System); err != nil {
+						s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+						continue
+					}
+				}
+				// Handle mode change
+				if msg.Mode == "tools" || msg.Mode == "improve" {
+					sess.mode = msg.Mode
+					// Update system prompt in history
+					sess.history.SetSystem(client.Message{
+						Role:    "system",
+						Content: s.getSystemPrompt(sess),
+					})
+					log.Info("Mode changed", "mode", sess.mode, "client_ip", clientIP)
+				}
+
+			case "chat":
+				s.runTurn(conn, sess, msg, asker)
+
+			case "project":
+				content, err := s.selectProject(sess, msg.ProjectID)
+				if err != nil {
+					s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+					continue
+				}
+				// The prompt names the project's directory
+				sess.history.SetSystem(client.Message{Role: "system", Content: s.getSystemPrompt(sess)})
+				log.Info("Project selected", "project_id", msg.ProjectID, "client_ip", clientIP)
+				s.sendMessage(conn, WSMessage{Type: "project", ProjectID: msg.ProjectID, Content: content})
+
+			case "model":
+				if msg.Model != "" && !s.modelAllowed(msg.Model) {
+					s.sendMessage(conn, WSMessage{Type: "error", Error: fmt.Sprintf("%s is not available in read-only mode", msg.Model)})
+				} else if msg.Model != "" {
+					log.Info("Model changed", "model", msg.Model, "client_ip", clientIP)
+					sess.client = s.client.WithModelOverride(msg.Model)
+					s.sendMessage(conn, WSMessage{
+						Type:    "system",
+						Content: fmt.Sprintf("Model changed to: %s", msg.Model),
+					})
+				}
+
+			case "resume":
+				if reattached {
+					continue
+				}
+				if msg.SessionID == "" {
+					// Only a resume token, and it had expired
+					if sess.stored != nil {
+						s.sendMessage(conn, WSMessage{Type: "session", SessionID: sess.stored.ID})
+					}
+					continue
+				}
+				n, err := s.resumeSession(ctx, sess, msg.SessionID)
+				if err != nil {
+					log.Warn("Failed to resume session", "session_id", msg.SessionID, "client_ip", clientIP, "error", err)
+					s.sendMessage(conn, WSMessage{Type: "error", Error: "Could not resume conversation: " + err.Error()})
+					if sess.stored != nil {
+						// Keep using the fresh session from the welcome message
+						s.sendMessage(conn, WSMessage{Type: "session", SessionID: sess.stored.ID})
+					}
+					continue
+				}
+				log.Info("Session resumed", "session_id", msg.SessionID, "messages", n, "client_ip", clientIP)
+				s.sendMessage(conn, WSMessage{
+					Type:      "session",
+					SessionID: msg.SessionID,
+					Content:   fmt.Sprintf("Resumed conversation (%d messages)", n),
+				})
+
+			case "approval_rules":
+				s.setApprovalRules(ctx, conn, sess, msg)
+
+			case "clear":
+				log.Info("Conversation cleared", "client_ip", clientIP)
+				sess.history.Clear() // Keep system message
+				sess.reads.Reset()
+				if sess.stored != nil {
+					// Start a new session; the old one stays in storage
+					sess.stored = newStoredSession(sess.userID)
+					s.sendMessage(conn, WSMessage{Type: "session", SessionID: sess.stored.ID})
+				}
+				s.sendMessage(conn, WSMessage{
+					Type:    "system",
+					Content: "Conversation cleared",
+				})
+			}
+		}
+	}()
 
 	for {
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				log.Info("WebSocket keepalive timed out", "client_ip", clientIP)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Error("WebSocket read error", "error", err)
 			}
 			break
 		}
+		extendDeadline()
 
+		// Answers and stops go straight to the running turn
 		var msg WSMessage
-		if err := json.Unmarshal(message, &msg); err != nil {
-			s.sendMessage(conn, WSMessage{Type: "error", Error: "Invalid message format"})
-			continue
-		}
-
-		switch msg.Type {
-		case "mode":
-			// Handle mode change
-			if msg.Mode == "tools" || msg.Mode == "improve" {
-				currentMode = msg.Mode
-				// Update system prompt in history
-				history[0] = client.Message{
-					Role:    "system",
-					Content: s.getSystemPrompt(currentMode),
+		if json.Unmarshal(message, &msg) == nil {
+			switch msg.Type {
+			case "answer", "answer_cancel":
+				if !asker.reply(msg) {
+					log.Debug("Answer without pending question", "client_ip", clientIP)
 				}
-				log.Info("Mode changed", "mode", currentMode, "client_ip", clientIP)
-			}
-
-		case "chat":
-			log.Debug("User message", "client_ip", clientIP, "content", truncateLog(msg.Content, 100))
-			if len(msg.Images) > 0 {
-				log.Debug("Message includes images", "count", len(msg.Images))
-			}
-			// Update mode if provided with chat message
-			if msg.Mode != "" && (msg.Mode == "tools" || msg.Mode == "improve") {
-				currentMode = msg.Mode
-				history[0] = client.Message{
-					Role:    "system",
-					Content: s.getSystemPrompt(currentMode),
+				continue
+			case "approval_response":
+				if !asker.approvalReply(msg) {
+					log.Debug("Approval without pending request", "request_id", msg.RequestID, "client_ip", clientIP)
+				}
+				continue
+			case "stop":
+				if !sess.stopTurn() {
+					log.Debug("Stop without running turn", "client_ip", clientIP)
+				}
+				continue
+			case "chat":
+				// One turn at a time; the worker releases it when done
+				if !sess.claimTurn(ctx) {
+					s.sendMessage(conn, WSMessage{Type: "error", Error: errTurnInProgress.Error()})
+					continue
 				}
 			}
-			mu.Lock()
-			s.handleChat(conn, msg.Content, msg.Images, &history, clientIP, userID, currentMode)
-			mu.Unlock()
-
-		case "model":
-			if msg.Model != "" {
-				log.Info("Model changed", "model", msg.Model, "client_ip", clientIP)
-				s.client.SetModel(msg.Model)
-				s.sendMessage(conn, WSMessage{
-					Type:    "system",
-					Content: fmt.Sprintf("Model changed to: %s", msg.Model),
-				})
-			}
-
-		case "clear":
-			log.Info("Conversation cleared", "client_ip", clientIP)
-			history = history[:1] // Keep system message
-			s.sendMessage(conn, WSMessage{
-				Type:    "system",
-				Content: "Conversation cleared",
-			})
 		}
+		incoming <- message
+	}
+	cancel()
+	close(incoming)
+	<-done
+	if sess.unsaved {
+		// A turn was cut short, e.g. by a shutdown
+		s.saveSession(context.Background(), sess)
 	}
 	log.Info("WebSocket connection closed", "client_ip", clientIP)
 }
 
+// toolsForMode returns the tools offered in a chat mode, as allowed by
+// the tool policy
+func (s *Server) toolsForMode(mode string) []client.Tool {
+	if mode == "improve" {
+		// Improvement mode: only SelfImprove tool
+		return s.registry.ToClientToolsForMode(mode, "SelfImprove")
+	}
+	// Tools mode: all tools except SelfImprove (unless explicitly needed)
+	return s.registry.ToClientToolsForMode(mode)
+}
+
+// sendToolsChanged pushes the current tool names for a mode to a client
+func (s *Server) sendToolsChanged(conn *websocket.Conn, mode string) {
+	var names []string
+	for _, t := range s.toolsForMode(mode) {
+		names = append(names, t.Function.Name)
+	}
+	sort.Strings(names)
+	s.sendMessage(conn, WSMessage{Type: "tools_changed", Tools: names})
+}
+
 func truncateLog(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -396,64 +722,179 @@ func truncateLog(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-func (s *Server) handleChat(conn *websocket.Conn, userMessage string, images []string, history *[]client.Message, clientIP string, userID string, mode string) {
-	ctx := context.Background()
+// chatSession is the per-connection state of a WebSocket chat
+type chatSession struct {
+	client   *client.Client // Session-scoped; never mutate the shared server client
+	history  *connHistory
+	clientIP string
+	userID   string
+	mode     string            // "tools" or "improve"
+	stored   *storage.Session  // Persisted copy, saved after each turn; nil without storage
+	unsaved  bool              // A turn changed history since the last save
+	reads    *tool.ReadTracker // Files the model has seen, reset with the conversation
+
+	// approvals auto-approve tool calls; saved with the session, see "approval_rules"
+	approvals *tool.ApprovalRules
+
+	// resumeToken reclaims this state from a new connection, see reattach
+	resumeToken string
+
+	// projectID is selected with "project"; file tools stay in its root
+	projectID string
+	// systemPrompt holds instructions the client added with "system"
+	systemPrompt string
+
+	turnMu     sync.Mutex
+	turnCtx    context.Context // Running turn, nil when idle
+	turnCancel context.CancelCauseFunc
+}
+
+// handleChat runs one turn: the model's replies and the tool calls they
+// ask for. The caller signals the end of the turn with "done".
+func (s *Server) handleChat(ctx context.Context, conn *websocket.Conn, sess *chatSession, userMessage string, images, imageIDs []string, opts client.RequestOptions) {
+	history, clientIP, userID, mode := sess.history, sess.clientIP, sess.userID, sess.mode
+	if errMsg := s.turnLimitError(history.Messages()); errMsg != "" {
+		s.sendMessage(conn, WSMessage{Type: "error", Error: errMsg})
+		return
+	}
+	sess.unsaved = true
 
-	// Check credits before processing
-	model := s.client.Model()
+	// Check credits before processing; the estimate covers the prompt only
+	model := sess.client.Model()
 	if s.credits != nil {
-		hasCredits, balance, cost := s.credits.CheckCredits(userID, model)
-		if !hasCredits {
-			s.sendMessage(conn, WSMessage{
-				Type:  "error",
-				Error: fmt.Sprintf("Insufficient credits: need %d, have %d. Please add more credits.", cost, balance),
-			})
-			s.sendMessage(conn, WSMessage{Type: "done"})
+		promptBytes := int(history.Bytes()) + len(userMessage)
+		if errMsg := s.creditError(userID, clientIP, model, promptBytes); errMsg != "" {
+			s.sendMessage(conn, WSMessage{Type: "error", Error: errMsg})
 			return
 		}
 	}
 
 	// Add user message (with images if present)
 	var msg client.Message
-	if len(images) > 0 {
+	if len(images) > 0 || len(imageIDs) > 0 {
+		// Spill image data to disk so only references stay in memory
+		refs := make([]string, 0, len(images)+len(imageIDs))
+		for _, img := range images {
+			ref, err := s.spillImage(img)
+			if err != nil {
+				s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+				return
+			}
+			refs = append(refs, ref)
+		}
+		// Uploaded images are already on disk
+		for _, id := range imageIDs {
+			ref, err := s.uploadImageRef(id)
+			if err != nil {
+				s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+				return
+			}
+			refs = append(refs, ref)
+		}
 		// Create multimodal message for vision models
-		msg = client.NewVisionMessage("user", userMessage, images...)
+		msg = client.NewVisionMessage("user", userMessage, refs...)
 	} else {
 		msg = client.Message{Role: "user", Content: userMessage}
 	}
-	*history = append(*history, msg)
+	history.Append(msg)
 
-	// Get tools based on mode
-	var tools []client.Tool
-	if mode == "improve" {
-		// Improvement mode: only SelfImprove tool
-		tools = s.registry.ToClientToolsFiltered([]string{"SelfImprove"})
-	} else {
-		// Tools mode: all tools except SelfImprove (unless explicitly needed)
-		tools = s.registry.ToClientTools()
+	ctx = tool.WithMode(ctx, mode)
+	ctx = tool.WithReadTracker(ctx, sess.reads)
+	ctx = tool.WithUploads(ctx, s.findUpload)
+	if s.credits != nil {
+		ctx = tool.WithBilling(ctx, userBilling{s: s, userID: userID, clientIP: clientIP})
+	}
+	ctx = s.executor.StartTurn(ctx)
+	caller := tool.Caller{User: userID}
+	if sess.stored != nil {
+		caller.SessionID = sess.stored.ID
 	}
+	ctx = tool.WithCaller(ctx, caller)
 
 	// Process with potential tool calls
+	var usage client.Usage
+	billed := false // Some response came from the provider rather than the cache
+	stopped, failed := false, false
+	toolBudget, halved := client.ToolBudget(model), false
+	servedModel := model
 	for {
+		// Tools are listed per request so registry changes apply mid-turn
+		tools := tool.FitToolBudget(model, s.toolsForMode(mode), toolBudget)
+
+		// Summarize old turns before the history outgrows the context window
+		if model := sess.client.Model(); conversation.NeedsCompaction(history.Messages(), model) {
+			before := conversation.EstimateTokens(history.Messages())
+			msgs, summaryUsage, err := conversation.CompactMessages(ctx, sess.client, history.Messages(), conversation.TokenBudget(model), conversation.KeepTurns)
+			if err != nil {
+				log.Warn("History summary failed", "client_ip", clientIP, "error", err)
+			}
+			// The summary is billed with the turn
+			if summaryUsage.PromptTokens+summaryUsage.CompletionTokens > 0 {
+				usage.Add(summaryUsage)
+				billed = true
+			}
+			history.Replace(msgs)
+			log.Info("Compacted history for context window", "client_ip", clientIP, "model", model,
+				"before_tokens", before, "after_tokens", conversation.EstimateTokens(msgs))
+		}
+
 		// Call API with streaming
-		stream, err := s.client.ChatCompletionStream(ctx, *history, tools)
+		stream, err := sess.client.ChatCompletionStream(ctx, s.resolveImages(history.Messages()), tools, opts)
+		if err != nil && client.IsContextLengthExceeded(err) && !halved {
+			// Retry once with half the tool budget for the rest of the turn
+			toolBudget, halved = toolBudget/2, true
+			log.Warn("Request too large, retrying with fewer tools", "client_ip", clientIP, "model", model, "budget_tokens", toolBudget)
+			tools = tool.FitToolBudget(model, s.toolsForMode(mode), toolBudget)
+			stream, err = sess.client.ChatCompletionStream(ctx, s.resolveImages(history.Messages()), tools, opts)
+		}
+		if err != nil && turnStopped(ctx) {
+			stopped = true
+			break
+		}
 		if err != nil {
 			log.Error("API error", "client_ip", clientIP, "error", err)
-			s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
-			return
+			s.sendMessage(conn, apiErrorMessage(err))
+			failed = true
+			break
+		}
+		if served := stream.Model(); served != servedModel {
+			// The provider is down; the turn is charged at the model that answered
+			servedModel = served
+			if served != model {
+				log.Warn("Fell back to another model", "client_ip", clientIP, "model", model, "fallback", served)
+				s.sendMessage(conn, WSMessage{Type: "system", Model: served, Content: fmt.Sprintf("%s is unavailable, fell back to %s", model, served)})
+			}
 		}
 
 		// Stream the response
 		msg, finishReason, err := s.streamResponse(conn, stream)
 		stream.Close()
+		if stream.Cached() {
+			// Replayed from the response cache, so nothing to charge
+			log.Debug("Served from the response cache", "client_ip", clientIP, "model", servedModel)
+		} else {
+			usage.Add(stream.Usage())
+			billed = true
+		}
 
+		if err != nil && turnStopped(ctx) {
+			// Keep what was streamed so the conversation stays coherent;
+			// unfinished tool calls have no results and are dropped
+			if msg.Content != "" {
+				history.Append(client.Message{Role: "assistant", Content: msg.Content})
+			}
+			stopped = true
+			break
+		}
 		if err != nil {
-			s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
-			return
+			log.Error("Stream error", "client_ip", clientIP, "error", err)
+			s.sendMessage(conn, apiErrorMessage(err))
+			failed = true
+			break
 		}
 
 		// Add assistant message to history
-		*history = append(*history, *msg)
+		history.Append(*msg)
 
 		// Check for tool calls
 		if finishReason == "tool_calls" && len(msg.ToolCalls) > 0 {
@@ -466,22 +907,43 @@ func (s *Server) handleChat(conn *websocket.Conn, userMessage string, images []s
 					Tool: tc.Function.Name,
 					Args: tc.Function.Arguments,
 				})
+			}
 
-				// Execute tool
-				result, _ := s.executor.ExecuteToolCall(ctx, tc)
+			// Execute independent tools concurrently, forwarding incremental
+			// output and throttled progress to the client (the executor
+			// serializes these writes)
+			progress := newProgressThrottle(func(m WSMessage) { s.sendMessage(conn, m) })
+			results := s.executor.ExecuteToolCallsParallelWithProgress(ctx, msg.ToolCalls, tool.DefaultMaxConcurrency,
+				func(tc client.ToolCall, stage, detail string) {
+					s.sendMessage(conn, WSMessage{
+						Type:    "tool_output",
+						Tool:    tc.Function.Name,
+						Content: stage,
+						Result:  detail,
+					})
+				},
+				func(tc client.ToolCall, stage string, pct int) {
+					progress.report(tc.ID, tc.Function.Name, stage, pct)
+				})
 
+			// Results go into history in the original call order
+			for i, tc := range msg.ToolCalls {
+				result := results[i]
 				if result.IsError {
 					log.Error("Tool execution error", "tool", tc.Function.Name, "error", truncateLog(result.Content, 100))
 				} else {
 					log.Debug("Tool completed", "tool", tc.Function.Name)
 				}
 
-				// Extract diff data if present
+				// Diffs come from metadata, or after the sentinel from
+				// plugins that predate it
 				resultContent := result.Content
-				diffData := ""
-				if parts := strings.SplitN(result.Content, "\n---DIFF_DATA---\n", 2); len(parts) == 2 {
+				diffData := result.Metadata[tool.MetadataDiff]
+				if parts := strings.SplitN(result.Content, tool.DiffSentinel, 2); len(parts) == 2 {
 					resultContent = parts[0]
-					diffData = parts[1]
+					if diffData == "" {
+						diffData = parts[1]
+					}
 				}
 
 				// Send tool result with args for file tracking
@@ -493,24 +955,38 @@ func (s *Server) handleChat(conn *websocket.Conn, userMessage string, images []s
 					Error:    boolToError(result.IsError),
 					DiffData: diffData,
 				})
+				if img, ok := s.imageMessage(tc.Function.Name, result); ok {
+					s.sendMessage(conn, img)
+				}
 
 				// Add to history
-				*history = append(*history, client.Message{
+				history.Append(client.Message{
 					Role:       "tool",
-					Content:    result.Content,
+					Content:    resultContent,
 					ToolCallID: tc.ID,
 				})
 			}
+
+			// Tool results are text only, so images tools read follow
+			// in a user message
+			if img, ok := s.toolImageMessage(sess.client.Model(), msg.ToolCalls, results); ok {
+				history.Append(img)
+			}
 			continue
 		}
 
 		// No more tool calls
 		break
 	}
+	if stopped {
+		log.Info("Turn stopped", "client_ip", clientIP)
+		s.sendMessage(conn, WSMessage{Type: "stopped"})
+	}
 
-	// Deduct credits after successful completion
-	if s.credits != nil {
-		if err := s.credits.UseCredits(userID, model, 0); err != nil {
+	// Deduct credits for every provider response of the turn, including
+	// earlier tool rounds when a later request failed
+	if s.credits != nil && billed {
+		if err := s.credits.UseCredits(userID, clientIP, servedModel, usage); err != nil {
 			log.Warn("Failed to deduct credits", "user_id", userID, "error", err)
 		} else {
 			// Send updated balance
@@ -521,9 +997,12 @@ func (s *Server) handleChat(conn *websocket.Conn, userMessage string, images []s
 			})
 		}
 	}
+	if failed {
+		return
+	}
 
-	// Signal end of response
-	s.sendMessage(conn, WSMessage{Type: "done"})
+	// Persist even if the client disconnected at the very end
+	s.saveSession(context.WithoutCancel(ctx), sess)
 }
 
 func (s *Server) streamResponse(conn *websocket.Conn, stream *client.StreamReader) (*client.Message, string, error) {
@@ -541,7 +1020,8 @@ func (s *Server) streamResponse(conn *websocket.Conn, stream *client.StreamReade
 			break
 		}
 		if err != nil {
-			return nil, "", err
+			// Callers keep the partial content of a stopped turn
+			return &client.Message{Role: "assistant", Content: content}, "", err
 		}
 
 		if len(chunk.Choices) == 0 {
@@ -607,41 +1087,37 @@ func (s *Server) streamResponse(conn *websocket.Conn, stream *client.StreamReade
 	return msg, finishReason, nil
 }
 
+// handleModels lists the catalog's models that have a provider key configured
 func (s *Server) handleModels(w http.ResponseWriter, r *http.Request) {
-	models := []string{
-		// Groq models
-		"llama-3.3-70b-versatile",
-		"llama-3.1-8b-instant",
-		"llama-3.2-90b-vision-preview",
-		"mixtral-8x7b-32768",
-		// Claude models
-		"claude-sonnet-4-20250514",
-		"claude-3-5-sonnet-20241022",
-		"claude-3-5-haiku-20241022",
-		"claude-3-opus-20240229",
-		// OpenAI models
-		"gpt-4o",
-		"gpt-4o-mini",
-	}
-
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
-		"models":  models,
-		"current": s.client.Model(),
+		"models":    s.availableModels(),
+		"current":   s.client.Model(),
+		"read_only": s.readOnly() != nil,
 	})
 }
 
+// handleUpload stores a single file under a random ID. Text and document
+// uploads return their text; images and other binaries are only returned by
+// reference, see GET /api/uploads/{id}.
 func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Parse multipart form (max 10MB)
-	if err := r.ParseMultipartForm(10 << 20); err != nil {
+	// Leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxUploadBytes+1<<20)
+	if err := r.ParseMultipartForm(maxUploadBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("File exceeds %d bytes", maxUploadBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
 		http.Error(w, "Failed to parse form", http.StatusBadRequest)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
 	file, header, err := r.FormFile("file")
 	if err != nil {
@@ -650,6 +1126,21 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
+	if header.Size > maxUploadBytes {
+		http.Error(w, fmt.Sprintf("File exceeds %d bytes", maxUploadBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+	name, err := sanitizeUploadName(header.Filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	contentType := uploadType(name)
+	if contentType == "" {
+		http.Error(w, "File type not allowed: "+filepath.Ext(name), http.StatusUnsupportedMediaType)
+		return
+	}
+
 	// Read file content
 	content, err := io.ReadAll(file)
 	if err != nil {
@@ -657,20 +1148,65 @@ func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Save file to upload directory
-	filePath := filepath.Join(s.uploadDir, header.Filename)
+	// Documents are returned as their extracted text, binaries not at all
+	var text string
+	switch knowledge.DetectContentType(name, content) {
+	case knowledge.ContentTypePDF, knowledge.ContentTypeDOCX:
+		if text, err = knowledge.ExtractText(name, content); err != nil {
+			http.Error(w, err.Error(), extractStatus(err))
+			return
+		}
+	default:
+		if isTextType(contentType) && utf8.Valid(content) {
+			text = string(content)
+		}
+	}
+
+	// Save file to upload directory under a random ID
+	id, err := newUploadID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	filePath := filepath.Join(s.uploadDir, id+"_"+name)
 	if err := os.WriteFile(filePath, content, 0644); err != nil {
 		http.Error(w, "Failed to save file", http.StatusInternalServerError)
 		return
 	}
 
+	resp := map[string]any{
+		"id":           id,
+		"url":          "/api/uploads/" + id,
+		"path":         filePath,
+		"name":         name,
+		"size":         len(content),
+		"content_type": contentType,
+	}
+	if text != "" {
+		resp["content"] = text
+	}
+
+	// Optionally index the upload in the knowledge base
+	if r.FormValue("knowledge") == "true" && s.knowledge != nil {
+		doc, err := s.knowledge.AddFile(r.Context(), name, content)
+		if err != nil {
+			http.Error(w, err.Error(), extractStatus(err))
+			return
+		}
+		log.Info("Added upload to knowledge base", "name", doc.Name, "content_type", doc.ContentType)
+		resp["document_id"] = doc.ID
+	}
+
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"path":    filePath,
-		"name":    header.Filename,
-		"size":    header.Size,
-		"content": string(content),
-	})
+	json.NewEncoder(w).Encode(resp)
+}
+
+// extractStatus maps knowledge extraction errors to HTTP status codes
+func extractStatus(err error) int {
+	if errors.Is(err, knowledge.ErrUnsupported) || errors.Is(err, knowledge.ErrEncrypted) {
+		return http.StatusUnprocessableEntity
+	}
+	return http.StatusInternalServerError
 }
 
 func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
@@ -680,14 +1216,25 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	userID, err := s.resolveUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
-		sessions, err := s.storage.ListSessions(ctx)
+		all, err := s.storage.ListSessions(ctx)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		sessions := []*storage.SessionMeta{}
+		for _, meta := range all {
+			if meta.OwnerID != "" && meta.OwnerID == userID {
+				sessions = append(sessions, meta)
+			}
+		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(sessions)
 
@@ -697,6 +1244,22 @@ func (s *Server) handleSessions(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
 			return
 		}
+		// Auto-approve rules are only set over the session's WebSocket; a
+		// posted session keeps the rules it already had
+		session.ApprovalRules = nil
+		existing, err := s.storage.LoadSession(ctx, session.ID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if existing != nil {
+			if existing.OwnerID == "" || existing.OwnerID != userID {
+				http.Error(w, "Session belongs to another user", http.StatusForbidden)
+				return
+			}
+			session.ApprovalRules = existing.ApprovalRules
+		}
+		session.OwnerID = userID
 		if err := s.storage.SaveSession(ctx, &session); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -715,6 +1278,28 @@ func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Callers only see the sessions saved for them
+	userID, err := s.resolveUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	// Search, import and export live under the sessions path
+	rest := strings.TrimPrefix(r.URL.Path, "/api/sessions/")
+	if rest == "search" {
+		s.handleSessionSearch(w, r, userID)
+		return
+	}
+	if rest == "import" {
+		s.handleSessionImport(w, r, userID)
+		return
+	}
+	if id, ok := strings.CutSuffix(rest, "/export"); ok {
+		s.handleSessionExport(w, r, id, userID)
+		return
+	}
+
 	// Extract session ID from path
 	id := filepath.Base(r.URL.Path)
 	if id == "" || id == "sessions" {
@@ -726,7 +1311,7 @@ func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
 
 	switch r.Method {
 	case http.MethodGet:
-		session, err := s.storage.LoadSession(ctx, id)
+		session, err := s.loadOwnedSession(ctx, id, userID)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -739,6 +1324,15 @@ func (s *Server) handleSession(w http.ResponseWriter, r *http.Request) {
 		json.NewEncoder(w).Encode(session)
 
 	case http.MethodDelete:
+		session, err := s.loadOwnedSession(ctx, id, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if session == nil {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
 		if err := s.storage.DeleteSession(ctx, id); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -757,8 +1351,13 @@ func (s *Server) sendMessage(conn *websocket.Conn, msg WSMessage) error {
 		log.Error("Failed to marshal WebSocket message", "error", err)
 		return err
 	}
+	defer s.conns.lockWrites(conn)()
+	conn.SetWriteDeadline(time.Now().Add(writeWait))
 	if err := conn.WriteMessage(websocket.TextMessage, data); err != nil {
 		log.Error("Failed to write WebSocket message", "error", err)
+		// The connection is unusable after a failed write; closing it
+		// ends the read loop, which cancels the running turn
+		conn.Close()
 		return err
 	}
 	return nil
@@ -791,39 +1390,121 @@ func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := s.auth.Authenticate(req.Username, req.Password)
+	token, err := s.auth.Authenticate(req.Username, req.Password)
+	if err != nil {
+		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		return
+	}
+	s.migrateAnonymousCredits(r, req.Username)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":  true,
+		"token":    token,
+		"username": req.Username,
+	})
+}
+
+func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := r.Header.Get("Authorization")
+	if token != "" && s.auth != nil {
+		// Remove "Bearer " prefix if present
+		if len(token) > 7 && token[:7] == "Bearer " {
+			token = token[7:]
+		}
+		s.auth.InvalidateToken(token)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "logged_out"})
+}
+
+// handleAuthRefresh extends the caller's token to a full lifetime
+func (s *Server) handleAuthRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth == nil {
+		http.Error(w, "Auth not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	expiresAt, err := s.auth.RefreshToken(requestToken(r))
+	if err != nil {
+		http.Error(w, "Invalid or expired token", http.StatusUnauthorized)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"success":    true,
+		"expires_at": expiresAt,
+	})
+}
+
+// handleAuthPassword changes the caller's password. Existing tokens are
+// revoked and a fresh one is returned.
+func (s *Server) handleAuthPassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.auth == nil {
+		http.Error(w, "Auth not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	username, ok := requestUsername(r)
+	if !ok {
+		user, err := s.authenticate(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		username = user.Username
+	}
+
+	var req struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if err := s.auth.ChangePassword(username, req.OldPassword, req.NewPassword); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			http.Error(w, "Invalid credentials", http.StatusForbidden)
+		case errors.Is(err, auth.ErrEmptyPassword):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		default:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+	log.Info("Password changed", "username", username)
+
+	token, err := s.auth.Authenticate(username, req.NewPassword)
 	if err != nil {
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]any{
-		"success":  true,
-		"token":    token,
-		"username": req.Username,
+		"success": true,
+		"token":   token,
 	})
 }
 
-func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	token := r.Header.Get("Authorization")
-	if token != "" && s.auth != nil {
-		// Remove "Bearer " prefix if present
-		if len(token) > 7 && token[:7] == "Bearer " {
-			token = token[7:]
-		}
-		s.auth.InvalidateToken(token)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "logged_out"})
-}
-
 func (s *Server) handleAuthStatus(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
@@ -951,6 +1632,11 @@ func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/projects/"), "/ingest"); ok {
+		s.handleProjectIngest(w, r, id)
+		return
+	}
+
 	// Extract project ID from path
 	id := filepath.Base(r.URL.Path)
 	if id == "" || id == "projects" {
@@ -1007,6 +1693,46 @@ func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleProjectIngest indexes a project's files in the knowledge base and
+// reports how many documents were added, updated, skipped and removed
+func (s *Server) handleProjectIngest(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.knowledge == nil {
+		http.Error(w, "Knowledge base not available", http.StatusServiceUnavailable)
+		return
+	}
+	proj, err := s.projects.Get(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	// The body is optional; without globs every text file is ingested
+	var req struct {
+		Include []string `json:"include"`
+		Exclude []string `json:"exclude"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	result, err := s.knowledge.IngestDirectory(r.Context(), proj.ID, proj.RootPath, req.Include, req.Exclude)
+	if err != nil {
+		log.Error("Failed to ingest project", "project_id", proj.ID, "root", proj.RootPath, "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Info("Ingested project into knowledge base", "project_id", proj.ID,
+		"added", result.Added, "updated", result.Updated, "skipped", result.Skipped, "removed", result.Removed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
 // Share handlers
 func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
 	if s.storage == nil {
@@ -1014,6 +1740,11 @@ func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	owner, err := s.requestOwner(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	ctx := r.Context()
 
 	switch r.Method {
@@ -1023,6 +1754,7 @@ func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
 			Title     string           `json:"title"`
 			Messages  []client.Message `json:"messages"`
 			ExpiresIn int              `json:"expires_in"` // hours, 0 = never
+			Password  string           `json:"password"`   // optional, required as ?key= to view
 		}
 		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 			http.Error(w, "Invalid request body", http.StatusBadRequest)
@@ -1030,7 +1762,7 @@ func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
 		}
 
 		// Generate share ID
-		shareID := generateShareID()
+		shareID := ids.New(12)
 
 		share := &storage.SharedConversation{
 			ShareID:   shareID,
@@ -1039,11 +1771,20 @@ func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
 			Messages:  req.Messages,
 			CreatedAt: timeNow(),
 			ViewCount: 0,
+			OwnerID:   owner,
 		}
 
 		if req.ExpiresIn > 0 {
 			share.ExpiresAt = timeNow().Add(timeDuration(req.ExpiresIn) * timeHour)
 		}
+		if req.Password != "" {
+			hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+			if err != nil {
+				http.Error(w, "Failed to set share password", http.StatusInternalServerError)
+				return
+			}
+			share.Password = string(hash)
+		}
 
 		if err := s.storage.SaveShare(ctx, share); err != nil {
 			log.Error("Failed to save share", "error", err)
@@ -1059,6 +1800,21 @@ func (s *Server) handleShare(w http.ResponseWriter, r *http.Request) {
 			"share_url": "/share/" + shareID,
 		})
 
+	case http.MethodGet:
+		shares, err := s.storage.ListSharesByOwner(ctx, owner)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		list := make([]map[string]any, 0, len(shares))
+		for _, share := range shares {
+			list = append(list, shareSummary(share))
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"shares": list})
+
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -1095,6 +1851,25 @@ func (s *Server) handleSharedView(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Password-protected shares need the key in the query string. Wrong
+	// keys are limited per share, so guessing can't be spread over clients.
+	if share.Password != "" {
+		if shareKeyFailures.exhausted(shareID) {
+			http.Error(w, "Too many wrong keys for this share link, try again later", http.StatusTooManyRequests)
+			return
+		}
+		key := r.URL.Query().Get("key")
+		if key == "" || bcrypt.CompareHashAndPassword([]byte(share.Password), []byte(key)) != nil {
+			if key != "" {
+				shareKeyFailures.allow(shareID)
+			}
+			http.Error(w, "This share link requires a password", http.StatusUnauthorized)
+			return
+		}
+	}
+	share.Password = ""
+	share.OwnerID = ""
+
 	// Increment view count
 	s.storage.IncrementShareViewCount(ctx, shareID)
 
@@ -1112,15 +1887,6 @@ func (s *Server) handleSharedView(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprintf(w, sharedViewHTML, share.Title, share.Title, formatMessagesHTML(share.Messages), share.ViewCount)
 }
 
-func generateShareID() string {
-	const charset = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
-	b := make([]byte, 12)
-	for i := range b {
-		b[i] = charset[randInt(len(charset))]
-	}
-	return string(b)
-}
-
 func formatMessagesHTML(messages []client.Message) string {
 	var sb strings.Builder
 	for _, msg := range messages {
@@ -1205,21 +1971,46 @@ func (s *Server) handleKnowledge(w http.ResponseWriter, r *http.Request) {
 		})
 
 	case http.MethodPost:
-		var req struct {
-			Name    string `json:"name"`
-			Content string `json:"content"`
-		}
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "Invalid request body", http.StatusBadRequest)
-			return
-		}
-		if req.Name == "" || req.Content == "" {
-			http.Error(w, "Name and content are required", http.StatusBadRequest)
-			return
+		var doc *knowledge.Document
+		var err error
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			// File upload: text, PDF or DOCX
+			if err := r.ParseMultipartForm(10 << 20); err != nil {
+				http.Error(w, "Failed to parse form", http.StatusBadRequest)
+				return
+			}
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				http.Error(w, "Failed to get file", http.StatusBadRequest)
+				return
+			}
+			data, err := io.ReadAll(file)
+			file.Close()
+			if err != nil {
+				http.Error(w, "Failed to read file", http.StatusInternalServerError)
+				return
+			}
+			doc, err = s.knowledge.AddFile(ctx, filepath.Base(header.Filename), data)
+		} else {
+			var req struct {
+				Name    string `json:"name"`
+				Content string `json:"content"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+			if req.Name == "" || req.Content == "" {
+				http.Error(w, "Name and content are required", http.StatusBadRequest)
+				return
+			}
+			doc, err = s.knowledge.AddDocument(ctx, req.Name, req.Content)
 		}
-
-		doc, err := s.knowledge.AddDocument(ctx, req.Name, req.Content)
 		if err != nil {
+			if status := extractStatus(err); status != http.StatusInternalServerError {
+				http.Error(w, err.Error(), status)
+				return
+			}
 			log.Error("Failed to add document to knowledge base", "error", err)
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
@@ -1260,6 +2051,33 @@ func (s *Server) handleKnowledgeDocument(w http.ResponseWriter, r *http.Request)
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(doc)
 
+	case http.MethodPut:
+		var req struct {
+			Name    string `json:"name"`
+			Content string `json:"content"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Content == "" {
+			http.Error(w, "Content is required", http.StatusBadRequest)
+			return
+		}
+		doc, err := s.knowledge.UpdateDocument(ctx, docID, req.Name, req.Content)
+		if errors.Is(err, knowledge.ErrNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Error("Failed to update knowledge document", "doc_id", docID, "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Info("Updated document in knowledge base", "doc_id", docID, "chunks", len(doc.Chunks))
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(doc)
+
 	case http.MethodDelete:
 		if err := s.knowledge.DeleteDocument(ctx, docID); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1348,6 +2166,19 @@ func (s *Server) handlePlugin(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(p)
 
+	case http.MethodPost:
+		if action != "health" {
+			http.Error(w, "Unknown action", http.StatusBadRequest)
+			return
+		}
+		status, err := s.plugins.HealthCheck(r.Context(), name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+
 	case http.MethodPut:
 		var err error
 		switch action {
@@ -1355,11 +2186,13 @@ func (s *Server) handlePlugin(w http.ResponseWriter, r *http.Request) {
 			err = s.plugins.EnablePlugin(name)
 			if err == nil {
 				log.Info("Enabled plugin", "name", name)
+				s.registry.NotifyChanged()
 			}
 		case "disable":
 			err = s.plugins.DisablePlugin(name)
 			if err == nil {
 				log.Info("Disabled plugin", "name", name)
+				s.registry.NotifyChanged()
 			}
 		default:
 			http.Error(w, "Unknown action", http.StatusBadRequest)
@@ -1388,187 +2221,8 @@ func (s *Server) handlePlugin(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// handleTTS handles text-to-speech requests using Kokoro TTS
-func (s *Server) handleTTS(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req struct {
-		Text  string `json:"text"`
-		Voice string `json:"voice"`
-		Speed float64 `json:"speed"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	if req.Text == "" {
-		http.Error(w, "Text is required", http.StatusBadRequest)
-		return
-	}
-
-	// Default values
-	if req.Voice == "" {
-		req.Voice = "jf_alpha"
-	}
-	if req.Speed == 0 {
-		req.Speed = 1.0
-	}
-
-	// Get FAL API key
-	falAPIKey := os.Getenv("FAL_API_KEY")
-	if falAPIKey == "" {
-		// Fallback: return empty response, let client use Web Speech API
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"fallback": true})
-		return
-	}
-
-	// Call Kokoro TTS API
-	ttsReq := map[string]any{
-		"prompt": req.Text,
-		"voice":  req.Voice,
-		"speed":  req.Speed,
-	}
-	ttsBody, _ := json.Marshal(ttsReq)
-
-	httpReq, err := http.NewRequest("POST", "https://fal.run/fal-ai/kokoro/japanese", bytes.NewReader(ttsBody))
-	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-		return
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Key "+falAPIKey)
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		log.Error("TTS request failed", "error", err)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"fallback": true, "error": err.Error()})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Error("TTS API error", "response", string(body))
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"fallback": true, "error": "TTS API error"})
-		return
-	}
-
-	// Parse response and return audio URL
-	var ttsResp struct {
-		Audio struct {
-			URL string `json:"url"`
-		} `json:"audio"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&ttsResp); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"fallback": true})
-		return
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]any{
-		"audio_url": ttsResp.Audio.URL,
-	})
-}
-
-// handleElevenLabsTTS handles text-to-speech using ElevenLabs API
-func (s *Server) handleElevenLabsTTS(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	var req struct {
-		Text    string  `json:"text"`
-		VoiceID string  `json:"voice_id"`
-		ModelID string  `json:"model_id"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	if req.Text == "" {
-		http.Error(w, "Text is required", http.StatusBadRequest)
-		return
-	}
-
-	// Get ElevenLabs API key from environment
-	apiKey := os.Getenv("ELEVENLABS_API_KEY")
-	if apiKey == "" {
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"fallback": true, "error": "ElevenLabs API key not configured"})
-		return
-	}
-
-	// Default voice ID (can be overridden)
-	voiceID := req.VoiceID
-	if voiceID == "" {
-		voiceID = os.Getenv("ELEVENLABS_VOICE_ID")
-		if voiceID == "" {
-			voiceID = "21m00Tcm4TlvDq8ikWAM" // Default: Rachel
-		}
-	}
-
-	// Default model
-	modelID := req.ModelID
-	if modelID == "" {
-		modelID = "eleven_multilingual_v2"
-	}
-
-	// Call ElevenLabs API
-	ttsReq := map[string]any{
-		"text":     req.Text,
-		"model_id": modelID,
-		"voice_settings": map[string]any{
-			"stability":        0.5,
-			"similarity_boost": 0.75,
-		},
-	}
-	ttsBody, _ := json.Marshal(ttsReq)
-
-	url := fmt.Sprintf("https://api.elevenlabs.io/v1/text-to-speech/%s", voiceID)
-	httpReq, err := http.NewRequest("POST", url, bytes.NewReader(ttsBody))
-	if err != nil {
-		http.Error(w, "Failed to create request", http.StatusInternalServerError)
-		return
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("xi-api-key", apiKey)
-	httpReq.Header.Set("Accept", "audio/mpeg")
-
-	client := &http.Client{Timeout: 30 * time.Second}
-	resp, err := client.Do(httpReq)
-	if err != nil {
-		log.Error("ElevenLabs TTS request failed", "error", err)
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"fallback": true, "error": err.Error()})
-		return
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		log.Error("ElevenLabs API error", "status", resp.StatusCode, "response", string(body))
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{"fallback": true, "error": "ElevenLabs API error"})
-		return
-	}
-
-	// Stream audio directly to client
-	w.Header().Set("Content-Type", "audio/mpeg")
-	io.Copy(w, resp.Body)
-}
-
-func (s *Server) getSystemPrompt(mode string) string {
+// builtinSystemPrompt returns the default system prompt for a mode
+func builtinSystemPrompt(mode string) string {
 	if mode == "improve" {
 		return `You are groq-go in IMPROVEMENT MODE. Your primary purpose is to improve your own source code.
 
@@ -1611,15 +2265,16 @@ You have access to tools for reading, writing, and editing files, searching the
 
 ## Available Tools
 - Read: Read file contents
-- Write: Create or overwrite files (ALWAYS use this for creating files, NOT bash echo/cat)
+- Write: Create or overwrite files (ALWAYS use this for creating files, NOT bash echo/cat); Read a file before overwriting it
 - Edit: Replace text in files
 - Glob: Find files by pattern
 - Grep: Search file contents
-- Bash: Execute shell commands (for running programs, NOT for creating files)
+- Bash: Execute shell commands (for running programs, NOT for creating files); run_in_background for long ones
+- BashOutput: Check on or kill a background Bash job
 - WebFetch: Fetch web content
 - Browser: Take screenshots, get JS-rendered content
 - Git: Execute git commands (status, diff, log, add, commit, push, pull, branch, checkout, stash)
-- ImageGen: Generate images from text prompts (requires STABILITY_API_KEY or OPENAI_API_KEY)
+- ImageGen: Generate images from text prompts with Stability AI, OpenAI or fal.ai (the user sees the image in the chat)
 - CodeExec: Execute code in a sandbox (JavaScript, Python, Go, shell)
 - KnowledgeSearch: Search the knowledge base for relevant information
 - KnowledgeList: List documents in the knowledge base
@@ -1710,24 +2365,29 @@ func (s *Server) handleCredits(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from request (IP-based for now)
-	clientIP := r.RemoteAddr
-	if fwdFor := r.Header.Get("X-Forwarded-For"); fwdFor != "" {
-		clientIP = strings.Split(fwdFor, ",")[0]
+	userID, err := s.resolveUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
-	userID := "user_" + strings.ReplaceAll(strings.ReplaceAll(clientIP, ".", "_"), ":", "_")
 
 	switch r.Method {
 	case http.MethodGet:
 		user := s.credits.GetOrCreateUser(userID, "")
+		pricing := s.credits.Pricing()
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{
-			"user_id":      user.UserID,
-			"balance":      user.Balance,
-			"total_used":   user.TotalUsed,
-			"total_bought": user.TotalBought,
-			"free_credits": user.FreeCredits,
-			"costs":        credits.CreditCost,
+			"user_id":                 user.UserID,
+			"balance":                 user.Balance,
+			"total_used":              user.TotalUsed,
+			"total_bought":            user.TotalBought,
+			"free_credits":            user.FreeCredits,
+			"costs":                   pricing.ModelCosts,
+			"default_cost":            pricing.DefaultCost,
+			"price_per_1k_prompt":     pricing.PricePer1kPrompt,
+			"price_per_1k_completion": pricing.PricePer1kCompletion,
+			"daily_usage":             s.credits.DailyUsage(user.UserID),
+			"daily_limits":            pricing.DailyLimits,
 		})
 
 	default:
@@ -1741,12 +2401,11 @@ func (s *Server) handleCreditAction(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get user ID from request
-	clientIP := r.RemoteAddr
-	if fwdFor := r.Header.Get("X-Forwarded-For"); fwdFor != "" {
-		clientIP = strings.Split(fwdFor, ",")[0]
+	userID, err := s.resolveUserID(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
 	}
-	userID := "user_" + strings.ReplaceAll(strings.ReplaceAll(clientIP, ".", "_"), ":", "_")
 
 	// Extract action from path: /api/credits/{action}
 	action := strings.TrimPrefix(r.URL.Path, "/api/credits/")
@@ -1757,22 +2416,21 @@ func (s *Server) handleCreditAction(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
-		user := s.credits.GetUserInfo(userID)
-		if user == nil {
+		if s.credits.GetUserInfo(userID) == nil {
 			http.Error(w, "User not found", http.StatusNotFound)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]any{
-			"transactions": user.Transactions,
-		})
+		s.handleCreditHistory(w, r, userID)
 
 	case "add":
-		// Admin endpoint to add credits (should be protected in production)
+		// Admin endpoint to add credits; purchases go through checkout
 		if r.Method != http.MethodPost {
 			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 			return
 		}
+		if !s.requireAdminUser(w, r) {
+			return
+		}
 		var req struct {
 			UserID string `json:"user_id"`
 			Amount int    `json:"amount"`
@@ -1803,6 +2461,31 @@ func (s *Server) handleCreditAction(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// streamVersionLogs sends new lines of a version's log as server-sent
+// events until the client goes away
+func (s *Server) streamVersionLogs(w http.ResponseWriter, r *http.Request, id string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	lines, err := s.versions.StreamVersionLogs(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for line := range lines {
+		fmt.Fprintf(w, "data: %s\n\n", line)
+		flusher.Flush()
+	}
+}
+
 func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 	if s.versions == nil {
 		http.Error(w, "Version management not available", http.StatusServiceUnavailable)
@@ -1830,7 +2513,11 @@ func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 	if action != "" && r.Method == http.MethodPost {
 		switch action {
 		case "build":
-			if err := s.versions.BuildVersion(ctx, id); err != nil {
+			if err := s.versions.BuildVersion(ctx, id, nil); err != nil {
+				if errors.Is(err, selfimprove.ErrAlreadyBuilding) {
+					http.Error(w, err.Error(), http.StatusConflict)
+					return
+				}
 				http.Error(w, err.Error(), http.StatusInternalServerError)
 				return
 			}
@@ -1863,6 +2550,28 @@ func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 			json.NewEncoder(w).Encode(map[string]string{"status": "stopped"})
 			return
 
+		case "promote":
+			// Promoting replaces the running agent, so only admins may
+			if !s.requireAdminUser(w, r) {
+				return
+			}
+			v, err := s.versions.PromoteVersion(ctx, id)
+			if err != nil {
+				status := http.StatusInternalServerError
+				if errors.Is(err, version.ErrSoakFailed) {
+					status = http.StatusConflict
+				}
+				http.Error(w, err.Error(), status)
+				return
+			}
+			log.Info("Promoted version", "id", id, "branch", v.Branch)
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{
+				"status":      "promoted",
+				"promoted_at": v.PromotedAt,
+			})
+			return
+
 		case "restart":
 			if err := s.versions.RestartVersion(ctx, id); err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -1883,6 +2592,12 @@ func (s *Server) handleVersion(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Follow logs: GET /api/versions/{id}/logs/stream
+	if action == "logs" && len(parts) > 2 && parts[2] == "stream" && r.Method == http.MethodGet {
+		s.streamVersionLogs(w, r, id)
+		return
+	}
+
 	// Handle logs action (GET)
 	if action == "logs" && r.Method == http.MethodGet {
 		logs, err := s.versions.GetVersionLogs(id, 100)
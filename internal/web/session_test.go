@@ -0,0 +1,62 @@
+package web
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSessionModelIsolation(t *testing.T) {
+	up := &scriptedUpstream{}
+	url := newTestServer(t, up)
+	models := []string{"llama-3.1-8b-instant", "mixtral-8x7b-32768"}
+
+	// readUntil fails the test, which only the test goroutine may do, so
+	// the connections report errors back instead
+	errs := make(chan error, len(models))
+	for _, model := range models {
+		conn := dialTestServer(t, url)
+		go func() {
+			conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+			conn.WriteJSON(WSMessage{Type: "model", Model: model})
+			for i := 0; i < 3; i++ {
+				conn.WriteJSON(WSMessage{Type: "chat", Content: fmt.Sprintf("hi %d", i)})
+				for {
+					var msg WSMessage
+					if err := conn.ReadJSON(&msg); err != nil {
+						errs <- fmt.Errorf("%s: waiting for done: %w", model, err)
+						return
+					}
+					if msg.Type == "done" {
+						break
+					}
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for range models {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	counts := make(map[string]int)
+	for _, m := range up.models {
+		counts[m]++
+	}
+	for _, model := range models {
+		if counts[model] != 3 {
+			t.Errorf("Expected 3 requests for %s, got %v", model, counts)
+		}
+	}
+
+	// The shared default is untouched
+	conn := dialTestServer(t, url)
+	welcome := readUntil(t, conn, "system")
+	if want := "Connected to groq-go. Model: llama-3.3-70b-versatile"; welcome.Content != want {
+		t.Errorf("Expected %q, got %q", want, welcome.Content)
+	}
+}
@@ -0,0 +1,177 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	"groq-go/internal/client"
+	"groq-go/internal/config"
+	"groq-go/internal/setup"
+)
+
+// setupState tracks the first-run setup flow
+type setupState struct {
+	mu          sync.Mutex
+	active      bool // Setup was enabled at startup
+	reconfigure bool // Started with --reconfigure on an existing configuration
+	configured  bool // Provider keys have been saved
+}
+
+// EnableSetup serves the /setup wizard. On first run (no keys configured)
+// the rest of the API stays locked until keys are saved and the first
+// admin user exists. With reconfigure, the wizard is available to admins
+// without locking the API.
+func (s *Server) EnableSetup(reconfigure bool) {
+	s.setup.mu.Lock()
+	defer s.setup.mu.Unlock()
+	s.setup.active = true
+	s.setup.reconfigure = reconfigure && config.Exists()
+	s.setup.configured = s.setup.reconfigure
+}
+
+// setupLocked reports whether the API is locked pending first-run setup
+func (s *Server) setupLocked() bool {
+	s.setup.mu.Lock()
+	defer s.setup.mu.Unlock()
+	if !s.setup.active || s.setup.reconfigure {
+		return false
+	}
+	if !s.setup.configured {
+		return true
+	}
+	return s.auth != nil && !s.auth.HasUsers()
+}
+
+// setupAvailable reports whether the setup wizard may be used
+func (s *Server) setupAvailable() bool {
+	s.setup.mu.Lock()
+	defer s.setup.mu.Unlock()
+	return s.setup.active && (s.setup.reconfigure || !s.setup.configured)
+}
+
+// setupAllowedPaths are reachable while the API is locked
+var setupAllowedPaths = map[string]bool{
+	"/setup":             true,
+	"/api/setup":         true,
+	"/api/auth/status":   true,
+	"/api/auth/register": true,
+	"/api/auth/login":    true,
+	"/favicon.svg":       true,
+	"/healthz":           true,
+}
+
+// setupGate locks the app until first-run setup completes
+func (s *Server) setupGate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.setupLocked() || setupAllowedPaths[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/api/") || r.URL.Path == "/ws" {
+			http.Error(w, "Setup required", http.StatusServiceUnavailable)
+			return
+		}
+		http.Redirect(w, r, "/setup", http.StatusFound)
+	})
+}
+
+// handleSetupPage serves the setup wizard page
+func (s *Server) handleSetupPage(w http.ResponseWriter, r *http.Request) {
+	if !s.setupAvailable() && !s.setupLocked() {
+		http.NotFound(w, r)
+		return
+	}
+	data, err := staticFiles.ReadFile("static/setup.html")
+	if err != nil {
+		http.Error(w, "Setup page not available", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(data)
+}
+
+// handleSetup reports setup status (GET) and saves provider keys (POST)
+func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.setup.mu.Lock()
+		configured := s.setup.configured
+		s.setup.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"available":  s.setupAvailable(),
+			"configured": configured,
+			"needs_user": s.auth != nil && !s.auth.HasUsers(),
+			"providers":  client.Providers,
+			"models":     client.ProviderModels(),
+		})
+
+	case http.MethodPost:
+		if !s.setupAvailable() {
+			http.Error(w, setup.ErrAlreadyConfigured.Error(), http.StatusConflict)
+			return
+		}
+
+		s.setup.mu.Lock()
+		reconfigure := s.setup.reconfigure
+		s.setup.mu.Unlock()
+
+		// Reconfiguring an existing install is an admin operation
+		if reconfigure && !s.requireAdmin(w, r) {
+			return
+		}
+
+		var req setup.Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		result, cfg, err := setup.Apply(r.Context(), req, reconfigure)
+		if err != nil {
+			status := http.StatusBadRequest
+			switch {
+			case errors.Is(err, setup.ErrAlreadyConfigured):
+				status = http.StatusConflict
+			case errors.Is(err, setup.ErrNoValidKeys):
+				status = http.StatusUnprocessableEntity
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(status)
+			json.NewEncoder(w).Encode(map[string]any{
+				"error":  err.Error(),
+				"result": result,
+			})
+			return
+		}
+
+		// Apply the new configuration to the running client
+		for provider, key := range cfg.ProviderKeys() {
+			s.client.SetProviderKey(provider, key)
+		}
+		s.client.SetModel(cfg.Model)
+
+		s.setup.mu.Lock()
+		s.setup.configured = true
+		if reconfigure {
+			// Reconfiguration is one-shot per start
+			s.setup.active = false
+		}
+		s.setup.mu.Unlock()
+
+		log.Info("Setup completed", "model", cfg.Model, "providers", len(cfg.ProviderKeys()))
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"result":     result,
+			"needs_user": s.auth != nil && !s.auth.HasUsers(),
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,114 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"groq-go/internal/storage"
+)
+
+// requestOwner identifies who a share belongs to, by the same user ID as
+// sessions and credits: the account when signed in, otherwise the client
+// IP. Shares saved under the bare username before that are moved to the
+// account first.
+func (s *Server) requestOwner(r *http.Request) (string, error) {
+	owner, err := s.resolveUserID(r)
+	if err != nil {
+		return "", err
+	}
+	username, ok := requestUsername(r)
+	if !ok {
+		if user, err := s.authenticate(r); err == nil {
+			username, ok = user.Username, true
+		}
+	}
+	if ok {
+		s.adoptShares(r.Context(), username, owner)
+	}
+	return owner, nil
+}
+
+// adoptShares moves the shares owned by legacyOwner to owner
+func (s *Server) adoptShares(ctx context.Context, legacyOwner, owner string) {
+	shares, err := s.storage.ListSharesByOwner(ctx, legacyOwner)
+	if err != nil {
+		log.Warn("Failed to list legacy shares", "owner", legacyOwner, "error", err)
+		return
+	}
+	for _, share := range shares {
+		share.OwnerID = owner
+		if err := s.storage.SaveShare(ctx, share); err != nil {
+			log.Warn("Failed to move share to its account", "share_id", share.ShareID, "error", err)
+		}
+	}
+}
+
+// shareSummary describes a share for its owner without the messages
+func shareSummary(share *storage.SharedConversation) map[string]any {
+	summary := map[string]any{
+		"share_id":   share.ShareID,
+		"session_id": share.SessionID,
+		"title":      share.Title,
+		"share_url":  "/share/" + share.ShareID,
+		"created_at": share.CreatedAt,
+		"view_count": share.ViewCount,
+		"protected":  share.Password != "",
+	}
+	if !share.ExpiresAt.IsZero() {
+		summary["expires_at"] = share.ExpiresAt
+	}
+	return summary
+}
+
+// handleShareItem revokes a share: DELETE /api/share/{id}. Only the owner
+// may revoke it.
+func (s *Server) handleShareItem(w http.ResponseWriter, r *http.Request) {
+	if s.storage == nil {
+		http.Error(w, "Storage not available", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	shareID := strings.TrimPrefix(r.URL.Path, "/api/share/")
+	if shareID == "" || strings.Contains(shareID, "/") {
+		http.Error(w, "Share ID required", http.StatusBadRequest)
+		return
+	}
+
+	// Looked up first so legacy shares are moved to the account
+	owner, err := s.requestOwner(r)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	ctx := r.Context()
+	share, err := s.storage.LoadShare(ctx, shareID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if share == nil {
+		http.Error(w, "Share not found", http.StatusNotFound)
+		return
+	}
+	if share.OwnerID == "" || share.OwnerID != owner {
+		http.Error(w, "Only the owner can revoke this share", http.StatusForbidden)
+		return
+	}
+
+	if err := s.storage.DeleteShare(ctx, shareID); err != nil {
+		log.Error("Failed to delete share", "share_id", shareID, "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Info("Revoked share link", "share_id", shareID)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
@@ -0,0 +1,178 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"groq-go/internal/storage"
+)
+
+func newShareTestServer(t *testing.T) *Server {
+	t.Helper()
+	store, err := storage.NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStorage failed: %v", err)
+	}
+	return &Server{storage: store}
+}
+
+// shareRequest calls a share handler as the client at ip
+func shareRequest(s *Server, handler http.HandlerFunc, method, target, ip, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.RemoteAddr = ip + ":1234"
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec
+}
+
+func createShare(t *testing.T, s *Server, ip, body string) string {
+	t.Helper()
+	rec := shareRequest(s, s.handleShare, http.MethodPost, "/api/share", ip, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Create share: status %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		ShareID string `json:"share_id"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	return resp.ShareID
+}
+
+func TestShareRevokeOwnerOnly(t *testing.T) {
+	s := newShareTestServer(t)
+	id := createShare(t, s, "10.0.0.1", `{"title":"Mine","messages":[{"role":"user","content":"hi"}]}`)
+
+	// Another client can neither see nor revoke it
+	rec := shareRequest(s, s.handleShare, http.MethodGet, "/api/share", "10.0.0.2", "")
+	if strings.Contains(rec.Body.String(), id) {
+		t.Errorf("Expected other clients not to list the share, got %s", rec.Body.String())
+	}
+	if rec := shareRequest(s, s.handleShareItem, http.MethodDelete, "/api/share/"+id, "10.0.0.2", ""); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a non-owner, got %d", rec.Code)
+	}
+
+	// The owner lists and revokes it
+	rec = shareRequest(s, s.handleShare, http.MethodGet, "/api/share", "10.0.0.1", "")
+	var list struct {
+		Shares []map[string]any `json:"shares"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Shares) != 1 || list.Shares[0]["share_id"] != id {
+		t.Fatalf("Expected the owner to list the share, got %+v", list)
+	}
+	if rec := shareRequest(s, s.handleShareItem, http.MethodDelete, "/api/share/"+id, "10.0.0.1", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the owner to revoke the share, got %d", rec.Code)
+	}
+	if rec := shareRequest(s, s.handleSharedView, http.MethodGet, "/share/"+id, "10.0.0.3", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected a revoked share to be gone, got %d", rec.Code)
+	}
+	if rec := shareRequest(s, s.handleShareItem, http.MethodDelete, "/api/share/"+id, "10.0.0.1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 revoking twice, got %d", rec.Code)
+	}
+}
+
+func TestSharePassword(t *testing.T) {
+	s := newShareTestServer(t)
+	id := createShare(t, s, "10.0.0.1", `{"title":"Secret","messages":[{"role":"user","content":"hi"}],"password":"hunter2"}`)
+
+	for _, target := range []string{"/share/" + id, "/share/" + id + "?key=wrong"} {
+		if rec := shareRequest(s, s.handleSharedView, http.MethodGet, target, "10.0.0.2", ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("%s: expected 401, got %d", target, rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/share/"+id+"?key=hunter2", nil)
+	req.Header.Set("Accept", "application/json")
+	rec := httptest.NewRecorder()
+	s.handleSharedView(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected the key to unlock the share, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, "Secret") || strings.Contains(body, "password") || strings.Contains(body, "owner_id") {
+		t.Errorf("Expected the share without password or owner, got %s", body)
+	}
+
+	// The owner's listing marks it as protected
+	rec = shareRequest(s, s.handleShare, http.MethodGet, "/api/share", "10.0.0.1", "")
+	if !strings.Contains(rec.Body.String(), `"protected":true`) {
+		t.Errorf("Expected the share to be listed as protected, got %s", rec.Body.String())
+	}
+}
+
+func TestSharePasswordAttemptsLimited(t *testing.T) {
+	orig := shareKeyFailures
+	shareKeyFailures = newRateLimiter(3, rateLimitWindow)
+	t.Cleanup(func() { shareKeyFailures = orig })
+	now := time.Now()
+	shareKeyFailures.now = func() time.Time { return now }
+
+	s := newShareTestServer(t)
+	id := createShare(t, s, "10.0.0.1", `{"title":"Secret","messages":[{"role":"user","content":"hi"}],"password":"hunter2"}`)
+
+	// Wrong keys count per share, whichever client sends them
+	for i, ip := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4"} {
+		if rec := shareRequest(s, s.handleSharedView, http.MethodGet, "/share/"+id+"?key=guess"+ip, ip, ""); rec.Code != http.StatusUnauthorized {
+			t.Errorf("Attempt %d: expected 401, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := shareRequest(s, s.handleSharedView, http.MethodGet, "/share/"+id+"?key=hunter2", "10.0.0.5", ""); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 once the share's attempts are used up, got %d", rec.Code)
+	}
+
+	now = now.Add(rateLimitWindow)
+	if rec := shareRequest(s, s.handleSharedView, http.MethodGet, "/share/"+id+"?key=hunter2", "10.0.0.5", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the key accepted after the window, got %d", rec.Code)
+	}
+}
+
+func TestShareOwnedByAccount(t *testing.T) {
+	s := newIdentityTestServer(t, true)
+	s.storage = newShareTestServer(t).storage
+	token := login(t, s, "10.0.0.1")
+	do := func(handler http.HandlerFunc, method, target, ip, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, strings.NewReader(body))
+		r.RemoteAddr = ip + ":1234"
+		r.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler(rec, r)
+		return rec
+	}
+
+	// Saved under the bare username before shares were keyed by account
+	legacy := &storage.SharedConversation{ShareID: "legacy123456", Title: "Old", OwnerID: "alice", CreatedAt: time.Now()}
+	if err := s.storage.SaveShare(context.Background(), legacy); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := do(s.handleShare, http.MethodPost, "/api/share", "10.0.0.1", `{"title":"New","messages":[{"role":"user","content":"hi"}]}`)
+	var created struct {
+		ShareID string `json:"share_id"`
+	}
+	json.NewDecoder(rec.Body).Decode(&created)
+	share, err := s.storage.LoadShare(context.Background(), created.ShareID)
+	if err != nil || share == nil || share.OwnerID != accountUserID("alice") {
+		t.Fatalf("Expected the share owned by the account, got %+v (%v)", share, err)
+	}
+
+	// The same account from another IP sees both, the old one moved over
+	rec = do(s.handleShare, http.MethodGet, "/api/share", "203.0.113.9", "")
+	var list struct {
+		Shares []map[string]any `json:"shares"`
+	}
+	json.NewDecoder(rec.Body).Decode(&list)
+	if len(list.Shares) != 2 {
+		t.Fatalf("Expected both shares listed, got %+v", list)
+	}
+	if moved, _ := s.storage.LoadShare(context.Background(), legacy.ShareID); moved.OwnerID != accountUserID("alice") {
+		t.Errorf("Expected the legacy share moved to the account, got %q", moved.OwnerID)
+	}
+	if rec := do(s.handleShareItem, http.MethodDelete, "/api/share/"+legacy.ShareID, "203.0.113.9", ""); rec.Code != http.StatusOK {
+		t.Errorf("Expected the owner to revoke the legacy share, got %d", rec.Code)
+	}
+}
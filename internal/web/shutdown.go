@@ -0,0 +1,213 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ShutdownTimeout is how long main gives Shutdown before exiting anyway.
+// Keep it below kill_timeout in fly.toml.
+const ShutdownTimeout = 25 * time.Second
+
+// shutdownGrace is how long Shutdown lets running turns finish before
+// cancelling them. A variable so tests can shorten it.
+var shutdownGrace = 20 * time.Second
+
+// restartNotice tells clients the server is going away
+const restartNotice = "Server restarting, reconnect in a few seconds"
+
+// HTTP server timeouts. There is no read or write timeout since uploads,
+// log streams and WebSockets legitimately run long.
+const (
+	readHeaderTimeout = 10 * time.Second
+	idleTimeout       = 2 * time.Minute
+)
+
+// wsConn is a WebSocket connection tracked for shutdown
+type wsConn struct {
+	conn    *websocket.Conn
+	writeMu sync.Mutex // gorilla/websocket allows one writer at a time
+	cancel  context.CancelFunc
+}
+
+// connRegistry tracks WebSocket connections and their running turns so
+// Shutdown can notify, drain and close them. The zero value is ready.
+type connRegistry struct {
+	mu       sync.Mutex
+	conns    map[*websocket.Conn]*wsConn
+	draining bool
+	turns    sync.WaitGroup
+	handlers sync.WaitGroup
+}
+
+// add tracks conn, whose turns are cancelled with cancel. It returns false
+// once the server is shutting down.
+func (r *connRegistry) add(conn *websocket.Conn, cancel context.CancelFunc) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return false
+	}
+	if r.conns == nil {
+		r.conns = make(map[*websocket.Conn]*wsConn)
+	}
+	r.conns[conn] = &wsConn{conn: conn, cancel: cancel}
+	r.handlers.Add(1)
+	return true
+}
+
+// remove stops tracking conn once its handler is done with it
+func (r *connRegistry) remove(conn *websocket.Conn) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.conns[conn]; ok {
+		delete(r.conns, conn)
+		r.handlers.Done()
+	}
+}
+
+// lockWrites serializes writes to conn; call the returned function to
+// release it. Untracked connections have a single writer and need no lock.
+func (r *connRegistry) lockWrites(conn *websocket.Conn) func() {
+	r.mu.Lock()
+	c, ok := r.conns[conn]
+	r.mu.Unlock()
+	if !ok {
+		return func() {}
+	}
+	c.writeMu.Lock()
+	return c.writeMu.Unlock
+}
+
+// beginTurn counts a chat turn as running. It returns false once the
+// server is shutting down; call endTurn otherwise.
+func (r *connRegistry) beginTurn() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.draining {
+		return false
+	}
+	r.turns.Add(1)
+	return true
+}
+
+func (r *connRegistry) endTurn() {
+	r.turns.Done()
+}
+
+// drain refuses new connections and turns and returns the current ones
+func (r *connRegistry) drain() []*wsConn {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.draining = true
+	conns := make([]*wsConn, 0, len(r.conns))
+	for _, c := range r.conns {
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+// wait waits for wg until ctx is done and reports whether it finished
+func wait(ctx context.Context, wg *sync.WaitGroup) bool {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Serve serves the web UI and API on l until Shutdown is called
+func (s *Server) Serve(l net.Listener) error {
+	handler, err := s.handler()
+	if err != nil {
+		return err
+	}
+
+	srv := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: readHeaderTimeout,
+		IdleTimeout:       idleTimeout,
+		MaxHeaderBytes:    1 << 20,
+	}
+	s.srvMu.Lock()
+	s.httpServer = srv
+	s.srvMu.Unlock()
+
+	log.Info("Starting web server", "addr", l.Addr().String())
+	if err := srv.Serve(l); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the server gracefully: it stops accepting connections,
+// tells WebSocket clients the server is restarting, lets running turns
+// finish for up to shutdownGrace before cancelling them, then closes the
+// connections, which save their sessions on the way out, and the storage.
+func (s *Server) Shutdown(ctx context.Context) error {
+	conns := s.conns.drain()
+	log.Info("Shutting down web server", "connections", len(conns))
+
+	s.srvMu.Lock()
+	srv := s.httpServer
+	s.srvMu.Unlock()
+	srvDone := make(chan error, 1)
+	if srv != nil {
+		// Closes the listener at once; WebSockets are hijacked and not waited for
+		go func() { srvDone <- srv.Shutdown(ctx) }()
+	} else {
+		srvDone <- nil
+	}
+
+	for _, c := range conns {
+		s.sendMessage(c.conn, WSMessage{Type: "system", Content: restartNotice})
+	}
+
+	graceCtx, cancel := context.WithTimeout(ctx, shutdownGrace)
+	finished := wait(graceCtx, &s.conns.turns)
+	cancel()
+	if !finished {
+		log.Warn("Cancelling turns still running after the grace period")
+		for _, c := range conns {
+			c.cancel()
+		}
+		wait(ctx, &s.conns.turns)
+	}
+
+	for _, c := range conns {
+		c.writeMu.Lock()
+		c.conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseServiceRestart, "server restarting"),
+			time.Now().Add(time.Second))
+		c.writeMu.Unlock()
+		c.conn.Close()
+	}
+	if !wait(ctx, &s.conns.handlers) {
+		log.Warn("Gave up waiting for WebSocket handlers to save sessions")
+	}
+
+	// Running jobs are cancelled and record their failure before storage closes
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
+
+	err := <-srvDone
+	if s.storage != nil {
+		if cerr := s.storage.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
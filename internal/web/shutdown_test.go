@@ -0,0 +1,126 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// serveTestServer runs s on a random port and returns its WebSocket URL
+func serveTestServer(t *testing.T, s *Server) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+	t.Cleanup(func() {
+		s.Shutdown(context.Background())
+		if err := <-served; err != nil {
+			t.Errorf("Serve returned %v", err)
+		}
+	})
+	return "ws://" + l.Addr().String() + "/ws"
+}
+
+// expectRestartClose reads until the server closes conn and checks that
+// the restart notice came first
+func expectRestartClose(t *testing.T, conn *websocket.Conn) {
+	t.Helper()
+	if msg := readUntil(t, conn, "system"); msg.Content != restartNotice {
+		t.Errorf("Expected the restart notice, got %q", msg.Content)
+	}
+	for {
+		var msg WSMessage
+		err := conn.ReadJSON(&msg)
+		if err == nil {
+			continue
+		}
+		var closeErr *websocket.CloseError
+		if !errors.As(err, &closeErr) || closeErr.Code != websocket.CloseServiceRestart {
+			t.Errorf("Expected a service restart close, got %v", err)
+		}
+		return
+	}
+}
+
+func TestShutdownNotifiesWebSockets(t *testing.T) {
+	s := &Server{client: client.New("test-key"), registry: tool.NewRegistry()}
+	url := serveTestServer(t, s)
+
+	conn := dialTestServer(t, url)
+	readUntil(t, conn, "system") // welcome
+
+	done := make(chan error, 1)
+	go func() { done <- s.Shutdown(context.Background()) }()
+	expectRestartClose(t, conn)
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Shutdown failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Shutdown did not return")
+	}
+	if _, _, err := websocket.DefaultDialer.Dial(url, nil); err == nil {
+		t.Error("Expected new connections to be refused after shutdown")
+	}
+}
+
+func TestShutdownCancelsTurnsAfterGrace(t *testing.T) {
+	orig := shutdownGrace
+	shutdownGrace = 100 * time.Millisecond
+	t.Cleanup(func() { shutdownGrace = orig })
+
+	up := &scriptedUpstream{replies: []client.Delta{{ToolCalls: []client.ToolCall{{
+		ID:       "call_1",
+		Type:     "function",
+		Function: client.FunctionCall{Name: "Block", Arguments: "{}"},
+	}}}}}
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+
+	bt := &blockingTool{canceled: make(chan struct{})}
+	registry := tool.NewRegistry()
+	registry.Register(bt)
+	store := newFakeStorage()
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: registry,
+		executor: tool.NewExecutor(registry),
+		storage:  store,
+	}
+	conn := dialTestServer(t, serveTestServer(t, s))
+	welcome := readUntil(t, conn, "system")
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "block"})
+	readUntil(t, conn, "tool_call")
+
+	start := time.Now()
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < shutdownGrace {
+		t.Errorf("Expected Shutdown to wait for the running turn, returned after %s", elapsed)
+	}
+	select {
+	case <-bt.canceled:
+	default:
+		t.Error("Expected the running tool to be canceled after the grace period")
+	}
+	expectRestartClose(t, conn)
+
+	saved, _ := store.LoadSession(context.Background(), welcome.SessionID)
+	if saved == nil || len(saved.Messages) == 0 || saved.Messages[0].Content != "block" {
+		t.Errorf("Expected the interrupted turn to be saved, got %+v", saved)
+	}
+}
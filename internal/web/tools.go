@@ -0,0 +1,49 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+)
+
+// chatModes are the WebSocket chat modes a tool policy can restrict
+var chatModes = []string{"tools", "improve"}
+
+// handleTools lists registered tools and whether the tool policy enables
+// them: GET /api/tools
+func (s *Server) handleTools(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.registry == nil {
+		http.Error(w, "Tools not available", http.StatusServiceUnavailable)
+		return
+	}
+
+	registered := s.registry.List()
+	sort.Slice(registered, func(i, j int) bool {
+		return registered[i].Name() < registered[j].Name()
+	})
+
+	list := make([]map[string]any, 0, len(registered))
+	for _, t := range registered {
+		modes := make(map[string]bool, len(chatModes))
+		for _, mode := range chatModes {
+			modes[mode] = s.registry.Enabled(mode, t.Name())
+		}
+		list = append(list, map[string]any{
+			"name":        t.Name(),
+			"description": t.Description(),
+			"enabled":     s.registry.Enabled("", t.Name()),
+			"modes":       modes,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"tools":     list,
+		"policy":    s.registry.Policy(),
+		"read_only": s.registry.ReadOnly(),
+	})
+}
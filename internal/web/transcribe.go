@@ -0,0 +1,140 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"groq-go/internal/client"
+)
+
+// maxTranscribeBytes caps the audio sent to /api/transcribe. A variable so
+// tests can shrink it.
+var maxTranscribeBytes int64 = client.MaxTranscriptionBytes
+
+// isAudioContent reports whether sniffed content may be audio. Formats
+// without a known signature, such as FLAC, sniff as octet-stream.
+func isAudioContent(content []byte) bool {
+	sniffed := http.DetectContentType(content)
+	return strings.HasPrefix(sniffed, "audio/") || strings.HasPrefix(sniffed, "video/") ||
+		sniffed == "application/ogg" || sniffed == "application/octet-stream"
+}
+
+// handleTranscribe turns uploaded audio into text with Whisper on Groq:
+// POST /api/transcribe with the audio in the multipart field "file" and
+// optional "language", "model", "prompt" and "response_format" fields.
+// response_format verbose_json adds the detected language, the duration
+// and timed segments. Each call costs the credits of one request to the
+// model. Read-only mode turns it off, like the Transcribe tool.
+func (s *Server) handleTranscribe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if s.readOnly() != nil {
+		http.Error(w, "Transcription is disabled in read-only mode", http.StatusForbidden)
+		return
+	}
+
+	// Leave room for the multipart framing around the file
+	r.Body = http.MaxBytesReader(w, r.Body, maxTranscribeBytes+1<<20)
+	if err := r.ParseMultipartForm(maxTranscribeBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, fmt.Sprintf("Audio exceeds %d bytes", maxTranscribeBytes), http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "Failed to parse form", http.StatusBadRequest)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Failed to get file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxTranscribeBytes {
+		http.Error(w, fmt.Sprintf("Audio exceeds %d bytes", maxTranscribeBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+	name, err := sanitizeUploadName(header.Filename)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !client.CanTranscribe(name) {
+		http.Error(w, "Audio format not supported: "+filepath.Ext(name), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	var opts client.TranscribeOptions
+	switch r.FormValue("response_format") {
+	case "", "json":
+	case "verbose_json":
+		opts.Timestamps = true
+	default:
+		http.Error(w, "response_format must be json or verbose_json", http.StatusBadRequest)
+		return
+	}
+	model := r.FormValue("model")
+	if model != "" && !slices.Contains(client.TranscriptionModels, model) {
+		http.Error(w, "Unknown transcription model: "+model, http.StatusBadRequest)
+		return
+	}
+	opts.Prompt = r.FormValue("prompt")
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		http.Error(w, "Failed to read file", http.StatusInternalServerError)
+		return
+	}
+	if !isAudioContent(content) {
+		http.Error(w, "File is not audio", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	// Each transcription is billed as one request to the Whisper model
+	if model == "" {
+		model = client.DefaultTranscriptionModel
+	}
+	var userID, clientIP string
+	if s.credits != nil {
+		id, err := s.resolveUserID(r)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		userID, clientIP = id, requestClientIP(r)
+		s.credits.GetOrCreateUser(userID, "")
+		if errMsg := s.creditError(userID, clientIP, model, 0); errMsg != "" {
+			http.Error(w, errMsg, http.StatusPaymentRequired)
+			return
+		}
+	}
+
+	result, err := s.client.Transcribe(r.Context(), content, name, model, r.FormValue("language"), opts)
+	if errors.Is(err, client.ErrNoTranscriptionKey) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		log.Error("Transcription failed", "file", name, "bytes", len(content), "error", err)
+		http.Error(w, "Transcription failed: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	if s.credits != nil {
+		userBilling{s: s, userID: userID, clientIP: clientIP}.Charge(model)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
@@ -0,0 +1,163 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/config"
+	"groq-go/internal/credits"
+)
+
+// wavBytes is enough of a WAV file for content sniffing
+var wavBytes = []byte("RIFF\x24\x00\x00\x00WAVEfmt \x10\x00\x00\x00\x01\x00\x01\x00")
+
+// newTranscribeServer returns a server whose Groq endpoint records the
+// audio it gets and answers with a transcription
+func newTranscribeServer(t *testing.T, apiKey string) (*Server, *[]byte) {
+	t.Helper()
+	var got []byte
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f, _, err := r.FormFile("file")
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		got, _ = io.ReadAll(f)
+		if r.FormValue("response_format") == "verbose_json" {
+			fmt.Fprint(w, `{"text": "hi", "language": "english", "duration": 1.2, "segments": [{"id": 0, "start": 0, "end": 1.2, "text": "hi"}]}`)
+			return
+		}
+		fmt.Fprintf(w, `{"text": "hi in %s"}`, r.FormValue("language"))
+	}))
+	t.Cleanup(upstream.Close)
+	return &Server{client: client.New(apiKey, client.WithBaseURL(upstream.URL))}, &got
+}
+
+func postTranscribe(t *testing.T, s *Server, name string, content []byte, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	for k, v := range fields {
+		mw.WriteField(k, v)
+	}
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/transcribe", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	s.handleTranscribe(w, req)
+	return w
+}
+
+func TestTranscribeEndpoint(t *testing.T) {
+	s, got := newTranscribeServer(t, "groq-key")
+
+	w := postTranscribe(t, s, "dictation.wav", wavBytes, map[string]string{"language": "en"})
+	if w.Code != http.StatusOK || !bytes.Equal(*got, wavBytes) {
+		t.Fatalf("Expected the audio passed on, got %d: %s", w.Code, w.Body.String())
+	}
+	var plain map[string]any
+	json.Unmarshal(w.Body.Bytes(), &plain)
+	if plain["text"] != "hi in en" || plain["segments"] != nil {
+		t.Errorf("Expected plain text, got %v", plain)
+	}
+
+	w = postTranscribe(t, s, "dictation.wav", wavBytes, map[string]string{"response_format": "verbose_json"})
+	var verbose client.Transcription
+	if err := json.Unmarshal(w.Body.Bytes(), &verbose); err != nil || verbose.Language != "english" || len(verbose.Segments) != 1 || verbose.Segments[0].End != 1.2 {
+		t.Errorf("Expected the segments passed through, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTranscribeEndpointRejects(t *testing.T) {
+	s, _ := newTranscribeServer(t, "groq-key")
+	orig := maxTranscribeBytes
+	maxTranscribeBytes = 1024
+	t.Cleanup(func() { maxTranscribeBytes = orig })
+
+	for _, tc := range []struct {
+		desc    string
+		name    string
+		content []byte
+		fields  map[string]string
+		status  int
+	}{
+		{"text file", "notes.txt", []byte("hello"), nil, http.StatusUnsupportedMediaType},
+		{"HTML named as audio", "evil.wav", []byte("<html><script>alert(1)</script></html>"), nil, http.StatusUnsupportedMediaType},
+		{"too large", "long.wav", append(wavBytes, make([]byte, 2048)...), nil, http.StatusRequestEntityTooLarge},
+		{"unknown format", "dictation.wav", wavBytes, map[string]string{"response_format": "srt"}, http.StatusBadRequest},
+		{"unknown model", "dictation.wav", wavBytes, map[string]string{"model": "llama-3.3-70b-versatile"}, http.StatusBadRequest},
+	} {
+		if w := postTranscribe(t, s, tc.name, tc.content, tc.fields); w.Code != tc.status {
+			t.Errorf("%s: expected %d, got %d: %s", tc.desc, tc.status, w.Code, w.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/transcribe", nil)
+	w := httptest.NewRecorder()
+	s.handleTranscribe(w, req)
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", w.Code)
+	}
+
+	noKey, _ := newTranscribeServer(t, "")
+	if w := postTranscribe(t, noKey, "dictation.wav", wavBytes, nil); w.Code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 without a Groq key, got %d", w.Code)
+	}
+}
+
+func TestTranscribeSharesSpeechBudget(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/api/transcribe", nil)
+	if class := classify(req); class != limitTTS {
+		t.Errorf("Expected transcription to count against the speech budget, got %s", class)
+	}
+}
+
+func TestTranscribeEndpointReadOnly(t *testing.T) {
+	s, got := newTranscribeServer(t, "groq-key")
+	s.cfg = &config.Config{ReadOnly: config.ReadOnlyConfig{Enabled: true}}
+
+	w := postTranscribe(t, s, "dictation.wav", wavBytes, nil)
+	if w.Code != http.StatusForbidden || *got != nil {
+		t.Errorf("Expected 403 without calling Groq in read-only mode, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestTranscribeEndpointCharges(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	s, got := newTranscribeServer(t, "groq-key")
+	manager, err := credits.NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.credits = manager
+	// httptest requests come from 192.0.2.1
+	userID := ipUserID("192.0.2.1")
+
+	if w := postTranscribe(t, s, "dictation.wav", wavBytes, nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected the transcription to run, got %d: %s", w.Code, w.Body.String())
+	}
+	balance := manager.GetBalance(userID)
+	if want := credits.FreeCreditsForNewUser - manager.ComputeCost(client.DefaultTranscriptionModel, client.Usage{}); balance != want {
+		t.Errorf("Expected one request charged, balance %d, got %d", want, balance)
+	}
+
+	manager.AddCredits(userID, -balance, "use", "")
+	*got = nil
+	w := postTranscribe(t, s, "dictation.wav", wavBytes, nil)
+	if w.Code != http.StatusPaymentRequired || *got != nil {
+		t.Errorf("Expected 402 without calling Groq, got %d: %s", w.Code, w.Body.String())
+	}
+}
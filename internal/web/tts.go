@@ -0,0 +1,82 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"unicode/utf8"
+
+	"groq-go/internal/tts"
+)
+
+// maxTTSChars bounds the text of one speech request
+const maxTTSChars = 5000
+
+// handleTTS speaks text with the requested provider, or the default one.
+// When no provider can speak, it answers with {"fallback": true} so the
+// client uses the browser's speech synthesis.
+func (s *Server) handleTTS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Text     string `json:"text"`
+		Provider string `json:"provider"`
+		tts.Options
+		VoiceID string `json:"voice_id"` // Older ElevenLabs clients
+		ModelID string `json:"model_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Text == "" {
+		http.Error(w, "Text is required", http.StatusBadRequest)
+		return
+	}
+	if utf8.RuneCountInString(req.Text) > maxTTSChars {
+		http.Error(w, "Text is too long", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if req.Voice == "" {
+		req.Voice = req.VoiceID
+	}
+	if req.Model == "" {
+		req.Model = req.ModelID
+	}
+
+	if s.tts == nil {
+		ttsFallback(w, http.StatusServiceUnavailable, tts.ErrUnavailable.Error())
+		return
+	}
+	audio, err := s.tts.Synthesize(r.Context(), req.Provider, req.Text, req.Options)
+	if errors.Is(err, tts.ErrUnavailable) {
+		ttsFallback(w, http.StatusServiceUnavailable, err.Error())
+		return
+	}
+	if err != nil {
+		log.Error("TTS failed", "provider", req.Provider, "error", err)
+		ttsFallback(w, http.StatusBadGateway, "TTS provider error")
+		return
+	}
+
+	cache := "miss"
+	if audio.Cached {
+		cache = "hit"
+	}
+	w.Header().Set("Content-Type", audio.MIME)
+	w.Header().Set("Content-Length", strconv.Itoa(len(audio.Data)))
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	w.Header().Set("X-TTS-Cache", cache)
+	w.Write(audio.Data)
+}
+
+// ttsFallback tells the client to speak the text itself
+func ttsFallback(w http.ResponseWriter, status int, reason string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{"fallback": true, "error": reason})
+}
@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"groq-go/internal/config"
+	"groq-go/internal/tts"
+)
+
+func TestHandleTTSCachesAudio(t *testing.T) {
+	var upstreamCalls atomic.Int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamCalls.Add(1)
+		w.Header().Set("Content-Type", "audio/mpeg")
+		io.WriteString(w, "ID3-"+strings.TrimPrefix(r.URL.Path, "/v1/text-to-speech/"))
+	}))
+	defer upstream.Close()
+
+	cache, err := tts.NewCache(t.TempDir(), tts.DefaultCacheMaxBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{tts: tts.NewService(cache, &tts.ElevenLabs{APIKey: "key", BaseURL: upstream.URL})}
+
+	speak := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		s.handleTTS(rec, httptest.NewRequest("POST", "/api/tts", strings.NewReader(body)))
+		return rec
+	}
+
+	rec := speak(`{"text":"hello","provider":"elevenlabs","voice":"rachel"}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != "ID3-rachel" || rec.Header().Get("X-TTS-Cache") != "miss" {
+		t.Fatalf("Expected fresh audio, got %d %q %q", rec.Code, rec.Body.String(), rec.Header().Get("X-TTS-Cache"))
+	}
+	if rec.Header().Get("Content-Type") != "audio/mpeg" || !strings.Contains(rec.Header().Get("Cache-Control"), "max-age") {
+		t.Errorf("Unexpected headers %v", rec.Header())
+	}
+
+	// The older voice_id field still works, and the repeat is served from the cache
+	rec = speak(`{"text":"hello","voice_id":"rachel"}`)
+	if rec.Code != http.StatusOK || rec.Body.String() != "ID3-rachel" || rec.Header().Get("X-TTS-Cache") != "hit" {
+		t.Errorf("Expected cached audio, got %d %q %q", rec.Code, rec.Body.String(), rec.Header().Get("X-TTS-Cache"))
+	}
+	if n := upstreamCalls.Load(); n != 1 {
+		t.Errorf("Expected one upstream call, got %d", n)
+	}
+}
+
+func TestHandleTTSFallback(t *testing.T) {
+	s := &Server{tts: tts.NewService(nil, tts.NewProviders(config.TTSConfig{})...)}
+
+	for _, body := range []string{`{"text":"hello"}`, `{"text":"hello","provider":"kokoro"}`} {
+		rec := httptest.NewRecorder()
+		s.handleTTS(rec, httptest.NewRequest("POST", "/api/tts", strings.NewReader(body)))
+		var resp map[string]any
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != http.StatusServiceUnavailable || resp["fallback"] != true {
+			t.Errorf("%s: expected a fallback, got %d %v", body, rec.Code, resp)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	s.handleTTS(rec, httptest.NewRequest("POST", "/api/tts", strings.NewReader(`{"text":""}`)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected empty text rejected, got %d", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	s.handleTTS(rec, httptest.NewRequest("POST", "/api/tts", strings.NewReader(`{"text":"`+strings.Repeat("a", maxTTSChars+1)+`"}`)))
+	if rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected long text rejected, got %d", rec.Code)
+	}
+}
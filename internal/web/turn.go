@@ -0,0 +1,148 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+var (
+	// errTurnStopped is the cause of a turn cancelled by a "stop" message
+	errTurnStopped = errors.New("stopped by user")
+	// errTurnInProgress rejects a chat message while a turn is running
+	errTurnInProgress = errors.New("a response is still being generated; send stop to cancel it first")
+)
+
+// claimTurn reserves the connection for a new turn, derived from the
+// connection's ctx. It returns false if a turn is already running.
+func (sess *chatSession) claimTurn(ctx context.Context) bool {
+	sess.turnMu.Lock()
+	defer sess.turnMu.Unlock()
+	if sess.turnCtx != nil {
+		return false
+	}
+	sess.turnCtx, sess.turnCancel = context.WithCancelCause(ctx)
+	return true
+}
+
+// currentTurn returns the context of the claimed turn
+func (sess *chatSession) currentTurn() context.Context {
+	sess.turnMu.Lock()
+	defer sess.turnMu.Unlock()
+	return sess.turnCtx
+}
+
+// finishTurn releases the connection for the next turn
+func (sess *chatSession) finishTurn() {
+	sess.turnMu.Lock()
+	defer sess.turnMu.Unlock()
+	if sess.turnCancel != nil {
+		sess.turnCancel(nil)
+	}
+	sess.turnCtx, sess.turnCancel = nil, nil
+}
+
+// stopTurn cancels the running turn and reports whether there was one
+func (sess *chatSession) stopTurn() bool {
+	sess.turnMu.Lock()
+	defer sess.turnMu.Unlock()
+	if sess.turnCancel == nil {
+		return false
+	}
+	sess.turnCancel(errTurnStopped)
+	return true
+}
+
+// turnStopped reports whether ctx was cancelled by a "stop" message
+func turnStopped(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errTurnStopped)
+}
+
+// Codes sent with "error" messages for provider errors the UI can act on
+const (
+	errCodeRateLimited     = "rate_limited"
+	errCodeAuthFailed      = "auth_failed"
+	errCodeContextExceeded = "context_length_exceeded"
+)
+
+// apiErrorMessage describes a failed model request. Rate limits, rejected
+// API keys and oversized conversations get a code and a plain message;
+// other errors are sent as they are.
+func apiErrorMessage(err error) WSMessage {
+	var apiErr *client.APIError
+	errors.As(err, &apiErr)
+	switch {
+	case client.IsRateLimit(err):
+		msg := WSMessage{Type: "error", Code: errCodeRateLimited, Error: fmt.Sprintf("Rate limited by %s, try again shortly", apiErr.Provider)}
+		if apiErr.RetryAfter > 0 {
+			secs := int((apiErr.RetryAfter + time.Second - 1) / time.Second)
+			msg.RetryAfter = secs
+			msg.Error = fmt.Sprintf("Rate limited by %s, try again in %ds", apiErr.Provider, secs)
+		}
+		return msg
+	case client.IsAuthError(err):
+		return WSMessage{Type: "error", Code: errCodeAuthFailed, Error: fmt.Sprintf("Invalid API key for %s; ask the administrator to check it, or pick another model", apiErr.Provider)}
+	case client.IsContextLengthExceeded(err):
+		return WSMessage{Type: "error", Code: errCodeContextExceeded, Error: "The conversation is too long for this model; start a new chat or pick a model with a larger context window"}
+	}
+	return WSMessage{Type: "error", Error: err.Error()}
+}
+
+// runTurn handles a chat message on the worker goroutine. The read loop
+// claimed the turn when the message arrived; it is released before "done"
+// so the client may send its next message right away.
+func (s *Server) runTurn(conn *websocket.Conn, sess *chatSession, msg WSMessage, asker *wsAsker) {
+	s.chatTurn(sess.currentTurn(), conn, sess, msg, asker)
+	sess.finishTurn()
+	s.sendMessage(conn, WSMessage{Type: "done"})
+}
+
+func (s *Server) chatTurn(ctx context.Context, conn *websocket.Conn, sess *chatSession, msg WSMessage, asker *wsAsker) {
+	log.Debug("User message", "client_ip", sess.clientIP, "content", truncateLog(msg.Content, 100))
+	if n := len(msg.Images) + len(msg.ImageIDs); n > 0 {
+		log.Debug("Message includes images", "count", n)
+	}
+	if msg.System != "" {
+		if err := s.setSessionPrompt(sess, msg.System); err != nil {
+			s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+			return
+		}
+	}
+	// Update mode if provided with chat message
+	if msg.Mode != "" && (msg.Mode == "tools" || msg.Mode == "improve") {
+		sess.mode = msg.Mode
+		sess.history.SetSystem(client.Message{
+			Role:    "system",
+			Content: s.getSystemPrompt(sess),
+		})
+	}
+	opts := client.RequestOptions{MaxTokens: msg.MaxTokens, Temperature: msg.Temperature}
+	if err := opts.Validate(); err != nil {
+		s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+		return
+	}
+	if !s.conns.beginTurn() {
+		s.sendMessage(conn, WSMessage{Type: "error", Error: restartNotice})
+		return
+	}
+	defer s.conns.endTurn()
+
+	// Tools stay within the session's project
+	sandbox, err := s.sandboxFor(sess)
+	if err != nil {
+		s.sendMessage(conn, WSMessage{Type: "error", Error: err.Error()})
+		return
+	}
+	ctx = tool.WithSandbox(ctx, sandbox)
+	ctx = tool.WithFormat(ctx, s.formatOnWrite())
+
+	// A fresh question budget for every turn; approvals go to the page too
+	ctx = tool.WithApproval(tool.WithAsk(ctx, asker.ask), asker.approve, sess.approvals)
+	s.handleChat(ctx, conn, sess, msg.Content, msg.Images, msg.ImageIDs, opts)
+}
@@ -0,0 +1,305 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"groq-go/internal/client"
+	"groq-go/internal/tool"
+)
+
+// hangingUpstream streams part of its first reply and then never finishes
+// it; later requests are answered by the scripted upstream
+type hangingUpstream struct {
+	scriptedUpstream
+	hung chan struct{} // Closed once the first request gives up
+}
+
+func (u *hangingUpstream) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	first := len(u.requests) == 0
+	u.mu.Unlock()
+	if !first {
+		u.scriptedUpstream.ServeHTTP(w, r)
+		return
+	}
+
+	var req client.ChatCompletionRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	u.mu.Lock()
+	u.requests = append(u.requests, req.Messages)
+	u.mu.Unlock()
+
+	data, _ := json.Marshal(client.StreamChunk{Choices: []client.Choice{{Delta: &client.Delta{Content: "partial answer"}}}})
+	w.Header().Set("Content-Type", "text/event-stream")
+	fmt.Fprintf(w, "data: %s\n\n", data)
+	w.(http.Flusher).Flush()
+	<-r.Context().Done()
+	close(u.hung)
+}
+
+func TestStopCancelsRunningTurn(t *testing.T) {
+	up := &hangingUpstream{hung: make(chan struct{})}
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: tool.NewRegistry(),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	conn := dialTestServer(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "write an essay"})
+	if msg := readUntil(t, conn, "token"); msg.Content != "partial answer" {
+		t.Fatalf("Expected the partial answer, got %q", msg.Content)
+	}
+
+	// A second chat while the first is running is refused
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "and another"})
+	if msg := readUntil(t, conn, "error"); msg.Error != errTurnInProgress.Error() {
+		t.Errorf("Expected the in-progress error, got %q", msg.Error)
+	}
+
+	start := time.Now()
+	conn.WriteJSON(WSMessage{Type: "stop"})
+	readUntil(t, conn, "stopped")
+	readUntil(t, conn, "done")
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the stop to take effect quickly, took %s", elapsed)
+	}
+	select {
+	case <-up.hung:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected the upstream request to be cancelled")
+	}
+
+	// The connection takes new turns, with the partial answer in history
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "shorter please"})
+	readUntil(t, conn, "done")
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	if len(up.requests) != 2 {
+		t.Fatalf("Expected 2 upstream requests, got %d", len(up.requests))
+	}
+	if got := roles(up.requests[1]); got != "system,user,assistant,user" {
+		t.Errorf("Expected the partial answer to stay in history, got roles %s", got)
+	}
+	if got := up.requests[1][2].Content; got != "partial answer" {
+		t.Errorf("Expected the partial answer in history, got %v", got)
+	}
+}
+
+func TestStopWithoutTurnIsIgnored(t *testing.T) {
+	conn := dialTestServer(t, newTestServer(t, &scriptedUpstream{}))
+	readUntil(t, conn, "system")
+
+	conn.WriteJSON(WSMessage{Type: "stop"})
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "hi"})
+	if msg := readUntil(t, conn, "token"); msg.Content != "done" {
+		t.Errorf("Expected the reply, got %q", msg.Content)
+	}
+	readUntil(t, conn, "done")
+}
+
+func TestTurnFallsBackToAnotherModel(t *testing.T) {
+	up := &scriptedUpstream{}
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(readBody(r), `"model":"llama-3.1-8b-instant"`) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		up.ServeHTTP(w, r)
+	}))
+	t.Cleanup(upstream.Close)
+	s := &Server{
+		client: client.New("test-key", client.WithBaseURL(upstream.URL), client.WithRetry(1, time.Millisecond),
+			client.WithFallbackModels("llama-3.1-8b-instant")),
+		registry: tool.NewRegistry(),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	conn := dialTestServer(t, "ws"+strings.TrimPrefix(srv.URL, "http"))
+	readUntil(t, conn, "system")
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "hello"})
+	msg := readUntil(t, conn, "system")
+	if msg.Model != "llama-3.1-8b-instant" || !strings.Contains(msg.Content, "fell back to llama-3.1-8b-instant") {
+		t.Errorf("Expected a fallback notice, got %+v", msg)
+	}
+	if msg := readUntil(t, conn, "token"); msg.Content != "done" {
+		t.Errorf("Expected the fallback's answer, got %q", msg.Content)
+	}
+	readUntil(t, conn, "done")
+}
+
+// TestTurnsWhileModelChanges runs turns while setup switches the model, as
+// saving new settings does; run with -race
+func TestTurnsWhileModelChanges(t *testing.T) {
+	up := &scriptedUpstream{}
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+	s := &Server{
+		client:   client.New("test-key", client.WithBaseURL(upstream.URL)),
+		registry: tool.NewRegistry(),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+
+	done := make(chan struct{})
+	switched := make(chan struct{})
+	stop := sync.OnceFunc(func() {
+		close(done)
+		<-switched
+	})
+	defer stop()
+	go func() {
+		defer close(switched)
+		models := []string{"llama-3.3-70b-versatile", "llama-3.1-8b-instant"}
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			s.client.SetModel(models[i%2])
+			s.client.SetProviderKey("groq", "test-key")
+		}
+	}()
+	for range 2 {
+		conn := dialTestServer(t, url)
+		for range 3 {
+			if types := turnMessages(t, conn, WSMessage{Type: "chat", Content: "hello"}); !slices.Contains(types, "token") {
+				t.Errorf("Expected the turn to stream, got %v", types)
+			}
+		}
+	}
+	stop()
+
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	if len(up.models) != 6 {
+		t.Errorf("Expected six upstream requests, got %d", len(up.models))
+	}
+}
+
+// readBody returns a request body and puts it back for the next reader
+func readBody(r *http.Request) string {
+	data, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(data))
+	return string(data)
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	tests := []struct {
+		err     error
+		code    string
+		retry   int
+		message string
+	}{
+		{&client.APIError{StatusCode: 429, Provider: "groq", RetryAfter: 19500 * time.Millisecond}, errCodeRateLimited, 20, "Rate limited by groq, try again in 20s"},
+		{&client.APIError{StatusCode: 429, Provider: "groq"}, errCodeRateLimited, 0, "Rate limited by groq, try again shortly"},
+		{fmt.Errorf("wrapped: %w", &client.APIError{StatusCode: 401, Provider: "anthropic"}), errCodeAuthFailed, 0, "Invalid API key for anthropic"},
+		{&client.APIError{StatusCode: 400, Provider: "openai", Code: "context_length_exceeded"}, errCodeContextExceeded, 0, "too long"},
+		{fmt.Errorf("unexpected EOF"), "", 0, "unexpected EOF"},
+	}
+	for _, tt := range tests {
+		msg := apiErrorMessage(tt.err)
+		if msg.Type != "error" || msg.Code != tt.code || msg.RetryAfter != tt.retry || !strings.Contains(msg.Error, tt.message) {
+			t.Errorf("%v: unexpected message %+v", tt.err, msg)
+		}
+	}
+}
+
+// turnMessages sends a chat message and returns the types of the messages
+// up to "done"
+func turnMessages(t *testing.T, conn *websocket.Conn, msg WSMessage) []string {
+	t.Helper()
+	conn.WriteJSON(msg)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var types []string
+	for {
+		var reply WSMessage
+		if err := conn.ReadJSON(&reply); err != nil {
+			t.Fatalf("Waiting for done: %v", err)
+		}
+		types = append(types, reply.Type)
+		if reply.Type == "done" {
+			return types
+		}
+	}
+}
+
+func TestCachedTurnIsNotCharged(t *testing.T) {
+	up := &scriptedUpstream{}
+	upstream := httptest.NewServer(up)
+	t.Cleanup(upstream.Close)
+	s := newIdentityTestServer(t, false)
+	s.client = client.New("test-key", client.WithBaseURL(upstream.URL), client.WithCache(t.TempDir(), time.Hour))
+	s.registry = tool.NewRegistry()
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	chat := WSMessage{Type: "chat", Content: "hello", Temperature: client.Float(0)}
+
+	first := turnMessages(t, dialTestServer(t, url), chat)
+	if !slices.Contains(first, "credits") {
+		t.Errorf("Expected the first turn to be charged, got %v", first)
+	}
+	second := turnMessages(t, dialTestServer(t, url), chat)
+	if slices.Contains(second, "credits") || !slices.Contains(second, "token") {
+		t.Errorf("Expected the cached turn to stream without a charge, got %v", second)
+	}
+	up.mu.Lock()
+	defer up.mu.Unlock()
+	if len(up.requests) != 1 {
+		t.Errorf("Expected one upstream request, got %d", len(up.requests))
+	}
+}
+
+func TestFailedTurnChargesEarlierToolRounds(t *testing.T) {
+	var calls int
+	var mu sync.Mutex
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		n := calls
+		mu.Unlock()
+		if n > 1 {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":{"message":"bad request"}}`)
+			return
+		}
+		delta := client.Delta{ToolCalls: []client.ToolCall{{ID: "call_1", Type: "function", Function: client.FunctionCall{Name: "Missing", Arguments: "{}"}}}}
+		data, _ := json.Marshal(client.StreamChunk{
+			Choices: []client.Choice{{Delta: &delta, FinishReason: "tool_calls"}},
+			Usage:   &client.Usage{PromptTokens: 20000, CompletionTokens: 5000, TotalTokens: 25000},
+		})
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprintf(w, "data: %s\n\ndata: [DONE]\n\n", data)
+	}))
+	t.Cleanup(upstream.Close)
+	s := newIdentityTestServer(t, false)
+	s.client = client.New("test-key", client.WithBaseURL(upstream.URL), client.WithRetry(1, time.Millisecond))
+	s.registry = tool.NewRegistry()
+	s.executor = tool.NewExecutor(s.registry)
+	srv := httptest.NewServer(http.HandlerFunc(s.handleWebSocket))
+	t.Cleanup(srv.Close)
+
+	types := turnMessages(t, dialTestServer(t, "ws"+strings.TrimPrefix(srv.URL, "http")), WSMessage{Type: "chat", Content: "hello"})
+	if !slices.Contains(types, "error") || !slices.Contains(types, "credits") {
+		t.Errorf("Expected an error and a charge for the tool round, got %v", types)
+	}
+}
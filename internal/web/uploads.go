@@ -0,0 +1,195 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxUploadBytes caps a single file sent to /api/upload. A variable so
+// tests can shrink it; larger files go through the chunked upload API.
+var maxUploadBytes int64 = 10 << 20
+
+// maxUploadNameLen bounds the sanitized name kept after the upload ID
+const maxUploadNameLen = 100
+
+var errUploadNotFound = errors.New("upload not found")
+
+// uploadTypes maps the extensions accepted by /api/upload to the type they
+// are served with. Text formats are served as text/plain so uploaded HTML
+// or SVG never runs in the app's origin.
+var uploadTypes = map[string]string{
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".pdf":  "application/pdf",
+	".mp3":  "audio/mpeg",
+	".m4a":  "audio/mp4",
+	".wav":  "audio/wav",
+	".ogg":  "audio/ogg",
+	".opus": "audio/ogg",
+	".webm": "audio/webm",
+	".flac": "audio/flac",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".txt":  "text/plain; charset=utf-8",
+	".md":   "text/plain; charset=utf-8",
+	".csv":  "text/plain; charset=utf-8",
+	".log":  "text/plain; charset=utf-8",
+	".json": "text/plain; charset=utf-8",
+	".yaml": "text/plain; charset=utf-8",
+	".yml":  "text/plain; charset=utf-8",
+	".toml": "text/plain; charset=utf-8",
+	".xml":  "text/plain; charset=utf-8",
+	".html": "text/plain; charset=utf-8",
+	".css":  "text/plain; charset=utf-8",
+	".go":   "text/plain; charset=utf-8",
+	".py":   "text/plain; charset=utf-8",
+	".js":   "text/plain; charset=utf-8",
+	".ts":   "text/plain; charset=utf-8",
+	".java": "text/plain; charset=utf-8",
+	".c":    "text/plain; charset=utf-8",
+	".h":    "text/plain; charset=utf-8",
+	".rs":   "text/plain; charset=utf-8",
+	".rb":   "text/plain; charset=utf-8",
+	".sh":   "text/plain; charset=utf-8",
+	".sql":  "text/plain; charset=utf-8",
+}
+
+// uploadType returns the served content type of name, or "" if its
+// extension is not allowed
+func uploadType(name string) string {
+	return uploadTypes[strings.ToLower(filepath.Ext(name))]
+}
+
+func isImageType(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+func isTextType(contentType string) bool {
+	return strings.HasPrefix(contentType, "text/")
+}
+
+// sanitizeUploadName reduces a client-supplied file name to a safe base
+// name: directories are stripped (including Windows separators) and
+// anything outside [A-Za-z0-9._-] becomes an underscore
+func sanitizeUploadName(name string) (string, error) {
+	name = filepath.Base(strings.ReplaceAll(strings.TrimSpace(name), `\`, "/"))
+	var b strings.Builder
+	for _, r := range name {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' || r == '_' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	name = b.String()
+	if name == "" || strings.HasPrefix(name, ".") {
+		return "", fmt.Errorf("invalid file name")
+	}
+	if len(name) > maxUploadNameLen {
+		ext := filepath.Ext(name)
+		if len(ext) > 10 {
+			ext = ""
+		}
+		name = name[:maxUploadNameLen-len(ext)] + ext
+	}
+	return name, nil
+}
+
+// newUploadID returns a random upload ID. Stored files are named
+// "<id>_<name>", like completed chunked uploads.
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate upload ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validUploadID reports whether id has the shape of an upload ID
+func validUploadID(id string) bool {
+	if len(id) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// findUpload returns the path and original name of the upload with id
+func (s *Server) findUpload(id string) (path, name string, err error) {
+	if !validUploadID(id) {
+		return "", "", errUploadNotFound
+	}
+	entries, err := os.ReadDir(s.uploadDir)
+	if err != nil {
+		return "", "", err
+	}
+	prefix := id + "_"
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasPrefix(entry.Name(), prefix) {
+			return filepath.Join(s.uploadDir, entry.Name()), strings.TrimPrefix(entry.Name(), prefix), nil
+		}
+	}
+	return "", "", errUploadNotFound
+}
+
+// uploadImageRef returns the history reference for an uploaded image, so
+// it is read from disk only when a request is sent to the provider
+func (s *Server) uploadImageRef(id string) (string, error) {
+	path, name, err := s.findUpload(id)
+	if err != nil {
+		return "", fmt.Errorf("image %s: %w", id, err)
+	}
+	contentType := uploadType(name)
+	if !isImageType(contentType) {
+		return "", fmt.Errorf("upload %s is not an image", id)
+	}
+	return uploadRefPrefix + contentType + ";" + filepath.Base(path), nil
+}
+
+// handleUploadFile serves an upload by ID: GET /api/uploads/{id}
+func (s *Server) handleUploadFile(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/api/uploads/")
+	path, name, err := s.findUpload(id)
+	if err != nil {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		http.Error(w, "Upload not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "Failed to read upload", http.StatusInternalServerError)
+		return
+	}
+
+	contentType := uploadType(name)
+	if contentType == "" {
+		// Completed chunked uploads may have any extension
+		contentType = "application/octet-stream"
+	}
+	disposition := "attachment"
+	if isImageType(contentType) {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
@@ -0,0 +1,215 @@
+package web
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"groq-go/internal/client"
+)
+
+// pngBytes is enough of a PNG for content sniffing
+var pngBytes = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR fake image data")
+
+func postUpload(t *testing.T, s *Server, name string, content []byte) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(content)
+	mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	s.handleUpload(w, req)
+	return w
+}
+
+func decodeUpload(t *testing.T, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var resp map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid response: %v", err)
+	}
+	return resp
+}
+
+func TestUploadSanitizesName(t *testing.T) {
+	dir := t.TempDir()
+	s := &Server{uploadDir: filepath.Join(dir, "uploads")}
+	os.Mkdir(s.uploadDir, 0755)
+
+	for _, name := range []string{"../../etc/cron.d/evil.txt", `..\..\evil.txt`, "/abs/path/evil.txt"} {
+		resp := decodeUpload(t, postUpload(t, s, name, []byte("hello")))
+		if resp["name"] != "evil.txt" {
+			t.Errorf("%q: expected name evil.txt, got %v", name, resp["name"])
+		}
+		path, _ := resp["path"].(string)
+		if filepath.Dir(path) != s.uploadDir {
+			t.Errorf("%q: file written outside upload dir: %s", name, path)
+		}
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the upload dir in %s, found %d entries", dir, len(entries))
+	}
+}
+
+func TestSanitizeUploadName(t *testing.T) {
+	tests := []struct {
+		in, want string
+		err      bool
+	}{
+		{in: "report.pdf", want: "report.pdf"},
+		{in: "my photo (1).png", want: "my_photo__1_.png"},
+		{in: "../secret.txt", want: "secret.txt"},
+		{in: "..", err: true},
+		{in: ".env", err: true},
+		{in: "", err: true},
+		{in: strings.Repeat("a", 300) + ".txt", want: strings.Repeat("a", maxUploadNameLen-4) + ".txt"},
+	}
+	for _, tt := range tests {
+		got, err := sanitizeUploadName(tt.in)
+		if tt.err {
+			if err == nil {
+				t.Errorf("sanitizeUploadName(%q) = %q, expected error", tt.in, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("sanitizeUploadName(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestUploadRejectsOversize(t *testing.T) {
+	old := maxUploadBytes
+	maxUploadBytes = 1024
+	defer func() { maxUploadBytes = old }()
+
+	s := &Server{uploadDir: t.TempDir()}
+	w := postUpload(t, s, "big.txt", bytes.Repeat([]byte("x"), 2048))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413, got %d", w.Code)
+	}
+	// Far past the limit the body itself is cut off
+	w = postUpload(t, s, "huge.txt", bytes.Repeat([]byte("x"), 2<<20))
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected 413 for huge body, got %d", w.Code)
+	}
+	if entries, _ := os.ReadDir(s.uploadDir); len(entries) != 0 {
+		t.Errorf("Expected nothing stored, found %d files", len(entries))
+	}
+}
+
+func TestUploadRejectsDisallowedType(t *testing.T) {
+	s := &Server{uploadDir: t.TempDir()}
+	for _, name := range []string{"run.exe", "payload.svg", "noext"} {
+		if w := postUpload(t, s, name, []byte("data")); w.Code != http.StatusUnsupportedMediaType {
+			t.Errorf("%s: expected 415, got %d", name, w.Code)
+		}
+	}
+}
+
+func TestUploadOmitsBinaryContent(t *testing.T) {
+	s := &Server{uploadDir: t.TempDir()}
+
+	resp := decodeUpload(t, postUpload(t, s, "shot.png", pngBytes))
+	if _, ok := resp["content"]; ok {
+		t.Error("Image upload echoed its content")
+	}
+	if resp["content_type"] != "image/png" {
+		t.Errorf("Expected image/png, got %v", resp["content_type"])
+	}
+
+	resp = decodeUpload(t, postUpload(t, s, "notes.txt", []byte("some notes")))
+	if resp["content"] != "some notes" {
+		t.Errorf("Expected text content, got %v", resp["content"])
+	}
+
+	// Binary data with a text extension is not echoed either
+	resp = decodeUpload(t, postUpload(t, s, "data.txt", []byte{0xff, 0xfe, 0x00, 0x80}))
+	if _, ok := resp["content"]; ok {
+		t.Error("Invalid UTF-8 upload echoed its content")
+	}
+}
+
+func TestUploadRetrieveByID(t *testing.T) {
+	s := &Server{uploadDir: t.TempDir()}
+	resp := decodeUpload(t, postUpload(t, s, "shot.png", pngBytes))
+	id, _ := resp["id"].(string)
+	if !validUploadID(id) {
+		t.Fatalf("Invalid upload ID %q", id)
+	}
+	if resp["url"] != "/api/uploads/"+id {
+		t.Errorf("Unexpected url %v", resp["url"])
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/uploads/"+id, nil)
+	w := httptest.NewRecorder()
+	s.handleUploadFile(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", w.Code)
+	}
+	if !bytes.Equal(w.Body.Bytes(), pngBytes) {
+		t.Error("Served content differs from upload")
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "image/png" {
+		t.Errorf("Expected image/png, got %q", ct)
+	}
+	if w.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Error("Missing nosniff header")
+	}
+
+	for _, bad := range []string{"0123456789abcdef0123456789abcdef", "../../etc/passwd", id[:10]} {
+		req := httptest.NewRequest(http.MethodGet, "/api/uploads/"+bad, nil)
+		w := httptest.NewRecorder()
+		s.handleUploadFile(w, req)
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%q: expected 404, got %d", bad, w.Code)
+		}
+	}
+}
+
+func TestUploadImageRefResolves(t *testing.T) {
+	s := &Server{uploadDir: t.TempDir()}
+	id, _ := decodeUpload(t, postUpload(t, s, "shot.png", pngBytes))["id"].(string)
+
+	ref, err := s.uploadImageRef(id)
+	if err != nil {
+		t.Fatalf("uploadImageRef failed: %v", err)
+	}
+	history := []client.Message{client.NewVisionMessage("user", "look", ref)}
+	parts := s.resolveImages(history)[0].Content.([]client.ContentPart)
+	want := "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngBytes)
+	if parts[1].ImageURL.URL != want {
+		t.Errorf("Expected inlined image, got %q", parts[1].ImageURL.URL)
+	}
+
+	textID, _ := decodeUpload(t, postUpload(t, s, "notes.txt", []byte("hi")))["id"].(string)
+	if _, err := s.uploadImageRef(textID); err == nil {
+		t.Error("Expected error referencing a text upload as an image")
+	}
+	if _, err := s.uploadImageRef("0123456789abcdef0123456789abcdef"); err == nil {
+		t.Error("Expected error for unknown upload")
+	}
+	// Clients cannot forge references to arbitrary files
+	if _, err := s.spillImage(uploadRefPrefix + "image/png;../../etc/passwd"); err == nil {
+		t.Error("Expected spillImage to reject upload references")
+	}
+}
@@ -0,0 +1,52 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"groq-go/internal/version"
+)
+
+func TestVersionEndpoints(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	vm, err := version.NewManager(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &Server{versions: vm}
+
+	rec := shareRequest(s, s.handleVersions, http.MethodPost, "/api/versions", "10.0.0.1", `{"name": "faster tools"}`)
+	var created version.AgentVersion
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil || created.ID == "" {
+		t.Fatalf("Expected the new version, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	// Listing and reading run alongside each other; run with -race
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := shareRequest(s, s.handleVersions, http.MethodGet, "/api/versions", "10.0.0.1", "")
+			var resp struct {
+				Versions []version.AgentVersion `json:"versions"`
+				Count    int                    `json:"count"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Count != 1 || resp.Versions[0].ID != created.ID {
+				t.Errorf("Expected the version listed, got %d %+v", rec.Code, resp)
+			}
+			rec = shareRequest(s, s.handleVersion, http.MethodGet, "/api/versions/"+created.ID, "10.0.0.1", "")
+			var got version.AgentVersion
+			if err := json.NewDecoder(rec.Body).Decode(&got); err != nil || got.Status != version.StatusPending {
+				t.Errorf("Expected the pending version, got %d %+v", rec.Code, got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if rec := shareRequest(s, s.handleVersion, http.MethodGet, "/api/versions/missing", "10.0.0.1", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,145 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"groq-go/internal/client"
+	"groq-go/internal/ids"
+	"groq-go/internal/storage"
+	"groq-go/internal/textutil"
+	"groq-go/internal/tool"
+)
+
+// maxSavedSessionBytes caps the history persisted for a WebSocket session.
+// A variable so tests can shrink it.
+var maxSavedSessionBytes int64 = 1 << 20
+
+// errSessionNotFound is returned when resuming an unknown session
+var errSessionNotFound = errors.New("session not found")
+
+// newSessionID returns an unguessable ID for a WebSocket chat session
+func newSessionID() string {
+	return "ws-" + ids.New(24)
+}
+
+// validSessionID reports whether id is safe to use as a storage key
+func validSessionID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// resumeSession seeds the connection history from a stored session.
+// The system prompt always comes from the current mode, never from storage.
+func (s *Server) resumeSession(ctx context.Context, sess *chatSession, id string) (int, error) {
+	if s.storage == nil {
+		return 0, fmt.Errorf("storage not available")
+	}
+	if !validSessionID(id) {
+		return 0, errSessionNotFound
+	}
+	stored, err := s.loadOwnedSession(ctx, id, sess.userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load session: %w", err)
+	}
+	if stored == nil {
+		return 0, errSessionNotFound
+	}
+
+	var msgs []client.Message
+	for _, msg := range stored.Messages {
+		if msg.Role != "system" {
+			msgs = append(msgs, msg)
+		}
+	}
+	sess.history.Clear()
+	sess.reads.Reset()
+	sess.history.Append(msgs...)
+	sess.stored = stored
+	sess.approvals = tool.NewApprovalRules(stored.ApprovalRules)
+	return len(msgs), nil
+}
+
+// loadOwnedSession loads the session id if owner saved it, and returns nil
+// for sessions of other users as for missing ones. Sessions saved without
+// an owner, such as by the REPL, belong to nobody on the web.
+func (s *Server) loadOwnedSession(ctx context.Context, id, owner string) (*storage.Session, error) {
+	stored, err := s.storage.LoadSession(ctx, id)
+	if err != nil || stored == nil {
+		return nil, err
+	}
+	if stored.OwnerID == "" || stored.OwnerID != owner {
+		return nil, nil
+	}
+	return stored, nil
+}
+
+// saveSession persists the conversation after a completed turn
+func (s *Server) saveSession(ctx context.Context, sess *chatSession) {
+	if s.storage == nil || sess.stored == nil {
+		return
+	}
+	sess.unsaved = false
+	sess.stored.Messages = sessionMessages(sess.history.Messages(), maxSavedSessionBytes)
+	sess.stored.ApprovalRules = sess.approvals.List()
+	if err := s.storage.SaveSession(ctx, sess.stored); err != nil {
+		log.Warn("Failed to save session", "session_id", sess.stored.ID, "error", err)
+	}
+}
+
+// sessionMessages returns the history to persist: the system prompt is
+// dropped, then the oldest turns and oversized tool results are trimmed
+// until it fits in maxBytes
+func sessionMessages(history []client.Message, maxBytes int64) []client.Message {
+	var msgs []client.Message
+	var size int64
+	for _, msg := range history {
+		if msg.Role == "system" {
+			continue
+		}
+		msgs = append(msgs, msg)
+		size += messageBytes(msg)
+	}
+
+	// Drop whole turns so tool calls stay paired with their results
+	for size > maxBytes {
+		next := -1
+		for i := 1; i < len(msgs); i++ {
+			if msgs[i].Role == "user" {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			break
+		}
+		for _, msg := range msgs[:next] {
+			size -= messageBytes(msg)
+		}
+		msgs = msgs[next:]
+	}
+
+	if size > maxBytes {
+		for i, msg := range msgs {
+			content, ok := msg.Content.(string)
+			if msg.Role != "tool" || !ok || len(content) <= maxToolResultBytes {
+				continue
+			}
+			msgs[i].Content = textutil.TruncateBytes(content, maxToolResultBytes) + "\n... [truncated]"
+		}
+	}
+	return msgs
+}
+
+// newStoredSession starts an empty session of owner with a fresh ID
+func newStoredSession(owner string) *storage.Session {
+	return &storage.Session{ID: newSessionID(), OwnerID: owner}
+}
@@ -0,0 +1,298 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"groq-go/internal/client"
+	"groq-go/internal/storage"
+	"groq-go/internal/tool"
+)
+
+// testUserID is the user of requests made with httptest.NewRequest, and
+// wsTestUserID that of connections to a test server
+var (
+	testUserID   = ipUserID("192.0.2.1")
+	wsTestUserID = ipUserID("127.0.0.1")
+)
+
+// fakeStorage keeps sessions in memory
+type fakeStorage struct {
+	mu       sync.Mutex
+	sessions map[string]*storage.Session
+	saves    int
+}
+
+func newFakeStorage() *fakeStorage {
+	return &fakeStorage{sessions: make(map[string]*storage.Session)}
+}
+
+func (f *fakeStorage) SaveSession(ctx context.Context, session *storage.Session) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := *session
+	copied.Messages = append([]client.Message(nil), session.Messages...)
+	f.sessions[session.ID] = &copied
+	f.saves++
+	return nil
+}
+
+func (f *fakeStorage) LoadSession(ctx context.Context, id string) (*storage.Session, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (f *fakeStorage) ListSessions(ctx context.Context) ([]*storage.SessionMeta, error) {
+	return nil, nil
+}
+
+func (f *fakeStorage) DeleteSession(ctx context.Context, id string) error {
+	return nil
+}
+
+func (f *fakeStorage) SearchSessions(ctx context.Context, query, owner string, limit int) ([]*storage.SessionMatch, error) {
+	return nil, nil
+}
+
+func (f *fakeStorage) SaveShare(ctx context.Context, share *storage.SharedConversation) error {
+	return nil
+}
+
+func (f *fakeStorage) LoadShare(ctx context.Context, shareID string) (*storage.SharedConversation, error) {
+	return nil, nil
+}
+
+func (f *fakeStorage) IncrementShareViewCount(ctx context.Context, shareID string) error {
+	return nil
+}
+
+func (f *fakeStorage) ListSharesByOwner(ctx context.Context, ownerID string) ([]*storage.SharedConversation, error) {
+	return nil, nil
+}
+
+func (f *fakeStorage) DeleteShare(ctx context.Context, shareID string) error {
+	return nil
+}
+
+func (f *fakeStorage) Close() error { return nil }
+
+func roles(msgs []client.Message) string {
+	var r []string
+	for _, m := range msgs {
+		r = append(r, m.Role)
+	}
+	return strings.Join(r, ",")
+}
+
+func TestSessionSavedAfterTurn(t *testing.T) {
+	store := newFakeStorage()
+	up := &scriptedUpstream{replies: []client.Delta{
+		{ToolCalls: []client.ToolCall{{
+			ID:       "call_1",
+			Type:     "function",
+			Function: client.FunctionCall{Name: "Missing", Arguments: "{}"},
+		}}},
+		{Content: "All done"},
+	}}
+	conn := dialTestServer(t, newTestServerWithStorage(t, up, store))
+
+	welcome := readUntil(t, conn, "system")
+	if welcome.SessionID == "" {
+		t.Fatal("Expected a session ID in the welcome message")
+	}
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "do it"})
+	readUntil(t, conn, "done")
+
+	saved, _ := store.LoadSession(context.Background(), welcome.SessionID)
+	if saved == nil {
+		t.Fatal("Expected the session to be saved after the turn")
+	}
+	if got := roles(saved.Messages); got != "user,assistant,tool,assistant" {
+		t.Errorf("Expected the system prompt to be left out, got %s", got)
+	}
+	if call := saved.Messages[1].ToolCalls; len(call) != 1 || call[0].ID != "call_1" {
+		t.Errorf("Expected the tool call to be saved, got %+v", saved.Messages[1])
+	}
+	if saved.Messages[2].ToolCallID != "call_1" {
+		t.Errorf("Expected the tool result to be saved, got %+v", saved.Messages[2])
+	}
+}
+
+func TestSessionResumeOnReconnect(t *testing.T) {
+	store := newFakeStorage()
+	store.SaveSession(context.Background(), &storage.Session{
+		ID:      "ws-existing",
+		OwnerID: wsTestUserID,
+		Messages: []client.Message{
+			{Role: "system", Content: "stale prompt"},
+			{Role: "user", Content: "read main.go"},
+			{Role: "assistant", ToolCalls: []client.ToolCall{{
+				ID:       "call_9",
+				Type:     "function",
+				Function: client.FunctionCall{Name: "Read", Arguments: `{"file_path":"main.go"}`},
+			}}},
+			{Role: "tool", ToolCallID: "call_9", Content: "package main"},
+			{Role: "assistant", Content: "It is the main package."},
+		},
+	})
+	up := &scriptedUpstream{}
+	conn := dialTestServer(t, newTestServerWithStorage(t, up, store))
+	readUntil(t, conn, "system")
+
+	conn.WriteJSON(WSMessage{Type: "resume", SessionID: "ws-existing"})
+	if msg := readUntil(t, conn, "session"); msg.SessionID != "ws-existing" {
+		t.Fatalf("Expected to resume ws-existing, got %+v", msg)
+	}
+
+	conn.WriteJSON(WSMessage{Type: "chat", Content: "and now?"})
+	readUntil(t, conn, "done")
+
+	up.mu.Lock()
+	sent := up.requests[0]
+	up.mu.Unlock()
+	if got := roles(sent); got != "system,user,assistant,tool,assistant,user" {
+		t.Fatalf("Expected seeded history, got %s", got)
+	}
+	if sent[0].Content == "stale prompt" {
+		t.Error("Expected the current system prompt, not the stored one")
+	}
+	if got := toolResult(sent, "call_9"); got != "package main" {
+		t.Errorf("Expected the stored tool result, got %q", got)
+	}
+
+	saved, _ := store.LoadSession(context.Background(), "ws-existing")
+	if got := roles(saved.Messages); got != "user,assistant,tool,assistant,user,assistant" {
+		t.Errorf("Expected the new turn appended, got %s", got)
+	}
+}
+
+func TestSessionResumeRejectsBadIDs(t *testing.T) {
+	store := newFakeStorage()
+	conn := dialTestServer(t, newTestServerWithStorage(t, &scriptedUpstream{}, store))
+	welcome := readUntil(t, conn, "system")
+
+	conn.WriteJSON(WSMessage{Type: "resume", SessionID: "../../etc/passwd"})
+	readUntil(t, conn, "error")
+	if msg := readUntil(t, conn, "session"); msg.SessionID != welcome.SessionID {
+		t.Errorf("Expected to keep session %s, got %s", welcome.SessionID, msg.SessionID)
+	}
+}
+
+func TestPostedSessionApprovalRulesIgnored(t *testing.T) {
+	store := newFakeStorage()
+	store.SaveSession(context.Background(), &storage.Session{
+		ID:            "ws-kept",
+		OwnerID:       wsTestUserID,
+		ApprovalRules: []tool.ApprovalRule{{Tool: "Read"}},
+	})
+	api := &Server{storage: store}
+	for _, body := range []string{
+		`{"id": "ws-planted", "messages": [{"role": "user", "content": "hi"}], "approval_rules": [{"tool": "Bash"}]}`,
+		`{"id": "ws-kept", "messages": [{"role": "user", "content": "hi"}], "approval_rules": [{"tool": "Bash"}]}`,
+	} {
+		req := httptest.NewRequest(http.MethodPost, "/api/sessions", strings.NewReader(body))
+		req.RemoteAddr = "127.0.0.1:1234"
+		rec := httptest.NewRecorder()
+		api.handleSessions(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("Expected the session saved, got %d: %s", rec.Code, rec.Body)
+		}
+	}
+
+	conn := dialTestServer(t, newTestServerWithStorage(t, &scriptedUpstream{}, store))
+	readUntil(t, conn, "system")
+	for id, want := range map[string]string{"ws-planted": "", "ws-kept": "Read"} {
+		conn.WriteJSON(WSMessage{Type: "resume", SessionID: id})
+		readUntil(t, conn, "session")
+		conn.WriteJSON(WSMessage{Type: "approval_rules"})
+		var got []string
+		for _, rule := range readUntil(t, conn, "approval_rules").Rules {
+			got = append(got, rule.String())
+		}
+		if strings.Join(got, ",") != want {
+			t.Errorf("%s: expected rules %q, got %q", id, want, got)
+		}
+	}
+}
+
+func TestSessionsScopedToOwner(t *testing.T) {
+	store := newFakeStorage()
+	store.SaveSession(context.Background(), &storage.Session{
+		ID:       "ws-theirs",
+		OwnerID:  ipUserID("198.51.100.7"),
+		Messages: []client.Message{client.NewTextMessage("user", "private")},
+	})
+	store.SaveSession(context.Background(), &storage.Session{
+		ID:       "ws-repl",
+		Messages: []client.Message{client.NewTextMessage("user", "local")},
+	})
+	api := &Server{storage: store}
+	call := func(handler http.HandlerFunc, method, target, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(method, target, strings.NewReader(body)))
+		return rec
+	}
+
+	if rec := call(api.handleSessions, http.MethodGet, "/api/sessions", ""); rec.Body.String() != "[]\n" {
+		t.Errorf("Expected no sessions listed, got %s", rec.Body)
+	}
+	for _, id := range []string{"ws-theirs", "ws-repl"} {
+		for _, tc := range []struct{ method, target string }{
+			{http.MethodGet, "/api/sessions/" + id},
+			{http.MethodGet, "/api/sessions/" + id + "/export"},
+			{http.MethodDelete, "/api/sessions/" + id},
+		} {
+			if rec := call(api.handleSession, tc.method, tc.target, ""); rec.Code != http.StatusNotFound {
+				t.Errorf("%s %s: expected 404, got %d", tc.method, tc.target, rec.Code)
+			}
+		}
+	}
+	if rec := call(api.handleSessions, http.MethodPost, "/api/sessions", `{"id": "ws-theirs", "messages": []}`); rec.Code != http.StatusForbidden {
+		t.Errorf("Expected overwriting another user's session to be refused, got %d", rec.Code)
+	}
+	if saved, _ := store.LoadSession(context.Background(), "ws-theirs"); saved == nil || len(saved.Messages) != 1 {
+		t.Errorf("Expected the session left alone, got %+v", saved)
+	}
+
+	conn := dialTestServer(t, newTestServerWithStorage(t, &scriptedUpstream{}, store))
+	welcome := readUntil(t, conn, "system")
+	conn.WriteJSON(WSMessage{Type: "resume", SessionID: "ws-theirs"})
+	readUntil(t, conn, "error")
+	if msg := readUntil(t, conn, "session"); msg.SessionID != welcome.SessionID {
+		t.Errorf("Expected to keep session %s, got %s", welcome.SessionID, msg.SessionID)
+	}
+}
+
+func TestSessionMessagesTruncation(t *testing.T) {
+	big := strings.Repeat("x", maxToolResultBytes*2)
+	history := []client.Message{
+		{Role: "system", Content: "prompt"},
+		{Role: "user", Content: "first"},
+		{Role: "assistant", Content: "old answer"},
+		{Role: "user", Content: "second"},
+		{Role: "assistant", ToolCalls: []client.ToolCall{{ID: "c1", Function: client.FunctionCall{Name: "Read"}}}},
+		{Role: "tool", ToolCallID: "c1", Content: big},
+	}
+
+	got := sessionMessages(history, int64(maxToolResultBytes+100))
+	if r := roles(got); r != "user,assistant,tool" {
+		t.Fatalf("Expected the oldest turn dropped, got %s", r)
+	}
+	if content := got[2].Content.(string); len(content) >= len(big) {
+		t.Errorf("Expected the tool result to be truncated, got %d bytes", len(content))
+	}
+	if history[5].Content != big {
+		t.Error("Expected the live history to be left untouched")
+	}
+}
@@ -2,18 +2,34 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"maps"
 	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
 
+	"groq-go/internal/backup"
 	"groq-go/internal/client"
 	"groq-go/internal/config"
+	"groq-go/internal/conversation"
+	"groq-go/internal/janitor"
 	"groq-go/internal/knowledge"
 	"groq-go/internal/logging"
 	"groq-go/internal/mcp"
+	"groq-go/internal/memory"
+	"groq-go/internal/notify"
 	"groq-go/internal/plugin"
+	"groq-go/internal/project"
 	"groq-go/internal/repl"
+	"groq-go/internal/scheduler"
 	"groq-go/internal/selfimprove"
+	"groq-go/internal/setup"
+	"groq-go/internal/storage"
 	"groq-go/internal/tool"
 	"groq-go/internal/tool/tools"
 	"groq-go/internal/version"
@@ -23,23 +39,100 @@ import (
 func main() {
 	if err := run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-		os.Exit(1)
+		os.Exit(repl.ExitCode(err))
 	}
 }
 
 func run() error {
 	// Parse flags
 	webMode := flag.Bool("web", false, "Start web server instead of CLI")
-	webAddr := flag.String("addr", ":8080", "Web server address")
+	webAddr := flag.String("addr", "", "Web server address (default web.addr from config, or :8080)")
+	reconfigure := flag.Bool("reconfigure", false, "Run the setup wizard even if configuration exists")
+	pretty := flag.Bool("pretty", false, "Render markdown and highlight code in CLI responses")
+	oneShot := flag.Bool("p", false, "Send the arguments and stdin as one message, print the reply and exit")
+	flag.BoolVar(oneShot, "prompt", false, "Same as -p")
+	output := flag.String("output", repl.OutputText, "One-shot output: text or json")
+	maxTurns := flag.Int("max-turns", 0, "Limit one-shot API calls per message (0 for no limit)")
+	readOnly := flag.Bool("read-only", false, "Hide tools that change anything, allow only cheap models and cap conversations (also read_only.enabled)")
 	flag.Parse()
 
+	// Subcommands; in one-shot mode the arguments are the prompt
+	if !*oneShot {
+		switch flag.Arg(0) {
+		case "gc":
+			return runGC(flag.Args()[1:])
+		case "backup":
+			return runBackup(flag.Args()[1:])
+		case "restore":
+			return runRestore(flag.Args()[1:])
+		}
+	}
+	if *output != repl.OutputText && *output != repl.OutputJSON {
+		return fmt.Errorf("unknown -output %q (want text or json)", *output)
+	}
+
 	// Load configuration
 	cfg, err := config.Load()
-	if err != nil {
+	needsSetup := errors.Is(err, config.ErrNotConfigured) && (config.IsFirstRun() || *reconfigure)
+	if err != nil && !needsSetup {
+		return err
+	}
+	if err != nil && *oneShot {
 		return err
 	}
 
+	// First run (or --reconfigure): the CLI runs the wizard now, web mode serves /setup
+	if !*webMode && !*oneShot && (needsSetup || *reconfigure) {
+		if stat, _ := os.Stdin.Stat(); stat.Mode()&os.ModeCharDevice == 0 {
+			return config.ErrNotConfigured
+		}
+		if _, err := setup.RunWizard(context.Background(), setup.NewTerminalPrompter(), os.Stdout, *reconfigure); err != nil {
+			return err
+		}
+		if cfg, err = config.Load(); err != nil {
+			return err
+		}
+	}
+	if *webAddr != "" {
+		cfg.Web.Addr = *webAddr
+	}
+	conversation.ContextLimit = cfg.ContextSize
+	if cfg.GrepMaxFileSize > 0 {
+		tools.GrepMaxFileSize = cfg.GrepMaxFileSize
+	}
+	if cfg.ToolResultMaxBytes > 0 {
+		tool.MaxResultBytes = cfg.ToolResultMaxBytes
+	}
+	if cfg.ToolTurnMaxBytes > 0 {
+		tool.MaxTurnResultBytes = cfg.ToolTurnMaxBytes
+	}
+
+	// Custom endpoints and models from the config join the built-in
+	// catalog before any routing
+	for _, e := range cfg.Endpoints {
+		if err := e.Register(client.Catalog); err != nil {
+			return fmt.Errorf("endpoints: %w", err)
+		}
+	}
+	for _, m := range cfg.Models {
+		if err := client.Catalog.Add(m.Info()); err != nil {
+			return fmt.Errorf("models: %w", err)
+		}
+	}
+
 	// Create API client with provider keys
+	// Read-only mode keeps to cheap models and tells the model why it
+	// cannot write; the tools are hidden once the registry exists
+	if *readOnly {
+		cfg.ReadOnly.Enabled = true
+	}
+	if cfg.ReadOnly.Enabled {
+		cfg.FallbackModels = slices.DeleteFunc(cfg.FallbackModels, func(m string) bool {
+			return !cfg.ReadOnly.AllowsModel(m)
+		})
+		cfg.SystemPromptAppend = strings.TrimSpace(cfg.SystemPromptAppend + "\n\n" + conversation.ReadOnlyNotice)
+	}
+
 	opts := []client.Option{client.WithModel(cfg.Model)}
 	if cfg.MoonshotKey != "" {
 		opts = append(opts, client.WithProviderKey("moonshot", cfg.MoonshotKey))
@@ -50,21 +143,80 @@ func run() error {
 	if cfg.ClaudeKey != "" {
 		opts = append(opts, client.WithProviderKey("anthropic", cfg.ClaudeKey))
 	}
+	if cfg.GeminiKey != "" {
+		opts = append(opts, client.WithProviderKey("gemini", cfg.GeminiKey))
+	}
+	for _, e := range cfg.Endpoints {
+		opts = append(opts, client.WithEndpoint(e.Name, e.BaseURL, e.APIKey))
+	}
+	if cfg.PromptCaching {
+		opts = append(opts, client.WithPromptCaching())
+	}
+	if len(cfg.FallbackModels) > 0 {
+		opts = append(opts, client.WithFallbackModels(cfg.FallbackModels...))
+	}
+	if cfg.Temperature != nil {
+		opts = append(opts, client.WithTemperature(*cfg.Temperature))
+	}
+	if cfg.Cache.Enabled {
+		dir := cfg.Cache.Dir
+		if dir == "" {
+			dir = config.CacheDir()
+		}
+		if cfg.Cache.MaxMB > 0 {
+			client.CacheMaxBytes = int64(cfg.Cache.MaxMB) << 20
+		}
+		opts = append(opts, client.WithCache(dir, time.Duration(cfg.Cache.TTLSeconds)*time.Second))
+		logging.Info("Response cache enabled", "dir", dir)
+	}
 	apiClient := client.New(cfg.APIKey, opts...)
+	if cfg.ReadOnly.Enabled && !cfg.ReadOnly.AllowsModel(apiClient.Model()) {
+		model, err := readOnlyModel(cfg.ReadOnly, apiClient)
+		if err != nil {
+			return err
+		}
+		logging.Info("Read-only mode switched model", "from", apiClient.Model(), "to", model)
+		apiClient.SetModel(model)
+	}
+
+	// House rules from system_prompt.md and SYSTEM_PROMPT_APPEND
+	systemPrompt, err := conversation.LoadPrompt(conversation.PromptFile(), cfg.SystemPromptAppend)
+	if err != nil {
+		return err
+	}
 
 	// Initialize knowledge base
-	kb, err := knowledge.NewKnowledgeBase(knowledge.DefaultKnowledgeDir())
+	var kbOpts []knowledge.Option
+	if cfg.Knowledge.ChunkSize > 0 {
+		kbOpts = append(kbOpts, knowledge.WithChunkSize(cfg.Knowledge.ChunkSize))
+	}
+	if cfg.Knowledge.ChunkOverlap > 0 {
+		kbOpts = append(kbOpts, knowledge.WithChunkOverlap(cfg.Knowledge.ChunkOverlap))
+	}
+	kb, err := knowledge.NewKnowledgeBase(knowledge.DefaultKnowledgeDir(), kbOpts...)
 	if err != nil {
 		logging.Warn("Failed to initialize knowledge base", "error", err)
 	}
 
+	// What the user asked to remember across sessions
+	memories, err := memory.Open(memory.DefaultPath())
+	if err != nil {
+		logging.Warn("Failed to load memories", "error", err)
+	}
+
+	// Events about builds, deploys and scheduled jobs go to the configured
+	// webhooks; queued events get a moment to go out on exit
+	notifier := notify.New(cfg.Notifications)
+	defer notifier.Close()
+
 	// Initialize self-improvement manager
 	var selfImproveManager *selfimprove.Manager
-	if os.Getenv("GITHUB_TOKEN") != "" {
-		selfImproveManager, err = selfimprove.NewManager()
+	if cfg.SelfImprove.GitHubToken != "" {
+		selfImproveManager, err = selfimprove.NewManager(cfg.SelfImprove)
 		if err != nil {
 			logging.Warn("Failed to initialize self-improve manager", "error", err)
 		} else {
+			selfImproveManager.SetNotifier(notifier)
 			// Initialize repo in background
 			go func() {
 				ctx := context.Background()
@@ -84,13 +236,39 @@ func run() error {
 		if err != nil {
 			logging.Warn("Failed to initialize version manager", "error", err)
 		} else {
+			versionManager.SetNotifier(notifier)
 			logging.Info("Version manager initialized")
 		}
 	}
 
 	// Create tool registry and register built-in tools
 	registry := tool.NewRegistry()
-	registerTools(registry, kb, selfImproveManager, versionManager)
+	registerTools(registry, kb, selfImproveManager, versionManager, cfg.SelfImprove.GitHubToken)
+	if memories != nil {
+		registry.Register(tools.NewMemoryTool(memories))
+	}
+	registry.Register(tools.NewTranscribeTool(apiClient))
+	defer func() {
+		if n := tools.StopBackgroundJobs(); n > 0 {
+			logging.Info("Stopped background jobs", "count", n)
+		}
+		tools.StopBrowser()
+	}()
+	if cfg.ReadOnly.Enabled {
+		registry.SetReadOnly(true)
+		logging.Info("Read-only mode enabled", "models", cfg.ReadOnly.AllowedModels(), "max_turns", cfg.ReadOnly.TurnLimit())
+	}
+	if cfg.Approval.Enabled {
+		registry.RequireApproval(cfg.Approval.Tools)
+		if cfg.Approval.TimeoutSeconds > 0 {
+			tool.ApprovalTimeout = time.Duration(cfg.Approval.TimeoutSeconds) * time.Second
+		}
+		logging.Info("Tool approval required", "tools", cfg.Approval.Tools, "timeout", tool.ApprovalTimeout)
+	}
+	if !cfg.Tools.IsZero() {
+		registry.SetPolicy(&cfg.Tools)
+		logging.Info("Tool policy applied", "allow", cfg.Tools.Allow, "deny", cfg.Tools.Deny)
+	}
 
 	// Initialize MCP manager
 	mcpManager := mcp.NewManager()
@@ -100,18 +278,24 @@ func run() error {
 	if err := mcpManager.LoadConfig(); err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: failed to load MCP config: %v\n", err)
 	} else {
-		ctx := context.Background()
-		if err := mcpManager.StartServers(ctx); err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: failed to start MCP servers: %v\n", err)
+		if cfg.MCP.StartTimeoutSeconds > 0 {
+			mcp.StartupWait = time.Duration(cfg.MCP.StartTimeoutSeconds) * time.Second
 		}
-
-		// Register MCP tools
-		mcpToolCount := mcp.RegisterMCPTools(registry, mcpManager)
-		if mcpToolCount > 0 {
-			fmt.Fprintf(os.Stderr, "Loaded %d MCP tools from %d servers\n", mcpToolCount, mcpManager.ServerCount())
+		started := mcpManager.StartServers(context.Background())
+		for _, name := range slices.Sorted(maps.Keys(started.Failed)) {
+			fmt.Fprintf(os.Stderr, "Warning: failed to start MCP server %s: %v\n", name, started.Failed[name])
+		}
+		if len(started.Pending) > 0 {
+			fmt.Fprintf(os.Stderr, "MCP servers still starting after %s: %s; their tools are added when ready\n", mcp.StartupWait, strings.Join(started.Pending, ", "))
 		}
 	}
 
+	// Register MCP tools; servers added at runtime register their own
+	mcpToolCount := mcp.RegisterMCPTools(registry, mcpManager)
+	if mcpToolCount > 0 {
+		fmt.Fprintf(os.Stderr, "Loaded %d MCP tools from %d servers\n", mcpToolCount, mcpManager.ServerCount())
+	}
+
 	// Initialize plugin manager
 	pluginManager, err := plugin.NewManager()
 	if err != nil {
@@ -126,8 +310,52 @@ func run() error {
 
 	// Start in web mode or CLI mode
 	if *webMode {
-		server := web.NewServer(apiClient, registry, kb, pluginManager, versionManager, *webAddr)
-		return server.Start()
+		// Recurring jobs run inside the web server, so only it gets the tool
+		sched, err := scheduler.New(scheduler.DefaultPath())
+		if err != nil {
+			logging.Warn("Failed to load schedules", "error", err)
+		} else {
+			sched.SetNotifier(notifier)
+			registry.Register(tools.NewScheduleTool(sched))
+		}
+
+		server := web.NewServer(cfg, apiClient, registry, kb, pluginManager, versionManager)
+		server.SetMCPManager(mcpManager)
+		if memories != nil {
+			server.SetMemory(memories)
+		}
+		server.SetNotifier(notifier)
+		if sched != nil {
+			server.SetScheduler(sched)
+		}
+		server.SetSystemPrompt(systemPrompt)
+		if needsSetup || *reconfigure {
+			server.EnableSetup(*reconfigure)
+		}
+		return serveWeb(server)
+	}
+
+	if *oneShot {
+		stat, _ := os.Stdin.Stat()
+		prompt, err := repl.ReadPrompt(flag.Args(), os.Stdin, stat.Mode()&os.ModeCharDevice == 0)
+		if err != nil {
+			return err
+		}
+		r := repl.NewOneShot(apiClient, registry)
+		r.SetFormat(cfg.FormatOnWrite())
+		r.SetSystemPrompt(systemPrompt)
+		if memories != nil {
+			r.SetMemory(memories)
+		}
+		if pm, err := project.NewManager(); err == nil {
+			r.SetProjects(pm, cfg.SandboxDisabled)
+		}
+		return r.RunOnce(prompt, repl.OneShotOptions{
+			Output:   *output,
+			MaxTurns: *maxTurns,
+			Stdout:   os.Stdout,
+			Stderr:   os.Stderr,
+		})
 	}
 
 	// Create and run REPL
@@ -135,22 +363,72 @@ func run() error {
 	if err != nil {
 		return err
 	}
+	r.SetAutosave(cfg.Autosave)
+	r.SetFormat(cfg.FormatOnWrite())
+	r.SetMCPManager(mcpManager)
+	r.SetSystemPrompt(systemPrompt)
+	if memories != nil {
+		r.SetMemory(memories)
+	}
+	r.SetPretty(*pretty)
+	if cfg.ReadOnly.Enabled {
+		r.SetReadOnly(cfg.ReadOnly.AllowedModels(), cfg.ReadOnly.TurnLimit())
+	}
+	if pm, err := project.NewManager(); err != nil {
+		logging.Warn("Failed to initialize project manager", "error", err)
+	} else {
+		r.SetProjects(pm, cfg.SandboxDisabled)
+	}
 
 	return r.Run()
 }
 
-func registerTools(registry *tool.Registry, kb *knowledge.KnowledgeBase, sim *selfimprove.Manager, vm *version.Manager) {
+// serveWeb runs the web server until it fails or SIGINT/SIGTERM asks it
+// to shut down gracefully
+func serveWeb(server *web.Server) error {
+	errc := make(chan error, 1)
+	go func() { errc <- server.Start() }()
+
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(sigs)
+
+	select {
+	case err := <-errc:
+		return err
+	case sig := <-sigs:
+		logging.Info("Received signal, shutting down", "signal", sig.String())
+		ctx, cancel := context.WithTimeout(context.Background(), web.ShutdownTimeout)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
+}
+
+// readOnlyModel picks the first model read-only mode allows that c has a
+// key for
+func readOnlyModel(ro config.ReadOnlyConfig, c *client.Client) (string, error) {
+	for _, m := range ro.AllowedModels() {
+		if c.HasKeyFor(m) {
+			return m, nil
+		}
+	}
+	return "", fmt.Errorf("read-only mode: no API key for any allowed model (%s); set read_only.models", strings.Join(ro.AllowedModels(), ", "))
+}
+
+func registerTools(registry *tool.Registry, kb *knowledge.KnowledgeBase, sim *selfimprove.Manager, vm *version.Manager, githubToken string) {
 	registry.Register(tools.NewReadTool())
 	registry.Register(tools.NewWriteTool())
 	registry.Register(tools.NewEditTool())
 	registry.Register(tools.NewGlobTool())
 	registry.Register(tools.NewGrepTool())
 	registry.Register(tools.NewBashTool())
+	registry.Register(tools.NewBashOutputTool())
 	registry.Register(tools.NewWebFetchTool())
 	registry.Register(tools.NewBrowserTool())
-	registry.Register(tools.NewGitTool())
+	registry.Register(tools.NewGitTool(githubToken))
 	registry.Register(tools.NewImageGenTool())
 	registry.Register(tools.NewCodeExecTool())
+	registry.Register(tools.NewAskUserTool())
 
 	// Knowledge base tools
 	if kb != nil {
@@ -168,3 +446,103 @@ func registerTools(registry *tool.Registry, kb *knowledge.KnowledgeBase, sim *se
 		registry.Register(tools.NewVersionTool(vm))
 	}
 }
+
+// runGC runs a single janitor sweep over the data directories
+func runGC(args []string) error {
+	fs := flag.NewFlagSet("gc", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Only report what would be removed")
+	retention := fs.Duration("retention", janitor.DefaultUploadRetention, "Keep unreferenced uploads newer than this")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg := janitor.DefaultConfig()
+	cfg.UploadRetention = *retention
+	store, err := storage.Open(context.Background(), os.Getenv("STORAGE_BACKEND"), storage.DefaultStorageDir())
+	if err != nil {
+		return fmt.Errorf("failed to open session storage: %w", err)
+	}
+	defer store.Close()
+	cfg.Sessions = store
+
+	report := janitor.New(cfg).Sweep(context.Background(), *dryRun)
+
+	verb := "Removed"
+	if report.DryRun {
+		verb = "Would remove"
+	}
+	for _, d := range report.Deletions {
+		fmt.Printf("%s %s (%s, %d bytes): %s\n", verb, d.Path, d.Category, d.Bytes, d.Reason)
+	}
+	for category, n := range report.Reclaimed {
+		fmt.Printf("%-10s %d bytes\n", category, n)
+	}
+	fmt.Printf("Total: %d bytes in %s\n", report.TotalBytes(), report.Duration.Round(time.Millisecond))
+	for _, e := range report.Errors {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", e)
+	}
+	return nil
+}
+
+// runBackup writes the data directory to a tar.gz archive
+func runBackup(args []string) error {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	output := fs.String("o", "", "Output file (default: groq-go-backup-<date>.tar.gz)")
+	noSecrets := fs.Bool("no-secrets", false, "Exclude API keys, users and MCP config")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	path := *output
+	if path == "" {
+		path = fmt.Sprintf("groq-go-backup-%s.tar.gz", time.Now().Format("20060102-150405"))
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+
+	manifest, err := backup.Create(f, backup.Options{IncludeSecrets: !*noSecrets})
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return fmt.Errorf("backup failed: %w", err)
+	}
+
+	fmt.Printf("Wrote %s (%d files)\n", path, manifest.Files)
+	for category, n := range manifest.Counts {
+		fmt.Printf("  %-10s %d\n", category, n)
+	}
+	return nil
+}
+
+// runRestore extracts a backup archive into the data directory
+func runRestore(args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Overwrite an existing data directory")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("usage: groq-go restore [-force] <backup.tar.gz>")
+	}
+
+	f, err := os.Open(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer f.Close()
+
+	manifest, err := backup.Restore(f, backup.RestoreOptions{Force: *force})
+	if err != nil {
+		return fmt.Errorf("restore failed: %w", err)
+	}
+
+	fmt.Printf("Restored %d files from backup created %s\n", manifest.Files, manifest.CreatedAt.Format(time.RFC3339))
+	if !manifest.IncludesSecrets {
+		fmt.Println("Backup did not include secrets; run with -reconfigure to set API keys.")
+	}
+	return nil
+}